@@ -29,7 +29,9 @@ func main() {
 	}
 
 	// Graceful shutdown
+	shutdownDone := make(chan struct{})
 	go func() {
+		defer close(shutdownDone)
 		sigCh := make(chan os.Signal, 1)
 		signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
 		<-sigCh
@@ -55,5 +57,10 @@ func main() {
 	}
 	if listenErr != nil && listenErr != http.ErrServerClosed {
 		log.Printf("server stopped: %v", listenErr)
+		return
 	}
+
+	// ListenAndServe returns as soon as Shutdown begins; wait for it to finish
+	// draining requests and stopping background jobs.
+	<-shutdownDone
 }
@@ -6,6 +6,8 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"github.com/kento/driver/backend/internal/eta"
 )
 
 // knownWeakSecrets is a blocklist of default/weak JWT secrets that must not
@@ -29,7 +31,25 @@ type Config struct {
 	FirebaseCredentialsPath  string
 	LocationStaleThreshold   time.Duration
 	LocationLogRetentionDays int
+	LocationHistoryMaxDays   int
 	ReservationReminderMin   int
+	SeriesHorizonDays        int
+	ReservationTravelCheck   bool
+	ReservationTripLead      time.Duration
+	MaintenancePlanLead      time.Duration
+	MileageAnomalyPct        float64
+	MileageAnomalyMinKm      float64
+	DocumentExpiryWarnDays   int
+	AutoDispatchMode         string
+	AutoDispatchWeightETA    float64
+	AutoDispatchWeightFair   float64
+	AutoDispatchWeightIdle   float64
+	DispatchAcceptTimeout    time.Duration
+	ETAProviders             []string
+	ETAProviderTimeout       time.Duration
+	ETASpeedProfile          string
+	ETALearnWindowDays       int
+	OSRMURL                  string
 	CORSOrigins              []string
 	RateLimitRate            float64
 	RateLimitBurst           int
@@ -48,8 +68,26 @@ func Load() (*Config, error) {
 		GoogleMapsAPIKey:         getEnv("GOOGLE_MAPS_API_KEY", ""),
 		FirebaseCredentialsPath:  getEnv("FIREBASE_CREDENTIALS_PATH", ""),
 		LocationStaleThreshold:   parseDuration(getEnv("LOCATION_STALE_THRESHOLD", "2m")),
-		LocationLogRetentionDays: 90,
-		ReservationReminderMin:   30,
+		LocationLogRetentionDays: parseCount(getEnv("LOCATION_LOG_RETENTION_DAYS", "90")),
+		LocationHistoryMaxDays:   parseCount(getEnv("LOCATION_HISTORY_MAX_DAYS", "730")),
+		ReservationReminderMin:   parseCount(getEnv("RESERVATION_REMINDER_MINUTES", "30")),
+		SeriesHorizonDays:        parseInt(getEnv("RESERVATION_SERIES_HORIZON_DAYS", "60")),
+		ReservationTravelCheck:   parseBool(getEnv("RESERVATION_TRAVEL_CHECK", "false")),
+		ReservationTripLead:      parseDuration(getEnv("RESERVATION_TRIP_LEAD", "30m")),
+		MaintenancePlanLead:      parseDuration(getEnv("MAINTENANCE_PLAN_LEAD", "72h")),
+		MileageAnomalyPct:        parseFloat(getEnv("MILEAGE_ANOMALY_PCT", "20")),
+		MileageAnomalyMinKm:      parseFloat(getEnv("MILEAGE_ANOMALY_MIN_KM", "5")),
+		DocumentExpiryWarnDays:   parseInt(getEnv("DOCUMENT_EXPIRY_WARN_DAYS", "30")),
+		AutoDispatchMode:         getEnv("AUTO_DISPATCH_MODE", "off"),
+		AutoDispatchWeightETA:    parseFloat(getEnv("AUTO_DISPATCH_WEIGHT_ETA", "1.0")),
+		AutoDispatchWeightFair:   parseFloat(getEnv("AUTO_DISPATCH_WEIGHT_FAIRNESS", "0.3")),
+		AutoDispatchWeightIdle:   parseFloat(getEnv("AUTO_DISPATCH_WEIGHT_IDLE", "0.2")),
+		DispatchAcceptTimeout:    parseDuration(getEnv("DISPATCH_ACCEPT_TIMEOUT", "2m")),
+		ETAProviders:             parseList(getEnv("ETA_PROVIDERS", "google,osrm,learned")),
+		ETAProviderTimeout:       parseDuration(getEnv("ETA_PROVIDER_TIMEOUT", "3s")),
+		ETASpeedProfile:          getEnv("ETA_SPEED_PROFILE", "18"),
+		ETALearnWindowDays:       parseInt(getEnv("ETA_LEARN_WINDOW_DAYS", "90")),
+		OSRMURL:                  getEnv("OSRM_URL", ""),
 		CORSOrigins:              parseCORSOrigins(getEnv("CORS_ORIGINS", "http://localhost:5173")),
 		RateLimitRate:            parseFloat(getEnv("RATE_LIMIT_RATE", "20")),
 		RateLimitBurst:           parseInt(getEnv("RATE_LIMIT_BURST", "40")),
@@ -77,6 +115,66 @@ func (c *Config) validate() error {
 		}
 	}
 
+	switch c.AutoDispatchMode {
+	case "off", "suggest", "auto":
+	default:
+		return fmt.Errorf("AUTO_DISPATCH_MODE must be one of off, suggest, auto (got %q)", c.AutoDispatchMode)
+	}
+
+	for _, p := range c.ETAProviders {
+		switch p {
+		case "google", "osrm", "learned":
+		default:
+			return fmt.Errorf("ETA_PROVIDERS may only list google, osrm and learned (got %q)", p)
+		}
+	}
+
+	if _, err := eta.ParseSpeedProfile(c.ETASpeedProfile); err != nil {
+		return fmt.Errorf("ETA_SPEED_PROFILE: %w", err)
+	}
+
+	// A retention of zero would have the location job downsample or drop
+	// every raw point
+	if c.LocationLogRetentionDays <= 0 {
+		return fmt.Errorf("LOCATION_LOG_RETENTION_DAYS must be a positive number of days")
+	}
+
+	if c.LocationHistoryMaxDays <= 0 {
+		return fmt.Errorf("LOCATION_HISTORY_MAX_DAYS must be a positive number of days")
+	}
+
+	if c.ReservationReminderMin <= 0 {
+		return fmt.Errorf("RESERVATION_REMINDER_MINUTES must be a positive number of minutes")
+	}
+
+	if c.SeriesHorizonDays < 1 {
+		return fmt.Errorf("RESERVATION_SERIES_HORIZON_DAYS must be at least 1 (got %d)", c.SeriesHorizonDays)
+	}
+
+	if c.ReservationTripLead <= 0 {
+		return fmt.Errorf("RESERVATION_TRIP_LEAD must be positive (got %s)", c.ReservationTripLead)
+	}
+
+	if c.MaintenancePlanLead <= 0 {
+		return fmt.Errorf("MAINTENANCE_PLAN_LEAD must be positive (got %s)", c.MaintenancePlanLead)
+	}
+
+	if c.MileageAnomalyPct <= 0 {
+		return fmt.Errorf("MILEAGE_ANOMALY_PCT must be positive (got %g)", c.MileageAnomalyPct)
+	}
+
+	if c.MileageAnomalyMinKm < 0 {
+		return fmt.Errorf("MILEAGE_ANOMALY_MIN_KM must not be negative (got %g)", c.MileageAnomalyMinKm)
+	}
+
+	if c.DocumentExpiryWarnDays < 0 {
+		return fmt.Errorf("DOCUMENT_EXPIRY_WARN_DAYS must not be negative (got %d)", c.DocumentExpiryWarnDays)
+	}
+
+	if c.ReservationTravelCheck && c.GoogleMapsAPIKey == "" {
+		return fmt.Errorf("RESERVATION_TRAVEL_CHECK needs GOOGLE_MAPS_API_KEY")
+	}
+
 	if c.Env != "production" {
 		return nil
 	}
@@ -129,6 +227,19 @@ func parseCORSOrigins(raw string) []string {
 	return origins
 }
 
+// parseList splits a comma-separated value, dropping blanks. Unlike
+// parseCORSOrigins an empty value means an empty list.
+func parseList(raw string) []string {
+	var items []string
+	for _, item := range strings.Split(raw, ",") {
+		item = strings.TrimSpace(item)
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
@@ -159,3 +270,21 @@ func parseInt(s string) int {
 	}
 	return i
 }
+
+// parseCount parses a setting that must be positive. Bad input gives 0 so
+// validate rejects it instead of running with a fallback.
+func parseCount(s string) int {
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return 0
+	}
+	return i
+}
+
+func parseBool(s string) bool {
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false
+	}
+	return b
+}
@@ -14,6 +14,13 @@ func clearEnv() {
 		"GOOGLE_MAPS_API_KEY", "FIREBASE_CREDENTIALS_PATH",
 		"LOCATION_STALE_THRESHOLD", "CORS_ORIGINS",
 		"RATE_LIMIT_RATE", "RATE_LIMIT_BURST",
+		"LOCATION_LOG_RETENTION_DAYS", "LOCATION_HISTORY_MAX_DAYS", "RESERVATION_REMINDER_MINUTES", "RESERVATION_SERIES_HORIZON_DAYS",
+		"RESERVATION_TRAVEL_CHECK", "RESERVATION_TRIP_LEAD", "MAINTENANCE_PLAN_LEAD",
+		"MILEAGE_ANOMALY_PCT", "MILEAGE_ANOMALY_MIN_KM", "DOCUMENT_EXPIRY_WARN_DAYS",
+		"AUTO_DISPATCH_MODE", "AUTO_DISPATCH_WEIGHT_ETA", "AUTO_DISPATCH_WEIGHT_FAIRNESS", "AUTO_DISPATCH_WEIGHT_IDLE",
+		"DISPATCH_ACCEPT_TIMEOUT",
+		"ETA_PROVIDERS", "ETA_PROVIDER_TIMEOUT", "ETA_SPEED_PROFILE", "OSRM_URL",
+		"ETA_LEARN_WINDOW_DAYS",
 	} {
 		os.Unsetenv(v)
 	}
@@ -47,9 +54,52 @@ func TestLoadDefaults(t *testing.T) {
 	if cfg.LocationLogRetentionDays != 90 {
 		t.Errorf("LocationLogRetentionDays = %d, want %d", cfg.LocationLogRetentionDays, 90)
 	}
+	if cfg.LocationHistoryMaxDays != 730 {
+		t.Errorf("LocationHistoryMaxDays = %d, want %d", cfg.LocationHistoryMaxDays, 730)
+	}
 	if cfg.ReservationReminderMin != 30 {
 		t.Errorf("ReservationReminderMin = %d, want %d", cfg.ReservationReminderMin, 30)
 	}
+	if cfg.SeriesHorizonDays != 60 {
+		t.Errorf("SeriesHorizonDays = %d, want %d", cfg.SeriesHorizonDays, 60)
+	}
+	if cfg.ReservationTravelCheck {
+		t.Error("ReservationTravelCheck = true, want false")
+	}
+	if cfg.ReservationTripLead != 30*time.Minute {
+		t.Errorf("ReservationTripLead = %v, want %v", cfg.ReservationTripLead, 30*time.Minute)
+	}
+	if cfg.MaintenancePlanLead != 72*time.Hour {
+		t.Errorf("MaintenancePlanLead = %v, want %v", cfg.MaintenancePlanLead, 72*time.Hour)
+	}
+	if cfg.MileageAnomalyPct != 20 || cfg.MileageAnomalyMinKm != 5 {
+		t.Errorf("MileageAnomaly = %v%% / %v km, want 20%% / 5 km", cfg.MileageAnomalyPct, cfg.MileageAnomalyMinKm)
+	}
+	if cfg.DocumentExpiryWarnDays != 30 {
+		t.Errorf("DocumentExpiryWarnDays = %d, want 30", cfg.DocumentExpiryWarnDays)
+	}
+	if cfg.AutoDispatchMode != "off" {
+		t.Errorf("AutoDispatchMode = %q, want %q", cfg.AutoDispatchMode, "off")
+	}
+	if cfg.AutoDispatchWeightETA != 1.0 || cfg.AutoDispatchWeightFair != 0.3 || cfg.AutoDispatchWeightIdle != 0.2 {
+		t.Errorf("AutoDispatch weights = %v/%v/%v, want 1/0.3/0.2",
+			cfg.AutoDispatchWeightETA, cfg.AutoDispatchWeightFair, cfg.AutoDispatchWeightIdle)
+	}
+	if cfg.DispatchAcceptTimeout != 2*time.Minute {
+		t.Errorf("DispatchAcceptTimeout = %v, want %v", cfg.DispatchAcceptTimeout, 2*time.Minute)
+	}
+	if strings.Join(cfg.ETAProviders, ",") != "google,osrm,learned" {
+		t.Errorf("ETAProviders = %v, want [google osrm learned]", cfg.ETAProviders)
+	}
+	if cfg.ETAProviderTimeout != 3*time.Second {
+		t.Errorf("ETAProviderTimeout = %v, want %v", cfg.ETAProviderTimeout, 3*time.Second)
+	}
+	if cfg.ETASpeedProfile != "18" {
+		t.Errorf("ETASpeedProfile = %q, want %q", cfg.ETASpeedProfile, "18")
+	}
+	if cfg.ETALearnWindowDays != 90 {
+		t.Errorf("ETALearnWindowDays = %d, want %d", cfg.ETALearnWindowDays, 90)
+	}
 	if len(cfg.CORSOrigins) != 1 || cfg.CORSOrigins[0] != "http://localhost:5173" {
 		t.Errorf("CORSOrigins = %v, want [http://localhost:5173]", cfg.CORSOrigins)
 	}
@@ -82,6 +132,9 @@ func TestLoadFromEnv(t *testing.T) {
 	os.Setenv("JWT_REFRESH_EXPIRY", "720h")
 	os.Setenv("LOCATION_STALE_THRESHOLD", "5m")
 	os.Setenv("CORS_ORIGINS", "https://app.example.com,https://admin.example.com")
+	os.Setenv("LOCATION_LOG_RETENTION_DAYS", "30")
+	os.Setenv("LOCATION_HISTORY_MAX_DAYS", "365")
+	os.Setenv("RESERVATION_REMINDER_MINUTES", "15")
 	defer clearEnv()
 
 	cfg, err := Load()
@@ -113,6 +166,104 @@ func TestLoadFromEnv(t *testing.T) {
 	if len(cfg.CORSOrigins) != 2 || cfg.CORSOrigins[0] != "https://app.example.com" {
 		t.Errorf("CORSOrigins = %v, want [https://app.example.com, https://admin.example.com]", cfg.CORSOrigins)
 	}
+	if cfg.LocationLogRetentionDays != 30 {
+		t.Errorf("LocationLogRetentionDays = %d, want %d", cfg.LocationLogRetentionDays, 30)
+	}
+	if cfg.LocationHistoryMaxDays != 365 {
+		t.Errorf("LocationHistoryMaxDays = %d, want %d", cfg.LocationHistoryMaxDays, 365)
+	}
+	if cfg.ReservationReminderMin != 15 {
+		t.Errorf("ReservationReminderMin = %d, want %d", cfg.ReservationReminderMin, 15)
+	}
+}
+
+func TestInvalidAutoDispatchMode(t *testing.T) {
+	clearEnv()
+	os.Setenv("JWT_SECRET", "test-dev-secret")
+	os.Setenv("AUTO_DISPATCH_MODE", "sometimes")
+	defer clearEnv()
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected error for invalid AUTO_DISPATCH_MODE")
+	}
+	if !strings.Contains(err.Error(), "AUTO_DISPATCH_MODE") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestInvalidETAProvider(t *testing.T) {
+	clearEnv()
+	os.Setenv("JWT_SECRET", "test-dev-secret")
+	os.Setenv("ETA_PROVIDERS", "osrm, valhalla")
+	defer clearEnv()
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected error for unknown ETA provider")
+	}
+	if !strings.Contains(err.Error(), "valhalla") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestInvalidETASpeedProfile(t *testing.T) {
+	clearEnv()
+	os.Setenv("JWT_SECRET", "test-dev-secret")
+	os.Setenv("ETA_SPEED_PROFILE", "18,7-9")
+	defer clearEnv()
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected error for unparsable ETA_SPEED_PROFILE")
+	}
+	if !strings.Contains(err.Error(), "ETA_SPEED_PROFILE") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+}
+
+func TestInvalidPositiveCounts(t *testing.T) {
+	for _, key := range []string{"LOCATION_LOG_RETENTION_DAYS", "LOCATION_HISTORY_MAX_DAYS", "RESERVATION_REMINDER_MINUTES"} {
+		for _, value := range []string{"0", "-5", "9O"} {
+			clearEnv()
+			os.Setenv("JWT_SECRET", "test-dev-secret")
+			os.Setenv(key, value)
+
+			_, err := Load()
+			if err == nil {
+				t.Errorf("%s=%s: expected error", key, value)
+				continue
+			}
+			if !strings.Contains(err.Error(), key) {
+				t.Errorf("%s=%s: unexpected error message: %v", key, value, err)
+			}
+		}
+	}
+	clearEnv()
+}
+
+func TestTravelCheckNeedsMapsKey(t *testing.T) {
+	clearEnv()
+	os.Setenv("JWT_SECRET", "test-dev-secret")
+	os.Setenv("RESERVATION_TRAVEL_CHECK", "true")
+	defer clearEnv()
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected error for travel check without a maps key")
+	}
+	if !strings.Contains(err.Error(), "GOOGLE_MAPS_API_KEY") {
+		t.Errorf("unexpected error message: %v", err)
+	}
+
+	os.Setenv("GOOGLE_MAPS_API_KEY", "key")
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.ReservationTravelCheck {
+		t.Error("ReservationTravelCheck = false, want true")
+	}
 }
 
 func TestParseDurationInvalid(t *testing.T) {
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_reservations_confirmed_start;
+DROP TABLE IF EXISTS reservation_reminders;
@@ -0,0 +1,13 @@
+-- One row per reminder delivered (or being delivered). The unique key makes
+-- claiming a reminder atomic across job runs and replicas; start_time is part
+-- of the key so moving a reservation produces a fresh reminder.
+CREATE TABLE IF NOT EXISTS reservation_reminders (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    reservation_id  UUID         NOT NULL REFERENCES reservations(id) ON DELETE CASCADE,
+    recipient_id    UUID         NOT NULL REFERENCES users(id),
+    start_time      TIMESTAMPTZ  NOT NULL,
+    sent_at         TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    UNIQUE (reservation_id, recipient_id, start_time)
+);
+
+CREATE INDEX IF NOT EXISTS idx_reservations_confirmed_start ON reservations(start_time) WHERE status = 'confirmed';
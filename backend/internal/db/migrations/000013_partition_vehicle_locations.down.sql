@@ -0,0 +1,24 @@
+CREATE TABLE vehicle_locations_unpartitioned (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vehicle_id      UUID         NOT NULL REFERENCES vehicles(id),
+    location        GEOGRAPHY(POINT, 4326) NOT NULL,
+    heading         REAL,
+    speed           REAL,
+    accuracy        REAL,
+    recorded_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+INSERT INTO vehicle_locations_unpartitioned (id, vehicle_id, location, heading, speed, accuracy, recorded_at)
+SELECT id, vehicle_id, location, heading, speed, accuracy, recorded_at FROM vehicle_locations
+UNION ALL
+SELECT id, vehicle_id, location, heading, speed, accuracy, recorded_at FROM vehicle_locations_downsampled;
+
+DROP TABLE vehicle_locations_downsampled;
+DROP TABLE vehicle_locations;
+
+ALTER TABLE vehicle_locations_unpartitioned RENAME TO vehicle_locations;
+ALTER TABLE vehicle_locations RENAME CONSTRAINT vehicle_locations_unpartitioned_pkey TO vehicle_locations_pkey;
+
+CREATE INDEX idx_vehicle_locations_vehicle_id ON vehicle_locations(vehicle_id);
+CREATE INDEX idx_vehicle_locations_recorded_at ON vehicle_locations(recorded_at DESC);
+CREATE INDEX idx_vehicle_locations_geo ON vehicle_locations USING GIST(location);
@@ -0,0 +1,68 @@
+-- Move vehicle_locations to daily range partitions so retention is a
+-- partition drop, and add a per-minute downsampled tier for long-term replay.
+-- Partition bounds are UTC days; the application creates upcoming partitions
+-- and the default partition only catches points outside them.
+
+ALTER TABLE vehicle_locations RENAME TO vehicle_locations_legacy;
+ALTER TABLE vehicle_locations_legacy RENAME CONSTRAINT vehicle_locations_pkey TO vehicle_locations_legacy_pkey;
+DROP INDEX IF EXISTS idx_vehicle_locations_vehicle_id;
+DROP INDEX IF EXISTS idx_vehicle_locations_recorded_at;
+DROP INDEX IF EXISTS idx_vehicle_locations_geo;
+
+CREATE TABLE vehicle_locations (
+    id              UUID         NOT NULL DEFAULT gen_random_uuid(),
+    vehicle_id      UUID         NOT NULL REFERENCES vehicles(id),
+    location        GEOGRAPHY(POINT, 4326) NOT NULL,
+    heading         REAL,
+    speed           REAL,
+    accuracy        REAL,
+    recorded_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (id, recorded_at)
+) PARTITION BY RANGE (recorded_at);
+
+CREATE INDEX idx_vehicle_locations_vehicle_recorded ON vehicle_locations(vehicle_id, recorded_at);
+CREATE INDEX idx_vehicle_locations_recorded_at ON vehicle_locations(recorded_at DESC);
+CREATE INDEX idx_vehicle_locations_geo ON vehicle_locations USING GIST(location);
+
+CREATE TABLE vehicle_locations_default PARTITION OF vehicle_locations DEFAULT;
+
+-- One partition per day from the oldest existing point to a week ahead.
+DO $$
+DECLARE
+    d    DATE;
+    last DATE := (NOW() AT TIME ZONE 'UTC')::date + 7;
+BEGIN
+    SELECT COALESCE(MIN((recorded_at AT TIME ZONE 'UTC')::date), (NOW() AT TIME ZONE 'UTC')::date)
+    INTO d FROM vehicle_locations_legacy;
+
+    WHILE d <= last LOOP
+        EXECUTE format(
+            'CREATE TABLE IF NOT EXISTS %I PARTITION OF vehicle_locations FOR VALUES FROM (%L) TO (%L)',
+            'vehicle_locations_p' || to_char(d, 'YYYYMMDD'),
+            d::timestamp AT TIME ZONE 'UTC',
+            (d + 1)::timestamp AT TIME ZONE 'UTC');
+        d := d + 1;
+    END LOOP;
+END $$;
+
+INSERT INTO vehicle_locations (id, vehicle_id, location, heading, speed, accuracy, recorded_at)
+SELECT id, vehicle_id, location, heading, speed, accuracy, recorded_at
+FROM vehicle_locations_legacy;
+
+DROP TABLE vehicle_locations_legacy;
+
+-- Downsampled tier: at most one point per vehicle per minute, partitioned by
+-- month. Rows are moved here when their raw partition ages out.
+CREATE TABLE vehicle_locations_downsampled (
+    id              UUID         NOT NULL DEFAULT gen_random_uuid(),
+    vehicle_id      UUID         NOT NULL REFERENCES vehicles(id),
+    bucket          TIMESTAMPTZ  NOT NULL,
+    location        GEOGRAPHY(POINT, 4326) NOT NULL,
+    heading         REAL,
+    speed           REAL,
+    accuracy        REAL,
+    recorded_at     TIMESTAMPTZ  NOT NULL,
+    PRIMARY KEY (vehicle_id, bucket)
+) PARTITION BY RANGE (bucket);
+
+CREATE TABLE vehicle_locations_downsampled_default PARTITION OF vehicle_locations_downsampled DEFAULT;
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_dispatches_assigned_at;
+ALTER TABLE dispatches DROP COLUMN IF EXISTS declined_vehicle_ids;
@@ -0,0 +1,6 @@
+-- Vehicles whose driver declined (or ignored) a dispatch offer; excluded when
+-- the dispatch is offered again.
+ALTER TABLE dispatches ADD COLUMN IF NOT EXISTS declined_vehicle_ids UUID[] NOT NULL DEFAULT '{}';
+
+-- The acceptance-timeout job scans offers waiting on a driver.
+CREATE INDEX IF NOT EXISTS idx_dispatches_assigned_at ON dispatches(assigned_at) WHERE status = 'assigned';
@@ -0,0 +1,13 @@
+-- Average driving speeds learned from completed trips, per ~2 km grid cell
+-- (cell_lat/cell_lng are floor(coordinate / 0.02)) and hour of week
+-- (0 = Monday 00:00). Rebuilt wholesale by the eta.learn_speeds job.
+CREATE TABLE IF NOT EXISTS travel_speeds (
+    cell_lat      INTEGER          NOT NULL,
+    cell_lng      INTEGER          NOT NULL,
+    hour_of_week  SMALLINT         NOT NULL CHECK (hour_of_week BETWEEN 0 AND 167),
+    distance_m    DOUBLE PRECISION NOT NULL,
+    duration_sec  DOUBLE PRECISION NOT NULL,
+    samples       INTEGER          NOT NULL,
+    trained_at    TIMESTAMPTZ      NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (cell_lat, cell_lng, hour_of_week)
+);
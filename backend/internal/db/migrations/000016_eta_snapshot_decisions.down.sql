@@ -0,0 +1,7 @@
+ALTER TABLE dispatch_eta_snapshots
+    DROP COLUMN IF EXISTS chosen,
+    DROP COLUMN IF EXISTS vehicle_status,
+    DROP COLUMN IF EXISTS provider,
+    DROP COLUMN IF EXISTS actor_id,
+    DROP COLUMN IF EXISTS trigger,
+    DROP COLUMN IF EXISTS batch_id;
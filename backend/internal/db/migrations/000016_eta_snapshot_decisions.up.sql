@@ -0,0 +1,15 @@
+-- Each ETA calculation or assignment stores every candidate vehicle as one
+-- batch, so a dispatch decision can be reconstructed later: who looked at
+-- which cars, where they were, what each estimate was and which was picked.
+ALTER TABLE dispatch_eta_snapshots
+    ADD COLUMN IF NOT EXISTS batch_id       UUID,
+    ADD COLUMN IF NOT EXISTS trigger        VARCHAR(20) NOT NULL DEFAULT 'calculate',
+    ADD COLUMN IF NOT EXISTS actor_id       UUID REFERENCES users(id),
+    ADD COLUMN IF NOT EXISTS provider       VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS vehicle_status VARCHAR(20) NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS chosen         BOOLEAN NOT NULL DEFAULT false;
+
+-- Earlier rows were never written with real origins; mark them as their own
+-- batches so reads don't have to special-case NULL.
+UPDATE dispatch_eta_snapshots SET batch_id = id WHERE batch_id IS NULL;
+ALTER TABLE dispatch_eta_snapshots ALTER COLUMN batch_id SET NOT NULL;
@@ -0,0 +1 @@
+ALTER TABLE dispatches DROP COLUMN IF EXISTS version;
@@ -0,0 +1,4 @@
+-- Every status change is a conditional update on the status and version the
+-- caller read, so two concurrent changes cannot both win.
+ALTER TABLE dispatches
+    ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
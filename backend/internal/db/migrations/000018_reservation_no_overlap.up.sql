@@ -0,0 +1,32 @@
+-- A vehicle can be held by at most one reservation at a time. Reservations
+-- in pending_conflict may overlap (that is what the conflict workflow is
+-- for); confirmed and pending_driver ones may not, and the database now
+-- enforces it so concurrent bookings cannot both win the slot.
+CREATE EXTENSION IF NOT EXISTS btree_gist;
+
+-- Existing double bookings would block the constraint: the later-created
+-- reservation of each clashing pair goes to the conflict queue.
+WITH clashes AS (
+    SELECT DISTINCT ON (b.id) a.id AS winner, b.id AS loser
+    FROM reservations a
+    JOIN reservations b
+        ON b.vehicle_id = a.vehicle_id
+        AND (b.created_at, b.id) > (a.created_at, a.id)
+        AND tstzrange(b.start_time, b.end_time) && tstzrange(a.start_time, a.end_time)
+    WHERE a.status IN ('confirmed', 'pending_driver')
+        AND b.status IN ('confirmed', 'pending_driver')
+    ORDER BY b.id, a.created_at
+), demoted AS (
+    UPDATE reservations r
+    SET status = 'pending_conflict', updated_at = NOW()
+    FROM clashes c
+    WHERE r.id = c.loser
+    RETURNING r.id
+)
+INSERT INTO reservation_conflicts (winning_reservation_id, losing_reservation_id)
+SELECT c.winner, c.loser FROM clashes c JOIN demoted d ON d.id = c.loser;
+
+ALTER TABLE reservations
+    ADD CONSTRAINT reservations_no_overlap
+    EXCLUDE USING gist (vehicle_id WITH =, tstzrange(start_time, end_time) WITH &&)
+    WHERE (status IN ('confirmed', 'pending_driver'));
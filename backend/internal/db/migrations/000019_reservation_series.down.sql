@@ -0,0 +1,8 @@
+DROP INDEX IF EXISTS idx_reservations_series_occurrence;
+
+ALTER TABLE reservations
+    DROP COLUMN IF EXISTS is_exception,
+    DROP COLUMN IF EXISTS occurrence_start,
+    DROP COLUMN IF EXISTS series_id;
+
+DROP TABLE IF EXISTS reservation_series;
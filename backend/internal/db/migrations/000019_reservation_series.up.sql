@@ -0,0 +1,38 @@
+-- Recurring reservations. A series holds an RRULE and the template of its
+-- bookings; occurrences are materialized into reservations over a rolling
+-- horizon so they take part in overlap checks and conflicts like any other
+-- booking.
+CREATE TABLE reservation_series (
+    id                  UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vehicle_id          UUID         NOT NULL REFERENCES vehicles(id),
+    requester_id        UUID         NOT NULL REFERENCES users(id),
+    rrule               TEXT         NOT NULL,
+    dtstart             TIMESTAMPTZ  NOT NULL,
+    duration_sec        INTEGER      NOT NULL CHECK (duration_sec > 0),
+    timezone            TEXT         NOT NULL DEFAULT 'UTC',
+    purpose             TEXT         NOT NULL,
+    destinations        TEXT[]       NOT NULL DEFAULT '{}',
+    notes               TEXT,
+    priority_level      INTEGER      NOT NULL,
+    status              VARCHAR(20)  NOT NULL DEFAULT 'active'
+                        CHECK (status IN ('active','ended','cancelled')),
+    materialized_until  TIMESTAMPTZ,
+    cancel_reason       TEXT,
+    cancelled_by        UUID         REFERENCES users(id),
+    created_at          TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at          TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_reservation_series_active ON reservation_series(materialized_until) WHERE status = 'active';
+
+-- occurrence_start is the slot the rule generated; it stays put when the
+-- occurrence is moved, so the materializer never recreates an edited or
+-- cancelled occurrence. is_exception marks occurrences edited on their own,
+-- which series-wide edits leave alone.
+ALTER TABLE reservations
+    ADD COLUMN series_id        UUID        REFERENCES reservation_series(id),
+    ADD COLUMN occurrence_start TIMESTAMPTZ,
+    ADD COLUMN is_exception     BOOLEAN     NOT NULL DEFAULT false;
+
+CREATE UNIQUE INDEX idx_reservations_series_occurrence
+    ON reservations(series_id, occurrence_start) WHERE series_id IS NOT NULL;
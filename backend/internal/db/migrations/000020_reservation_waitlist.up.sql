@@ -0,0 +1,28 @@
+-- Requests for a slot no vehicle was free for. When a reservation releases
+-- its vehicle, waiting entries overlapping the freed time are retried in
+-- priority, then FIFO, order; an entry that is still waiting when its slot
+-- starts expires.
+CREATE TABLE reservation_waitlist (
+    id              UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    requester_id    UUID         NOT NULL REFERENCES users(id),
+    start_time      TIMESTAMPTZ  NOT NULL,
+    end_time        TIMESTAMPTZ  NOT NULL,
+    purpose         TEXT         NOT NULL,
+    destinations    TEXT[]       NOT NULL DEFAULT '{}',
+    notes           TEXT,
+    passenger_name  VARCHAR(200),
+    pickup_address  TEXT,
+    pickup_lat      DOUBLE PRECISION,
+    pickup_lng      DOUBLE PRECISION,
+    priority_level  INTEGER      NOT NULL,
+    status          VARCHAR(20)  NOT NULL DEFAULT 'waiting'
+                    CHECK (status IN ('waiting','booked','expired','cancelled')),
+    reservation_id  UUID         REFERENCES reservations(id),
+    created_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at      TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    CHECK (end_time > start_time)
+);
+
+CREATE INDEX idx_reservation_waitlist_waiting
+    ON reservation_waitlist(start_time, end_time) WHERE status = 'waiting';
+CREATE INDEX idx_reservation_waitlist_requester ON reservation_waitlist(requester_id, created_at DESC);
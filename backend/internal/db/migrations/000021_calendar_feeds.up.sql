@@ -0,0 +1,15 @@
+-- Private iCalendar feed URLs. The token in the URL is the only credential a
+-- calendar client sends, so a feed stays readable until it is revoked.
+-- subject_id is a vehicle for 'vehicle' feeds and a user otherwise.
+CREATE TABLE calendar_feeds (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    token         TEXT         NOT NULL UNIQUE,
+    subject_type  VARCHAR(20)  NOT NULL CHECK (subject_type IN ('vehicle','driver','requester')),
+    subject_id    UUID         NOT NULL,
+    created_by    UUID         NOT NULL REFERENCES users(id),
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    revoked_at    TIMESTAMPTZ
+);
+
+CREATE INDEX idx_calendar_feeds_created_by ON calendar_feeds(created_by) WHERE revoked_at IS NULL;
+
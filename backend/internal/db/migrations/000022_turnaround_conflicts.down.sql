@@ -0,0 +1,6 @@
+DROP INDEX IF EXISTS idx_conflicts_pair;
+ALTER TABLE reservation_conflicts
+    DROP COLUMN IF EXISTS available_gap_sec,
+    DROP COLUMN IF EXISTS required_gap_sec,
+    DROP COLUMN IF EXISTS kind;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS turnaround_min;
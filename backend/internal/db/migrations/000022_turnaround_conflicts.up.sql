@@ -0,0 +1,18 @@
+-- Minutes a vehicle needs between two bookings (refuel, clean, hand over).
+-- Reservations closer together than this, or than this plus the drive from
+-- the earlier trip's last stop to the next pickup, conflict even though their
+-- times do not overlap.
+ALTER TABLE vehicles
+    ADD COLUMN turnaround_min INTEGER NOT NULL DEFAULT 0
+        CHECK (turnaround_min BETWEEN 0 AND 240);
+
+-- What a conflict is about. For spacing conflicts the gap the two bookings
+-- need and the gap they have are kept so dispatchers can see by how much.
+ALTER TABLE reservation_conflicts
+    ADD COLUMN kind VARCHAR(20) NOT NULL DEFAULT 'overlap'
+        CHECK (kind IN ('overlap','turnaround','travel')),
+    ADD COLUMN required_gap_sec INTEGER,
+    ADD COLUMN available_gap_sec INTEGER;
+
+CREATE INDEX idx_conflicts_pair ON reservation_conflicts(winning_reservation_id, losing_reservation_id)
+    WHERE status = 'pending';
@@ -0,0 +1,2 @@
+DROP INDEX IF EXISTS idx_dispatches_reservation;
+ALTER TABLE dispatches DROP COLUMN IF EXISTS reservation_id;
@@ -0,0 +1,8 @@
+-- The trip a confirmed reservation turns into shortly before it starts, so
+-- the driver app can run it through the usual dispatch flow. A reservation
+-- gets at most one trip; the index also makes creating it idempotent.
+ALTER TABLE dispatches
+    ADD COLUMN reservation_id UUID REFERENCES reservations(id) ON DELETE SET NULL;
+
+CREATE UNIQUE INDEX idx_dispatches_reservation ON dispatches(reservation_id)
+    WHERE reservation_id IS NOT NULL;
@@ -0,0 +1,23 @@
+ALTER TABLE vehicles ADD COLUMN is_maintenance BOOLEAN NOT NULL DEFAULT false;
+
+UPDATE vehicles v SET is_maintenance = true
+WHERE EXISTS (
+    SELECT 1 FROM maintenance_windows mw
+    WHERE mw.vehicle_id = v.id AND mw.status = 'in_progress'
+);
+
+DELETE FROM reservation_conflicts WHERE kind = 'maintenance';
+
+DROP INDEX IF EXISTS idx_conflicts_maintenance;
+ALTER TABLE reservation_conflicts
+    DROP CONSTRAINT IF EXISTS reservation_conflicts_winner_check,
+    DROP COLUMN IF EXISTS maintenance_window_id,
+    DROP CONSTRAINT IF EXISTS reservation_conflicts_kind_check,
+    ADD CONSTRAINT reservation_conflicts_kind_check
+        CHECK (kind IN ('overlap','turnaround','travel')),
+    ALTER COLUMN winning_reservation_id SET NOT NULL;
+
+ALTER TABLE vehicles DROP COLUMN IF EXISTS odometer_km;
+
+DROP TABLE IF EXISTS maintenance_windows;
+DROP TABLE IF EXISTS maintenance_schedules;
@@ -0,0 +1,86 @@
+-- Planned maintenance replaces the is_maintenance flag. A window blocks its
+-- vehicle from start_time to end_time while planned, and for as long as the
+-- work is in progress, even past end_time.
+CREATE TABLE maintenance_windows (
+    id            UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vehicle_id    UUID         NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    schedule_id   UUID,
+    type          VARCHAR(20)  NOT NULL
+                  CHECK (type IN ('inspection','oil_change','tyres','brakes','repair','cleaning','other')),
+    status        VARCHAR(20)  NOT NULL DEFAULT 'planned'
+                  CHECK (status IN ('planned','in_progress','done','cancelled')),
+    start_time    TIMESTAMPTZ  NOT NULL,
+    end_time      TIMESTAMPTZ  NOT NULL,
+    vendor        VARCHAR(200),
+    notes         TEXT,
+    odometer_km   INTEGER CHECK (odometer_km >= 0),
+    created_by    UUID         NOT NULL REFERENCES users(id),
+    completed_at  TIMESTAMPTZ,
+    created_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at    TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    CHECK (end_time > start_time)
+);
+
+CREATE INDEX idx_maintenance_vehicle_time ON maintenance_windows(vehicle_id, start_time)
+    WHERE status IN ('planned','in_progress');
+
+-- Recurring maintenance, due every interval_days since it was last done, or
+-- every interval_km on the odometer, whichever comes first. A job plans a
+-- window of duration_min when one falls due.
+CREATE TABLE maintenance_schedules (
+    id             UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vehicle_id     UUID         NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    type           VARCHAR(20)  NOT NULL
+                   CHECK (type IN ('inspection','oil_change','tyres','brakes','repair','cleaning','other')),
+    vendor         VARCHAR(200),
+    notes          TEXT,
+    duration_min   INTEGER      NOT NULL CHECK (duration_min > 0),
+    interval_days  INTEGER CHECK (interval_days > 0),
+    interval_km    INTEGER CHECK (interval_km > 0),
+    last_done_at   TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    last_done_km   INTEGER CHECK (last_done_km >= 0),
+    created_by     UUID         NOT NULL REFERENCES users(id),
+    created_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at     TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    CHECK (interval_days IS NOT NULL OR interval_km IS NOT NULL)
+);
+
+CREATE INDEX idx_maintenance_schedules_vehicle ON maintenance_schedules(vehicle_id);
+
+ALTER TABLE maintenance_windows
+    ADD CONSTRAINT maintenance_windows_schedule_fk
+        FOREIGN KEY (schedule_id) REFERENCES maintenance_schedules(id) ON DELETE SET NULL;
+
+-- A schedule has at most one window planned or under way at a time, which
+-- also keeps the planning job from creating it twice.
+CREATE UNIQUE INDEX idx_maintenance_schedule_open ON maintenance_windows(schedule_id)
+    WHERE schedule_id IS NOT NULL AND status IN ('planned','in_progress');
+
+-- Last known odometer reading, for distance-based schedules.
+ALTER TABLE vehicles ADD COLUMN odometer_km INTEGER CHECK (odometer_km >= 0);
+
+-- Vehicles flagged today are in the workshop now; keep them there until
+-- someone completes the work.
+INSERT INTO maintenance_windows (vehicle_id, type, status, start_time, end_time, notes, created_by)
+SELECT v.id, 'other', 'in_progress', NOW(), NOW() + INTERVAL '1 day',
+    'Migrated from the maintenance flag',
+    COALESCE((SELECT id FROM users WHERE role = 'admin' ORDER BY created_at LIMIT 1), v.driver_id)
+FROM vehicles v
+WHERE v.is_maintenance;
+
+ALTER TABLE vehicles DROP COLUMN is_maintenance;
+
+-- Reservations overlapping a maintenance window conflict with the window
+-- rather than with another reservation.
+ALTER TABLE reservation_conflicts
+    ALTER COLUMN winning_reservation_id DROP NOT NULL,
+    ADD COLUMN maintenance_window_id UUID REFERENCES maintenance_windows(id) ON DELETE CASCADE,
+    DROP CONSTRAINT reservation_conflicts_kind_check,
+    ADD CONSTRAINT reservation_conflicts_kind_check
+        CHECK (kind IN ('overlap','turnaround','travel','maintenance')),
+    ADD CONSTRAINT reservation_conflicts_winner_check
+        CHECK ((kind = 'maintenance') = (maintenance_window_id IS NOT NULL)
+            AND (maintenance_window_id IS NULL) = (winning_reservation_id IS NOT NULL));
+
+CREATE INDEX idx_conflicts_maintenance ON reservation_conflicts(maintenance_window_id)
+    WHERE status = 'pending';
@@ -0,0 +1,8 @@
+DROP TABLE IF EXISTS fuel_logs;
+
+DROP INDEX IF EXISTS idx_attendance_vehicle_clock_in;
+ALTER TABLE driver_attendance
+    DROP CONSTRAINT IF EXISTS driver_attendance_odometer_check,
+    DROP COLUMN IF EXISTS clock_out_odometer_km,
+    DROP COLUMN IF EXISTS clock_in_odometer_km,
+    DROP COLUMN IF EXISTS vehicle_id;
@@ -0,0 +1,26 @@
+-- Odometer readings taken at clock-in and clock-out, on the vehicle the
+-- driver had at clock-in. A shift with both gives the distance driven.
+ALTER TABLE driver_attendance
+    ADD COLUMN vehicle_id             UUID REFERENCES vehicles(id) ON DELETE SET NULL,
+    ADD COLUMN clock_in_odometer_km   INTEGER CHECK (clock_in_odometer_km >= 0),
+    ADD COLUMN clock_out_odometer_km  INTEGER CHECK (clock_out_odometer_km >= 0),
+    ADD CONSTRAINT driver_attendance_odometer_check
+        CHECK (clock_out_odometer_km >= clock_in_odometer_km);
+
+CREATE INDEX idx_attendance_vehicle_clock_in ON driver_attendance(vehicle_id, clock_in_at)
+    WHERE vehicle_id IS NOT NULL;
+
+CREATE TABLE fuel_logs (
+    id           UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vehicle_id   UUID          NOT NULL REFERENCES vehicles(id) ON DELETE CASCADE,
+    driver_id    UUID          NOT NULL REFERENCES users(id),
+    litres       NUMERIC(7,2)  NOT NULL CHECK (litres > 0),
+    cost         NUMERIC(10,2) NOT NULL CHECK (cost >= 0),
+    station      VARCHAR(200),
+    odometer_km  INTEGER CHECK (odometer_km >= 0),
+    receipt_url  TEXT,
+    fueled_at    TIMESTAMPTZ   NOT NULL DEFAULT NOW(),
+    created_at   TIMESTAMPTZ   NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX idx_fuel_logs_vehicle_fueled ON fuel_logs(vehicle_id, fueled_at);
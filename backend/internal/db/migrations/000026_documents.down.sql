@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS document_expiry_alerts;
+DROP TABLE IF EXISTS documents;
@@ -0,0 +1,34 @@
+-- Registration, insurance and similar papers of a vehicle, or a driver's
+-- licence. A document is valid through its expiry date (UTC); renewing one
+-- means adding the new document, so the latest of each type is current.
+CREATE TABLE documents (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    vehicle_id  UUID REFERENCES vehicles(id) ON DELETE CASCADE,
+    user_id     UUID REFERENCES users(id) ON DELETE CASCADE,
+    type        VARCHAR(30)  NOT NULL
+        CHECK (type IN ('registration', 'insurance', 'emission_test', 'driver_licence', 'other')),
+    number      VARCHAR(100),
+    issued_on   DATE,
+    expires_on  DATE,
+    scan_file   TEXT,
+    notes       TEXT,
+    created_by  UUID         NOT NULL REFERENCES users(id),
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    CHECK ((vehicle_id IS NULL) <> (user_id IS NULL)),
+    CHECK (expires_on >= issued_on)
+);
+
+CREATE INDEX idx_documents_vehicle ON documents(vehicle_id, type) WHERE vehicle_id IS NOT NULL;
+CREATE INDEX idx_documents_user ON documents(user_id, type) WHERE user_id IS NOT NULL;
+CREATE INDEX idx_documents_expires ON documents(expires_on) WHERE expires_on IS NOT NULL;
+
+-- Expiry warnings already sent, claimed before sending so each goes out once
+-- per document, stage and expiry date.
+CREATE TABLE document_expiry_alerts (
+    document_id UUID        NOT NULL REFERENCES documents(id) ON DELETE CASCADE,
+    stage       VARCHAR(20) NOT NULL CHECK (stage IN ('expiring', 'expired')),
+    expires_on  DATE        NOT NULL,
+    sent_at     TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (document_id, stage, expires_on)
+);
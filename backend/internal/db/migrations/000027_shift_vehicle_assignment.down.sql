@@ -0,0 +1,13 @@
+-- Each vehicle goes back to the driver of its latest shift. Vehicles nobody
+-- ever drove have no driver to restore, so the column stays nullable.
+ALTER TABLE vehicles ADD COLUMN driver_id UUID REFERENCES users(id);
+
+UPDATE vehicles v
+SET driver_id = (
+    SELECT a.driver_id FROM driver_attendance a
+    WHERE a.vehicle_id = v.id
+    ORDER BY a.clock_in_at DESC LIMIT 1);
+
+CREATE INDEX idx_vehicles_driver_id ON vehicles(driver_id);
+
+DROP INDEX IF EXISTS uq_attendance_open_vehicle;
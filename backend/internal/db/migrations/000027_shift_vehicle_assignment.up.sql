@@ -0,0 +1,19 @@
+-- A vehicle is no longer owned by one driver. Each shift is the assignment:
+-- the driver checks the vehicle out at clock-in and returns it at clock-out,
+-- so whoever has the open shift on a vehicle holds it.
+
+-- Shifts from before 000025 recorded no vehicle; they were on the driver's
+-- own one. Where a driver has several open shifts only the latest keeps it,
+-- so that the index below holds.
+UPDATE driver_attendance a
+SET vehicle_id = (SELECT v.id FROM vehicles v WHERE v.driver_id = a.driver_id ORDER BY v.created_at LIMIT 1)
+WHERE a.vehicle_id IS NULL
+    AND (a.clock_out_at IS NOT NULL OR NOT EXISTS (
+        SELECT 1 FROM driver_attendance o
+        WHERE o.driver_id = a.driver_id AND o.clock_out_at IS NULL AND o.clock_in_at > a.clock_in_at));
+
+CREATE UNIQUE INDEX uq_attendance_open_vehicle ON driver_attendance(vehicle_id)
+    WHERE clock_out_at IS NULL AND vehicle_id IS NOT NULL;
+
+DROP INDEX IF EXISTS idx_vehicles_driver_id;
+ALTER TABLE vehicles DROP COLUMN driver_id;
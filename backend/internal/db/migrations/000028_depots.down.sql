@@ -0,0 +1,6 @@
+DROP TABLE IF EXISTS user_depots;
+
+DROP INDEX IF EXISTS idx_vehicles_depot_id;
+ALTER TABLE vehicles DROP COLUMN IF EXISTS depot_id;
+
+DROP TABLE IF EXISTS depots;
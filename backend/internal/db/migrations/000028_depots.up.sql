@@ -0,0 +1,33 @@
+-- Depots are the offices vehicles and drivers belong to. Dispatchers work
+-- the depots they are members of and see only those; a vehicle without a
+-- depot is shared by all.
+CREATE TABLE depots (
+    id          UUID PRIMARY KEY DEFAULT gen_random_uuid(),
+    name        VARCHAR(100) NOT NULL UNIQUE,
+    address     TEXT,
+    location    GEOGRAPHY(POINT, 4326),
+    created_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW(),
+    updated_at  TIMESTAMPTZ  NOT NULL DEFAULT NOW()
+);
+
+ALTER TABLE vehicles ADD COLUMN depot_id UUID REFERENCES depots(id) ON DELETE SET NULL;
+CREATE INDEX idx_vehicles_depot_id ON vehicles(depot_id);
+
+-- Depot membership of dispatchers (any number) and drivers
+CREATE TABLE user_depots (
+    user_id   UUID NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    depot_id  UUID NOT NULL REFERENCES depots(id) ON DELETE CASCADE,
+    PRIMARY KEY (user_id, depot_id)
+);
+
+CREATE INDEX idx_user_depots_depot_id ON user_depots(depot_id);
+
+-- The existing fleet starts as one depot, so dispatchers keep seeing all of
+-- it until admins split it up.
+INSERT INTO depots (name) VALUES ('Main');
+
+UPDATE vehicles SET depot_id = (SELECT id FROM depots WHERE name = 'Main');
+
+INSERT INTO user_depots (user_id, depot_id)
+SELECT u.id, d.id FROM users u, depots d
+WHERE d.name = 'Main' AND u.role IN ('dispatcher', 'driver');
@@ -0,0 +1,2 @@
+DELETE FROM audit_logs WHERE actor_id IS NULL;
+ALTER TABLE audit_logs ALTER COLUMN actor_id SET NOT NULL;
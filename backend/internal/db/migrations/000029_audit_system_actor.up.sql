@@ -0,0 +1,3 @@
+-- Work the system does on its own, such as expiring unanswered trip offers,
+-- is logged without an actor rather than in some user's name.
+ALTER TABLE audit_logs ALTER COLUMN actor_id DROP NOT NULL;
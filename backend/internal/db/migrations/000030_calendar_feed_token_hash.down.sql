@@ -0,0 +1,7 @@
+-- The tokens cannot be recovered from their hashes, so existing feeds are
+-- revoked and their subscribers have to create new ones.
+ALTER TABLE calendar_feeds ADD COLUMN token TEXT;
+UPDATE calendar_feeds SET token = token_hash, revoked_at = COALESCE(revoked_at, NOW());
+ALTER TABLE calendar_feeds ALTER COLUMN token SET NOT NULL;
+ALTER TABLE calendar_feeds ADD CONSTRAINT calendar_feeds_token_key UNIQUE (token);
+ALTER TABLE calendar_feeds DROP COLUMN token_hash;
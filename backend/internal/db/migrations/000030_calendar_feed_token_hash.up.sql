@@ -0,0 +1,7 @@
+-- Feed tokens are credentials; keep only their SHA-256 so a leaked dump or
+-- backup cannot be used to read anyone's schedule.
+ALTER TABLE calendar_feeds ADD COLUMN token_hash TEXT;
+UPDATE calendar_feeds SET token_hash = encode(sha256(convert_to(token, 'UTF8')), 'hex');
+ALTER TABLE calendar_feeds ALTER COLUMN token_hash SET NOT NULL;
+ALTER TABLE calendar_feeds ADD CONSTRAINT calendar_feeds_token_hash_key UNIQUE (token_hash);
+ALTER TABLE calendar_feeds DROP COLUMN token;
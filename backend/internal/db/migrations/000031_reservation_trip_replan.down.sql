@@ -0,0 +1,4 @@
+-- Fails while a reservation has a cancelled trip and a later one.
+DROP INDEX IF EXISTS idx_dispatches_reservation;
+CREATE UNIQUE INDEX idx_dispatches_reservation ON dispatches(reservation_id)
+    WHERE reservation_id IS NOT NULL;
@@ -0,0 +1,6 @@
+-- A trip cancelled because its reservation was moved or lost its slot no
+-- longer counts, so the reservation gets a fresh trip once it is confirmed
+-- and due again. Only one live trip per reservation is allowed.
+DROP INDEX IF EXISTS idx_dispatches_reservation;
+CREATE UNIQUE INDEX idx_dispatches_reservation ON dispatches(reservation_id)
+    WHERE reservation_id IS NOT NULL AND status <> 'cancelled';
@@ -0,0 +1 @@
+ALTER TABLE reservation_waitlist DROP COLUMN IF EXISTS depot_ids;
@@ -0,0 +1,4 @@
+-- The depot scope of whoever joined the waitlist, NULL for every depot. A
+-- freed slot only books the entry onto a vehicle within it, and dispatchers
+-- only see entries whose scope meets theirs.
+ALTER TABLE reservation_waitlist ADD COLUMN depot_ids UUID[];
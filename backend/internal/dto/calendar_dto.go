@@ -0,0 +1,17 @@
+package dto
+
+import "github.com/kento/driver/backend/internal/model"
+
+// CreateCalendarFeedRequest asks for a feed URL. SubjectID is a vehicle ID
+// for vehicle feeds and a user ID otherwise; empty means the caller's own.
+type CreateCalendarFeedRequest struct {
+	Type      model.CalendarFeedType `json:"type" validate:"required,oneof=vehicle driver requester"`
+	SubjectID string                 `json:"subject_id,omitempty"`
+}
+
+// CalendarFeedResponse is a feed with the URL to subscribe to. The URL is
+// only known, and only returned, when the feed is created.
+type CalendarFeedResponse struct {
+	model.CalendarFeed
+	URL string `json:"url,omitempty"`
+}
@@ -0,0 +1,27 @@
+package dto
+
+type CreateDepotRequest struct {
+	Name      string   `json:"name" validate:"required"`
+	Address   *string  `json:"address,omitempty"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+type UpdateDepotRequest struct {
+	Name      *string  `json:"name,omitempty"`
+	Address   *string  `json:"address,omitempty"`
+	Latitude  *float64 `json:"latitude,omitempty"`
+	Longitude *float64 `json:"longitude,omitempty"`
+}
+
+// UserDepots lists the depots a user is a member of. As a request body it
+// replaces them.
+type UserDepots struct {
+	DepotIDs []string `json:"depot_ids"`
+}
+
+// TransferVehicleRequest moves a vehicle to a depot; null shares it between
+// all depots.
+type TransferVehicleRequest struct {
+	DepotID *string `json:"depot_id"`
+}
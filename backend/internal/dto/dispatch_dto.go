@@ -30,9 +30,15 @@ type CancelDispatchRequest struct {
 	Reason string `json:"reason"`
 }
 
+type DriverTripDeclineRequest struct {
+	Reason string `json:"reason"`
+}
+
 type CalculateETARequest struct {
 	PickupLat float64 `json:"pickup_lat" validate:"required"`
 	PickupLng float64 `json:"pickup_lng" validate:"required"`
+	// DispatchID, when set, records the candidate list against that dispatch.
+	DispatchID *string `json:"dispatch_id,omitempty"`
 }
 
 type VehicleETA struct {
@@ -46,4 +52,28 @@ type VehicleETA struct {
 	DistanceM   int     `json:"distance_m"`
 	DurationSec int     `json:"duration_sec"`
 	IsAvailable bool    `json:"is_available"`
+	Provider    string  `json:"provider"`
+}
+
+// AutoDispatchCandidate is one ranked vehicle considered by the auto-dispatch
+// engine. Component scores are in [0,1]; Score is their weighted sum.
+type AutoDispatchCandidate struct {
+	VehicleID     string  `json:"vehicle_id"`
+	VehicleName   string  `json:"vehicle_name"`
+	DurationSec   int     `json:"duration_sec"`
+	DistanceM     int     `json:"distance_m"`
+	ShiftTrips    int     `json:"shift_trips"`
+	IdleSec       int     `json:"idle_sec"`
+	ETAScore      float64 `json:"eta_score"`
+	FairnessScore float64 `json:"fairness_score"`
+	IdleScore     float64 `json:"idle_score"`
+	Score         float64 `json:"score"`
+}
+
+type AutoDispatchResult struct {
+	Mode       string                  `json:"mode"` // "suggest" or "auto"
+	VehicleID  *string                 `json:"vehicle_id,omitempty"`
+	Assigned   bool                    `json:"assigned"`
+	Reason     string                  `json:"reason"`
+	Candidates []AutoDispatchCandidate `json:"candidates"`
 }
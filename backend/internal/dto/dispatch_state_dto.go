@@ -0,0 +1,21 @@
+package dto
+
+import "github.com/kento/driver/backend/internal/model"
+
+// DispatchStateMachine describes the dispatch lifecycle so clients can decide
+// which actions to offer without hard-coding the rules.
+type DispatchStateMachine struct {
+	Statuses    []model.DispatchStatus     `json:"statuses"`
+	Terminal    []model.DispatchStatus     `json:"terminal"`
+	Actors      []model.DispatchActor      `json:"actors"`
+	Transitions []model.DispatchTransition `json:"transitions"`
+}
+
+// InvalidTransitionDetails accompanies an INVALID_TRANSITION error. Allowed
+// lists the statuses the actor could move the dispatch to instead.
+type InvalidTransitionDetails struct {
+	From    model.DispatchStatus   `json:"from"`
+	To      model.DispatchStatus   `json:"to"`
+	Actor   model.DispatchActor    `json:"actor"`
+	Allowed []model.DispatchStatus `json:"allowed"`
+}
@@ -0,0 +1,20 @@
+package dto
+
+import "github.com/kento/driver/backend/internal/model"
+
+// CreateDocumentRequest adds a document. Dates are calendar days,
+// YYYY-MM-DD.
+type CreateDocumentRequest struct {
+	Type      model.DocumentType `json:"type" validate:"required"`
+	Number    *string            `json:"number,omitempty"`
+	IssuedOn  *string            `json:"issued_on,omitempty"`
+	ExpiresOn *string            `json:"expires_on,omitempty"`
+	Notes     *string            `json:"notes,omitempty"`
+}
+
+type UpdateDocumentRequest struct {
+	Number    *string `json:"number,omitempty"`
+	IssuedOn  *string `json:"issued_on,omitempty"`
+	ExpiresOn *string `json:"expires_on,omitempty"`
+	Notes     *string `json:"notes,omitempty"`
+}
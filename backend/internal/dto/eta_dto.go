@@ -0,0 +1,77 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/kento/driver/backend/internal/eta"
+	"github.com/kento/driver/backend/internal/model"
+)
+
+// ETAAccuracyReport compares the learned travel-time model with the flat
+// speed profile on recent completed trips. The model is retrained on trips
+// before the evaluation window so it is never scored on trips it learned from.
+type ETAAccuracyReport struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+	// TrainingLegs is how many legs the held-out model learned from.
+	TrainingLegs int `json:"training_legs"`
+	// Coverage is the fraction of evaluated legs the learned speeds could
+	// answer; the rest fall back to the flat profile, as they do live.
+	Coverage    float64      `json:"coverage"`
+	Learned     eta.Accuracy `json:"learned"`
+	Flat        eta.Accuracy `json:"flat"`
+	FlatProfile string       `json:"flat_profile"`
+	// Improvement is the relative reduction in mean absolute error over the
+	// flat profile; negative when the model does worse.
+	Improvement   float64    `json:"improvement"`
+	LiveCells     int        `json:"live_cells"`
+	LiveTrainedAt *time.Time `json:"live_trained_at,omitempty"`
+}
+
+// ETADecision is one recorded batch of candidate estimates for a dispatch:
+// what a dispatcher (or the auto-dispatcher) saw when calculating ETAs or
+// assigning a vehicle.
+type ETADecision struct {
+	BatchID      string    `json:"batch_id"`
+	Trigger      string    `json:"trigger"`
+	ActorID      *string   `json:"actor_id,omitempty"`
+	ActorName    *string   `json:"actor_name,omitempty"`
+	CalculatedAt time.Time `json:"calculated_at"`
+	// ChosenVehicleID is the vehicle assigned or suggested; ChosenRank is its
+	// 1-based position by ETA among the candidates.
+	ChosenVehicleID  *string                     `json:"chosen_vehicle_id,omitempty"`
+	ChosenRank       int                         `json:"chosen_rank,omitempty"`
+	FastestVehicleID string                      `json:"fastest_vehicle_id"`
+	Candidates       []model.DispatchETASnapshot `json:"candidates"`
+}
+
+// PickupComparison sets the ETA the assigned vehicle was given at assignment
+// against when it actually reached the pickup. Error fields are positive
+// when the vehicle was late.
+type PickupComparison struct {
+	BatchID              string     `json:"batch_id"`
+	VehicleID            string     `json:"vehicle_id"`
+	Provider             string     `json:"provider"`
+	CalculatedAt         time.Time  `json:"calculated_at"`
+	PredictedDurationSec int        `json:"predicted_duration_sec"`
+	PredictedPickupAt    time.Time  `json:"predicted_pickup_at"`
+	ActualPickupAt       *time.Time `json:"actual_pickup_at,omitempty"`
+	ErrorSec             *int       `json:"error_sec,omitempty"`
+	// ActualDriveSec excludes the time before the driver set off, isolating
+	// routing error from acceptance delay.
+	ActualDriveSec *int `json:"actual_drive_sec,omitempty"`
+	DriveErrorSec  *int `json:"drive_error_sec,omitempty"`
+}
+
+// DispatchETAReview reconstructs the ETA decisions made for a dispatch, for
+// post-incident review.
+type DispatchETAReview struct {
+	DispatchID string            `json:"dispatch_id"`
+	Status     string            `json:"status"`
+	VehicleID  *string           `json:"vehicle_id,omitempty"`
+	AssignedAt *time.Time        `json:"assigned_at,omitempty"`
+	EnRouteAt  *time.Time        `json:"en_route_at,omitempty"`
+	ArrivedAt  *time.Time        `json:"arrived_at,omitempty"`
+	Pickup     *PickupComparison `json:"pickup,omitempty"`
+	Decisions  []ETADecision     `json:"decisions"`
+}
@@ -0,0 +1,45 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/kento/driver/backend/internal/model"
+)
+
+type CreateMaintenanceWindowRequest struct {
+	Type      model.MaintenanceType `json:"type" validate:"required"`
+	StartTime time.Time             `json:"start_time" validate:"required"`
+	EndTime   time.Time             `json:"end_time" validate:"required"`
+	Vendor    *string               `json:"vendor,omitempty"`
+	Notes     *string               `json:"notes,omitempty"`
+}
+
+type UpdateMaintenanceWindowRequest struct {
+	Type      *model.MaintenanceType `json:"type,omitempty"`
+	StartTime *time.Time             `json:"start_time,omitempty"`
+	EndTime   *time.Time             `json:"end_time,omitempty"`
+	Vendor    *string                `json:"vendor,omitempty"`
+	Notes     *string                `json:"notes,omitempty"`
+}
+
+type CompleteMaintenanceRequest struct {
+	OdometerKm *int `json:"odometer_km,omitempty"`
+}
+
+type CancelMaintenanceRequest struct {
+	Reason string `json:"reason"`
+}
+
+// CreateMaintenanceScheduleRequest sets up recurring maintenance. Intervals
+// count from LastDoneAt (default now) and LastDoneKm (default the vehicle's
+// odometer reading).
+type CreateMaintenanceScheduleRequest struct {
+	Type         model.MaintenanceType `json:"type" validate:"required"`
+	DurationMin  int                   `json:"duration_min" validate:"required"`
+	IntervalDays *int                  `json:"interval_days,omitempty"`
+	IntervalKm   *int                  `json:"interval_km,omitempty"`
+	LastDoneAt   *time.Time            `json:"last_done_at,omitempty"`
+	LastDoneKm   *int                  `json:"last_done_km,omitempty"`
+	Vendor       *string               `json:"vendor,omitempty"`
+	Notes        *string               `json:"notes,omitempty"`
+}
@@ -0,0 +1,52 @@
+package dto
+
+import "time"
+
+// ClockInRequest is the optional body of clock-in. VehicleID is the vehicle
+// to check out for the shift; without it the driver takes that of their last
+// shift, if free.
+type ClockInRequest struct {
+	VehicleID  *string `json:"vehicle_id,omitempty"`
+	OdometerKm *int    `json:"odometer_km,omitempty"`
+}
+
+// OdometerRequest is the optional body of clock-out.
+type OdometerRequest struct {
+	OdometerKm *int `json:"odometer_km,omitempty"`
+}
+
+type CreateFuelLogRequest struct {
+	Litres     float64    `json:"litres" validate:"required"`
+	Cost       float64    `json:"cost"`
+	Station    *string    `json:"station,omitempty"`
+	OdometerKm *int       `json:"odometer_km,omitempty"`
+	FueledAt   *time.Time `json:"fueled_at,omitempty"`
+}
+
+// VehicleMileage reports a vehicle's distance and refuelling over [From, To).
+// OdometerKm is the latest reading on record; the distances are what the
+// odometer and GPS track show for the period.
+type VehicleMileage struct {
+	VehicleID          string         `json:"vehicle_id"`
+	From               time.Time      `json:"from"`
+	To                 time.Time      `json:"to"`
+	OdometerKm         *int           `json:"odometer_km,omitempty"`
+	DistanceOdometerKm float64        `json:"distance_odometer_km"`
+	DistanceGPSKm      float64        `json:"distance_gps_km"`
+	FuelLitres         float64        `json:"fuel_litres"`
+	FuelCost           float64        `json:"fuel_cost"`
+	KmPerLitre         *float64       `json:"km_per_litre"`
+	Days               []DailyMileage `json:"days"`
+	Anomalies          []DailyMileage `json:"anomalies"`
+}
+
+// DailyMileage is one UTC day of a VehicleMileage. OdometerKm is nil when no
+// shift that day has both readings.
+type DailyMileage struct {
+	Date       string   `json:"date"`
+	OdometerKm *float64 `json:"odometer_km"`
+	GPSKm      float64  `json:"gps_km"`
+	FuelLitres float64  `json:"fuel_litres"`
+	FuelCost   float64  `json:"fuel_cost"`
+	Anomaly    bool     `json:"anomaly"`
+}
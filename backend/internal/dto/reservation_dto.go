@@ -1,6 +1,10 @@
 package dto
 
-import "time"
+import (
+	"time"
+
+	"github.com/kento/driver/backend/internal/model"
+)
 
 type CreateReservationRequest struct {
 	VehicleID    string    `json:"vehicle_id" validate:"required,uuid"`
@@ -48,6 +52,33 @@ type ForceAssignRequest struct {
 	Reason string `json:"reason" validate:"required"`
 }
 
+// ConflictSuggestions lists ways to settle a conflict by moving its losing
+// reservation: to another vehicle for the same slot, or to a nearby slot on
+// the same vehicle. Both come best first.
+type ConflictSuggestions struct {
+	ConflictID    string              `json:"conflict_id"`
+	ReservationID string              `json:"reservation_id"`
+	Vehicles      []VehicleSuggestion `json:"vehicles"`
+	Slots         []SlotSuggestion    `json:"slots"`
+}
+
+// VehicleSuggestion is a vehicle free for the slot. SlackSec is the spare
+// time to its nearest booking either side after turnaround, capped at a day.
+type VehicleSuggestion struct {
+	VehicleID    string `json:"vehicle_id"`
+	VehicleName  string `json:"vehicle_name"`
+	LicensePlate string `json:"license_plate"`
+	SlackSec     int    `json:"slack_sec"`
+}
+
+// SlotSuggestion is a free slot of the same length. ShiftSec is how far it
+// moves the reservation, negative when earlier.
+type SlotSuggestion struct {
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	ShiftSec  int       `json:"shift_sec"`
+}
+
 // Unified booking flow DTOs
 
 type UnifiedBookingRequest struct {
@@ -63,14 +94,47 @@ type UnifiedBookingRequest struct {
 	Destinations  []string   `json:"destinations,omitempty"`
 	PassengerName *string    `json:"passenger_name,omitempty"`
 	Notes         *string    `json:"notes,omitempty"`
+	Waitlist      bool       `json:"waitlist,omitempty"` // mode=any: queue the request when no vehicle is free
 }
 
 type UnifiedBookingResponse struct {
-	Type        string      `json:"type"` // "dispatch" or "reservation"
-	Dispatch    interface{} `json:"dispatch,omitempty"`
-	Reservation interface{} `json:"reservation,omitempty"`
+	Type         string              `json:"type"` // "dispatch", "reservation" or "waitlist"
+	Dispatch     interface{}         `json:"dispatch,omitempty"`
+	Reservation  interface{}         `json:"reservation,omitempty"`
+	Waitlist     interface{}         `json:"waitlist,omitempty"`
+	AutoDispatch *AutoDispatchResult `json:"auto_dispatch,omitempty"`
 }
 
 type DriverReservationDeclineRequest struct {
 	Reason string `json:"reason"`
 }
+
+// Recurring reservation DTOs
+
+// CreateReservationSeriesRequest defines a recurring booking. StartTime and
+// EndTime are the first occurrence; every occurrence keeps its wall-clock
+// start and length in Timezone (IANA name, default UTC).
+type CreateReservationSeriesRequest struct {
+	VehicleID    string    `json:"vehicle_id" validate:"required,uuid"`
+	StartTime    time.Time `json:"start_time" validate:"required"`
+	EndTime      time.Time `json:"end_time" validate:"required"`
+	RRule        string    `json:"rrule" validate:"required"` // e.g. FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR
+	Timezone     string    `json:"timezone,omitempty"`
+	Purpose      string    `json:"purpose" validate:"required"`
+	Destinations []string  `json:"destinations,omitempty"`
+	Notes        *string   `json:"notes,omitempty"`
+}
+
+// UpdateReservationSeriesRequest changes every upcoming occurrence not
+// edited on its own.
+type UpdateReservationSeriesRequest struct {
+	VehicleID    *string  `json:"vehicle_id,omitempty"`
+	Purpose      *string  `json:"purpose,omitempty"`
+	Destinations []string `json:"destinations,omitempty"`
+	Notes        *string  `json:"notes,omitempty"`
+}
+
+type ReservationSeriesDetail struct {
+	model.ReservationSeries
+	Occurrences []model.Reservation `json:"occurrences"`
+}
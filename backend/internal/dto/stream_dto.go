@@ -0,0 +1,30 @@
+package dto
+
+import "github.com/kento/driver/backend/internal/model"
+
+// FleetSnapshot is the first event sent on a fleet stream connection.
+type FleetSnapshot struct {
+	Vehicles   []model.VehicleWithStatus `json:"vehicles"`
+	Dispatches []model.Dispatch          `json:"dispatches"`
+}
+
+// VehicleStatusChange is the payload of a vehicle.status stream event.
+type VehicleStatusChange struct {
+	PreviousStatus model.VehicleStatus     `json:"previous_status"`
+	Vehicle        model.VehicleWithStatus `json:"vehicle"`
+}
+
+// RideUpdate is the payload of passenger ride stream events. Fields are only
+// set when they are part of the change being reported.
+type RideUpdate struct {
+	Dispatch *model.Dispatch      `json:"dispatch,omitempty"`
+	Location *model.LocationPoint `json:"location,omitempty"`
+	ETA      *RideETA             `json:"eta,omitempty"`
+}
+
+// RideETA estimates time to the ride's next stop ("pickup" or "dropoff").
+type RideETA struct {
+	Target      string `json:"target"`
+	DurationSec int    `json:"duration_sec"`
+	DistanceM   int    `json:"distance_m"`
+}
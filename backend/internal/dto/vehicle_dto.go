@@ -1,17 +1,54 @@
 package dto
 
-type ToggleMaintenanceRequest struct {
-	IsMaintenance bool `json:"is_maintenance"`
+import (
+	"time"
+
+	"github.com/kento/driver/backend/internal/model"
+)
+
+type SetTurnaroundRequest struct {
+	TurnaroundMin int `json:"turnaround_min"`
 }
 
 type CreateVehicleRequest struct {
-	Name         string `json:"name"`
-	LicensePlate string `json:"license_plate"`
-	DriverID     string `json:"driver_id"`
+	Name         string  `json:"name"`
+	LicensePlate string  `json:"license_plate"`
+	DepotID      *string `json:"depot_id,omitempty"`
 }
 
 type UpdateVehicleRequest struct {
 	Name         string `json:"name"`
 	LicensePlate string `json:"license_plate"`
-	DriverID     string `json:"driver_id"`
+}
+
+// FleetAvailability is the free/busy grid of every vehicle over a window.
+// Interval bounds are snapped outwards to the granularity.
+type FleetAvailability struct {
+	From        time.Time             `json:"from"`
+	To          time.Time             `json:"to"`
+	Granularity string                `json:"granularity"`
+	Vehicles    []VehicleAvailability `json:"vehicles"`
+}
+
+type VehicleAvailability struct {
+	VehicleID    string         `json:"vehicle_id"`
+	VehicleName  string         `json:"vehicle_name"`
+	LicensePlate string         `json:"license_plate"`
+	DriverName   string         `json:"driver_name"`
+	Busy         []BusyInterval `json:"busy"`
+	Free         []TimeInterval `json:"free"`
+}
+
+type TimeInterval struct {
+	Start time.Time `json:"start"`
+	End   time.Time `json:"end"`
+}
+
+// BusyInterval is time a vehicle cannot take new work. RefID and Status
+// identify the reservation or dispatch holding it.
+type BusyInterval struct {
+	TimeInterval
+	Kind   model.AvailabilityKind `json:"kind"`
+	RefID  *string                `json:"ref_id,omitempty"`
+	Status *string                `json:"status,omitempty"`
 }
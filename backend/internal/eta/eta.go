@@ -0,0 +1,103 @@
+// Package eta estimates driving times from vehicles to a destination.
+//
+// A Provider answers many-origins-to-one-destination queries, the shape of
+// every dispatch question ("how far is each car from this pickup?"). Routing
+// backends are chained so that a slow or failing backend falls through to the
+// next one, ending at a straight-line estimate that needs no network.
+package eta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+type Point struct {
+	Lat float64
+	Lng float64
+}
+
+// Estimate is the travel estimate for one origin. Provider names the backend
+// that produced it; it is empty when that backend could not route the origin.
+type Estimate struct {
+	DurationSec int
+	DistanceM   int
+	Provider    string
+}
+
+// OK reports whether the estimate holds a result.
+func (e Estimate) OK() bool {
+	return e.Provider != ""
+}
+
+// Provider estimates travel from each origin to dest. The result has one
+// entry per origin, in order; origins the provider cannot route are left as
+// zero Estimates.
+type Provider interface {
+	Name() string
+	Estimate(ctx context.Context, origins []Point, dest Point) ([]Estimate, error)
+}
+
+// Chain asks providers in order, each under its own timeout, and passes only
+// the origins still unanswered on to the next provider.
+type Chain struct {
+	providers []Provider
+	timeout   time.Duration
+}
+
+func NewChain(timeout time.Duration, providers ...Provider) *Chain {
+	return &Chain{providers: providers, timeout: timeout}
+}
+
+func (c *Chain) Name() string {
+	return "chain"
+}
+
+func (c *Chain) Estimate(ctx context.Context, origins []Point, dest Point) ([]Estimate, error) {
+	results := make([]Estimate, len(origins))
+	pending := make([]int, len(origins))
+	for i := range pending {
+		pending[i] = i
+	}
+
+	var errs []error
+	for _, p := range c.providers {
+		if len(pending) == 0 {
+			break
+		}
+		batch := make([]Point, len(pending))
+		for i, idx := range pending {
+			batch[i] = origins[idx]
+		}
+
+		pctx, cancel := context.WithTimeout(ctx, c.timeout)
+		got, err := p.Estimate(pctx, batch, dest)
+		cancel()
+		if err == nil && len(got) != len(batch) {
+			err = fmt.Errorf("returned %d estimates for %d origins", len(got), len(batch))
+		}
+		if err != nil {
+			log.Printf("[eta] provider=%s origins=%d error: %v", p.Name(), len(batch), err)
+			errs = append(errs, fmt.Errorf("%s: %w", p.Name(), err))
+			continue
+		}
+
+		var still []int
+		for i, idx := range pending {
+			if got[i].OK() {
+				results[idx] = got[i]
+			} else {
+				still = append(still, idx)
+			}
+		}
+		pending = still
+	}
+
+	if len(pending) > 0 {
+		errs = append(errs, fmt.Errorf("%d of %d origins unrouted", len(pending), len(origins)))
+		return results, errors.Join(errs...)
+	}
+	return results, nil
+}
@@ -0,0 +1,236 @@
+package eta
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kento/driver/backend/internal/maps"
+)
+
+type fakeProvider struct {
+	name  string
+	err   error
+	delay time.Duration
+	// routes reports which origins (by latitude) this provider can route
+	routes func(p Point) bool
+	calls  [][]Point
+}
+
+func (f *fakeProvider) Name() string { return f.name }
+
+func (f *fakeProvider) Estimate(ctx context.Context, origins []Point, dest Point) ([]Estimate, error) {
+	f.calls = append(f.calls, origins)
+	if f.delay > 0 {
+		select {
+		case <-time.After(f.delay):
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	out := make([]Estimate, len(origins))
+	for i, o := range origins {
+		if f.routes == nil || f.routes(o) {
+			out[i] = Estimate{DurationSec: 100 * (i + 1), Provider: f.name}
+		}
+	}
+	return out, nil
+}
+
+func TestChain_FallsThroughOnError(t *testing.T) {
+	primary := &fakeProvider{name: "primary", err: errors.New("quota exceeded")}
+	fallback := &fakeProvider{name: "fallback"}
+	c := NewChain(time.Second, primary, fallback)
+
+	got, err := c.Estimate(context.Background(), []Point{{Lat: 1}, {Lat: 2}}, Point{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for i, e := range got {
+		if e.Provider != "fallback" {
+			t.Errorf("estimate %d provider = %q, want fallback", i, e.Provider)
+		}
+	}
+}
+
+func TestChain_TimeoutFallsThrough(t *testing.T) {
+	slow := &fakeProvider{name: "slow", delay: time.Second}
+	fallback := &fakeProvider{name: "fallback"}
+	c := NewChain(20*time.Millisecond, slow, fallback)
+
+	start := time.Now()
+	got, err := c.Estimate(context.Background(), []Point{{Lat: 1}}, Point{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Errorf("chain took %v, want the slow provider cut off", elapsed)
+	}
+	if got[0].Provider != "fallback" {
+		t.Errorf("provider = %q, want fallback", got[0].Provider)
+	}
+}
+
+func TestChain_OnlyUnroutedOriginsFallThrough(t *testing.T) {
+	primary := &fakeProvider{name: "primary", routes: func(p Point) bool { return p.Lat != 2 }}
+	fallback := &fakeProvider{name: "fallback"}
+	c := NewChain(time.Second, primary, fallback)
+
+	got, err := c.Estimate(context.Background(), []Point{{Lat: 1}, {Lat: 2}, {Lat: 3}}, Point{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []string{"primary", "fallback", "primary"}
+	for i, e := range got {
+		if e.Provider != want[i] {
+			t.Errorf("estimate %d provider = %q, want %q", i, e.Provider, want[i])
+		}
+	}
+	if len(fallback.calls) != 1 || len(fallback.calls[0]) != 1 || fallback.calls[0][0].Lat != 2 {
+		t.Errorf("fallback asked for %v, want only the unrouted origin", fallback.calls)
+	}
+}
+
+func TestChain_AllFail(t *testing.T) {
+	c := NewChain(time.Second, &fakeProvider{name: "a", err: errors.New("down")})
+
+	_, err := c.Estimate(context.Background(), []Point{{Lat: 1}}, Point{})
+	if err == nil {
+		t.Fatal("expected error when no provider can route")
+	}
+	if !strings.Contains(err.Error(), "a: down") {
+		t.Errorf("error %q should name the failing provider", err)
+	}
+}
+
+func TestParseSpeedProfile(t *testing.T) {
+	p, err := ParseSpeedProfile("18, 7-9:12, 17:10")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	tests := []struct {
+		hour int
+		want float64
+	}{
+		{6, 18}, {7, 12}, {9, 12}, {10, 18}, {17, 10}, {18, 18},
+	}
+	for _, tt := range tests {
+		at := time.Date(2026, 1, 5, tt.hour, 30, 0, 0, time.Local)
+		if got := p.At(at); got != tt.want {
+			t.Errorf("At(%02d:30) = %v, want %v", tt.hour, got, tt.want)
+		}
+	}
+}
+
+func TestParseSpeedProfile_Invalid(t *testing.T) {
+	for _, s := range []string{"", "fast", "0", "18,7-9", "18,9-7:12", "18,7-24:12", "18,7-9:-1"} {
+		if _, err := ParseSpeedProfile(s); err == nil {
+			t.Errorf("ParseSpeedProfile(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestHaversine_Estimate(t *testing.T) {
+	h := NewHaversine(SpeedProfile{DefaultKmh: 36}) // 10 m/s
+	// ~1.11 km per 0.01 degree of latitude
+	got, _ := h.Estimate(context.Background(), []Point{{Lat: 14.56, Lng: 121.02}, {Lat: 14.55, Lng: 121.02}}, Point{Lat: 14.55, Lng: 121.02})
+
+	if got[0].DistanceM < 1100 || got[0].DistanceM > 1125 {
+		t.Errorf("distance = %d, want ~1112", got[0].DistanceM)
+	}
+	if got[0].DurationSec < 110 || got[0].DurationSec > 113 {
+		t.Errorf("duration = %d, want ~111", got[0].DurationSec)
+	}
+	if got[1].DurationSec != MinDurationSec {
+		t.Errorf("zero-distance duration = %d, want %d", got[1].DurationSec, MinDurationSec)
+	}
+}
+
+type fakeMatrix struct {
+	batches [][]maps.LatLng
+}
+
+func (f *fakeMatrix) ComputeRouteMatrix(ctx context.Context, origins []maps.LatLng, destination maps.LatLng) ([]maps.MatrixElement, error) {
+	f.batches = append(f.batches, origins)
+	var out []maps.MatrixElement
+	for i, o := range origins {
+		// Origins at latitude 0 are unroutable
+		out = append(out, maps.MatrixElement{OriginIndex: i, DurationSec: int(o.Lat), DistanceMeters: 10, OK: o.Lat != 0})
+	}
+	return out, nil
+}
+
+func TestGoogle_BatchesOrigins(t *testing.T) {
+	client := &fakeMatrix{}
+	g := NewGoogle(client)
+
+	origins := make([]Point, maps.MaxMatrixOrigins+5)
+	for i := range origins {
+		origins[i] = Point{Lat: float64(100 + i)}
+	}
+	origins[3].Lat = 0
+
+	got, err := g.Estimate(context.Background(), origins, Point{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(client.batches) != 2 || len(client.batches[1]) != 5 {
+		t.Fatalf("batches = %d (last %d), want 2 with 5 in the last", len(client.batches), len(client.batches[len(client.batches)-1]))
+	}
+	if got[3].OK() {
+		t.Errorf("unroutable origin reported as %+v", got[3])
+	}
+	if last := got[len(got)-1]; last.DurationSec != 100+len(origins)-1 || last.Provider != "google" {
+		t.Errorf("last estimate = %+v, want duration %d from google", last, 100+len(origins)-1)
+	}
+}
+
+func TestOSRM_Estimate(t *testing.T) {
+	var gotPath, gotQuery string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath, gotQuery = r.URL.Path, r.URL.RawQuery
+		fmt.Fprint(w, `{"code":"Ok","durations":[[300.4],[null]],"distances":[[2500.2],[null]]}`)
+	}))
+	defer srv.Close()
+
+	o := NewOSRM(srv.URL + "/")
+	got, err := o.Estimate(context.Background(),
+		[]Point{{Lat: 14.5, Lng: 121.0}, {Lat: 14.6, Lng: 121.1}}, Point{Lat: 14.55, Lng: 121.05})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if want := "/table/v1/driving/121.000000,14.500000;121.100000,14.600000;121.050000,14.550000"; gotPath != want {
+		t.Errorf("path = %q, want %q", gotPath, want)
+	}
+	if !strings.Contains(gotQuery, "sources=0;1") || !strings.Contains(gotQuery, "destinations=2") {
+		t.Errorf("query = %q, want sources=0;1 and destinations=2", gotQuery)
+	}
+	if got[0].DurationSec != 300 || got[0].DistanceM != 2500 || got[0].Provider != "osrm" {
+		t.Errorf("estimate 0 = %+v, want 300s/2500m from osrm", got[0])
+	}
+	if got[1].OK() {
+		t.Errorf("unroutable origin reported as %+v", got[1])
+	}
+}
+
+func TestOSRM_Error(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprint(w, `{"code":"InvalidQuery","message":"bad coordinates"}`)
+	}))
+	defer srv.Close()
+
+	_, err := NewOSRM(srv.URL).Estimate(context.Background(), []Point{{Lat: 1}}, Point{})
+	if err == nil || !strings.Contains(err.Error(), "InvalidQuery") {
+		t.Errorf("err = %v, want InvalidQuery", err)
+	}
+}
@@ -0,0 +1,57 @@
+package eta
+
+import (
+	"context"
+
+	"github.com/kento/driver/backend/internal/maps"
+)
+
+type matrixClient interface {
+	ComputeRouteMatrix(ctx context.Context, origins []maps.LatLng, destination maps.LatLng) ([]maps.MatrixElement, error)
+}
+
+// Google estimates traffic-aware driving times with the Routes API route
+// matrix, batching origins to stay within request limits.
+type Google struct {
+	client matrixClient
+}
+
+func NewGoogle(client matrixClient) *Google {
+	return &Google{client: client}
+}
+
+func (g *Google) Name() string {
+	return "google"
+}
+
+func (g *Google) Estimate(ctx context.Context, origins []Point, dest Point) ([]Estimate, error) {
+	results := make([]Estimate, len(origins))
+	to := maps.LatLng{Lat: dest.Lat, Lng: dest.Lng}
+
+	for start := 0; start < len(origins); start += maps.MaxMatrixOrigins {
+		end := start + maps.MaxMatrixOrigins
+		if end > len(origins) {
+			end = len(origins)
+		}
+		batch := make([]maps.LatLng, 0, end-start)
+		for _, o := range origins[start:end] {
+			batch = append(batch, maps.LatLng{Lat: o.Lat, Lng: o.Lng})
+		}
+
+		elements, err := g.client.ComputeRouteMatrix(ctx, batch, to)
+		if err != nil {
+			return nil, err
+		}
+		for _, e := range elements {
+			if !e.OK || e.OriginIndex < 0 || e.OriginIndex >= len(batch) {
+				continue
+			}
+			dur := e.DurationSec
+			if dur < MinDurationSec {
+				dur = MinDurationSec
+			}
+			results[start+e.OriginIndex] = Estimate{DurationSec: dur, DistanceM: e.DistanceMeters, Provider: g.Name()}
+		}
+	}
+	return results, nil
+}
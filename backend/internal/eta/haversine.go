@@ -0,0 +1,114 @@
+package eta
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// MinDurationSec is the shortest estimate ever reported; nothing in city
+// traffic is less than a minute away.
+const MinDurationSec = 60
+
+// SpeedProfile is the average driving speed assumed for straight-line
+// estimates, optionally overridden for specific hours of the day.
+type SpeedProfile struct {
+	DefaultKmh float64
+	HourlyKmh  map[int]float64 // hour of day (0-23, server local time)
+}
+
+// ParseSpeedProfile reads a profile such as "18" or "18,7-9:12,17-19:10":
+// a default speed in km/h followed by optional hour ranges (inclusive) with
+// their own speed.
+func ParseSpeedProfile(s string) (SpeedProfile, error) {
+	parts := strings.Split(s, ",")
+	def, err := strconv.ParseFloat(strings.TrimSpace(parts[0]), 64)
+	if err != nil || def <= 0 {
+		return SpeedProfile{}, fmt.Errorf("invalid default speed %q", parts[0])
+	}
+
+	p := SpeedProfile{DefaultKmh: def}
+	for _, part := range parts[1:] {
+		hours, speed, ok := strings.Cut(strings.TrimSpace(part), ":")
+		if !ok {
+			return SpeedProfile{}, fmt.Errorf("invalid hour range %q: want FROM-TO:KMH", part)
+		}
+		kmh, err := strconv.ParseFloat(speed, 64)
+		if err != nil || kmh <= 0 {
+			return SpeedProfile{}, fmt.Errorf("invalid speed in %q", part)
+		}
+		fromStr, toStr, ok := strings.Cut(hours, "-")
+		if !ok {
+			toStr = fromStr
+		}
+		from, err1 := strconv.Atoi(fromStr)
+		to, err2 := strconv.Atoi(toStr)
+		if err1 != nil || err2 != nil || from < 0 || to > 23 || from > to {
+			return SpeedProfile{}, fmt.Errorf("invalid hours in %q", part)
+		}
+		if p.HourlyKmh == nil {
+			p.HourlyKmh = make(map[int]float64)
+		}
+		for h := from; h <= to; h++ {
+			p.HourlyKmh[h] = kmh
+		}
+	}
+	return p, nil
+}
+
+// At returns the speed in km/h to assume at time t.
+func (p SpeedProfile) At(t time.Time) float64 {
+	if kmh, ok := p.HourlyKmh[t.Hour()]; ok {
+		return kmh
+	}
+	return p.DefaultKmh
+}
+
+// Duration is the time in seconds to cover distM at the speed for time at,
+// never less than MinDurationSec.
+func (p SpeedProfile) Duration(distM float64, at time.Time) int {
+	dur := int(math.Round(distM / (p.At(at) * 1000 / 3600)))
+	if dur < MinDurationSec {
+		dur = MinDurationSec
+	}
+	return dur
+}
+
+// Haversine estimates straight-line distance at the profile speed. It never
+// fails, which makes it the last provider of every chain.
+type Haversine struct {
+	profile SpeedProfile
+	now     func() time.Time
+}
+
+func NewHaversine(profile SpeedProfile) *Haversine {
+	return &Haversine{profile: profile, now: time.Now}
+}
+
+func (h *Haversine) Name() string {
+	return "haversine"
+}
+
+func (h *Haversine) Estimate(ctx context.Context, origins []Point, dest Point) ([]Estimate, error) {
+	now := h.now()
+	results := make([]Estimate, len(origins))
+	for i, o := range origins {
+		distM := Distance(o, dest)
+		results[i] = Estimate{DurationSec: h.profile.Duration(distM, now), DistanceM: int(math.Round(distM)), Provider: h.Name()}
+	}
+	return results, nil
+}
+
+// Distance returns the great-circle distance between a and b in meters.
+func Distance(a, b Point) float64 {
+	const R = 6371000 // Earth radius in meters
+	dLat := (b.Lat - a.Lat) * math.Pi / 180
+	dLng := (b.Lng - a.Lng) * math.Pi / 180
+	x := math.Sin(dLat/2)*math.Sin(dLat/2) +
+		math.Cos(a.Lat*math.Pi/180)*math.Cos(b.Lat*math.Pi/180)*
+			math.Sin(dLng/2)*math.Sin(dLng/2)
+	return R * 2 * math.Atan2(math.Sqrt(x), math.Sqrt(1-x))
+}
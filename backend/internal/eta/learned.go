@@ -0,0 +1,265 @@
+package eta
+
+import (
+	"context"
+	"math"
+	"sync/atomic"
+	"time"
+)
+
+// CellSizeDeg is the side of the grid cells speeds are learned for, about
+// 2 km near the equator.
+const CellSizeDeg = 0.02
+
+// MinSamples is how many trips a cell/hour (or hour) needs before its average
+// is trusted.
+const MinSamples = 3
+
+// Learned trips outside these bounds are GPS glitches or trips that were
+// left open, not traffic.
+const (
+	minLegDistanceM = 200
+	minLegSec       = 60
+	maxLegSec       = 3 * 3600
+	minLegKmh       = 1
+	maxLegKmh       = 120
+)
+
+// Cell identifies a grid cell by its integer coordinates.
+type Cell struct {
+	Lat int
+	Lng int
+}
+
+// CellOf returns the grid cell containing p.
+func CellOf(p Point) Cell {
+	return Cell{Lat: int(math.Floor(p.Lat / CellSizeDeg)), Lng: int(math.Floor(p.Lng / CellSizeDeg))}
+}
+
+// HourOfWeek numbers the hours of the week from Monday 00:00 (0) to Sunday
+// 23:00 (167), in t's location.
+func HourOfWeek(t time.Time) int {
+	day := (int(t.Weekday()) + 6) % 7 // Monday = 0
+	return day*24 + t.Hour()
+}
+
+// Leg is one observed drive: from a point to another, starting at StartedAt
+// and taking DurationSec.
+type Leg struct {
+	From        Point
+	To          Point
+	StartedAt   time.Time
+	DurationSec int
+}
+
+// usable reports whether the leg looks like real driving.
+func (l Leg) usable() bool {
+	if l.DurationSec < minLegSec || l.DurationSec > maxLegSec {
+		return false
+	}
+	d := Distance(l.From, l.To)
+	if d < minLegDistanceM {
+		return false
+	}
+	kmh := d / float64(l.DurationSec) * 3.6
+	return kmh >= minLegKmh && kmh <= maxLegKmh
+}
+
+// SpeedStat aggregates the legs observed in one cell during one hour of the
+// week. Speeds are straight-line distance over time, so they already account
+// for road detours and can be applied directly to haversine distances.
+type SpeedStat struct {
+	Cell        Cell
+	HourOfWeek  int
+	DistanceM   float64
+	DurationSec float64
+	Samples     int
+}
+
+// Kmh is the average speed of the aggregated legs.
+func (s SpeedStat) Kmh() float64 {
+	if s.DurationSec <= 0 {
+		return 0
+	}
+	return s.DistanceM / s.DurationSec * 3.6
+}
+
+func (s *SpeedStat) add(o SpeedStat) {
+	s.DistanceM += o.DistanceM
+	s.DurationSec += o.DurationSec
+	s.Samples += o.Samples
+}
+
+type cellHour struct {
+	cell Cell
+	how  int
+}
+
+// SpeedTable holds learned speeds by cell and hour of week, with a fleet-wide
+// average per hour of week for cells without enough trips.
+type SpeedTable struct {
+	cells map[cellHour]SpeedStat
+	hours [168]SpeedStat
+}
+
+// NewSpeedTable builds a table from stored aggregates.
+func NewSpeedTable(stats []SpeedStat) *SpeedTable {
+	t := &SpeedTable{cells: make(map[cellHour]SpeedStat, len(stats))}
+	for _, s := range stats {
+		if s.HourOfWeek < 0 || s.HourOfWeek >= 168 {
+			continue
+		}
+		k := cellHour{s.Cell, s.HourOfWeek}
+		agg := t.cells[k]
+		agg.Cell, agg.HourOfWeek = s.Cell, s.HourOfWeek
+		agg.add(s)
+		t.cells[k] = agg
+		t.hours[s.HourOfWeek].add(s)
+	}
+	return t
+}
+
+// BuildSpeedTable learns a table from observed legs, attributing each leg to
+// the cell of its midpoint and the hour of week it started in. Implausible
+// legs are ignored.
+func BuildSpeedTable(legs []Leg) *SpeedTable {
+	stats := make([]SpeedStat, 0, len(legs))
+	for _, l := range legs {
+		if !l.usable() {
+			continue
+		}
+		stats = append(stats, SpeedStat{
+			Cell:        CellOf(midpoint(l.From, l.To)),
+			HourOfWeek:  HourOfWeek(l.StartedAt),
+			DistanceM:   Distance(l.From, l.To),
+			DurationSec: float64(l.DurationSec),
+			Samples:     1,
+		})
+	}
+	return NewSpeedTable(stats)
+}
+
+// Stats returns the per-cell aggregates, for storage.
+func (t *SpeedTable) Stats() []SpeedStat {
+	out := make([]SpeedStat, 0, len(t.cells))
+	for _, s := range t.cells {
+		out = append(out, s)
+	}
+	return out
+}
+
+// Len is the number of cell/hour aggregates in the table.
+func (t *SpeedTable) Len() int {
+	return len(t.cells)
+}
+
+// Speed returns the learned speed in km/h for a drive through the cell of p
+// at time at: the cell's own average if it has enough trips, otherwise the
+// fleet-wide average for that hour of the week. ok is false when neither is
+// known.
+func (t *SpeedTable) Speed(p Point, at time.Time) (kmh float64, ok bool) {
+	how := HourOfWeek(at)
+	if s, found := t.cells[cellHour{CellOf(p), how}]; found && s.Samples >= MinSamples {
+		return s.Kmh(), true
+	}
+	if s := t.hours[how]; s.Samples >= MinSamples {
+		return s.Kmh(), true
+	}
+	return 0, false
+}
+
+// Predict estimates a drive from one point to another starting at at.
+func (t *SpeedTable) Predict(from, to Point, at time.Time) (Estimate, bool) {
+	kmh, ok := t.Speed(midpoint(from, to), at)
+	if !ok || kmh <= 0 {
+		return Estimate{}, false
+	}
+	d := Distance(from, to)
+	dur := int(math.Round(d / (kmh / 3.6)))
+	if dur < MinDurationSec {
+		dur = MinDurationSec
+	}
+	return Estimate{DurationSec: dur, DistanceM: int(math.Round(d)), Provider: "learned"}, true
+}
+
+func midpoint(a, b Point) Point {
+	return Point{Lat: (a.Lat + b.Lat) / 2, Lng: (a.Lng + b.Lng) / 2}
+}
+
+// Learned estimates with speeds learned from the fleet's own completed trips.
+// Origins in cells and hours it has no data for are left unrouted so the
+// chain falls through. The table is swapped in atomically as it is retrained.
+type Learned struct {
+	table atomic.Pointer[SpeedTable]
+	now   func() time.Time
+}
+
+func NewLearned() *Learned {
+	l := &Learned{now: time.Now}
+	l.table.Store(NewSpeedTable(nil))
+	return l
+}
+
+// SetTable replaces the speeds used for estimates.
+func (l *Learned) SetTable(t *SpeedTable) {
+	l.table.Store(t)
+}
+
+// Table returns the speeds currently in use.
+func (l *Learned) Table() *SpeedTable {
+	return l.table.Load()
+}
+
+func (l *Learned) Name() string {
+	return "learned"
+}
+
+func (l *Learned) Estimate(ctx context.Context, origins []Point, dest Point) ([]Estimate, error) {
+	t, now := l.Table(), l.now()
+	results := make([]Estimate, len(origins))
+	for i, o := range origins {
+		if e, ok := t.Predict(o, dest, now); ok {
+			results[i] = e
+		}
+	}
+	return results, nil
+}
+
+// Accuracy summarises how far predicted durations were from actual ones.
+type Accuracy struct {
+	Samples int `json:"samples"`
+	// MAESec is the mean absolute error in seconds.
+	MAESec float64 `json:"mae_sec"`
+	// MAPE is the mean absolute error as a fraction of the actual duration.
+	MAPE float64 `json:"mape"`
+	// BiasSec is the mean signed error; positive means predictions run long.
+	BiasSec float64 `json:"bias_sec"`
+}
+
+// Evaluate scores predict against the actual durations of the usable legs.
+// Legs predict cannot answer are skipped.
+func Evaluate(legs []Leg, predict func(Leg) (int, bool)) Accuracy {
+	var a Accuracy
+	var absSum, pctSum, biasSum float64
+	for _, l := range legs {
+		if !l.usable() {
+			continue
+		}
+		pred, ok := predict(l)
+		if !ok {
+			continue
+		}
+		diff := float64(pred - l.DurationSec)
+		absSum += math.Abs(diff)
+		pctSum += math.Abs(diff) / float64(l.DurationSec)
+		biasSum += diff
+		a.Samples++
+	}
+	if a.Samples > 0 {
+		n := float64(a.Samples)
+		a.MAESec = math.Round(absSum/n*10) / 10
+		a.MAPE = math.Round(pctSum/n*1000) / 1000
+		a.BiasSec = math.Round(biasSum/n*10) / 10
+	}
+	return a
+}
@@ -0,0 +1,123 @@
+package eta
+
+import (
+	"context"
+	"testing"
+	"time"
+)
+
+// monday8 is a Monday 08:15, hour of week 8.
+var monday8 = time.Date(2026, 3, 2, 8, 15, 0, 0, time.UTC)
+
+// legAt builds a leg of ~1112 m (0.01 degrees of latitude) near Makati
+// driven in durationSec.
+func legAt(start time.Time, durationSec int) Leg {
+	return Leg{
+		From:        Point{Lat: 14.550, Lng: 121.025},
+		To:          Point{Lat: 14.560, Lng: 121.025},
+		StartedAt:   start,
+		DurationSec: durationSec,
+	}
+}
+
+func TestHourOfWeek(t *testing.T) {
+	if got := HourOfWeek(monday8); got != 8 {
+		t.Errorf("Monday 08:15 = %d, want 8", got)
+	}
+	sunday := time.Date(2026, 3, 8, 23, 59, 0, 0, time.UTC)
+	if got := HourOfWeek(sunday); got != 167 {
+		t.Errorf("Sunday 23:59 = %d, want 167", got)
+	}
+}
+
+func TestSpeedTable_CellThenHourFallback(t *testing.T) {
+	var legs []Leg
+	for i := 0; i < MinSamples; i++ {
+		legs = append(legs, legAt(monday8, 400)) // ~10 km/h
+	}
+	// A single fast leg elsewhere in the same hour: not enough for its own
+	// cell, but it feeds the hourly average.
+	far := legAt(monday8, 100)
+	far.From.Lat, far.To.Lat = 14.650, 14.660
+	legs = append(legs, far)
+	tbl := BuildSpeedTable(legs)
+
+	kmh, ok := tbl.Speed(Point{Lat: 14.555, Lng: 121.025}, monday8)
+	if !ok || kmh < 9.5 || kmh > 10.5 {
+		t.Errorf("cell speed = %v/%v, want ~10 km/h", kmh, ok)
+	}
+
+	// Unseen cell falls back to the hour's fleet-wide average (4 legs, 1300 s).
+	kmh, ok = tbl.Speed(Point{Lat: 15.0, Lng: 121.0}, monday8)
+	if !ok || kmh < 12 || kmh > 12.7 {
+		t.Errorf("hourly fallback = %v/%v, want ~12.3 km/h", kmh, ok)
+	}
+
+	// Unseen hour has nothing to offer.
+	if _, ok := tbl.Speed(Point{Lat: 14.555, Lng: 121.025}, monday8.Add(2*time.Hour)); ok {
+		t.Error("expected no speed for an hour without trips")
+	}
+}
+
+func TestSpeedTable_IgnoresImplausibleLegs(t *testing.T) {
+	legs := []Leg{
+		legAt(monday8, 10),    // too short
+		legAt(monday8, 20000), // left open
+		legAt(monday8, 30),    // 133 km/h through the city
+	}
+	if n := BuildSpeedTable(legs).Len(); n != 0 {
+		t.Errorf("table has %d aggregates, want 0", n)
+	}
+}
+
+func TestSpeedTable_RoundTripsThroughStats(t *testing.T) {
+	var legs []Leg
+	for i := 0; i < MinSamples; i++ {
+		legs = append(legs, legAt(monday8, 400))
+	}
+	restored := NewSpeedTable(BuildSpeedTable(legs).Stats())
+
+	kmh, ok := restored.Speed(Point{Lat: 14.555, Lng: 121.025}, monday8)
+	if !ok || kmh < 9.5 || kmh > 10.5 {
+		t.Errorf("restored speed = %v/%v, want ~10 km/h", kmh, ok)
+	}
+}
+
+func TestLearned_LeavesUnknownOriginsUnrouted(t *testing.T) {
+	var legs []Leg
+	for i := 0; i < MinSamples; i++ {
+		legs = append(legs, legAt(monday8, 400))
+	}
+	l := NewLearned()
+	l.SetTable(BuildSpeedTable(legs))
+
+	l.now = func() time.Time { return monday8 }
+	got, _ := l.Estimate(context.Background(), []Point{{Lat: 14.550, Lng: 121.025}}, Point{Lat: 14.560, Lng: 121.025})
+	if !got[0].OK() || got[0].Provider != "learned" || got[0].DurationSec < 390 || got[0].DurationSec > 410 {
+		t.Errorf("estimate = %+v, want ~400s from learned", got[0])
+	}
+
+	l.now = func() time.Time { return monday8.Add(5 * time.Hour) }
+	got, _ = l.Estimate(context.Background(), []Point{{Lat: 14.550, Lng: 121.025}}, Point{Lat: 14.560, Lng: 121.025})
+	if got[0].OK() {
+		t.Errorf("estimate for an unlearned hour = %+v, want unrouted", got[0])
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	legs := []Leg{legAt(monday8, 400), legAt(monday8, 200), legAt(monday8, 5)}
+	acc := Evaluate(legs, func(l Leg) (int, bool) { return 300, true })
+
+	if acc.Samples != 2 {
+		t.Fatalf("samples = %d, want 2 (implausible leg skipped)", acc.Samples)
+	}
+	if acc.MAESec != 100 {
+		t.Errorf("MAE = %v, want 100", acc.MAESec)
+	}
+	if acc.BiasSec != 0 {
+		t.Errorf("bias = %v, want 0", acc.BiasSec)
+	}
+	if acc.MAPE != 0.375 { // (100/400 + 100/200) / 2
+		t.Errorf("MAPE = %v, want 0.375", acc.MAPE)
+	}
+}
@@ -0,0 +1,100 @@
+package eta
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// OSRM queries the table service of an OSRM-compatible routing server
+// (OSRM itself, or any stand-in that serves /table/v1/driving).
+type OSRM struct {
+	baseURL    string
+	httpClient *http.Client
+}
+
+func NewOSRM(baseURL string) *OSRM {
+	return &OSRM{
+		baseURL:    strings.TrimRight(baseURL, "/"),
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (o *OSRM) Name() string {
+	return "osrm"
+}
+
+type osrmTableResponse struct {
+	Code      string       `json:"code"`
+	Message   string       `json:"message"`
+	Durations [][]*float64 `json:"durations"`
+	Distances [][]*float64 `json:"distances"`
+}
+
+func (o *OSRM) Estimate(ctx context.Context, origins []Point, dest Point) ([]Estimate, error) {
+	if len(origins) == 0 {
+		return nil, nil
+	}
+
+	// Coordinates are lng,lat; the destination goes last.
+	coords := make([]string, 0, len(origins)+1)
+	sources := make([]string, 0, len(origins))
+	for i, p := range origins {
+		coords = append(coords, formatCoord(p))
+		sources = append(sources, strconv.Itoa(i))
+	}
+	coords = append(coords, formatCoord(dest))
+
+	url := fmt.Sprintf("%s/table/v1/driving/%s?sources=%s&destinations=%d&annotations=duration,distance",
+		o.baseURL, strings.Join(coords, ";"), strings.Join(sources, ";"), len(origins))
+
+	req, err := http.NewRequestWithContext(ctx, "GET", url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+	resp, err := o.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("osrm request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+	var table osrmTableResponse
+	if err := json.Unmarshal(body, &table); err != nil {
+		return nil, fmt.Errorf("osrm response (status %d): %w", resp.StatusCode, err)
+	}
+	if resp.StatusCode != http.StatusOK || table.Code != "Ok" {
+		return nil, fmt.Errorf("osrm error (status %d): %s %s", resp.StatusCode, table.Code, table.Message)
+	}
+	if len(table.Durations) != len(origins) {
+		return nil, fmt.Errorf("osrm returned %d rows for %d origins", len(table.Durations), len(origins))
+	}
+
+	results := make([]Estimate, len(origins))
+	for i, row := range table.Durations {
+		// null means no route between the pair
+		if len(row) == 0 || row[0] == nil {
+			continue
+		}
+		dur := int(math.Round(*row[0]))
+		if dur < MinDurationSec {
+			dur = MinDurationSec
+		}
+		e := Estimate{DurationSec: dur, Provider: o.Name()}
+		if i < len(table.Distances) && len(table.Distances[i]) > 0 && table.Distances[i][0] != nil {
+			e.DistanceM = int(math.Round(*table.Distances[i][0]))
+		}
+		results[i] = e
+	}
+	return results, nil
+}
+
+func formatCoord(p Point) string {
+	return strconv.FormatFloat(p.Lng, 'f', 6, 64) + "," + strconv.FormatFloat(p.Lat, 'f', 6, 64)
+}
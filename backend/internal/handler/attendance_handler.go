@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/kento/driver/backend/internal/dto"
 	"github.com/kento/driver/backend/internal/middleware"
 	"github.com/kento/driver/backend/internal/model"
 	"github.com/kento/driver/backend/pkg/apperror"
@@ -20,7 +22,16 @@ func NewAttendanceHandler(attendanceSvc attendanceService) *AttendanceHandler {
 func (h *AttendanceHandler) ClockIn(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetClaims(r.Context())
 
-	attendance, err := h.attendanceSvc.ClockIn(r.Context(), claims.UserID)
+	// The body is optional
+	var req dto.ClockInRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperror.WriteError(w, apperror.ErrBadRequest)
+			return
+		}
+	}
+
+	attendance, err := h.attendanceSvc.ClockIn(r.Context(), claims.UserID, req.VehicleID, req.OdometerKm)
 	if err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
@@ -36,7 +47,16 @@ func (h *AttendanceHandler) ClockIn(w http.ResponseWriter, r *http.Request) {
 func (h *AttendanceHandler) ClockOut(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetClaims(r.Context())
 
-	if err := h.attendanceSvc.ClockOut(r.Context(), claims.UserID); err != nil {
+	// The body is optional
+	var req dto.OdometerRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperror.WriteError(w, apperror.ErrBadRequest)
+			return
+		}
+	}
+
+	if err := h.attendanceSvc.ClockOut(r.Context(), claims.UserID, req.OdometerKm); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -104,3 +124,24 @@ func (h *AttendanceHandler) GetHistory(w http.ResponseWriter, r *http.Request) {
 
 	apperror.WriteSuccess(w, records)
 }
+
+// ListAssignments lists who had the vehicle checked out on their shifts,
+// latest first.
+func (h *AttendanceHandler) ListAssignments(w http.ResponseWriter, r *http.Request) {
+	vehicleID := chi.URLParam(r, "id")
+	limit, ok := parseIntParam(w, r, "limit", 50)
+	if !ok {
+		return
+	}
+	if limit <= 0 || limit > 200 {
+		limit = 50
+	}
+
+	assignments, err := h.attendanceSvc.ListAssignments(r.Context(), vehicleID, limit)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, assignments)
+}
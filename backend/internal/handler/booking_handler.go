@@ -44,7 +44,7 @@ func (h *BookingHandler) CreateBooking(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	resp, err := h.bookingSvc.CreateBooking(r.Context(), req, claims.UserID, user.PriorityLevel)
+	resp, err := h.bookingSvc.CreateBooking(r.Context(), req, claims.UserID, user.PriorityLevel, middleware.GetDepotIDs(r.Context()))
 	if err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
@@ -120,6 +120,28 @@ func (h *BookingHandler) DeclineReservation(w http.ResponseWriter, r *http.Reque
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *BookingHandler) DeclineTrip(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.DriverTripDeclineRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	if err := h.bookingSvc.DriverDeclineDispatch(r.Context(), id, claims.UserID, req.Reason); err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
 func (h *BookingHandler) PendingReservations(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetClaims(r.Context())
 
@@ -0,0 +1,71 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+func TestBooking_DeclineTrip_Success(t *testing.T) {
+	var gotID, gotDriver, gotReason string
+	svc := &mockBookingSvc{
+		driverDeclineTripFn: func(ctx context.Context, dispatchID, driverID, reason string) error {
+			gotID, gotDriver, gotReason = dispatchID, driverID, reason
+			return nil
+		},
+	}
+	h := NewBookingHandler(svc, &mockAuthSvc{})
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"reason":"flat tire"}`))
+	req = withChiParam(req, "id", "d1")
+	req = withClaims(req, "driver1", "emp1", "driver")
+	rec := httptest.NewRecorder()
+
+	h.DeclineTrip(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if gotID != "d1" || gotDriver != "driver1" || gotReason != "flat tire" {
+		t.Errorf("DriverDeclineDispatch(%q, %q, %q), want (d1, driver1, flat tire)", gotID, gotDriver, gotReason)
+	}
+}
+
+func TestBooking_DeclineTrip_InvalidBody(t *testing.T) {
+	h := NewBookingHandler(&mockBookingSvc{}, &mockAuthSvc{})
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`not json`))
+	req = withChiParam(req, "id", "d1")
+	req = withClaims(req, "driver1", "emp1", "driver")
+	rec := httptest.NewRecorder()
+
+	h.DeclineTrip(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestBooking_DeclineTrip_NotYourVehicle(t *testing.T) {
+	svc := &mockBookingSvc{
+		driverDeclineTripFn: func(ctx context.Context, dispatchID, driverID, reason string) error {
+			return apperror.New(403, "NOT_YOUR_VEHICLE", "this trip is not assigned to your vehicle")
+		},
+	}
+	h := NewBookingHandler(svc, &mockAuthSvc{})
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{}`))
+	req = withChiParam(req, "id", "d1")
+	req = withClaims(req, "driver2", "emp2", "driver")
+	rec := httptest.NewRecorder()
+
+	h.DeclineTrip(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if code := decodeError(t, rec); code != "NOT_YOUR_VEHICLE" {
+		t.Errorf("error code = %q, want NOT_YOUR_VEHICLE", code)
+	}
+}
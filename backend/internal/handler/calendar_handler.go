@@ -0,0 +1,119 @@
+package handler
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/middleware"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+type CalendarHandler struct {
+	calendarSvc calendarService
+}
+
+func NewCalendarHandler(calendarSvc calendarService) *CalendarHandler {
+	return &CalendarHandler{calendarSvc: calendarSvc}
+}
+
+func (h *CalendarHandler) CreateFeed(w http.ResponseWriter, r *http.Request) {
+	var req dto.CreateCalendarFeedRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+	if req.Type == "" {
+		apperror.WriteErrorMsg(w, 400, "VALIDATION_ERROR", "type is required")
+		return
+	}
+
+	feed, err := h.calendarSvc.CreateFeed(r.Context(), subjectOf(r), req.Type, req.SubjectID)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteCreated(w, feedResponse(r, feed))
+}
+
+// ListFeeds returns the caller's active feeds.
+func (h *CalendarHandler) ListFeeds(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r.Context())
+
+	feeds, err := h.calendarSvc.ListFeeds(r.Context(), claims.UserID)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	resp := make([]dto.CalendarFeedResponse, len(feeds))
+	for i := range feeds {
+		resp[i] = feedResponse(r, &feeds[i])
+	}
+	apperror.WriteSuccess(w, resp)
+}
+
+func (h *CalendarHandler) RevokeFeed(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if err := h.calendarSvc.RevokeFeed(r.Context(), subjectOf(r), id); err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// Feed serves the iCalendar document behind a feed token. It is public: the
+// token in the URL is the credential, since calendar clients cannot log in.
+func (h *CalendarHandler) Feed(w http.ResponseWriter, r *http.Request) {
+	token := chi.URLParam(r, "token")
+
+	cal, err := h.calendarSvc.Feed(r.Context(), token)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	if cal == nil {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Cache-Control", "private, max-age=300")
+	if err := cal.Write(w); err != nil {
+		log.Printf("[calendar] write feed: %v", err)
+	}
+}
+
+// feedResponse adds the subscription URL, built from the host the request
+// came in on. Stored feeds no longer carry their token and get no URL.
+func feedResponse(r *http.Request, feed *model.CalendarFeed) dto.CalendarFeedResponse {
+	if feed.Token == "" {
+		return dto.CalendarFeedResponse{CalendarFeed: *feed}
+	}
+	scheme := "http"
+	if r.TLS != nil || r.Header.Get("X-Forwarded-Proto") == "https" {
+		scheme = "https"
+	}
+	return dto.CalendarFeedResponse{
+		CalendarFeed: *feed,
+		URL:          scheme + "://" + r.Host + "/api/v1/calendar/" + feed.Token + ".ics",
+	}
+}
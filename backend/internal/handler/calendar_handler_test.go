@@ -0,0 +1,101 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kento/driver/backend/internal/ical"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/policy"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+func TestCalendar_CreateFeed_ReturnsURL(t *testing.T) {
+	var gotSub policy.Subject
+	var gotType model.CalendarFeedType
+	svc := &mockCalendarSvc{
+		createFeedFn: func(_ context.Context, sub policy.Subject, feedType model.CalendarFeedType, _ string) (*model.CalendarFeed, error) {
+			gotSub, gotType = sub, feedType
+			return &model.CalendarFeed{ID: "f-1", Token: "tok123", SubjectType: feedType}, nil
+		},
+	}
+	h := NewCalendarHandler(svc)
+	req := httptest.NewRequest("POST", "http://fleet.example.com/api/v1/calendar-feeds", strings.NewReader(`{"type":"driver"}`))
+	req = withClaims(req, "drv-1", "D1", "driver")
+	rec := httptest.NewRecorder()
+
+	h.CreateFeed(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if gotSub.UserID != "drv-1" || gotSub.Role != model.RoleDriver || gotType != model.CalendarFeedDriver {
+		t.Errorf("CreateFeed(%+v, %q)", gotSub, gotType)
+	}
+	if !strings.Contains(rec.Body.String(), `"url":"http://fleet.example.com/api/v1/calendar/tok123.ics"`) {
+		t.Errorf("body lacks feed url: %s", rec.Body.String())
+	}
+}
+
+func TestCalendar_CreateFeed_Forbidden(t *testing.T) {
+	svc := &mockCalendarSvc{
+		createFeedFn: func(context.Context, policy.Subject, model.CalendarFeedType, string) (*model.CalendarFeed, error) {
+			return nil, apperror.New(403, "FORBIDDEN", "feed shows someone else's schedule")
+		},
+	}
+	h := NewCalendarHandler(svc)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"type":"requester","subject_id":"u-2"}`))
+	req = withClaims(req, "u-1", "E1", "viewer")
+	rec := httptest.NewRecorder()
+
+	h.CreateFeed(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestCalendar_Feed_UnknownToken(t *testing.T) {
+	h := NewCalendarHandler(&mockCalendarSvc{})
+	req := withChiParam(httptest.NewRequest("GET", "/", nil), "token", "nope")
+	rec := httptest.NewRecorder()
+
+	h.Feed(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestCalendar_Feed_ServesICS(t *testing.T) {
+	start := time.Date(2026, 11, 2, 0, 0, 0, 0, time.UTC)
+	svc := &mockCalendarSvc{
+		feedFn: func(_ context.Context, token string) (*ical.Calendar, error) {
+			if token != "tok123" {
+				return nil, nil
+			}
+			return &ical.Calendar{ProdID: "-//test//EN", Events: []ical.Event{{
+				UID: "r-1", Start: start, End: start.Add(time.Hour), Summary: "Airport run", Status: ical.StatusCancelled,
+			}}}, nil
+		},
+	}
+	h := NewCalendarHandler(svc)
+	req := withChiParam(httptest.NewRequest("GET", "/", nil), "token", "tok123")
+	rec := httptest.NewRecorder()
+
+	h.Feed(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/calendar") {
+		t.Errorf("Content-Type = %q", ct)
+	}
+	if !strings.Contains(rec.Body.String(), "STATUS:CANCELLED\r\n") {
+		t.Errorf("body lacks cancelled status:\n%s", rec.Body.String())
+	}
+}
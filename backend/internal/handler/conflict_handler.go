@@ -7,6 +7,7 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/kento/driver/backend/internal/dto"
 	"github.com/kento/driver/backend/internal/middleware"
+	"github.com/kento/driver/backend/internal/model"
 	"github.com/kento/driver/backend/pkg/apperror"
 )
 
@@ -20,7 +21,7 @@ func NewConflictHandler(conflictSvc conflictService, reservationSvc reservationS
 }
 
 func (h *ConflictHandler) ListPending(w http.ResponseWriter, r *http.Request) {
-	conflicts, err := h.conflictSvc.ListPending(r.Context())
+	conflicts, err := h.conflictSvc.ListPending(r.Context(), middleware.GetDepotIDs(r.Context()))
 	if err != nil {
 		apperror.WriteError(w, apperror.ErrInternal)
 		return
@@ -31,7 +32,7 @@ func (h *ConflictHandler) ListPending(w http.ResponseWriter, r *http.Request) {
 func (h *ConflictHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	conflict, err := h.conflictSvc.GetByID(r.Context(), id)
+	conflict, err := h.conflictSvc.GetByID(r.Context(), id, middleware.GetDepotIDs(r.Context()))
 	if err != nil {
 		apperror.WriteError(w, apperror.ErrInternal)
 		return
@@ -41,8 +42,11 @@ func (h *ConflictHandler) Get(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	// Get both reservations for detail view
-	winning, _ := h.reservationSvc.GetByID(r.Context(), conflict.WinningReservationID)
+	// Get both reservations for detail view; a maintenance window has none
+	var winning *model.Reservation
+	if conflict.WinningReservationID != nil {
+		winning, _ = h.reservationSvc.GetByID(r.Context(), *conflict.WinningReservationID)
+	}
 	losing, _ := h.reservationSvc.GetByID(r.Context(), conflict.LosingReservationID)
 
 	apperror.WriteSuccess(w, map[string]interface{}{
@@ -52,6 +56,23 @@ func (h *ConflictHandler) Get(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+// Suggestions lists vehicles and nearby slots the losing reservation could
+// move to.
+func (h *ConflictHandler) Suggestions(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	suggestions, err := h.conflictSvc.Suggestions(r.Context(), id, middleware.GetDepotIDs(r.Context()))
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	apperror.WriteSuccess(w, suggestions)
+}
+
 func (h *ConflictHandler) Reassign(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	claims := middleware.GetClaims(r.Context())
@@ -62,7 +83,7 @@ func (h *ConflictHandler) Reassign(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.conflictSvc.ResolveReassign(r.Context(), id, req.NewVehicleID, claims.UserID, req.Reason); err != nil {
+	if err := h.conflictSvc.ResolveReassign(r.Context(), id, req.NewVehicleID, claims.UserID, req.Reason, middleware.GetDepotIDs(r.Context())); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -84,7 +105,7 @@ func (h *ConflictHandler) ChangeTime(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	conflict, err := h.conflictSvc.GetByID(r.Context(), id)
+	conflict, err := h.conflictSvc.GetByID(r.Context(), id, middleware.GetDepotIDs(r.Context()))
 	if err != nil || conflict == nil {
 		apperror.WriteError(w, apperror.ErrNotFound)
 		return
@@ -99,7 +120,7 @@ func (h *ConflictHandler) ChangeTime(w http.ResponseWriter, r *http.Request) {
 	losingRes.StartTime = req.NewStartTime
 	losingRes.EndTime = req.NewEndTime
 
-	if err := h.conflictSvc.ResolveChangeTime(r.Context(), id, claims.UserID, req.Reason, losingRes); err != nil {
+	if err := h.conflictSvc.ResolveChangeTime(r.Context(), id, claims.UserID, req.Reason, losingRes, middleware.GetDepotIDs(r.Context())); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -121,7 +142,7 @@ func (h *ConflictHandler) Cancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.conflictSvc.ResolveCancel(r.Context(), id, claims.UserID, req.Reason); err != nil {
+	if err := h.conflictSvc.ResolveCancel(r.Context(), id, claims.UserID, req.Reason, middleware.GetDepotIDs(r.Context())); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -143,7 +164,7 @@ func (h *ConflictHandler) ForceAssign(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.conflictSvc.ForceAssign(r.Context(), id, claims.UserID, req.Reason); err != nil {
+	if err := h.conflictSvc.ForceAssign(r.Context(), id, claims.UserID, req.Reason, middleware.GetDepotIDs(r.Context())); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+func TestConflict_Suggestions(t *testing.T) {
+	var gotID string
+	svc := &mockConflictSvc{
+		suggestionsFn: func(_ context.Context, id string, _ []string) (*dto.ConflictSuggestions, error) {
+			gotID = id
+			return &dto.ConflictSuggestions{
+				ConflictID:    id,
+				ReservationID: "r-2",
+				Vehicles:      []dto.VehicleSuggestion{{VehicleID: "v-2", VehicleName: "Van", SlackSec: 3600}},
+				Slots:         []dto.SlotSuggestion{},
+			}, nil
+		},
+	}
+	h := NewConflictHandler(svc, nil)
+	req := withChiParam(httptest.NewRequest("GET", "/", nil), "id", "c-1")
+	rec := httptest.NewRecorder()
+
+	h.Suggestions(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp dto.ConflictSuggestions
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if gotID != "c-1" || resp.ReservationID != "r-2" || len(resp.Vehicles) != 1 || resp.Vehicles[0].SlackSec != 3600 {
+		t.Errorf("Suggestions(%q) = %+v", gotID, resp)
+	}
+}
+
+func TestConflict_Suggestions_AlreadyResolved(t *testing.T) {
+	svc := &mockConflictSvc{
+		suggestionsFn: func(context.Context, string, []string) (*dto.ConflictSuggestions, error) {
+			return nil, apperror.New(400, "ALREADY_RESOLVED", "conflict is already resolved")
+		},
+	}
+	h := NewConflictHandler(svc, nil)
+	req := withChiParam(httptest.NewRequest("GET", "/", nil), "id", "c-1")
+	rec := httptest.NewRecorder()
+
+	h.Suggestions(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if code := decodeError(t, rec); code != "ALREADY_RESOLVED" {
+		t.Errorf("code = %q, want ALREADY_RESOLVED", code)
+	}
+}
+
+func TestConflict_Reassign_OutOfDepot(t *testing.T) {
+	var gotDepots []string
+	svc := &mockConflictSvc{
+		resolveReassignFn: func(_ context.Context, _, _, _, _ string, depotIDs []string) error {
+			gotDepots = depotIDs
+			return apperror.New(403, "OUT_OF_DEPOT", "the vehicle belongs to a depot you do not dispatch for")
+		},
+	}
+	h := NewConflictHandler(svc, nil)
+	body := strings.NewReader(`{"new_vehicle_id":"v-2","reason":"move"}`)
+	req := withChiParam(httptest.NewRequest("POST", "/", body), "id", "c-1")
+	req = withDepots(withClaims(req, "u-1", "dispatch001", "dispatcher"), "d-north")
+	rec := httptest.NewRecorder()
+
+	h.Reassign(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if code := decodeError(t, rec); code != "OUT_OF_DEPOT" {
+		t.Errorf("code = %q, want OUT_OF_DEPOT", code)
+	}
+	if len(gotDepots) != 1 || gotDepots[0] != "d-north" {
+		t.Errorf("depot scope = %v, want [d-north]", gotDepots)
+	}
+}
+
+func TestConflict_Get_OutOfDepot(t *testing.T) {
+	svc := &mockConflictSvc{
+		getByIDFn: func(_ context.Context, id string, depotIDs []string) (*model.ReservationConflict, error) {
+			if depotIDs == nil {
+				t.Errorf("GetByID(%q) was called without the depot scope", id)
+			}
+			return nil, nil
+		},
+	}
+	h := NewConflictHandler(svc, nil)
+	req := withDepots(withChiParam(httptest.NewRequest("GET", "/", nil), "id", "c-1"), "d-north")
+	rec := httptest.NewRecorder()
+
+	h.Get(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
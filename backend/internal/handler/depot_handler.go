@@ -0,0 +1,145 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/middleware"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+// DepotHandler serves depots and depot membership. Dispatchers only see the
+// depots they work.
+type DepotHandler struct {
+	depotSvc depotService
+}
+
+func NewDepotHandler(depotSvc depotService) *DepotHandler {
+	return &DepotHandler{depotSvc: depotSvc}
+}
+
+func (h *DepotHandler) List(w http.ResponseWriter, r *http.Request) {
+	depots, err := h.depotSvc.List(r.Context(), middleware.GetDepotIDs(r.Context()))
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	apperror.WriteSuccess(w, depots)
+}
+
+func (h *DepotHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	depot, err := h.depotSvc.GetByID(r.Context(), id)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	if depot == nil || !model.InDepots(middleware.GetDepotIDs(r.Context()), &depot.ID) {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+
+	apperror.WriteSuccess(w, depot)
+}
+
+func (h *DepotHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.CreateDepotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	depot, err := h.depotSvc.Create(r.Context(), claims.UserID, req)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteCreated(w, depot)
+}
+
+func (h *DepotHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.UpdateDepotRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	depot, err := h.depotSvc.Update(r.Context(), claims.UserID, id, req)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, depot)
+}
+
+func (h *DepotHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	if err := h.depotSvc.Delete(r.Context(), claims.UserID, id); err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUserDepots returns the depots a user is a member of.
+func (h *DepotHandler) GetUserDepots(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+
+	ids, err := h.depotSvc.DepotIDsOf(r.Context(), userID)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, dto.UserDepots{DepotIDs: ids})
+}
+
+// SetUserDepots replaces the depots a user is a member of.
+func (h *DepotHandler) SetUserDepots(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.UserDepots
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	ids, err := h.depotSvc.SetUserDepots(r.Context(), claims.UserID, userID, req.DepotIDs)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, dto.UserDepots{DepotIDs: ids})
+}
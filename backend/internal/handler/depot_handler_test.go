@@ -0,0 +1,129 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/middleware"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+// withDepots scopes the request to depotIDs, as DepotScope does for
+// dispatchers.
+func withDepots(req *http.Request, depotIDs ...string) *http.Request {
+	if depotIDs == nil {
+		depotIDs = []string{}
+	}
+	return req.WithContext(context.WithValue(req.Context(), middleware.DepotIDsKey, depotIDs))
+}
+
+func TestDepot_List_Scoped(t *testing.T) {
+	var got []string
+	svc := &mockDepotSvc{
+		listFn: func(_ context.Context, depotIDs []string) ([]model.Depot, error) {
+			got = depotIDs
+			return []model.Depot{{ID: "d-1", Name: "North"}}, nil
+		},
+	}
+	h := NewDepotHandler(svc)
+	req := withDepots(withClaims(httptest.NewRequest("GET", "/", nil), "u-1", "E1", "dispatcher"), "d-1")
+	rec := httptest.NewRecorder()
+
+	h.List(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if len(got) != 1 || got[0] != "d-1" {
+		t.Errorf("depotIDs = %v, want [d-1]", got)
+	}
+}
+
+func TestDepot_Get_OutOfScope(t *testing.T) {
+	svc := &mockDepotSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Depot, error) {
+			return &model.Depot{ID: id, Name: "South"}, nil
+		},
+	}
+	h := NewDepotHandler(svc)
+	req := withDepots(withChiParam(httptest.NewRequest("GET", "/", nil), "id", "d-2"), "d-1")
+	rec := httptest.NewRecorder()
+
+	h.Get(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDepot_Create(t *testing.T) {
+	var gotBy string
+	var gotReq dto.CreateDepotRequest
+	svc := &mockDepotSvc{
+		createFn: func(_ context.Context, by string, req dto.CreateDepotRequest) (*model.Depot, error) {
+			gotBy, gotReq = by, req
+			return &model.Depot{ID: "d-1", Name: req.Name, Latitude: req.Latitude, Longitude: req.Longitude}, nil
+		},
+	}
+	h := NewDepotHandler(svc)
+	body := `{"name":"North","latitude":35.68,"longitude":139.76}`
+	req := withClaims(httptest.NewRequest("POST", "/", strings.NewReader(body)), "u-1", "E1", "admin")
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if gotBy != "u-1" || gotReq.Name != "North" || gotReq.Latitude == nil || *gotReq.Latitude != 35.68 {
+		t.Errorf("Create(%q, %+v)", gotBy, gotReq)
+	}
+}
+
+func TestDepot_Create_NameTaken(t *testing.T) {
+	svc := &mockDepotSvc{
+		createFn: func(context.Context, string, dto.CreateDepotRequest) (*model.Depot, error) {
+			return nil, apperror.New(409, "DEPOT_NAME_TAKEN", "another depot already has that name")
+		},
+	}
+	h := NewDepotHandler(svc)
+	req := withClaims(httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"North"}`)), "u-1", "E1", "admin")
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if code := decodeError(t, rec); code != "DEPOT_NAME_TAKEN" {
+		t.Errorf("error code = %q, want DEPOT_NAME_TAKEN", code)
+	}
+}
+
+func TestDepot_SetUserDepots(t *testing.T) {
+	var gotUser string
+	var gotIDs []string
+	svc := &mockDepotSvc{
+		setUserDepotsFn: func(_ context.Context, _, userID string, depotIDs []string) ([]string, error) {
+			gotUser, gotIDs = userID, depotIDs
+			return depotIDs, nil
+		},
+	}
+	h := NewDepotHandler(svc)
+	req := withClaims(withChiParam(httptest.NewRequest("PUT", "/", strings.NewReader(`{"depot_ids":["d-1","d-2"]}`)), "id", "u-2"), "u-1", "E1", "admin")
+	rec := httptest.NewRecorder()
+
+	h.SetUserDepots(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotUser != "u-2" || len(gotIDs) != 2 {
+		t.Errorf("SetUserDepots(%q, %v)", gotUser, gotIDs)
+	}
+}
@@ -8,6 +8,7 @@ import (
 	"github.com/kento/driver/backend/internal/dto"
 	"github.com/kento/driver/backend/internal/middleware"
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/policy"
 	"github.com/kento/driver/backend/pkg/apperror"
 )
 
@@ -60,6 +61,10 @@ func (h *DispatchHandler) QuickBoard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.vehicleInScope(w, r, req.VehicleID) {
+		return
+	}
+
 	dispatch, err := h.dispatchSvc.QuickBoard(r.Context(), req, claims.UserID)
 	if err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
@@ -77,7 +82,11 @@ func (h *DispatchHandler) Alight(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	claims := middleware.GetClaims(r.Context())
 
-	if err := h.dispatchSvc.UpdateStatus(r.Context(), id, model.DispatchStatusCompleted, claims.UserID); err != nil {
+	if !h.dispatchInScope(w, r, id) {
+		return
+	}
+
+	if err := h.dispatchSvc.UpdateStatus(r.Context(), id, model.DispatchStatusCompleted, dispatchActor(claims.Role), claims.UserID); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -116,7 +125,7 @@ func (h *DispatchHandler) DriverBoard(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 	if vehicle == nil {
-		apperror.WriteErrorMsg(w, 404, "NO_VEHICLE", "no vehicle assigned to this driver")
+		apperror.WriteErrorMsg(w, 404, "NO_VEHICLE", "driver has no vehicle checked out")
 		return
 	}
 
@@ -156,8 +165,12 @@ func (h *DispatchHandler) List(w http.ResponseWriter, r *http.Request) {
 		limit = 50
 	}
 
-	dispatches, err := h.dispatchSvc.List(r.Context(), status, limit, offset)
+	dispatches, err := h.dispatchSvc.List(r.Context(), subjectOf(r), status, middleware.GetDepotIDs(r.Context()), limit, offset)
 	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
 		apperror.WriteError(w, apperror.ErrInternal)
 		return
 	}
@@ -168,13 +181,16 @@ func (h *DispatchHandler) List(w http.ResponseWriter, r *http.Request) {
 func (h *DispatchHandler) Get(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
-	dispatch, err := h.dispatchSvc.GetByID(r.Context(), id)
+	dispatch, err := h.dispatchSvc.GetFor(r.Context(), subjectOf(r), policy.DispatchRead, id)
 	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
 		apperror.WriteError(w, apperror.ErrInternal)
 		return
 	}
-	if dispatch == nil {
-		apperror.WriteError(w, apperror.ErrNotFound)
+	if !h.dispatchVisible(w, r, dispatch) {
 		return
 	}
 
@@ -191,7 +207,11 @@ func (h *DispatchHandler) Assign(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.dispatchSvc.Assign(r.Context(), id, req.VehicleID, claims.UserID); err != nil {
+	if !h.vehicleInScope(w, r, req.VehicleID) {
+		return
+	}
+
+	if err := h.dispatchSvc.Assign(r.Context(), id, req.VehicleID, dispatchActor(claims.Role), claims.UserID, middleware.GetDepotIDs(r.Context())); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -213,7 +233,11 @@ func (h *DispatchHandler) Cancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := h.dispatchSvc.Cancel(r.Context(), id, req.Reason, claims.UserID); err != nil {
+	if !h.dispatchInScope(w, r, id) {
+		return
+	}
+
+	if err := h.dispatchSvc.Cancel(r.Context(), id, req.Reason, dispatchActor(claims.Role), claims.UserID); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -237,8 +261,20 @@ func (h *DispatchHandler) CalculateETAs(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	etas, err := h.dispatchSvc.CalculateETAs(r.Context(), req.PickupLat, req.PickupLng)
+	depotIDs := middleware.GetDepotIDs(r.Context())
+	var etas []dto.VehicleETA
+	var err error
+	if req.DispatchID != nil && *req.DispatchID != "" {
+		claims := middleware.GetClaims(r.Context())
+		etas, err = h.dispatchSvc.CalculateDispatchETAs(r.Context(), *req.DispatchID, req.PickupLat, req.PickupLng, claims.UserID, depotIDs)
+	} else {
+		etas, err = h.dispatchSvc.CalculateETAs(r.Context(), req.PickupLat, req.PickupLng, depotIDs)
+	}
 	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
 		apperror.WriteError(w, apperror.ErrInternal)
 		return
 	}
@@ -249,6 +285,10 @@ func (h *DispatchHandler) CalculateETAs(w http.ResponseWriter, r *http.Request)
 func (h *DispatchHandler) GetETASnapshots(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 
+	if !h.dispatchIDVisible(w, r, id) {
+		return
+	}
+
 	snapshots, err := h.dispatchSvc.GetETASnapshots(r.Context(), id)
 	if err != nil {
 		apperror.WriteError(w, apperror.ErrInternal)
@@ -258,6 +298,129 @@ func (h *DispatchHandler) GetETASnapshots(w http.ResponseWriter, r *http.Request
 	apperror.WriteSuccess(w, snapshots)
 }
 
+// ReviewETAs reconstructs the ETA decisions for a dispatch and compares the
+// assigned vehicle's ETA with its actual pickup time.
+func (h *DispatchHandler) ReviewETAs(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if !h.dispatchIDVisible(w, r, id) {
+		return
+	}
+
+	review, err := h.dispatchSvc.ReviewETAs(r.Context(), id)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, review)
+}
+
+// StateMachine describes the dispatch lifecycle: statuses, and which actor
+// may move a dispatch from one to another.
+func (h *DispatchHandler) StateMachine(w http.ResponseWriter, r *http.Request) {
+	apperror.WriteSuccess(w, h.dispatchSvc.StateMachine())
+}
+
+// subjectOf is the policy subject for the authenticated caller.
+func subjectOf(r *http.Request) policy.Subject {
+	claims := middleware.GetClaims(r.Context())
+	if claims == nil {
+		return policy.Subject{}
+	}
+	return policy.Subject{UserID: claims.UserID, Role: model.Role(claims.Role)}
+}
+
+// dispatchActor maps the caller's role to the state machine actor.
+func dispatchActor(role string) model.DispatchActor {
+	return model.DispatchActorForRole(model.Role(role))
+}
+
+// vehicleInScope refuses with 403 when the vehicle belongs to a depot the
+// caller does not dispatch for. Unknown vehicles are left to the service.
+func (h *DispatchHandler) vehicleInScope(w http.ResponseWriter, r *http.Request, vehicleID string) bool {
+	ok, err := vehicleInDepots(r, h.vehicleSvc, vehicleID)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return false
+	}
+	if !ok {
+		apperror.WriteErrorMsg(w, 403, "OUT_OF_DEPOT", "the vehicle belongs to a depot you do not dispatch for")
+		return false
+	}
+	return true
+}
+
+// dispatchInScope is vehicleInScope for the vehicle of dispatch id.
+// Dispatches without a vehicle, and unknown ones, are left to the service.
+func (h *DispatchHandler) dispatchInScope(w http.ResponseWriter, r *http.Request, id string) bool {
+	if middleware.GetDepotIDs(r.Context()) == nil {
+		return true
+	}
+	d, err := h.dispatchSvc.GetByID(r.Context(), id)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return false
+	}
+	if d == nil || d.VehicleID == nil {
+		return true
+	}
+	return h.vehicleInScope(w, r, *d.VehicleID)
+}
+
+// dispatchVisible answers 404 for a dispatch on a vehicle outside the
+// caller's depots, so its existence does not leak.
+func (h *DispatchHandler) dispatchVisible(w http.ResponseWriter, r *http.Request, d *model.Dispatch) bool {
+	if d.VehicleID == nil {
+		return true
+	}
+	ok, err := vehicleInDepots(r, h.vehicleSvc, *d.VehicleID)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return false
+	}
+	if !ok {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return false
+	}
+	return true
+}
+
+// dispatchIDVisible is dispatchVisible for dispatch id. Unknown dispatches
+// are left to the service.
+func (h *DispatchHandler) dispatchIDVisible(w http.ResponseWriter, r *http.Request, id string) bool {
+	if middleware.GetDepotIDs(r.Context()) == nil {
+		return true
+	}
+	d, err := h.dispatchSvc.GetByID(r.Context(), id)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return false
+	}
+	if d == nil {
+		return true
+	}
+	return h.dispatchVisible(w, r, d)
+}
+
+// vehicleInDepots reports whether the vehicle lies within the caller's
+// depot scope. Unknown vehicles count as in scope.
+func vehicleInDepots(r *http.Request, vehicles vehicleService, vehicleID string) (bool, error) {
+	depotIDs := middleware.GetDepotIDs(r.Context())
+	if depotIDs == nil {
+		return true, nil
+	}
+	v, err := vehicles.GetByID(r.Context(), vehicleID)
+	if err != nil {
+		return false, err
+	}
+	return v == nil || model.InDepots(depotIDs, v.DepotID), nil
+}
+
 // Driver endpoints
 func (h *DispatchHandler) CurrentTrip(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetClaims(r.Context())
@@ -279,7 +442,7 @@ func (h *DispatchHandler) AcceptTrip(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	claims := middleware.GetClaims(r.Context())
 
-	if err := h.dispatchSvc.UpdateStatus(r.Context(), id, model.DispatchStatusAccepted, claims.UserID); err != nil {
+	if err := h.dispatchSvc.UpdateStatus(r.Context(), id, model.DispatchStatusAccepted, dispatchActor(claims.Role), claims.UserID); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -295,7 +458,7 @@ func (h *DispatchHandler) EnRouteTrip(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	claims := middleware.GetClaims(r.Context())
 
-	if err := h.dispatchSvc.UpdateStatus(r.Context(), id, model.DispatchStatusEnRoute, claims.UserID); err != nil {
+	if err := h.dispatchSvc.UpdateStatus(r.Context(), id, model.DispatchStatusEnRoute, dispatchActor(claims.Role), claims.UserID); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -311,7 +474,7 @@ func (h *DispatchHandler) ArriveTrip(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	claims := middleware.GetClaims(r.Context())
 
-	if err := h.dispatchSvc.UpdateStatus(r.Context(), id, model.DispatchStatusArrived, claims.UserID); err != nil {
+	if err := h.dispatchSvc.UpdateStatus(r.Context(), id, model.DispatchStatusArrived, dispatchActor(claims.Role), claims.UserID); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -327,7 +490,7 @@ func (h *DispatchHandler) CompleteTrip(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	claims := middleware.GetClaims(r.Context())
 
-	if err := h.dispatchSvc.UpdateStatus(r.Context(), id, model.DispatchStatusCompleted, claims.UserID); err != nil {
+	if err := h.dispatchSvc.UpdateStatus(r.Context(), id, model.DispatchStatusCompleted, dispatchActor(claims.Role), claims.UserID); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -2,12 +2,16 @@ package handler
 
 import (
 	"context"
+	"encoding/json"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
 
+	"github.com/kento/driver/backend/internal/dto"
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/policy"
+	"github.com/kento/driver/backend/pkg/apperror"
 )
 
 func TestDispatch_Create_MissingFields(t *testing.T) {
@@ -52,7 +56,7 @@ func TestDispatch_List_InvalidLimit(t *testing.T) {
 
 func TestDispatch_List_Success(t *testing.T) {
 	svc := &mockDispatchSvc{
-		listFn: func(ctx context.Context, status string, limit, offset int) ([]model.Dispatch, error) {
+		listFn: func(ctx context.Context, status string, depotIDs []string, limit, offset int) ([]model.Dispatch, error) {
 			return []model.Dispatch{{ID: "d1"}}, nil
 		},
 	}
@@ -107,3 +111,284 @@ func TestDispatch_CalculateETAs_ValidZeroCoord(t *testing.T) {
 		t.Errorf("status = %d, (0,0) should be valid GPS coordinates", rec.Code)
 	}
 }
+
+func TestDispatch_CalculateETAs_RecordsForDispatch(t *testing.T) {
+	var gotDispatch, gotActor string
+	svc := &mockDispatchSvc{
+		calculateETAsFn: func(ctx context.Context, lat, lng float64, depotIDs []string) ([]dto.VehicleETA, error) {
+			t.Error("plain CalculateETAs called; want the dispatch-scoped variant")
+			return nil, nil
+		},
+		calculateDispatchETAsFn: func(ctx context.Context, dispatchID string, lat, lng float64, actorID string, depotIDs []string) ([]dto.VehicleETA, error) {
+			gotDispatch, gotActor = dispatchID, actorID
+			return []dto.VehicleETA{{VehicleID: "v-1", DurationSec: 300, Provider: "google"}}, nil
+		},
+	}
+	h := NewDispatchHandler(svc, &mockVehicleSvc{})
+	body := `{"pickup_lat":14.55,"pickup_lng":121.02,"dispatch_id":"d-1"}`
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req = withClaims(req, "dispatcher-1", "dispatch001", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.CalculateETAs(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotDispatch != "d-1" || gotActor != "dispatcher-1" {
+		t.Errorf("recorded for dispatch %q by %q, want d-1 by dispatcher-1", gotDispatch, gotActor)
+	}
+}
+
+func TestDispatch_CalculateETAs_UnknownDispatch(t *testing.T) {
+	svc := &mockDispatchSvc{
+		calculateDispatchETAsFn: func(ctx context.Context, dispatchID string, lat, lng float64, actorID string, depotIDs []string) ([]dto.VehicleETA, error) {
+			return nil, apperror.ErrNotFound
+		},
+	}
+	h := NewDispatchHandler(svc, &mockVehicleSvc{})
+	body := `{"pickup_lat":14.55,"pickup_lng":121.02,"dispatch_id":"missing"}`
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req = withClaims(req, "dispatcher-1", "dispatch001", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.CalculateETAs(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDispatch_ReviewETAs(t *testing.T) {
+	late := 240
+	svc := &mockDispatchSvc{
+		reviewETAsFn: func(ctx context.Context, dispatchID string) (*dto.DispatchETAReview, error) {
+			if dispatchID != "d-1" {
+				return nil, apperror.ErrNotFound
+			}
+			return &dto.DispatchETAReview{
+				DispatchID: "d-1",
+				Pickup:     &dto.PickupComparison{VehicleID: "v-2", PredictedDurationSec: 300, ErrorSec: &late},
+				Decisions:  []dto.ETADecision{{BatchID: "b-1", Trigger: model.ETATriggerAssign, ChosenRank: 2, FastestVehicleID: "v-1"}},
+			}, nil
+		},
+	}
+	h := NewDispatchHandler(svc, &mockVehicleSvc{})
+
+	req := withChiParam(httptest.NewRequest("GET", "/", nil), "id", "d-1")
+	rec := httptest.NewRecorder()
+	h.ReviewETAs(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp dto.DispatchETAReview
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Pickup == nil || *resp.Pickup.ErrorSec != 240 || len(resp.Decisions) != 1 || resp.Decisions[0].ChosenRank != 2 {
+		t.Errorf("response = %+v", resp)
+	}
+
+	req = withChiParam(httptest.NewRequest("GET", "/", nil), "id", "missing")
+	rec = httptest.NewRecorder()
+	h.ReviewETAs(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDispatch_AcceptTrip_InvalidTransition(t *testing.T) {
+	var gotActor model.DispatchActor
+	svc := &mockDispatchSvc{
+		updateStatusFn: func(_ context.Context, _ string, to model.DispatchStatus, actor model.DispatchActor, _ string) error {
+			gotActor = actor
+			return apperror.New(409, "INVALID_TRANSITION", "dispatch cannot move from completed to accepted").
+				WithDetails(dto.InvalidTransitionDetails{From: model.DispatchStatusCompleted, To: to, Actor: actor})
+		},
+	}
+	h := NewDispatchHandler(svc, &mockVehicleSvc{})
+
+	req := withClaims(withChiParam(httptest.NewRequest("POST", "/", nil), "id", "d-1"), "u-1", "E1", "driver")
+	rec := httptest.NewRecorder()
+	h.AcceptTrip(rec, req)
+
+	if gotActor != model.DispatchActorDriver {
+		t.Errorf("actor = %q, want driver", gotActor)
+	}
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	var resp struct {
+		Error struct {
+			Code    string                       `json:"code"`
+			Details dto.InvalidTransitionDetails `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Error.Code != "INVALID_TRANSITION" || resp.Error.Details.From != model.DispatchStatusCompleted {
+		t.Errorf("error = %+v", resp.Error)
+	}
+}
+
+func TestDispatch_StateMachine(t *testing.T) {
+	h := NewDispatchHandler(&mockDispatchSvc{}, &mockVehicleSvc{})
+
+	rec := httptest.NewRecorder()
+	h.StateMachine(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp dto.DispatchStateMachine
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if len(resp.Transitions) != len(model.DispatchTransitions) {
+		t.Errorf("transitions = %d, want %d", len(resp.Transitions), len(model.DispatchTransitions))
+	}
+}
+
+func TestDispatch_Get_OtherPassengerForbidden(t *testing.T) {
+	svc := &mockDispatchSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Dispatch, error) {
+			return &model.Dispatch{ID: id, RequesterID: "p-2"}, nil
+		},
+	}
+	h := NewDispatchHandler(svc, &mockVehicleSvc{})
+
+	req := withClaims(withChiParam(httptest.NewRequest("GET", "/", nil), "id", "d-1"), "p-1", "", "passenger")
+	rec := httptest.NewRecorder()
+	h.Get(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	req = withClaims(withChiParam(httptest.NewRequest("GET", "/", nil), "id", "d-1"), "p-2", "", "passenger")
+	rec = httptest.NewRecorder()
+	h.Get(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("requester status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestDispatch_Assign_OutOfDepot(t *testing.T) {
+	north := "depot-north"
+	svc := &mockDispatchSvc{
+		assignFn: func(context.Context, string, string, model.DispatchActor, string) error {
+			t.Error("Assign called for a vehicle of another depot")
+			return nil
+		},
+	}
+	vehicles := &mockVehicleSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Vehicle, error) {
+			return &model.Vehicle{ID: id, DepotID: &north}, nil
+		},
+	}
+	h := NewDispatchHandler(svc, vehicles)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"vehicle_id":"v-1"}`))
+	req = withDepots(withChiParam(withClaims(req, "dispatcher-1", "dispatch001", "dispatcher"), "id", "d-1"), "depot-south")
+	rec := httptest.NewRecorder()
+
+	h.Assign(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if code := decodeError(t, rec); code != "OUT_OF_DEPOT" {
+		t.Errorf("code = %q, want OUT_OF_DEPOT", code)
+	}
+}
+
+func TestDispatch_Get_OutOfDepot(t *testing.T) {
+	north, vehicleID := "depot-north", "v-1"
+	svc := &mockDispatchSvc{
+		getForFn: func(_ context.Context, _ policy.Subject, _ policy.Action, id string) (*model.Dispatch, error) {
+			return &model.Dispatch{ID: id, VehicleID: &vehicleID}, nil
+		},
+	}
+	vehicles := &mockVehicleSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Vehicle, error) {
+			return &model.Vehicle{ID: id, DepotID: &north}, nil
+		},
+	}
+	h := NewDispatchHandler(svc, vehicles)
+	req := withDepots(withChiParam(httptest.NewRequest("GET", "/", nil), "id", "d-1"), "depot-south")
+	rec := httptest.NewRecorder()
+
+	h.Get(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestDispatch_Cancel_OutOfDepot(t *testing.T) {
+	north, vehicleID := "depot-north", "v-1"
+	svc := &mockDispatchSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Dispatch, error) {
+			return &model.Dispatch{ID: id, VehicleID: &vehicleID}, nil
+		},
+		cancelFn: func(context.Context, string, string, model.DispatchActor, string) error {
+			t.Error("Cancel called for a dispatch of another depot")
+			return nil
+		},
+	}
+	vehicles := &mockVehicleSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Vehicle, error) {
+			return &model.Vehicle{ID: id, DepotID: &north}, nil
+		},
+	}
+	h := NewDispatchHandler(svc, vehicles)
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"reason":"no longer needed"}`))
+	req = withDepots(withChiParam(withClaims(req, "dispatcher-1", "dispatch001", "dispatcher"), "id", "d-1"), "depot-south")
+	rec := httptest.NewRecorder()
+
+	h.Cancel(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if code := decodeError(t, rec); code != "OUT_OF_DEPOT" {
+		t.Errorf("code = %q, want OUT_OF_DEPOT", code)
+	}
+}
+
+func TestDispatch_ETAEndpoints_OutOfDepot(t *testing.T) {
+	north, vehicleID := "depot-north", "v-1"
+	svc := &mockDispatchSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Dispatch, error) {
+			return &model.Dispatch{ID: id, VehicleID: &vehicleID}, nil
+		},
+		getETASnapshotsFn: func(context.Context, string) ([]model.DispatchETASnapshot, error) {
+			t.Error("GetETASnapshots called for a dispatch of another depot")
+			return nil, nil
+		},
+		reviewETAsFn: func(context.Context, string) (*dto.DispatchETAReview, error) {
+			t.Error("ReviewETAs called for a dispatch of another depot")
+			return nil, nil
+		},
+	}
+	vehicles := &mockVehicleSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Vehicle, error) {
+			return &model.Vehicle{ID: id, DepotID: &north}, nil
+		},
+	}
+	h := NewDispatchHandler(svc, vehicles)
+
+	for name, fn := range map[string]http.HandlerFunc{"snapshots": h.GetETASnapshots, "review": h.ReviewETAs} {
+		req := withDepots(withChiParam(httptest.NewRequest("GET", "/", nil), "id", "d-1"), "depot-south")
+		rec := httptest.NewRecorder()
+
+		fn(rec, req)
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("%s: status = %d, want %d", name, rec.Code, http.StatusNotFound)
+		}
+	}
+}
@@ -0,0 +1,272 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/middleware"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+// DocumentHandler serves vehicle and driver documents. Scans are kept in
+// scanDir, outside the public uploads, and only served to admins.
+type DocumentHandler struct {
+	documentSvc documentService
+	scanDir     string
+}
+
+func NewDocumentHandler(documentSvc documentService, scanDir string) *DocumentHandler {
+	return &DocumentHandler{documentSvc: documentSvc, scanDir: scanDir}
+}
+
+func (h *DocumentHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseIntParam(w, r, "limit", 0)
+	if !ok {
+		return
+	}
+	offset, ok := parseIntParam(w, r, "offset", 0)
+	if !ok {
+		return
+	}
+	var expiringWithin *int
+	if r.URL.Query().Has("expiring_within_days") {
+		days, ok := parseIntParam(w, r, "expiring_within_days", 0)
+		if !ok {
+			return
+		}
+		expiringWithin = &days
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	q := r.URL.Query()
+	docs, err := h.documentSvc.List(r.Context(), q.Get("vehicle_id"), q.Get("user_id"), q.Get("type"), expiringWithin, limit, offset)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, docs)
+}
+
+func (h *DocumentHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	doc, err := h.documentSvc.GetByID(r.Context(), id)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	if doc == nil {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+
+	apperror.WriteSuccess(w, doc)
+}
+
+func (h *DocumentHandler) CreateForVehicle(w http.ResponseWriter, r *http.Request) {
+	vehicleID := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.CreateDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	doc, err := h.documentSvc.CreateForVehicle(r.Context(), claims.UserID, vehicleID, req)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteCreated(w, doc)
+}
+
+func (h *DocumentHandler) CreateForUser(w http.ResponseWriter, r *http.Request) {
+	userID := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.CreateDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	doc, err := h.documentSvc.CreateForUser(r.Context(), claims.UserID, userID, req)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteCreated(w, doc)
+}
+
+func (h *DocumentHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.UpdateDocumentRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	doc, err := h.documentSvc.Update(r.Context(), claims.UserID, id, req)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, doc)
+}
+
+func (h *DocumentHandler) Delete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	doc, err := h.documentSvc.GetByID(r.Context(), id)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	if doc == nil {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+
+	if err := h.documentSvc.Delete(r.Context(), claims.UserID, id); err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	if doc.ScanFile != nil {
+		h.removeScan(*doc.ScanFile)
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// UploadScan stores a scan of the document, an image or a PDF, replacing
+// any earlier one.
+func (h *DocumentHandler) UploadScan(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	doc, err := h.documentSvc.GetByID(r.Context(), id)
+	if err != nil || doc == nil {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+
+	// 10 MB max
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("scan")
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".webp" && ext != ".pdf" {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+	if !validateMagicBytes(file, ext) {
+		apperror.WriteErrorMsg(w, 400, "INVALID_FILE", "file content does not match declared type")
+		return
+	}
+	if seeker, ok := file.(io.Seeker); ok {
+		seeker.Seek(0, io.SeekStart)
+	}
+
+	if err := os.MkdirAll(h.scanDir, 0700); err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	filename := fmt.Sprintf("%s%s", uuid.New().String(), ext)
+	dst, err := os.Create(filepath.Join(h.scanDir, filename))
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	if err := h.documentSvc.SetScan(r.Context(), claims.UserID, id, &filename); err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	if doc.ScanFile != nil {
+		h.removeScan(*doc.ScanFile)
+	}
+
+	apperror.WriteSuccess(w, map[string]string{"scan_file": filename})
+}
+
+func (h *DocumentHandler) DownloadScan(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	doc, err := h.documentSvc.GetByID(r.Context(), id)
+	if err != nil || doc == nil || doc.ScanFile == nil {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+	base := filepath.Base(*doc.ScanFile)
+	if base == "." || base == "/" || base == ".." {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "private, no-store")
+	http.ServeFile(w, r, filepath.Join(h.scanDir, base))
+}
+
+// removeScan deletes a replaced scan (safe: uses only basename).
+func (h *DocumentHandler) removeScan(scanFile string) {
+	base := filepath.Base(scanFile)
+	if base == "." || base == "/" || base == ".." {
+		return
+	}
+	os.Remove(filepath.Join(h.scanDir, base))
+}
@@ -0,0 +1,135 @@
+package handler
+
+import (
+	"bytes"
+	"context"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+)
+
+func scanRequest(t *testing.T, filename string, content []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	mw := multipart.NewWriter(&body)
+	part, err := mw.CreateFormFile("scan", filename)
+	if err != nil {
+		t.Fatal(err)
+	}
+	part.Write(content)
+	mw.Close()
+	req := httptest.NewRequest("POST", "/", &body)
+	req.Header.Set("Content-Type", mw.FormDataContentType())
+	return req
+}
+
+func TestDocument_CreateForVehicle(t *testing.T) {
+	var gotBy, gotVehicle string
+	var gotReq dto.CreateDocumentRequest
+	svc := &mockDocumentSvc{
+		createForVehicleFn: func(_ context.Context, by, vehicleID string, req dto.CreateDocumentRequest) (*model.Document, error) {
+			gotBy, gotVehicle, gotReq = by, vehicleID, req
+			return &model.Document{ID: "doc-1", VehicleID: &vehicleID, Type: req.Type}, nil
+		},
+	}
+	h := NewDocumentHandler(svc, t.TempDir())
+	body := `{"type":"insurance","number":"INS-2291","expires_on":"2027-03-31"}`
+	req := withClaims(withChiParam(httptest.NewRequest("POST", "/", strings.NewReader(body)), "id", "v-1"), "u-1", "E1", "admin")
+	rec := httptest.NewRecorder()
+
+	h.CreateForVehicle(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if gotBy != "u-1" || gotVehicle != "v-1" || gotReq.Type != model.DocumentTypeInsurance ||
+		gotReq.ExpiresOn == nil || *gotReq.ExpiresOn != "2027-03-31" {
+		t.Errorf("CreateForVehicle(%q, %q, %+v)", gotBy, gotVehicle, gotReq)
+	}
+}
+
+func TestDocument_List_ExpiringWithin(t *testing.T) {
+	var got *int
+	svc := &mockDocumentSvc{
+		listFn: func(_ context.Context, _, _, _ string, expiringWithin *int, _, _ int) ([]model.Document, error) {
+			got = expiringWithin
+			return []model.Document{}, nil
+		},
+	}
+	h := NewDocumentHandler(svc, t.TempDir())
+	req := withClaims(httptest.NewRequest("GET", "/?expiring_within_days=30", nil), "u-1", "E1", "admin")
+	rec := httptest.NewRecorder()
+
+	h.List(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if got == nil || *got != 30 {
+		t.Errorf("expiringWithin = %v, want 30", got)
+	}
+}
+
+func TestDocument_UploadScan_PDF(t *testing.T) {
+	dir := t.TempDir()
+	var gotFile *string
+	svc := &mockDocumentSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Document, error) {
+			return &model.Document{ID: id}, nil
+		},
+		setScanFn: func(_ context.Context, _, _ string, scanFile *string) error {
+			gotFile = scanFile
+			return nil
+		},
+	}
+	h := NewDocumentHandler(svc, dir)
+	req := withClaims(withChiParam(scanRequest(t, "licence.pdf", []byte("%PDF-1.7\n...")), "id", "doc-1"), "u-1", "E1", "admin")
+	rec := httptest.NewRecorder()
+
+	h.UploadScan(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotFile == nil || filepath.Ext(*gotFile) != ".pdf" {
+		t.Fatalf("scan file = %v, want a .pdf", gotFile)
+	}
+	if _, err := os.Stat(filepath.Join(dir, *gotFile)); err != nil {
+		t.Errorf("scan not stored: %v", err)
+	}
+}
+
+func TestDocument_UploadScan_ContentMismatch(t *testing.T) {
+	called := false
+	svc := &mockDocumentSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Document, error) {
+			return &model.Document{ID: id}, nil
+		},
+		setScanFn: func(context.Context, string, string, *string) error {
+			called = true
+			return nil
+		},
+	}
+	h := NewDocumentHandler(svc, t.TempDir())
+	req := withClaims(withChiParam(scanRequest(t, "licence.pdf", []byte("<html>not a pdf</html>")), "id", "doc-1"), "u-1", "E1", "admin")
+	rec := httptest.NewRecorder()
+
+	h.UploadScan(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if code := decodeError(t, rec); code != "INVALID_FILE" {
+		t.Errorf("code = %q, want INVALID_FILE", code)
+	}
+	if called {
+		t.Error("scan recorded despite mismatched content")
+	}
+}
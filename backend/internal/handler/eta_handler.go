@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+type ETAHandler struct {
+	travelSvc travelModel
+}
+
+func NewETAHandler(travelSvc travelModel) *ETAHandler {
+	return &ETAHandler{travelSvc: travelSvc}
+}
+
+// Accuracy compares learned ETAs with the flat speed profile on trips
+// completed in the last ?days= days (default 14).
+func (h *ETAHandler) Accuracy(w http.ResponseWriter, r *http.Request) {
+	days, ok := parseIntParam(w, r, "days", 14)
+	if !ok {
+		return
+	}
+
+	report, err := h.travelSvc.Accuracy(r.Context(), days)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	apperror.WriteSuccess(w, report)
+}
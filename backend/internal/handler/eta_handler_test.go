@@ -0,0 +1,76 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/eta"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+func TestETAAccuracy_DefaultDays(t *testing.T) {
+	var gotDays int
+	h := NewETAHandler(&mockTravelModel{
+		accuracyFn: func(ctx context.Context, days int) (*dto.ETAAccuracyReport, error) {
+			gotDays = days
+			return &dto.ETAAccuracyReport{
+				Learned:     eta.Accuracy{Samples: 40, MAESec: 90},
+				Flat:        eta.Accuracy{Samples: 40, MAESec: 150},
+				Improvement: 0.4,
+			}, nil
+		},
+	})
+	req := httptest.NewRequest("GET", "/admin/eta/accuracy", nil)
+	rec := httptest.NewRecorder()
+
+	h.Accuracy(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotDays != 14 {
+		t.Errorf("days = %d, want default 14", gotDays)
+	}
+	var resp dto.ETAAccuracyReport
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.Learned.MAESec != 90 || resp.Flat.MAESec != 150 || resp.Improvement != 0.4 {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestETAAccuracy_InvalidDays(t *testing.T) {
+	h := NewETAHandler(&mockTravelModel{})
+	req := httptest.NewRequest("GET", "/admin/eta/accuracy?days=two", nil)
+	rec := httptest.NewRecorder()
+
+	h.Accuracy(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestETAAccuracy_ServiceValidation(t *testing.T) {
+	h := NewETAHandler(&mockTravelModel{
+		accuracyFn: func(ctx context.Context, days int) (*dto.ETAAccuracyReport, error) {
+			return nil, apperror.New(400, "VALIDATION_ERROR", "days must be between 1 and 90")
+		},
+	})
+	req := httptest.NewRequest("GET", "/admin/eta/accuracy?days=365", nil)
+	rec := httptest.NewRecorder()
+
+	h.Accuracy(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if code := decodeError(t, rec); code != "VALIDATION_ERROR" {
+		t.Errorf("code = %q, want VALIDATION_ERROR", code)
+	}
+}
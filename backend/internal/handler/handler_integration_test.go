@@ -248,7 +248,7 @@ func TestDispatchCreate_MissingPickupAddress(t *testing.T) {
 
 func TestDispatchList_Success(t *testing.T) {
 	mock := &mockDispatchSvc{
-		listFn: func(_ context.Context, _ string, _, _ int) ([]model.Dispatch, error) {
+		listFn: func(_ context.Context, _ string, _ []string, _, _ int) ([]model.Dispatch, error) {
 			return []model.Dispatch{{ID: "d-1"}, {ID: "d-2"}}, nil
 		},
 	}
@@ -301,7 +301,7 @@ func TestDispatchGet_NotFound(t *testing.T) {
 
 func TestDispatchAssign_Success(t *testing.T) {
 	mock := &mockDispatchSvc{
-		assignFn: func(_ context.Context, _, _, _ string) error { return nil },
+		assignFn: func(_ context.Context, _, _ string, _ model.DispatchActor, _ string) error { return nil },
 	}
 	h := &DispatchHandler{dispatchSvc: mock}
 
@@ -320,7 +320,7 @@ func TestDispatchAssign_Success(t *testing.T) {
 
 func TestDispatchAssign_ServiceError(t *testing.T) {
 	mock := &mockDispatchSvc{
-		assignFn: func(_ context.Context, _, _, _ string) error {
+		assignFn: func(_ context.Context, _, _ string, _ model.DispatchActor, _ string) error {
 			return apperror.New(400, "INVALID_STATUS", "dispatch is not in pending status")
 		},
 	}
@@ -341,7 +341,7 @@ func TestDispatchAssign_ServiceError(t *testing.T) {
 
 func TestDispatchCancel_Success(t *testing.T) {
 	mock := &mockDispatchSvc{
-		cancelFn: func(_ context.Context, _, _, _ string) error { return nil },
+		cancelFn: func(_ context.Context, _, _ string, _ model.DispatchActor, _ string) error { return nil },
 	}
 	h := &DispatchHandler{dispatchSvc: mock}
 
@@ -374,7 +374,7 @@ func TestDispatchCalculateETAs_ValidationError(t *testing.T) {
 
 func TestDispatchCalculateETAs_Success(t *testing.T) {
 	mock := &mockDispatchSvc{
-		calculateETAsFn: func(_ context.Context, _, _ float64) ([]dto.VehicleETA, error) {
+		calculateETAsFn: func(_ context.Context, _, _ float64, _ []string) ([]dto.VehicleETA, error) {
 			return []dto.VehicleETA{{VehicleID: "v-1", DurationSec: 300}}, nil
 		},
 	}
@@ -429,7 +429,7 @@ func TestDispatchCurrentTrip_NoTrip(t *testing.T) {
 
 func TestDispatchAcceptTrip_Success(t *testing.T) {
 	mock := &mockDispatchSvc{
-		updateStatusFn: func(_ context.Context, _ string, _ model.DispatchStatus, _ string) error {
+		updateStatusFn: func(_ context.Context, _ string, _ model.DispatchStatus, _ model.DispatchActor, _ string) error {
 			return nil
 		},
 	}
@@ -487,7 +487,7 @@ func TestDispatchQuickBoard_Success(t *testing.T) {
 
 func TestVehicleList_Success(t *testing.T) {
 	mock := &mockVehicleSvc{
-		listWithStatusFn: func(_ context.Context) ([]model.VehicleWithStatus, error) {
+		listWithStatusFn: func(_ context.Context, _ []string) ([]model.VehicleWithStatus, error) {
 			return []model.VehicleWithStatus{
 				{ID: "v-1", Name: "Car A", Status: model.VehicleStatusAvailable},
 			}, nil
@@ -543,7 +543,7 @@ func TestVehicleGet_NotFound(t *testing.T) {
 func TestVehicleCreate_ValidationError(t *testing.T) {
 	h := &VehicleHandler{}
 
-	body := `{"name":"","license_plate":"","driver_id":""}`
+	body := `{"name":"","license_plate":""}`
 	req := httptest.NewRequest("POST", "/api/v1/vehicles", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	req = withClaims(req, "admin-1", "admin001", "admin")
@@ -557,13 +557,13 @@ func TestVehicleCreate_ValidationError(t *testing.T) {
 
 func TestVehicleCreate_Success(t *testing.T) {
 	mock := &mockVehicleSvc{
-		createFn: func(_ context.Context, _, name, plate, did string) (*model.Vehicle, error) {
-			return &model.Vehicle{ID: "v-new", Name: name, LicensePlate: plate, DriverID: did}, nil
+		createFn: func(_ context.Context, _, name, plate string, _ *string) (*model.Vehicle, error) {
+			return &model.Vehicle{ID: "v-new", Name: name, LicensePlate: plate}, nil
 		},
 	}
 	h := &VehicleHandler{vehicleSvc: mock}
 
-	body := `{"name":"Car B","license_plate":"ABC-123","driver_id":"drv-1"}`
+	body := `{"name":"Car B","license_plate":"ABC-123"}`
 	req := httptest.NewRequest("POST", "/api/v1/vehicles", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	req = withClaims(req, "admin-1", "admin001", "admin")
@@ -578,7 +578,7 @@ func TestVehicleCreate_Success(t *testing.T) {
 func TestVehicleUpdate_ValidationError(t *testing.T) {
 	h := &VehicleHandler{}
 
-	body := `{"name":"","license_plate":"","driver_id":""}`
+	body := `{"name":"","license_plate":""}`
 	req := httptest.NewRequest("PUT", "/api/v1/vehicles/v-1", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	req = withClaims(req, "admin-1", "admin001", "admin")
@@ -593,11 +593,11 @@ func TestVehicleUpdate_ValidationError(t *testing.T) {
 
 func TestVehicleUpdate_Success(t *testing.T) {
 	mock := &mockVehicleSvc{
-		updateFn: func(_ context.Context, _, _, _, _, _ string) error { return nil },
+		updateFn: func(_ context.Context, _, _, _, _ string) error { return nil },
 	}
 	h := &VehicleHandler{vehicleSvc: mock}
 
-	body := `{"name":"Car B","license_plate":"XYZ-999","driver_id":"drv-2"}`
+	body := `{"name":"Car B","license_plate":"XYZ-999"}`
 	req := httptest.NewRequest("PUT", "/api/v1/vehicles/v-1", strings.NewReader(body))
 	req.Header.Set("Content-Type", "application/json")
 	req = withClaims(req, "admin-1", "admin001", "admin")
@@ -629,7 +629,7 @@ func TestVehicleDelete_Success(t *testing.T) {
 
 func TestVehicleListAvailable_Success(t *testing.T) {
 	mock := &mockVehicleSvc{
-		listAvailableFn: func(_ context.Context) ([]model.VehicleWithStatus, error) {
+		listAvailableFn: func(_ context.Context, _ []string) ([]model.VehicleWithStatus, error) {
 			return []model.VehicleWithStatus{}, nil
 		},
 	}
@@ -644,32 +644,13 @@ func TestVehicleListAvailable_Success(t *testing.T) {
 	}
 }
 
-func TestVehicleToggleMaintenance_Success(t *testing.T) {
-	mock := &mockVehicleSvc{
-		toggleMaintenanceFn: func(_ context.Context, _, _ string, _ bool) error { return nil },
-	}
-	h := &VehicleHandler{vehicleSvc: mock}
-
-	body := `{"is_maintenance":true}`
-	req := httptest.NewRequest("PATCH", "/api/v1/vehicles/v-1/maintenance", strings.NewReader(body))
-	req.Header.Set("Content-Type", "application/json")
-	req = withClaims(req, "user-1", "emp001", "dispatcher")
-	req = withChiParam(req, "id", "v-1")
-	rec := httptest.NewRecorder()
-	h.ToggleMaintenance(rec, req)
-
-	if rec.Code != http.StatusNoContent {
-		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
-	}
-}
-
 // ===================================================================
 // Attendance handler integration tests
 // ===================================================================
 
 func TestAttendanceClockIn_Success(t *testing.T) {
 	mock := &mockAttendanceSvc{
-		clockInFn: func(_ context.Context, did string) (*model.DriverAttendance, error) {
+		clockInFn: func(_ context.Context, did string, _ *string, _ *int) (*model.DriverAttendance, error) {
 			return &model.DriverAttendance{ID: "att-1", DriverID: did, DriverStatus: model.DriverStatusActive}, nil
 		},
 	}
@@ -687,7 +668,7 @@ func TestAttendanceClockIn_Success(t *testing.T) {
 
 func TestAttendanceClockIn_AlreadyClockedIn(t *testing.T) {
 	mock := &mockAttendanceSvc{
-		clockInFn: func(_ context.Context, _ string) (*model.DriverAttendance, error) {
+		clockInFn: func(_ context.Context, _ string, _ *string, _ *int) (*model.DriverAttendance, error) {
 			return nil, apperror.New(400, "ALREADY_CLOCKED_IN", "driver is already clocked in")
 		},
 	}
@@ -706,9 +687,77 @@ func TestAttendanceClockIn_AlreadyClockedIn(t *testing.T) {
 	}
 }
 
+func TestAttendanceClockIn_ChecksOutVehicle(t *testing.T) {
+	var gotVehicle *string
+	mock := &mockAttendanceSvc{
+		clockInFn: func(_ context.Context, did string, vid *string, _ *int) (*model.DriverAttendance, error) {
+			gotVehicle = vid
+			return &model.DriverAttendance{ID: "att-1", DriverID: did, VehicleID: vid}, nil
+		},
+	}
+	h := &AttendanceHandler{attendanceSvc: mock}
+
+	body := `{"vehicle_id":"v-2"}`
+	req := httptest.NewRequest("POST", "/api/v1/attendance/clock-in", strings.NewReader(body))
+	req = withClaims(req, "driver-1", "drv001", "driver")
+	rec := httptest.NewRecorder()
+	h.ClockIn(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if gotVehicle == nil || *gotVehicle != "v-2" {
+		t.Errorf("vehicle = %v, want v-2", gotVehicle)
+	}
+}
+
+func TestAttendanceClockIn_VehicleTaken(t *testing.T) {
+	mock := &mockAttendanceSvc{
+		clockInFn: func(_ context.Context, _ string, _ *string, _ *int) (*model.DriverAttendance, error) {
+			return nil, apperror.New(409, "VEHICLE_TAKEN", "vehicle is checked out by another driver")
+		},
+	}
+	h := &AttendanceHandler{attendanceSvc: mock}
+
+	body := `{"vehicle_id":"v-2"}`
+	req := httptest.NewRequest("POST", "/api/v1/attendance/clock-in", strings.NewReader(body))
+	req = withClaims(req, "driver-1", "drv001", "driver")
+	rec := httptest.NewRecorder()
+	h.ClockIn(rec, req)
+
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusConflict)
+	}
+	if code := decodeError(t, rec); code != "VEHICLE_TAKEN" {
+		t.Errorf("error code = %q, want %q", code, "VEHICLE_TAKEN")
+	}
+}
+
+func TestAttendanceListAssignments(t *testing.T) {
+	mock := &mockAttendanceSvc{
+		listAssignFn: func(_ context.Context, vid string, limit int) ([]model.VehicleAssignment, error) {
+			if vid != "v-1" || limit != 50 {
+				t.Errorf("ListAssignments(%q, %d), want (v-1, 50)", vid, limit)
+			}
+			return []model.VehicleAssignment{{DriverName: "Day Driver"}}, nil
+		},
+	}
+	h := &AttendanceHandler{attendanceSvc: mock}
+
+	req := httptest.NewRequest("GET", "/api/v1/vehicles/v-1/assignments", nil)
+	req = withClaims(req, "disp-1", "disp001", "dispatcher")
+	req = withChiParam(req, "id", "v-1")
+	rec := httptest.NewRecorder()
+	h.ListAssignments(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
 func TestAttendanceClockOut_Success(t *testing.T) {
 	mock := &mockAttendanceSvc{
-		clockOutFn: func(_ context.Context, _ string) error { return nil },
+		clockOutFn: func(_ context.Context, _ string, _ *int) error { return nil },
 	}
 	h := &AttendanceHandler{attendanceSvc: mock}
 
@@ -722,6 +771,29 @@ func TestAttendanceClockOut_Success(t *testing.T) {
 	}
 }
 
+func TestAttendanceClockOut_WithOdometer(t *testing.T) {
+	var got *int
+	mock := &mockAttendanceSvc{
+		clockOutFn: func(_ context.Context, _ string, odometerKm *int) error {
+			got = odometerKm
+			return nil
+		},
+	}
+	h := &AttendanceHandler{attendanceSvc: mock}
+
+	req := httptest.NewRequest("POST", "/api/v1/attendance/clock-out", strings.NewReader(`{"odometer_km":48213}`))
+	req = withClaims(req, "driver-1", "drv001", "driver")
+	rec := httptest.NewRecorder()
+	h.ClockOut(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got == nil || *got != 48213 {
+		t.Errorf("odometer = %v, want 48213", got)
+	}
+}
+
 func TestAttendanceUpdateDriverStatus_InvalidStatus(t *testing.T) {
 	h := &AttendanceHandler{}
 
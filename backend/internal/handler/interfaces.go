@@ -5,8 +5,12 @@ import (
 	"time"
 
 	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/ical"
+	"github.com/kento/driver/backend/internal/jobs"
 	"github.com/kento/driver/backend/internal/maps"
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/policy"
+	"github.com/kento/driver/backend/internal/realtime"
 )
 
 // Service interfaces used by handlers.
@@ -22,46 +26,67 @@ type dispatchService interface {
 	Create(ctx context.Context, req dto.CreateDispatchRequest, requesterID string) (*model.Dispatch, error)
 	QuickBoard(ctx context.Context, req dto.QuickBoardRequest, dispatcherID string) (*model.Dispatch, error)
 	GetByID(ctx context.Context, id string) (*model.Dispatch, error)
-	List(ctx context.Context, status string, limit, offset int) ([]model.Dispatch, error)
+	GetFor(ctx context.Context, sub policy.Subject, action policy.Action, id string) (*model.Dispatch, error)
+	List(ctx context.Context, sub policy.Subject, status string, depotIDs []string, limit, offset int) ([]model.Dispatch, error)
 	ListByRequester(ctx context.Context, requesterID, status string, limit, offset int) ([]model.Dispatch, error)
-	Assign(ctx context.Context, dispatchID, vehicleID, dispatcherID string) error
-	UpdateStatus(ctx context.Context, dispatchID string, status model.DispatchStatus, actorID string) error
-	Cancel(ctx context.Context, dispatchID, reason, actorID string) error
+	ListActive(ctx context.Context) ([]model.Dispatch, error)
+	Assign(ctx context.Context, dispatchID, vehicleID string, actor model.DispatchActor, dispatcherID string, depotIDs []string) error
+	UpdateStatus(ctx context.Context, dispatchID string, status model.DispatchStatus, actor model.DispatchActor, actorID string) error
+	Cancel(ctx context.Context, dispatchID, reason string, actor model.DispatchActor, actorID string) error
+	StateMachine() dto.DispatchStateMachine
 	GetCurrentTripByDriverID(ctx context.Context, driverID string) (*model.Dispatch, error)
 	GetETASnapshots(ctx context.Context, dispatchID string) ([]model.DispatchETASnapshot, error)
-	CalculateETAs(ctx context.Context, pickupLat, pickupLng float64) ([]dto.VehicleETA, error)
+	CalculateETAs(ctx context.Context, pickupLat, pickupLng float64, depotIDs []string) ([]dto.VehicleETA, error)
+	CalculateDispatchETAs(ctx context.Context, dispatchID string, pickupLat, pickupLng float64, actorID string, depotIDs []string) ([]dto.VehicleETA, error)
+	ReviewETAs(ctx context.Context, dispatchID string) (*dto.DispatchETAReview, error)
 	RateDispatch(ctx context.Context, dispatchID string, rating int, comment string) error
+	EstimateRideETA(ctx context.Context, d *model.Dispatch, lat, lng float64) *dto.RideETA
 }
 
 type vehicleService interface {
-	ListWithStatus(ctx context.Context) ([]model.VehicleWithStatus, error)
+	ListWithStatus(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error)
 	GetByID(ctx context.Context, id string) (*model.Vehicle, error)
 	GetByDriverID(ctx context.Context, driverID string) (*model.Vehicle, error)
-	ListAvailable(ctx context.Context) ([]model.VehicleWithStatus, error)
-	Create(ctx context.Context, actorID, name, licensePlate, driverID string) (*model.Vehicle, error)
-	Update(ctx context.Context, actorID, vehicleID, name, licensePlate, driverID string) error
+	ListAvailable(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error)
+	Create(ctx context.Context, actorID, name, licensePlate string, depotID *string) (*model.Vehicle, error)
+	Update(ctx context.Context, actorID, vehicleID, name, licensePlate string) error
 	Delete(ctx context.Context, actorID, vehicleID string) error
 	UpdatePhotoURL(ctx context.Context, vehicleID string, photoURL *string) error
-	ToggleMaintenance(ctx context.Context, actorID, vehicleID string, maintenance bool) error
+	SetTurnaround(ctx context.Context, actorID, vehicleID string, minutes int) error
+	Transfer(ctx context.Context, actorID, vehicleID string, depotID *string) (*model.Vehicle, error)
+	Availability(ctx context.Context, from, to time.Time, granularity time.Duration, depotIDs []string) (*dto.FleetAvailability, error)
+}
+
+type depotService interface {
+	List(ctx context.Context, depotIDs []string) ([]model.Depot, error)
+	GetByID(ctx context.Context, id string) (*model.Depot, error)
+	Create(ctx context.Context, actorID string, req dto.CreateDepotRequest) (*model.Depot, error)
+	Update(ctx context.Context, actorID, id string, req dto.UpdateDepotRequest) (*model.Depot, error)
+	Delete(ctx context.Context, actorID, id string) error
+	DepotIDsOf(ctx context.Context, userID string) ([]string, error)
+	SetUserDepots(ctx context.Context, actorID, userID string, depotIDs []string) ([]string, error)
 }
 
 type locationService interface {
 	ReportLocations(ctx context.Context, vehicleID string, points []model.LocationPoint) error
 	GetHistory(ctx context.Context, vehicleID string, from, to time.Time) ([]model.VehicleLocation, error)
+	GetCurrent(ctx context.Context, vehicleID string) (*model.VehicleLocationCurrent, error)
 }
 
 type attendanceService interface {
-	ClockIn(ctx context.Context, driverID string) (*model.DriverAttendance, error)
-	ClockOut(ctx context.Context, driverID string) error
+	ClockIn(ctx context.Context, driverID string, vehicleID *string, odometerKm *int) (*model.DriverAttendance, error)
+	ClockOut(ctx context.Context, driverID string, odometerKm *int) error
 	UpdateDriverStatus(ctx context.Context, driverID string, status model.DriverStatus) (*model.DriverAttendance, error)
 	GetStatus(ctx context.Context, driverID string) (*model.DriverAttendance, error)
 	GetHistory(ctx context.Context, driverID string, limit int) ([]model.DriverAttendance, error)
+	ListAssignments(ctx context.Context, vehicleID string, limit int) ([]model.VehicleAssignment, error)
 }
 
 type bookingService interface {
-	CreateBooking(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, priorityLevel int) (*dto.UnifiedBookingResponse, error)
+	CreateBooking(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, priorityLevel int, depotIDs []string) (*dto.UnifiedBookingResponse, error)
 	DriverAcceptReservation(ctx context.Context, reservationID, driverID string) error
 	DriverDeclineReservation(ctx context.Context, reservationID, driverID, reason string) error
+	DriverDeclineDispatch(ctx context.Context, dispatchID, driverID, reason string) error
 	GetVehicleTimeline(ctx context.Context, vehicleID string, date time.Time) ([]model.ReservationWithDetails, error)
 	GetPendingByDriverID(ctx context.Context, driverID string) ([]model.ReservationWithDetails, error)
 }
@@ -69,19 +94,73 @@ type bookingService interface {
 type reservationService interface {
 	Create(ctx context.Context, req dto.CreateReservationRequest, requesterID string, priorityLevel int) (*model.Reservation, error)
 	GetByID(ctx context.Context, id string) (*model.Reservation, error)
-	List(ctx context.Context, vehicleID string, from, to time.Time, status string, limit, offset int) ([]model.ReservationWithDetails, error)
+	List(ctx context.Context, vehicleID string, from, to time.Time, status string, depotIDs []string, limit, offset int) ([]model.ReservationWithDetails, error)
 	Cancel(ctx context.Context, id, cancelledBy, reason string) error
 	Update(ctx context.Context, id string, req dto.UpdateReservationRequest, actorID string) (*model.Reservation, error)
 	CheckAvailability(ctx context.Context, vehicleID string, startTime, endTime time.Time) ([]model.Reservation, error)
 }
 
+type reservationSeriesService interface {
+	Create(ctx context.Context, req dto.CreateReservationSeriesRequest, requesterID string, priorityLevel int) (*dto.ReservationSeriesDetail, error)
+	GetByID(ctx context.Context, id string) (*dto.ReservationSeriesDetail, error)
+	List(ctx context.Context, requesterID, status string, depotIDs []string, limit, offset int) ([]model.ReservationSeries, error)
+	Update(ctx context.Context, id string, req dto.UpdateReservationSeriesRequest, actorID string) (*dto.ReservationSeriesDetail, error)
+	Cancel(ctx context.Context, id, cancelledBy, reason string) error
+}
+
+type waitlistService interface {
+	GetByID(ctx context.Context, id string) (*model.WaitlistEntry, error)
+	GetFor(ctx context.Context, sub policy.Subject, id string) (*model.WaitlistEntry, error)
+	List(ctx context.Context, requesterID, status string, depotIDs []string, limit, offset int) ([]model.WaitlistEntry, error)
+	Cancel(ctx context.Context, sub policy.Subject, id string) error
+}
+
+type calendarService interface {
+	CreateFeed(ctx context.Context, sub policy.Subject, feedType model.CalendarFeedType, subjectID string) (*model.CalendarFeed, error)
+	ListFeeds(ctx context.Context, userID string) ([]model.CalendarFeed, error)
+	RevokeFeed(ctx context.Context, sub policy.Subject, id string) error
+	Feed(ctx context.Context, token string) (*ical.Calendar, error)
+}
+
 type conflictService interface {
-	ListPending(ctx context.Context) ([]model.ReservationConflict, error)
-	GetByID(ctx context.Context, id string) (*model.ReservationConflict, error)
-	ResolveReassign(ctx context.Context, conflictID, newVehicleID, resolvedBy, reason string) error
-	ResolveChangeTime(ctx context.Context, conflictID, resolvedBy, reason string, losingRes *model.Reservation) error
-	ResolveCancel(ctx context.Context, conflictID, resolvedBy, reason string) error
-	ForceAssign(ctx context.Context, conflictID, resolvedBy, reason string) error
+	ListPending(ctx context.Context, depotIDs []string) ([]model.ReservationConflict, error)
+	GetByID(ctx context.Context, id string, depotIDs []string) (*model.ReservationConflict, error)
+	ResolveReassign(ctx context.Context, conflictID, newVehicleID, resolvedBy, reason string, depotIDs []string) error
+	ResolveChangeTime(ctx context.Context, conflictID, resolvedBy, reason string, losingRes *model.Reservation, depotIDs []string) error
+	ResolveCancel(ctx context.Context, conflictID, resolvedBy, reason string, depotIDs []string) error
+	ForceAssign(ctx context.Context, conflictID, resolvedBy, reason string, depotIDs []string) error
+	Suggestions(ctx context.Context, conflictID string, depotIDs []string) (*dto.ConflictSuggestions, error)
+}
+
+type maintenanceService interface {
+	List(ctx context.Context, vehicleID, status string, from, to time.Time, limit, offset int) ([]model.MaintenanceWindow, error)
+	GetByID(ctx context.Context, id string) (*model.MaintenanceWindow, error)
+	CreateWindow(ctx context.Context, actorID, vehicleID string, req dto.CreateMaintenanceWindowRequest) (*model.MaintenanceWindow, error)
+	UpdateWindow(ctx context.Context, actorID, id string, req dto.UpdateMaintenanceWindowRequest) (*model.MaintenanceWindow, error)
+	StartWindow(ctx context.Context, actorID, id string) (*model.MaintenanceWindow, error)
+	CompleteWindow(ctx context.Context, actorID, id string, odometerKm *int) (*model.MaintenanceWindow, error)
+	CancelWindow(ctx context.Context, actorID, id, reason string) error
+	CreateSchedule(ctx context.Context, actorID, vehicleID string, req dto.CreateMaintenanceScheduleRequest) (*model.MaintenanceSchedule, error)
+	ListSchedules(ctx context.Context, vehicleID string) ([]model.MaintenanceSchedule, error)
+	DeleteSchedule(ctx context.Context, actorID, id string) error
+}
+
+type documentService interface {
+	List(ctx context.Context, vehicleID, userID, docType string, expiringWithin *int, limit, offset int) ([]model.Document, error)
+	GetByID(ctx context.Context, id string) (*model.Document, error)
+	CreateForVehicle(ctx context.Context, actorID, vehicleID string, req dto.CreateDocumentRequest) (*model.Document, error)
+	CreateForUser(ctx context.Context, actorID, userID string, req dto.CreateDocumentRequest) (*model.Document, error)
+	Update(ctx context.Context, actorID, id string, req dto.UpdateDocumentRequest) (*model.Document, error)
+	SetScan(ctx context.Context, actorID, id string, scanFile *string) error
+	Delete(ctx context.Context, actorID, id string) error
+}
+
+type mileageService interface {
+	CreateFuelLog(ctx context.Context, driverID string, req dto.CreateFuelLogRequest) (*model.FuelLog, error)
+	GetFuelLog(ctx context.Context, id string) (*model.FuelLog, error)
+	SetFuelReceipt(ctx context.Context, id string, receiptURL *string) error
+	ListFuelLogs(ctx context.Context, vehicleID string, from, to time.Time) ([]model.FuelLog, error)
+	Mileage(ctx context.Context, vehicleID string, from, to time.Time) (*dto.VehicleMileage, error)
 }
 
 type userRepository interface {
@@ -102,6 +181,19 @@ type routeComputer interface {
 	ComputeRoute(ctx context.Context, origin, destination maps.LatLng, intermediates []maps.LatLng) (*maps.RouteResult, error)
 }
 
+type eventSubscriber interface {
+	Subscribe(buffer int, filter func(realtime.Event) bool) *realtime.Subscription
+}
+
+type jobMonitor interface {
+	IsLeader() bool
+	Statuses() []jobs.Status
+}
+
+type travelModel interface {
+	Accuracy(ctx context.Context, days int) (*dto.ETAAccuracyReport, error)
+}
+
 type passengerAuthService interface {
 	RegisterPassenger(ctx context.Context, req dto.PassengerRegisterRequest) (*dto.LoginResponse, error)
 	LoginByPhone(ctx context.Context, req dto.PassengerLoginRequest) (*dto.LoginResponse, error)
@@ -0,0 +1,24 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+type JobHandler struct {
+	monitor jobMonitor
+}
+
+func NewJobHandler(monitor jobMonitor) *JobHandler {
+	return &JobHandler{monitor: monitor}
+}
+
+// List reports this replica's view of the background jobs. Only the leader
+// runs jobs, so followers show no recent runs.
+func (h *JobHandler) List(w http.ResponseWriter, r *http.Request) {
+	apperror.WriteSuccess(w, map[string]interface{}{
+		"leader": h.monitor.IsLeader(),
+		"jobs":   h.monitor.Statuses(),
+	})
+}
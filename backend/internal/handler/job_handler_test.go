@@ -0,0 +1,35 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/kento/driver/backend/internal/jobs"
+)
+
+func TestJobs_List(t *testing.T) {
+	h := NewJobHandler(&mockJobMonitor{
+		leader:   true,
+		statuses: []jobs.Status{{Name: "token.clean_expired", LastStatus: "ok", Runs: 2}},
+	})
+	req := httptest.NewRequest("GET", "/admin/jobs", nil)
+	rec := httptest.NewRecorder()
+
+	h.List(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	var resp struct {
+		Leader bool          `json:"leader"`
+		Jobs   []jobs.Status `json:"jobs"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if !resp.Leader || len(resp.Jobs) != 1 || resp.Jobs[0].Runs != 2 {
+		t.Errorf("response = %+v", resp)
+	}
+}
@@ -28,7 +28,7 @@ func (h *LocationHandler) Report(w http.ResponseWriter, r *http.Request) {
 
 	vehicle, err := h.vehicleSvc.GetByDriverID(r.Context(), claims.UserID)
 	if err != nil || vehicle == nil {
-		apperror.WriteErrorMsg(w, 400, "NO_VEHICLE", "no vehicle assigned to this driver")
+		apperror.WriteErrorMsg(w, 400, "NO_VEHICLE", "driver has no vehicle checked out")
 		return
 	}
 
@@ -0,0 +1,233 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/middleware"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+type MaintenanceHandler struct {
+	maintenanceSvc maintenanceService
+}
+
+func NewMaintenanceHandler(maintenanceSvc maintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{maintenanceSvc: maintenanceSvc}
+}
+
+func (h *MaintenanceHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseIntParam(w, r, "limit", 0)
+	if !ok {
+		return
+	}
+	offset, ok := parseIntParam(w, r, "offset", 0)
+	if !ok {
+		return
+	}
+	from, ok := parseTimeParam(w, r, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseTimeParam(w, r, "to")
+	if !ok {
+		return
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	windows, err := h.maintenanceSvc.List(r.Context(), r.URL.Query().Get("vehicle_id"), r.URL.Query().Get("status"), from, to, limit, offset)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, windows)
+}
+
+func (h *MaintenanceHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	window, err := h.maintenanceSvc.GetByID(r.Context(), id)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	if window == nil {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+
+	apperror.WriteSuccess(w, window)
+}
+
+func (h *MaintenanceHandler) Create(w http.ResponseWriter, r *http.Request) {
+	vehicleID := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.CreateMaintenanceWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+	if req.Type == "" || req.StartTime.IsZero() || req.EndTime.IsZero() {
+		apperror.WriteErrorMsg(w, 400, "VALIDATION_ERROR", "type, start_time and end_time are required")
+		return
+	}
+
+	window, err := h.maintenanceSvc.CreateWindow(r.Context(), claims.UserID, vehicleID, req)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteCreated(w, window)
+}
+
+func (h *MaintenanceHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.UpdateMaintenanceWindowRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	window, err := h.maintenanceSvc.UpdateWindow(r.Context(), claims.UserID, id, req)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, window)
+}
+
+func (h *MaintenanceHandler) Start(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	window, err := h.maintenanceSvc.StartWindow(r.Context(), claims.UserID, id)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, window)
+}
+
+func (h *MaintenanceHandler) Complete(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	// The body is optional
+	var req dto.CompleteMaintenanceRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperror.WriteError(w, apperror.ErrBadRequest)
+			return
+		}
+	}
+
+	window, err := h.maintenanceSvc.CompleteWindow(r.Context(), claims.UserID, id, req.OdometerKm)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, window)
+}
+
+func (h *MaintenanceHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.CancelMaintenanceRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			apperror.WriteError(w, apperror.ErrBadRequest)
+			return
+		}
+	}
+
+	if err := h.maintenanceSvc.CancelWindow(r.Context(), claims.UserID, id, req.Reason); err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+func (h *MaintenanceHandler) ListSchedules(w http.ResponseWriter, r *http.Request) {
+	vehicleID := chi.URLParam(r, "id")
+
+	schedules, err := h.maintenanceSvc.ListSchedules(r.Context(), vehicleID)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, schedules)
+}
+
+func (h *MaintenanceHandler) CreateSchedule(w http.ResponseWriter, r *http.Request) {
+	vehicleID := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.CreateMaintenanceScheduleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	schedule, err := h.maintenanceSvc.CreateSchedule(r.Context(), claims.UserID, vehicleID, req)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteCreated(w, schedule)
+}
+
+func (h *MaintenanceHandler) DeleteSchedule(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	if err := h.maintenanceSvc.DeleteSchedule(r.Context(), claims.UserID, id); err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
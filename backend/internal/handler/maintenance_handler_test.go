@@ -0,0 +1,110 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+func TestMaintenance_Create(t *testing.T) {
+	var gotBy, gotVehicle string
+	var gotReq dto.CreateMaintenanceWindowRequest
+	svc := &mockMaintenanceSvc{
+		createWindowFn: func(_ context.Context, by, vehicleID string, req dto.CreateMaintenanceWindowRequest) (*model.MaintenanceWindow, error) {
+			gotBy, gotVehicle, gotReq = by, vehicleID, req
+			return &model.MaintenanceWindow{ID: "m-1", VehicleID: vehicleID, Type: req.Type, Status: model.MaintenanceStatusPlanned}, nil
+		},
+	}
+	h := NewMaintenanceHandler(svc)
+	body := `{"type":"inspection","start_time":"2026-11-03T09:00:00Z","end_time":"2026-11-03T17:00:00Z","vendor":"City Garage"}`
+	req := withClaims(withChiParam(httptest.NewRequest("POST", "/", strings.NewReader(body)), "id", "v-1"), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if gotBy != "u-1" || gotVehicle != "v-1" || gotReq.Type != model.MaintenanceTypeInspection ||
+		gotReq.Vendor == nil || *gotReq.Vendor != "City Garage" {
+		t.Errorf("CreateWindow(%q, %q, %+v)", gotBy, gotVehicle, gotReq)
+	}
+	var resp model.MaintenanceWindow
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("decode: %v", err)
+	}
+	if resp.ID != "m-1" || resp.Status != model.MaintenanceStatusPlanned {
+		t.Errorf("response = %+v", resp)
+	}
+}
+
+func TestMaintenance_Create_MissingTimes(t *testing.T) {
+	called := false
+	svc := &mockMaintenanceSvc{
+		createWindowFn: func(context.Context, string, string, dto.CreateMaintenanceWindowRequest) (*model.MaintenanceWindow, error) {
+			called = true
+			return nil, nil
+		},
+	}
+	h := NewMaintenanceHandler(svc)
+	req := withClaims(withChiParam(httptest.NewRequest("POST", "/", strings.NewReader(`{"type":"repair"}`)), "id", "v-1"), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if called {
+		t.Error("service called despite missing times")
+	}
+}
+
+func TestMaintenance_Complete_WithoutBody(t *testing.T) {
+	var gotOdometer *int
+	svc := &mockMaintenanceSvc{
+		completeWindowFn: func(_ context.Context, _, id string, odometerKm *int) (*model.MaintenanceWindow, error) {
+			gotOdometer = odometerKm
+			return &model.MaintenanceWindow{ID: id, Status: model.MaintenanceStatusDone}, nil
+		},
+	}
+	h := NewMaintenanceHandler(svc)
+	req := withClaims(withChiParam(httptest.NewRequest("POST", "/", nil), "id", "m-1"), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Complete(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotOdometer != nil {
+		t.Errorf("odometer = %v, want nil", *gotOdometer)
+	}
+}
+
+func TestMaintenance_Cancel_Started(t *testing.T) {
+	svc := &mockMaintenanceSvc{
+		cancelWindowFn: func(context.Context, string, string, string) error {
+			return apperror.New(400, "INVALID_STATUS", "only planned maintenance can be cancelled")
+		},
+	}
+	h := NewMaintenanceHandler(svc)
+	req := withClaims(withChiParam(httptest.NewRequest("POST", "/", strings.NewReader(`{"reason":"vendor closed"}`)), "id", "m-1"), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Cancel(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if code := decodeError(t, rec); code != "INVALID_STATUS" {
+		t.Errorf("code = %q, want INVALID_STATUS", code)
+	}
+}
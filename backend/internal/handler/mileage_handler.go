@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/uuid"
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/middleware"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+type MileageHandler struct {
+	mileageSvc mileageService
+	uploadDir  string
+}
+
+func NewMileageHandler(mileageSvc mileageService, uploadDir string) *MileageHandler {
+	return &MileageHandler{mileageSvc: mileageSvc, uploadDir: uploadDir}
+}
+
+func (h *MileageHandler) CreateFuelLog(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.CreateFuelLogRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	f, err := h.mileageSvc.CreateFuelLog(r.Context(), claims.UserID, req)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteCreated(w, f)
+}
+
+// UploadReceipt attaches a photo of the receipt to one of the caller's own
+// fuel logs, replacing any earlier one.
+func (h *MileageHandler) UploadReceipt(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	f, err := h.mileageSvc.GetFuelLog(r.Context(), id)
+	if err != nil || f == nil {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+	if f.DriverID != claims.UserID {
+		apperror.WriteError(w, apperror.ErrForbidden)
+		return
+	}
+
+	// 10 MB max
+	r.Body = http.MaxBytesReader(w, r.Body, 10<<20)
+	if err := r.ParseMultipartForm(10 << 20); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("receipt")
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+	defer file.Close()
+
+	ext := strings.ToLower(filepath.Ext(header.Filename))
+	if ext != ".jpg" && ext != ".jpeg" && ext != ".png" && ext != ".webp" {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+	if !validateMagicBytes(file, ext) {
+		apperror.WriteErrorMsg(w, 400, "INVALID_FILE", "file content does not match declared type")
+		return
+	}
+	if seeker, ok := file.(io.Seeker); ok {
+		seeker.Seek(0, io.SeekStart)
+	}
+
+	receiptDir := filepath.Join(h.uploadDir, "receipts")
+	if err := os.MkdirAll(receiptDir, 0755); err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	// Delete the old receipt if any (safe: uses only basename)
+	if f.ReceiptURL != nil {
+		if base := filepath.Base(*f.ReceiptURL); base != "." && base != "/" && base != ".." {
+			os.Remove(filepath.Join(receiptDir, base))
+		}
+	}
+
+	filename := fmt.Sprintf("%s%s", uuid.New().String(), ext)
+	dst, err := os.Create(filepath.Join(receiptDir, filename))
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	defer dst.Close()
+
+	if _, err := io.Copy(dst, file); err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	receiptURL := fmt.Sprintf("/uploads/receipts/%s", filename)
+	if err := h.mileageSvc.SetFuelReceipt(r.Context(), id, &receiptURL); err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, map[string]string{"receipt_url": receiptURL})
+}
+
+func (h *MileageHandler) ListFuelLogs(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	from, ok := parseTimeParam(w, r, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseTimeParam(w, r, "to")
+	if !ok {
+		return
+	}
+
+	logs, err := h.mileageSvc.ListFuelLogs(r.Context(), id, from, to)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, logs)
+}
+
+// Mileage reports a vehicle's odometer and GPS distance, refuelling and fuel
+// economy per day, with the days on which the two distances disagree.
+func (h *MileageHandler) Mileage(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	from, ok := parseTimeParam(w, r, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseTimeParam(w, r, "to")
+	if !ok {
+		return
+	}
+
+	report, err := h.mileageSvc.Mileage(r.Context(), id, from, to)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, report)
+}
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+)
+
+func TestMileage_CreateFuelLog(t *testing.T) {
+	var gotDriver string
+	var gotReq dto.CreateFuelLogRequest
+	svc := &mockMileageSvc{
+		createFuelLogFn: func(_ context.Context, driverID string, req dto.CreateFuelLogRequest) (*model.FuelLog, error) {
+			gotDriver, gotReq = driverID, req
+			return &model.FuelLog{ID: "f-1", DriverID: driverID, Litres: req.Litres, Cost: req.Cost}, nil
+		},
+	}
+	h := NewMileageHandler(svc, t.TempDir())
+	body := `{"litres":42.5,"cost":7225,"station":"Route 1","odometer_km":48100}`
+	req := withClaims(httptest.NewRequest("POST", "/", strings.NewReader(body)), "d-1", "E1", "driver")
+	rec := httptest.NewRecorder()
+
+	h.CreateFuelLog(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if gotDriver != "d-1" || gotReq.Litres != 42.5 || gotReq.Cost != 7225 ||
+		gotReq.OdometerKm == nil || *gotReq.OdometerKm != 48100 {
+		t.Errorf("CreateFuelLog(%q, %+v)", gotDriver, gotReq)
+	}
+}
+
+func TestMileage_UploadReceipt_NotOwner(t *testing.T) {
+	svc := &mockMileageSvc{
+		getFuelLogFn: func(_ context.Context, id string) (*model.FuelLog, error) {
+			return &model.FuelLog{ID: id, DriverID: "d-2"}, nil
+		},
+	}
+	h := NewMileageHandler(svc, t.TempDir())
+	req := withClaims(withChiParam(httptest.NewRequest("POST", "/", nil), "id", "f-1"), "d-1", "E1", "driver")
+	rec := httptest.NewRecorder()
+
+	h.UploadReceipt(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestMileage_Mileage_InvalidFrom(t *testing.T) {
+	called := false
+	svc := &mockMileageSvc{
+		mileageFn: func(context.Context, string, time.Time, time.Time) (*dto.VehicleMileage, error) {
+			called = true
+			return nil, nil
+		},
+	}
+	h := NewMileageHandler(svc, t.TempDir())
+	req := withChiParam(httptest.NewRequest("GET", "/?from=yesterday", nil), "id", "v-1")
+	rec := httptest.NewRecorder()
+
+	h.Mileage(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if called {
+		t.Error("service called despite invalid from")
+	}
+}
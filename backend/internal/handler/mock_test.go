@@ -5,8 +5,12 @@ import (
 	"time"
 
 	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/ical"
+	"github.com/kento/driver/backend/internal/jobs"
 	"github.com/kento/driver/backend/internal/maps"
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/policy"
+	"github.com/kento/driver/backend/pkg/apperror"
 )
 
 // ── Mock: authService ──
@@ -44,15 +48,20 @@ type mockDispatchSvc struct {
 	createFn            func(ctx context.Context, req dto.CreateDispatchRequest, requesterID string) (*model.Dispatch, error)
 	quickBoardFn        func(ctx context.Context, req dto.QuickBoardRequest, dispatcherID string) (*model.Dispatch, error)
 	getByIDFn           func(ctx context.Context, id string) (*model.Dispatch, error)
-	listFn              func(ctx context.Context, status string, limit, offset int) ([]model.Dispatch, error)
+	getForFn            func(ctx context.Context, sub policy.Subject, action policy.Action, id string) (*model.Dispatch, error)
+	listFn              func(ctx context.Context, status string, depotIDs []string, limit, offset int) ([]model.Dispatch, error)
 	listByRequesterFn   func(ctx context.Context, requesterID, status string, limit, offset int) ([]model.Dispatch, error)
-	assignFn            func(ctx context.Context, dispatchID, vehicleID, dispatcherID string) error
-	updateStatusFn      func(ctx context.Context, dispatchID string, status model.DispatchStatus, actorID string) error
-	cancelFn            func(ctx context.Context, dispatchID, reason, actorID string) error
+	listActiveFn        func(ctx context.Context) ([]model.Dispatch, error)
+	assignFn            func(ctx context.Context, dispatchID, vehicleID string, actor model.DispatchActor, dispatcherID string) error
+	updateStatusFn      func(ctx context.Context, dispatchID string, status model.DispatchStatus, actor model.DispatchActor, actorID string) error
+	cancelFn            func(ctx context.Context, dispatchID, reason string, actor model.DispatchActor, actorID string) error
 	getCurrentTripFn    func(ctx context.Context, driverID string) (*model.Dispatch, error)
 	getETASnapshotsFn   func(ctx context.Context, dispatchID string) ([]model.DispatchETASnapshot, error)
-	calculateETAsFn     func(ctx context.Context, pickupLat, pickupLng float64) ([]dto.VehicleETA, error)
+	calculateETAsFn     func(ctx context.Context, pickupLat, pickupLng float64, depotIDs []string) ([]dto.VehicleETA, error)
+	calculateDispatchETAsFn func(ctx context.Context, dispatchID string, pickupLat, pickupLng float64, actorID string, depotIDs []string) ([]dto.VehicleETA, error)
+	reviewETAsFn        func(ctx context.Context, dispatchID string) (*dto.DispatchETAReview, error)
 	rateDispatchFn      func(ctx context.Context, dispatchID string, rating int, comment string) error
+	estimateRideETAFn   func(ctx context.Context, d *model.Dispatch, lat, lng float64) *dto.RideETA
 }
 
 func (m *mockDispatchSvc) Create(ctx context.Context, req dto.CreateDispatchRequest, requesterID string) (*model.Dispatch, error) {
@@ -76,9 +85,28 @@ func (m *mockDispatchSvc) GetByID(ctx context.Context, id string) (*model.Dispat
 	return nil, nil
 }
 
-func (m *mockDispatchSvc) List(ctx context.Context, status string, limit, offset int) ([]model.Dispatch, error) {
+// GetFor falls back to getByIDFn and, when the request carried claims, the
+// real passenger/staff rules (driver rules need the vehicle and always deny).
+func (m *mockDispatchSvc) GetFor(ctx context.Context, sub policy.Subject, action policy.Action, id string) (*model.Dispatch, error) {
+	if m.getForFn != nil {
+		return m.getForFn(ctx, sub, action, id)
+	}
+	d, err := m.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, apperror.ErrNotFound
+	}
+	if sub.Role != "" && !policy.CanDispatch(sub, action, policy.Dispatch{RequesterID: d.RequesterID}).Allowed {
+		return nil, apperror.ErrForbidden
+	}
+	return d, nil
+}
+
+func (m *mockDispatchSvc) List(ctx context.Context, sub policy.Subject, status string, depotIDs []string, limit, offset int) ([]model.Dispatch, error) {
 	if m.listFn != nil {
-		return m.listFn(ctx, status, limit, offset)
+		return m.listFn(ctx, status, depotIDs, limit, offset)
 	}
 	return nil, nil
 }
@@ -90,27 +118,38 @@ func (m *mockDispatchSvc) ListByRequester(ctx context.Context, requesterID, stat
 	return nil, nil
 }
 
-func (m *mockDispatchSvc) Assign(ctx context.Context, dispatchID, vehicleID, dispatcherID string) error {
+func (m *mockDispatchSvc) ListActive(ctx context.Context) ([]model.Dispatch, error) {
+	if m.listActiveFn != nil {
+		return m.listActiveFn(ctx)
+	}
+	return nil, nil
+}
+
+func (m *mockDispatchSvc) Assign(ctx context.Context, dispatchID, vehicleID string, actor model.DispatchActor, dispatcherID string, depotIDs []string) error {
 	if m.assignFn != nil {
-		return m.assignFn(ctx, dispatchID, vehicleID, dispatcherID)
+		return m.assignFn(ctx, dispatchID, vehicleID, actor, dispatcherID)
 	}
 	return nil
 }
 
-func (m *mockDispatchSvc) UpdateStatus(ctx context.Context, dispatchID string, status model.DispatchStatus, actorID string) error {
+func (m *mockDispatchSvc) UpdateStatus(ctx context.Context, dispatchID string, status model.DispatchStatus, actor model.DispatchActor, actorID string) error {
 	if m.updateStatusFn != nil {
-		return m.updateStatusFn(ctx, dispatchID, status, actorID)
+		return m.updateStatusFn(ctx, dispatchID, status, actor, actorID)
 	}
 	return nil
 }
 
-func (m *mockDispatchSvc) Cancel(ctx context.Context, dispatchID, reason, actorID string) error {
+func (m *mockDispatchSvc) Cancel(ctx context.Context, dispatchID, reason string, actor model.DispatchActor, actorID string) error {
 	if m.cancelFn != nil {
-		return m.cancelFn(ctx, dispatchID, reason, actorID)
+		return m.cancelFn(ctx, dispatchID, reason, actor, actorID)
 	}
 	return nil
 }
 
+func (m *mockDispatchSvc) StateMachine() dto.DispatchStateMachine {
+	return dto.DispatchStateMachine{Transitions: model.DispatchTransitions}
+}
+
 func (m *mockDispatchSvc) GetCurrentTripByDriverID(ctx context.Context, driverID string) (*model.Dispatch, error) {
 	if m.getCurrentTripFn != nil {
 		return m.getCurrentTripFn(ctx, driverID)
@@ -125,9 +164,23 @@ func (m *mockDispatchSvc) GetETASnapshots(ctx context.Context, dispatchID string
 	return nil, nil
 }
 
-func (m *mockDispatchSvc) CalculateETAs(ctx context.Context, pickupLat, pickupLng float64) ([]dto.VehicleETA, error) {
+func (m *mockDispatchSvc) CalculateETAs(ctx context.Context, pickupLat, pickupLng float64, depotIDs []string) ([]dto.VehicleETA, error) {
 	if m.calculateETAsFn != nil {
-		return m.calculateETAsFn(ctx, pickupLat, pickupLng)
+		return m.calculateETAsFn(ctx, pickupLat, pickupLng, depotIDs)
+	}
+	return nil, nil
+}
+
+func (m *mockDispatchSvc) CalculateDispatchETAs(ctx context.Context, dispatchID string, pickupLat, pickupLng float64, actorID string, depotIDs []string) ([]dto.VehicleETA, error) {
+	if m.calculateDispatchETAsFn != nil {
+		return m.calculateDispatchETAsFn(ctx, dispatchID, pickupLat, pickupLng, actorID, depotIDs)
+	}
+	return nil, nil
+}
+
+func (m *mockDispatchSvc) ReviewETAs(ctx context.Context, dispatchID string) (*dto.DispatchETAReview, error) {
+	if m.reviewETAsFn != nil {
+		return m.reviewETAsFn(ctx, dispatchID)
 	}
 	return nil, nil
 }
@@ -139,23 +192,32 @@ func (m *mockDispatchSvc) RateDispatch(ctx context.Context, dispatchID string, r
 	return nil
 }
 
+func (m *mockDispatchSvc) EstimateRideETA(ctx context.Context, d *model.Dispatch, lat, lng float64) *dto.RideETA {
+	if m.estimateRideETAFn != nil {
+		return m.estimateRideETAFn(ctx, d, lat, lng)
+	}
+	return nil
+}
+
 // ── Mock: vehicleService ──
 
 type mockVehicleSvc struct {
-	listWithStatusFn    func(ctx context.Context) ([]model.VehicleWithStatus, error)
+	listWithStatusFn    func(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error)
 	getByIDFn           func(ctx context.Context, id string) (*model.Vehicle, error)
 	getByDriverIDFn     func(ctx context.Context, driverID string) (*model.Vehicle, error)
-	listAvailableFn     func(ctx context.Context) ([]model.VehicleWithStatus, error)
-	createFn            func(ctx context.Context, actorID, name, licensePlate, driverID string) (*model.Vehicle, error)
-	updateFn            func(ctx context.Context, actorID, vehicleID, name, licensePlate, driverID string) error
+	listAvailableFn     func(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error)
+	createFn            func(ctx context.Context, actorID, name, licensePlate string, depotID *string) (*model.Vehicle, error)
+	updateFn            func(ctx context.Context, actorID, vehicleID, name, licensePlate string) error
 	deleteFn            func(ctx context.Context, actorID, vehicleID string) error
 	updatePhotoURLFn    func(ctx context.Context, vehicleID string, photoURL *string) error
-	toggleMaintenanceFn func(ctx context.Context, actorID, vehicleID string, maintenance bool) error
+	setTurnaroundFn     func(ctx context.Context, actorID, vehicleID string, minutes int) error
+	transferFn          func(ctx context.Context, actorID, vehicleID string, depotID *string) (*model.Vehicle, error)
+	availabilityFn      func(ctx context.Context, from, to time.Time, granularity time.Duration, depotIDs []string) (*dto.FleetAvailability, error)
 }
 
-func (m *mockVehicleSvc) ListWithStatus(ctx context.Context) ([]model.VehicleWithStatus, error) {
+func (m *mockVehicleSvc) ListWithStatus(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error) {
 	if m.listWithStatusFn != nil {
-		return m.listWithStatusFn(ctx)
+		return m.listWithStatusFn(ctx, depotIDs)
 	}
 	return nil, nil
 }
@@ -174,23 +236,23 @@ func (m *mockVehicleSvc) GetByDriverID(ctx context.Context, driverID string) (*m
 	return nil, nil
 }
 
-func (m *mockVehicleSvc) ListAvailable(ctx context.Context) ([]model.VehicleWithStatus, error) {
+func (m *mockVehicleSvc) ListAvailable(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error) {
 	if m.listAvailableFn != nil {
-		return m.listAvailableFn(ctx)
+		return m.listAvailableFn(ctx, depotIDs)
 	}
 	return nil, nil
 }
 
-func (m *mockVehicleSvc) Create(ctx context.Context, actorID, name, licensePlate, driverID string) (*model.Vehicle, error) {
+func (m *mockVehicleSvc) Create(ctx context.Context, actorID, name, licensePlate string, depotID *string) (*model.Vehicle, error) {
 	if m.createFn != nil {
-		return m.createFn(ctx, actorID, name, licensePlate, driverID)
+		return m.createFn(ctx, actorID, name, licensePlate, depotID)
 	}
 	return &model.Vehicle{ID: "v1"}, nil
 }
 
-func (m *mockVehicleSvc) Update(ctx context.Context, actorID, vehicleID, name, licensePlate, driverID string) error {
+func (m *mockVehicleSvc) Update(ctx context.Context, actorID, vehicleID, name, licensePlate string) error {
 	if m.updateFn != nil {
-		return m.updateFn(ctx, actorID, vehicleID, name, licensePlate, driverID)
+		return m.updateFn(ctx, actorID, vehicleID, name, licensePlate)
 	}
 	return nil
 }
@@ -209,18 +271,33 @@ func (m *mockVehicleSvc) UpdatePhotoURL(ctx context.Context, vehicleID string, p
 	return nil
 }
 
-func (m *mockVehicleSvc) ToggleMaintenance(ctx context.Context, actorID, vehicleID string, maintenance bool) error {
-	if m.toggleMaintenanceFn != nil {
-		return m.toggleMaintenanceFn(ctx, actorID, vehicleID, maintenance)
+func (m *mockVehicleSvc) SetTurnaround(ctx context.Context, actorID, vehicleID string, minutes int) error {
+	if m.setTurnaroundFn != nil {
+		return m.setTurnaroundFn(ctx, actorID, vehicleID, minutes)
 	}
 	return nil
 }
 
+func (m *mockVehicleSvc) Transfer(ctx context.Context, actorID, vehicleID string, depotID *string) (*model.Vehicle, error) {
+	if m.transferFn != nil {
+		return m.transferFn(ctx, actorID, vehicleID, depotID)
+	}
+	return &model.Vehicle{ID: vehicleID, DepotID: depotID}, nil
+}
+
+func (m *mockVehicleSvc) Availability(ctx context.Context, from, to time.Time, granularity time.Duration, depotIDs []string) (*dto.FleetAvailability, error) {
+	if m.availabilityFn != nil {
+		return m.availabilityFn(ctx, from, to, granularity, depotIDs)
+	}
+	return &dto.FleetAvailability{}, nil
+}
+
 // ── Mock: locationService ──
 
 type mockLocationSvc struct {
 	reportFn    func(ctx context.Context, vehicleID string, points []model.LocationPoint) error
 	getHistoryFn func(ctx context.Context, vehicleID string, from, to time.Time) ([]model.VehicleLocation, error)
+	getCurrentFn func(ctx context.Context, vehicleID string) (*model.VehicleLocationCurrent, error)
 }
 
 func (m *mockLocationSvc) ReportLocations(ctx context.Context, vehicleID string, points []model.LocationPoint) error {
@@ -237,26 +314,34 @@ func (m *mockLocationSvc) GetHistory(ctx context.Context, vehicleID string, from
 	return nil, nil
 }
 
+func (m *mockLocationSvc) GetCurrent(ctx context.Context, vehicleID string) (*model.VehicleLocationCurrent, error) {
+	if m.getCurrentFn != nil {
+		return m.getCurrentFn(ctx, vehicleID)
+	}
+	return nil, nil
+}
+
 // ── Mock: attendanceService ──
 
 type mockAttendanceSvc struct {
-	clockInFn      func(context.Context, string) (*model.DriverAttendance, error)
-	clockOutFn     func(context.Context, string) error
+	clockInFn      func(context.Context, string, *string, *int) (*model.DriverAttendance, error)
+	clockOutFn     func(context.Context, string, *int) error
 	updateStatusFn func(context.Context, string, model.DriverStatus) (*model.DriverAttendance, error)
 	getStatusFn    func(context.Context, string) (*model.DriverAttendance, error)
 	getHistoryFn   func(context.Context, string, int) ([]model.DriverAttendance, error)
+	listAssignFn   func(context.Context, string, int) ([]model.VehicleAssignment, error)
 }
 
-func (m *mockAttendanceSvc) ClockIn(ctx context.Context, did string) (*model.DriverAttendance, error) {
+func (m *mockAttendanceSvc) ClockIn(ctx context.Context, did string, vid *string, odo *int) (*model.DriverAttendance, error) {
 	if m.clockInFn != nil {
-		return m.clockInFn(ctx, did)
+		return m.clockInFn(ctx, did, vid, odo)
 	}
 	return &model.DriverAttendance{}, nil
 }
 
-func (m *mockAttendanceSvc) ClockOut(ctx context.Context, did string) error {
+func (m *mockAttendanceSvc) ClockOut(ctx context.Context, did string, odo *int) error {
 	if m.clockOutFn != nil {
-		return m.clockOutFn(ctx, did)
+		return m.clockOutFn(ctx, did, odo)
 	}
 	return nil
 }
@@ -282,6 +367,13 @@ func (m *mockAttendanceSvc) GetHistory(ctx context.Context, did string, limit in
 	return nil, nil
 }
 
+func (m *mockAttendanceSvc) ListAssignments(ctx context.Context, vid string, limit int) ([]model.VehicleAssignment, error) {
+	if m.listAssignFn != nil {
+		return m.listAssignFn(ctx, vid, limit)
+	}
+	return nil, nil
+}
+
 // ── Mock: userRepository ──
 
 type mockUserRepo struct {
@@ -371,16 +463,17 @@ func (m *mockRouteComputer) ComputeRoute(ctx context.Context, origin, destinatio
 // ── Mock: bookingService ──
 
 type mockBookingSvc struct {
-	createBookingFn         func(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, priorityLevel int) (*dto.UnifiedBookingResponse, error)
+	createBookingFn         func(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, priorityLevel int, depotIDs []string) (*dto.UnifiedBookingResponse, error)
 	driverAcceptFn          func(ctx context.Context, reservationID, driverID string) error
 	driverDeclineFn         func(ctx context.Context, reservationID, driverID, reason string) error
+	driverDeclineTripFn     func(ctx context.Context, dispatchID, driverID, reason string) error
 	getVehicleTimelineFn    func(ctx context.Context, vehicleID string, date time.Time) ([]model.ReservationWithDetails, error)
 	getPendingByDriverIDFn  func(ctx context.Context, driverID string) ([]model.ReservationWithDetails, error)
 }
 
-func (m *mockBookingSvc) CreateBooking(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, priorityLevel int) (*dto.UnifiedBookingResponse, error) {
+func (m *mockBookingSvc) CreateBooking(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, priorityLevel int, depotIDs []string) (*dto.UnifiedBookingResponse, error) {
 	if m.createBookingFn != nil {
-		return m.createBookingFn(ctx, req, requesterID, priorityLevel)
+		return m.createBookingFn(ctx, req, requesterID, priorityLevel, depotIDs)
 	}
 	return &dto.UnifiedBookingResponse{}, nil
 }
@@ -399,6 +492,13 @@ func (m *mockBookingSvc) DriverDeclineReservation(ctx context.Context, reservati
 	return nil
 }
 
+func (m *mockBookingSvc) DriverDeclineDispatch(ctx context.Context, dispatchID, driverID, reason string) error {
+	if m.driverDeclineTripFn != nil {
+		return m.driverDeclineTripFn(ctx, dispatchID, driverID, reason)
+	}
+	return nil
+}
+
 func (m *mockBookingSvc) GetVehicleTimeline(ctx context.Context, vehicleID string, date time.Time) ([]model.ReservationWithDetails, error) {
 	if m.getVehicleTimelineFn != nil {
 		return m.getVehicleTimelineFn(ctx, vehicleID, date)
@@ -481,3 +581,429 @@ func (m *mockPassengerAuthSvc) LoginByPhone(ctx context.Context, req dto.Passeng
 	}
 	return &dto.LoginResponse{}, nil
 }
+
+// ── Mock: jobMonitor ──
+
+type mockJobMonitor struct {
+	leader   bool
+	statuses []jobs.Status
+}
+
+func (m *mockJobMonitor) IsLeader() bool          { return m.leader }
+func (m *mockJobMonitor) Statuses() []jobs.Status { return m.statuses }
+
+// ── Mock: travelModel ──
+
+type mockTravelModel struct {
+	accuracyFn func(ctx context.Context, days int) (*dto.ETAAccuracyReport, error)
+}
+
+func (m *mockTravelModel) Accuracy(ctx context.Context, days int) (*dto.ETAAccuracyReport, error) {
+	if m.accuracyFn != nil {
+		return m.accuracyFn(ctx, days)
+	}
+	return &dto.ETAAccuracyReport{}, nil
+}
+
+// ── Mock: reservationSeriesService ──
+
+type mockSeriesSvc struct {
+	createFn func(ctx context.Context, req dto.CreateReservationSeriesRequest, requesterID string, priorityLevel int) (*dto.ReservationSeriesDetail, error)
+	getFn    func(ctx context.Context, id string) (*dto.ReservationSeriesDetail, error)
+	updateFn func(ctx context.Context, id string, req dto.UpdateReservationSeriesRequest, actorID string) (*dto.ReservationSeriesDetail, error)
+	cancelFn func(ctx context.Context, id, cancelledBy, reason string) error
+}
+
+func (m *mockSeriesSvc) Create(ctx context.Context, req dto.CreateReservationSeriesRequest, requesterID string, priorityLevel int) (*dto.ReservationSeriesDetail, error) {
+	if m.createFn != nil {
+		return m.createFn(ctx, req, requesterID, priorityLevel)
+	}
+	return &dto.ReservationSeriesDetail{}, nil
+}
+
+func (m *mockSeriesSvc) GetByID(ctx context.Context, id string) (*dto.ReservationSeriesDetail, error) {
+	if m.getFn != nil {
+		return m.getFn(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockSeriesSvc) List(ctx context.Context, requesterID, status string, depotIDs []string, limit, offset int) ([]model.ReservationSeries, error) {
+	return []model.ReservationSeries{}, nil
+}
+
+func (m *mockSeriesSvc) Update(ctx context.Context, id string, req dto.UpdateReservationSeriesRequest, actorID string) (*dto.ReservationSeriesDetail, error) {
+	if m.updateFn != nil {
+		return m.updateFn(ctx, id, req, actorID)
+	}
+	return &dto.ReservationSeriesDetail{}, nil
+}
+
+func (m *mockSeriesSvc) Cancel(ctx context.Context, id, cancelledBy, reason string) error {
+	if m.cancelFn != nil {
+		return m.cancelFn(ctx, id, cancelledBy, reason)
+	}
+	return nil
+}
+
+// ── Mock: waitlistService ──
+
+type mockWaitlistSvc struct {
+	getFn    func(ctx context.Context, id string) (*model.WaitlistEntry, error)
+	cancelFn func(ctx context.Context, sub policy.Subject, id string) error
+}
+
+func (m *mockWaitlistSvc) GetByID(ctx context.Context, id string) (*model.WaitlistEntry, error) {
+	if m.getFn != nil {
+		return m.getFn(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockWaitlistSvc) GetFor(ctx context.Context, sub policy.Subject, id string) (*model.WaitlistEntry, error) {
+	e, err := m.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if e == nil {
+		return nil, apperror.ErrNotFound
+	}
+	if sub.Role != "" && !policy.CanWaitlist(sub, policy.WaitlistRead, policy.WaitlistEntry{RequesterID: e.RequesterID}).Allowed {
+		return nil, apperror.ErrForbidden
+	}
+	return e, nil
+}
+
+func (m *mockWaitlistSvc) List(ctx context.Context, requesterID, status string, depotIDs []string, limit, offset int) ([]model.WaitlistEntry, error) {
+	return []model.WaitlistEntry{}, nil
+}
+
+func (m *mockWaitlistSvc) Cancel(ctx context.Context, sub policy.Subject, id string) error {
+	if m.cancelFn != nil {
+		return m.cancelFn(ctx, sub, id)
+	}
+	return nil
+}
+
+// ── Mock: calendarService ──
+
+type mockCalendarSvc struct {
+	createFeedFn func(ctx context.Context, sub policy.Subject, feedType model.CalendarFeedType, subjectID string) (*model.CalendarFeed, error)
+	revokeFeedFn func(ctx context.Context, sub policy.Subject, id string) error
+	feedFn       func(ctx context.Context, token string) (*ical.Calendar, error)
+}
+
+func (m *mockCalendarSvc) CreateFeed(ctx context.Context, sub policy.Subject, feedType model.CalendarFeedType, subjectID string) (*model.CalendarFeed, error) {
+	if m.createFeedFn != nil {
+		return m.createFeedFn(ctx, sub, feedType, subjectID)
+	}
+	return &model.CalendarFeed{}, nil
+}
+
+func (m *mockCalendarSvc) ListFeeds(ctx context.Context, userID string) ([]model.CalendarFeed, error) {
+	return []model.CalendarFeed{}, nil
+}
+
+func (m *mockCalendarSvc) RevokeFeed(ctx context.Context, sub policy.Subject, id string) error {
+	if m.revokeFeedFn != nil {
+		return m.revokeFeedFn(ctx, sub, id)
+	}
+	return nil
+}
+
+func (m *mockCalendarSvc) Feed(ctx context.Context, token string) (*ical.Calendar, error) {
+	if m.feedFn != nil {
+		return m.feedFn(ctx, token)
+	}
+	return nil, nil
+}
+
+// ── Mock: conflictService ──
+
+type mockConflictSvc struct {
+	listPendingFn     func(ctx context.Context, depotIDs []string) ([]model.ReservationConflict, error)
+	getByIDFn         func(ctx context.Context, id string, depotIDs []string) (*model.ReservationConflict, error)
+	resolveReassignFn func(ctx context.Context, conflictID, newVehicleID, resolvedBy, reason string, depotIDs []string) error
+	suggestionsFn     func(ctx context.Context, conflictID string, depotIDs []string) (*dto.ConflictSuggestions, error)
+}
+
+func (m *mockConflictSvc) ListPending(ctx context.Context, depotIDs []string) ([]model.ReservationConflict, error) {
+	if m.listPendingFn != nil {
+		return m.listPendingFn(ctx, depotIDs)
+	}
+	return []model.ReservationConflict{}, nil
+}
+
+func (m *mockConflictSvc) GetByID(ctx context.Context, id string, depotIDs []string) (*model.ReservationConflict, error) {
+	if m.getByIDFn != nil {
+		return m.getByIDFn(ctx, id, depotIDs)
+	}
+	return nil, nil
+}
+
+func (m *mockConflictSvc) ResolveReassign(ctx context.Context, conflictID, newVehicleID, resolvedBy, reason string, depotIDs []string) error {
+	if m.resolveReassignFn != nil {
+		return m.resolveReassignFn(ctx, conflictID, newVehicleID, resolvedBy, reason, depotIDs)
+	}
+	return nil
+}
+
+func (m *mockConflictSvc) ResolveChangeTime(ctx context.Context, conflictID, resolvedBy, reason string, losingRes *model.Reservation, depotIDs []string) error {
+	return nil
+}
+
+func (m *mockConflictSvc) ResolveCancel(ctx context.Context, conflictID, resolvedBy, reason string, depotIDs []string) error {
+	return nil
+}
+
+func (m *mockConflictSvc) ForceAssign(ctx context.Context, conflictID, resolvedBy, reason string, depotIDs []string) error {
+	return nil
+}
+
+func (m *mockConflictSvc) Suggestions(ctx context.Context, conflictID string, depotIDs []string) (*dto.ConflictSuggestions, error) {
+	if m.suggestionsFn != nil {
+		return m.suggestionsFn(ctx, conflictID, depotIDs)
+	}
+	return &dto.ConflictSuggestions{}, nil
+}
+
+// ── Mock: maintenanceService ──
+
+type mockMaintenanceSvc struct {
+	getByIDFn        func(ctx context.Context, id string) (*model.MaintenanceWindow, error)
+	createWindowFn   func(ctx context.Context, actorID, vehicleID string, req dto.CreateMaintenanceWindowRequest) (*model.MaintenanceWindow, error)
+	completeWindowFn func(ctx context.Context, actorID, id string, odometerKm *int) (*model.MaintenanceWindow, error)
+	cancelWindowFn   func(ctx context.Context, actorID, id, reason string) error
+}
+
+func (m *mockMaintenanceSvc) List(ctx context.Context, vehicleID, status string, from, to time.Time, limit, offset int) ([]model.MaintenanceWindow, error) {
+	return []model.MaintenanceWindow{}, nil
+}
+
+func (m *mockMaintenanceSvc) GetByID(ctx context.Context, id string) (*model.MaintenanceWindow, error) {
+	if m.getByIDFn != nil {
+		return m.getByIDFn(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockMaintenanceSvc) CreateWindow(ctx context.Context, actorID, vehicleID string, req dto.CreateMaintenanceWindowRequest) (*model.MaintenanceWindow, error) {
+	if m.createWindowFn != nil {
+		return m.createWindowFn(ctx, actorID, vehicleID, req)
+	}
+	return &model.MaintenanceWindow{}, nil
+}
+
+func (m *mockMaintenanceSvc) UpdateWindow(ctx context.Context, actorID, id string, req dto.UpdateMaintenanceWindowRequest) (*model.MaintenanceWindow, error) {
+	return &model.MaintenanceWindow{}, nil
+}
+
+func (m *mockMaintenanceSvc) StartWindow(ctx context.Context, actorID, id string) (*model.MaintenanceWindow, error) {
+	return &model.MaintenanceWindow{}, nil
+}
+
+func (m *mockMaintenanceSvc) CompleteWindow(ctx context.Context, actorID, id string, odometerKm *int) (*model.MaintenanceWindow, error) {
+	if m.completeWindowFn != nil {
+		return m.completeWindowFn(ctx, actorID, id, odometerKm)
+	}
+	return &model.MaintenanceWindow{}, nil
+}
+
+func (m *mockMaintenanceSvc) CancelWindow(ctx context.Context, actorID, id, reason string) error {
+	if m.cancelWindowFn != nil {
+		return m.cancelWindowFn(ctx, actorID, id, reason)
+	}
+	return nil
+}
+
+func (m *mockMaintenanceSvc) CreateSchedule(ctx context.Context, actorID, vehicleID string, req dto.CreateMaintenanceScheduleRequest) (*model.MaintenanceSchedule, error) {
+	return &model.MaintenanceSchedule{}, nil
+}
+
+func (m *mockMaintenanceSvc) ListSchedules(ctx context.Context, vehicleID string) ([]model.MaintenanceSchedule, error) {
+	return []model.MaintenanceSchedule{}, nil
+}
+
+func (m *mockMaintenanceSvc) DeleteSchedule(ctx context.Context, actorID, id string) error {
+	return nil
+}
+
+// ── Mock: mileageService ──
+
+type mockMileageSvc struct {
+	createFuelLogFn func(context.Context, string, dto.CreateFuelLogRequest) (*model.FuelLog, error)
+	getFuelLogFn    func(context.Context, string) (*model.FuelLog, error)
+	mileageFn       func(context.Context, string, time.Time, time.Time) (*dto.VehicleMileage, error)
+}
+
+func (m *mockMileageSvc) CreateFuelLog(ctx context.Context, driverID string, req dto.CreateFuelLogRequest) (*model.FuelLog, error) {
+	if m.createFuelLogFn != nil {
+		return m.createFuelLogFn(ctx, driverID, req)
+	}
+	return &model.FuelLog{}, nil
+}
+
+func (m *mockMileageSvc) GetFuelLog(ctx context.Context, id string) (*model.FuelLog, error) {
+	if m.getFuelLogFn != nil {
+		return m.getFuelLogFn(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockMileageSvc) SetFuelReceipt(ctx context.Context, id string, receiptURL *string) error {
+	return nil
+}
+
+func (m *mockMileageSvc) ListFuelLogs(ctx context.Context, vehicleID string, from, to time.Time) ([]model.FuelLog, error) {
+	return []model.FuelLog{}, nil
+}
+
+func (m *mockMileageSvc) Mileage(ctx context.Context, vehicleID string, from, to time.Time) (*dto.VehicleMileage, error) {
+	if m.mileageFn != nil {
+		return m.mileageFn(ctx, vehicleID, from, to)
+	}
+	return &dto.VehicleMileage{}, nil
+}
+
+// ── Mock: documentService ──
+
+type mockDocumentSvc struct {
+	listFn             func(context.Context, string, string, string, *int, int, int) ([]model.Document, error)
+	getByIDFn          func(context.Context, string) (*model.Document, error)
+	createForVehicleFn func(context.Context, string, string, dto.CreateDocumentRequest) (*model.Document, error)
+	setScanFn          func(context.Context, string, string, *string) error
+}
+
+func (m *mockDocumentSvc) List(ctx context.Context, vehicleID, userID, docType string, expiringWithin *int, limit, offset int) ([]model.Document, error) {
+	if m.listFn != nil {
+		return m.listFn(ctx, vehicleID, userID, docType, expiringWithin, limit, offset)
+	}
+	return []model.Document{}, nil
+}
+
+func (m *mockDocumentSvc) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	if m.getByIDFn != nil {
+		return m.getByIDFn(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockDocumentSvc) CreateForVehicle(ctx context.Context, actorID, vehicleID string, req dto.CreateDocumentRequest) (*model.Document, error) {
+	if m.createForVehicleFn != nil {
+		return m.createForVehicleFn(ctx, actorID, vehicleID, req)
+	}
+	return &model.Document{}, nil
+}
+
+func (m *mockDocumentSvc) CreateForUser(ctx context.Context, actorID, userID string, req dto.CreateDocumentRequest) (*model.Document, error) {
+	return &model.Document{}, nil
+}
+
+func (m *mockDocumentSvc) Update(ctx context.Context, actorID, id string, req dto.UpdateDocumentRequest) (*model.Document, error) {
+	return &model.Document{}, nil
+}
+
+func (m *mockDocumentSvc) SetScan(ctx context.Context, actorID, id string, scanFile *string) error {
+	if m.setScanFn != nil {
+		return m.setScanFn(ctx, actorID, id, scanFile)
+	}
+	return nil
+}
+
+func (m *mockDocumentSvc) Delete(ctx context.Context, actorID, id string) error {
+	return nil
+}
+
+// ── Mock: depotService ──
+
+type mockDepotSvc struct {
+	listFn          func(ctx context.Context, depotIDs []string) ([]model.Depot, error)
+	getByIDFn       func(ctx context.Context, id string) (*model.Depot, error)
+	createFn        func(ctx context.Context, actorID string, req dto.CreateDepotRequest) (*model.Depot, error)
+	setUserDepotsFn func(ctx context.Context, actorID, userID string, depotIDs []string) ([]string, error)
+}
+
+func (m *mockDepotSvc) List(ctx context.Context, depotIDs []string) ([]model.Depot, error) {
+	if m.listFn != nil {
+		return m.listFn(ctx, depotIDs)
+	}
+	return []model.Depot{}, nil
+}
+
+func (m *mockDepotSvc) GetByID(ctx context.Context, id string) (*model.Depot, error) {
+	if m.getByIDFn != nil {
+		return m.getByIDFn(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockDepotSvc) Create(ctx context.Context, actorID string, req dto.CreateDepotRequest) (*model.Depot, error) {
+	if m.createFn != nil {
+		return m.createFn(ctx, actorID, req)
+	}
+	return &model.Depot{}, nil
+}
+
+func (m *mockDepotSvc) Update(ctx context.Context, actorID, id string, req dto.UpdateDepotRequest) (*model.Depot, error) {
+	return &model.Depot{}, nil
+}
+
+func (m *mockDepotSvc) Delete(ctx context.Context, actorID, id string) error {
+	return nil
+}
+
+func (m *mockDepotSvc) DepotIDsOf(ctx context.Context, userID string) ([]string, error) {
+	return []string{}, nil
+}
+
+func (m *mockDepotSvc) SetUserDepots(ctx context.Context, actorID, userID string, depotIDs []string) ([]string, error) {
+	if m.setUserDepotsFn != nil {
+		return m.setUserDepotsFn(ctx, actorID, userID, depotIDs)
+	}
+	return depotIDs, nil
+}
+
+// ── Mock: reservationService ──
+
+type mockReservationSvc struct {
+	getByIDFn func(ctx context.Context, id string) (*model.Reservation, error)
+	cancelFn  func(ctx context.Context, id, cancelledBy, reason string) error
+	updateFn  func(ctx context.Context, id string, req dto.UpdateReservationRequest, actorID string) (*model.Reservation, error)
+	createFn  func(ctx context.Context, req dto.CreateReservationRequest, requesterID string, priorityLevel int) (*model.Reservation, error)
+}
+
+func (m *mockReservationSvc) Create(ctx context.Context, req dto.CreateReservationRequest, requesterID string, priorityLevel int) (*model.Reservation, error) {
+	if m.createFn != nil {
+		return m.createFn(ctx, req, requesterID, priorityLevel)
+	}
+	return &model.Reservation{}, nil
+}
+
+func (m *mockReservationSvc) GetByID(ctx context.Context, id string) (*model.Reservation, error) {
+	if m.getByIDFn != nil {
+		return m.getByIDFn(ctx, id)
+	}
+	return nil, nil
+}
+
+func (m *mockReservationSvc) List(ctx context.Context, vehicleID string, from, to time.Time, status string, depotIDs []string, limit, offset int) ([]model.ReservationWithDetails, error) {
+	return []model.ReservationWithDetails{}, nil
+}
+
+func (m *mockReservationSvc) Cancel(ctx context.Context, id, cancelledBy, reason string) error {
+	if m.cancelFn != nil {
+		return m.cancelFn(ctx, id, cancelledBy, reason)
+	}
+	return nil
+}
+
+func (m *mockReservationSvc) Update(ctx context.Context, id string, req dto.UpdateReservationRequest, actorID string) (*model.Reservation, error) {
+	if m.updateFn != nil {
+		return m.updateFn(ctx, id, req, actorID)
+	}
+	return &model.Reservation{ID: id}, nil
+}
+
+func (m *mockReservationSvc) CheckAvailability(ctx context.Context, vehicleID string, startTime, endTime time.Time) ([]model.Reservation, error) {
+	return []model.Reservation{}, nil
+}
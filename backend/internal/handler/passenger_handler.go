@@ -8,6 +8,8 @@ import (
 	"github.com/go-chi/chi/v5"
 	"github.com/kento/driver/backend/internal/dto"
 	"github.com/kento/driver/backend/internal/middleware"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/policy"
 	"github.com/kento/driver/backend/pkg/apperror"
 )
 
@@ -129,7 +131,7 @@ func (h *PassengerHandler) GetNearbyVehicles(w http.ResponseWriter, r *http.Requ
 		return
 	}
 
-	vehicles, err := h.dispatchSvc.CalculateETAs(r.Context(), req.PickupLat, req.PickupLng)
+	vehicles, err := h.dispatchSvc.CalculateETAs(r.Context(), req.PickupLat, req.PickupLng, nil)
 	if err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
@@ -189,7 +191,7 @@ func (h *PassengerHandler) RequestRide(w http.ResponseWriter, r *http.Request) {
 		Destinations:  destinations,
 	}
 
-	resp, err := h.bookingSvc.CreateBooking(r.Context(), bookingReq, claims.UserID, 0)
+	resp, err := h.bookingSvc.CreateBooking(r.Context(), bookingReq, claims.UserID, 0, nil)
 	if err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
@@ -266,25 +268,8 @@ func (h *PassengerHandler) CancelRide(w http.ResponseWriter, r *http.Request) {
 
 	dispatchID := chi.URLParam(r, "id")
 
-	// Verify this dispatch belongs to the passenger
-	dispatch, err := h.dispatchSvc.GetByID(r.Context(), dispatchID)
-	if err != nil || dispatch == nil {
-		apperror.WriteError(w, apperror.ErrNotFound)
-		return
-	}
-
-	if dispatch.RequesterID != claims.UserID {
-		apperror.WriteError(w, apperror.ErrForbidden)
-		return
-	}
-
-	// Only allow cancellation for pending/assigned status
-	if dispatch.Status != "pending" && dispatch.Status != "assigned" {
-		apperror.WriteErrorMsg(w, 400, "CANNOT_CANCEL", "ride can only be cancelled when pending or assigned")
-		return
-	}
-
-	if err := h.dispatchSvc.Cancel(r.Context(), dispatchID, "cancelled by passenger", claims.UserID); err != nil {
+	// The state machine only lets passengers cancel before the driver accepts
+	if err := h.dispatchSvc.Cancel(r.Context(), dispatchID, "cancelled by passenger", model.DispatchActorRequester, claims.UserID); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
 			return
@@ -305,14 +290,13 @@ func (h *PassengerHandler) GetDriverLocation(w http.ResponseWriter, r *http.Requ
 
 	dispatchID := chi.URLParam(r, "id")
 
-	dispatch, err := h.dispatchSvc.GetByID(r.Context(), dispatchID)
-	if err != nil || dispatch == nil {
-		apperror.WriteError(w, apperror.ErrNotFound)
-		return
-	}
-
-	if dispatch.RequesterID != claims.UserID {
-		apperror.WriteError(w, apperror.ErrForbidden)
+	dispatch, err := h.dispatchSvc.GetFor(r.Context(), subjectOf(r), policy.DispatchTrack, dispatchID)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
 		return
 	}
 
@@ -348,14 +332,12 @@ func (h *PassengerHandler) RateRide(w http.ResponseWriter, r *http.Request) {
 
 	dispatchID := chi.URLParam(r, "id")
 
-	dispatch, err := h.dispatchSvc.GetByID(r.Context(), dispatchID)
-	if err != nil || dispatch == nil {
-		apperror.WriteError(w, apperror.ErrNotFound)
-		return
-	}
-
-	if dispatch.RequesterID != claims.UserID {
-		apperror.WriteError(w, apperror.ErrForbidden)
+	if _, err := h.dispatchSvc.GetFor(r.Context(), subjectOf(r), policy.DispatchRate, dispatchID); err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
 		return
 	}
 
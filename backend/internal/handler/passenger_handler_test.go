@@ -154,7 +154,7 @@ func TestPassenger_GetRideHistory_InvalidLimit(t *testing.T) {
 
 func TestPassenger_GetNearbyVehicles_Success(t *testing.T) {
 	h := NewPassengerHandler(&mockPassengerAuthSvc{}, &mockDispatchSvc{
-		calculateETAsFn: func(_ context.Context, lat, lng float64) ([]dto.VehicleETA, error) {
+		calculateETAsFn: func(_ context.Context, lat, lng float64, _ []string) ([]dto.VehicleETA, error) {
 			return []dto.VehicleETA{
 				{VehicleID: "v-1", VehicleName: "Car A", DriverName: "Driver 1", DurationSec: 300, IsAvailable: true},
 			}, nil
@@ -211,7 +211,7 @@ func TestPassenger_GetNearbyVehicles_NoClaims(t *testing.T) {
 func TestPassenger_RequestRide_WithVehicleID(t *testing.T) {
 	var capturedReq dto.UnifiedBookingRequest
 	h := NewPassengerHandler(&mockPassengerAuthSvc{}, &mockDispatchSvc{}, &mockLocationSvc{}, &mockBookingSvc{
-		createBookingFn: func(_ context.Context, req dto.UnifiedBookingRequest, _ string, _ int) (*dto.UnifiedBookingResponse, error) {
+		createBookingFn: func(_ context.Context, req dto.UnifiedBookingRequest, _ string, _ int, _ []string) (*dto.UnifiedBookingResponse, error) {
 			capturedReq = req
 			return &dto.UnifiedBookingResponse{Type: "dispatch", Dispatch: &model.Dispatch{ID: "d1"}}, nil
 		},
@@ -238,7 +238,7 @@ func TestPassenger_RequestRide_WithVehicleID(t *testing.T) {
 func TestPassenger_RequestRide_WithoutVehicleID(t *testing.T) {
 	var capturedReq dto.UnifiedBookingRequest
 	h := NewPassengerHandler(&mockPassengerAuthSvc{}, &mockDispatchSvc{}, &mockLocationSvc{}, &mockBookingSvc{
-		createBookingFn: func(_ context.Context, req dto.UnifiedBookingRequest, _ string, _ int) (*dto.UnifiedBookingResponse, error) {
+		createBookingFn: func(_ context.Context, req dto.UnifiedBookingRequest, _ string, _ int, _ []string) (*dto.UnifiedBookingResponse, error) {
 			capturedReq = req
 			return &dto.UnifiedBookingResponse{Type: "dispatch"}, nil
 		},
@@ -14,10 +14,11 @@ import (
 type ReservationHandler struct {
 	reservationSvc reservationService
 	userSvc        authService
+	vehicleSvc     vehicleService
 }
 
-func NewReservationHandler(reservationSvc reservationService, userSvc authService) *ReservationHandler {
-	return &ReservationHandler{reservationSvc: reservationSvc, userSvc: userSvc}
+func NewReservationHandler(reservationSvc reservationService, userSvc authService, vehicleSvc vehicleService) *ReservationHandler {
+	return &ReservationHandler{reservationSvc: reservationSvc, userSvc: userSvc, vehicleSvc: vehicleSvc}
 }
 
 func (h *ReservationHandler) Create(w http.ResponseWriter, r *http.Request) {
@@ -33,6 +34,9 @@ func (h *ReservationHandler) Create(w http.ResponseWriter, r *http.Request) {
 		apperror.WriteErrorMsg(w, 400, "VALIDATION_ERROR", "vehicle_id, start_time, end_time, and purpose are required")
 		return
 	}
+	if !h.vehicleInScope(w, r, req.VehicleID) {
+		return
+	}
 
 	user, err := h.userSvc.GetUser(r.Context(), claims.UserID)
 	if err != nil || user == nil {
@@ -78,7 +82,7 @@ func (h *ReservationHandler) List(w http.ResponseWriter, r *http.Request) {
 		limit = 50
 	}
 
-	reservations, err := h.reservationSvc.List(r.Context(), vehicleID, from, to, status, limit, offset)
+	reservations, err := h.reservationSvc.List(r.Context(), vehicleID, from, to, status, middleware.GetDepotIDs(r.Context()), limit, offset)
 	if err != nil {
 		apperror.WriteError(w, apperror.ErrInternal)
 		return
@@ -99,6 +103,15 @@ func (h *ReservationHandler) Get(w http.ResponseWriter, r *http.Request) {
 		apperror.WriteError(w, apperror.ErrNotFound)
 		return
 	}
+	ok, err := vehicleInDepots(r, h.vehicleSvc, reservation.VehicleID)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	if !ok {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
 
 	apperror.WriteSuccess(w, reservation)
 }
@@ -113,6 +126,13 @@ func (h *ReservationHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.reservationInScope(w, r, id) {
+		return
+	}
+	if req.VehicleID != nil && !h.vehicleInScope(w, r, *req.VehicleID) {
+		return
+	}
+
 	reservation, err := h.reservationSvc.Update(r.Context(), id, req, claims.UserID)
 	if err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
@@ -136,6 +156,10 @@ func (h *ReservationHandler) Cancel(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if !h.reservationInScope(w, r, id) {
+		return
+	}
+
 	if err := h.reservationSvc.Cancel(r.Context(), id, claims.UserID, req.Reason); err != nil {
 		if appErr, ok := err.(*apperror.AppError); ok {
 			apperror.WriteError(w, appErr)
@@ -177,3 +201,35 @@ func (h *ReservationHandler) CheckAvailability(w http.ResponseWriter, r *http.Re
 		"conflict_count": len(overlaps),
 	})
 }
+
+// vehicleInScope refuses with 403 when the vehicle belongs to a depot the
+// caller does not dispatch for. Unknown vehicles are left to the service.
+func (h *ReservationHandler) vehicleInScope(w http.ResponseWriter, r *http.Request, vehicleID string) bool {
+	ok, err := vehicleInDepots(r, h.vehicleSvc, vehicleID)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return false
+	}
+	if !ok {
+		apperror.WriteErrorMsg(w, 403, "OUT_OF_DEPOT", "the vehicle belongs to a depot you do not dispatch for")
+		return false
+	}
+	return true
+}
+
+// reservationInScope is vehicleInScope for the vehicle of reservation id.
+// Unknown reservations are left to the service.
+func (h *ReservationHandler) reservationInScope(w http.ResponseWriter, r *http.Request, id string) bool {
+	if middleware.GetDepotIDs(r.Context()) == nil {
+		return true
+	}
+	res, err := h.reservationSvc.GetByID(r.Context(), id)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return false
+	}
+	if res == nil {
+		return true
+	}
+	return h.vehicleInScope(w, r, res.VehicleID)
+}
@@ -0,0 +1,107 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+)
+
+// depotVehicles serves every vehicle as belonging to the depot of its ID.
+func depotVehicles() *mockVehicleSvc {
+	return &mockVehicleSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Vehicle, error) {
+			depot := "depot-" + strings.TrimPrefix(id, "v-")
+			return &model.Vehicle{ID: id, DepotID: &depot}, nil
+		},
+	}
+}
+
+func northReservation(_ context.Context, id string) (*model.Reservation, error) {
+	return &model.Reservation{ID: id, VehicleID: "v-north"}, nil
+}
+
+func TestReservation_Get_OutOfDepot(t *testing.T) {
+	svc := &mockReservationSvc{getByIDFn: northReservation}
+	h := NewReservationHandler(svc, &mockAuthSvc{}, depotVehicles())
+	req := withDepots(withChiParam(httptest.NewRequest("GET", "/", nil), "id", "r-1"), "depot-south")
+	rec := httptest.NewRecorder()
+
+	h.Get(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestReservation_Cancel_OutOfDepot(t *testing.T) {
+	svc := &mockReservationSvc{
+		getByIDFn: northReservation,
+		cancelFn: func(context.Context, string, string, string) error {
+			t.Error("Cancel called for a reservation of another depot")
+			return nil
+		},
+	}
+	h := NewReservationHandler(svc, &mockAuthSvc{}, depotVehicles())
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"reason":"no longer needed"}`))
+	req = withDepots(withChiParam(withClaims(req, "u-1", "dispatch001", "dispatcher"), "id", "r-1"), "depot-south")
+	rec := httptest.NewRecorder()
+
+	h.Cancel(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if code := decodeError(t, rec); code != "OUT_OF_DEPOT" {
+		t.Errorf("code = %q, want OUT_OF_DEPOT", code)
+	}
+}
+
+func TestReservation_Update_ToVehicleOutOfDepot(t *testing.T) {
+	svc := &mockReservationSvc{
+		getByIDFn: func(_ context.Context, id string) (*model.Reservation, error) {
+			return &model.Reservation{ID: id, VehicleID: "v-south"}, nil
+		},
+		updateFn: func(context.Context, string, dto.UpdateReservationRequest, string) (*model.Reservation, error) {
+			t.Error("Update moved a reservation to a vehicle of another depot")
+			return nil, nil
+		},
+	}
+	h := NewReservationHandler(svc, &mockAuthSvc{}, depotVehicles())
+	req := httptest.NewRequest("PUT", "/", strings.NewReader(`{"vehicle_id":"v-north"}`))
+	req = withDepots(withChiParam(withClaims(req, "u-1", "dispatch001", "dispatcher"), "id", "r-1"), "depot-south")
+	rec := httptest.NewRecorder()
+
+	h.Update(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestReservation_Create_VehicleOutOfDepot(t *testing.T) {
+	svc := &mockReservationSvc{
+		createFn: func(context.Context, dto.CreateReservationRequest, string, int) (*model.Reservation, error) {
+			t.Error("Create booked a vehicle of another depot")
+			return nil, nil
+		},
+	}
+	h := NewReservationHandler(svc, &mockAuthSvc{}, depotVehicles())
+	body := `{"vehicle_id":"v-north","start_time":"2026-01-01T08:00:00Z","end_time":"2026-01-01T10:00:00Z","purpose":"delivery"}`
+	req := httptest.NewRequest("POST", "/", strings.NewReader(body))
+	req = withDepots(withClaims(req, "u-1", "dispatch001", "dispatcher"), "depot-south")
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if code := decodeError(t, rec); code != "OUT_OF_DEPOT" {
+		t.Errorf("code = %q, want OUT_OF_DEPOT", code)
+	}
+}
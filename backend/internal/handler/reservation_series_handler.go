@@ -0,0 +1,193 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/middleware"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+type ReservationSeriesHandler struct {
+	seriesSvc  reservationSeriesService
+	userSvc    authService
+	vehicleSvc vehicleService
+}
+
+func NewReservationSeriesHandler(seriesSvc reservationSeriesService, userSvc authService, vehicleSvc vehicleService) *ReservationSeriesHandler {
+	return &ReservationSeriesHandler{seriesSvc: seriesSvc, userSvc: userSvc, vehicleSvc: vehicleSvc}
+}
+
+func (h *ReservationSeriesHandler) Create(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.CreateReservationSeriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	if req.VehicleID == "" || req.Purpose == "" || req.RRule == "" || req.StartTime.IsZero() || req.EndTime.IsZero() {
+		apperror.WriteErrorMsg(w, 400, "VALIDATION_ERROR", "vehicle_id, start_time, end_time, rrule, and purpose are required")
+		return
+	}
+	if !h.vehicleInScope(w, r, req.VehicleID) {
+		return
+	}
+
+	user, err := h.userSvc.GetUser(r.Context(), claims.UserID)
+	if err != nil || user == nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	series, err := h.seriesSvc.Create(r.Context(), req, claims.UserID, user.PriorityLevel)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteCreated(w, series)
+}
+
+func (h *ReservationSeriesHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseIntParam(w, r, "limit", 0)
+	if !ok {
+		return
+	}
+	offset, ok := parseIntParam(w, r, "offset", 0)
+	if !ok {
+		return
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	depotIDs := middleware.GetDepotIDs(r.Context())
+	series, err := h.seriesSvc.List(r.Context(), r.URL.Query().Get("requester_id"), r.URL.Query().Get("status"), depotIDs, limit, offset)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, series)
+}
+
+func (h *ReservationSeriesHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	series, err := h.seriesSvc.GetByID(r.Context(), id)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	if series == nil {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+	ok, err := vehicleInDepots(r, h.vehicleSvc, series.VehicleID)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	if !ok {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+
+	apperror.WriteSuccess(w, series)
+}
+
+func (h *ReservationSeriesHandler) Update(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.UpdateReservationSeriesRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	if !h.seriesInScope(w, r, id) {
+		return
+	}
+	if req.VehicleID != nil && !h.vehicleInScope(w, r, *req.VehicleID) {
+		return
+	}
+
+	series, err := h.seriesSvc.Update(r.Context(), id, req, claims.UserID)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, series)
+}
+
+func (h *ReservationSeriesHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.CancelReservationRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	if !h.seriesInScope(w, r, id) {
+		return
+	}
+
+	if err := h.seriesSvc.Cancel(r.Context(), id, claims.UserID, req.Reason); err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// vehicleInScope answers 403 OUT_OF_DEPOT when vehicleID lies outside the
+// caller's depots.
+func (h *ReservationSeriesHandler) vehicleInScope(w http.ResponseWriter, r *http.Request, vehicleID string) bool {
+	ok, err := vehicleInDepots(r, h.vehicleSvc, vehicleID)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return false
+	}
+	if !ok {
+		apperror.WriteErrorMsg(w, 403, "OUT_OF_DEPOT", "the vehicle belongs to a depot you do not dispatch for")
+		return false
+	}
+	return true
+}
+
+// seriesInScope is vehicleInScope for the vehicle of series id. Unknown
+// series are left to the service.
+func (h *ReservationSeriesHandler) seriesInScope(w http.ResponseWriter, r *http.Request, id string) bool {
+	if middleware.GetDepotIDs(r.Context()) == nil {
+		return true
+	}
+	series, err := h.seriesSvc.GetByID(r.Context(), id)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return false
+	}
+	if series == nil {
+		return true
+	}
+	return h.vehicleInScope(w, r, series.VehicleID)
+}
@@ -0,0 +1,197 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+func seriesUser() *mockAuthSvc {
+	return &mockAuthSvc{
+		getUserFn: func(_ context.Context, id string) (*model.User, error) {
+			return &model.User{ID: id, PriorityLevel: 3}, nil
+		},
+	}
+}
+
+func TestSeries_Create_MissingRRule(t *testing.T) {
+	h := NewReservationSeriesHandler(&mockSeriesSvc{}, seriesUser(), depotVehicles())
+	body := `{"vehicle_id":"v-1","purpose":"exec","start_time":"2026-11-02T08:00:00+08:00","end_time":"2026-11-02T09:00:00+08:00"}`
+	req := withClaims(httptest.NewRequest("POST", "/", strings.NewReader(body)), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestSeries_Create_PassesPriority(t *testing.T) {
+	var gotRule string
+	var gotPriority int
+	svc := &mockSeriesSvc{
+		createFn: func(_ context.Context, req dto.CreateReservationSeriesRequest, _ string, priority int) (*dto.ReservationSeriesDetail, error) {
+			gotRule, gotPriority = req.RRule, priority
+			return &dto.ReservationSeriesDetail{}, nil
+		},
+	}
+	h := NewReservationSeriesHandler(svc, seriesUser(), depotVehicles())
+	body := `{"vehicle_id":"v-1","purpose":"exec","rrule":"FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR",
+		"start_time":"2026-11-02T08:00:00+08:00","end_time":"2026-11-02T09:00:00+08:00","timezone":"Asia/Manila"}`
+	req := withClaims(httptest.NewRequest("POST", "/", strings.NewReader(body)), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusCreated)
+	}
+	if gotRule != "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR" || gotPriority != 3 {
+		t.Errorf("Create(rrule %q, priority %d)", gotRule, gotPriority)
+	}
+}
+
+func TestSeries_Create_InvalidRRule(t *testing.T) {
+	svc := &mockSeriesSvc{
+		createFn: func(context.Context, dto.CreateReservationSeriesRequest, string, int) (*dto.ReservationSeriesDetail, error) {
+			return nil, apperror.New(400, "INVALID_RRULE", "rrule: unsupported FREQ \"YEARLY\"")
+		},
+	}
+	h := NewReservationSeriesHandler(svc, seriesUser(), depotVehicles())
+	body := `{"vehicle_id":"v-1","purpose":"exec","rrule":"FREQ=YEARLY",
+		"start_time":"2026-11-02T08:00:00+08:00","end_time":"2026-11-02T09:00:00+08:00"}`
+	req := withClaims(httptest.NewRequest("POST", "/", strings.NewReader(body)), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if code := decodeError(t, rec); code != "INVALID_RRULE" {
+		t.Errorf("code = %q, want INVALID_RRULE", code)
+	}
+}
+
+func TestSeries_Get_NotFound(t *testing.T) {
+	h := NewReservationSeriesHandler(&mockSeriesSvc{}, seriesUser(), depotVehicles())
+	req := withChiParam(httptest.NewRequest("GET", "/", nil), "id", "missing")
+	rec := httptest.NewRecorder()
+
+	h.Get(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestSeries_Cancel(t *testing.T) {
+	var gotID, gotBy, gotReason string
+	svc := &mockSeriesSvc{
+		cancelFn: func(_ context.Context, id, by, reason string) error {
+			gotID, gotBy, gotReason = id, by, reason
+			return nil
+		},
+	}
+	h := NewReservationSeriesHandler(svc, seriesUser(), depotVehicles())
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"reason":"exec left"}`))
+	req = withClaims(withChiParam(req, "id", "s-1"), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Cancel(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if gotID != "s-1" || gotBy != "u-1" || gotReason != "exec left" {
+		t.Errorf("Cancel(%q, %q, %q)", gotID, gotBy, gotReason)
+	}
+}
+
+func northSeries(_ context.Context, id string) (*dto.ReservationSeriesDetail, error) {
+	return &dto.ReservationSeriesDetail{ReservationSeries: model.ReservationSeries{ID: id, VehicleID: "v-north"}}, nil
+}
+
+func TestSeries_Get_OutOfDepot(t *testing.T) {
+	h := NewReservationSeriesHandler(&mockSeriesSvc{getFn: northSeries}, seriesUser(), depotVehicles())
+	req := withDepots(withChiParam(httptest.NewRequest("GET", "/", nil), "id", "s-1"), "depot-south")
+	rec := httptest.NewRecorder()
+
+	h.Get(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestSeries_Create_VehicleOutOfDepot(t *testing.T) {
+	svc := &mockSeriesSvc{
+		createFn: func(context.Context, dto.CreateReservationSeriesRequest, string, int) (*dto.ReservationSeriesDetail, error) {
+			t.Error("Create booked a vehicle of another depot")
+			return nil, nil
+		},
+	}
+	h := NewReservationSeriesHandler(svc, seriesUser(), depotVehicles())
+	body := `{"vehicle_id":"v-north","purpose":"exec","rrule":"FREQ=DAILY",
+		"start_time":"2026-11-02T08:00:00+08:00","end_time":"2026-11-02T09:00:00+08:00"}`
+	req := withClaims(httptest.NewRequest("POST", "/", strings.NewReader(body)), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Create(rec, withDepots(req, "depot-south"))
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if code := decodeError(t, rec); code != "OUT_OF_DEPOT" {
+		t.Errorf("code = %q, want OUT_OF_DEPOT", code)
+	}
+}
+
+func TestSeries_Update_ToVehicleOutOfDepot(t *testing.T) {
+	svc := &mockSeriesSvc{
+		getFn: func(_ context.Context, id string) (*dto.ReservationSeriesDetail, error) {
+			return &dto.ReservationSeriesDetail{ReservationSeries: model.ReservationSeries{ID: id, VehicleID: "v-south"}}, nil
+		},
+		updateFn: func(context.Context, string, dto.UpdateReservationSeriesRequest, string) (*dto.ReservationSeriesDetail, error) {
+			t.Error("Update moved a series to a vehicle of another depot")
+			return nil, nil
+		},
+	}
+	h := NewReservationSeriesHandler(svc, seriesUser(), depotVehicles())
+	req := httptest.NewRequest("PUT", "/", strings.NewReader(`{"vehicle_id":"v-north"}`))
+	req = withDepots(withClaims(withChiParam(req, "id", "s-1"), "u-1", "E1", "dispatcher"), "depot-south")
+	rec := httptest.NewRecorder()
+
+	h.Update(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestSeries_Cancel_OutOfDepot(t *testing.T) {
+	svc := &mockSeriesSvc{
+		getFn: northSeries,
+		cancelFn: func(context.Context, string, string, string) error {
+			t.Error("Cancel called for a series of another depot")
+			return nil
+		},
+	}
+	h := NewReservationSeriesHandler(svc, seriesUser(), depotVehicles())
+	req := httptest.NewRequest("POST", "/", strings.NewReader(`{"reason":"exec left"}`))
+	req = withDepots(withClaims(withChiParam(req, "id", "s-1"), "u-1", "E1", "dispatcher"), "depot-south")
+	rec := httptest.NewRecorder()
+
+	h.Cancel(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
@@ -0,0 +1,373 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/eta"
+	"github.com/kento/driver/backend/internal/middleware"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/policy"
+	"github.com/kento/driver/backend/internal/realtime"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+const (
+	streamBuffer    = 64
+	streamHeartbeat = 25 * time.Second
+
+	// A ride stream re-estimates its ETA on a location fix only once the
+	// last estimate is this old or the vehicle has moved this far since.
+	rideETAMaxAge  = 30 * time.Second
+	rideETAMinMove = 200 // metres
+)
+
+type StreamHandler struct {
+	hub         eventSubscriber
+	vehicleSvc  vehicleService
+	dispatchSvc dispatchService
+	locationSvc locationService
+	heartbeat   time.Duration
+}
+
+func NewStreamHandler(hub eventSubscriber, vehicleSvc vehicleService, dispatchSvc dispatchService, locationSvc locationService) *StreamHandler {
+	return &StreamHandler{
+		hub:         hub,
+		vehicleSvc:  vehicleSvc,
+		dispatchSvc: dispatchSvc,
+		locationSvc: locationSvc,
+		heartbeat:   streamHeartbeat,
+	}
+}
+
+// Fleet streams vehicle positions, computed-status changes and dispatch
+// lifecycle events as Server-Sent Events. The first event is a snapshot of
+// the fleet and active dispatches. Pass ?vehicle_id=a,b to follow specific
+// vehicles; without it the whole fleet is streamed. Dispatchers only get
+// the vehicles of their depots and shared ones, and the dispatches on them
+// or on no vehicle yet.
+func (h *StreamHandler) Fleet(w http.ResponseWriter, r *http.Request) {
+	vehicleIDs := parseIDSet(r.URL.Query().Get("vehicle_id"))
+	depotIDs := middleware.GetDepotIDs(r.Context())
+
+	var scope *depotScope
+	if depotIDs != nil {
+		// Seeded before subscribing, so events between the subscription and
+		// the snapshot are not dropped as unknown vehicles.
+		scoped, err := h.vehicleSvc.ListWithStatus(r.Context(), depotIDs)
+		if err != nil {
+			apperror.WriteError(w, apperror.ErrInternal)
+			return
+		}
+		scope = newDepotScope(depotIDs, scoped)
+	}
+
+	// Subscribe before reading the snapshot so no change falls in between.
+	sub := h.hub.Subscribe(streamBuffer, func(e realtime.Event) bool {
+		return (len(vehicleIDs) == 0 || vehicleIDs[e.VehicleID]) && scope.allows(e)
+	})
+	defer sub.Close()
+
+	vehicles, err := h.vehicleSvc.ListWithStatus(r.Context(), depotIDs)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	dispatches, err := h.dispatchSvc.ListActive(r.Context())
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	scope.add(vehicles)
+
+	snapshot := dto.FleetSnapshot{Vehicles: []model.VehicleWithStatus{}, Dispatches: []model.Dispatch{}}
+	for _, v := range vehicles {
+		if len(vehicleIDs) == 0 || vehicleIDs[v.ID] {
+			snapshot.Vehicles = append(snapshot.Vehicles, v)
+		}
+	}
+	for _, d := range dispatches {
+		if len(vehicleIDs) == 0 || (d.VehicleID != nil && vehicleIDs[*d.VehicleID]) {
+			if d.VehicleID == nil || scope.has(*d.VehicleID) {
+				snapshot.Dispatches = append(snapshot.Dispatches, d)
+			}
+		}
+	}
+
+	sse, err := newSSEWriter(w)
+	if err != nil {
+		return
+	}
+	if err := sse.Send(realtime.Event{Type: realtime.EventSnapshot, Data: snapshot, At: time.Now()}); err != nil {
+		return
+	}
+
+	h.pump(r, sse, sub)
+}
+
+// depotScope is the set of vehicles a depot-scoped fleet stream may see. It
+// follows vehicles created in, or transferred into or out of, its depots.
+// Hub filters run on the publishers' goroutines, hence the lock. A nil
+// depotScope allows everything.
+type depotScope struct {
+	depotIDs []string
+	mu       sync.Mutex
+	vehicles map[string]bool
+}
+
+func newDepotScope(depotIDs []string, vehicles []model.VehicleWithStatus) *depotScope {
+	s := &depotScope{depotIDs: depotIDs, vehicles: make(map[string]bool, len(vehicles))}
+	s.add(vehicles)
+	return s
+}
+
+func (s *depotScope) add(vehicles []model.VehicleWithStatus) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, v := range vehicles {
+		s.vehicles[v.ID] = true
+	}
+}
+
+func (s *depotScope) has(vehicleID string) bool {
+	if s == nil {
+		return true
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.vehicles[vehicleID]
+}
+
+// allows reports whether e concerns a vehicle in scope. Events on no
+// vehicle, such as pending dispatches, are allowed. A vehicle moved out of
+// scope still sends that update, so the client sees it leave.
+func (s *depotScope) allows(e realtime.Event) bool {
+	if s == nil {
+		return true
+	}
+	vehicleID := e.VehicleID
+	if a, ok := e.Data.(*model.DriverAttendance); ok && a.VehicleID != nil {
+		vehicleID = *a.VehicleID
+	}
+	if vehicleID == "" {
+		return true
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if v, ok := e.Data.(*model.Vehicle); ok && e.Type == realtime.EventVehicleUpdated {
+		was := s.vehicles[v.ID]
+		s.vehicles[v.ID] = model.InDepots(s.depotIDs, v.DepotID)
+		return was || s.vehicles[v.ID]
+	}
+	return s.vehicles[vehicleID]
+}
+
+// pump forwards subscription events until the client goes away or the
+// subscription is closed (slow consumer or server shutdown).
+func (h *StreamHandler) pump(r *http.Request, sse *sseWriter, sub *realtime.Subscription) {
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			if err := sse.Send(e); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if err := sse.Ping(); err != nil {
+				return
+			}
+		}
+	}
+}
+
+// Ride streams the caller's own dispatch: status transitions, the assigned
+// vehicle's position and a recomputed ETA to the next stop. Only the vehicle
+// currently assigned to this dispatch is ever streamed; if the dispatch is
+// reassigned the stream follows the new vehicle. The stream ends once the
+// ride is completed or cancelled.
+func (h *StreamHandler) Ride(w http.ResponseWriter, r *http.Request) {
+	claims := middleware.GetClaims(r.Context())
+	if claims == nil {
+		apperror.WriteError(w, apperror.ErrUnauthorized)
+		return
+	}
+	dispatchID := chi.URLParam(r, "id")
+
+	// The filter runs on the publisher's goroutine, so the followed vehicle
+	// is shared through an atomic.
+	var vehicleID atomic.Value
+	vehicleID.Store("")
+	sub := h.hub.Subscribe(streamBuffer, func(e realtime.Event) bool {
+		switch e.Type {
+		case realtime.EventDispatchUpdated:
+			return e.DispatchID == dispatchID
+		case realtime.EventVehicleLocation:
+			v := vehicleID.Load().(string)
+			return v != "" && e.VehicleID == v
+		}
+		return false
+	})
+	defer sub.Close()
+
+	dispatch, err := h.dispatchSvc.GetFor(r.Context(), subjectOf(r), policy.DispatchTrack, dispatchID)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	var location *model.LocationPoint
+	if dispatch.VehicleID != nil {
+		vehicleID.Store(*dispatch.VehicleID)
+		location = h.currentLocation(r.Context(), *dispatch.VehicleID)
+	}
+
+	sse, err := newSSEWriter(w)
+	if err != nil {
+		return
+	}
+	var lastETA rideETACache
+	snapshot := dto.RideUpdate{Dispatch: dispatch, Location: location, ETA: h.rideETA(r.Context(), dispatch, location, &lastETA)}
+	if err := sse.Send(realtime.Event{Type: realtime.EventSnapshot, DispatchID: dispatchID, Data: snapshot, At: time.Now()}); err != nil {
+		return
+	}
+	if rideEnded(dispatch) {
+		return
+	}
+
+	ticker := time.NewTicker(h.heartbeat)
+	defer ticker.Stop()
+
+	for {
+		var out realtime.Event
+		select {
+		case <-r.Context().Done():
+			return
+		case <-ticker.C:
+			if err := sse.Ping(); err != nil {
+				return
+			}
+			continue
+		case e, ok := <-sub.Events():
+			if !ok {
+				return
+			}
+			switch e.Type {
+			case realtime.EventDispatchUpdated:
+				d, ok := e.Data.(*model.Dispatch)
+				if !ok {
+					continue
+				}
+				dispatch = d
+				// The target or the vehicle may have changed
+				lastETA = rideETACache{}
+				next := ""
+				if d.VehicleID != nil {
+					next = *d.VehicleID
+				}
+				if next != vehicleID.Load().(string) {
+					vehicleID.Store(next)
+					location = nil
+					if next != "" {
+						location = h.currentLocation(r.Context(), next)
+					}
+				}
+				out = realtime.Event{Type: realtime.EventRideStatus, DispatchID: dispatchID, At: e.At,
+					Data: dto.RideUpdate{Dispatch: d, Location: location, ETA: h.rideETA(r.Context(), d, location, &lastETA)}}
+			case realtime.EventVehicleLocation:
+				p, ok := e.Data.(model.LocationPoint)
+				// Drop points from a vehicle that was unassigned while queued.
+				if !ok || e.VehicleID != vehicleID.Load().(string) {
+					continue
+				}
+				location = &p
+				out = realtime.Event{Type: realtime.EventRideLocation, DispatchID: dispatchID, At: e.At,
+					Data: dto.RideUpdate{Location: location, ETA: h.rideETA(r.Context(), dispatch, location, &lastETA)}}
+			default:
+				continue
+			}
+		}
+
+		if err := sse.Send(out); err != nil {
+			return
+		}
+		if rideEnded(dispatch) {
+			return
+		}
+	}
+}
+
+func (h *StreamHandler) currentLocation(ctx context.Context, vehicleID string) *model.LocationPoint {
+	cur, err := h.locationSvc.GetCurrent(ctx, vehicleID)
+	if err != nil || cur == nil {
+		return nil
+	}
+	return &model.LocationPoint{
+		Latitude:   cur.Latitude,
+		Longitude:  cur.Longitude,
+		Heading:    cur.Heading,
+		Speed:      cur.Speed,
+		Accuracy:   cur.Accuracy,
+		RecordedAt: cur.RecordedAt,
+	}
+}
+
+// rideETACache is the last ETA a ride stream estimated, and where and when.
+// The zero value holds nothing.
+type rideETACache struct {
+	eta  *dto.RideETA
+	from eta.Point
+	at   time.Time
+}
+
+// rideETA estimates the ride's ETA from loc. Drivers report their position
+// every few seconds and each estimate may run the whole provider chain, so
+// the cached one is reused until it is rideETAMaxAge old or the vehicle has
+// moved rideETAMinMove from where it was made.
+func (h *StreamHandler) rideETA(ctx context.Context, d *model.Dispatch, loc *model.LocationPoint, last *rideETACache) *dto.RideETA {
+	if loc == nil {
+		return nil
+	}
+	here := eta.Point{Lat: loc.Latitude, Lng: loc.Longitude}
+	now := time.Now()
+	if !last.at.IsZero() && now.Sub(last.at) < rideETAMaxAge && eta.Distance(last.from, here) < rideETAMinMove {
+		return last.eta
+	}
+	*last = rideETACache{eta: h.dispatchSvc.EstimateRideETA(ctx, d, here.Lat, here.Lng), from: here, at: now}
+	return last.eta
+}
+
+func rideEnded(d *model.Dispatch) bool {
+	return d.Status == model.DispatchStatusCompleted || d.Status == model.DispatchStatusCancelled
+}
+
+// parseIDSet splits a comma-separated query value into a lookup set.
+func parseIDSet(raw string) map[string]bool {
+	set := make(map[string]bool)
+	for _, id := range strings.Split(raw, ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			set[id] = true
+		}
+	}
+	return set
+}
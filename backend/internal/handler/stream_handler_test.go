@@ -0,0 +1,310 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/realtime"
+)
+
+// readSSE returns the event name and data of the next event on the stream,
+// skipping comment lines.
+func readSSE(t *testing.T, r *bufio.Reader) (string, string) {
+	t.Helper()
+	var name, data string
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read stream: %v", err)
+		}
+		line = strings.TrimRight(line, "\n")
+		switch {
+		case line == "" && name != "":
+			return name, data
+		case strings.HasPrefix(line, "event: "):
+			name = strings.TrimPrefix(line, "event: ")
+		case strings.HasPrefix(line, "data: "):
+			data = strings.TrimPrefix(line, "data: ")
+		}
+	}
+}
+
+func TestStream_Fleet_SnapshotThenFilteredEvents(t *testing.T) {
+	hub := realtime.NewHub()
+	vehicleSvc := &mockVehicleSvc{
+		listWithStatusFn: func(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error) {
+			return []model.VehicleWithStatus{{ID: "v1"}, {ID: "v2"}}, nil
+		},
+	}
+	v2 := "v2"
+	dispatchSvc := &mockDispatchSvc{
+		listActiveFn: func(ctx context.Context) ([]model.Dispatch, error) {
+			return []model.Dispatch{{ID: "d1"}, {ID: "d2", VehicleID: &v2}}, nil
+		},
+	}
+	h := NewStreamHandler(hub, vehicleSvc, dispatchSvc, &mockLocationSvc{})
+	srv := httptest.NewServer(http.HandlerFunc(h.Fleet))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL + "?vehicle_id=v2")
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Errorf("Content-Type = %q, want text/event-stream", ct)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	name, data := readSSE(t, body)
+	if name != string(realtime.EventSnapshot) {
+		t.Fatalf("first event = %q, want snapshot", name)
+	}
+	var snap struct {
+		Data struct {
+			Vehicles   []model.VehicleWithStatus `json:"vehicles"`
+			Dispatches []model.Dispatch          `json:"dispatches"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(data), &snap); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+	if len(snap.Data.Vehicles) != 1 || snap.Data.Vehicles[0].ID != "v2" {
+		t.Errorf("snapshot vehicles = %+v, want only v2", snap.Data.Vehicles)
+	}
+	if len(snap.Data.Dispatches) != 1 || snap.Data.Dispatches[0].ID != "d2" {
+		t.Errorf("snapshot dispatches = %+v, want only d2", snap.Data.Dispatches)
+	}
+
+	hub.Publish(realtime.Event{Type: realtime.EventVehicleLocation, VehicleID: "v1"})
+	hub.Publish(realtime.Event{Type: realtime.EventVehicleStatus, VehicleID: "v2"})
+
+	name, data = readSSE(t, body)
+	if name != string(realtime.EventVehicleStatus) || !strings.Contains(data, `"vehicle_id":"v2"`) {
+		t.Errorf("got event %q %s, want vehicle.status for v2", name, data)
+	}
+
+	// Closing the hub ends the stream.
+	hub.Close()
+	if _, err := body.ReadString('\n'); err == nil {
+		t.Error("expected stream to end after hub close")
+	}
+}
+
+func TestStream_Fleet_DepotScoped(t *testing.T) {
+	hub := realtime.NewHub()
+	defer hub.Close()
+	var gotDepots []string
+	vehicleSvc := &mockVehicleSvc{
+		listWithStatusFn: func(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error) {
+			gotDepots = depotIDs
+			return []model.VehicleWithStatus{{ID: "v1"}}, nil
+		},
+	}
+	v1, v2 := "v1", "v2"
+	dispatchSvc := &mockDispatchSvc{
+		listActiveFn: func(ctx context.Context) ([]model.Dispatch, error) {
+			return []model.Dispatch{{ID: "d1"}, {ID: "d2", VehicleID: &v2}, {ID: "d3", VehicleID: &v1}}, nil
+		},
+	}
+	h := NewStreamHandler(hub, vehicleSvc, dispatchSvc, &mockLocationSvc{})
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		h.Fleet(w, withDepots(r, "north"))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body := bufio.NewReader(resp.Body)
+	_, data := readSSE(t, body)
+	if len(gotDepots) != 1 || gotDepots[0] != "north" {
+		t.Errorf("vehicles listed for depots %v, want [north]", gotDepots)
+	}
+	var snap struct {
+		Data struct {
+			Dispatches []model.Dispatch `json:"dispatches"`
+		} `json:"data"`
+	}
+	if err := json.Unmarshal([]byte(data), &snap); err != nil {
+		t.Fatalf("decode snapshot: %v", err)
+	}
+	if len(snap.Data.Dispatches) != 2 || snap.Data.Dispatches[0].ID != "d1" || snap.Data.Dispatches[1].ID != "d3" {
+		t.Errorf("snapshot dispatches = %+v, want d1 and d3", snap.Data.Dispatches)
+	}
+
+	// v2 is in another depot until it is transferred in.
+	north := "north"
+	hub.Publish(realtime.Event{Type: realtime.EventVehicleLocation, VehicleID: "v2"})
+	hub.Publish(realtime.Event{Type: realtime.EventVehicleUpdated, VehicleID: "v2", Data: &model.Vehicle{ID: "v2", DepotID: &north}})
+	hub.Publish(realtime.Event{Type: realtime.EventVehicleLocation, VehicleID: "v2"})
+
+	for _, want := range []realtime.EventType{realtime.EventVehicleUpdated, realtime.EventVehicleLocation} {
+		name, data := readSSE(t, body)
+		if name != string(want) || !strings.Contains(data, `"vehicle_id":"v2"`) {
+			t.Errorf("got event %q %s, want %s for v2", name, data, want)
+		}
+	}
+}
+
+func TestStream_Fleet_SnapshotError(t *testing.T) {
+	vehicleSvc := &mockVehicleSvc{
+		listWithStatusFn: func(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error) {
+			return nil, errors.New("db down")
+		},
+	}
+	h := NewStreamHandler(realtime.NewHub(), vehicleSvc, &mockDispatchSvc{}, &mockLocationSvc{})
+	req := httptest.NewRequest("GET", "/stream/fleet", nil)
+	rec := httptest.NewRecorder()
+
+	h.Fleet(rec, req)
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusInternalServerError)
+	}
+}
+
+func TestStream_Ride_OtherPassengerForbidden(t *testing.T) {
+	dispatchSvc := &mockDispatchSvc{
+		getByIDFn: func(ctx context.Context, id string) (*model.Dispatch, error) {
+			return &model.Dispatch{ID: id, RequesterID: "someone-else"}, nil
+		},
+	}
+	h := NewStreamHandler(realtime.NewHub(), &mockVehicleSvc{}, dispatchSvc, &mockLocationSvc{})
+	req := httptest.NewRequest("GET", "/passenger/rides/d1/stream", nil)
+	req = withClaims(req, "p1", "", "passenger")
+	req = withChiParam(req, "id", "d1")
+	rec := httptest.NewRecorder()
+
+	h.Ride(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
+
+func TestStream_Ride_FollowsAssignedVehicleUntilCompleted(t *testing.T) {
+	hub := realtime.NewHub()
+	v1 := "v1"
+	pickupLat, pickupLng := 14.55, 121.02
+	dispatchSvc := &mockDispatchSvc{
+		getByIDFn: func(ctx context.Context, id string) (*model.Dispatch, error) {
+			return &model.Dispatch{ID: id, RequesterID: "p1", VehicleID: &v1, Status: model.DispatchStatusAccepted,
+				PickupLat: &pickupLat, PickupLng: &pickupLng}, nil
+		},
+		estimateRideETAFn: func(ctx context.Context, d *model.Dispatch, lat, lng float64) *dto.RideETA {
+			return &dto.RideETA{Target: "pickup", DurationSec: 120}
+		},
+	}
+	locationSvc := &mockLocationSvc{
+		getCurrentFn: func(ctx context.Context, vehicleID string) (*model.VehicleLocationCurrent, error) {
+			return &model.VehicleLocationCurrent{VehicleID: vehicleID, Latitude: 14.56, Longitude: 121.03}, nil
+		},
+	}
+	h := NewStreamHandler(hub, &mockVehicleSvc{}, dispatchSvc, locationSvc)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = withClaims(r, "p1", "", "passenger")
+		h.Ride(w, withChiParam(r, "id", "d1"))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+	body := bufio.NewReader(resp.Body)
+
+	name, data := readSSE(t, body)
+	if name != string(realtime.EventSnapshot) || !strings.Contains(data, `"duration_sec":120`) {
+		t.Fatalf("got %q %s, want snapshot with ETA", name, data)
+	}
+
+	// Another vehicle's position must never reach the passenger.
+	hub.Publish(realtime.Event{Type: realtime.EventVehicleLocation, VehicleID: "v2", Data: model.LocationPoint{Latitude: 1}})
+	hub.Publish(realtime.Event{Type: realtime.EventVehicleLocation, VehicleID: "v1", Data: model.LocationPoint{Latitude: 14.551}})
+
+	name, data = readSSE(t, body)
+	if name != string(realtime.EventRideLocation) || !strings.Contains(data, `"latitude":14.551`) {
+		t.Errorf("got %q %s, want ride.location for v1", name, data)
+	}
+
+	done := &model.Dispatch{ID: "d1", RequesterID: "p1", VehicleID: &v1, Status: model.DispatchStatusCompleted}
+	hub.Publish(realtime.Event{Type: realtime.EventDispatchUpdated, DispatchID: "d1", VehicleID: "v1", Data: done})
+
+	name, data = readSSE(t, body)
+	if name != string(realtime.EventRideStatus) || !strings.Contains(data, `"status":"completed"`) {
+		t.Errorf("got %q %s, want ride.status completed", name, data)
+	}
+	if _, err := body.ReadString('\n'); err == nil {
+		t.Error("expected stream to close after ride completed")
+	}
+}
+
+func TestStream_Ride_ThrottlesETA(t *testing.T) {
+	hub := realtime.NewHub()
+	v1 := "v1"
+	pickupLat, pickupLng := 14.55, 121.02
+	var estimates atomic.Int32
+	dispatchSvc := &mockDispatchSvc{
+		getByIDFn: func(ctx context.Context, id string) (*model.Dispatch, error) {
+			return &model.Dispatch{ID: id, RequesterID: "p1", VehicleID: &v1, Status: model.DispatchStatusEnRoute,
+				PickupLat: &pickupLat, PickupLng: &pickupLng}, nil
+		},
+		estimateRideETAFn: func(ctx context.Context, d *model.Dispatch, lat, lng float64) *dto.RideETA {
+			return &dto.RideETA{Target: "pickup", DurationSec: 100 + int(estimates.Add(1))}
+		},
+	}
+	locationSvc := &mockLocationSvc{
+		getCurrentFn: func(ctx context.Context, vehicleID string) (*model.VehicleLocationCurrent, error) {
+			return &model.VehicleLocationCurrent{VehicleID: vehicleID, Latitude: 14.56, Longitude: 121.03}, nil
+		},
+	}
+	h := NewStreamHandler(hub, &mockVehicleSvc{}, dispatchSvc, locationSvc)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r = withClaims(r, "p1", "", "passenger")
+		h.Ride(w, withChiParam(r, "id", "d1"))
+	}))
+	defer srv.Close()
+
+	resp, err := http.Get(srv.URL)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer resp.Body.Close()
+	body := bufio.NewReader(resp.Body)
+
+	if _, data := readSSE(t, body); !strings.Contains(data, `"duration_sec":101`) {
+		t.Fatalf("snapshot %s, want the first estimate", data)
+	}
+
+	// About 15 m on: the snapshot's estimate still stands
+	hub.Publish(realtime.Event{Type: realtime.EventVehicleLocation, VehicleID: "v1",
+		Data: model.LocationPoint{Latitude: 14.5601, Longitude: 121.0301}})
+	if _, data := readSSE(t, body); !strings.Contains(data, `"duration_sec":101`) {
+		t.Errorf("nearby fix %s, want the cached estimate", data)
+	}
+
+	// About 1 km on: estimated afresh
+	hub.Publish(realtime.Event{Type: realtime.EventVehicleLocation, VehicleID: "v1",
+		Data: model.LocationPoint{Latitude: 14.551, Longitude: 121.03}})
+	if _, data := readSSE(t, body); !strings.Contains(data, `"duration_sec":102`) {
+		t.Errorf("distant fix %s, want a new estimate", data)
+	}
+	if n := estimates.Load(); n != 2 {
+		t.Errorf("estimated %d times, want 2", n)
+	}
+}
@@ -23,6 +23,7 @@ var allowedMIMESignatures = map[string][][]byte{
 	".jpeg": {{0xFF, 0xD8, 0xFF}},
 	".png":  {{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}},
 	".webp": {{'R', 'I', 'F', 'F'}}, // RIFF header; full check includes "WEBP" at offset 8
+	".pdf":  {{'%', 'P', 'D', 'F', '-'}},
 }
 
 type VehicleHandler struct {
@@ -36,7 +37,7 @@ func NewVehicleHandler(vehicleSvc vehicleService, locationSvc locationService, u
 }
 
 func (h *VehicleHandler) List(w http.ResponseWriter, r *http.Request) {
-	vehicles, err := h.vehicleSvc.ListWithStatus(r.Context())
+	vehicles, err := h.vehicleSvc.ListWithStatus(r.Context(), middleware.GetDepotIDs(r.Context()))
 	if err != nil {
 		apperror.WriteError(w, apperror.ErrInternal)
 		return
@@ -59,7 +60,7 @@ func (h *VehicleHandler) Get(w http.ResponseWriter, r *http.Request) {
 }
 
 func (h *VehicleHandler) ListAvailable(w http.ResponseWriter, r *http.Request) {
-	vehicles, err := h.vehicleSvc.ListAvailable(r.Context())
+	vehicles, err := h.vehicleSvc.ListAvailable(r.Context(), middleware.GetDepotIDs(r.Context()))
 	if err != nil {
 		apperror.WriteError(w, apperror.ErrInternal)
 		return
@@ -67,6 +68,46 @@ func (h *VehicleHandler) ListAvailable(w http.ResponseWriter, r *http.Request) {
 	apperror.WriteSuccess(w, vehicles)
 }
 
+// Availability returns every vehicle's free/busy intervals. from defaults to
+// now, to to one day after from, granularity to 15m.
+func (h *VehicleHandler) Availability(w http.ResponseWriter, r *http.Request) {
+	from, ok := parseTimeParam(w, r, "from")
+	if !ok {
+		return
+	}
+	to, ok := parseTimeParam(w, r, "to")
+	if !ok {
+		return
+	}
+	if from.IsZero() {
+		from = time.Now()
+	}
+	if to.IsZero() {
+		to = from.Add(24 * time.Hour)
+	}
+
+	granularity := 15 * time.Minute
+	if g := r.URL.Query().Get("granularity"); g != "" {
+		d, err := time.ParseDuration(g)
+		if err != nil {
+			apperror.WriteErrorMsg(w, 400, "VALIDATION_ERROR", "granularity must be a duration such as 15m or 1h")
+			return
+		}
+		granularity = d
+	}
+
+	grid, err := h.vehicleSvc.Availability(r.Context(), from, to, granularity, middleware.GetDepotIDs(r.Context()))
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	apperror.WriteSuccess(w, grid)
+}
+
 func (h *VehicleHandler) Create(w http.ResponseWriter, r *http.Request) {
 	claims := middleware.GetClaims(r.Context())
 
@@ -76,13 +117,17 @@ func (h *VehicleHandler) Create(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Name == "" || req.LicensePlate == "" || req.DriverID == "" {
+	if req.Name == "" || req.LicensePlate == "" {
 		apperror.WriteError(w, apperror.ErrBadRequest)
 		return
 	}
 
-	vehicle, err := h.vehicleSvc.Create(r.Context(), claims.UserID, req.Name, req.LicensePlate, req.DriverID)
+	vehicle, err := h.vehicleSvc.Create(r.Context(), claims.UserID, req.Name, req.LicensePlate, req.DepotID)
 	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
 		apperror.WriteError(w, apperror.ErrInternal)
 		return
 	}
@@ -101,12 +146,12 @@ func (h *VehicleHandler) Update(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if req.Name == "" || req.LicensePlate == "" || req.DriverID == "" {
+	if req.Name == "" || req.LicensePlate == "" {
 		apperror.WriteError(w, apperror.ErrBadRequest)
 		return
 	}
 
-	if err := h.vehicleSvc.Update(r.Context(), claims.UserID, id, req.Name, req.LicensePlate, req.DriverID); err != nil {
+	if err := h.vehicleSvc.Update(r.Context(), claims.UserID, id, req.Name, req.LicensePlate); err != nil {
 		apperror.WriteError(w, apperror.ErrInternal)
 		return
 	}
@@ -114,6 +159,30 @@ func (h *VehicleHandler) Update(w http.ResponseWriter, r *http.Request) {
 	w.WriteHeader(http.StatusNoContent)
 }
 
+// Transfer moves a vehicle to another depot.
+func (h *VehicleHandler) Transfer(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+	claims := middleware.GetClaims(r.Context())
+
+	var req dto.TransferVehicleRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		apperror.WriteError(w, apperror.ErrBadRequest)
+		return
+	}
+
+	vehicle, err := h.vehicleSvc.Transfer(r.Context(), claims.UserID, id, req.DepotID)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, vehicle)
+}
+
 func (h *VehicleHandler) Delete(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	claims := middleware.GetClaims(r.Context())
@@ -270,17 +339,21 @@ func validateMagicBytes(r io.Reader, ext string) bool {
 	return false
 }
 
-func (h *VehicleHandler) ToggleMaintenance(w http.ResponseWriter, r *http.Request) {
+func (h *VehicleHandler) SetTurnaround(w http.ResponseWriter, r *http.Request) {
 	id := chi.URLParam(r, "id")
 	claims := middleware.GetClaims(r.Context())
 
-	var req dto.ToggleMaintenanceRequest
+	var req dto.SetTurnaroundRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		apperror.WriteError(w, apperror.ErrBadRequest)
 		return
 	}
 
-	if err := h.vehicleSvc.ToggleMaintenance(r.Context(), claims.UserID, id, req.IsMaintenance); err != nil {
+	if err := h.vehicleSvc.SetTurnaround(r.Context(), claims.UserID, id, req.TurnaroundMin); err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
 		apperror.WriteError(w, apperror.ErrInternal)
 		return
 	}
@@ -7,13 +7,16 @@ import (
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/kento/driver/backend/internal/dto"
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/pkg/apperror"
 )
 
 func TestVehicle_List_Success(t *testing.T) {
 	svc := &mockVehicleSvc{
-		listWithStatusFn: func(ctx context.Context) ([]model.VehicleWithStatus, error) {
+		listWithStatusFn: func(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error) {
 			return []model.VehicleWithStatus{{ID: "v1"}}, nil
 		},
 	}
@@ -43,7 +46,7 @@ func TestVehicle_Get_NotFound(t *testing.T) {
 
 func TestVehicle_Create_MissingFields(t *testing.T) {
 	h := NewVehicleHandler(&mockVehicleSvc{}, &mockLocationSvc{}, "/tmp/test-uploads")
-	body := `{"name":"","license_plate":"","driver_id":""}`
+	body := `{"name":"","license_plate":""}`
 	req := httptest.NewRequest("POST", "/vehicles", strings.NewReader(body))
 	req = withClaims(req, "admin1", "adm1", "admin")
 	rec := httptest.NewRecorder()
@@ -57,12 +60,12 @@ func TestVehicle_Create_MissingFields(t *testing.T) {
 
 func TestVehicle_Create_Success(t *testing.T) {
 	svc := &mockVehicleSvc{
-		createFn: func(ctx context.Context, actorID, name, licensePlate, driverID string) (*model.Vehicle, error) {
+		createFn: func(ctx context.Context, actorID, name, licensePlate string, depotID *string) (*model.Vehicle, error) {
 			return &model.Vehicle{ID: "v1", Name: name}, nil
 		},
 	}
 	h := NewVehicleHandler(svc, &mockLocationSvc{}, "/tmp/test-uploads")
-	body := `{"name":"Van 1","license_plate":"ABC-123","driver_id":"d1"}`
+	body := `{"name":"Van 1","license_plate":"ABC-123"}`
 	req := httptest.NewRequest("POST", "/vehicles", strings.NewReader(body))
 	req = withClaims(req, "admin1", "adm1", "admin")
 	rec := httptest.NewRecorder()
@@ -122,3 +125,154 @@ func TestSafeDeleteOldPhoto_Traversal(t *testing.T) {
 	h.safeDeleteOldPhoto(".")
 	h.safeDeleteOldPhoto("/")
 }
+
+func TestVehicle_Availability_Defaults(t *testing.T) {
+	var gotFrom, gotTo time.Time
+	var gotGranularity time.Duration
+	svc := &mockVehicleSvc{
+		availabilityFn: func(_ context.Context, from, to time.Time, g time.Duration, _ []string) (*dto.FleetAvailability, error) {
+			gotFrom, gotTo, gotGranularity = from, to, g
+			return &dto.FleetAvailability{}, nil
+		},
+	}
+	h := NewVehicleHandler(svc, &mockLocationSvc{}, "/tmp/test-uploads")
+	req := httptest.NewRequest("GET", "/fleet/availability?from=2026-11-02T00:00:00Z", nil)
+	rec := httptest.NewRecorder()
+
+	h.Availability(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if want := time.Date(2026, 11, 3, 0, 0, 0, 0, time.UTC); !gotTo.Equal(want) || gotTo.Sub(gotFrom) != 24*time.Hour {
+		t.Errorf("window = %v..%v, want one day from 2026-11-02", gotFrom, gotTo)
+	}
+	if gotGranularity != 15*time.Minute {
+		t.Errorf("granularity = %v, want 15m", gotGranularity)
+	}
+}
+
+func TestVehicle_Availability_InvalidGranularity(t *testing.T) {
+	h := NewVehicleHandler(&mockVehicleSvc{}, &mockLocationSvc{}, "/tmp/test-uploads")
+	req := httptest.NewRequest("GET", "/fleet/availability?granularity=quarter", nil)
+	rec := httptest.NewRecorder()
+
+	h.Availability(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+}
+
+func TestVehicle_Availability_ServiceRejectsWindow(t *testing.T) {
+	svc := &mockVehicleSvc{
+		availabilityFn: func(context.Context, time.Time, time.Time, time.Duration, []string) (*dto.FleetAvailability, error) {
+			return nil, apperror.New(400, "WINDOW_TOO_LARGE", "availability window may span at most 31 days")
+		},
+	}
+	h := NewVehicleHandler(svc, &mockLocationSvc{}, "/tmp/test-uploads")
+	req := httptest.NewRequest("GET", "/fleet/availability?from=2026-01-01T00:00:00Z&to=2026-06-01T00:00:00Z", nil)
+	rec := httptest.NewRecorder()
+
+	h.Availability(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if code := decodeError(t, rec); code != "WINDOW_TOO_LARGE" {
+		t.Errorf("code = %q, want WINDOW_TOO_LARGE", code)
+	}
+}
+
+func TestVehicle_SetTurnaround_Success(t *testing.T) {
+	var got int
+	svc := &mockVehicleSvc{
+		setTurnaroundFn: func(ctx context.Context, actorID, vehicleID string, minutes int) error {
+			got = minutes
+			return nil
+		},
+	}
+	h := NewVehicleHandler(svc, &mockLocationSvc{}, "/tmp/test-uploads")
+	req := httptest.NewRequest("PATCH", "/vehicles/v1/turnaround", strings.NewReader(`{"turnaround_min":20}`))
+	req = withChiParam(req, "id", "v1")
+	req = withClaims(req, "disp1", "d1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.SetTurnaround(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if got != 20 {
+		t.Errorf("minutes = %d, want 20", got)
+	}
+}
+
+func TestVehicle_SetTurnaround_OutOfRange(t *testing.T) {
+	svc := &mockVehicleSvc{
+		setTurnaroundFn: func(context.Context, string, string, int) error {
+			return apperror.New(400, "INVALID_TURNAROUND", "turnaround_min must be between 0 and 240")
+		},
+	}
+	h := NewVehicleHandler(svc, &mockLocationSvc{}, "/tmp/test-uploads")
+	req := httptest.NewRequest("PATCH", "/vehicles/v1/turnaround", strings.NewReader(`{"turnaround_min":-5}`))
+	req = withChiParam(req, "id", "v1")
+	req = withClaims(req, "disp1", "d1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.SetTurnaround(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if code := decodeError(t, rec); code != "INVALID_TURNAROUND" {
+		t.Errorf("code = %q, want INVALID_TURNAROUND", code)
+	}
+}
+
+func TestVehicle_List_PassesDepotScope(t *testing.T) {
+	var got []string
+	svc := &mockVehicleSvc{
+		listWithStatusFn: func(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error) {
+			got = depotIDs
+			return []model.VehicleWithStatus{}, nil
+		},
+	}
+	h := NewVehicleHandler(svc, &mockLocationSvc{}, "/tmp/test-uploads")
+	req := withDepots(withClaims(httptest.NewRequest("GET", "/vehicles", nil), "disp1", "d1", "dispatcher"), "depot-1")
+	rec := httptest.NewRecorder()
+
+	h.List(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if len(got) != 1 || got[0] != "depot-1" {
+		t.Errorf("depotIDs = %v, want [depot-1]", got)
+	}
+}
+
+func TestVehicle_Transfer(t *testing.T) {
+	var gotVehicle string
+	var gotDepot *string
+	svc := &mockVehicleSvc{
+		transferFn: func(ctx context.Context, actorID, vehicleID string, depotID *string) (*model.Vehicle, error) {
+			gotVehicle, gotDepot = vehicleID, depotID
+			return &model.Vehicle{ID: vehicleID, DepotID: depotID}, nil
+		},
+	}
+	h := NewVehicleHandler(svc, &mockLocationSvc{}, "/tmp/test-uploads")
+	req := httptest.NewRequest("POST", "/vehicles/v1/transfer", strings.NewReader(`{"depot_id":"depot-2"}`))
+	req = withChiParam(req, "id", "v1")
+	req = withClaims(req, "admin1", "a1", "admin")
+	rec := httptest.NewRecorder()
+
+	h.Transfer(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+	if gotVehicle != "v1" || gotDepot == nil || *gotDepot != "depot-2" {
+		t.Errorf("Transfer(%q, %v)", gotVehicle, gotDepot)
+	}
+}
@@ -0,0 +1,98 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/kento/driver/backend/internal/middleware"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+type WaitlistHandler struct {
+	waitlistSvc waitlistService
+}
+
+func NewWaitlistHandler(waitlistSvc waitlistService) *WaitlistHandler {
+	return &WaitlistHandler{waitlistSvc: waitlistSvc}
+}
+
+func (h *WaitlistHandler) List(w http.ResponseWriter, r *http.Request) {
+	limit, ok := parseIntParam(w, r, "limit", 0)
+	if !ok {
+		return
+	}
+	offset, ok := parseIntParam(w, r, "offset", 0)
+	if !ok {
+		return
+	}
+	if limit <= 0 || limit > 100 {
+		limit = 50
+	}
+
+	depotIDs := middleware.GetDepotIDs(r.Context())
+	entries, err := h.waitlistSvc.List(r.Context(), r.URL.Query().Get("requester_id"), r.URL.Query().Get("status"), depotIDs, limit, offset)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	apperror.WriteSuccess(w, entries)
+}
+
+func (h *WaitlistHandler) Get(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	entry, err := h.waitlistSvc.GetFor(r.Context(), subjectOf(r), id)
+	if err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+	if !entry.InDepots(middleware.GetDepotIDs(r.Context())) {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return
+	}
+
+	apperror.WriteSuccess(w, entry)
+}
+
+func (h *WaitlistHandler) Cancel(w http.ResponseWriter, r *http.Request) {
+	id := chi.URLParam(r, "id")
+
+	if !h.entryInScope(w, r, id) {
+		return
+	}
+
+	if err := h.waitlistSvc.Cancel(r.Context(), subjectOf(r), id); err != nil {
+		if appErr, ok := err.(*apperror.AppError); ok {
+			apperror.WriteError(w, appErr)
+			return
+		}
+		apperror.WriteError(w, apperror.ErrInternal)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// entryInScope answers 404 for an entry queued outside the caller's depots,
+// as it is left out of the list. Unknown entries are left to the service.
+func (h *WaitlistHandler) entryInScope(w http.ResponseWriter, r *http.Request, id string) bool {
+	depotIDs := middleware.GetDepotIDs(r.Context())
+	if depotIDs == nil {
+		return true
+	}
+	entry, err := h.waitlistSvc.GetByID(r.Context(), id)
+	if err != nil {
+		apperror.WriteError(w, apperror.ErrInternal)
+		return false
+	}
+	if entry != nil && !entry.InDepots(depotIDs) {
+		apperror.WriteError(w, apperror.ErrNotFound)
+		return false
+	}
+	return true
+}
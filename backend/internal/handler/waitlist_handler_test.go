@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lib/pq"
+
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/policy"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+func TestWaitlist_Get_NotFound(t *testing.T) {
+	h := NewWaitlistHandler(&mockWaitlistSvc{})
+	req := withChiParam(httptest.NewRequest("GET", "/", nil), "id", "missing")
+	rec := httptest.NewRecorder()
+
+	h.Get(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestWaitlist_Cancel(t *testing.T) {
+	var gotID, gotBy string
+	svc := &mockWaitlistSvc{
+		cancelFn: func(_ context.Context, sub policy.Subject, id string) error {
+			gotID, gotBy = id, sub.UserID
+			return nil
+		},
+	}
+	h := NewWaitlistHandler(svc)
+	req := withClaims(withChiParam(httptest.NewRequest("POST", "/", nil), "id", "w-1"), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Cancel(rec, req)
+
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNoContent)
+	}
+	if gotID != "w-1" || gotBy != "u-1" {
+		t.Errorf("Cancel(%q, %q)", gotID, gotBy)
+	}
+}
+
+func TestWaitlist_Cancel_AlreadyBooked(t *testing.T) {
+	svc := &mockWaitlistSvc{
+		cancelFn: func(context.Context, policy.Subject, string) error {
+			return apperror.New(400, "INVALID_STATUS", "waitlist entry is no longer waiting")
+		},
+	}
+	h := NewWaitlistHandler(svc)
+	req := withClaims(withChiParam(httptest.NewRequest("POST", "/", nil), "id", "w-1"), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Cancel(rec, req)
+
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if code := decodeError(t, rec); code != "INVALID_STATUS" {
+		t.Errorf("code = %q, want INVALID_STATUS", code)
+	}
+}
+
+func northEntry(_ context.Context, id string) (*model.WaitlistEntry, error) {
+	return &model.WaitlistEntry{ID: id, DepotIDs: pq.StringArray{"depot-north"}}, nil
+}
+
+func TestWaitlist_Get_OutOfDepot(t *testing.T) {
+	h := NewWaitlistHandler(&mockWaitlistSvc{getFn: northEntry})
+	req := withDepots(withChiParam(httptest.NewRequest("GET", "/", nil), "id", "w-1"), "depot-south")
+	rec := httptest.NewRecorder()
+
+	h.Get(rec, req)
+
+	if rec.Code != http.StatusNotFound {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestWaitlist_Cancel_OutOfDepot(t *testing.T) {
+	svc := &mockWaitlistSvc{
+		getFn: northEntry,
+		cancelFn: func(context.Context, policy.Subject, string) error {
+			t.Error("Cancel called for an entry of another depot")
+			return nil
+		},
+	}
+	h := NewWaitlistHandler(svc)
+	req := withClaims(withChiParam(httptest.NewRequest("POST", "/", nil), "id", "w-1"), "u-1", "E1", "dispatcher")
+	rec := httptest.NewRecorder()
+
+	h.Cancel(rec, withDepots(req, "depot-south"))
+
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+}
+
+func TestWaitlist_Get_OtherRequester(t *testing.T) {
+	svc := &mockWaitlistSvc{
+		getFn: func(_ context.Context, id string) (*model.WaitlistEntry, error) {
+			return &model.WaitlistEntry{ID: id, RequesterID: "u-2"}, nil
+		},
+	}
+	h := NewWaitlistHandler(svc)
+	req := withClaims(withChiParam(httptest.NewRequest("GET", "/", nil), "id", "w-1"), "u-3", "P3", "passenger")
+	rec := httptest.NewRecorder()
+
+	h.Get(rec, req)
+
+	if rec.Code != http.StatusForbidden {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+}
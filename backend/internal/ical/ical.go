@@ -0,0 +1,151 @@
+// Package ical writes iCalendar (RFC 5545) feeds that calendar clients such
+// as Google Calendar and Outlook can subscribe to. Only what a published
+// feed of timed events needs is supported.
+package ical
+
+import (
+	"bufio"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// Status is an event's STATUS. Clients drop CANCELLED events from a
+// subscribed calendar and may show TENTATIVE ones differently.
+type Status string
+
+const (
+	StatusConfirmed Status = "CONFIRMED"
+	StatusTentative Status = "TENTATIVE"
+	StatusCancelled Status = "CANCELLED"
+)
+
+// Event is one VEVENT. UID must stay the same for the life of the event;
+// Sequence must grow whenever it changes so clients replace their copy.
+type Event struct {
+	UID         string
+	Sequence    int
+	Stamp       time.Time
+	Start       time.Time
+	End         time.Time
+	Summary     string
+	Location    string
+	Description string
+	Status      Status
+}
+
+// Calendar is a published feed.
+type Calendar struct {
+	ProdID string
+	Name   string
+	// Refresh suggests how often clients should poll; zero leaves it to them.
+	Refresh time.Duration
+	Events  []Event
+}
+
+// maxLine is the longest content line RFC 5545 allows, in octets, without
+// the CRLF.
+const maxLine = 75
+
+// Write encodes c to w.
+func (c *Calendar) Write(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	put := func(name, value string) {
+		writeFolded(bw, name+":"+value)
+	}
+
+	put("BEGIN", "VCALENDAR")
+	put("VERSION", "2.0")
+	put("PRODID", c.ProdID)
+	put("CALSCALE", "GREGORIAN")
+	put("METHOD", "PUBLISH")
+	if c.Name != "" {
+		put("X-WR-CALNAME", Escape(c.Name))
+	}
+	if c.Refresh > 0 {
+		d := duration(c.Refresh)
+		writeFolded(bw, "REFRESH-INTERVAL;VALUE=DURATION:"+d)
+		put("X-PUBLISHED-TTL", d)
+	}
+	for _, e := range c.Events {
+		put("BEGIN", "VEVENT")
+		put("UID", e.UID)
+		put("SEQUENCE", strconv.Itoa(e.Sequence))
+		put("DTSTAMP", utc(e.Stamp))
+		put("DTSTART", utc(e.Start))
+		put("DTEND", utc(e.End))
+		put("SUMMARY", Escape(e.Summary))
+		if e.Location != "" {
+			put("LOCATION", Escape(e.Location))
+		}
+		if e.Description != "" {
+			put("DESCRIPTION", Escape(e.Description))
+		}
+		if e.Status != "" {
+			put("STATUS", string(e.Status))
+		}
+		put("END", "VEVENT")
+	}
+	put("END", "VCALENDAR")
+	return bw.Flush()
+}
+
+// Escape escapes a TEXT value: backslashes, semicolons, commas and newlines.
+func Escape(s string) string {
+	var b strings.Builder
+	for _, r := range s {
+		switch r {
+		case '\\', ';', ',':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\r':
+			// dropped; \r\n becomes a single \n
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// writeFolded writes line ended by CRLF, folding it into continuation lines
+// (CRLF + space) of at most maxLine octets without splitting a character.
+func writeFolded(w *bufio.Writer, line string) {
+	limit := maxLine
+	for len(line) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(line[cut]) {
+			cut--
+		}
+		w.WriteString(line[:cut])
+		w.WriteString("\r\n ")
+		line = line[cut:]
+		// The leading space counts towards the next line's length
+		limit = maxLine - 1
+	}
+	w.WriteString(line)
+	w.WriteString("\r\n")
+}
+
+func utc(t time.Time) string {
+	return t.UTC().Format("20060102T150405Z")
+}
+
+// duration formats d as an RFC 5545 DURATION, whole seconds only.
+func duration(d time.Duration) string {
+	s := int(d / time.Second)
+	out := "PT"
+	if h := s / 3600; h > 0 {
+		out += strconv.Itoa(h) + "H"
+	}
+	if m := s % 3600 / 60; m > 0 {
+		out += strconv.Itoa(m) + "M"
+	}
+	if sec := s % 60; sec > 0 || out == "PT" {
+		out += strconv.Itoa(sec) + "S"
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package ical
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func TestEscape(t *testing.T) {
+	got := Escape("Airport; Terminal 3, Gate\\B\r\nreturn")
+	want := `Airport\; Terminal 3\, Gate\\B\nreturn`
+	if got != want {
+		t.Errorf("Escape = %q, want %q", got, want)
+	}
+}
+
+func TestWrite_Event(t *testing.T) {
+	start := time.Date(2026, 11, 2, 8, 0, 0, 0, time.FixedZone("PHT", 8*3600))
+	cal := &Calendar{
+		ProdID:  "-//test//EN",
+		Name:    "Van 1",
+		Refresh: 15 * time.Minute,
+		Events: []Event{{
+			UID:      "reservation-1",
+			Sequence: 3,
+			Stamp:    start,
+			Start:    start,
+			End:      start.Add(time.Hour),
+			Summary:  "Client visit",
+			Location: "Makati, Ayala Ave",
+			Status:   StatusCancelled,
+		}},
+	}
+	var buf bytes.Buffer
+	if err := cal.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+
+	for _, line := range []string{
+		"BEGIN:VCALENDAR\r\n",
+		"X-WR-CALNAME:Van 1\r\n",
+		"REFRESH-INTERVAL;VALUE=DURATION:PT15M\r\n",
+		"UID:reservation-1\r\n",
+		"SEQUENCE:3\r\n",
+		"DTSTART:20261102T000000Z\r\n",
+		"DTEND:20261102T010000Z\r\n",
+		"LOCATION:Makati\\, Ayala Ave\r\n",
+		"STATUS:CANCELLED\r\n",
+		"END:VCALENDAR\r\n",
+	} {
+		if !strings.Contains(out, line) {
+			t.Errorf("output lacks %q:\n%s", line, out)
+		}
+	}
+	if strings.Contains(out, "DESCRIPTION") {
+		t.Error("empty description was written")
+	}
+}
+
+func TestWrite_FoldsLongLines(t *testing.T) {
+	summary := strings.Repeat("Ünïcödé ", 30)
+	cal := &Calendar{ProdID: "-//test//EN", Events: []Event{{UID: "x", Summary: summary}}}
+	var buf bytes.Buffer
+	if err := cal.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\r\n"), "\r\n")
+	var unfolded strings.Builder
+	for _, l := range lines {
+		if len(l) > maxLine {
+			t.Errorf("line of %d octets: %q", len(l), l)
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("fold split a character: %q", l)
+		}
+		if strings.HasPrefix(l, " ") {
+			unfolded.WriteString(l[1:])
+		} else {
+			unfolded.WriteString("\n" + l)
+		}
+	}
+	if !strings.Contains(unfolded.String(), "\nSUMMARY:"+summary+"\n") {
+		t.Error("unfolded summary does not match")
+	}
+}
+
+func TestDuration(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		15 * time.Minute:            "PT15M",
+		time.Hour + 30*time.Second:  "PT1H30S",
+		0:                           "PT0S",
+		2*time.Hour + 5*time.Minute: "PT2H5M",
+	} {
+		if got := duration(d); got != want {
+			t.Errorf("duration(%v) = %q, want %q", d, got, want)
+		}
+	}
+}
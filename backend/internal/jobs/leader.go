@@ -0,0 +1,71 @@
+package jobs
+
+import (
+	"context"
+	"database/sql"
+	"sync"
+
+	"github.com/jmoiron/sqlx"
+)
+
+// AdvisoryLockKey identifies the scheduler's leader lock. It is an arbitrary
+// constant; it only has to be unique among advisory locks in this database.
+const AdvisoryLockKey int64 = 0x6a6f6273 // "jobs"
+
+// PGLeader elects a leader with a session-level Postgres advisory lock held
+// on a dedicated connection. If that connection dies the server drops the
+// lock, and another replica picks it up on its next tick.
+type PGLeader struct {
+	db  *sqlx.DB
+	key int64
+
+	mu   sync.Mutex
+	conn *sql.Conn
+}
+
+func NewPGLeader(db *sqlx.DB, key int64) *PGLeader {
+	return &PGLeader{db: db, key: key}
+}
+
+func (l *PGLeader) Acquire(ctx context.Context) (bool, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn != nil {
+		if _, err := l.conn.ExecContext(ctx, `SELECT 1`); err == nil {
+			return true, nil
+		}
+		// Session is gone, and the lock with it.
+		l.conn.Close()
+		l.conn = nil
+	}
+
+	conn, err := l.db.Conn(ctx)
+	if err != nil {
+		return false, err
+	}
+	var locked bool
+	if err := conn.QueryRowContext(ctx, `SELECT pg_try_advisory_lock($1)`, l.key).Scan(&locked); err != nil {
+		conn.Close()
+		return false, err
+	}
+	if !locked {
+		conn.Close()
+		return false, nil
+	}
+	l.conn = conn
+	return true, nil
+}
+
+func (l *PGLeader) Release(ctx context.Context) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.conn == nil {
+		return nil
+	}
+	_, err := l.conn.ExecContext(ctx, `SELECT pg_advisory_unlock($1)`, l.key)
+	l.conn.Close()
+	l.conn = nil
+	return err
+}
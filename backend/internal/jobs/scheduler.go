@@ -0,0 +1,226 @@
+package jobs
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RunFunc performs one run of a job and reports how many items it processed.
+type RunFunc func(ctx context.Context) (int, error)
+
+// Leader decides whether this replica may run jobs. Acquire is called before
+// every run and must be cheap when leadership is already held.
+type Leader interface {
+	Acquire(ctx context.Context) (bool, error)
+	Release(ctx context.Context) error
+}
+
+// Status is the last known state of a registered job.
+type Status struct {
+	Name         string     `json:"name"`
+	Interval     string     `json:"interval"`
+	Running      bool       `json:"running"`
+	LastRunAt    *time.Time `json:"last_run_at,omitempty"`
+	LastDuration int64      `json:"last_duration_ms"`
+	LastStatus   string     `json:"last_status,omitempty"`
+	LastError    string     `json:"last_error,omitempty"`
+	LastItems    int        `json:"last_items"`
+	Runs         int        `json:"runs"`
+	Failures     int        `json:"failures"`
+}
+
+type job struct {
+	name     string
+	interval time.Duration
+	run      RunFunc
+}
+
+// Scheduler runs registered jobs on their own intervals. Only the replica
+// holding leadership runs anything; the others skip their ticks.
+type Scheduler struct {
+	leader  Leader
+	timeout time.Duration
+
+	mu       sync.RWMutex
+	jobs     []job
+	status   map[string]*Status
+	isLeader bool
+
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+	started bool
+	stopped sync.Once
+}
+
+func NewScheduler(leader Leader) *Scheduler {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &Scheduler{
+		leader:  leader,
+		timeout: 5 * time.Minute,
+		status:  make(map[string]*Status),
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// Register adds a job. Jobs must be registered before Start.
+func (s *Scheduler) Register(name string, interval time.Duration, run RunFunc) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.jobs = append(s.jobs, job{name: name, interval: interval, run: run})
+	s.status[name] = &Status{Name: name, Interval: interval.String()}
+}
+
+// Start launches one goroutine per job. Each job first runs one interval
+// after start, so a restart loop cannot hammer the database.
+func (s *Scheduler) Start() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.started {
+		return
+	}
+	s.started = true
+	for _, j := range s.jobs {
+		s.wg.Add(1)
+		go s.loop(j)
+	}
+	log.Printf("[jobs] scheduler started jobs=%d", len(s.jobs))
+}
+
+// Stop cancels running jobs, waits for them to return and gives up
+// leadership so another replica can take over immediately.
+func (s *Scheduler) Stop() {
+	s.stopped.Do(func() {
+		s.cancel()
+		s.wg.Wait()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := s.leader.Release(ctx); err != nil {
+			log.Printf("[jobs] release leadership: %v", err)
+		}
+		log.Println("[jobs] scheduler stopped")
+	})
+}
+
+func (s *Scheduler) loop(j job) {
+	defer s.wg.Done()
+	ticker := time.NewTicker(j.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.ctx.Done():
+			return
+		case <-ticker.C:
+			s.RunOnce(j.name)
+		}
+	}
+}
+
+// RunOnce runs the named job now if this replica is the leader. It is what
+// each tick calls, and is exported for tests and manual triggering.
+func (s *Scheduler) RunOnce(name string) {
+	s.mu.RLock()
+	var j *job
+	for i := range s.jobs {
+		if s.jobs[i].name == name {
+			j = &s.jobs[i]
+			break
+		}
+	}
+	s.mu.RUnlock()
+	if j == nil {
+		return
+	}
+
+	leader, err := s.leader.Acquire(s.ctx)
+	s.setLeader(leader)
+	if err != nil {
+		log.Printf("[jobs] job=%s status=skipped reason=leader_check err=%q", j.name, err)
+		return
+	}
+	if !leader {
+		return
+	}
+
+	s.update(j.name, func(st *Status) { st.Running = true })
+
+	ctx, cancel := context.WithTimeout(s.ctx, s.timeout)
+	start := time.Now()
+	items, err := s.safeRun(ctx, j)
+	elapsed := time.Since(start)
+	cancel()
+
+	s.update(j.name, func(st *Status) {
+		st.Running = false
+		st.LastRunAt = &start
+		st.LastDuration = elapsed.Milliseconds()
+		st.LastItems = items
+		st.Runs++
+		if err != nil {
+			st.LastStatus = "error"
+			st.LastError = err.Error()
+			st.Failures++
+		} else {
+			st.LastStatus = "ok"
+			st.LastError = ""
+		}
+	})
+
+	if err != nil {
+		log.Printf("[jobs] job=%s status=error duration=%s items=%d err=%q", j.name, elapsed.Round(time.Millisecond), items, err)
+		return
+	}
+	log.Printf("[jobs] job=%s status=ok duration=%s items=%d", j.name, elapsed.Round(time.Millisecond), items)
+}
+
+// safeRun keeps a panicking job from taking the whole server down.
+func (s *Scheduler) safeRun(ctx context.Context, j *job) (items int, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	return j.run(ctx)
+}
+
+func (s *Scheduler) setLeader(leader bool) {
+	s.mu.Lock()
+	changed := s.isLeader != leader
+	s.isLeader = leader
+	s.mu.Unlock()
+	if changed {
+		log.Printf("[jobs] leadership=%t", leader)
+	}
+}
+
+func (s *Scheduler) update(name string, fn func(*Status)) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	fn(s.status[name])
+}
+
+// IsLeader reports whether this replica held leadership at its last check.
+func (s *Scheduler) IsLeader() bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.isLeader
+}
+
+// Statuses returns a copy of every job's status, sorted by name.
+func (s *Scheduler) Statuses() []Status {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	out := make([]Status, 0, len(s.status))
+	for _, st := range s.status {
+		out = append(out, *st)
+	}
+	sort.Slice(out, func(i, k int) bool { return out[i].Name < out[k].Name })
+	return out
+}
@@ -0,0 +1,109 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type fakeLeader struct {
+	leader   atomic.Bool
+	released atomic.Bool
+}
+
+func (f *fakeLeader) Acquire(ctx context.Context) (bool, error) { return f.leader.Load(), nil }
+func (f *fakeLeader) Release(ctx context.Context) error         { f.released.Store(true); return nil }
+
+func TestScheduler_RunOnceRecordsStatus(t *testing.T) {
+	leader := &fakeLeader{}
+	leader.leader.Store(true)
+	s := NewScheduler(leader)
+	s.Register("ok", time.Hour, func(ctx context.Context) (int, error) { return 3, nil })
+	s.Register("fails", time.Hour, func(ctx context.Context) (int, error) { return 0, errors.New("boom") })
+
+	s.RunOnce("ok")
+	s.RunOnce("fails")
+
+	st := s.Statuses()
+	if len(st) != 2 || st[0].Name != "fails" || st[1].Name != "ok" {
+		t.Fatalf("statuses = %+v, want fails then ok", st)
+	}
+	if st[1].LastStatus != "ok" || st[1].LastItems != 3 || st[1].Runs != 1 {
+		t.Errorf("ok job status = %+v", st[1])
+	}
+	if st[0].LastStatus != "error" || st[0].LastError != "boom" || st[0].Failures != 1 {
+		t.Errorf("failing job status = %+v", st[0])
+	}
+	if !s.IsLeader() {
+		t.Error("expected scheduler to report leadership")
+	}
+}
+
+func TestScheduler_FollowerSkipsRuns(t *testing.T) {
+	s := NewScheduler(&fakeLeader{})
+	var runs atomic.Int32
+	s.Register("job", time.Hour, func(ctx context.Context) (int, error) {
+		runs.Add(1)
+		return 0, nil
+	})
+
+	s.RunOnce("job")
+
+	if runs.Load() != 0 {
+		t.Errorf("runs = %d, want 0 on a follower", runs.Load())
+	}
+	if s.Statuses()[0].Runs != 0 {
+		t.Error("follower should not record runs")
+	}
+}
+
+func TestScheduler_PanicIsRecordedAsError(t *testing.T) {
+	leader := &fakeLeader{}
+	leader.leader.Store(true)
+	s := NewScheduler(leader)
+	s.Register("panics", time.Hour, func(ctx context.Context) (int, error) { panic("bad") })
+
+	s.RunOnce("panics")
+
+	if st := s.Statuses()[0]; st.LastStatus != "error" || st.LastError != "panic: bad" {
+		t.Errorf("status = %+v, want recovered panic", st)
+	}
+}
+
+func TestScheduler_StopCancelsRunningJobAndReleases(t *testing.T) {
+	leader := &fakeLeader{}
+	leader.leader.Store(true)
+	s := NewScheduler(leader)
+	started := make(chan struct{})
+	var once atomic.Bool
+	s.Register("long", 10*time.Millisecond, func(ctx context.Context) (int, error) {
+		if once.CompareAndSwap(false, true) {
+			close(started)
+		}
+		<-ctx.Done()
+		return 0, ctx.Err()
+	})
+
+	s.Start()
+	select {
+	case <-started:
+	case <-time.After(2 * time.Second):
+		t.Fatal("job never started")
+	}
+
+	stopped := make(chan struct{})
+	go func() {
+		s.Stop()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+	case <-time.After(2 * time.Second):
+		t.Fatal("Stop did not return")
+	}
+	if !leader.released.Load() {
+		t.Error("expected leadership to be released on stop")
+	}
+}
@@ -0,0 +1,119 @@
+package maps
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// MaxMatrixOrigins is the number of origins sent per route matrix request.
+// Traffic-aware matrices are limited to 100 elements; with a single
+// destination 25 keeps requests small and latency low.
+const MaxMatrixOrigins = 25
+
+// MatrixElement is the route from one origin to the destination. OK is false
+// when Google found no route for that pair.
+type MatrixElement struct {
+	OriginIndex    int
+	DurationSec    int
+	DistanceMeters int
+	OK             bool
+}
+
+// ComputeRouteMatrix calls the Google Routes API for driving times from each
+// origin to a single destination. At most MaxMatrixOrigins origins are
+// accepted per call; results are indexed like origins.
+func (c *Client) ComputeRouteMatrix(ctx context.Context, origins []LatLng, destination LatLng) ([]MatrixElement, error) {
+	if len(origins) > MaxMatrixOrigins {
+		return nil, fmt.Errorf("too many origins: %d > %d", len(origins), MaxMatrixOrigins)
+	}
+
+	reqBody := matrixAPIRequest{
+		Destinations:      []matrixWaypoint{{Waypoint: toWaypoint(destination)}},
+		TravelMode:        "DRIVE",
+		RoutingPreference: "TRAFFIC_AWARE",
+	}
+	for _, o := range origins {
+		reqBody.Origins = append(reqBody.Origins, matrixWaypoint{Waypoint: toWaypoint(o)})
+	}
+
+	jsonBody, err := json.Marshal(reqBody)
+	if err != nil {
+		return nil, fmt.Errorf("marshal request: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, "POST",
+		"https://routes.googleapis.com/distanceMatrix/v2:computeRouteMatrix", bytes.NewReader(jsonBody))
+	if err != nil {
+		return nil, fmt.Errorf("create request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Goog-Api-Key", c.apiKey)
+	req.Header.Set("X-Goog-FieldMask", "originIndex,duration,distanceMeters,condition")
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("route matrix request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := io.ReadAll(resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("route matrix error (status %d): %s", resp.StatusCode, string(body))
+	}
+
+	var elements []matrixAPIElement
+	if err := json.Unmarshal(body, &elements); err != nil {
+		return nil, fmt.Errorf("unmarshal response: %w", err)
+	}
+
+	results := make([]MatrixElement, len(origins))
+	for i := range results {
+		results[i].OriginIndex = i
+	}
+	for _, e := range elements {
+		// Zero values are omitted from the response, so a missing
+		// originIndex means origin 0.
+		if e.OriginIndex < 0 || e.OriginIndex >= len(origins) {
+			continue
+		}
+		results[e.OriginIndex] = MatrixElement{
+			OriginIndex:    e.OriginIndex,
+			DurationSec:    parseDurationStr(e.Duration),
+			DistanceMeters: e.DistanceMeters,
+			OK:             e.Condition == "ROUTE_EXISTS",
+		}
+	}
+	return results, nil
+}
+
+// --- Google Routes API matrix request/response types ---
+
+type matrixAPIRequest struct {
+	Origins           []matrixWaypoint `json:"origins"`
+	Destinations      []matrixWaypoint `json:"destinations"`
+	TravelMode        string           `json:"travelMode"`
+	RoutingPreference string           `json:"routingPreference"`
+}
+
+type matrixWaypoint struct {
+	Waypoint *routeWaypoint `json:"waypoint"`
+}
+
+type matrixAPIElement struct {
+	OriginIndex    int    `json:"originIndex"`
+	Duration       string `json:"duration"`
+	DistanceMeters int    `json:"distanceMeters"`
+	Condition      string `json:"condition"`
+}
+
+func toWaypoint(p LatLng) *routeWaypoint {
+	return &routeWaypoint{
+		Location: &routeLocation{LatLng: &routeLatLng{Latitude: p.Lat, Longitude: p.Lng}},
+	}
+}
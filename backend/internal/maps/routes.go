@@ -45,8 +45,58 @@ func NewClient(apiKey string) *Client {
 
 // ComputeRoute calls the Google Routes API to get a driving route.
 func (c *Client) ComputeRoute(ctx context.Context, origin, destination LatLng, intermediates []LatLng) (*RouteResult, error) {
-	reqBody := buildRoutesRequest(origin, destination, intermediates)
+	result, err := c.computeRoutes(ctx, buildRoutesRequest(origin, destination, intermediates),
+		"routes.duration,routes.distanceMeters,routes.polyline.encodedPolyline,routes.legs.duration,routes.legs.distanceMeters")
+	if err != nil {
+		return nil, err
+	}
+
+	route := result.Routes[0]
+	r := &RouteResult{
+		Polyline:       route.Polyline.EncodedPolyline,
+		DurationSec:    parseDurationStr(route.Duration),
+		DistanceMeters: route.DistanceMeters,
+	}
+
+	for _, leg := range route.Legs {
+		dur := parseDurationStr(leg.Duration)
+		r.Legs = append(r.Legs, RouteLeg{
+			DurationSec:    dur,
+			DistanceMeters: leg.DistanceMeters,
+			DurationText:   formatDuration(dur),
+			DistanceText:   formatDistance(leg.DistanceMeters),
+		})
+	}
+
+	return r, nil
+}
+
+// Place is a route endpoint given by coordinates or, when those are not
+// known, by an address for the Routes API to geocode.
+type Place struct {
+	LatLng  *LatLng
+	Address string
+}
+
+// TravelTime returns the traffic-aware driving time from one place to
+// another, departing now.
+func (c *Client) TravelTime(ctx context.Context, from, to Place) (time.Duration, error) {
+	req := routesAPIRequest{
+		Origin:            placeWaypoint(from),
+		Destination:       placeWaypoint(to),
+		TravelMode:        "DRIVE",
+		RoutingPreference: "TRAFFIC_AWARE",
+	}
+	result, err := c.computeRoutes(ctx, req, "routes.duration")
+	if err != nil {
+		return 0, err
+	}
+	return time.Duration(parseDurationStr(result.Routes[0].Duration)) * time.Second, nil
+}
 
+// computeRoutes posts reqBody to computeRoutes, asking for the fields in
+// fieldMask, and returns a response with at least one route.
+func (c *Client) computeRoutes(ctx context.Context, reqBody routesAPIRequest, fieldMask string) (*routesAPIResponse, error) {
 	jsonBody, err := json.Marshal(reqBody)
 	if err != nil {
 		return nil, fmt.Errorf("marshal request: %w", err)
@@ -60,8 +110,7 @@ func (c *Client) ComputeRoute(ctx context.Context, origin, destination LatLng, i
 
 	req.Header.Set("Content-Type", "application/json")
 	req.Header.Set("X-Goog-Api-Key", c.apiKey)
-	req.Header.Set("X-Goog-FieldMask",
-		"routes.duration,routes.distanceMeters,routes.polyline.encodedPolyline,routes.legs.duration,routes.legs.distanceMeters")
+	req.Header.Set("X-Goog-FieldMask", fieldMask)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -83,25 +132,14 @@ func (c *Client) ComputeRoute(ctx context.Context, origin, destination LatLng, i
 	if len(result.Routes) == 0 {
 		return nil, fmt.Errorf("no routes returned")
 	}
+	return &result, nil
+}
 
-	route := result.Routes[0]
-	r := &RouteResult{
-		Polyline:       route.Polyline.EncodedPolyline,
-		DurationSec:    parseDurationStr(route.Duration),
-		DistanceMeters: route.DistanceMeters,
-	}
-
-	for _, leg := range route.Legs {
-		dur := parseDurationStr(leg.Duration)
-		r.Legs = append(r.Legs, RouteLeg{
-			DurationSec:    dur,
-			DistanceMeters: leg.DistanceMeters,
-			DurationText:   formatDuration(dur),
-			DistanceText:   formatDistance(leg.DistanceMeters),
-		})
+func placeWaypoint(p Place) *routeWaypoint {
+	if p.LatLng != nil {
+		return toWaypoint(*p.LatLng)
 	}
-
-	return r, nil
+	return &routeWaypoint{Address: p.Address}
 }
 
 // --- Google Routes API request/response types ---
@@ -116,7 +154,8 @@ type routesAPIRequest struct {
 }
 
 type routeWaypoint struct {
-	Location *routeLocation `json:"location"`
+	Location *routeLocation `json:"location,omitempty"`
+	Address  string         `json:"address,omitempty"`
 }
 
 type routeLocation struct {
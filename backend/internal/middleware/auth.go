@@ -59,6 +59,23 @@ func JWTAuth(secret string, blacklist ...TokenBlacklist) func(http.Handler) http
 	}
 }
 
+// TokenFromQuery copies a bearer token from the given query parameter into the
+// Authorization header when the header is absent. Browsers cannot set headers
+// on EventSource connections, so streaming routes accept the token this way.
+// It must run before JWTAuth and only be mounted on those routes.
+func TokenFromQuery(param string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.Header.Get("Authorization") == "" {
+				if token := r.URL.Query().Get(param); token != "" {
+					r.Header.Set("Authorization", "Bearer "+token)
+				}
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
 func GetClaims(ctx context.Context) *jwt.Claims {
 	claims, ok := ctx.Value(ClaimsKey).(*jwt.Claims)
 	if !ok {
@@ -169,3 +169,41 @@ func TestGetClaims_NoClaims(t *testing.T) {
 		t.Error("expected nil claims for context without claims")
 	}
 }
+
+func TestTokenFromQuery_PromotesQueryToken(t *testing.T) {
+	token, _ := jwt.GenerateAccessToken(testSecret, 15*time.Minute, "user-1", "emp001", "dispatcher")
+
+	handler := TokenFromQuery("access_token")(JWTAuth(testSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if GetClaims(r.Context()) == nil {
+			t.Error("expected claims in context")
+		}
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest("GET", "/stream?access_token="+token, nil)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
+
+func TestTokenFromQuery_HeaderTakesPrecedence(t *testing.T) {
+	token, _ := jwt.GenerateAccessToken(testSecret, 15*time.Minute, "user-1", "emp001", "dispatcher")
+
+	handler := TokenFromQuery("access_token")(JWTAuth(testSecret)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})))
+
+	req := httptest.NewRequest("GET", "/stream?access_token=garbage", nil)
+	req.Header.Set("Authorization", "Bearer "+token)
+	rec := httptest.NewRecorder()
+
+	handler.ServeHTTP(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want %d", rec.Code, http.StatusOK)
+	}
+}
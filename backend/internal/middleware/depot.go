@@ -0,0 +1,51 @@
+package middleware
+
+import (
+	"context"
+	"log"
+	"net/http"
+
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+const DepotIDsKey contextKey = "depot_ids"
+
+// DepotLoader looks up the depots a user is a member of.
+type DepotLoader interface {
+	DepotIDsOf(ctx context.Context, userID string) ([]string, error)
+}
+
+// DepotScope puts the depots a dispatcher works into the request context, so
+// lists and candidate searches can be limited to them. Other roles are not
+// scoped. It must run after JWTAuth.
+func DepotScope(loader DepotLoader) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims := GetClaims(r.Context())
+			if claims == nil || claims.Role != "dispatcher" {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			depotIDs, err := loader.DepotIDsOf(r.Context(), claims.UserID)
+			if err != nil {
+				log.Printf("[depot] depots of %s: %v", claims.UserID, err)
+				apperror.WriteError(w, apperror.ErrInternal)
+				return
+			}
+			if depotIDs == nil {
+				depotIDs = []string{}
+			}
+
+			ctx := context.WithValue(r.Context(), DepotIDsKey, depotIDs)
+			next.ServeHTTP(w, r.WithContext(ctx))
+		})
+	}
+}
+
+// GetDepotIDs returns the depots the caller is limited to, nil when the
+// caller sees every depot.
+func GetDepotIDs(ctx context.Context) []string {
+	depotIDs, _ := ctx.Value(DepotIDsKey).([]string)
+	return depotIDs
+}
@@ -0,0 +1,64 @@
+package middleware
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+type stubDepotLoader struct {
+	ids []string
+	err error
+}
+
+func (s stubDepotLoader) DepotIDsOf(ctx context.Context, userID string) ([]string, error) {
+	return s.ids, s.err
+}
+
+func TestDepotScope(t *testing.T) {
+	tests := []struct {
+		name     string
+		role     string
+		loader   stubDepotLoader
+		wantIDs  []string
+		wantNil  bool
+		wantCode int
+	}{
+		{"dispatcher gets their depots", "dispatcher", stubDepotLoader{ids: []string{"d1", "d2"}}, []string{"d1", "d2"}, false, http.StatusOK},
+		{"dispatcher without depots sees none", "dispatcher", stubDepotLoader{}, []string{}, false, http.StatusOK},
+		{"admin is not scoped", "admin", stubDepotLoader{ids: []string{"d1"}}, nil, true, http.StatusOK},
+		{"viewer is not scoped", "viewer", stubDepotLoader{ids: []string{"d1"}}, nil, true, http.StatusOK},
+		{"lookup failure", "dispatcher", stubDepotLoader{err: errors.New("db down")}, nil, true, http.StatusInternalServerError},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			var got []string
+			handler := DepotScope(tc.loader)(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				got = GetDepotIDs(r.Context())
+				w.WriteHeader(http.StatusOK)
+			}))
+
+			req := httptest.NewRequest("GET", "/test", nil)
+			req = req.WithContext(makeAuthContext(tc.role))
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+
+			if rec.Code != tc.wantCode {
+				t.Fatalf("status = %d, want %d", rec.Code, tc.wantCode)
+			}
+			if (got == nil) != tc.wantNil {
+				t.Fatalf("GetDepotIDs nil = %v, want %v", got == nil, tc.wantNil)
+			}
+			if len(got) != len(tc.wantIDs) {
+				t.Fatalf("GetDepotIDs = %v, want %v", got, tc.wantIDs)
+			}
+			for i := range got {
+				if got[i] != tc.wantIDs[i] {
+					t.Errorf("GetDepotIDs = %v, want %v", got, tc.wantIDs)
+				}
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package middleware
 import (
 	"log"
 	"net/http"
+	"strings"
 	"time"
 
 	chiMiddleware "github.com/go-chi/chi/v5/middleware"
@@ -18,6 +19,12 @@ func (rw *responseWriter) WriteHeader(code int) {
 	rw.ResponseWriter.WriteHeader(code)
 }
 
+// Unwrap exposes the underlying writer so http.ResponseController can reach
+// Flush and SetWriteDeadline (needed by streaming endpoints).
+func (rw *responseWriter) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
 func Logger(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
@@ -27,7 +34,25 @@ func Logger(next http.Handler) http.Handler {
 
 		reqID := chiMiddleware.GetReqID(r.Context())
 		log.Printf("[%s] %s %s %d %s remote=%s ua=%s",
-			reqID, r.Method, r.URL.Path, wrapped.status,
+			reqID, r.Method, logPath(r.URL.Path), wrapped.status,
 			time.Since(start), r.RemoteAddr, r.UserAgent())
 	})
 }
+
+// logPath hides the token in calendar feed paths: it is the feed's only
+// credential and would otherwise end up in every access log.
+func logPath(path string) string {
+	i := strings.Index(path, "/calendar/")
+	if i < 0 {
+		return path
+	}
+	rest := path[i+len("/calendar/"):]
+	if rest == "" {
+		return path
+	}
+	ext := ""
+	if j := strings.LastIndexByte(rest, '.'); j >= 0 {
+		ext = rest[j:]
+	}
+	return path[:i] + "/calendar/[redacted]" + ext
+}
@@ -0,0 +1,21 @@
+package middleware
+
+import "testing"
+
+func TestLogPath(t *testing.T) {
+	tests := []struct {
+		path string
+		want string
+	}{
+		{"/api/v1/calendar/s3cr3t-Tok_en.ics", "/api/v1/calendar/[redacted].ics"},
+		{"/api/v1/calendar/s3cr3t", "/api/v1/calendar/[redacted]"},
+		{"/api/v1/calendar-feeds", "/api/v1/calendar-feeds"},
+		{"/api/v1/calendar-feeds/f-1", "/api/v1/calendar-feeds/f-1"},
+		{"/api/v1/reservations", "/api/v1/reservations"},
+	}
+	for _, tc := range tests {
+		if got := logPath(tc.path); got != tc.want {
+			t.Errorf("logPath(%q) = %q, want %q", tc.path, got, tc.want)
+		}
+	}
+}
@@ -9,11 +9,23 @@ const (
 	DriverStatusWaiting DriverStatus = "waiting"
 )
 
+// DriverAttendance is one shift. VehicleID is the vehicle the driver checked
+// out at clock-in and returns at clock-out; the odometer readings are its.
 type DriverAttendance struct {
-	ID           string       `db:"id" json:"id"`
-	DriverID     string       `db:"driver_id" json:"driver_id"`
-	DriverStatus DriverStatus `db:"driver_status" json:"driver_status"`
-	ClockInAt    time.Time    `db:"clock_in_at" json:"clock_in_at"`
-	ClockOutAt   *time.Time   `db:"clock_out_at" json:"clock_out_at,omitempty"`
-	CreatedAt    time.Time    `db:"created_at" json:"created_at"`
+	ID                 string       `db:"id" json:"id"`
+	DriverID           string       `db:"driver_id" json:"driver_id"`
+	VehicleID          *string      `db:"vehicle_id" json:"vehicle_id,omitempty"`
+	DriverStatus       DriverStatus `db:"driver_status" json:"driver_status"`
+	ClockInAt          time.Time    `db:"clock_in_at" json:"clock_in_at"`
+	ClockOutAt         *time.Time   `db:"clock_out_at" json:"clock_out_at,omitempty"`
+	ClockInOdometerKm  *int         `db:"clock_in_odometer_km" json:"clock_in_odometer_km,omitempty"`
+	ClockOutOdometerKm *int         `db:"clock_out_odometer_km" json:"clock_out_odometer_km,omitempty"`
+	CreatedAt          time.Time    `db:"created_at" json:"created_at"`
+}
+
+// VehicleAssignment is a shift seen from the vehicle checked out on it: who
+// held the vehicle and when.
+type VehicleAssignment struct {
+	DriverAttendance
+	DriverName string `db:"driver_name" json:"driver_name"`
 }
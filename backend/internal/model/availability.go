@@ -0,0 +1,29 @@
+package model
+
+import "time"
+
+// AvailabilityKind says why a vehicle is unavailable during an interval, or
+// that it is free.
+type AvailabilityKind string
+
+const (
+	AvailabilityFree         AvailabilityKind = "free"
+	AvailabilityReservation  AvailabilityKind = "reservation"
+	AvailabilityDispatch     AvailabilityKind = "dispatch"
+	AvailabilityMaintenance  AvailabilityKind = "maintenance"
+	AvailabilityDriverAbsent AvailabilityKind = "driver_absent"
+)
+
+// AvailabilityInterval is one row of the fleet availability grid: a busy or
+// free interval of one vehicle, already clipped to the requested window.
+type AvailabilityInterval struct {
+	VehicleID    string           `db:"vehicle_id"`
+	VehicleName  string           `db:"vehicle_name"`
+	LicensePlate string           `db:"license_plate"`
+	DriverName   string           `db:"driver_name"`
+	Kind         AvailabilityKind `db:"kind"`
+	RefID        *string          `db:"ref_id"`
+	Status       *string          `db:"status"`
+	Start        time.Time        `db:"start_time"`
+	End          time.Time        `db:"end_time"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+type CalendarFeedType string
+
+const (
+	CalendarFeedVehicle   CalendarFeedType = "vehicle"
+	CalendarFeedDriver    CalendarFeedType = "driver"
+	CalendarFeedRequester CalendarFeedType = "requester"
+)
+
+func (t CalendarFeedType) IsValid() bool {
+	switch t {
+	case CalendarFeedVehicle, CalendarFeedDriver, CalendarFeedRequester:
+		return true
+	}
+	return false
+}
+
+// CalendarFeed is a private iCalendar URL listing the reservations of a
+// vehicle, of the trips assigned to a driver, or of a requester. Whoever holds
+// the token can read it until it is revoked. Only the token's hash is
+// stored, so Token is set just on the feed returned when it is created.
+type CalendarFeed struct {
+	ID          string           `db:"id" json:"id"`
+	Token       string           `db:"-" json:"token,omitempty"`
+	TokenHash   string           `db:"token_hash" json:"-"`
+	SubjectType CalendarFeedType `db:"subject_type" json:"subject_type"`
+	SubjectID   string           `db:"subject_id" json:"subject_id"`
+	CreatedBy   string           `db:"created_by" json:"created_by"`
+	CreatedAt   time.Time        `db:"created_at" json:"created_at"`
+	RevokedAt   *time.Time       `db:"revoked_at" json:"revoked_at,omitempty"`
+}
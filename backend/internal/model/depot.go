@@ -0,0 +1,30 @@
+package model
+
+import "time"
+
+// Depot is an office vehicles and drivers belong to. Dispatchers are members
+// of one or more depots and only see and act on those.
+type Depot struct {
+	ID        string    `db:"id" json:"id"`
+	Name      string    `db:"name" json:"name"`
+	Address   *string   `db:"address" json:"address,omitempty"`
+	Latitude  *float64  `db:"latitude" json:"latitude,omitempty"`
+	Longitude *float64  `db:"longitude" json:"longitude,omitempty"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time `db:"updated_at" json:"updated_at"`
+}
+
+// InDepots reports whether something of depot depotID lies within the depot
+// scope depotIDs. A nil scope spans every depot, and something without a
+// depot is shared by all.
+func InDepots(depotIDs []string, depotID *string) bool {
+	if depotIDs == nil || depotID == nil {
+		return true
+	}
+	for _, id := range depotIDs {
+		if id == *depotID {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,27 @@
+package model
+
+import "testing"
+
+func TestInDepots(t *testing.T) {
+	north, south := "north", "south"
+
+	tests := []struct {
+		name     string
+		depotIDs []string
+		depotID  *string
+		want     bool
+	}{
+		{"unscoped sees every depot", nil, &north, true},
+		{"member depot", []string{"south", "north"}, &north, true},
+		{"other depot", []string{"south"}, &north, false},
+		{"no depots sees none", []string{}, &south, false},
+		{"shared vehicle is seen by all", []string{}, nil, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := InDepots(tc.depotIDs, tc.depotID); got != tc.want {
+				t.Errorf("InDepots(%v, %v) = %v, want %v", tc.depotIDs, tc.depotID, got, tc.want)
+			}
+		})
+	}
+}
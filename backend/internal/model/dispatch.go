@@ -1,6 +1,10 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
 
 type DispatchStatus string
 
@@ -40,16 +44,45 @@ type Dispatch struct {
 	CancelledAt          *time.Time     `db:"cancelled_at" json:"cancelled_at,omitempty"`
 	EstimatedEndAt       *time.Time     `db:"estimated_end_at" json:"estimated_end_at,omitempty"`
 	CancelReason         *string        `db:"cancel_reason" json:"cancel_reason,omitempty"`
+	DeclinedVehicleIDs   pq.StringArray `db:"declined_vehicle_ids" json:"declined_vehicle_ids,omitempty"`
+	ReservationID        *string        `db:"reservation_id" json:"reservation_id,omitempty"`
+	Version              int            `db:"version" json:"version"`
 	CreatedAt            time.Time      `db:"created_at" json:"created_at"`
 	UpdatedAt            time.Time      `db:"updated_at" json:"updated_at"`
 }
 
+// ETA snapshot triggers: what prompted the candidate list to be recorded.
+const (
+	ETATriggerCalculate   = "calculate"
+	ETATriggerAssign      = "assign"
+	ETATriggerAutoSuggest = "auto_suggest"
+	ETATriggerAutoAssign  = "auto_assign"
+)
+
+// DispatchETASnapshot is one candidate vehicle's estimate at the moment a
+// dispatch decision was made. Rows sharing a BatchID were recorded together.
 type DispatchETASnapshot struct {
-	ID           string    `db:"id" json:"id"`
-	DispatchID   string    `db:"dispatch_id" json:"dispatch_id"`
-	VehicleID    string    `db:"vehicle_id" json:"vehicle_id"`
-	VehicleName  string    `db:"vehicle_name" json:"vehicle_name,omitempty"`
-	DurationSec  int       `db:"duration_sec" json:"duration_sec"`
-	DistanceM    int       `db:"distance_m" json:"distance_m"`
-	CalculatedAt time.Time `db:"calculated_at" json:"calculated_at"`
+	ID            string    `db:"id" json:"id"`
+	BatchID       string    `db:"batch_id" json:"batch_id"`
+	DispatchID    string    `db:"dispatch_id" json:"dispatch_id"`
+	VehicleID     string    `db:"vehicle_id" json:"vehicle_id"`
+	VehicleName   string    `db:"vehicle_name" json:"vehicle_name,omitempty"`
+	VehicleStatus string    `db:"vehicle_status" json:"vehicle_status"`
+	OriginLat     float64   `db:"origin_lat" json:"origin_lat"`
+	OriginLng     float64   `db:"origin_lng" json:"origin_lng"`
+	DurationSec   int       `db:"duration_sec" json:"duration_sec"`
+	DistanceM     int       `db:"distance_m" json:"distance_m"`
+	Provider      string    `db:"provider" json:"provider"`
+	Trigger       string    `db:"trigger" json:"trigger"`
+	ActorID       *string   `db:"actor_id" json:"actor_id,omitempty"`
+	ActorName     *string   `db:"actor_name" json:"actor_name,omitempty"`
+	Chosen        bool      `db:"chosen" json:"chosen"`
+	CalculatedAt  time.Time `db:"calculated_at" json:"calculated_at"`
+}
+
+// VehicleWorkload summarises a vehicle's current shift for fair dispatching.
+type VehicleWorkload struct {
+	VehicleID  string     `db:"vehicle_id" json:"vehicle_id"`
+	ShiftTrips int        `db:"shift_trips" json:"shift_trips"`
+	IdleSince  *time.Time `db:"idle_since" json:"idle_since,omitempty"`
 }
@@ -0,0 +1,117 @@
+package model
+
+// DispatchActor is the kind of party triggering a dispatch status change.
+type DispatchActor string
+
+const (
+	DispatchActorDriver     DispatchActor = "driver"
+	DispatchActorDispatcher DispatchActor = "dispatcher"
+	DispatchActorRequester  DispatchActor = "requester"
+	DispatchActorSystem     DispatchActor = "system"
+)
+
+// DispatchActors lists every kind of actor.
+var DispatchActors = []DispatchActor{
+	DispatchActorDriver,
+	DispatchActorDispatcher,
+	DispatchActorRequester,
+	DispatchActorSystem,
+}
+
+// DispatchActorForRole maps a user's role to the actor it acts as on
+// dispatches. Admins act as dispatchers; everyone else who can book acts as
+// the requester.
+func DispatchActorForRole(role Role) DispatchActor {
+	switch role {
+	case RoleDriver:
+		return DispatchActorDriver
+	case RoleAdmin, RoleDispatcher:
+		return DispatchActorDispatcher
+	default:
+		return DispatchActorRequester
+	}
+}
+
+// DispatchTransition is one allowed edge of the dispatch state machine.
+type DispatchTransition struct {
+	From   DispatchStatus  `json:"from"`
+	To     DispatchStatus  `json:"to"`
+	Action string          `json:"action"`
+	Actors []DispatchActor `json:"actors"`
+}
+
+// AllowedBy reports whether actor may trigger the transition.
+func (t DispatchTransition) AllowedBy(actor DispatchActor) bool {
+	for _, a := range t.Actors {
+		if a == actor {
+			return true
+		}
+	}
+	return false
+}
+
+// DispatchStatuses lists every dispatch status in lifecycle order.
+var DispatchStatuses = []DispatchStatus{
+	DispatchStatusPending,
+	DispatchStatusAssigned,
+	DispatchStatusAccepted,
+	DispatchStatusEnRoute,
+	DispatchStatusArrived,
+	DispatchStatusCompleted,
+	DispatchStatusCancelled,
+}
+
+// DispatchTransitions is the dispatch state machine: every status change not
+// listed here is rejected. "arrived" means arrived at the pickup; quick-board
+// trips start en route with the passenger aboard and end with an alight.
+var DispatchTransitions = []DispatchTransition{
+	{DispatchStatusPending, DispatchStatusAssigned, "assign", []DispatchActor{DispatchActorDispatcher, DispatchActorSystem}},
+	{DispatchStatusPending, DispatchStatusEnRoute, "quick_board", []DispatchActor{DispatchActorDispatcher, DispatchActorDriver}},
+	{DispatchStatusPending, DispatchStatusCancelled, "cancel", []DispatchActor{DispatchActorDispatcher, DispatchActorRequester, DispatchActorSystem}},
+
+	{DispatchStatusAssigned, DispatchStatusAccepted, "accept", []DispatchActor{DispatchActorDriver}},
+	{DispatchStatusAssigned, DispatchStatusPending, "decline", []DispatchActor{DispatchActorDriver, DispatchActorSystem}},
+	{DispatchStatusAssigned, DispatchStatusCancelled, "cancel", []DispatchActor{DispatchActorDispatcher, DispatchActorRequester, DispatchActorSystem}},
+
+	{DispatchStatusAccepted, DispatchStatusEnRoute, "depart", []DispatchActor{DispatchActorDriver}},
+	{DispatchStatusAccepted, DispatchStatusCancelled, "cancel", []DispatchActor{DispatchActorDispatcher}},
+
+	{DispatchStatusEnRoute, DispatchStatusArrived, "arrive", []DispatchActor{DispatchActorDriver}},
+	{DispatchStatusEnRoute, DispatchStatusCompleted, "alight", []DispatchActor{DispatchActorDriver, DispatchActorDispatcher}},
+	{DispatchStatusEnRoute, DispatchStatusCancelled, "cancel", []DispatchActor{DispatchActorDispatcher}},
+
+	{DispatchStatusArrived, DispatchStatusCompleted, "complete", []DispatchActor{DispatchActorDriver, DispatchActorDispatcher}},
+	{DispatchStatusArrived, DispatchStatusCancelled, "cancel", []DispatchActor{DispatchActorDispatcher}},
+}
+
+// IsTerminal reports whether no transition leaves the status.
+func (s DispatchStatus) IsTerminal() bool {
+	for _, t := range DispatchTransitions {
+		if t.From == s {
+			return false
+		}
+	}
+	return true
+}
+
+// FindDispatchTransition returns the transition from one status to another.
+func FindDispatchTransition(from, to DispatchStatus) (DispatchTransition, bool) {
+	for _, t := range DispatchTransitions {
+		if t.From == from && t.To == to {
+			return t, true
+		}
+	}
+	return DispatchTransition{}, false
+}
+
+// NextDispatchStatuses lists the statuses actor may move a dispatch to from
+// its current status.
+func NextDispatchStatuses(from DispatchStatus, actor DispatchActor) []DispatchStatus {
+	var next []DispatchStatus
+	for _, t := range DispatchTransitions {
+		if t.From == from && t.AllowedBy(actor) {
+			next = append(next, t.To)
+		}
+	}
+	return next
+}
@@ -0,0 +1,90 @@
+package model
+
+import "testing"
+
+func TestDispatchTransitions_Allowed(t *testing.T) {
+	tests := []struct {
+		from  DispatchStatus
+		to    DispatchStatus
+		actor DispatchActor
+		want  bool
+	}{
+		{DispatchStatusPending, DispatchStatusAssigned, DispatchActorDispatcher, true},
+		{DispatchStatusAssigned, DispatchStatusAccepted, DispatchActorDriver, true},
+		{DispatchStatusAssigned, DispatchStatusAccepted, DispatchActorDispatcher, false},
+		{DispatchStatusAssigned, DispatchStatusCancelled, DispatchActorRequester, true},
+		{DispatchStatusAccepted, DispatchStatusCancelled, DispatchActorRequester, false},
+		{DispatchStatusEnRoute, DispatchStatusCompleted, DispatchActorDispatcher, true},
+		{DispatchStatusCompleted, DispatchStatusEnRoute, DispatchActorDispatcher, false},
+		{DispatchStatusAssigned, DispatchStatusCompleted, DispatchActorDriver, false},
+	}
+
+	for _, tc := range tests {
+		t.Run(string(tc.from)+"->"+string(tc.to), func(t *testing.T) {
+			tr, ok := FindDispatchTransition(tc.from, tc.to)
+			if got := ok && tr.AllowedBy(tc.actor); got != tc.want {
+				t.Errorf("%s by %s = %v, want %v", tc.from+"->"+tc.to, tc.actor, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestDispatchTransitions_Complete(t *testing.T) {
+	known := map[DispatchStatus]bool{}
+	for _, s := range DispatchStatuses {
+		known[s] = true
+	}
+	seen := map[[2]DispatchStatus]bool{}
+	for _, tr := range DispatchTransitions {
+		if !known[tr.From] || !known[tr.To] {
+			t.Errorf("transition %s->%s uses an unknown status", tr.From, tr.To)
+		}
+		if len(tr.Actors) == 0 || tr.Action == "" {
+			t.Errorf("transition %s->%s needs an action and actors", tr.From, tr.To)
+		}
+		k := [2]DispatchStatus{tr.From, tr.To}
+		if seen[k] {
+			t.Errorf("transition %s->%s listed twice", tr.From, tr.To)
+		}
+		seen[k] = true
+	}
+}
+
+func TestDispatchStatus_IsTerminal(t *testing.T) {
+	for _, s := range DispatchStatuses {
+		want := s == DispatchStatusCompleted || s == DispatchStatusCancelled
+		if got := s.IsTerminal(); got != want {
+			t.Errorf("%s.IsTerminal() = %v, want %v", s, got, want)
+		}
+	}
+}
+
+func TestNextDispatchStatuses(t *testing.T) {
+	got := NextDispatchStatuses(DispatchStatusAssigned, DispatchActorDriver)
+	want := []DispatchStatus{DispatchStatusAccepted, DispatchStatusPending}
+	if len(got) != len(want) {
+		t.Fatalf("next = %v, want %v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("next = %v, want %v", got, want)
+		}
+	}
+}
+
+func TestDispatchActorForRole(t *testing.T) {
+	tests := []struct {
+		role Role
+		want DispatchActor
+	}{
+		{RoleAdmin, DispatchActorDispatcher},
+		{RoleDispatcher, DispatchActorDispatcher},
+		{RoleDriver, DispatchActorDriver},
+		{RolePassenger, DispatchActorRequester},
+	}
+	for _, tc := range tests {
+		if got := DispatchActorForRole(tc.role); got != tc.want {
+			t.Errorf("DispatchActorForRole(%q) = %q, want %q", tc.role, got, tc.want)
+		}
+	}
+}
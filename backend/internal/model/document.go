@@ -0,0 +1,84 @@
+package model
+
+import "time"
+
+type DocumentType string
+
+const (
+	DocumentTypeRegistration  DocumentType = "registration"
+	DocumentTypeInsurance     DocumentType = "insurance"
+	DocumentTypeEmissionTest  DocumentType = "emission_test"
+	DocumentTypeDriverLicence DocumentType = "driver_licence"
+	DocumentTypeOther         DocumentType = "other"
+)
+
+func (t DocumentType) IsValid() bool {
+	switch t {
+	case DocumentTypeRegistration, DocumentTypeInsurance, DocumentTypeEmissionTest,
+		DocumentTypeDriverLicence, DocumentTypeOther:
+		return true
+	}
+	return false
+}
+
+// IsCompliance reports whether a vehicle may not be assigned while its
+// current document of type t has expired.
+func (t DocumentType) IsCompliance() bool {
+	return t == DocumentTypeRegistration || t == DocumentTypeInsurance || t == DocumentTypeEmissionTest
+}
+
+// AppliesTo reports whether documents of type t can belong to a vehicle
+// (forVehicle) or otherwise to a user. Other documents can belong to either.
+func (t DocumentType) AppliesTo(forVehicle bool) bool {
+	switch t {
+	case DocumentTypeDriverLicence:
+		return !forVehicle
+	case DocumentTypeOther:
+		return true
+	}
+	return forVehicle
+}
+
+// Document is a paper held for a vehicle or a user, with an optional scan.
+// Dates are calendar days; a document is valid through ExpiresOn (UTC).
+type Document struct {
+	ID        string       `db:"id" json:"id"`
+	VehicleID *string      `db:"vehicle_id" json:"vehicle_id,omitempty"`
+	UserID    *string      `db:"user_id" json:"user_id,omitempty"`
+	Type      DocumentType `db:"type" json:"type"`
+	Number    *string      `db:"number" json:"number,omitempty"`
+	IssuedOn  *time.Time   `db:"issued_on" json:"issued_on,omitempty"`
+	ExpiresOn *time.Time   `db:"expires_on" json:"expires_on,omitempty"`
+	ScanFile  *string      `db:"scan_file" json:"scan_file,omitempty"`
+	Notes     *string      `db:"notes" json:"notes,omitempty"`
+	CreatedBy string       `db:"created_by" json:"created_by"`
+	CreatedAt time.Time    `db:"created_at" json:"created_at"`
+	UpdatedAt time.Time    `db:"updated_at" json:"updated_at"`
+}
+
+const (
+	DocumentStageExpiring = "expiring"
+	DocumentStageExpired  = "expired"
+)
+
+// ExpiryStage says which warning, if any, a document expiring on expiresOn
+// is due on day today: expiring from warnDays before its expiry date up to
+// that date, expired after it.
+func ExpiryStage(expiresOn, today time.Time, warnDays int) (string, bool) {
+	expiresOn = expiresOn.UTC().Truncate(24 * time.Hour)
+	today = today.UTC().Truncate(24 * time.Hour)
+	switch {
+	case today.After(expiresOn):
+		return DocumentStageExpired, true
+	case !today.Before(expiresOn.AddDate(0, 0, -warnDays)):
+		return DocumentStageExpiring, true
+	}
+	return "", false
+}
+
+// DueDocument is the current document of its subject and type with an
+// expiry warning due.
+type DueDocument struct {
+	Document
+	SubjectName string `db:"subject_name"`
+}
@@ -0,0 +1,52 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExpiryStage(t *testing.T) {
+	expires := time.Date(2026, 11, 30, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name      string
+		today     time.Time
+		wantStage string
+		wantOK    bool
+	}{
+		{"well ahead", time.Date(2026, 10, 1, 12, 0, 0, 0, time.UTC), "", false},
+		{"day before warning", time.Date(2026, 10, 31, 23, 59, 0, 0, time.UTC), "", false},
+		{"first warning day", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), DocumentStageExpiring, true},
+		{"expiry day is still valid", time.Date(2026, 11, 30, 18, 0, 0, 0, time.UTC), DocumentStageExpiring, true},
+		{"day after expiry", time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC), DocumentStageExpired, true},
+		{"other zone, same UTC day", time.Date(2026, 12, 1, 8, 0, 0, 0, time.FixedZone("JST", 9*3600)), DocumentStageExpiring, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			stage, ok := ExpiryStage(expires, tc.today, 29)
+			if stage != tc.wantStage || ok != tc.wantOK {
+				t.Errorf("ExpiryStage = (%q, %v), want (%q, %v)", stage, ok, tc.wantStage, tc.wantOK)
+			}
+		})
+	}
+}
+
+func TestDocumentType_AppliesTo(t *testing.T) {
+	tests := []struct {
+		typ        DocumentType
+		forVehicle bool
+		want       bool
+	}{
+		{DocumentTypeInsurance, true, true},
+		{DocumentTypeInsurance, false, false},
+		{DocumentTypeDriverLicence, false, true},
+		{DocumentTypeDriverLicence, true, false},
+		{DocumentTypeOther, true, true},
+		{DocumentTypeOther, false, true},
+	}
+	for _, tc := range tests {
+		if got := tc.typ.AppliesTo(tc.forVehicle); got != tc.want {
+			t.Errorf("%s.AppliesTo(%v) = %v, want %v", tc.typ, tc.forVehicle, got, tc.want)
+		}
+	}
+}
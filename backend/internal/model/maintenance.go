@@ -0,0 +1,92 @@
+package model
+
+import "time"
+
+type MaintenanceStatus string
+
+const (
+	MaintenanceStatusPlanned    MaintenanceStatus = "planned"
+	MaintenanceStatusInProgress MaintenanceStatus = "in_progress"
+	MaintenanceStatusDone       MaintenanceStatus = "done"
+	MaintenanceStatusCancelled  MaintenanceStatus = "cancelled"
+)
+
+// IsOpen reports whether a window in status still blocks its vehicle.
+func (s MaintenanceStatus) IsOpen() bool {
+	return s == MaintenanceStatusPlanned || s == MaintenanceStatusInProgress
+}
+
+type MaintenanceType string
+
+const (
+	MaintenanceTypeInspection MaintenanceType = "inspection"
+	MaintenanceTypeOilChange  MaintenanceType = "oil_change"
+	MaintenanceTypeTyres      MaintenanceType = "tyres"
+	MaintenanceTypeBrakes     MaintenanceType = "brakes"
+	MaintenanceTypeRepair     MaintenanceType = "repair"
+	MaintenanceTypeCleaning   MaintenanceType = "cleaning"
+	MaintenanceTypeOther      MaintenanceType = "other"
+)
+
+func (t MaintenanceType) IsValid() bool {
+	switch t {
+	case MaintenanceTypeInspection, MaintenanceTypeOilChange, MaintenanceTypeTyres,
+		MaintenanceTypeBrakes, MaintenanceTypeRepair, MaintenanceTypeCleaning, MaintenanceTypeOther:
+		return true
+	}
+	return false
+}
+
+// MaintenanceWindow takes a vehicle out of service. It blocks the vehicle
+// from StartTime to EndTime while planned, and until it is done while in
+// progress, however long the work overruns.
+type MaintenanceWindow struct {
+	ID          string            `db:"id" json:"id"`
+	VehicleID   string            `db:"vehicle_id" json:"vehicle_id"`
+	ScheduleID  *string           `db:"schedule_id" json:"schedule_id,omitempty"`
+	Type        MaintenanceType   `db:"type" json:"type"`
+	Status      MaintenanceStatus `db:"status" json:"status"`
+	StartTime   time.Time         `db:"start_time" json:"start_time"`
+	EndTime     time.Time         `db:"end_time" json:"end_time"`
+	Vendor      *string           `db:"vendor" json:"vendor,omitempty"`
+	Notes       *string           `db:"notes" json:"notes,omitempty"`
+	OdometerKm  *int              `db:"odometer_km" json:"odometer_km,omitempty"`
+	CreatedBy   string            `db:"created_by" json:"created_by"`
+	CompletedAt *time.Time        `db:"completed_at" json:"completed_at,omitempty"`
+	CreatedAt   time.Time         `db:"created_at" json:"created_at"`
+	UpdatedAt   time.Time         `db:"updated_at" json:"updated_at"`
+}
+
+// MaintenanceSchedule is recurring maintenance, due IntervalDays after it
+// was last done or IntervalKm further on the odometer, whichever comes
+// first. At least one interval is set.
+type MaintenanceSchedule struct {
+	ID           string          `db:"id" json:"id"`
+	VehicleID    string          `db:"vehicle_id" json:"vehicle_id"`
+	Type         MaintenanceType `db:"type" json:"type"`
+	Vendor       *string         `db:"vendor" json:"vendor,omitempty"`
+	Notes        *string         `db:"notes" json:"notes,omitempty"`
+	DurationMin  int             `db:"duration_min" json:"duration_min"`
+	IntervalDays *int            `db:"interval_days" json:"interval_days,omitempty"`
+	IntervalKm   *int            `db:"interval_km" json:"interval_km,omitempty"`
+	LastDoneAt   time.Time       `db:"last_done_at" json:"last_done_at"`
+	LastDoneKm   *int            `db:"last_done_km" json:"last_done_km,omitempty"`
+	CreatedBy    string          `db:"created_by" json:"created_by"`
+	CreatedAt    time.Time       `db:"created_at" json:"created_at"`
+	UpdatedAt    time.Time       `db:"updated_at" json:"updated_at"`
+}
+
+// DueAt returns when the schedule falls due by date, given the vehicle's
+// current odometer reading (nil when unknown). A schedule already due by
+// distance is due at now. It is false when neither interval can be
+// evaluated yet.
+func (s *MaintenanceSchedule) DueAt(odometerKm *int, now time.Time) (time.Time, bool) {
+	if s.IntervalKm != nil && s.LastDoneKm != nil && odometerKm != nil &&
+		*odometerKm >= *s.LastDoneKm+*s.IntervalKm {
+		return now, true
+	}
+	if s.IntervalDays != nil {
+		return s.LastDoneAt.AddDate(0, 0, *s.IntervalDays), true
+	}
+	return time.Time{}, false
+}
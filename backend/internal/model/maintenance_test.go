@@ -0,0 +1,38 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMaintenanceSchedule_DueAt(t *testing.T) {
+	now := time.Date(2026, 11, 2, 9, 0, 0, 0, time.UTC)
+	done := now.AddDate(0, -2, 0)
+	intp := func(n int) *int { return &n }
+
+	tests := []struct {
+		name     string
+		days     *int
+		km       *int
+		doneKm   *int
+		odometer *int
+		want     time.Time
+		wantOK   bool
+	}{
+		{"by date", intp(90), nil, nil, nil, done.AddDate(0, 0, 90), true},
+		{"distance not reached", intp(90), intp(10000), intp(50000), intp(55000), done.AddDate(0, 0, 90), true},
+		{"distance reached first", intp(90), intp(10000), intp(50000), intp(60000), now, true},
+		{"distance only, reached", nil, intp(10000), intp(50000), intp(61000), now, true},
+		{"distance only, not reached", nil, intp(10000), intp(50000), intp(52000), time.Time{}, false},
+		{"distance only, odometer unknown", nil, intp(10000), intp(50000), nil, time.Time{}, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			s := &MaintenanceSchedule{IntervalDays: tc.days, IntervalKm: tc.km, LastDoneAt: done, LastDoneKm: tc.doneKm}
+			got, ok := s.DueAt(tc.odometer, now)
+			if !got.Equal(tc.want) || ok != tc.wantOK {
+				t.Errorf("DueAt = (%v, %v), want (%v, %v)", got, ok, tc.want, tc.wantOK)
+			}
+		})
+	}
+}
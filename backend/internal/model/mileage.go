@@ -0,0 +1,51 @@
+package model
+
+import (
+	"math"
+	"time"
+)
+
+// FuelLog is one refuelling of a vehicle, recorded by its driver.
+type FuelLog struct {
+	ID         string    `db:"id" json:"id"`
+	VehicleID  string    `db:"vehicle_id" json:"vehicle_id"`
+	DriverID   string    `db:"driver_id" json:"driver_id"`
+	Litres     float64   `db:"litres" json:"litres"`
+	Cost       float64   `db:"cost" json:"cost"`
+	Station    *string   `db:"station" json:"station,omitempty"`
+	OdometerKm *int      `db:"odometer_km" json:"odometer_km,omitempty"`
+	ReceiptURL *string   `db:"receipt_url" json:"receipt_url,omitempty"`
+	FueledAt   time.Time `db:"fueled_at" json:"fueled_at"`
+	CreatedAt  time.Time `db:"created_at" json:"created_at"`
+}
+
+// DailyDistance is the distance a vehicle covered on one UTC day.
+type DailyDistance struct {
+	Day time.Time `db:"day"`
+	Km  float64   `db:"km"`
+}
+
+// ShiftDistance is the distance shown on the odometer over one shift on a
+// vehicle, next to the GPS distance tracked over the same shift. Day is the
+// UTC day the shift clocked in.
+type ShiftDistance struct {
+	Day        time.Time `db:"day"`
+	OdometerKm float64   `db:"odometer_km"`
+	GPSKm      float64   `db:"gps_km"`
+}
+
+// DailyFuel totals a vehicle's refuelling on one UTC day.
+type DailyFuel struct {
+	Day    time.Time `db:"day"`
+	Litres float64   `db:"litres"`
+	Cost   float64   `db:"cost"`
+}
+
+// DistanceDiverges reports whether the odometer and GPS distances for the
+// same period disagree: by more than minKm, and by more than pct percent of
+// the longer one. The absolute floor keeps short days from flagging on GPS
+// noise.
+func DistanceDiverges(odometerKm, gpsKm, pct, minKm float64) bool {
+	diff := math.Abs(odometerKm - gpsKm)
+	return diff > minKm && diff > math.Max(odometerKm, gpsKm)*pct/100
+}
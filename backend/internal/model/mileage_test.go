@@ -0,0 +1,26 @@
+package model
+
+import "testing"
+
+func TestDistanceDiverges(t *testing.T) {
+	tests := []struct {
+		name     string
+		odometer float64
+		gps      float64
+		want     bool
+	}{
+		{"agree", 120, 118.4, false},
+		{"within percent", 100, 85, false},
+		{"odometer far ahead", 180, 100, true},
+		{"gps far ahead", 40, 95, true},
+		{"short day noise", 3, 0.5, false},
+		{"no gps at all", 60, 0, true},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DistanceDiverges(tc.odometer, tc.gps, 20, 5); got != tc.want {
+				t.Errorf("DistanceDiverges(%v, %v) = %v, want %v", tc.odometer, tc.gps, got, tc.want)
+			}
+		})
+	}
+}
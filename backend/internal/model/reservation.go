@@ -35,10 +35,93 @@ type Reservation struct {
 	CancelReason        *string           `db:"cancel_reason" json:"cancel_reason,omitempty"`
 	CancelledBy         *string           `db:"cancelled_by" json:"cancelled_by,omitempty"`
 	DeclinedByDriverIDs pq.StringArray    `db:"declined_by_driver_ids" json:"declined_by_driver_ids,omitempty"`
+	SeriesID            *string           `db:"series_id" json:"series_id,omitempty"`
+	OccurrenceStart     *time.Time        `db:"occurrence_start" json:"occurrence_start,omitempty"`
+	IsException         bool              `db:"is_exception" json:"is_exception,omitempty"`
 	CreatedAt           time.Time         `db:"created_at" json:"created_at"`
 	UpdatedAt           time.Time         `db:"updated_at" json:"updated_at"`
 }
 
+type SeriesStatus string
+
+const (
+	SeriesStatusActive    SeriesStatus = "active"
+	SeriesStatusEnded     SeriesStatus = "ended"
+	SeriesStatusCancelled SeriesStatus = "cancelled"
+)
+
+// ReservationSeries is a recurring booking. Its occurrences are ordinary
+// reservations, materialized ahead of time up to MaterializedUntil.
+type ReservationSeries struct {
+	ID                string         `db:"id" json:"id"`
+	VehicleID         string         `db:"vehicle_id" json:"vehicle_id"`
+	RequesterID       string         `db:"requester_id" json:"requester_id"`
+	RRule             string         `db:"rrule" json:"rrule"`
+	DTStart           time.Time      `db:"dtstart" json:"dtstart"`
+	DurationSec       int            `db:"duration_sec" json:"duration_sec"`
+	Timezone          string         `db:"timezone" json:"timezone"`
+	Purpose           string         `db:"purpose" json:"purpose"`
+	Destinations      pq.StringArray `db:"destinations" json:"destinations"`
+	Notes             *string        `db:"notes" json:"notes,omitempty"`
+	PriorityLevel     int            `db:"priority_level" json:"priority_level"`
+	Status            SeriesStatus   `db:"status" json:"status"`
+	MaterializedUntil *time.Time     `db:"materialized_until" json:"materialized_until,omitempty"`
+	CancelReason      *string        `db:"cancel_reason" json:"cancel_reason,omitempty"`
+	CancelledBy       *string        `db:"cancelled_by" json:"cancelled_by,omitempty"`
+	CreatedAt         time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt         time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// Duration is how long each occurrence lasts.
+func (s *ReservationSeries) Duration() time.Duration {
+	return time.Duration(s.DurationSec) * time.Second
+}
+
+type WaitlistStatus string
+
+const (
+	WaitlistStatusWaiting   WaitlistStatus = "waiting"
+	WaitlistStatusBooked    WaitlistStatus = "booked"
+	WaitlistStatusExpired   WaitlistStatus = "expired"
+	WaitlistStatusCancelled WaitlistStatus = "cancelled"
+)
+
+// WaitlistEntry is a booking request for a slot no vehicle was free for. It
+// holds everything needed to book it later; ReservationID is set once it is.
+type WaitlistEntry struct {
+	ID            string         `db:"id" json:"id"`
+	RequesterID   string         `db:"requester_id" json:"requester_id"`
+	StartTime     time.Time      `db:"start_time" json:"start_time"`
+	EndTime       time.Time      `db:"end_time" json:"end_time"`
+	Purpose       string         `db:"purpose" json:"purpose"`
+	Destinations  pq.StringArray `db:"destinations" json:"destinations"`
+	Notes         *string        `db:"notes" json:"notes,omitempty"`
+	PassengerName *string        `db:"passenger_name" json:"passenger_name,omitempty"`
+	PickupAddress *string        `db:"pickup_address" json:"pickup_address,omitempty"`
+	PickupLat     *float64       `db:"pickup_lat" json:"pickup_lat,omitempty"`
+	PickupLng     *float64       `db:"pickup_lng" json:"pickup_lng,omitempty"`
+	PriorityLevel int            `db:"priority_level" json:"priority_level"`
+	Status        WaitlistStatus `db:"status" json:"status"`
+	ReservationID *string        `db:"reservation_id" json:"reservation_id,omitempty"`
+	DepotIDs      pq.StringArray `db:"depot_ids" json:"depot_ids,omitempty"`
+	CreatedAt     time.Time      `db:"created_at" json:"created_at"`
+	UpdatedAt     time.Time      `db:"updated_at" json:"updated_at"`
+}
+
+// InDepots reports whether the entry's depot scope meets the depot scope
+// depotIDs. A nil scope on either side spans every depot.
+func (e *WaitlistEntry) InDepots(depotIDs []string) bool {
+	if depotIDs == nil || e.DepotIDs == nil {
+		return true
+	}
+	for _, id := range e.DepotIDs {
+		if InDepots(depotIDs, &id) {
+			return true
+		}
+	}
+	return false
+}
+
 type ReservationWithDetails struct {
 	Reservation
 	VehicleName   string `db:"vehicle_name" json:"vehicle_name"`
@@ -46,6 +129,17 @@ type ReservationWithDetails struct {
 	DriverName    string `db:"driver_name" json:"driver_name,omitempty"`
 }
 
+// DueReminder is a reservation reminder owed to one recipient: the requester
+// or the driver of the reserved vehicle.
+type DueReminder struct {
+	ReservationID string    `db:"reservation_id"`
+	RecipientID   string    `db:"recipient_id"`
+	RecipientRole string    `db:"recipient_role"`
+	StartTime     time.Time `db:"start_time"`
+	Purpose       string    `db:"purpose"`
+	VehicleName   string    `db:"vehicle_name"`
+}
+
 type ConflictStatus string
 
 const (
@@ -56,13 +150,60 @@ const (
 	ConflictStatusForceAssigned     ConflictStatus = "force_assigned"
 )
 
+// ConflictKind says why two reservations conflict: their times overlap, or
+// the gap between them is shorter than the vehicle's turnaround, or than the
+// turnaround plus the drive to the next pickup. A maintenance conflict is
+// between a reservation and a maintenance window on its vehicle.
+type ConflictKind string
+
+const (
+	ConflictKindOverlap     ConflictKind = "overlap"
+	ConflictKindTurnaround  ConflictKind = "turnaround"
+	ConflictKindTravel      ConflictKind = "travel"
+	ConflictKindMaintenance ConflictKind = "maintenance"
+)
+
+// CheckSpacing reports whether a trip ending at earlierEnd leaves enough time
+// before one starting at laterStart, given the vehicle's turnaround and the
+// drive between them (zero when unknown). On a violation it returns the kind
+// and the gap that would have been needed.
+func CheckSpacing(earlierEnd, laterStart time.Time, turnaround, drive time.Duration) (ConflictKind, time.Duration, bool) {
+	gap := laterStart.Sub(earlierEnd)
+	if gap < turnaround {
+		return ConflictKindTurnaround, turnaround, false
+	}
+	if gap < turnaround+drive {
+		return ConflictKindTravel, turnaround + drive, false
+	}
+	return "", 0, true
+}
+
+// ReservationConflict is won by a reservation or, for maintenance conflicts,
+// by a maintenance window; exactly one of WinningReservationID and
+// MaintenanceWindowID is set.
 type ReservationConflict struct {
 	ID                   string         `db:"id" json:"id"`
-	WinningReservationID string         `db:"winning_reservation_id" json:"winning_reservation_id"`
+	WinningReservationID *string        `db:"winning_reservation_id" json:"winning_reservation_id"`
 	LosingReservationID  string         `db:"losing_reservation_id" json:"losing_reservation_id"`
+	MaintenanceWindowID  *string        `db:"maintenance_window_id" json:"maintenance_window_id,omitempty"`
+	Kind                 ConflictKind   `db:"kind" json:"kind"`
+	RequiredGapSec       *int           `db:"required_gap_sec" json:"required_gap_sec,omitempty"`
+	AvailableGapSec      *int           `db:"available_gap_sec" json:"available_gap_sec,omitempty"`
 	Status               ConflictStatus `db:"status" json:"status"`
 	ResolvedBy           *string        `db:"resolved_by" json:"resolved_by,omitempty"`
 	ResolutionReason     *string        `db:"resolution_reason" json:"resolution_reason,omitempty"`
 	ResolvedAt           *time.Time     `db:"resolved_at" json:"resolved_at,omitempty"`
 	CreatedAt            time.Time      `db:"created_at" json:"created_at"`
 }
+
+// Other returns the reservation in the conflict that is not id, or "" when
+// the other side is a maintenance window.
+func (c *ReservationConflict) Other(id string) string {
+	if c.LosingReservationID != id {
+		return c.LosingReservationID
+	}
+	if c.WinningReservationID != nil {
+		return *c.WinningReservationID
+	}
+	return ""
+}
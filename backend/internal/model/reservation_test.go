@@ -0,0 +1,34 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestCheckSpacing(t *testing.T) {
+	end := time.Date(2026, 11, 2, 10, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name       string
+		gap        time.Duration
+		turnaround time.Duration
+		drive      time.Duration
+		wantKind   ConflictKind
+		wantNeed   time.Duration
+		wantOK     bool
+	}{
+		{"back to back, no buffer", 0, 0, 0, "", 0, true},
+		{"inside turnaround", 10 * time.Minute, 15 * time.Minute, 0, ConflictKindTurnaround, 15 * time.Minute, false},
+		{"turnaround met, drive unknown", 15 * time.Minute, 15 * time.Minute, 0, "", 0, true},
+		{"turnaround met, drive too long", 30 * time.Minute, 15 * time.Minute, 20 * time.Minute, ConflictKindTravel, 35 * time.Minute, false},
+		{"turnaround and drive met", 35 * time.Minute, 15 * time.Minute, 20 * time.Minute, "", 0, true},
+		{"drive only", 10 * time.Minute, 0, 25 * time.Minute, ConflictKindTravel, 25 * time.Minute, false},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			kind, need, ok := CheckSpacing(end, end.Add(tc.gap), tc.turnaround, tc.drive)
+			if kind != tc.wantKind || need != tc.wantNeed || ok != tc.wantOK {
+				t.Errorf("CheckSpacing = (%q, %v, %v), want (%q, %v, %v)", kind, need, ok, tc.wantKind, tc.wantNeed, tc.wantOK)
+			}
+		})
+	}
+}
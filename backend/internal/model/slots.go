@@ -0,0 +1,85 @@
+package model
+
+import (
+	"sort"
+	"time"
+)
+
+// Busy is a time a vehicle is taken, by a reservation or otherwise.
+type Busy struct {
+	Start time.Time
+	End   time.Time
+}
+
+// FreeSlots returns up to n start times for a booking of length d on a
+// vehicle taken during busy, nearest to want first and earlier first on a
+// tie. Each slot keeps gap clear of every busy interval, starts no earlier
+// than notBefore and no more than window away from want. want itself is
+// never returned: the caller already knows it is taken.
+//
+// The nearest free start in either direction always touches a busy interval
+// (or notBefore), so only those edges are tried.
+func FreeSlots(busy []Busy, want time.Time, d, gap, window time.Duration, notBefore time.Time, n int) []time.Time {
+	var candidates []time.Time
+	if want.Before(notBefore) {
+		candidates = append(candidates, notBefore)
+	}
+	for _, b := range busy {
+		candidates = append(candidates, b.End.Add(gap), b.Start.Add(-gap-d))
+	}
+
+	fits := func(start time.Time) bool {
+		end := start.Add(d)
+		for _, b := range busy {
+			if end.Add(gap).After(b.Start) && start.Before(b.End.Add(gap)) {
+				return false
+			}
+		}
+		return true
+	}
+
+	seen := make(map[time.Time]bool)
+	var slots []time.Time
+	for _, c := range candidates {
+		if c.Equal(want) || c.Before(notBefore) || absDuration(c.Sub(want)) > window {
+			continue
+		}
+		c = c.UTC()
+		if seen[c] || !fits(c) {
+			continue
+		}
+		seen[c] = true
+		slots = append(slots, c)
+	}
+
+	sort.Slice(slots, func(i, j int) bool {
+		di, dj := absDuration(slots[i].Sub(want)), absDuration(slots[j].Sub(want))
+		if di != dj {
+			return di < dj
+		}
+		return slots[i].Before(slots[j])
+	})
+	if len(slots) > n {
+		slots = slots[:n]
+	}
+	return slots
+}
+
+func absDuration(d time.Duration) time.Duration {
+	if d < 0 {
+		return -d
+	}
+	return d
+}
+
+// SlotCandidate is a vehicle with nothing booked during a slot, and where
+// its nearest bookings either side of the slot end and start. Those are nil
+// when nothing is booked close by.
+type SlotCandidate struct {
+	VehicleID     string     `db:"vehicle_id"`
+	VehicleName   string     `db:"vehicle_name"`
+	LicensePlate  string     `db:"license_plate"`
+	TurnaroundMin int        `db:"turnaround_min"`
+	PrevEnd       *time.Time `db:"prev_end"`
+	NextStart     *time.Time `db:"next_start"`
+}
@@ -0,0 +1,42 @@
+package model
+
+import (
+	"testing"
+	"time"
+)
+
+func TestFreeSlots(t *testing.T) {
+	at := func(h, m int) time.Time { return time.Date(2026, 11, 2, h, m, 0, 0, time.UTC) }
+	// 9:00-10:00 and 11:00-12:00 are taken; the wanted hour is 10:30.
+	busy := []Busy{{at(9, 0), at(10, 0)}, {at(11, 0), at(12, 0)}}
+	want := at(10, 30)
+	early := at(0, 0)
+
+	tests := []struct {
+		name      string
+		gap       time.Duration
+		window    time.Duration
+		notBefore time.Time
+		n         int
+		wantSlots []time.Time
+	}{
+		{"no gap", 0, 24 * time.Hour, early, 4, []time.Time{at(10, 0), at(12, 0), at(8, 0)}},
+		{"gap closes the hole between", 15 * time.Minute, 24 * time.Hour, early, 4, []time.Time{at(12, 15), at(7, 45)}},
+		{"not before", 0, 24 * time.Hour, at(10, 15), 4, []time.Time{at(12, 0)}},
+		{"window", 0, time.Hour, early, 4, []time.Time{at(10, 0)}},
+		{"limit", 0, 24 * time.Hour, early, 1, []time.Time{at(10, 0)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := FreeSlots(busy, want, time.Hour, tc.gap, tc.window, tc.notBefore, tc.n)
+			if len(got) != len(tc.wantSlots) {
+				t.Fatalf("FreeSlots = %v, want %v", got, tc.wantSlots)
+			}
+			for i := range got {
+				if !got[i].Equal(tc.wantSlots[i]) {
+					t.Fatalf("FreeSlots = %v, want %v", got, tc.wantSlots)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// TripLeg is one observed drive taken from a completed dispatch: the approach
+// to the pickup (from where the vehicle was when the driver set off) or the
+// ride to the dropoff (from where the vehicle was once it left the pickup).
+type TripLeg struct {
+	DispatchID string    `db:"dispatch_id" json:"dispatch_id"`
+	FromLat    float64   `db:"from_lat" json:"from_lat"`
+	FromLng    float64   `db:"from_lng" json:"from_lng"`
+	ToLat      float64   `db:"to_lat" json:"to_lat"`
+	ToLng      float64   `db:"to_lng" json:"to_lng"`
+	StartedAt  time.Time `db:"started_at" json:"started_at"`
+	EndedAt    time.Time `db:"ended_at" json:"ended_at"`
+}
+
+// TravelSpeed is a learned average speed for one grid cell and hour of week.
+type TravelSpeed struct {
+	CellLat     int       `db:"cell_lat" json:"cell_lat"`
+	CellLng     int       `db:"cell_lng" json:"cell_lng"`
+	HourOfWeek  int       `db:"hour_of_week" json:"hour_of_week"`
+	DistanceM   float64   `db:"distance_m" json:"distance_m"`
+	DurationSec float64   `db:"duration_sec" json:"duration_sec"`
+	Samples     int       `db:"samples" json:"samples"`
+	TrainedAt   time.Time `db:"trained_at" json:"trained_at"`
+}
@@ -14,12 +14,19 @@ const (
 	VehicleStatusWaiting      VehicleStatus = "waiting"
 )
 
+// Vehicle is a car in the fleet. IsMaintenance is derived: it is true while
+// a maintenance window covers the current time or its work is in progress.
+// DriverID is derived too: the driver holding the vehicle on their open
+// shift, nil while nobody does. A vehicle without a depot is shared by all.
 type Vehicle struct {
 	ID            string    `db:"id" json:"id"`
 	Name          string    `db:"name" json:"name"`
 	LicensePlate  string    `db:"license_plate" json:"license_plate"`
-	DriverID      string    `db:"driver_id" json:"driver_id"`
+	DepotID       *string   `db:"depot_id" json:"depot_id,omitempty"`
+	DriverID      *string   `db:"driver_id" json:"driver_id,omitempty"`
 	IsMaintenance bool      `db:"is_maintenance" json:"is_maintenance"`
+	TurnaroundMin int       `db:"turnaround_min" json:"turnaround_min"`
+	OdometerKm    *int      `db:"odometer_km" json:"odometer_km,omitempty"`
 	PhotoURL      *string   `db:"photo_url" json:"photo_url,omitempty"`
 	CreatedAt     time.Time `db:"created_at" json:"created_at"`
 	UpdatedAt     time.Time `db:"updated_at" json:"updated_at"`
@@ -29,9 +36,12 @@ type VehicleWithStatus struct {
 	ID             string        `db:"id" json:"id"`
 	Name           string        `db:"name" json:"name"`
 	LicensePlate   string        `db:"license_plate" json:"license_plate"`
-	DriverID       string        `db:"driver_id" json:"driver_id"`
+	DepotID        *string       `db:"depot_id" json:"depot_id,omitempty"`
+	DriverID       *string       `db:"driver_id" json:"driver_id,omitempty"`
 	DriverName     string        `db:"driver_name" json:"driver_name"`
 	IsMaintenance  bool          `db:"is_maintenance" json:"is_maintenance"`
+	TurnaroundMin  int           `db:"turnaround_min" json:"turnaround_min"`
+	OdometerKm     *int          `db:"odometer_km" json:"odometer_km,omitempty"`
 	IsClockedIn    bool          `db:"is_clocked_in" json:"is_clocked_in"`
 	PhotoURL       *string       `db:"photo_url" json:"photo_url,omitempty"`
 	Status         VehicleStatus `db:"computed_status" json:"status"`
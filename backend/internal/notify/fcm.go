@@ -42,19 +42,29 @@ func NewFCMService(credPath string, userRepo *repository.UserRepo) (*FCMService,
 
 // NotifyUser sends a push notification to a specific user by user ID.
 func (s *FCMService) NotifyUser(ctx context.Context, userID, title, body string, data map[string]string) {
+	_ = s.SendToUser(ctx, userID, title, body, data)
+}
+
+// SendToUser is NotifyUser for callers that need to know whether delivery to
+// FCM failed (e.g. to retry later). A user without a registered token, or FCM
+// being disabled, is not an error: there is nothing to deliver.
+func (s *FCMService) SendToUser(ctx context.Context, userID, title, body string, data map[string]string) error {
 	if s.client == nil {
-		return
+		return nil
 	}
 
 	user, err := s.userRepo.GetByID(ctx, userID)
-	if err != nil || user == nil || user.FCMToken == nil {
-		return
+	if err != nil {
+		return err
+	}
+	if user == nil || user.FCMToken == nil {
+		return nil
 	}
 
-	s.sendToToken(ctx, *user.FCMToken, title, body, data)
+	return s.sendToToken(ctx, *user.FCMToken, title, body, data)
 }
 
-// NotifyVehicleDriver sends a push notification to the driver assigned to a vehicle.
+// NotifyVehicleDriver sends a push notification to the driver holding a vehicle on their shift.
 func (s *FCMService) NotifyVehicleDriver(ctx context.Context, vehicleID, title, body string, data map[string]string) {
 	if s.client == nil {
 		return
@@ -90,7 +100,7 @@ func (s *FCMService) NotifyRole(ctx context.Context, title, body string, data ma
 	}
 }
 
-func (s *FCMService) sendToToken(ctx context.Context, token, title, body string, data map[string]string) {
+func (s *FCMService) sendToToken(ctx context.Context, token, title, body string, data map[string]string) error {
 	msg := &messaging.Message{
 		Token: token,
 		Notification: &messaging.Notification{
@@ -115,5 +125,7 @@ func (s *FCMService) sendToToken(ctx context.Context, token, title, body string,
 
 	if _, err := s.client.Send(ctx, msg); err != nil {
 		log.Printf("[notify] FCM send error: %v", err)
+		return err
 	}
+	return nil
 }
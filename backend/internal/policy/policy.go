@@ -0,0 +1,191 @@
+// Package policy decides whether a caller may act on a specific resource.
+// Role checks in middleware only say who may reach an endpoint; the rules
+// here look at the resource itself (whose vehicle, whose booking). Rules are
+// pure functions of the subject and the resource so they can be tested
+// without a database; services load the resource and enforce the decision.
+package policy
+
+import "github.com/kento/driver/backend/internal/model"
+
+// Subject is the caller a decision is made for.
+type Subject struct {
+	UserID string
+	Role   model.Role
+}
+
+// System is the subject for work the backend does on its own (timeouts,
+// auto-dispatch). It is allowed everything.
+var System = Subject{Role: "system"}
+
+// Action names what the subject wants to do.
+type Action string
+
+const (
+	DispatchRead       Action = "dispatch.read"
+	DispatchList       Action = "dispatch.list"
+	DispatchTransition Action = "dispatch.transition"
+	DispatchCancel     Action = "dispatch.cancel"
+	DispatchRate       Action = "dispatch.rate"
+	DispatchTrack      Action = "dispatch.track"
+
+	CalendarSubscribe Action = "calendar.subscribe"
+	CalendarRevoke    Action = "calendar.revoke"
+
+	WaitlistRead   Action = "waitlist.read"
+	WaitlistCancel Action = "waitlist.cancel"
+)
+
+// Decision is the outcome of evaluating a rule. Rule names the rule that
+// decided, for the audit trail.
+type Decision struct {
+	Allowed bool
+	Rule    string
+	Reason  string
+}
+
+func allow(rule string) Decision {
+	return Decision{Allowed: true, Rule: rule}
+}
+
+func deny(rule, reason string) Decision {
+	return Decision{Rule: rule, Reason: reason}
+}
+
+// Dispatch is the part of a dispatch the rules look at.
+type Dispatch struct {
+	RequesterID string
+	// VehicleDriverID is the driver holding the dispatch's vehicle on their
+	// shift; empty while unassigned or nobody holds it.
+	VehicleDriverID string
+}
+
+func isStaff(r model.Role) bool {
+	return r == model.RoleAdmin || r == model.RoleDispatcher
+}
+
+// CanDispatch decides whether sub may perform action on d.
+//
+//   - Admins and dispatchers may do anything; viewers may only read.
+//   - A driver may read, track and move a dispatch only while it is on the
+//     vehicle they drive.
+//   - A passenger may read, track, cancel and rate only dispatches they
+//     requested.
+func CanDispatch(sub Subject, action Action, d Dispatch) Decision {
+	switch {
+	case sub == System:
+		return allow("system")
+	case isStaff(sub.Role):
+		return allow("staff")
+	}
+
+	switch sub.Role {
+	case model.RoleViewer:
+		if action == DispatchRead || action == DispatchList {
+			return allow("viewer.read")
+		}
+		return deny("viewer.read", "viewers have read-only access")
+
+	case model.RoleDriver:
+		switch action {
+		case DispatchRead, DispatchTrack, DispatchTransition:
+		default:
+			return deny("driver.actions", "drivers may not "+string(action))
+		}
+		if d.VehicleDriverID == "" || d.VehicleDriverID != sub.UserID {
+			return deny("driver.own_vehicle", "dispatch is not on the caller's vehicle")
+		}
+		return allow("driver.own_vehicle")
+
+	case model.RolePassenger:
+		switch action {
+		case DispatchRead, DispatchTrack, DispatchCancel, DispatchRate:
+		default:
+			return deny("passenger.actions", "passengers may not "+string(action))
+		}
+		if d.RequesterID != sub.UserID {
+			return deny("passenger.own_dispatch", "dispatch was requested by someone else")
+		}
+		return allow("passenger.own_dispatch")
+	}
+	return deny("unknown_role", "role "+string(sub.Role)+" has no dispatch permissions")
+}
+
+// CanListDispatches decides whether sub may list dispatches across the
+// fleet. Passengers and drivers have their own scoped endpoints.
+func CanListDispatches(sub Subject) Decision {
+	return CanDispatch(sub, DispatchList, Dispatch{})
+}
+
+// CalendarFeed is the part of a calendar feed the rules look at.
+type CalendarFeed struct {
+	Type model.CalendarFeedType
+	// OwnerID is the user whose schedule the feed shows: the vehicle's
+	// driver, the driver, or the requester. Empty for a vehicle without one.
+	OwnerID string
+	// CreatedBy is who created the feed; empty before it exists.
+	CreatedBy string
+}
+
+// CanCalendarFeed decides whether sub may perform action on f.
+//
+//   - Admins and dispatchers may subscribe to and revoke any feed.
+//   - Viewers may also subscribe to any vehicle's feed.
+//   - Anyone else may subscribe only to their own schedule: a driver to
+//     their vehicle or driver feed, a requester to their bookings.
+//   - Whoever created a feed may revoke it.
+func CanCalendarFeed(sub Subject, action Action, f CalendarFeed) Decision {
+	if sub == System {
+		return allow("system")
+	}
+	if isStaff(sub.Role) {
+		return allow("staff")
+	}
+	if action == CalendarRevoke {
+		if f.CreatedBy != "" && f.CreatedBy == sub.UserID {
+			return allow("creator")
+		}
+		return deny("creator", "feed was created by someone else")
+	}
+	if sub.Role == model.RoleViewer && f.Type == model.CalendarFeedVehicle {
+		return allow("viewer.vehicle")
+	}
+	if f.OwnerID == "" || f.OwnerID != sub.UserID {
+		return deny("own_schedule", "feed shows someone else's schedule")
+	}
+	return allow("own_schedule")
+}
+
+// WaitlistEntry is the part of a waitlist entry the rules look at.
+type WaitlistEntry struct {
+	RequesterID string
+}
+
+// CanWaitlist decides whether sub may perform action on e.
+//
+//   - Admins and dispatchers may read and withdraw any entry.
+//   - Anyone else may only read and withdraw entries they queued.
+func CanWaitlist(sub Subject, action Action, e WaitlistEntry) Decision {
+	switch {
+	case sub == System:
+		return allow("system")
+	case isStaff(sub.Role):
+		return allow("staff")
+	}
+	if e.RequesterID == "" || e.RequesterID != sub.UserID {
+		return deny("requester", "waitlist entry was queued by someone else")
+	}
+	return allow("requester")
+}
+
+// ForActor is the subject behind a dispatch state machine actor.
+func ForActor(actor model.DispatchActor, userID string) Subject {
+	switch actor {
+	case model.DispatchActorDriver:
+		return Subject{UserID: userID, Role: model.RoleDriver}
+	case model.DispatchActorRequester:
+		return Subject{UserID: userID, Role: model.RolePassenger}
+	case model.DispatchActorDispatcher:
+		return Subject{UserID: userID, Role: model.RoleDispatcher}
+	}
+	return System
+}
@@ -0,0 +1,126 @@
+package policy
+
+import (
+	"testing"
+
+	"github.com/kento/driver/backend/internal/model"
+)
+
+func TestCanDispatch(t *testing.T) {
+	mine := Dispatch{RequesterID: "p1", VehicleDriverID: "drv1"}
+	unassigned := Dispatch{RequesterID: "p1"}
+
+	tests := []struct {
+		name   string
+		sub    Subject
+		action Action
+		d      Dispatch
+		want   bool
+		rule   string
+	}{
+		{"dispatcher cancels", Subject{"u1", model.RoleDispatcher}, DispatchCancel, mine, true, "staff"},
+		{"admin transitions", Subject{"u1", model.RoleAdmin}, DispatchTransition, mine, true, "staff"},
+		{"system transitions", System, DispatchTransition, mine, true, "system"},
+		{"viewer reads", Subject{"u1", model.RoleViewer}, DispatchRead, mine, true, "viewer.read"},
+		{"viewer cancels", Subject{"u1", model.RoleViewer}, DispatchCancel, mine, false, "viewer.read"},
+
+		{"driver moves own trip", Subject{"drv1", model.RoleDriver}, DispatchTransition, mine, true, "driver.own_vehicle"},
+		{"driver moves other trip", Subject{"drv2", model.RoleDriver}, DispatchTransition, mine, false, "driver.own_vehicle"},
+		{"driver reads unassigned", Subject{"drv1", model.RoleDriver}, DispatchRead, unassigned, false, "driver.own_vehicle"},
+		{"driver cancels own trip", Subject{"drv1", model.RoleDriver}, DispatchCancel, mine, false, "driver.actions"},
+		{"driver lists", Subject{"drv1", model.RoleDriver}, DispatchList, Dispatch{}, false, "driver.actions"},
+
+		{"passenger reads own", Subject{"p1", model.RolePassenger}, DispatchRead, mine, true, "passenger.own_dispatch"},
+		{"passenger tracks other", Subject{"p2", model.RolePassenger}, DispatchTrack, mine, false, "passenger.own_dispatch"},
+		{"passenger rates own", Subject{"p1", model.RolePassenger}, DispatchRate, mine, true, "passenger.own_dispatch"},
+		{"passenger transitions", Subject{"p1", model.RolePassenger}, DispatchTransition, mine, false, "passenger.actions"},
+		{"passenger lists", Subject{"p1", model.RolePassenger}, DispatchList, Dispatch{}, false, "passenger.actions"},
+
+		{"no role", Subject{UserID: "x"}, DispatchRead, mine, false, "unknown_role"},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CanDispatch(tc.sub, tc.action, tc.d)
+			if got.Allowed != tc.want || got.Rule != tc.rule {
+				t.Errorf("got %+v, want allowed=%v by %q", got, tc.want, tc.rule)
+			}
+			if !got.Allowed && got.Reason == "" {
+				t.Error("denial without a reason")
+			}
+		})
+	}
+}
+
+func TestForActor(t *testing.T) {
+	if got := ForActor(model.DispatchActorDriver, "d1"); got != (Subject{"d1", model.RoleDriver}) {
+		t.Errorf("driver actor = %+v", got)
+	}
+	if got := ForActor(model.DispatchActorRequester, "p1"); got.Role != model.RolePassenger {
+		t.Errorf("requester actor = %+v", got)
+	}
+	if got := ForActor(model.DispatchActorSystem, "u1"); got != System {
+		t.Errorf("system actor = %+v, want System", got)
+	}
+}
+
+func TestCanCalendarFeed(t *testing.T) {
+	vehicle := CalendarFeed{Type: model.CalendarFeedVehicle, OwnerID: "drv1"}
+	bookings := CalendarFeed{Type: model.CalendarFeedRequester, OwnerID: "u2"}
+	created := CalendarFeed{Type: model.CalendarFeedRequester, OwnerID: "u2", CreatedBy: "u2"}
+
+	tests := []struct {
+		name   string
+		sub    Subject
+		action Action
+		f      CalendarFeed
+		want   bool
+		rule   string
+	}{
+		{"dispatcher subscribes to anyone", Subject{"u1", model.RoleDispatcher}, CalendarSubscribe, bookings, true, "staff"},
+		{"viewer subscribes to vehicle", Subject{"u1", model.RoleViewer}, CalendarSubscribe, vehicle, true, "viewer.vehicle"},
+		{"viewer subscribes to requester", Subject{"u1", model.RoleViewer}, CalendarSubscribe, bookings, false, "own_schedule"},
+		{"driver subscribes to own vehicle", Subject{"drv1", model.RoleDriver}, CalendarSubscribe, vehicle, true, "own_schedule"},
+		{"driver subscribes to other vehicle", Subject{"drv2", model.RoleDriver}, CalendarSubscribe, vehicle, false, "own_schedule"},
+		{"vehicle without driver", Subject{"drv1", model.RoleDriver}, CalendarSubscribe, CalendarFeed{Type: model.CalendarFeedVehicle}, false, "own_schedule"},
+		{"requester subscribes to own", Subject{"u2", model.RolePassenger}, CalendarSubscribe, bookings, true, "own_schedule"},
+		{"creator revokes", Subject{"u2", model.RolePassenger}, CalendarRevoke, created, true, "creator"},
+		{"other revokes", Subject{"u3", model.RoleViewer}, CalendarRevoke, created, false, "creator"},
+		{"admin revokes", Subject{"u1", model.RoleAdmin}, CalendarRevoke, created, true, "staff"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CanCalendarFeed(tc.sub, tc.action, tc.f)
+			if got.Allowed != tc.want || got.Rule != tc.rule {
+				t.Errorf("got %+v, want allowed=%v by %q", got, tc.want, tc.rule)
+			}
+		})
+	}
+}
+
+func TestCanWaitlist(t *testing.T) {
+	entry := WaitlistEntry{RequesterID: "u2"}
+
+	tests := []struct {
+		name   string
+		sub    Subject
+		action Action
+		want   bool
+		rule   string
+	}{
+		{"dispatcher cancels anyone's", Subject{"u1", model.RoleDispatcher}, WaitlistCancel, true, "staff"},
+		{"admin reads anyone's", Subject{"u1", model.RoleAdmin}, WaitlistRead, true, "staff"},
+		{"requester cancels own", Subject{"u2", model.RolePassenger}, WaitlistCancel, true, "requester"},
+		{"other cancels", Subject{"u3", model.RolePassenger}, WaitlistCancel, false, "requester"},
+		{"viewer reads", Subject{"u3", model.RoleViewer}, WaitlistRead, false, "requester"},
+		{"system", System, WaitlistCancel, true, "system"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			got := CanWaitlist(tc.sub, tc.action, entry)
+			if got.Allowed != tc.want || got.Rule != tc.rule {
+				t.Errorf("got %+v, want allowed=%v by %q", got, tc.want, tc.rule)
+			}
+		})
+	}
+}
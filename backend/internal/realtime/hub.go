@@ -0,0 +1,127 @@
+package realtime
+
+import (
+	"sync"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventSnapshot         EventType = "snapshot"
+	EventVehicleLocation  EventType = "vehicle.location"
+	EventVehicleStatus    EventType = "vehicle.status"
+	EventVehicleUpdated   EventType = "vehicle.updated"
+	EventVehicleDeleted   EventType = "vehicle.deleted"
+	EventDispatchUpdated  EventType = "dispatch.updated"
+	EventDriverAttendance EventType = "driver.attendance"
+
+	// Passenger ride stream events, derived per connection
+	EventRideStatus   EventType = "ride.status"
+	EventRideLocation EventType = "ride.location"
+)
+
+// Event is a single change pushed to streaming clients.
+type Event struct {
+	Type       EventType   `json:"type"`
+	VehicleID  string      `json:"vehicle_id,omitempty"`
+	DispatchID string      `json:"dispatch_id,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	At         time.Time   `json:"at"`
+}
+
+// Hub fans out published events to subscribers. Publishing never blocks:
+// a subscriber whose buffer is full is dropped (its channel is closed) so the
+// client reconnects and resynchronises from a fresh snapshot.
+type Hub struct {
+	mu     sync.RWMutex
+	subs   map[*Subscription]struct{}
+	closed bool
+}
+
+func NewHub() *Hub {
+	return &Hub{subs: make(map[*Subscription]struct{})}
+}
+
+// Subscription receives the events accepted by its filter.
+type Subscription struct {
+	hub    *Hub
+	ch     chan Event
+	filter func(Event) bool
+	once   sync.Once
+}
+
+// Subscribe registers a subscriber. A nil filter accepts every event.
+// Subscribing to a closed hub returns an already-closed subscription.
+func (h *Hub) Subscribe(buffer int, filter func(Event) bool) *Subscription {
+	sub := &Subscription{hub: h, ch: make(chan Event, buffer), filter: filter}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.closed {
+		sub.once.Do(func() { close(sub.ch) })
+		return sub
+	}
+	h.subs[sub] = struct{}{}
+	return sub
+}
+
+// Publish delivers an event to every matching subscriber.
+func (h *Hub) Publish(e Event) {
+	if e.At.IsZero() {
+		e.At = time.Now()
+	}
+
+	var slow []*Subscription
+	h.mu.RLock()
+	for sub := range h.subs {
+		if sub.filter != nil && !sub.filter(e) {
+			continue
+		}
+		select {
+		case sub.ch <- e:
+		default:
+			slow = append(slow, sub)
+		}
+	}
+	h.mu.RUnlock()
+
+	for _, sub := range slow {
+		sub.Close()
+	}
+}
+
+// Close disconnects all subscribers. Used on server shutdown so long-lived
+// streams do not hold the listener open.
+func (h *Hub) Close() {
+	h.mu.Lock()
+	h.closed = true
+	subs := h.subs
+	h.subs = make(map[*Subscription]struct{})
+	h.mu.Unlock()
+
+	for sub := range subs {
+		sub.once.Do(func() { close(sub.ch) })
+	}
+}
+
+// Closed reports whether Close has been called.
+func (h *Hub) Closed() bool {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.closed
+}
+
+// Events returns the channel of delivered events. It is closed when the
+// subscription is dropped or the hub shuts down.
+func (s *Subscription) Events() <-chan Event {
+	return s.ch
+}
+
+// Close unregisters the subscription. Safe to call more than once.
+func (s *Subscription) Close() {
+	s.hub.mu.Lock()
+	delete(s.hub.subs, s)
+	s.hub.mu.Unlock()
+	s.once.Do(func() { close(s.ch) })
+}
@@ -0,0 +1,74 @@
+package realtime
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHub_PublishDeliversMatchingEvents(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(4, func(e Event) bool { return e.VehicleID == "v1" })
+	defer sub.Close()
+
+	h.Publish(Event{Type: EventVehicleLocation, VehicleID: "v2"})
+	h.Publish(Event{Type: EventVehicleLocation, VehicleID: "v1"})
+
+	select {
+	case e := <-sub.Events():
+		if e.VehicleID != "v1" {
+			t.Errorf("VehicleID = %q, want %q", e.VehicleID, "v1")
+		}
+		if e.At.IsZero() {
+			t.Error("expected At to be stamped on publish")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an event")
+	}
+
+	select {
+	case e := <-sub.Events():
+		t.Errorf("unexpected event %+v", e)
+	default:
+	}
+}
+
+func TestHub_SlowSubscriberIsDropped(t *testing.T) {
+	h := NewHub()
+	slow := h.Subscribe(1, nil)
+	fast := h.Subscribe(8, nil)
+	defer fast.Close()
+
+	h.Publish(Event{Type: EventVehicleStatus})
+	h.Publish(Event{Type: EventVehicleStatus})
+
+	<-slow.Events()
+	if _, ok := <-slow.Events(); ok {
+		t.Error("expected slow subscription to be closed")
+	}
+	if len(fast.Events()) != 2 {
+		t.Errorf("fast subscriber got %d events, want 2", len(fast.Events()))
+	}
+}
+
+func TestHub_CloseDisconnectsSubscribers(t *testing.T) {
+	h := NewHub()
+	sub := h.Subscribe(1, nil)
+
+	h.Close()
+
+	if _, ok := <-sub.Events(); ok {
+		t.Error("expected subscription to be closed")
+	}
+	if !h.Closed() {
+		t.Error("expected hub to report closed")
+	}
+
+	late := h.Subscribe(1, nil)
+	if _, ok := <-late.Events(); ok {
+		t.Error("expected subscription on closed hub to be closed")
+	}
+
+	// Closing an already-closed subscription must not panic.
+	sub.Close()
+	h.Publish(Event{Type: EventVehicleStatus})
+}
@@ -7,8 +7,26 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/lib/pq"
 )
 
+// ErrVehicleHeld is returned by ClockIn when another open shift already has
+// the vehicle checked out.
+var ErrVehicleHeld = errors.New("vehicle is checked out on another shift")
+
+// heldErr translates violations of uq_attendance_open_vehicle into
+// ErrVehicleHeld.
+func heldErr(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "uq_attendance_open_vehicle" {
+		return ErrVehicleHeld
+	}
+	return err
+}
+
+const attendanceColumns = `id, driver_id, vehicle_id, driver_status, clock_in_at, clock_out_at,
+	clock_in_odometer_km, clock_out_odometer_km, created_at`
+
 type AttendanceRepo struct {
 	db *sqlx.DB
 }
@@ -20,7 +38,7 @@ func NewAttendanceRepo(db *sqlx.DB) *AttendanceRepo {
 func (r *AttendanceRepo) GetActiveByDriverID(ctx context.Context, driverID string) (*model.DriverAttendance, error) {
 	var a model.DriverAttendance
 	err := r.db.GetContext(ctx, &a,
-		`SELECT id, driver_id, driver_status, clock_in_at, clock_out_at, created_at
+		`SELECT `+attendanceColumns+`
 		 FROM driver_attendance
 		 WHERE driver_id = $1 AND clock_out_at IS NULL
 		 ORDER BY clock_in_at DESC LIMIT 1`, driverID)
@@ -30,17 +48,18 @@ func (r *AttendanceRepo) GetActiveByDriverID(ctx context.Context, driverID strin
 	return &a, err
 }
 
-func (r *AttendanceRepo) ClockIn(ctx context.Context, driverID string) (*model.DriverAttendance, error) {
+// ClockIn opens a shift with vehicleID, if any, checked out until clock-out.
+func (r *AttendanceRepo) ClockIn(ctx context.Context, driverID string, vehicleID *string, odometerKm *int) (*model.DriverAttendance, error) {
 	var a model.DriverAttendance
 	err := r.db.GetContext(ctx, &a,
-		`INSERT INTO driver_attendance (driver_id) VALUES ($1)
-		 RETURNING id, driver_id, driver_status, clock_in_at, clock_out_at, created_at`, driverID)
-	return &a, err
+		`INSERT INTO driver_attendance (driver_id, vehicle_id, clock_in_odometer_km) VALUES ($1, $2, $3)
+		 RETURNING `+attendanceColumns, driverID, vehicleID, odometerKm)
+	return &a, heldErr(err)
 }
 
-func (r *AttendanceRepo) ClockOut(ctx context.Context, id string) error {
+func (r *AttendanceRepo) ClockOut(ctx context.Context, id string, odometerKm *int) error {
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE driver_attendance SET clock_out_at = NOW() WHERE id = $1`, id)
+		`UPDATE driver_attendance SET clock_out_at = NOW(), clock_out_odometer_km = $2 WHERE id = $1`, id, odometerKm)
 	return err
 }
 
@@ -50,10 +69,24 @@ func (r *AttendanceRepo) UpdateDriverStatus(ctx context.Context, id string, stat
 	return err
 }
 
+// ListByVehicleID returns the vehicle's shifts, latest first: who had it
+// checked out and when.
+func (r *AttendanceRepo) ListByVehicleID(ctx context.Context, vehicleID string, limit int) ([]model.VehicleAssignment, error) {
+	var records []model.VehicleAssignment
+	err := r.db.SelectContext(ctx, &records,
+		`SELECT `+attendanceColumns+`,
+			(SELECT u.name FROM users u WHERE u.id = driver_id) AS driver_name
+		 FROM driver_attendance
+		 WHERE vehicle_id = $1
+		 ORDER BY clock_in_at DESC
+		 LIMIT $2`, vehicleID, limit)
+	return records, err
+}
+
 func (r *AttendanceRepo) ListByDriverID(ctx context.Context, driverID string, limit int) ([]model.DriverAttendance, error) {
 	var records []model.DriverAttendance
 	err := r.db.SelectContext(ctx, &records,
-		`SELECT id, driver_id, driver_status, clock_in_at, clock_out_at, created_at
+		`SELECT `+attendanceColumns+`
 		 FROM driver_attendance
 		 WHERE driver_id = $1
 		 ORDER BY clock_in_at DESC
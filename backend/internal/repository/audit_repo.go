@@ -18,10 +18,12 @@ func NewAuditRepo(db *sqlx.DB) *AuditRepo {
 	return &AuditRepo{db: db}
 }
 
+// Create stores an entry. An empty ActorID records work the system did on its
+// own.
 func (r *AuditRepo) Create(ctx context.Context, log *model.AuditLog) error {
 	_, err := r.db.ExecContext(ctx, `
 		INSERT INTO audit_logs (actor_id, action, target_type, target_id, before_state, after_state, reason, ip_address)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)`,
+		VALUES (NULLIF($1, '')::uuid, $2, $3, $4, $5, $6, $7, $8)`,
 		log.ActorID, log.Action, log.TargetType, log.TargetID,
 		log.BeforeState, log.AfterState, log.Reason, log.IPAddress)
 	return err
@@ -30,10 +32,11 @@ func (r *AuditRepo) Create(ctx context.Context, log *model.AuditLog) error {
 func (r *AuditRepo) List(ctx context.Context, actorID, action, targetType string, from, to time.Time, limit, offset int) ([]model.AuditLog, error) {
 	var logs []model.AuditLog
 	query := `
-		SELECT a.id, a.actor_id, u.name AS actor_name, a.action, a.target_type, a.target_id,
+		SELECT a.id, COALESCE(a.actor_id::text, '') AS actor_id, COALESCE(u.name, 'system') AS actor_name,
+			a.action, a.target_type, a.target_id,
 			a.before_state, a.after_state, a.reason, a.ip_address, a.created_at
 		FROM audit_logs a
-		JOIN users u ON u.id = a.actor_id
+		LEFT JOIN users u ON u.id = a.actor_id
 		WHERE 1=1`
 
 	args := []interface{}{}
@@ -75,10 +78,11 @@ func (r *AuditRepo) List(ctx context.Context, actorID, action, targetType string
 func (r *AuditRepo) GetByID(ctx context.Context, id string) (*model.AuditLog, error) {
 	var log model.AuditLog
 	err := r.db.GetContext(ctx, &log, `
-		SELECT a.id, a.actor_id, u.name AS actor_name, a.action, a.target_type, a.target_id,
+		SELECT a.id, COALESCE(a.actor_id::text, '') AS actor_id, COALESCE(u.name, 'system') AS actor_name,
+			a.action, a.target_type, a.target_id,
 			a.before_state, a.after_state, a.reason, a.ip_address, a.created_at
 		FROM audit_logs a
-		JOIN users u ON u.id = a.actor_id
+		LEFT JOIN users u ON u.id = a.actor_id
 		WHERE a.id = $1`, id)
 	return &log, err
 }
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kento/driver/backend/internal/model"
+)
+
+type CalendarRepo struct {
+	db *sqlx.DB
+}
+
+func NewCalendarRepo(db *sqlx.DB) *CalendarRepo {
+	return &CalendarRepo{db: db}
+}
+
+const calendarFeedColumns = `id, token_hash, subject_type, subject_id, created_by, created_at, revoked_at`
+
+// feedTokenHash is what is stored in place of a feed token, so the table
+// alone does not let anyone read a feed.
+func feedTokenHash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Create stores f under the hash of f.Token. The token itself is kept only
+// on f, for the caller to hand out once.
+func (r *CalendarRepo) Create(ctx context.Context, f *model.CalendarFeed) error {
+	return r.db.GetContext(ctx, f, `
+		INSERT INTO calendar_feeds (token_hash, subject_type, subject_id, created_by)
+		VALUES ($1, $2, $3, $4)
+		RETURNING `+calendarFeedColumns,
+		feedTokenHash(f.Token), f.SubjectType, f.SubjectID, f.CreatedBy)
+}
+
+func (r *CalendarRepo) GetByID(ctx context.Context, id string) (*model.CalendarFeed, error) {
+	var f model.CalendarFeed
+	err := r.db.GetContext(ctx, &f, `SELECT `+calendarFeedColumns+` FROM calendar_feeds WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &f, err
+}
+
+// GetActiveByToken returns the unrevoked feed with token, or nil.
+func (r *CalendarRepo) GetActiveByToken(ctx context.Context, token string) (*model.CalendarFeed, error) {
+	var f model.CalendarFeed
+	err := r.db.GetContext(ctx, &f, `
+		SELECT `+calendarFeedColumns+` FROM calendar_feeds
+		WHERE token_hash = $1 AND revoked_at IS NULL`, feedTokenHash(token))
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &f, err
+}
+
+// ListByCreator returns the unrevoked feeds a user created, newest first.
+func (r *CalendarRepo) ListByCreator(ctx context.Context, userID string) ([]model.CalendarFeed, error) {
+	var feeds []model.CalendarFeed
+	err := r.db.SelectContext(ctx, &feeds, `
+		SELECT `+calendarFeedColumns+` FROM calendar_feeds
+		WHERE created_by = $1 AND revoked_at IS NULL
+		ORDER BY created_at DESC`, userID)
+	return feeds, err
+}
+
+func (r *CalendarRepo) Revoke(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE calendar_feeds SET revoked_at = NOW()
+		WHERE id = $1 AND revoked_at IS NULL`, id)
+	return err
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/lib/pq"
 )
 
 type ConflictRepo struct {
@@ -17,22 +18,57 @@ func NewConflictRepo(db *sqlx.DB) *ConflictRepo {
 	return &ConflictRepo{db: db}
 }
 
-func (r *ConflictRepo) Create(ctx context.Context, winningID, losingID string) (*model.ReservationConflict, error) {
+const conflictColumns = `id, winning_reservation_id, losing_reservation_id, maintenance_window_id, kind,
+	required_gap_sec, available_gap_sec, status, resolved_by, resolution_reason, resolved_at, created_at`
+
+// CreateUnlessPending files a conflict of kind between two reservations.
+// Clashes are re-detected on every change to a reservation, so nothing is
+// filed while the pair has one pending; it returns nil then. The gaps are
+// only known for turnaround and travel conflicts.
+func (r *ConflictRepo) CreateUnlessPending(ctx context.Context, winningID, losingID string, kind model.ConflictKind, requiredSec, availableSec *int) (*model.ReservationConflict, error) {
 	var c model.ReservationConflict
 	err := r.db.GetContext(ctx, &c, `
-		INSERT INTO reservation_conflicts (winning_reservation_id, losing_reservation_id)
-		VALUES ($1, $2)
-		RETURNING id, winning_reservation_id, losing_reservation_id, status, resolved_by,
-			resolution_reason, resolved_at, created_at`,
-		winningID, losingID)
+		INSERT INTO reservation_conflicts (winning_reservation_id, losing_reservation_id, kind,
+			required_gap_sec, available_gap_sec)
+		SELECT $1, $2, $3, $4, $5
+		WHERE NOT EXISTS (
+			SELECT 1 FROM reservation_conflicts
+			WHERE status = 'pending'
+				AND ((winning_reservation_id = $1 AND losing_reservation_id = $2)
+					OR (winning_reservation_id = $2 AND losing_reservation_id = $1))
+		)
+		RETURNING `+conflictColumns,
+		winningID, losingID, kind, requiredSec, availableSec)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &c, err
+}
+
+// CreateForMaintenance files a maintenance conflict between a window and a
+// reservation it overlaps, unless the pair has one pending; it returns nil
+// then.
+func (r *ConflictRepo) CreateForMaintenance(ctx context.Context, windowID, losingID string) (*model.ReservationConflict, error) {
+	var c model.ReservationConflict
+	err := r.db.GetContext(ctx, &c, `
+		INSERT INTO reservation_conflicts (maintenance_window_id, losing_reservation_id, kind)
+		SELECT $1, $2, 'maintenance'
+		WHERE NOT EXISTS (
+			SELECT 1 FROM reservation_conflicts
+			WHERE status = 'pending' AND maintenance_window_id = $1 AND losing_reservation_id = $2
+		)
+		RETURNING `+conflictColumns,
+		windowID, losingID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
 	return &c, err
 }
 
 func (r *ConflictRepo) GetByID(ctx context.Context, id string) (*model.ReservationConflict, error) {
 	var c model.ReservationConflict
 	err := r.db.GetContext(ctx, &c, `
-		SELECT id, winning_reservation_id, losing_reservation_id, status, resolved_by,
-			resolution_reason, resolved_at, created_at
+		SELECT `+conflictColumns+`
 		FROM reservation_conflicts WHERE id = $1`, id)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -40,14 +76,41 @@ func (r *ConflictRepo) GetByID(ctx context.Context, id string) (*model.Reservati
 	return &c, err
 }
 
-func (r *ConflictRepo) ListPending(ctx context.Context) ([]model.ReservationConflict, error) {
+// ListPending returns the pending conflicts whose losing reservation's
+// vehicle lies within the depot scope depotIDs, nil for all.
+func (r *ConflictRepo) ListPending(ctx context.Context, depotIDs []string) ([]model.ReservationConflict, error) {
 	var conflicts []model.ReservationConflict
 	err := r.db.SelectContext(ctx, &conflicts, `
-		SELECT id, winning_reservation_id, losing_reservation_id, status, resolved_by,
-			resolution_reason, resolved_at, created_at
+		SELECT `+conflictColumns+`
 		FROM reservation_conflicts
 		WHERE status = 'pending'
-		ORDER BY created_at DESC`)
+			AND `+inDepots(`(SELECT v.depot_id FROM reservations res JOIN vehicles v ON v.id = res.vehicle_id
+				WHERE res.id = losing_reservation_id)`, "$1")+`
+		ORDER BY created_at DESC`, pq.Array(depotIDs))
+	return conflicts, err
+}
+
+// ListPendingFor returns the pending conflicts reservationID is part of, on
+// either side.
+func (r *ConflictRepo) ListPendingFor(ctx context.Context, reservationID string) ([]model.ReservationConflict, error) {
+	var conflicts []model.ReservationConflict
+	err := r.db.SelectContext(ctx, &conflicts, `
+		SELECT `+conflictColumns+`
+		FROM reservation_conflicts
+		WHERE status = 'pending'
+			AND (winning_reservation_id = $1 OR losing_reservation_id = $1)
+		ORDER BY created_at`, reservationID)
+	return conflicts, err
+}
+
+// ListPendingForWindow returns the pending conflicts of a maintenance window.
+func (r *ConflictRepo) ListPendingForWindow(ctx context.Context, windowID string) ([]model.ReservationConflict, error) {
+	var conflicts []model.ReservationConflict
+	err := r.db.SelectContext(ctx, &conflicts, `
+		SELECT `+conflictColumns+`
+		FROM reservation_conflicts
+		WHERE status = 'pending' AND maintenance_window_id = $1
+		ORDER BY created_at`, windowID)
 	return conflicts, err
 }
 
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/lib/pq"
+)
+
+// ErrDepotNameTaken is returned when another depot already has the name.
+var ErrDepotNameTaken = errors.New("depot name is taken")
+
+func depotNameErr(err error) error {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == "23505" && pqErr.Constraint == "depots_name_key" {
+		return ErrDepotNameTaken
+	}
+	return err
+}
+
+// inDepots is true when the depot expr, a UUID expression, lies within the
+// depot scope arg, a uuid[] parameter. A NULL scope spans every depot, and
+// rows without a depot are shared by all.
+func inDepots(expr, arg string) string {
+	return `(` + arg + `::uuid[] IS NULL OR ` + expr + ` IS NULL OR ` + expr + ` = ANY(` + arg + `::uuid[]))`
+}
+
+const depotColumns = `id, name, address,
+	ST_Y(location::geometry) AS latitude, ST_X(location::geometry) AS longitude,
+	created_at, updated_at`
+
+type DepotRepo struct {
+	db *sqlx.DB
+}
+
+func NewDepotRepo(db *sqlx.DB) *DepotRepo {
+	return &DepotRepo{db: db}
+}
+
+// depotLocation builds the location of a depot, NULL without coordinates.
+const depotLocation = `CASE WHEN $3::float8 IS NULL OR $4::float8 IS NULL THEN NULL
+	ELSE ST_SetSRID(ST_MakePoint($4, $3), 4326)::geography END`
+
+func (r *DepotRepo) Create(ctx context.Context, d *model.Depot) error {
+	err := r.db.GetContext(ctx, d, `
+		INSERT INTO depots (name, address, location)
+		VALUES ($1, $2, `+depotLocation+`)
+		RETURNING `+depotColumns,
+		d.Name, d.Address, d.Latitude, d.Longitude)
+	return depotNameErr(err)
+}
+
+func (r *DepotRepo) GetByID(ctx context.Context, id string) (*model.Depot, error) {
+	var d model.Depot
+	err := r.db.GetContext(ctx, &d, `SELECT `+depotColumns+` FROM depots WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &d, err
+}
+
+// List returns the depots within the scope depotIDs, nil for all, by name.
+func (r *DepotRepo) List(ctx context.Context, depotIDs []string) ([]model.Depot, error) {
+	var depots []model.Depot
+	err := r.db.SelectContext(ctx, &depots, `
+		SELECT `+depotColumns+` FROM depots
+		WHERE `+inDepots("id", "$1")+`
+		ORDER BY name`, pq.Array(depotIDs))
+	return depots, err
+}
+
+func (r *DepotRepo) Update(ctx context.Context, d *model.Depot) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE depots SET name = $1, address = $2, location = `+depotLocation+`, updated_at = NOW()
+		WHERE id = $5`,
+		d.Name, d.Address, d.Latitude, d.Longitude, d.ID)
+	return depotNameErr(err)
+}
+
+// Delete removes a depot. Its vehicles become shared and its members lose
+// their membership.
+func (r *DepotRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM depots WHERE id = $1`, id)
+	return err
+}
+
+// ListUserDepotIDs returns the depots the user is a member of.
+func (r *DepotRepo) ListUserDepotIDs(ctx context.Context, userID string) ([]string, error) {
+	var ids []string
+	err := r.db.SelectContext(ctx, &ids,
+		`SELECT depot_id FROM user_depots WHERE user_id = $1 ORDER BY depot_id`, userID)
+	return ids, err
+}
+
+// SetUserDepots replaces the depots the user is a member of.
+func (r *DepotRepo) SetUserDepots(ctx context.Context, userID string, depotIDs []string) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM user_depots WHERE user_id = $1`, userID); err != nil {
+		return err
+	}
+	if _, err := tx.ExecContext(ctx, `
+		INSERT INTO user_depots (user_id, depot_id)
+		SELECT $1::uuid, unnest($2::uuid[])
+		ON CONFLICT DO NOTHING`, userID, pq.Array(depotIDs)); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
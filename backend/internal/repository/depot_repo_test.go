@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/kento/driver/backend/internal/db"
+)
+
+func TestDepotNameErr(t *testing.T) {
+	dup := &pq.Error{Code: "23505", Constraint: "depots_name_key"}
+	if err := depotNameErr(dup); !errors.Is(err, ErrDepotNameTaken) {
+		t.Errorf("depotNameErr(duplicate name) = %v, want ErrDepotNameTaken", err)
+	}
+	other := &pq.Error{Code: "23505", Constraint: "vehicles_license_plate_key"}
+	if err := depotNameErr(other); err != other {
+		t.Errorf("depotNameErr(unique violation) = %v, want it unchanged", err)
+	}
+}
+
+// TestFindAvailableVehicleForSlot_DepotScope checks a dispatcher's search
+// only offers vehicles of their depots and shared ones. Needs a migrated
+// Postgres in TEST_DATABASE_URL.
+func TestFindAvailableVehicleForSlot_DepotScope(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	conn, err := db.Connect(url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+	if err := db.RunMigrations(conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+	var north, south string
+	for name, id := range map[string]*string{"North " + suffix: &north, "South " + suffix: &south} {
+		if err := conn.GetContext(ctx, id, `INSERT INTO depots (name) VALUES ($1) RETURNING id`, name); err != nil {
+			t.Fatalf("insert depot: %v", err)
+		}
+	}
+	var inNorth, inSouth, shared string
+	for plate, v := range map[string]struct {
+		id    *string
+		depot interface{}
+	}{
+		"N-" + suffix: {&inNorth, north},
+		"S-" + suffix: {&inSouth, south},
+		"X-" + suffix: {&shared, nil},
+	} {
+		if err := conn.GetContext(ctx, v.id, `
+			INSERT INTO vehicles (name, license_plate, depot_id)
+			VALUES ('Depot Test', $1, $2) RETURNING id`, plate, v.depot); err != nil {
+			t.Fatalf("insert vehicle: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM vehicles WHERE id IN ($1, $2, $3)`, inNorth, inSouth, shared)
+		conn.Exec(`DELETE FROM depots WHERE id IN ($1, $2)`, north, south)
+	})
+
+	repo := NewReservationRepo(conn)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Minute)
+	ids, err := repo.FindAvailableVehicleForSlot(ctx, start, start.Add(time.Hour), nil, []string{north})
+	if err != nil {
+		t.Fatalf("FindAvailableVehicleForSlot: %v", err)
+	}
+
+	found := map[string]bool{}
+	for _, id := range ids {
+		found[id] = true
+	}
+	if !found[inNorth] || !found[shared] || found[inSouth] {
+		t.Errorf("north scope offered north=%v shared=%v south=%v; want true, true, false",
+			found[inNorth], found[shared], found[inSouth])
+	}
+}
@@ -4,9 +4,11 @@ import (
 	"context"
 	"database/sql"
 	"errors"
+	"time"
 
 	"github.com/jmoiron/sqlx"
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/lib/pq"
 )
 
 type DispatchRepo struct {
@@ -40,12 +42,45 @@ func (r *DispatchRepo) Create(ctx context.Context, d *model.Dispatch) error {
 		  ST_Y(dropoff_location::geometry) AS dropoff_lat, ST_X(dropoff_location::geometry) AS dropoff_lng,
 		  status, estimated_duration_sec, estimated_distance_m, estimated_end_at,
 		  assigned_at, accepted_at, en_route_at, arrived_at, completed_at, cancelled_at,
-		  cancel_reason, created_at, updated_at`,
+		  cancel_reason, declined_vehicle_ids, reservation_id, version, created_at, updated_at`,
 		d.RequesterID, d.Purpose, d.PassengerName, d.PassengerCount, d.Notes,
 		d.PickupAddress, d.PickupLat, d.PickupLng,
 		d.DropoffAddress, d.DropoffLat, d.DropoffLng, d.EstimatedEndAt)
 }
 
+// CreateForReservation stores the trip for a reservation, already offered to
+// its vehicle. It returns false when the reservation has a trip already.
+func (r *DispatchRepo) CreateForReservation(ctx context.Context, d *model.Dispatch) (bool, error) {
+	err := r.db.GetContext(ctx, d, `
+		INSERT INTO dispatches (
+			requester_id, purpose, passenger_name, passenger_count, notes,
+			pickup_address, pickup_location, dropoff_address, estimated_end_at,
+			vehicle_id, reservation_id, status, assigned_at
+		) VALUES (
+			$1, $2, $3, $4, $5, $6,
+			CASE WHEN $7::float8 IS NOT NULL AND $8::float8 IS NOT NULL
+				THEN ST_SetSRID(ST_MakePoint($8, $7), 4326)::geography
+				ELSE NULL END,
+			$9, $10, $11, $12, 'assigned', NOW()
+		)
+		ON CONFLICT (reservation_id) WHERE reservation_id IS NOT NULL AND status <> 'cancelled' DO NOTHING
+		RETURNING id, vehicle_id, requester_id, dispatcher_id, purpose, passenger_name,
+		  passenger_count, notes, pickup_address,
+		  ST_Y(pickup_location::geometry) AS pickup_lat, ST_X(pickup_location::geometry) AS pickup_lng,
+		  dropoff_address,
+		  ST_Y(dropoff_location::geometry) AS dropoff_lat, ST_X(dropoff_location::geometry) AS dropoff_lng,
+		  status, estimated_duration_sec, estimated_distance_m, estimated_end_at,
+		  assigned_at, accepted_at, en_route_at, arrived_at, completed_at, cancelled_at,
+		  cancel_reason, declined_vehicle_ids, reservation_id, version, created_at, updated_at`,
+		d.RequesterID, d.Purpose, d.PassengerName, d.PassengerCount, d.Notes,
+		d.PickupAddress, d.PickupLat, d.PickupLng, d.DropoffAddress, d.EstimatedEndAt,
+		d.VehicleID, d.ReservationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
 func (r *DispatchRepo) GetByID(ctx context.Context, id string) (*model.Dispatch, error) {
 	var d model.Dispatch
 	err := r.db.GetContext(ctx, &d, `
@@ -58,7 +93,7 @@ func (r *DispatchRepo) GetByID(ctx context.Context, id string) (*model.Dispatch,
 			ST_X(dropoff_location::geometry) AS dropoff_lng,
 			status, estimated_duration_sec, estimated_distance_m, estimated_end_at,
 			assigned_at, accepted_at, en_route_at, arrived_at, completed_at, cancelled_at,
-			cancel_reason, created_at, updated_at
+			cancel_reason, declined_vehicle_ids, reservation_id, version, created_at, updated_at
 		FROM dispatches WHERE id = $1`, id)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -66,7 +101,10 @@ func (r *DispatchRepo) GetByID(ctx context.Context, id string) (*model.Dispatch,
 	return &d, err
 }
 
-func (r *DispatchRepo) List(ctx context.Context, status string, limit, offset int) ([]model.Dispatch, error) {
+// List returns dispatches newest first. Only those within the depot scope
+// depotIDs, nil for all, are returned: a dispatch belongs to the depot of its
+// vehicle, so unassigned ones are seen by every depot.
+func (r *DispatchRepo) List(ctx context.Context, status string, depotIDs []string, limit, offset int) ([]model.Dispatch, error) {
 	var dispatches []model.Dispatch
 	query := `
 		SELECT id, vehicle_id, requester_id, dispatcher_id, purpose, passenger_name,
@@ -78,59 +116,136 @@ func (r *DispatchRepo) List(ctx context.Context, status string, limit, offset in
 			ST_X(dropoff_location::geometry) AS dropoff_lng,
 			status, estimated_duration_sec, estimated_distance_m, estimated_end_at,
 			assigned_at, accepted_at, en_route_at, arrived_at, completed_at, cancelled_at,
-			cancel_reason, created_at, updated_at
-		FROM dispatches`
+			cancel_reason, declined_vehicle_ids, reservation_id, version, created_at, updated_at
+		FROM dispatches
+		WHERE ` + inDepots(`(SELECT v.depot_id FROM vehicles v WHERE v.id = dispatches.vehicle_id)`, "$3")
 
+	args := []interface{}{limit, offset, pq.Array(depotIDs)}
 	if status != "" {
-		query += ` WHERE status = $3`
-		query += ` ORDER BY created_at DESC LIMIT $1 OFFSET $2`
-		err := r.db.SelectContext(ctx, &dispatches, query, limit, offset, status)
-		return dispatches, err
+		query += ` AND status = $4`
+		args = append(args, status)
 	}
 	query += ` ORDER BY created_at DESC LIMIT $1 OFFSET $2`
-	err := r.db.SelectContext(ctx, &dispatches, query, limit, offset)
+	err := r.db.SelectContext(ctx, &dispatches, query, args...)
 	return dispatches, err
 }
 
-func (r *DispatchRepo) Assign(ctx context.Context, dispatchID, vehicleID, dispatcherID string) error {
-	_, err := r.db.ExecContext(ctx, `
+// Status changes below are conditional on the status and version the caller
+// read. false means the dispatch changed in the meantime and nothing was
+// written.
+
+func (r *DispatchRepo) Assign(ctx context.Context, dispatchID string, version int, vehicleID, dispatcherID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
 		UPDATE dispatches
-		SET vehicle_id = $1, dispatcher_id = $2, status = 'assigned', assigned_at = NOW(), updated_at = NOW()
-		WHERE id = $3 AND status = 'pending'`,
-		vehicleID, dispatcherID, dispatchID)
-	return err
+		SET vehicle_id = $1, dispatcher_id = $2, status = 'assigned', assigned_at = NOW(),
+			version = version + 1, updated_at = NOW()
+		WHERE id = $3 AND status = 'pending' AND version = $4`,
+		vehicleID, dispatcherID, dispatchID, version)
+	return affected(result, err)
+}
+
+// Board puts a passenger straight into a vehicle: the pending dispatch is
+// assigned, accepted and en route in one step.
+func (r *DispatchRepo) Board(ctx context.Context, dispatchID string, version int, vehicleID, dispatcherID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE dispatches
+		SET vehicle_id = $1, dispatcher_id = $2, status = 'en_route',
+			assigned_at = NOW(), accepted_at = NOW(), en_route_at = NOW(),
+			version = version + 1, updated_at = NOW()
+		WHERE id = $3 AND status = 'pending' AND version = $4`,
+		vehicleID, dispatcherID, dispatchID, version)
+	return affected(result, err)
 }
 
-func (r *DispatchRepo) UpdateStatus(ctx context.Context, id string, status model.DispatchStatus) error {
-	var timestampCol string
-	switch status {
+// ReleaseOffer puts an assigned dispatch back to pending and records the
+// vehicle as having declined it. It only applies while the offer to that
+// vehicle is still open; false means the driver accepted or the dispatch was
+// reassigned in the meantime.
+func (r *DispatchRepo) ReleaseOffer(ctx context.Context, dispatchID string, version int, vehicleID string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
+		UPDATE dispatches
+		SET vehicle_id = NULL, status = 'pending', assigned_at = NULL,
+			declined_vehicle_ids = array_append(declined_vehicle_ids, $2::uuid),
+			version = version + 1, updated_at = NOW()
+		WHERE id = $1 AND status = 'assigned' AND vehicle_id = $2 AND version = $3`,
+		dispatchID, vehicleID, version)
+	return affected(result, err)
+}
+
+// ListOffersAssignedBefore returns dispatches still waiting for the driver to
+// accept an offer made before the cutoff.
+func (r *DispatchRepo) ListOffersAssignedBefore(ctx context.Context, before time.Time) ([]model.Dispatch, error) {
+	var dispatches []model.Dispatch
+	err := r.db.SelectContext(ctx, &dispatches, `
+		SELECT id, vehicle_id, requester_id, dispatcher_id, purpose, passenger_name,
+			passenger_count, notes, pickup_address,
+			ST_Y(pickup_location::geometry) AS pickup_lat,
+			ST_X(pickup_location::geometry) AS pickup_lng,
+			dropoff_address,
+			ST_Y(dropoff_location::geometry) AS dropoff_lat,
+			ST_X(dropoff_location::geometry) AS dropoff_lng,
+			status, estimated_duration_sec, estimated_distance_m, estimated_end_at,
+			assigned_at, accepted_at, en_route_at, arrived_at, completed_at, cancelled_at,
+			cancel_reason, declined_vehicle_ids, reservation_id, version, created_at, updated_at
+		FROM dispatches
+		WHERE status = 'assigned' AND assigned_at < $1 AND reservation_id IS NULL
+		ORDER BY assigned_at`, before)
+	return dispatches, err
+}
+
+// ListUnstartedPastReservations returns trips still waiting for a vehicle or
+// an accept whose reservation has already ended.
+func (r *DispatchRepo) ListUnstartedPastReservations(ctx context.Context) ([]model.Dispatch, error) {
+	var dispatches []model.Dispatch
+	err := r.db.SelectContext(ctx, &dispatches, `
+		SELECT d.id, d.vehicle_id, d.requester_id, d.dispatcher_id, d.purpose, d.passenger_name,
+			d.passenger_count, d.notes, d.pickup_address,
+			ST_Y(d.pickup_location::geometry) AS pickup_lat,
+			ST_X(d.pickup_location::geometry) AS pickup_lng,
+			d.dropoff_address,
+			ST_Y(d.dropoff_location::geometry) AS dropoff_lat,
+			ST_X(d.dropoff_location::geometry) AS dropoff_lng,
+			d.status, d.estimated_duration_sec, d.estimated_distance_m, d.estimated_end_at,
+			d.assigned_at, d.accepted_at, d.en_route_at, d.arrived_at, d.completed_at, d.cancelled_at,
+			d.cancel_reason, d.declined_vehicle_ids, d.reservation_id, d.version, d.created_at, d.updated_at
+		FROM dispatches d
+		JOIN reservations r ON r.id = d.reservation_id
+		WHERE d.status IN ('pending','assigned') AND r.end_time < NOW()
+		ORDER BY r.end_time`)
+	return dispatches, err
+}
+
+// Transition moves a dispatch from one status to another, stamping the
+// matching timestamp column. Whether the transition is allowed is the
+// caller's business; see model.DispatchTransitions.
+func (r *DispatchRepo) Transition(ctx context.Context, id string, from model.DispatchStatus, version int, to model.DispatchStatus) (bool, error) {
+	set := "status = $1"
+	switch to {
 	case model.DispatchStatusAccepted:
-		timestampCol = "accepted_at"
+		set += ", accepted_at = NOW()"
 	case model.DispatchStatusEnRoute:
-		timestampCol = "en_route_at"
+		set += ", en_route_at = NOW()"
 	case model.DispatchStatusArrived:
-		timestampCol = "arrived_at"
+		set += ", arrived_at = NOW()"
 	case model.DispatchStatusCompleted:
-		timestampCol = "completed_at"
-	default:
-		_, err := r.db.ExecContext(ctx,
-			`UPDATE dispatches SET status = $1, updated_at = NOW() WHERE id = $2`, status, id)
-		return err
+		set += ", completed_at = NOW()"
 	}
 
-	_, err := r.db.ExecContext(ctx,
-		`UPDATE dispatches SET status = $1, `+timestampCol+` = NOW(), updated_at = NOW() WHERE id = $2`,
-		status, id)
-	return err
+	result, err := r.db.ExecContext(ctx,
+		`UPDATE dispatches SET `+set+`, version = version + 1, updated_at = NOW()
+		WHERE id = $2 AND status = $3 AND version = $4`,
+		to, id, from, version)
+	return affected(result, err)
 }
 
-func (r *DispatchRepo) Cancel(ctx context.Context, id, reason string) error {
-	_, err := r.db.ExecContext(ctx, `
+func (r *DispatchRepo) Cancel(ctx context.Context, id string, from model.DispatchStatus, version int, reason string) (bool, error) {
+	result, err := r.db.ExecContext(ctx, `
 		UPDATE dispatches
-		SET status = 'cancelled', cancelled_at = NOW(), cancel_reason = $1, updated_at = NOW()
-		WHERE id = $2 AND status NOT IN ('completed', 'cancelled')`,
-		reason, id)
-	return err
+		SET status = 'cancelled', cancelled_at = NOW(), cancel_reason = $1,
+			version = version + 1, updated_at = NOW()
+		WHERE id = $2 AND status = $3 AND version = $4`,
+		reason, id, from, version)
+	return affected(result, err)
 }
 
 func (r *DispatchRepo) ListByRequester(ctx context.Context, requesterID, status string, limit, offset int) ([]model.Dispatch, error) {
@@ -145,7 +260,7 @@ func (r *DispatchRepo) ListByRequester(ctx context.Context, requesterID, status
 			ST_X(dropoff_location::geometry) AS dropoff_lng,
 			status, estimated_duration_sec, estimated_distance_m, estimated_end_at,
 			assigned_at, accepted_at, en_route_at, arrived_at, completed_at, cancelled_at,
-			cancel_reason, created_at, updated_at
+			cancel_reason, declined_vehicle_ids, reservation_id, version, created_at, updated_at
 		FROM dispatches WHERE requester_id = $1`
 
 	if status != "" {
@@ -171,7 +286,7 @@ func (r *DispatchRepo) GetActiveByVehicleID(ctx context.Context, vehicleID strin
 			ST_X(dropoff_location::geometry) AS dropoff_lng,
 			status, estimated_duration_sec, estimated_distance_m, estimated_end_at,
 			assigned_at, accepted_at, en_route_at, arrived_at, completed_at, cancelled_at,
-			cancel_reason, created_at, updated_at
+			cancel_reason, declined_vehicle_ids, reservation_id, version, created_at, updated_at
 		FROM dispatches
 		WHERE vehicle_id = $1 AND status IN ('assigned','accepted','en_route','arrived')
 		ORDER BY created_at DESC LIMIT 1`, vehicleID)
@@ -181,6 +296,28 @@ func (r *DispatchRepo) GetActiveByVehicleID(ctx context.Context, vehicleID strin
 	return &d, err
 }
 
+// GetActiveByReservationID returns the unfinished trip of a reservation.
+func (r *DispatchRepo) GetActiveByReservationID(ctx context.Context, reservationID string) (*model.Dispatch, error) {
+	var d model.Dispatch
+	err := r.db.GetContext(ctx, &d, `
+		SELECT id, vehicle_id, requester_id, dispatcher_id, purpose, passenger_name,
+			passenger_count, notes, pickup_address,
+			ST_Y(pickup_location::geometry) AS pickup_lat,
+			ST_X(pickup_location::geometry) AS pickup_lng,
+			dropoff_address,
+			ST_Y(dropoff_location::geometry) AS dropoff_lat,
+			ST_X(dropoff_location::geometry) AS dropoff_lng,
+			status, estimated_duration_sec, estimated_distance_m, estimated_end_at,
+			assigned_at, accepted_at, en_route_at, arrived_at, completed_at, cancelled_at,
+			cancel_reason, declined_vehicle_ids, reservation_id, version, created_at, updated_at
+		FROM dispatches
+		WHERE reservation_id = $1 AND status IN ('pending','assigned','accepted','en_route','arrived')`, reservationID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &d, err
+}
+
 func (r *DispatchRepo) GetActiveByDriverID(ctx context.Context, driverID string) (*model.Dispatch, error) {
 	var d model.Dispatch
 	err := r.db.GetContext(ctx, &d, `
@@ -193,10 +330,10 @@ func (r *DispatchRepo) GetActiveByDriverID(ctx context.Context, driverID string)
 			ST_X(d.dropoff_location::geometry) AS dropoff_lng,
 			d.status, d.estimated_duration_sec, d.estimated_distance_m, d.estimated_end_at,
 			d.assigned_at, d.accepted_at, d.en_route_at, d.arrived_at, d.completed_at, d.cancelled_at,
-			d.cancel_reason, d.created_at, d.updated_at
+			d.cancel_reason, d.declined_vehicle_ids, d.reservation_id, d.version, d.created_at, d.updated_at
 		FROM dispatches d
-		JOIN vehicles v ON v.id = d.vehicle_id
-		WHERE v.driver_id = $1 AND d.status IN ('assigned','accepted','en_route','arrived')
+		JOIN driver_attendance a ON a.vehicle_id = d.vehicle_id AND a.clock_out_at IS NULL
+		WHERE a.driver_id = $1 AND d.status IN ('assigned','accepted','en_route','arrived')
 		ORDER BY d.created_at DESC LIMIT 1`, driverID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
@@ -204,12 +341,25 @@ func (r *DispatchRepo) GetActiveByDriverID(ctx context.Context, driverID string)
 	return &d, err
 }
 
-func (r *DispatchRepo) SaveETASnapshot(ctx context.Context, snap *model.DispatchETASnapshot) error {
-	_, err := r.db.ExecContext(ctx, `
-		INSERT INTO dispatch_eta_snapshots (dispatch_id, vehicle_id, duration_sec, distance_m, origin_lat, origin_lng)
-		VALUES ($1, $2, $3, $4, $5, $6)`,
-		snap.DispatchID, snap.VehicleID, snap.DurationSec, snap.DistanceM, 0.0, 0.0)
-	return err
+// SaveETASnapshots stores one batch of candidate estimates atomically, all
+// stamped with the same calculation time.
+func (r *DispatchRepo) SaveETASnapshots(ctx context.Context, snaps []model.DispatchETASnapshot) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	for _, snap := range snaps {
+		if _, err := tx.NamedExecContext(ctx, `
+			INSERT INTO dispatch_eta_snapshots (batch_id, dispatch_id, vehicle_id, vehicle_status, origin_lat, origin_lng,
+				duration_sec, distance_m, provider, trigger, actor_id, chosen, calculated_at)
+			VALUES (:batch_id, :dispatch_id, :vehicle_id, :vehicle_status, :origin_lat, :origin_lng,
+				:duration_sec, :distance_m, :provider, :trigger, :actor_id, :chosen, :calculated_at)`, snap); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
 }
 
 func (r *DispatchRepo) RateDispatch(ctx context.Context, dispatchID string, rating int, comment string) error {
@@ -219,14 +369,68 @@ func (r *DispatchRepo) RateDispatch(ctx context.Context, dispatchID string, rati
 	return err
 }
 
+// GetETASnapshots returns every recorded batch for a dispatch, newest batch
+// first and fastest candidate first within a batch.
 func (r *DispatchRepo) GetETASnapshots(ctx context.Context, dispatchID string) ([]model.DispatchETASnapshot, error) {
 	var snapshots []model.DispatchETASnapshot
 	err := r.db.SelectContext(ctx, &snapshots, `
-		SELECT s.id, s.dispatch_id, s.vehicle_id, v.name AS vehicle_name,
-			s.duration_sec, s.distance_m, s.calculated_at
+		SELECT s.id, s.batch_id, s.dispatch_id, s.vehicle_id, v.name AS vehicle_name, s.vehicle_status,
+			s.origin_lat, s.origin_lng, s.duration_sec, s.distance_m, s.provider, s.trigger,
+			s.actor_id, u.name AS actor_name, s.chosen, s.calculated_at
 		FROM dispatch_eta_snapshots s
 		JOIN vehicles v ON v.id = s.vehicle_id
+		LEFT JOIN users u ON u.id = s.actor_id
 		WHERE s.dispatch_id = $1
-		ORDER BY s.duration_sec ASC`, dispatchID)
+		ORDER BY s.calculated_at DESC, s.batch_id, s.duration_sec ASC`, dispatchID)
 	return snapshots, err
 }
+
+// ListActive returns every dispatch that has not reached a terminal status.
+func (r *DispatchRepo) ListActive(ctx context.Context) ([]model.Dispatch, error) {
+	var dispatches []model.Dispatch
+	err := r.db.SelectContext(ctx, &dispatches, `
+		SELECT id, vehicle_id, requester_id, dispatcher_id, purpose, passenger_name,
+			passenger_count, notes, pickup_address,
+			ST_Y(pickup_location::geometry) AS pickup_lat,
+			ST_X(pickup_location::geometry) AS pickup_lng,
+			dropoff_address,
+			ST_Y(dropoff_location::geometry) AS dropoff_lat,
+			ST_X(dropoff_location::geometry) AS dropoff_lng,
+			status, estimated_duration_sec, estimated_distance_m, estimated_end_at,
+			assigned_at, accepted_at, en_route_at, arrived_at, completed_at, cancelled_at,
+			cancel_reason, declined_vehicle_ids, reservation_id, version, created_at, updated_at
+		FROM dispatches
+		WHERE status IN ('pending','assigned','accepted','en_route','arrived')
+		ORDER BY created_at DESC`)
+	return dispatches, err
+}
+
+// ListWorkloads returns, per vehicle, how many trips were assigned since the
+// driver holding it clocked in and when the vehicle last became free (the later of its
+// last completed trip and the clock-in).
+func (r *DispatchRepo) ListWorkloads(ctx context.Context) ([]model.VehicleWorkload, error) {
+	var workloads []model.VehicleWorkload
+	err := r.db.SelectContext(ctx, &workloads, `
+		SELECT v.id AS vehicle_id,
+			COALESCE((
+				SELECT COUNT(*) FROM dispatches d
+				WHERE d.vehicle_id = v.id AND d.assigned_at >= da.clock_in_at
+					AND d.status <> 'cancelled'
+			), 0) AS shift_trips,
+			GREATEST(
+				(SELECT MAX(d.completed_at) FROM dispatches d WHERE d.vehicle_id = v.id),
+				da.clock_in_at
+			) AS idle_since
+		FROM vehicles v
+		LEFT JOIN driver_attendance da ON da.vehicle_id = v.id AND da.clock_out_at IS NULL`)
+	return workloads, err
+}
+
+// affected reports whether a conditional update matched a row.
+func affected(result sql.Result, err error) (bool, error) {
+	if err != nil {
+		return false, err
+	}
+	n, err := result.RowsAffected()
+	return n > 0, err
+}
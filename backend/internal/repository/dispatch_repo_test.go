@@ -0,0 +1,83 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/testdb"
+)
+
+// TestCreateForReservation_Once turns a reservation due soon into a trip
+// twice. Only the first call may create one, and the reservation is no
+// longer due afterwards. Needs a migrated Postgres in TEST_DATABASE_URL.
+func TestCreateForReservation_Once(t *testing.T) {
+	conn := testdb.Open(t)
+	ctx := context.Background()
+	f := testdb.NewFixture(t, conn, "Trip Test", model.RoleDriver)
+	userID, vehicleID := f.UserID, f.VehicleID
+
+	reservations := NewReservationRepo(conn)
+	dispatches := NewDispatchRepo(conn)
+	start := time.Now().Add(10 * time.Minute).Truncate(time.Minute)
+	res := &model.Reservation{
+		VehicleID:   vehicleID,
+		RequesterID: userID,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Purpose:     "trip test",
+		Status:      model.ReservationStatusConfirmed,
+	}
+	if err := reservations.Create(ctx, res); err != nil {
+		t.Fatalf("create reservation: %v", err)
+	}
+
+	due, err := reservations.ListDueForTrip(ctx, 30*time.Minute)
+	if err != nil {
+		t.Fatalf("list due: %v", err)
+	}
+	if !containsReservation(due, res.ID) {
+		t.Fatalf("reservation starting in 10 minutes is not due")
+	}
+
+	newTrip := func() *model.Dispatch {
+		return &model.Dispatch{
+			RequesterID:    userID,
+			Purpose:        res.Purpose,
+			PassengerCount: 1,
+			PickupAddress:  "(reservation)",
+			VehicleID:      &vehicleID,
+			ReservationID:  &res.ID,
+		}
+	}
+	first := newTrip()
+	created, err := dispatches.CreateForReservation(ctx, first)
+	if err != nil || !created {
+		t.Fatalf("first CreateForReservation = %v, %v; want true", created, err)
+	}
+	if first.Status != model.DispatchStatusAssigned || first.AssignedAt == nil {
+		t.Errorf("trip status = %s, assigned_at = %v; want assigned now", first.Status, first.AssignedAt)
+	}
+	created, err = dispatches.CreateForReservation(ctx, newTrip())
+	if err != nil || created {
+		t.Errorf("second CreateForReservation = %v, %v; want false", created, err)
+	}
+
+	due, err = reservations.ListDueForTrip(ctx, 30*time.Minute)
+	if err != nil {
+		t.Fatalf("list due: %v", err)
+	}
+	if containsReservation(due, res.ID) {
+		t.Error("reservation with a trip is still due")
+	}
+}
+
+func containsReservation(list []model.Reservation, id string) bool {
+	for _, r := range list {
+		if r.ID == id {
+			return true
+		}
+	}
+	return false
+}
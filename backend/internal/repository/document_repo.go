@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kento/driver/backend/internal/model"
+)
+
+type DocumentRepo struct {
+	db *sqlx.DB
+}
+
+func NewDocumentRepo(db *sqlx.DB) *DocumentRepo {
+	return &DocumentRepo{db: db}
+}
+
+const documentColumns = `d.id, d.vehicle_id, d.user_id, d.type, d.number, d.issued_on, d.expires_on,
+	d.scan_file, d.notes, d.created_by, d.created_at, d.updated_at`
+
+// documentIsCurrent is true for a document d that no later one of the same
+// subject and type has replaced. Other documents are unrelated to each
+// other, so each stays current.
+const documentIsCurrent = `(d.type = 'other' OR NOT EXISTS (
+	SELECT 1 FROM documents n
+	WHERE n.id <> d.id AND n.type = d.type
+		AND n.vehicle_id IS NOT DISTINCT FROM d.vehicle_id
+		AND n.user_id IS NOT DISTINCT FROM d.user_id
+		AND (n.expires_on IS NULL OR n.expires_on > d.expires_on)))`
+
+const utcToday = `(NOW() AT TIME ZONE 'UTC')::date`
+
+// complianceExpiredBy is true for a vehicle v whose latest document of some
+// compliance type expires before day, a DATE expression. Types the vehicle
+// has no document of do not count.
+func complianceExpiredBy(day string) string {
+	return `EXISTS (
+		SELECT 1 FROM documents doc
+		WHERE doc.vehicle_id = v.id AND doc.type IN ('registration', 'insurance', 'emission_test')
+		GROUP BY doc.type
+		HAVING bool_and(doc.expires_on IS NOT NULL) AND MAX(doc.expires_on) < ` + day + `)`
+}
+
+// dateArg passes a calendar day as a DATE literal, so the session time zone
+// cannot shift it.
+func dateArg(t *time.Time) interface{} {
+	if t == nil {
+		return nil
+	}
+	return t.UTC().Format("2006-01-02")
+}
+
+func (r *DocumentRepo) Create(ctx context.Context, doc *model.Document) error {
+	return r.db.GetContext(ctx, doc, `
+		INSERT INTO documents AS d (vehicle_id, user_id, type, number, issued_on, expires_on, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING `+documentColumns,
+		doc.VehicleID, doc.UserID, doc.Type, doc.Number, dateArg(doc.IssuedOn), dateArg(doc.ExpiresOn), doc.Notes, doc.CreatedBy)
+}
+
+func (r *DocumentRepo) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	var doc model.Document
+	err := r.db.GetContext(ctx, &doc, `SELECT `+documentColumns+` FROM documents d WHERE d.id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &doc, err
+}
+
+// List returns documents by subject and type. With expiringWithin set, only
+// current documents expiring within that many days, or already expired,
+// soonest first.
+func (r *DocumentRepo) List(ctx context.Context, vehicleID, userID, docType string, expiringWithin *int, limit, offset int) ([]model.Document, error) {
+	var docs []model.Document
+	query := `SELECT ` + documentColumns + ` FROM documents d WHERE 1=1`
+
+	args := []interface{}{}
+	argIdx := 1
+
+	if vehicleID != "" {
+		query += ` AND d.vehicle_id = $` + itoa(argIdx)
+		args = append(args, vehicleID)
+		argIdx++
+	}
+	if userID != "" {
+		query += ` AND d.user_id = $` + itoa(argIdx)
+		args = append(args, userID)
+		argIdx++
+	}
+	if docType != "" {
+		query += ` AND d.type = $` + itoa(argIdx)
+		args = append(args, docType)
+		argIdx++
+	}
+	if expiringWithin != nil {
+		query += ` AND d.expires_on <= ` + utcToday + ` + $` + itoa(argIdx) + `::int AND ` + documentIsCurrent
+		args = append(args, *expiringWithin)
+		argIdx++
+		query += ` ORDER BY d.expires_on, d.created_at`
+	} else {
+		query += ` ORDER BY d.type, d.expires_on DESC NULLS FIRST, d.created_at DESC`
+	}
+
+	query += ` LIMIT $` + itoa(argIdx) + ` OFFSET $` + itoa(argIdx+1)
+	args = append(args, limit, offset)
+
+	err := r.db.SelectContext(ctx, &docs, query, args...)
+	return docs, err
+}
+
+func (r *DocumentRepo) Update(ctx context.Context, doc *model.Document) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE documents
+		SET number = $1, issued_on = $2, expires_on = $3, notes = $4, updated_at = NOW()
+		WHERE id = $5`,
+		doc.Number, dateArg(doc.IssuedOn), dateArg(doc.ExpiresOn), doc.Notes, doc.ID)
+	return err
+}
+
+func (r *DocumentRepo) SetScan(ctx context.Context, id string, scanFile *string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE documents SET scan_file = $1, updated_at = NOW() WHERE id = $2`, scanFile, id)
+	return err
+}
+
+func (r *DocumentRepo) Delete(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM documents WHERE id = $1`, id)
+	return err
+}
+
+// ListExpiring returns the current documents expiring within warnDays, or
+// expired, that have not yet had their expired warning, with the name of the
+// vehicle or user they belong to.
+func (r *DocumentRepo) ListExpiring(ctx context.Context, warnDays int) ([]model.DueDocument, error) {
+	var docs []model.DueDocument
+	err := r.db.SelectContext(ctx, &docs, `
+		SELECT `+documentColumns+`, COALESCE(v.name, u.name) AS subject_name
+		FROM documents d
+		LEFT JOIN vehicles v ON v.id = d.vehicle_id
+		LEFT JOIN users u ON u.id = d.user_id
+		WHERE d.expires_on <= `+utcToday+` + $1::int
+			AND `+documentIsCurrent+`
+			AND NOT EXISTS (
+				SELECT 1 FROM document_expiry_alerts a
+				WHERE a.document_id = d.id AND a.stage = 'expired' AND a.expires_on = d.expires_on
+			)
+		ORDER BY d.expires_on`, warnDays)
+	return docs, err
+}
+
+// ClaimExpiryAlert records an expiry warning as sent. It returns false when
+// another run or replica already claimed it.
+func (r *DocumentRepo) ClaimExpiryAlert(ctx context.Context, documentID, stage string, expiresOn time.Time) (bool, error) {
+	var id string
+	err := r.db.GetContext(ctx, &id, `
+		INSERT INTO document_expiry_alerts (document_id, stage, expires_on)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (document_id, stage, expires_on) DO NOTHING
+		RETURNING document_id`, documentID, stage, dateArg(&expiresOn))
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
@@ -0,0 +1,253 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+)
+
+// vehicle_locations is partitioned by UTC day and vehicle_locations_downsampled
+// by UTC month. Partition names encode their range so the maintenance code can
+// find what to create or drop without parsing partition bounds.
+const (
+	rawLocationTable         = "vehicle_locations"
+	downsampledLocationTable = "vehicle_locations_downsampled"
+	rawPartitionPrefix       = rawLocationTable + "_p"
+	downsampledPartPrefix    = downsampledLocationTable + "_p"
+)
+
+type locationPartition struct {
+	Name string
+	From time.Time
+	To   time.Time
+}
+
+func rawPartitionFor(t time.Time) locationPartition {
+	t = t.UTC()
+	from := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	return locationPartition{
+		Name: rawPartitionPrefix + from.Format("20060102"),
+		From: from,
+		To:   from.AddDate(0, 0, 1),
+	}
+}
+
+func downsampledPartitionFor(t time.Time) locationPartition {
+	t = t.UTC()
+	from := time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return locationPartition{
+		Name: downsampledPartPrefix + from.Format("200601"),
+		From: from,
+		To:   from.AddDate(0, 1, 0),
+	}
+}
+
+// parseLocationPartition recovers the range of a partition from its name.
+// Names that don't follow the scheme (e.g. the default partitions) are
+// reported as not ok.
+func parseLocationPartition(name string) (locationPartition, bool) {
+	if suffix := strings.TrimPrefix(name, downsampledPartPrefix); suffix != name {
+		t, err := time.Parse("200601", suffix)
+		if err != nil {
+			return locationPartition{}, false
+		}
+		return downsampledPartitionFor(t), true
+	}
+	if suffix := strings.TrimPrefix(name, rawPartitionPrefix); suffix != name {
+		t, err := time.Parse("20060102", suffix)
+		if err != nil {
+			return locationPartition{}, false
+		}
+		return rawPartitionFor(t), true
+	}
+	return locationPartition{}, false
+}
+
+// listPartitions returns the named partitions attached to parent, oldest first.
+func (r *LocationRepo) listPartitions(ctx context.Context, parent string) ([]locationPartition, error) {
+	var names []string
+	err := r.db.SelectContext(ctx, &names, `
+		SELECT c.relname
+		FROM pg_inherits i
+		JOIN pg_class c ON c.oid = i.inhrelid
+		JOIN pg_class p ON p.oid = i.inhparent
+		WHERE p.relname = $1
+		ORDER BY c.relname`, parent)
+	if err != nil {
+		return nil, err
+	}
+	var parts []locationPartition
+	for _, n := range names {
+		if p, ok := parseLocationPartition(n); ok {
+			parts = append(parts, p)
+		}
+	}
+	return parts, nil
+}
+
+// ensurePartition creates and attaches p to parent unless it already exists.
+// Rows that landed in the default partition for p's range are moved into the
+// new partition first, otherwise attaching would fail.
+func (r *LocationRepo) ensurePartition(ctx context.Context, tx *sqlx.Tx, parent, key string, p locationPartition) (bool, error) {
+	var exists bool
+	if err := tx.GetContext(ctx, &exists, `SELECT to_regclass($1) IS NOT NULL`, p.Name); err != nil {
+		return false, err
+	}
+	if exists {
+		return false, nil
+	}
+
+	name := pq.QuoteIdentifier(p.Name)
+	def := pq.QuoteIdentifier(parent + "_default")
+	stmts := []string{
+		fmt.Sprintf(`CREATE TABLE %s (LIKE %s INCLUDING DEFAULTS INCLUDING CONSTRAINTS)`, name, parent),
+		fmt.Sprintf(`WITH moved AS (DELETE FROM %s WHERE %s >= $1 AND %s < $2 RETURNING *)
+			INSERT INTO %s SELECT * FROM moved`, def, key, key, name),
+		fmt.Sprintf(`ALTER TABLE %s ATTACH PARTITION %s FOR VALUES FROM (%s) TO (%s)`,
+			parent, name, pq.QuoteLiteral(p.From.Format(time.RFC3339)), pq.QuoteLiteral(p.To.Format(time.RFC3339))),
+	}
+	for i, stmt := range stmts {
+		var err error
+		if i == 1 {
+			_, err = tx.ExecContext(ctx, stmt, p.From, p.To)
+		} else {
+			_, err = tx.ExecContext(ctx, stmt)
+		}
+		if err != nil {
+			return false, fmt.Errorf("create partition %s: %w", p.Name, err)
+		}
+	}
+	return true, nil
+}
+
+// EnsurePartitions creates the daily raw partitions covering [from, to] and
+// returns how many were added.
+func (r *LocationRepo) EnsurePartitions(ctx context.Context, from, to time.Time) (int, error) {
+	created := 0
+	for day := rawPartitionFor(from); !day.From.After(to); day = rawPartitionFor(day.To) {
+		var ok bool
+		err := r.inTx(ctx, func(tx *sqlx.Tx) (err error) {
+			ok, err = r.ensurePartition(ctx, tx, rawLocationTable, "recorded_at", day)
+			return err
+		})
+		if err != nil {
+			return created, err
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// DownsampleBefore moves raw history recorded before the cutoff into the
+// downsampled tier, keeping the first point of every vehicle-minute. Whole
+// daily partitions are downsampled and dropped in one transaction each, so
+// readers never see a range in both tiers or in neither. Returns the number
+// of raw partitions dropped.
+func (r *LocationRepo) DownsampleBefore(ctx context.Context, before time.Time) (int, error) {
+	parts, err := r.listPartitions(ctx, rawLocationTable)
+	if err != nil {
+		return 0, err
+	}
+
+	dropped := 0
+	for _, p := range parts {
+		if p.To.After(before) {
+			break
+		}
+		err := r.inTx(ctx, func(tx *sqlx.Tx) error {
+			if err := r.downsample(ctx, tx, pq.QuoteIdentifier(p.Name), p.From, p.To); err != nil {
+				return err
+			}
+			_, err := tx.ExecContext(ctx, `DROP TABLE `+pq.QuoteIdentifier(p.Name))
+			return err
+		})
+		if err != nil {
+			return dropped, fmt.Errorf("downsample %s: %w", p.Name, err)
+		}
+		dropped++
+	}
+
+	// Points outside every daily partition sit in the default partition;
+	// age them out the same way, just without a drop.
+	err = r.inTx(ctx, func(tx *sqlx.Tx) error {
+		def := pq.QuoteIdentifier(rawLocationTable + "_default")
+		if err := r.downsample(ctx, tx, def, time.Time{}, before); err != nil {
+			return err
+		}
+		_, err := tx.ExecContext(ctx, `DELETE FROM `+def+` WHERE recorded_at < $1`, before)
+		return err
+	})
+	return dropped, err
+}
+
+// downsample copies one point per vehicle-minute from src rows in [from, to)
+// into the downsampled tier, creating the monthly partitions it needs.
+func (r *LocationRepo) downsample(ctx context.Context, tx *sqlx.Tx, src string, from, to time.Time) error {
+	var months []time.Time
+	err := tx.SelectContext(ctx, &months, `
+		SELECT DISTINCT date_trunc('month', recorded_at AT TIME ZONE 'UTC') AT TIME ZONE 'UTC'
+		FROM `+src+`
+		WHERE recorded_at >= $1 AND recorded_at < $2`, from, to)
+	if err != nil {
+		return err
+	}
+	for _, m := range months {
+		if _, err := r.ensurePartition(ctx, tx, downsampledLocationTable, "bucket", downsampledPartitionFor(m)); err != nil {
+			return err
+		}
+	}
+
+	_, err = tx.ExecContext(ctx, `
+		INSERT INTO vehicle_locations_downsampled (vehicle_id, bucket, location, heading, speed, accuracy, recorded_at)
+		SELECT DISTINCT ON (vehicle_id, date_trunc('minute', recorded_at))
+			vehicle_id, date_trunc('minute', recorded_at), location, heading, speed, accuracy, recorded_at
+		FROM `+src+`
+		WHERE recorded_at >= $1 AND recorded_at < $2
+		ORDER BY vehicle_id, date_trunc('minute', recorded_at), recorded_at
+		ON CONFLICT (vehicle_id, bucket) DO NOTHING`, from, to)
+	return err
+}
+
+// DeleteDownsampledBefore enforces the hard retention limit on the
+// downsampled tier by dropping monthly partitions that end before the cutoff.
+// Returns the number of partitions dropped.
+func (r *LocationRepo) DeleteDownsampledBefore(ctx context.Context, before time.Time) (int, error) {
+	parts, err := r.listPartitions(ctx, downsampledLocationTable)
+	if err != nil {
+		return 0, err
+	}
+
+	dropped := 0
+	for _, p := range parts {
+		if p.To.After(before) {
+			break
+		}
+		if _, err := r.db.ExecContext(ctx, `DROP TABLE `+pq.QuoteIdentifier(p.Name)); err != nil {
+			return dropped, fmt.Errorf("drop %s: %w", p.Name, err)
+		}
+		dropped++
+	}
+
+	_, err = r.db.ExecContext(ctx, `
+		DELETE FROM vehicle_locations_downsampled_default WHERE bucket < $1`, before)
+	return dropped, err
+}
+
+func (r *LocationRepo) inTx(ctx context.Context, fn func(tx *sqlx.Tx) error) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if err := fn(tx); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
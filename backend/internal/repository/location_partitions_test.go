@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRawPartitionFor(t *testing.T) {
+	manila := time.FixedZone("PHT", 8*3600)
+	// 02:30 in Manila is still the previous day in UTC.
+	p := rawPartitionFor(time.Date(2026, 3, 1, 2, 30, 0, 0, manila))
+
+	if p.Name != "vehicle_locations_p20260228" {
+		t.Errorf("Name = %q, want vehicle_locations_p20260228", p.Name)
+	}
+	if want := time.Date(2026, 2, 28, 0, 0, 0, 0, time.UTC); !p.From.Equal(want) {
+		t.Errorf("From = %v, want %v", p.From, want)
+	}
+	if want := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC); !p.To.Equal(want) {
+		t.Errorf("To = %v, want %v", p.To, want)
+	}
+}
+
+func TestDownsampledPartitionFor(t *testing.T) {
+	p := downsampledPartitionFor(time.Date(2026, 12, 31, 23, 59, 0, 0, time.UTC))
+
+	if p.Name != "vehicle_locations_downsampled_p202612" {
+		t.Errorf("Name = %q, want vehicle_locations_downsampled_p202612", p.Name)
+	}
+	if want := time.Date(2027, 1, 1, 0, 0, 0, 0, time.UTC); !p.To.Equal(want) {
+		t.Errorf("To = %v, want %v", p.To, want)
+	}
+}
+
+func TestParseLocationPartition(t *testing.T) {
+	tests := []struct {
+		name     string
+		ok       bool
+		wantFrom time.Time
+	}{
+		{"vehicle_locations_p20260115", true, time.Date(2026, 1, 15, 0, 0, 0, 0, time.UTC)},
+		{"vehicle_locations_downsampled_p202601", true, time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)},
+		{"vehicle_locations_default", false, time.Time{}},
+		{"vehicle_locations_downsampled_default", false, time.Time{}},
+		{"vehicle_locations_p2026", false, time.Time{}},
+	}
+	for _, tt := range tests {
+		p, ok := parseLocationPartition(tt.name)
+		if ok != tt.ok {
+			t.Errorf("parseLocationPartition(%q) ok = %v, want %v", tt.name, ok, tt.ok)
+			continue
+		}
+		if ok && (p.Name != tt.name || !p.From.Equal(tt.wantFrom)) {
+			t.Errorf("parseLocationPartition(%q) = %+v, want from %v", tt.name, p, tt.wantFrom)
+		}
+	}
+}
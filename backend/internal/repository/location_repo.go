@@ -55,6 +55,9 @@ func (r *LocationRepo) BatchInsert(ctx context.Context, vehicleID string, points
 	return tx.Commit()
 }
 
+// GetHistory returns the track of a vehicle between from and to. Raw points
+// and the per-minute downsampled tier never overlap, so older ranges simply
+// come back at lower resolution.
 func (r *LocationRepo) GetHistory(ctx context.Context, vehicleID string, from, to time.Time) ([]model.VehicleLocation, error) {
 	var locations []model.VehicleLocation
 	err := r.db.SelectContext(ctx, &locations, `
@@ -64,6 +67,15 @@ func (r *LocationRepo) GetHistory(ctx context.Context, vehicleID string, from, t
 			heading, speed, accuracy, recorded_at
 		FROM vehicle_locations
 		WHERE vehicle_id = $1 AND recorded_at BETWEEN $2 AND $3
+		UNION ALL
+		SELECT id, vehicle_id,
+			ST_Y(location::geometry) AS latitude,
+			ST_X(location::geometry) AS longitude,
+			heading, speed, accuracy, recorded_at
+		FROM vehicle_locations_downsampled
+		WHERE vehicle_id = $1
+			AND bucket BETWEEN date_trunc('minute', $2::timestamptz) AND $3
+			AND recorded_at BETWEEN $2 AND $3
 		ORDER BY recorded_at ASC`, vehicleID, from, to)
 	return locations, err
 }
@@ -0,0 +1,233 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kento/driver/backend/internal/model"
+)
+
+type MaintenanceRepo struct {
+	db *sqlx.DB
+}
+
+func NewMaintenanceRepo(db *sqlx.DB) *MaintenanceRepo {
+	return &MaintenanceRepo{db: db}
+}
+
+const maintenanceColumns = `id, vehicle_id, schedule_id, type, status, start_time, end_time,
+	vendor, notes, odometer_km, created_by, completed_at, created_at, updated_at`
+
+const scheduleColumns = `id, vehicle_id, type, vendor, notes, duration_min, interval_days,
+	interval_km, last_done_at, last_done_km, created_by, created_at, updated_at`
+
+// inMaintenance is true for a vehicle v that a maintenance window blocks
+// now. Work in progress has always started.
+const inMaintenance = `EXISTS (
+	SELECT 1 FROM maintenance_windows mw
+	WHERE mw.vehicle_id = v.id
+		AND (mw.status = 'in_progress'
+			OR (mw.status = 'planned' AND mw.start_time <= NOW() AND mw.end_time > NOW())))`
+
+// maintenanceBlocks is true for a maintenance window mw that blocks its
+// vehicle at some point in [$lo, $hi): planned and overlapping it, or in
+// progress and not over before it starts. Work in progress blocks until it
+// is done, so its end is never earlier than now.
+func maintenanceBlocks(lo, hi string) string {
+	return `(mw.status IN ('planned', 'in_progress')
+		AND mw.start_time < ` + hi + `
+		AND (CASE WHEN mw.status = 'in_progress' THEN GREATEST(mw.end_time, NOW()) ELSE mw.end_time END) > ` + lo + `)`
+}
+
+// inMaintenanceDuring is true for a vehicle v that a maintenance window
+// blocks at some point in [lo, hi).
+func inMaintenanceDuring(lo, hi string) string {
+	return `EXISTS (SELECT 1 FROM maintenance_windows mw WHERE mw.vehicle_id = v.id AND ` + maintenanceBlocks(lo, hi) + `)`
+}
+
+func (r *MaintenanceRepo) Create(ctx context.Context, w *model.MaintenanceWindow) error {
+	return r.db.GetContext(ctx, w, `
+		INSERT INTO maintenance_windows (vehicle_id, type, start_time, end_time, vendor, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+maintenanceColumns,
+		w.VehicleID, w.Type, w.StartTime, w.EndTime, w.Vendor, w.Notes, w.CreatedBy)
+}
+
+// CreateForSchedule plans a window for a schedule that fell due. Returns
+// false, leaving w as is, if the schedule already has one open.
+func (r *MaintenanceRepo) CreateForSchedule(ctx context.Context, w *model.MaintenanceWindow) (bool, error) {
+	err := r.db.GetContext(ctx, w, `
+		INSERT INTO maintenance_windows (vehicle_id, schedule_id, type, start_time, end_time, vendor, notes, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		ON CONFLICT (schedule_id) WHERE schedule_id IS NOT NULL AND status IN ('planned','in_progress') DO NOTHING
+		RETURNING `+maintenanceColumns,
+		w.VehicleID, w.ScheduleID, w.Type, w.StartTime, w.EndTime, w.Vendor, w.Notes, w.CreatedBy)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (r *MaintenanceRepo) GetByID(ctx context.Context, id string) (*model.MaintenanceWindow, error) {
+	var w model.MaintenanceWindow
+	err := r.db.GetContext(ctx, &w, `SELECT `+maintenanceColumns+` FROM maintenance_windows WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &w, err
+}
+
+// List returns windows overlapping [from, to), soonest first. Zero times
+// leave that side open.
+func (r *MaintenanceRepo) List(ctx context.Context, vehicleID, status string, from, to time.Time, limit, offset int) ([]model.MaintenanceWindow, error) {
+	var windows []model.MaintenanceWindow
+	query := `SELECT ` + maintenanceColumns + ` FROM maintenance_windows WHERE 1=1`
+
+	args := []interface{}{}
+	argIdx := 1
+
+	if vehicleID != "" {
+		query += ` AND vehicle_id = $` + itoa(argIdx)
+		args = append(args, vehicleID)
+		argIdx++
+	}
+	if status != "" {
+		query += ` AND status = $` + itoa(argIdx)
+		args = append(args, status)
+		argIdx++
+	}
+	if !from.IsZero() {
+		query += ` AND end_time > $` + itoa(argIdx)
+		args = append(args, from)
+		argIdx++
+	}
+	if !to.IsZero() {
+		query += ` AND start_time < $` + itoa(argIdx)
+		args = append(args, to)
+		argIdx++
+	}
+
+	query += ` ORDER BY start_time LIMIT $` + itoa(argIdx) + ` OFFSET $` + itoa(argIdx+1)
+	args = append(args, limit, offset)
+
+	err := r.db.SelectContext(ctx, &windows, query, args...)
+	return windows, err
+}
+
+// FindBlocking returns the windows that keep vehicleID out of service at
+// some point in [startTime, endTime).
+func (r *MaintenanceRepo) FindBlocking(ctx context.Context, vehicleID string, startTime, endTime time.Time) ([]model.MaintenanceWindow, error) {
+	var windows []model.MaintenanceWindow
+	err := r.db.SelectContext(ctx, &windows, `
+		SELECT `+maintenanceColumns+`
+		FROM maintenance_windows mw
+		WHERE mw.vehicle_id = $1 AND `+maintenanceBlocks("$2", "$3")+`
+		ORDER BY mw.start_time`, vehicleID, startTime, endTime)
+	return windows, err
+}
+
+// ListOverrunning returns the windows whose work is still in progress past
+// their planned end.
+func (r *MaintenanceRepo) ListOverrunning(ctx context.Context) ([]model.MaintenanceWindow, error) {
+	var windows []model.MaintenanceWindow
+	err := r.db.SelectContext(ctx, &windows, `
+		SELECT `+maintenanceColumns+`
+		FROM maintenance_windows
+		WHERE status = 'in_progress' AND end_time < NOW()
+		ORDER BY end_time`)
+	return windows, err
+}
+
+// Update saves the editable fields of a window that is still open. Returns
+// false if it was done or cancelled meanwhile.
+func (r *MaintenanceRepo) Update(ctx context.Context, w *model.MaintenanceWindow) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `
+		UPDATE maintenance_windows
+		SET type = $1, start_time = $2, end_time = $3, vendor = $4, notes = $5, updated_at = NOW()
+		WHERE id = $6 AND status IN ('planned', 'in_progress')`,
+		w.Type, w.StartTime, w.EndTime, w.Vendor, w.Notes, w.ID))
+}
+
+// Start marks planned work as under way. Work started early moves the start
+// of the window to now.
+func (r *MaintenanceRepo) Start(ctx context.Context, id string) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `
+		UPDATE maintenance_windows
+		SET status = 'in_progress', start_time = LEAST(start_time, NOW()), updated_at = NOW()
+		WHERE id = $1 AND status = 'planned'`, id))
+}
+
+// Complete marks a window that has begun done, ending it now.
+func (r *MaintenanceRepo) Complete(ctx context.Context, id string, odometerKm *int) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `
+		UPDATE maintenance_windows
+		SET status = 'done', end_time = GREATEST(NOW(), start_time + INTERVAL '1 minute'),
+			odometer_km = $2, completed_at = NOW(), updated_at = NOW()
+		WHERE id = $1 AND status IN ('planned', 'in_progress') AND start_time <= NOW()`, id, odometerKm))
+}
+
+// Cancel drops a window whose work has not started.
+func (r *MaintenanceRepo) Cancel(ctx context.Context, id string) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `
+		UPDATE maintenance_windows SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND status = 'planned'`, id))
+}
+
+func (r *MaintenanceRepo) CreateSchedule(ctx context.Context, s *model.MaintenanceSchedule) error {
+	return r.db.GetContext(ctx, s, `
+		INSERT INTO maintenance_schedules (vehicle_id, type, vendor, notes, duration_min, interval_days,
+			interval_km, last_done_at, last_done_km, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+scheduleColumns,
+		s.VehicleID, s.Type, s.Vendor, s.Notes, s.DurationMin, s.IntervalDays,
+		s.IntervalKm, s.LastDoneAt, s.LastDoneKm, s.CreatedBy)
+}
+
+func (r *MaintenanceRepo) GetSchedule(ctx context.Context, id string) (*model.MaintenanceSchedule, error) {
+	var s model.MaintenanceSchedule
+	err := r.db.GetContext(ctx, &s, `SELECT `+scheduleColumns+` FROM maintenance_schedules WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &s, err
+}
+
+func (r *MaintenanceRepo) ListSchedules(ctx context.Context, vehicleID string) ([]model.MaintenanceSchedule, error) {
+	var schedules []model.MaintenanceSchedule
+	err := r.db.SelectContext(ctx, &schedules, `
+		SELECT `+scheduleColumns+` FROM maintenance_schedules
+		WHERE vehicle_id = $1 ORDER BY created_at`, vehicleID)
+	return schedules, err
+}
+
+func (r *MaintenanceRepo) DeleteSchedule(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `DELETE FROM maintenance_schedules WHERE id = $1`, id)
+	return err
+}
+
+// ListUnplannedSchedules returns the schedules with no window planned or in
+// progress, the ones that may need planning.
+func (r *MaintenanceRepo) ListUnplannedSchedules(ctx context.Context) ([]model.MaintenanceSchedule, error) {
+	var schedules []model.MaintenanceSchedule
+	err := r.db.SelectContext(ctx, &schedules, `
+		SELECT `+scheduleColumns+` FROM maintenance_schedules s
+		WHERE NOT EXISTS (
+			SELECT 1 FROM maintenance_windows mw
+			WHERE mw.schedule_id = s.id AND mw.status IN ('planned', 'in_progress')
+		)`)
+	return schedules, err
+}
+
+// MarkScheduleDone restarts a schedule's intervals from completed work. An
+// unknown odometer reading keeps the previous one.
+func (r *MaintenanceRepo) MarkScheduleDone(ctx context.Context, id string, doneAt time.Time, odometerKm *int) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE maintenance_schedules
+		SET last_done_at = $2, last_done_km = COALESCE($3, last_done_km), updated_at = NOW()
+		WHERE id = $1`, id, doneAt, odometerKm)
+	return err
+}
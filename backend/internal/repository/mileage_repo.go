@@ -0,0 +1,130 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kento/driver/backend/internal/model"
+)
+
+type MileageRepo struct {
+	db *sqlx.DB
+}
+
+func NewMileageRepo(db *sqlx.DB) *MileageRepo {
+	return &MileageRepo{db: db}
+}
+
+const fuelLogColumns = `id, vehicle_id, driver_id, litres, cost, station, odometer_km,
+	receipt_url, fueled_at, created_at`
+
+// GPS points less accurate than gpsMaxAccuracyM, and hops implying more than
+// gpsMaxSpeedMS (about 250 km/h), are fixes gone astray rather than driving.
+const (
+	gpsMaxAccuracyM = 100
+	gpsMaxSpeedMS   = 70
+)
+
+func (r *MileageRepo) CreateFuelLog(ctx context.Context, f *model.FuelLog) error {
+	return r.db.GetContext(ctx, f, `
+		INSERT INTO fuel_logs (vehicle_id, driver_id, litres, cost, station, odometer_km, fueled_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+fuelLogColumns,
+		f.VehicleID, f.DriverID, f.Litres, f.Cost, f.Station, f.OdometerKm, f.FueledAt)
+}
+
+func (r *MileageRepo) GetFuelLog(ctx context.Context, id string) (*model.FuelLog, error) {
+	var f model.FuelLog
+	err := r.db.GetContext(ctx, &f, `SELECT `+fuelLogColumns+` FROM fuel_logs WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &f, err
+}
+
+func (r *MileageRepo) SetFuelReceipt(ctx context.Context, id string, receiptURL *string) error {
+	_, err := r.db.ExecContext(ctx, `UPDATE fuel_logs SET receipt_url = $1 WHERE id = $2`, receiptURL, id)
+	return err
+}
+
+// ListFuelLogs returns a vehicle's refuelling in [from, to), newest first.
+func (r *MileageRepo) ListFuelLogs(ctx context.Context, vehicleID string, from, to time.Time) ([]model.FuelLog, error) {
+	var logs []model.FuelLog
+	err := r.db.SelectContext(ctx, &logs, `
+		SELECT `+fuelLogColumns+` FROM fuel_logs
+		WHERE vehicle_id = $1 AND fueled_at >= $2 AND fueled_at < $3
+		ORDER BY fueled_at DESC`, vehicleID, from, to)
+	return logs, err
+}
+
+// DailyFuel totals a vehicle's refuelling per UTC day in [from, to).
+func (r *MileageRepo) DailyFuel(ctx context.Context, vehicleID string, from, to time.Time) ([]model.DailyFuel, error) {
+	var days []model.DailyFuel
+	err := r.db.SelectContext(ctx, &days, `
+		SELECT (fueled_at AT TIME ZONE 'UTC')::date AS day, SUM(litres) AS litres, SUM(cost) AS cost
+		FROM fuel_logs
+		WHERE vehicle_id = $1 AND fueled_at >= $2 AND fueled_at < $3
+		GROUP BY day
+		ORDER BY day`, vehicleID, from, to)
+	return days, err
+}
+
+// ShiftDistances returns, for each shift driven on a vehicle with clock-in
+// in [from, to), the distance shown on the odometer and the GPS distance
+// tracked between clock-in and clock-out. A shift is dated by its UTC day of
+// clock-in. Shifts missing either reading are left out.
+func (r *MileageRepo) ShiftDistances(ctx context.Context, vehicleID string, from, to time.Time) ([]model.ShiftDistance, error) {
+	var shifts []model.ShiftDistance
+	err := r.db.SelectContext(ctx, &shifts, `
+		SELECT (a.clock_in_at AT TIME ZONE 'UTC')::date AS day,
+			(a.clock_out_odometer_km - a.clock_in_odometer_km)::float8 AS odometer_km,
+			COALESCE((SELECT SUM(m) FROM (`+gpsHops("a.vehicle_id", "a.clock_in_at", "a.clock_out_at")+`) hops), 0) / 1000 AS gps_km
+		FROM driver_attendance a
+		WHERE a.vehicle_id = $1 AND a.clock_in_at >= $2 AND a.clock_in_at < $3
+			AND a.clock_out_at IS NOT NULL
+			AND a.clock_in_odometer_km IS NOT NULL AND a.clock_out_odometer_km IS NOT NULL
+		ORDER BY a.clock_in_at`, vehicleID, from, to)
+	return shifts, err
+}
+
+// DailyGPSDistance sums the distance between consecutive location fixes of
+// a vehicle per UTC day in [from, to), raw and downsampled points alike. A
+// hop counts on the day it ends.
+func (r *MileageRepo) DailyGPSDistance(ctx context.Context, vehicleID string, from, to time.Time) ([]model.DailyDistance, error) {
+	var days []model.DailyDistance
+	err := r.db.SelectContext(ctx, &days, `
+		SELECT (recorded_at AT TIME ZONE 'UTC')::date AS day, SUM(m) / 1000 AS km
+		FROM (`+gpsHops("$1", "$2", "$3")+`) hops
+		GROUP BY day
+		ORDER BY day`, vehicleID, from, to)
+	return days, err
+}
+
+// gpsHops selects the hops between consecutive location fixes of vehicle in
+// [lo, hi), raw and downsampled points alike, as the metres m covered by the
+// hop ending at recorded_at. Stray fixes and impossible jumps are dropped.
+func gpsHops(vehicle, lo, hi string) string {
+	return `SELECT recorded_at, m
+		FROM (
+			SELECT recorded_at,
+				ST_Distance(location, LAG(location) OVER w) AS m,
+				EXTRACT(EPOCH FROM recorded_at - LAG(recorded_at) OVER w) AS sec
+			FROM (
+				SELECT location, recorded_at FROM vehicle_locations
+				WHERE vehicle_id = ` + vehicle + ` AND recorded_at >= ` + lo + ` AND recorded_at < ` + hi + `
+					AND (accuracy IS NULL OR accuracy <= ` + itoa(gpsMaxAccuracyM) + `)
+				UNION ALL
+				SELECT location, recorded_at FROM vehicle_locations_downsampled
+				WHERE vehicle_id = ` + vehicle + `
+					AND bucket >= date_trunc('minute', ` + lo + `::timestamptz) AND bucket < ` + hi + `
+					AND recorded_at >= ` + lo + ` AND recorded_at < ` + hi + `
+					AND (accuracy IS NULL OR accuracy <= ` + itoa(gpsMaxAccuracyM) + `)
+			) p
+			WINDOW w AS (ORDER BY recorded_at)
+		) h
+		WHERE m IS NOT NULL AND m <= GREATEST(sec, 1) * ` + itoa(gpsMaxSpeedMS)
+}
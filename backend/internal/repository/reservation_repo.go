@@ -21,26 +21,52 @@ func NewReservationRepo(db *sqlx.DB) *ReservationRepo {
 	return &ReservationRepo{db: db}
 }
 
+// ErrReservationOverlap is returned when a write would leave two confirmed
+// or pending_driver reservations holding the same vehicle at the same time.
+var ErrReservationOverlap = errors.New("reservation overlaps another on the same vehicle")
+
+// ErrOccurrenceExists is returned by Create when the series occurrence was
+// already materialized, e.g. by a concurrent run.
+var ErrOccurrenceExists = errors.New("series occurrence already exists")
+
+// overlapErr translates violations of the reservations_no_overlap exclusion
+// constraint and the series occurrence index into their sentinel errors.
+func overlapErr(err error) error {
+	var pqErr *pq.Error
+	if !errors.As(err, &pqErr) {
+		return err
+	}
+	switch {
+	case pqErr.Code == "23P01" && pqErr.Constraint == "reservations_no_overlap":
+		return ErrReservationOverlap
+	case pqErr.Code == "23505" && pqErr.Constraint == "idx_reservations_series_occurrence":
+		return ErrOccurrenceExists
+	}
+	return err
+}
+
 const reservationColumns = `id, vehicle_id, requester_id, start_time, end_time, purpose, destinations, notes,
 	passenger_name, pickup_address,
 	ST_Y(pickup_location::geometry) AS pickup_lat, ST_X(pickup_location::geometry) AS pickup_lng,
 	priority_level, status, cancel_reason, cancelled_by, declined_by_driver_ids,
+	series_id, occurrence_start, is_exception,
 	created_at, updated_at`
 
 func (r *ReservationRepo) Create(ctx context.Context, res *model.Reservation) error {
-	return r.db.GetContext(ctx, res, `
+	return overlapErr(r.db.GetContext(ctx, res, `
 		INSERT INTO reservations (vehicle_id, requester_id, start_time, end_time, purpose, destinations, notes,
-			passenger_name, pickup_address, pickup_location, priority_level, status)
+			passenger_name, pickup_address, pickup_location, priority_level, status,
+			series_id, occurrence_start)
 		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9,
 			CASE WHEN $10::float8 IS NOT NULL AND $11::float8 IS NOT NULL
 				THEN ST_SetSRID(ST_MakePoint($11, $10), 4326)::geography
 				ELSE NULL END,
-			$12, $13)
+			$12, $13, $14, $15)
 		RETURNING `+reservationColumns,
 		res.VehicleID, res.RequesterID, res.StartTime, res.EndTime,
 		res.Purpose, pq.Array(res.Destinations), res.Notes,
 		res.PassengerName, res.PickupAddress, res.PickupLat, res.PickupLng,
-		res.PriorityLevel, res.Status)
+		res.PriorityLevel, res.Status, res.SeriesID, res.OccurrenceStart))
 }
 
 func (r *ReservationRepo) GetByID(ctx context.Context, id string) (*model.Reservation, error) {
@@ -54,13 +80,16 @@ func (r *ReservationRepo) GetByID(ctx context.Context, id string) (*model.Reserv
 	return &res, err
 }
 
-func (r *ReservationRepo) List(ctx context.Context, vehicleID string, from, to time.Time, status string, limit, offset int) ([]model.ReservationWithDetails, error) {
+// List returns reservations by start time. Only those of vehicles within the
+// depot scope depotIDs, nil for all, are returned.
+func (r *ReservationRepo) List(ctx context.Context, vehicleID string, from, to time.Time, status string, depotIDs []string, limit, offset int) ([]model.ReservationWithDetails, error) {
 	var reservations []model.ReservationWithDetails
 	query := `
 		SELECT r.id, r.vehicle_id, r.requester_id, r.start_time, r.end_time, r.purpose,
 			r.destinations, r.notes, r.passenger_name, r.pickup_address,
 			ST_Y(r.pickup_location::geometry) AS pickup_lat, ST_X(r.pickup_location::geometry) AS pickup_lng,
 			r.priority_level, r.status, r.cancel_reason, r.cancelled_by, r.declined_by_driver_ids,
+			r.series_id, r.occurrence_start, r.is_exception,
 			r.created_at, r.updated_at,
 			v.name AS vehicle_name, u.name AS requester_name
 		FROM reservations r
@@ -91,6 +120,11 @@ func (r *ReservationRepo) List(ctx context.Context, vehicleID string, from, to t
 		args = append(args, status)
 		argIdx++
 	}
+	if depotIDs != nil {
+		query += ` AND ` + inDepots("v.depot_id", "$"+itoa(argIdx))
+		args = append(args, pq.Array(depotIDs))
+		argIdx++
+	}
 
 	query += ` ORDER BY r.start_time ASC LIMIT $` + itoa(argIdx) + ` OFFSET $` + itoa(argIdx+1)
 	args = append(args, limit, offset)
@@ -120,10 +154,34 @@ func (r *ReservationRepo) FindOverlapping(ctx context.Context, vehicleID string,
 	return reservations, err
 }
 
+// FindNeighbours returns the reservations holding vehicleID that do not
+// overlap [startTime, endTime) but end or start within `within` of it.
+func (r *ReservationRepo) FindNeighbours(ctx context.Context, vehicleID string, startTime, endTime time.Time, within time.Duration, excludeID string) ([]model.Reservation, error) {
+	var reservations []model.Reservation
+	query := `
+		SELECT ` + reservationColumns + `
+		FROM reservations
+		WHERE vehicle_id = $1
+			AND status IN ('confirmed', 'pending_driver')
+			AND ((end_time <= $2 AND end_time > $2 - make_interval(secs => $4))
+				OR (start_time >= $3 AND start_time < $3 + make_interval(secs => $4)))`
+
+	args := []interface{}{vehicleID, startTime, endTime, within.Seconds()}
+
+	if excludeID != "" {
+		query += ` AND id != $5`
+		args = append(args, excludeID)
+	}
+	query += ` ORDER BY start_time`
+
+	err := r.db.SelectContext(ctx, &reservations, query, args...)
+	return reservations, err
+}
+
 func (r *ReservationRepo) UpdateStatus(ctx context.Context, id string, status model.ReservationStatus) error {
 	_, err := r.db.ExecContext(ctx,
 		`UPDATE reservations SET status = $1, updated_at = NOW() WHERE id = $2`, status, id)
-	return err
+	return overlapErr(err)
 }
 
 func (r *ReservationRepo) Cancel(ctx context.Context, id, cancelledBy, reason string) error {
@@ -138,50 +196,136 @@ func (r *ReservationRepo) Update(ctx context.Context, res *model.Reservation) er
 	_, err := r.db.ExecContext(ctx, `
 		UPDATE reservations
 		SET vehicle_id = $1, start_time = $2, end_time = $3, purpose = $4,
-			destinations = $5, notes = $6, updated_at = NOW()
-		WHERE id = $7`,
+			destinations = $5, notes = $6, is_exception = $7, updated_at = NOW()
+		WHERE id = $8`,
 		res.VehicleID, res.StartTime, res.EndTime, res.Purpose,
-		pq.Array(res.Destinations), res.Notes, res.ID)
-	return err
+		pq.Array(res.Destinations), res.Notes, res.IsException, res.ID)
+	return overlapErr(err)
+}
+
+// UpdateWithStatus saves res's changes and sets its status in one statement,
+// so the reservations_no_overlap constraint checks the new slot and status
+// together and a failure changes neither.
+func (r *ReservationRepo) UpdateWithStatus(ctx context.Context, res *model.Reservation, status model.ReservationStatus) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE reservations
+		SET vehicle_id = $1, start_time = $2, end_time = $3, purpose = $4,
+			destinations = $5, notes = $6, is_exception = $7, status = $8, updated_at = NOW()
+		WHERE id = $9`,
+		res.VehicleID, res.StartTime, res.EndTime, res.Purpose,
+		pq.Array(res.Destinations), res.Notes, res.IsException, status, res.ID)
+	return overlapErr(err)
 }
 
 func (r *ReservationRepo) UpdateVehicle(ctx context.Context, id, vehicleID string) error {
 	_, err := r.db.ExecContext(ctx, `
 		UPDATE reservations SET vehicle_id = $1, status = 'pending_driver', updated_at = NOW()
 		WHERE id = $2`, vehicleID, id)
-	return err
+	return overlapErr(err)
 }
 
-func (r *ReservationRepo) GetUpcomingReminders(ctx context.Context, minutesBefore int) ([]model.ReservationWithDetails, error) {
-	var reservations []model.ReservationWithDetails
-	err := r.db.SelectContext(ctx, &reservations, `
-		SELECT r.id, r.vehicle_id, r.requester_id, r.start_time, r.end_time, r.purpose,
-			r.destinations, r.notes, r.passenger_name, r.pickup_address,
-			ST_Y(r.pickup_location::geometry) AS pickup_lat, ST_X(r.pickup_location::geometry) AS pickup_lng,
-			r.priority_level, r.status, r.cancel_reason, r.cancelled_by, r.declined_by_driver_ids,
-			r.created_at, r.updated_at,
-			v.name AS vehicle_name, u.name AS requester_name
+// ListDueReminders returns reminders owed for confirmed reservations starting
+// within the next leadMinutes that have not been sent for the current start
+// time. Using the whole lead window (not a one-minute slice) means a late or
+// skipped job run still catches up.
+func (r *ReservationRepo) ListDueReminders(ctx context.Context, leadMinutes int) ([]model.DueReminder, error) {
+	var reminders []model.DueReminder
+	err := r.db.SelectContext(ctx, &reminders, `
+		SELECT r.id AS reservation_id, rc.recipient_id, rc.recipient_role,
+			r.start_time, r.purpose, v.name AS vehicle_name
 		FROM reservations r
 		JOIN vehicles v ON v.id = r.vehicle_id
-		JOIN users u ON u.id = r.requester_id
+		CROSS JOIN LATERAL (
+			VALUES (r.requester_id, 'requester'), (`+vehicleHolder+`, 'driver')
+		) AS rc(recipient_id, recipient_role)
 		WHERE r.status = 'confirmed'
-			AND r.start_time BETWEEN NOW() + ($1 - 1) * INTERVAL '1 minute'
-			AND NOW() + $1 * INTERVAL '1 minute'`, minutesBefore)
-	return reservations, err
+			AND r.start_time > NOW()
+			AND r.start_time <= NOW() + $1 * INTERVAL '1 minute'
+			AND rc.recipient_id IS NOT NULL
+			AND NOT EXISTS (
+				SELECT 1 FROM reservation_reminders rr
+				WHERE rr.reservation_id = r.id
+					AND rr.recipient_id = rc.recipient_id
+					AND rr.start_time = r.start_time
+			)
+		ORDER BY r.start_time`, leadMinutes)
+	return reminders, err
+}
+
+// ClaimReminder records a reminder as sent. It returns false when another
+// run or replica already claimed it.
+func (r *ReservationRepo) ClaimReminder(ctx context.Context, reservationID, recipientID string, startTime time.Time) (bool, error) {
+	var id string
+	err := r.db.GetContext(ctx, &id, `
+		INSERT INTO reservation_reminders (reservation_id, recipient_id, start_time)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (reservation_id, recipient_id, start_time) DO NOTHING
+		RETURNING id`, reservationID, recipientID, startTime)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// ReleaseReminder drops a claim whose delivery failed so the next run retries.
+func (r *ReservationRepo) ReleaseReminder(ctx context.Context, reservationID, recipientID string, startTime time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		DELETE FROM reservation_reminders
+		WHERE reservation_id = $1 AND recipient_id = $2 AND start_time = $3`,
+		reservationID, recipientID, startTime)
+	return err
 }
 
 func (r *ReservationRepo) AutoCompleteExpired(ctx context.Context) (int64, error) {
 	result, err := r.db.ExecContext(ctx, `
 		UPDATE reservations
 		SET status = 'completed', updated_at = NOW()
-		WHERE status = 'confirmed' AND end_time < NOW()`)
+		WHERE status = 'confirmed' AND end_time < NOW()
+			AND NOT EXISTS (
+				SELECT 1 FROM dispatches d
+				WHERE d.reservation_id = reservations.id
+					AND d.status IN ('pending','assigned','accepted','en_route','arrived')
+			)`)
 	if err != nil {
 		return 0, err
 	}
 	return result.RowsAffected()
 }
 
-// FindPendingByDriverID returns pending_driver reservations for vehicles assigned to a driver.
+// Complete marks a confirmed reservation completed, as when its trip ends.
+// It reports whether the reservation was still confirmed.
+func (r *ReservationRepo) Complete(ctx context.Context, id string) (bool, error) {
+	return affected(r.db.ExecContext(ctx, `
+		UPDATE reservations SET status = 'completed', updated_at = NOW()
+		WHERE id = $1 AND status = 'confirmed'`, id))
+}
+
+// ListDueForTrip returns confirmed reservations starting within lead that
+// have no trip yet, or only one cancelled when the reservation was moved,
+// leaving out those whose vehicle is still on another trip; they come up
+// again on a later run.
+func (r *ReservationRepo) ListDueForTrip(ctx context.Context, lead time.Duration) ([]model.Reservation, error) {
+	var reservations []model.Reservation
+	err := r.db.SelectContext(ctx, &reservations, `
+		SELECT `+reservationColumns+`
+		FROM reservations
+		WHERE status = 'confirmed'
+			AND start_time <= NOW() + make_interval(secs => $1)
+			AND end_time > NOW()
+			AND NOT EXISTS (
+				SELECT 1 FROM dispatches d
+				WHERE d.reservation_id = reservations.id AND d.status <> 'cancelled'
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM dispatches d
+				WHERE d.vehicle_id = reservations.vehicle_id
+					AND d.status IN ('assigned','accepted','en_route','arrived')
+			)
+		ORDER BY start_time`, lead.Seconds())
+	return reservations, err
+}
+
+// FindPendingByDriverID returns pending_driver reservations for the vehicle the driver holds on their shift.
 func (r *ReservationRepo) FindPendingByDriverID(ctx context.Context, driverID string) ([]model.ReservationWithDetails, error) {
 	var reservations []model.ReservationWithDetails
 	err := r.db.SelectContext(ctx, &reservations, `
@@ -189,30 +333,77 @@ func (r *ReservationRepo) FindPendingByDriverID(ctx context.Context, driverID st
 			r.destinations, r.notes, r.passenger_name, r.pickup_address,
 			ST_Y(r.pickup_location::geometry) AS pickup_lat, ST_X(r.pickup_location::geometry) AS pickup_lng,
 			r.priority_level, r.status, r.cancel_reason, r.cancelled_by, r.declined_by_driver_ids,
+			r.series_id, r.occurrence_start, r.is_exception,
 			r.created_at, r.updated_at,
 			v.name AS vehicle_name, u.name AS requester_name
 		FROM reservations r
 		JOIN vehicles v ON v.id = r.vehicle_id
 		JOIN users u ON u.id = r.requester_id
-		WHERE v.driver_id = $1
+		WHERE `+vehicleHolder+` = $1
 			AND r.status = 'pending_driver'
 		ORDER BY r.start_time ASC`, driverID)
 	return reservations, err
 }
 
 // FindAvailableVehicleForSlot returns vehicle IDs available during a time slot, excluding given IDs.
-func (r *ReservationRepo) FindAvailableVehicleForSlot(ctx context.Context, startTime, endTime time.Time, excludeVehicleIDs []string) ([]string, error) {
+// A vehicle's bookings must leave its turnaround free on either side of the slot; travel
+// time is not checked. Vehicles whose compliance documents expire before the slot ends are
+// not available. Only vehicles within the depot scope depotIDs, nil for all, are considered.
+func (r *ReservationRepo) FindAvailableVehicleForSlot(ctx context.Context, startTime, endTime time.Time, excludeVehicleIDs, depotIDs []string) ([]string, error) {
 	var vehicleIDs []string
 	err := r.db.SelectContext(ctx, &vehicleIDs, `
 		SELECT v.id
 		FROM vehicles v
-		JOIN users u ON u.id = v.driver_id
-		WHERE v.is_maintenance = false
-			AND v.id != ALL($3::uuid[])
+		WHERE NOT `+inMaintenanceDuring("$1", "$2")+`
+			AND NOT `+complianceExpiredBy(`(($2::timestamptz - interval '1 second') AT TIME ZONE 'UTC')::date`)+`
+			AND v.id != ALL(COALESCE($3::uuid[], '{}'))
+			AND `+inDepots("v.depot_id", "$4")+`
 			AND NOT EXISTS (
 				SELECT 1 FROM reservations res
 				WHERE res.vehicle_id = v.id
 					AND res.status IN ('confirmed', 'pending_driver')
+					AND res.start_time < $2 + make_interval(mins => v.turnaround_min)
+					AND res.end_time > $1 - make_interval(mins => v.turnaround_min)
+			)
+			AND NOT EXISTS (
+				SELECT 1 FROM dispatches d
+				WHERE d.vehicle_id = v.id
+					AND d.status IN ('assigned','accepted','en_route','arrived')
+			)
+		ORDER BY v.name`, startTime, endTime, pq.Array(excludeVehicleIDs), pq.Array(depotIDs))
+	return vehicleIDs, err
+}
+
+// ListFreeVehicles returns the vehicles not in maintenance during the slot, other than
+// excludeVehicleID, with no active reservation during [startTime, endTime).
+// Bookings ending or starting more than `within` away from the slot are not
+// reported as its neighbours. As in FindAvailableVehicleForSlot, vehicles on an
+// active dispatch or whose compliance documents expire before the slot ends are
+// left out, and only vehicles within the depot scope depotIDs, nil for all, are
+// listed.
+func (r *ReservationRepo) ListFreeVehicles(ctx context.Context, startTime, endTime time.Time, within time.Duration, excludeVehicleID string, depotIDs []string) ([]model.SlotCandidate, error) {
+	var candidates []model.SlotCandidate
+	err := r.db.SelectContext(ctx, &candidates, `
+		SELECT v.id AS vehicle_id, v.name AS vehicle_name, v.license_plate, v.turnaround_min,
+			(SELECT MAX(res.end_time) FROM reservations res
+			 WHERE res.vehicle_id = v.id
+				AND res.status IN ('confirmed', 'pending_conflict', 'pending_driver')
+				AND res.end_time <= $1
+				AND res.end_time > $1 - make_interval(secs => $3)) AS prev_end,
+			(SELECT MIN(res.start_time) FROM reservations res
+			 WHERE res.vehicle_id = v.id
+				AND res.status IN ('confirmed', 'pending_conflict', 'pending_driver')
+				AND res.start_time >= $2
+				AND res.start_time < $2 + make_interval(secs => $3)) AS next_start
+		FROM vehicles v
+		WHERE NOT `+inMaintenanceDuring("$1", "$2")+`
+			AND NOT `+complianceExpiredBy(`(($2::timestamptz - interval '1 second') AT TIME ZONE 'UTC')::date`)+`
+			AND v.id != $4
+			AND `+inDepots("v.depot_id", "$5")+`
+			AND NOT EXISTS (
+				SELECT 1 FROM reservations res
+				WHERE res.vehicle_id = v.id
+					AND res.status IN ('confirmed', 'pending_conflict', 'pending_driver')
 					AND res.start_time < $2
 					AND res.end_time > $1
 			)
@@ -221,8 +412,8 @@ func (r *ReservationRepo) FindAvailableVehicleForSlot(ctx context.Context, start
 				WHERE d.vehicle_id = v.id
 					AND d.status IN ('assigned','accepted','en_route','arrived')
 			)
-		ORDER BY v.name`, startTime, endTime, pq.Array(excludeVehicleIDs))
-	return vehicleIDs, err
+		ORDER BY v.name`, startTime, endTime, within.Seconds(), excludeVehicleID, pq.Array(depotIDs))
+	return candidates, err
 }
 
 // AddDeclinedDriver appends a driver_id to the declined_by_driver_ids array.
@@ -245,6 +436,7 @@ func (r *ReservationRepo) GetDayReservations(ctx context.Context, vehicleID stri
 			r.destinations, r.notes, r.passenger_name, r.pickup_address,
 			ST_Y(r.pickup_location::geometry) AS pickup_lat, ST_X(r.pickup_location::geometry) AS pickup_lng,
 			r.priority_level, r.status, r.cancel_reason, r.cancelled_by, r.declined_by_driver_ids,
+			r.series_id, r.occurrence_start, r.is_exception,
 			r.created_at, r.updated_at,
 			v.name AS vehicle_name, u.name AS requester_name
 		FROM reservations r
@@ -258,6 +450,40 @@ func (r *ReservationRepo) GetDayReservations(ctx context.Context, vehicleID stri
 	return reservations, err
 }
 
+// ListFeed returns the reservations of a vehicle, of a requester or of a
+// driver (the other IDs left empty) that overlap [from, to), in every
+// status, for a calendar feed. A driver's are those whose trip was assigned
+// to the vehicle they held on a shift at the time. Cancelled ones are
+// included so subscribed calendars drop them.
+func (r *ReservationRepo) ListFeed(ctx context.Context, vehicleID, requesterID, driverID string, from, to time.Time) ([]model.ReservationWithDetails, error) {
+	var reservations []model.ReservationWithDetails
+	err := r.db.SelectContext(ctx, &reservations, `
+		SELECT r.id, r.vehicle_id, r.requester_id, r.start_time, r.end_time, r.purpose,
+			r.destinations, r.notes, r.passenger_name, r.pickup_address,
+			ST_Y(r.pickup_location::geometry) AS pickup_lat, ST_X(r.pickup_location::geometry) AS pickup_lng,
+			r.priority_level, r.status, r.cancel_reason, r.cancelled_by, r.declined_by_driver_ids,
+			r.series_id, r.occurrence_start, r.is_exception,
+			r.created_at, r.updated_at,
+			v.name AS vehicle_name, u.name AS requester_name
+		FROM reservations r
+		JOIN vehicles v ON v.id = r.vehicle_id
+		JOIN users u ON u.id = r.requester_id
+		WHERE ($1 = '' OR r.vehicle_id::text = $1)
+			AND ($2 = '' OR r.requester_id::text = $2)
+			AND ($3 = '' OR EXISTS (
+				SELECT 1 FROM dispatches d
+				JOIN driver_attendance a ON a.vehicle_id = d.vehicle_id
+				WHERE d.reservation_id = r.id
+					AND a.driver_id::text = $3
+					AND d.assigned_at >= a.clock_in_at
+					AND (a.clock_out_at IS NULL OR d.assigned_at < a.clock_out_at)))
+			AND r.start_time < $5
+			AND r.end_time > $4
+		ORDER BY r.start_time ASC
+		LIMIT 2000`, vehicleID, requesterID, driverID, from, to)
+	return reservations, err
+}
+
 // Helper for building parameterized queries
 func itoa(i int) string {
 	return strconv.Itoa(i)
@@ -0,0 +1,321 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/lib/pq"
+
+	"github.com/kento/driver/backend/internal/db"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/testdb"
+)
+
+func TestOverlapErr(t *testing.T) {
+	violation := &pq.Error{Code: "23P01", Constraint: "reservations_no_overlap"}
+	if err := overlapErr(violation); !errors.Is(err, ErrReservationOverlap) {
+		t.Errorf("overlapErr(exclusion violation) = %v, want ErrReservationOverlap", err)
+	}
+
+	dup := &pq.Error{Code: "23505", Constraint: "idx_reservations_series_occurrence"}
+	if err := overlapErr(dup); !errors.Is(err, ErrOccurrenceExists) {
+		t.Errorf("overlapErr(duplicate occurrence) = %v, want ErrOccurrenceExists", err)
+	}
+
+	other := &pq.Error{Code: "23505", Constraint: "vehicles_license_plate_key"}
+	if err := overlapErr(other); err != other {
+		t.Errorf("overlapErr(unique violation) = %v, want it unchanged", err)
+	}
+	if err := overlapErr(nil); err != nil {
+		t.Errorf("overlapErr(nil) = %v, want nil", err)
+	}
+}
+
+// TestReservationCreate_ConcurrentSameSlot books one slot from many
+// goroutines at once. Exactly one may hold it. Needs a migrated Postgres in
+// TEST_DATABASE_URL.
+func TestReservationCreate_ConcurrentSameSlot(t *testing.T) {
+	conn := testdb.Open(t)
+	ctx := context.Background()
+	f := testdb.NewFixture(t, conn, "Overlap Test", model.RoleDriver)
+	userID, vehicleID := f.UserID, f.VehicleID
+
+	repo := NewReservationRepo(conn)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Minute)
+
+	const workers = 20
+	var (
+		wg       sync.WaitGroup
+		mu       sync.Mutex
+		created  int
+		rejected int
+		other    []error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Staggered but overlapping windows, so the ranges differ too.
+			offset := time.Duration(i) * time.Minute
+			err := repo.Create(ctx, &model.Reservation{
+				VehicleID:   vehicleID,
+				RequesterID: userID,
+				StartTime:   start.Add(offset),
+				EndTime:     start.Add(offset + time.Hour),
+				Purpose:     fmt.Sprintf("worker %d", i),
+				Status:      model.ReservationStatusConfirmed,
+			})
+			mu.Lock()
+			defer mu.Unlock()
+			switch {
+			case err == nil:
+				created++
+			case errors.Is(err, ErrReservationOverlap):
+				rejected++
+			default:
+				other = append(other, err)
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	if len(other) > 0 {
+		t.Fatalf("unexpected errors: %v", other)
+	}
+	if created != 1 || rejected != workers-1 {
+		t.Errorf("created %d, rejected %d; want 1 and %d", created, rejected, workers-1)
+	}
+}
+
+// TestFindAvailableVehicleForSlot_Turnaround checks a vehicle is only free
+// for a slot leaving its turnaround after the booking before it. Needs a
+// migrated Postgres in TEST_DATABASE_URL.
+func TestFindAvailableVehicleForSlot_Turnaround(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	conn, err := db.Connect(url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+	if err := db.RunMigrations(conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+	var userID, vehicleID string
+	if err := conn.GetContext(ctx, &userID, `
+		INSERT INTO users (employee_id, password_hash, name, role)
+		VALUES ($1, 'x', 'Turnaround Test', 'driver') RETURNING id`, "turnaround-"+suffix); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	if err := conn.GetContext(ctx, &vehicleID, `
+		INSERT INTO vehicles (name, license_plate, turnaround_min)
+		VALUES ('Turnaround Test', $1, 30) RETURNING id`, "TRN-"+suffix); err != nil {
+		t.Fatalf("insert vehicle: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM reservations WHERE vehicle_id = $1`, vehicleID)
+		conn.Exec(`DELETE FROM vehicles WHERE id = $1`, vehicleID)
+		conn.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	})
+
+	repo := NewReservationRepo(conn)
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Minute)
+	if err := repo.Create(ctx, &model.Reservation{
+		VehicleID:   vehicleID,
+		RequesterID: userID,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Purpose:     "turnaround test",
+		Status:      model.ReservationStatusConfirmed,
+	}); err != nil {
+		t.Fatalf("create reservation: %v", err)
+	}
+
+	for _, tc := range []struct {
+		after time.Duration
+		want  bool
+	}{
+		{15 * time.Minute, false},
+		{30 * time.Minute, true},
+	} {
+		from := start.Add(time.Hour + tc.after)
+		ids, err := repo.FindAvailableVehicleForSlot(ctx, from, from.Add(time.Hour), nil, nil)
+		if err != nil {
+			t.Fatalf("FindAvailableVehicleForSlot: %v", err)
+		}
+		found := false
+		for _, id := range ids {
+			found = found || id == vehicleID
+		}
+		if found != tc.want {
+			t.Errorf("slot %s after the booking: offered=%v, want %v", tc.after, found, tc.want)
+		}
+	}
+}
+
+// TestListFreeVehicles_Unavailable checks conflict suggestions leave out a
+// vehicle on an active dispatch and one whose insurance lapses before the
+// slot ends. Needs a migrated Postgres in TEST_DATABASE_URL.
+func TestListFreeVehicles_Unavailable(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	conn, err := db.Connect(url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+	if err := db.RunMigrations(conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+	var userID, free, busy, lapsed string
+	if err := conn.GetContext(ctx, &userID, `
+		INSERT INTO users (employee_id, password_hash, name, role)
+		VALUES ($1, 'x', 'Free Test', 'dispatcher') RETURNING id`, "free-"+suffix); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	for plate, id := range map[string]*string{"FRE-" + suffix: &free, "BSY-" + suffix: &busy, "LPS-" + suffix: &lapsed} {
+		if err := conn.GetContext(ctx, id, `
+			INSERT INTO vehicles (name, license_plate)
+			VALUES ('Free Test', $1) RETURNING id`, plate); err != nil {
+			t.Fatalf("insert vehicle: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM dispatches WHERE requester_id = $1`, userID)
+		conn.Exec(`DELETE FROM documents WHERE created_by = $1`, userID)
+		conn.Exec(`DELETE FROM vehicles WHERE id IN ($1, $2, $3)`, free, busy, lapsed)
+		conn.Exec(`DELETE FROM users WHERE id = $1`, userID)
+	})
+
+	start := time.Now().Add(72 * time.Hour).Truncate(time.Minute)
+	if _, err := conn.ExecContext(ctx, `
+		INSERT INTO dispatches (vehicle_id, requester_id, purpose, pickup_address, status)
+		VALUES ($1, $2, 'free test', 'depot', 'en_route')`, busy, userID); err != nil {
+		t.Fatalf("insert dispatch: %v", err)
+	}
+	if _, err := conn.ExecContext(ctx, `
+		INSERT INTO documents (vehicle_id, type, expires_on, created_by)
+		VALUES ($1, 'insurance', $2, $3)`, lapsed, start.Add(-24*time.Hour).UTC().Format("2006-01-02"), userID); err != nil {
+		t.Fatalf("insert document: %v", err)
+	}
+
+	repo := NewReservationRepo(conn)
+	candidates, err := repo.ListFreeVehicles(ctx, start, start.Add(time.Hour), time.Hour, uuid.NewString(), nil)
+	if err != nil {
+		t.Fatalf("ListFreeVehicles: %v", err)
+	}
+	found := map[string]bool{}
+	for _, c := range candidates {
+		found[c.VehicleID] = true
+	}
+	if !found[free] || found[busy] || found[lapsed] {
+		t.Errorf("offered free=%v busy=%v lapsed=%v; want true, false, false", found[free], found[busy], found[lapsed])
+	}
+}
+
+// TestListFeed_Driver hands one vehicle from one driver to another between
+// two shifts. Each driver's feed must list only the reservation whose trip
+// was assigned on their own shift. Needs a migrated Postgres in
+// TEST_DATABASE_URL.
+func TestListFeed_Driver(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	conn, err := db.Connect(url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+	if err := db.RunMigrations(conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+	var earlyID, lateID, vehicleID string
+	for _, u := range []struct {
+		id   *string
+		name string
+	}{{&earlyID, "early"}, {&lateID, "late"}} {
+		if err := conn.GetContext(ctx, u.id, `
+			INSERT INTO users (employee_id, password_hash, name, role)
+			VALUES ($1, 'x', 'Feed Test', 'driver') RETURNING id`, "feed-"+u.name+"-"+suffix); err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+	}
+	if err := conn.GetContext(ctx, &vehicleID, `
+		INSERT INTO vehicles (name, license_plate)
+		VALUES ('Feed Test', $1) RETURNING id`, "FED-"+suffix); err != nil {
+		t.Fatalf("insert vehicle: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM dispatches WHERE vehicle_id = $1`, vehicleID)
+		conn.Exec(`DELETE FROM reservations WHERE vehicle_id = $1`, vehicleID)
+		conn.Exec(`DELETE FROM driver_attendance WHERE vehicle_id = $1`, vehicleID)
+		conn.Exec(`DELETE FROM vehicles WHERE id = $1`, vehicleID)
+		conn.Exec(`DELETE FROM users WHERE id IN ($1, $2)`, earlyID, lateID)
+	})
+
+	now := time.Now().Truncate(time.Minute)
+	if _, err := conn.ExecContext(ctx, `
+		INSERT INTO driver_attendance (driver_id, vehicle_id, clock_in_at, clock_out_at)
+		VALUES ($1, $3, $4, $5), ($2, $3, $5, NULL)`,
+		earlyID, lateID, vehicleID, now.Add(-6*time.Hour), now.Add(-3*time.Hour)); err != nil {
+		t.Fatalf("insert shifts: %v", err)
+	}
+
+	repo := NewReservationRepo(conn)
+	book := func(start, assigned time.Time) string {
+		res := &model.Reservation{
+			VehicleID: vehicleID, RequesterID: earlyID, Purpose: "feed test",
+			StartTime: start, EndTime: start.Add(time.Hour),
+			Status: model.ReservationStatusConfirmed,
+		}
+		if err := repo.Create(ctx, res); err != nil {
+			t.Fatalf("create reservation: %v", err)
+		}
+		if _, err := conn.ExecContext(ctx, `
+			INSERT INTO dispatches (vehicle_id, requester_id, purpose, pickup_address, status, assigned_at, reservation_id)
+			VALUES ($1, $2, 'feed test', '(reservation)', 'assigned', $3, $4)`,
+			vehicleID, earlyID, assigned, res.ID); err != nil {
+			t.Fatalf("insert trip: %v", err)
+		}
+		return res.ID
+	}
+	earlyRes := book(now.Add(-5*time.Hour), now.Add(-5*time.Hour-30*time.Minute))
+	lateRes := book(now.Add(-time.Hour), now.Add(-90*time.Minute))
+
+	for _, tc := range []struct {
+		driverID string
+		want     string
+	}{{earlyID, earlyRes}, {lateID, lateRes}} {
+		got, err := repo.ListFeed(ctx, "", "", tc.driverID, now.Add(-24*time.Hour), now.Add(24*time.Hour))
+		if err != nil {
+			t.Fatalf("ListFeed: %v", err)
+		}
+		if len(got) != 1 || got[0].ID != tc.want {
+			ids := make([]string, len(got))
+			for i := range got {
+				ids[i] = got[i].ID
+			}
+			t.Errorf("driver %s feed = %v, want [%s]", tc.driverID, ids, tc.want)
+		}
+	}
+}
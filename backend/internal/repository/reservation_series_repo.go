@@ -0,0 +1,147 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/kento/driver/backend/internal/model"
+)
+
+type ReservationSeriesRepo struct {
+	db *sqlx.DB
+}
+
+func NewReservationSeriesRepo(db *sqlx.DB) *ReservationSeriesRepo {
+	return &ReservationSeriesRepo{db: db}
+}
+
+const seriesColumns = `id, vehicle_id, requester_id, rrule, dtstart, duration_sec, timezone,
+	purpose, destinations, notes, priority_level, status, materialized_until,
+	cancel_reason, cancelled_by, created_at, updated_at`
+
+func (r *ReservationSeriesRepo) Create(ctx context.Context, s *model.ReservationSeries) error {
+	return r.db.GetContext(ctx, s, `
+		INSERT INTO reservation_series (vehicle_id, requester_id, rrule, dtstart, duration_sec, timezone,
+			purpose, destinations, notes, priority_level)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING `+seriesColumns,
+		s.VehicleID, s.RequesterID, s.RRule, s.DTStart, s.DurationSec, s.Timezone,
+		s.Purpose, pq.Array(s.Destinations), s.Notes, s.PriorityLevel)
+}
+
+func (r *ReservationSeriesRepo) GetByID(ctx context.Context, id string) (*model.ReservationSeries, error) {
+	var s model.ReservationSeries
+	err := r.db.GetContext(ctx, &s, `SELECT `+seriesColumns+` FROM reservation_series WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &s, err
+}
+
+// List returns series newest first. Only series on vehicles within the depot
+// scope depotIDs, nil for all, are returned.
+func (r *ReservationSeriesRepo) List(ctx context.Context, requesterID, status string, depotIDs []string, limit, offset int) ([]model.ReservationSeries, error) {
+	var series []model.ReservationSeries
+	query := `SELECT ` + seriesColumns + ` FROM reservation_series WHERE 1=1`
+
+	args := []interface{}{}
+	argIdx := 1
+
+	if requesterID != "" {
+		query += ` AND requester_id = $` + itoa(argIdx)
+		args = append(args, requesterID)
+		argIdx++
+	}
+	if status != "" {
+		query += ` AND status = $` + itoa(argIdx)
+		args = append(args, status)
+		argIdx++
+	}
+	if depotIDs != nil {
+		query += ` AND EXISTS (SELECT 1 FROM vehicles v WHERE v.id = reservation_series.vehicle_id AND ` +
+			inDepots("v.depot_id", "$"+itoa(argIdx)) + `)`
+		args = append(args, pq.Array(depotIDs))
+		argIdx++
+	}
+
+	query += ` ORDER BY created_at DESC LIMIT $` + itoa(argIdx) + ` OFFSET $` + itoa(argIdx+1)
+	args = append(args, limit, offset)
+
+	err := r.db.SelectContext(ctx, &series, query, args...)
+	return series, err
+}
+
+// ListDue returns active series not yet materialized up to horizon.
+func (r *ReservationSeriesRepo) ListDue(ctx context.Context, horizon time.Time) ([]model.ReservationSeries, error) {
+	var series []model.ReservationSeries
+	err := r.db.SelectContext(ctx, &series, `
+		SELECT `+seriesColumns+`
+		FROM reservation_series
+		WHERE status = 'active'
+			AND (materialized_until IS NULL OR materialized_until < $1)
+		ORDER BY materialized_until NULLS FIRST`, horizon)
+	return series, err
+}
+
+func (r *ReservationSeriesRepo) SetMaterializedUntil(ctx context.Context, id string, until time.Time) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE reservation_series SET materialized_until = $1, updated_at = NOW()
+		WHERE id = $2`, until, id)
+	return err
+}
+
+// UpdateTemplate saves the fields series-wide edits may change.
+func (r *ReservationSeriesRepo) UpdateTemplate(ctx context.Context, s *model.ReservationSeries) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE reservation_series
+		SET vehicle_id = $1, purpose = $2, destinations = $3, notes = $4, updated_at = NOW()
+		WHERE id = $5`,
+		s.VehicleID, s.Purpose, pq.Array(s.Destinations), s.Notes, s.ID)
+	return err
+}
+
+func (r *ReservationSeriesRepo) End(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE reservation_series SET status = 'ended', updated_at = NOW()
+		WHERE id = $1 AND status = 'active'`, id)
+	return err
+}
+
+func (r *ReservationSeriesRepo) Cancel(ctx context.Context, id, cancelledBy, reason string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE reservation_series
+		SET status = 'cancelled', cancelled_by = $1, cancel_reason = $2, updated_at = NOW()
+		WHERE id = $3`, cancelledBy, reason, id)
+	return err
+}
+
+// OccurrenceStarts returns the generated slots already materialized for a
+// series in [from, to), whatever their status: a cancelled occurrence must
+// not come back.
+func (r *ReservationSeriesRepo) OccurrenceStarts(ctx context.Context, seriesID string, from, to time.Time) ([]time.Time, error) {
+	var starts []time.Time
+	err := r.db.SelectContext(ctx, &starts, `
+		SELECT occurrence_start FROM reservations
+		WHERE series_id = $1 AND occurrence_start >= $2 AND occurrence_start < $3`,
+		seriesID, from, to)
+	return starts, err
+}
+
+// UpcomingOccurrences returns the series' occurrences starting after from
+// that still hold or wait for a vehicle.
+func (r *ReservationSeriesRepo) UpcomingOccurrences(ctx context.Context, seriesID string, from time.Time) ([]model.Reservation, error) {
+	var reservations []model.Reservation
+	err := r.db.SelectContext(ctx, &reservations, `
+		SELECT `+reservationColumns+`
+		FROM reservations
+		WHERE series_id = $1
+			AND start_time > $2
+			AND status IN ('confirmed', 'pending_conflict', 'pending_driver')
+		ORDER BY start_time`, seriesID, from)
+	return reservations, err
+}
@@ -0,0 +1,96 @@
+package repository
+
+import (
+	"context"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/kento/driver/backend/internal/model"
+)
+
+type TravelRepo struct {
+	db *sqlx.DB
+}
+
+func NewTravelRepo(db *sqlx.DB) *TravelRepo {
+	return &TravelRepo{db: db}
+}
+
+// departRadiusM is how far from the pickup a vehicle must be for the ride
+// to count as under way.
+const departRadiusM = 100
+
+// ListTripLegs returns the drives of dispatches completed since the given
+// time. The approach leg starts at the vehicle's last GPS fix within five
+// minutes before the driver set off. Arrival only means the driver is at the
+// pickup; the passenger may take a while to board, so the ride leg starts at
+// the first fix after arrival that is departRadiusM away from it. Dispatches
+// without the fixes do not contribute the leg.
+func (r *TravelRepo) ListTripLegs(ctx context.Context, since time.Time) ([]model.TripLeg, error) {
+	var legs []model.TripLeg
+	err := r.db.SelectContext(ctx, &legs, `
+		SELECT d.id AS dispatch_id,
+			ST_Y(fix.location::geometry) AS from_lat, ST_X(fix.location::geometry) AS from_lng,
+			ST_Y(d.pickup_location::geometry) AS to_lat, ST_X(d.pickup_location::geometry) AS to_lng,
+			d.en_route_at AS started_at, d.arrived_at AS ended_at
+		FROM dispatches d
+		CROSS JOIN LATERAL (
+			SELECT vl.location FROM vehicle_locations vl
+			WHERE vl.vehicle_id = d.vehicle_id
+				AND vl.recorded_at BETWEEN d.en_route_at - INTERVAL '5 minutes' AND d.en_route_at
+			ORDER BY vl.recorded_at DESC
+			LIMIT 1
+		) fix
+		WHERE d.status = 'completed' AND d.completed_at >= $1
+			AND d.pickup_location IS NOT NULL
+			AND d.en_route_at IS NOT NULL AND d.arrived_at > d.en_route_at
+		UNION ALL
+		SELECT d.id,
+			ST_Y(fix.location::geometry), ST_X(fix.location::geometry),
+			ST_Y(d.dropoff_location::geometry), ST_X(d.dropoff_location::geometry),
+			fix.recorded_at, d.completed_at
+		FROM dispatches d
+		CROSS JOIN LATERAL (
+			SELECT vl.location, vl.recorded_at FROM vehicle_locations vl
+			WHERE vl.vehicle_id = d.vehicle_id
+				AND vl.recorded_at > d.arrived_at AND vl.recorded_at < d.completed_at
+				AND NOT ST_DWithin(vl.location, d.pickup_location, $2)
+			ORDER BY vl.recorded_at
+			LIMIT 1
+		) fix
+		WHERE d.status = 'completed' AND d.completed_at >= $1
+			AND d.pickup_location IS NOT NULL AND d.dropoff_location IS NOT NULL
+			AND d.arrived_at IS NOT NULL AND d.completed_at > d.arrived_at
+		ORDER BY started_at`, since, departRadiusM)
+	return legs, err
+}
+
+// ListSpeeds returns the learned speed table.
+func (r *TravelRepo) ListSpeeds(ctx context.Context) ([]model.TravelSpeed, error) {
+	var speeds []model.TravelSpeed
+	err := r.db.SelectContext(ctx, &speeds, `
+		SELECT cell_lat, cell_lng, hour_of_week, distance_m, duration_sec, samples, trained_at
+		FROM travel_speeds`)
+	return speeds, err
+}
+
+// ReplaceSpeeds swaps the learned speed table for a freshly trained one.
+func (r *TravelRepo) ReplaceSpeeds(ctx context.Context, speeds []model.TravelSpeed) error {
+	tx, err := r.db.BeginTxx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.ExecContext(ctx, `DELETE FROM travel_speeds`); err != nil {
+		return err
+	}
+	for _, s := range speeds {
+		if _, err := tx.NamedExecContext(ctx, `
+			INSERT INTO travel_speeds (cell_lat, cell_lng, hour_of_week, distance_m, duration_sec, samples, trained_at)
+			VALUES (:cell_lat, :cell_lng, :hour_of_week, :distance_m, :duration_sec, :samples, :trained_at)`, s); err != nil {
+			return err
+		}
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kento/driver/backend/internal/eta"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/testdb"
+)
+
+// TestListTripLegs_PickupWait completes two identical rides, one after the
+// driver waited twenty minutes at the pickup. The wait is not driving, so
+// both ride legs must give the same speed. Needs a migrated Postgres in
+// TEST_DATABASE_URL.
+func TestListTripLegs_PickupWait(t *testing.T) {
+	conn := testdb.Open(t)
+	ctx := context.Background()
+	f := testdb.NewFixture(t, conn, "Trip Leg Test", model.RoleDispatcher)
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM vehicle_locations WHERE vehicle_id = $1`, f.VehicleID)
+	})
+
+	pickup := eta.Point{Lat: 35.68, Lng: 139.70}
+	underway := eta.Point{Lat: 35.683, Lng: 139.70} // about 330 m on
+	dropoff := eta.Point{Lat: 35.70, Lng: 139.70}
+	fix := func(p eta.Point, at time.Time) {
+		t.Helper()
+		if _, err := conn.ExecContext(ctx, `
+			INSERT INTO vehicle_locations (vehicle_id, location, recorded_at)
+			VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326)::geography, $4)`, f.VehicleID, p.Lng, p.Lat, at); err != nil {
+			t.Fatalf("insert location: %v", err)
+		}
+	}
+	ride := func(arrived, departed time.Time) string {
+		t.Helper()
+		fix(pickup, arrived)
+		fix(pickup, departed)
+		fix(underway, departed.Add(30*time.Second))
+		var id string
+		if err := conn.GetContext(ctx, &id, `
+			INSERT INTO dispatches (vehicle_id, requester_id, purpose, pickup_address,
+				pickup_location, dropoff_location, status, arrived_at, completed_at)
+			VALUES ($1, $2, 'trip leg test', '(test)',
+				ST_SetSRID(ST_MakePoint($3, $4), 4326)::geography,
+				ST_SetSRID(ST_MakePoint($5, $6), 4326)::geography,
+				'completed', $7, $8)
+			RETURNING id`, f.VehicleID, f.UserID, pickup.Lng, pickup.Lat, dropoff.Lng, dropoff.Lat,
+			arrived, departed.Add(10*time.Minute)); err != nil {
+			t.Fatalf("insert dispatch: %v", err)
+		}
+		return id
+	}
+
+	base := time.Now().Add(-3 * time.Hour).Truncate(time.Minute)
+	straight := ride(base, base)
+	waited := ride(base.Add(time.Hour), base.Add(time.Hour+20*time.Minute))
+
+	legs, err := NewTravelRepo(conn).ListTripLegs(ctx, base.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("ListTripLegs: %v", err)
+	}
+	kmh := make(map[string]float64)
+	for _, l := range legs {
+		if l.DispatchID != straight && l.DispatchID != waited {
+			continue
+		}
+		d := eta.Distance(eta.Point{Lat: l.FromLat, Lng: l.FromLng}, eta.Point{Lat: l.ToLat, Lng: l.ToLng})
+		kmh[l.DispatchID] = d / l.EndedAt.Sub(l.StartedAt).Seconds() * 3.6
+	}
+	if len(kmh) != 2 {
+		t.Fatalf("got ride legs for %d of the 2 dispatches", len(kmh))
+	}
+	if diff := kmh[waited] - kmh[straight]; diff < -0.01 || diff > 0.01 {
+		t.Errorf("ride after a pickup wait learned %.1f km/h, without one %.1f km/h", kmh[waited], kmh[straight])
+	}
+}
@@ -65,12 +65,14 @@ func (r *UserRepo) UpdateFCMToken(ctx context.Context, id string, token string)
 	return err
 }
 
+// GetDriversByVehicleIDs returns the drivers holding the vehicles on their
+// open shifts.
 func (r *UserRepo) GetDriversByVehicleIDs(ctx context.Context, vehicleIDs []string) ([]model.User, error) {
 	query, args, err := sqlx.In(
 		`SELECT u.id, u.employee_id, u.password_hash, u.name, u.role, u.priority_level, u.phone_number, u.fcm_token, u.is_active, u.created_at, u.updated_at
 		 FROM users u
-		 JOIN vehicles v ON v.driver_id = u.id
-		 WHERE v.id IN (?)`, vehicleIDs)
+		 JOIN driver_attendance a ON a.driver_id = u.id AND a.clock_out_at IS NULL
+		 WHERE a.vehicle_id IN (?)`, vehicleIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -8,6 +8,7 @@ import (
 
 	"github.com/jmoiron/sqlx"
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/lib/pq"
 )
 
 type VehicleRepo struct {
@@ -18,16 +19,28 @@ func NewVehicleRepo(db *sqlx.DB) *VehicleRepo {
 	return &VehicleRepo{db: db}
 }
 
-func (r *VehicleRepo) ListWithStatus(ctx context.Context, staleThreshold time.Duration) ([]model.VehicleWithStatus, error) {
+// vehicleHolder is the driver whose open shift has vehicle v checked out, or
+// NULL. uq_attendance_open_vehicle allows at most one.
+const vehicleHolder = `(SELECT a.driver_id FROM driver_attendance a WHERE a.vehicle_id = v.id AND a.clock_out_at IS NULL)`
+
+const vehicleColumns = `v.id, v.name, v.license_plate, v.depot_id, ` + vehicleHolder + ` AS driver_id, ` + inMaintenance + ` AS is_maintenance,
+	v.turnaround_min, v.odometer_km, v.photo_url, v.created_at, v.updated_at`
+
+// ListWithStatus returns the vehicles within the depot scope depotIDs, nil
+// for all, with their live status.
+func (r *VehicleRepo) ListWithStatus(ctx context.Context, staleThreshold time.Duration, depotIDs []string) ([]model.VehicleWithStatus, error) {
 	var vehicles []model.VehicleWithStatus
 	err := r.db.SelectContext(ctx, &vehicles, `
 		SELECT
 			v.id,
 			v.name,
 			v.license_plate,
-			v.driver_id,
-			u.name AS driver_name,
-			v.is_maintenance,
+			v.depot_id,
+			da.driver_id,
+			COALESCE(u.name, '') AS driver_name,
+			`+inMaintenance+` AS is_maintenance,
+			v.turnaround_min,
+			v.odometer_km,
 			v.photo_url,
 			(da.id IS NOT NULL) AS is_clocked_in,
 			ST_Y(vlc.location::geometry) AS latitude,
@@ -36,7 +49,7 @@ func (r *VehicleRepo) ListWithStatus(ctx context.Context, staleThreshold time.Du
 			vlc.speed,
 			vlc.recorded_at AS location_at,
 			CASE
-				WHEN v.is_maintenance THEN 'maintenance'
+				WHEN `+inMaintenance+` THEN 'maintenance'
 				WHEN da.id IS NULL THEN 'driver_absent'
 				WHEN d.id IS NOT NULL THEN 'in_trip'
 				WHEN res.id IS NOT NULL THEN 'reserved'
@@ -45,55 +58,83 @@ func (r *VehicleRepo) ListWithStatus(ctx context.Context, staleThreshold time.Du
 				ELSE 'available'
 			END AS computed_status
 		FROM vehicles v
-		JOIN users u ON u.id = v.driver_id
+		LEFT JOIN driver_attendance da ON da.vehicle_id = v.id AND da.clock_out_at IS NULL
+		LEFT JOIN users u ON u.id = da.driver_id
 		LEFT JOIN vehicle_location_current vlc ON vlc.vehicle_id = v.id
-		LEFT JOIN driver_attendance da ON da.driver_id = v.driver_id AND da.clock_out_at IS NULL
 		LEFT JOIN dispatches d ON d.vehicle_id = v.id
 			AND d.status IN ('assigned','accepted','en_route','arrived')
 		LEFT JOIN reservations res ON res.vehicle_id = v.id
 			AND res.status = 'confirmed'
 			AND NOW() BETWEEN res.start_time AND res.end_time
+		WHERE `+inDepots("v.depot_id", "$2")+`
 		ORDER BY v.name
-	`, staleThreshold.String())
+	`, staleThreshold.String(), pq.Array(depotIDs))
 	return vehicles, err
 }
 
 func (r *VehicleRepo) GetByID(ctx context.Context, id string) (*model.Vehicle, error) {
 	var v model.Vehicle
 	err := r.db.GetContext(ctx, &v,
-		`SELECT id, name, license_plate, driver_id, is_maintenance, photo_url, created_at, updated_at
-		 FROM vehicles WHERE id = $1`, id)
+		`SELECT `+vehicleColumns+` FROM vehicles v WHERE v.id = $1`, id)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	return &v, err
 }
 
+// GetByDriverID returns the vehicle the driver has checked out on their open
+// shift, nil when they are off shift or drive none.
 func (r *VehicleRepo) GetByDriverID(ctx context.Context, driverID string) (*model.Vehicle, error) {
 	var v model.Vehicle
 	err := r.db.GetContext(ctx, &v,
-		`SELECT id, name, license_plate, driver_id, is_maintenance, photo_url, created_at, updated_at
-		 FROM vehicles WHERE driver_id = $1`, driverID)
+		`SELECT `+vehicleColumns+`
+		 FROM vehicles v
+		 JOIN driver_attendance a ON a.vehicle_id = v.id AND a.clock_out_at IS NULL
+		 WHERE a.driver_id = $1`, driverID)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &v, err
+}
+
+// GetLastByDriverID returns the vehicle of the driver's latest shift that
+// had one, nil when they never drove.
+func (r *VehicleRepo) GetLastByDriverID(ctx context.Context, driverID string) (*model.Vehicle, error) {
+	var v model.Vehicle
+	err := r.db.GetContext(ctx, &v,
+		`SELECT `+vehicleColumns+`
+		 FROM vehicles v
+		 JOIN driver_attendance a ON a.vehicle_id = v.id
+		 WHERE a.driver_id = $1
+		 ORDER BY a.clock_in_at DESC LIMIT 1`, driverID)
 	if errors.Is(err, sql.ErrNoRows) {
 		return nil, nil
 	}
 	return &v, err
 }
 
-func (r *VehicleRepo) Create(ctx context.Context, name, licensePlate, driverID string) (*model.Vehicle, error) {
+func (r *VehicleRepo) Create(ctx context.Context, name, licensePlate string, depotID *string) (*model.Vehicle, error) {
 	var v model.Vehicle
 	err := r.db.GetContext(ctx, &v,
-		`INSERT INTO vehicles (name, license_plate, driver_id)
+		`INSERT INTO vehicles AS v (name, license_plate, depot_id)
 		 VALUES ($1, $2, $3)
-		 RETURNING id, name, license_plate, driver_id, is_maintenance, photo_url, created_at, updated_at`,
-		name, licensePlate, driverID)
+		 RETURNING `+vehicleColumns,
+		name, licensePlate, depotID)
 	return &v, err
 }
 
-func (r *VehicleRepo) Update(ctx context.Context, id, name, licensePlate, driverID string) error {
+func (r *VehicleRepo) Update(ctx context.Context, id, name, licensePlate string) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE vehicles SET name = $1, license_plate = $2, updated_at = NOW() WHERE id = $3`,
+		name, licensePlate, id)
+	return err
+}
+
+// SetDepot moves the vehicle to a depot, nil to share it between all.
+func (r *VehicleRepo) SetDepot(ctx context.Context, id string, depotID *string) error {
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE vehicles SET name = $1, license_plate = $2, driver_id = $3, updated_at = NOW() WHERE id = $4`,
-		name, licensePlate, driverID, id)
+		`UPDATE vehicles SET depot_id = $1, updated_at = NOW() WHERE id = $2`,
+		depotID, id)
 	return err
 }
 
@@ -109,15 +150,71 @@ func (r *VehicleRepo) UpdatePhotoURL(ctx context.Context, id string, photoURL *s
 	return err
 }
 
-func (r *VehicleRepo) ToggleMaintenance(ctx context.Context, id string, maintenance bool) error {
+// InMaintenance reports whether a maintenance window blocks the vehicle now.
+func (r *VehicleRepo) InMaintenance(ctx context.Context, id string) (bool, error) {
+	var in bool
+	err := r.db.GetContext(ctx, &in, `SELECT `+inMaintenance+` FROM vehicles v WHERE v.id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	return in, err
+}
+
+// ExpiredDocuments returns the compliance document types whose latest
+// document on the vehicle expired before today (UTC).
+func (r *VehicleRepo) ExpiredDocuments(ctx context.Context, id string) ([]model.DocumentType, error) {
+	return r.expiredDocuments(ctx, utcToday, id)
+}
+
+// ExpiredDocumentVehicleIDs returns which of vehicles ids have a compliance
+// document type whose latest document expired before today (UTC), in one
+// query for ranking many vehicles at once.
+func (r *VehicleRepo) ExpiredDocumentVehicleIDs(ctx context.Context, ids []string) ([]string, error) {
+	var expired []string
+	err := r.db.SelectContext(ctx, &expired, `
+		SELECT v.id FROM vehicles v
+		WHERE v.id = ANY($1::uuid[]) AND `+complianceExpiredBy(utcToday), pq.Array(ids))
+	return expired, err
+}
+
+// ExpiredDocumentsBy returns the compliance document types whose latest
+// document on the vehicle expires before a slot ending at end does, the same
+// cut-off FindAvailableVehicleForSlot uses.
+func (r *VehicleRepo) ExpiredDocumentsBy(ctx context.Context, id string, end time.Time) ([]model.DocumentType, error) {
+	return r.expiredDocuments(ctx, `(($2::timestamptz - interval '1 second') AT TIME ZONE 'UTC')::date`, id, end)
+}
+
+// expiredDocuments lists the compliance types of vehicle args[0] that
+// expired before day, a DATE expression over args.
+func (r *VehicleRepo) expiredDocuments(ctx context.Context, day string, args ...interface{}) ([]model.DocumentType, error) {
+	var types []model.DocumentType
+	err := r.db.SelectContext(ctx, &types, `
+		SELECT doc.type FROM documents doc
+		WHERE doc.vehicle_id = $1 AND doc.type IN ('registration', 'insurance', 'emission_test')
+		GROUP BY doc.type
+		HAVING bool_and(doc.expires_on IS NOT NULL) AND MAX(doc.expires_on) < `+day+`
+		ORDER BY doc.type`, args...)
+	return types, err
+}
+
+// RecordOdometer raises the vehicle's last known odometer reading to km.
+// Lower readings, e.g. typos, never wind it back.
+func (r *VehicleRepo) RecordOdometer(ctx context.Context, id string, km int) error {
 	_, err := r.db.ExecContext(ctx,
-		`UPDATE vehicles SET is_maintenance = $1, updated_at = NOW() WHERE id = $2`,
-		maintenance, id)
+		`UPDATE vehicles SET odometer_km = GREATEST(COALESCE(odometer_km, 0), $1), updated_at = NOW() WHERE id = $2`,
+		km, id)
 	return err
 }
 
-func (r *VehicleRepo) ListAvailable(ctx context.Context, staleThreshold time.Duration) ([]model.VehicleWithStatus, error) {
-	all, err := r.ListWithStatus(ctx, staleThreshold)
+func (r *VehicleRepo) SetTurnaround(ctx context.Context, id string, minutes int) error {
+	_, err := r.db.ExecContext(ctx,
+		`UPDATE vehicles SET turnaround_min = $1, updated_at = NOW() WHERE id = $2`,
+		minutes, id)
+	return err
+}
+
+func (r *VehicleRepo) ListAvailable(ctx context.Context, staleThreshold time.Duration, depotIDs []string) ([]model.VehicleWithStatus, error) {
+	all, err := r.ListWithStatus(ctx, staleThreshold, depotIDs)
 	if err != nil {
 		return nil, err
 	}
@@ -129,3 +226,87 @@ func (r *VehicleRepo) ListAvailable(ctx context.Context, staleThreshold time.Dur
 	}
 	return available, nil
 }
+
+// Availability returns every vehicle's busy and free intervals in [from, to)
+// in one query. Busy intervals come from reservations that hold or may take
+// the vehicle, active dispatches, maintenance windows and, for the past
+// part of the window, time nobody had the vehicle checked out on a shift. Their bounds are
+// snapped outwards to the granularity grid (anchored at from) and clipped to
+// the window; free intervals are what remains.
+//
+// An active dispatch is busy until its estimated_end_at, or until now when it
+// has none or is running late. Future shifts are unknown, so the future
+// counts as staffed. The driver name is that of the current holder. Only
+// vehicles within the depot scope depotIDs, nil for all, are returned.
+func (r *VehicleRepo) Availability(ctx context.Context, from, to time.Time, granularity time.Duration, depotIDs []string) ([]model.AvailabilityInterval, error) {
+	var rows []model.AvailabilityInterval
+	err := r.db.SelectContext(ctx, &rows, `
+		WITH win AS (
+			SELECT $1::timestamptz AS lo, $2::timestamptz AS hi, make_interval(secs => $3) AS g
+		), busy AS (
+			SELECT res.vehicle_id, 'reservation' AS kind, res.id AS ref_id, res.status::text AS status,
+				res.start_time AS s, res.end_time AS e
+			FROM reservations res, win
+			WHERE res.status IN ('confirmed', 'pending_driver', 'pending_conflict')
+				AND res.start_time < win.hi AND res.end_time > win.lo
+
+			UNION ALL
+			SELECT d.vehicle_id, 'dispatch', d.id, d.status::text,
+				COALESCE(d.assigned_at, d.created_at), GREATEST(COALESCE(d.estimated_end_at, NOW()), NOW())
+			FROM dispatches d, win
+			WHERE d.vehicle_id IS NOT NULL
+				AND d.status IN ('assigned', 'accepted', 'en_route', 'arrived')
+				AND COALESCE(d.assigned_at, d.created_at) < win.hi
+				AND GREATEST(COALESCE(d.estimated_end_at, NOW()), NOW()) > win.lo
+
+			UNION ALL
+			SELECT mw.vehicle_id, 'maintenance', mw.id, mw.status::text,
+				mw.start_time, CASE WHEN mw.status = 'in_progress' THEN GREATEST(mw.end_time, NOW()) ELSE mw.end_time END
+			FROM maintenance_windows mw, win
+			WHERE `+maintenanceBlocks("win.lo", "win.hi")+`
+
+			UNION ALL
+			SELECT v.id, 'driver_absent', NULL, NULL, lower(gap), upper(gap)
+			FROM vehicles v, win,
+				LATERAL unnest(
+					tstzmultirange(tstzrange(win.lo, GREATEST(win.lo, LEAST(win.hi, NOW()))))
+					- COALESCE((
+						SELECT range_agg(tstzrange(a.clock_in_at, COALESCE(a.clock_out_at, 'infinity')))
+						FROM driver_attendance a
+						WHERE a.vehicle_id = v.id
+							AND a.clock_in_at < win.hi
+							AND COALESCE(a.clock_out_at, 'infinity') > win.lo
+					), '{}'::tstzmultirange)
+				) AS gap
+		), snapped AS (
+			SELECT b.vehicle_id, b.kind, b.ref_id, b.status,
+				GREATEST(date_bin(win.g, b.s, win.lo), win.lo) AS s,
+				LEAST(CASE WHEN date_bin(win.g, b.e, win.lo) < b.e
+					THEN date_bin(win.g, b.e, win.lo) + win.g
+					ELSE b.e END, win.hi) AS e
+			FROM busy b, win
+		), free AS (
+			SELECT v.id AS vehicle_id, 'free' AS kind, NULL::uuid AS ref_id, NULL AS status,
+				lower(f) AS s, upper(f) AS e
+			FROM vehicles v, win,
+				LATERAL unnest(
+					tstzmultirange(tstzrange(win.lo, win.hi))
+					- COALESCE((
+						SELECT range_agg(tstzrange(sn.s, sn.e)) FROM snapped sn WHERE sn.vehicle_id = v.id
+					), '{}'::tstzmultirange)
+				) AS f
+		)
+		SELECT x.vehicle_id, v.name AS vehicle_name, v.license_plate, COALESCE(u.name, '') AS driver_name,
+			x.kind, x.ref_id, x.status, x.s AS start_time, x.e AS end_time
+		FROM (
+			SELECT vehicle_id, kind, ref_id, status, s, e FROM snapped WHERE s < e
+			UNION ALL
+			SELECT vehicle_id, kind, ref_id, status, s, e FROM free
+		) x
+		JOIN vehicles v ON v.id = x.vehicle_id
+		LEFT JOIN users u ON u.id = `+vehicleHolder+`
+		WHERE `+inDepots("v.depot_id", "$4")+`
+		ORDER BY v.name, v.id, x.s, x.kind`,
+		from, to, granularity.Seconds(), pq.Array(depotIDs))
+	return rows, err
+}
@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+	"github.com/lib/pq"
+
+	"github.com/kento/driver/backend/internal/model"
+)
+
+type WaitlistRepo struct {
+	db *sqlx.DB
+}
+
+func NewWaitlistRepo(db *sqlx.DB) *WaitlistRepo {
+	return &WaitlistRepo{db: db}
+}
+
+const waitlistColumns = `id, requester_id, start_time, end_time, purpose, destinations, notes,
+	passenger_name, pickup_address, pickup_lat, pickup_lng, priority_level, status,
+	reservation_id, depot_ids, created_at, updated_at`
+
+func (r *WaitlistRepo) Create(ctx context.Context, e *model.WaitlistEntry) error {
+	return r.db.GetContext(ctx, e, `
+		INSERT INTO reservation_waitlist (requester_id, start_time, end_time, purpose, destinations, notes,
+			passenger_name, pickup_address, pickup_lat, pickup_lng, priority_level, depot_ids)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING `+waitlistColumns,
+		e.RequesterID, e.StartTime, e.EndTime, e.Purpose, pq.Array(e.Destinations), e.Notes,
+		e.PassengerName, e.PickupAddress, e.PickupLat, e.PickupLng, e.PriorityLevel, e.DepotIDs)
+}
+
+func (r *WaitlistRepo) GetByID(ctx context.Context, id string) (*model.WaitlistEntry, error) {
+	var e model.WaitlistEntry
+	err := r.db.GetContext(ctx, &e, `SELECT `+waitlistColumns+` FROM reservation_waitlist WHERE id = $1`, id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	return &e, err
+}
+
+// List returns entries newest first. Only entries whose depot scope meets
+// depotIDs, nil for all, are returned.
+func (r *WaitlistRepo) List(ctx context.Context, requesterID, status string, depotIDs []string, limit, offset int) ([]model.WaitlistEntry, error) {
+	var entries []model.WaitlistEntry
+	query := `SELECT ` + waitlistColumns + ` FROM reservation_waitlist WHERE 1=1`
+
+	args := []interface{}{}
+	argIdx := 1
+
+	if requesterID != "" {
+		query += ` AND requester_id = $` + itoa(argIdx)
+		args = append(args, requesterID)
+		argIdx++
+	}
+	if status != "" {
+		query += ` AND status = $` + itoa(argIdx)
+		args = append(args, status)
+		argIdx++
+	}
+	if depotIDs != nil {
+		query += ` AND (depot_ids IS NULL OR depot_ids && $` + itoa(argIdx) + `::uuid[])`
+		args = append(args, pq.Array(depotIDs))
+		argIdx++
+	}
+
+	query += ` ORDER BY created_at DESC LIMIT $` + itoa(argIdx) + ` OFFSET $` + itoa(argIdx+1)
+	args = append(args, limit, offset)
+
+	err := r.db.SelectContext(ctx, &entries, query, args...)
+	return entries, err
+}
+
+// FindWaiting returns the waiting entries whose slot overlaps [startTime,
+// endTime) and has not started yet, in the order they are served: higher
+// priority first, then first come.
+func (r *WaitlistRepo) FindWaiting(ctx context.Context, startTime, endTime time.Time) ([]model.WaitlistEntry, error) {
+	var entries []model.WaitlistEntry
+	err := r.db.SelectContext(ctx, &entries, `
+		SELECT `+waitlistColumns+`
+		FROM reservation_waitlist
+		WHERE status = 'waiting'
+			AND start_time < $2
+			AND end_time > $1
+			AND start_time > NOW()
+		ORDER BY priority_level DESC, created_at`, startTime, endTime)
+	return entries, err
+}
+
+// Claim marks a waiting entry booked so concurrent re-evaluations skip it.
+// Returns false if it was no longer waiting.
+func (r *WaitlistRepo) Claim(ctx context.Context, id string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE reservation_waitlist SET status = 'booked', updated_at = NOW()
+		WHERE id = $1 AND status = 'waiting'`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// Release puts a claimed entry back on the list after booking it failed.
+func (r *WaitlistRepo) Release(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE reservation_waitlist SET status = 'waiting', updated_at = NOW()
+		WHERE id = $1 AND status = 'booked' AND reservation_id IS NULL`, id)
+	return err
+}
+
+func (r *WaitlistRepo) SetReservation(ctx context.Context, id, reservationID string) error {
+	_, err := r.db.ExecContext(ctx, `
+		UPDATE reservation_waitlist SET reservation_id = $1, updated_at = NOW()
+		WHERE id = $2`, reservationID, id)
+	return err
+}
+
+// Cancel withdraws a waiting entry. Returns false if it was no longer
+// waiting.
+func (r *WaitlistRepo) Cancel(ctx context.Context, id string) (bool, error) {
+	res, err := r.db.ExecContext(ctx, `
+		UPDATE reservation_waitlist SET status = 'cancelled', updated_at = NOW()
+		WHERE id = $1 AND status = 'waiting'`, id)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n == 1, err
+}
+
+// ExpireStarted expires the waiting entries whose slot has started and
+// returns them.
+func (r *WaitlistRepo) ExpireStarted(ctx context.Context) ([]model.WaitlistEntry, error) {
+	var entries []model.WaitlistEntry
+	err := r.db.SelectContext(ctx, &entries, `
+		UPDATE reservation_waitlist SET status = 'expired', updated_at = NOW()
+		WHERE status = 'waiting' AND start_time <= NOW()
+		RETURNING `+waitlistColumns)
+	return entries, err
+}
@@ -0,0 +1,352 @@
+// Package rrule parses and expands the subset of iCalendar recurrence rules
+// (RFC 5545 RRULE) that reservation series use: DAILY, WEEKLY and MONTHLY
+// frequencies with INTERVAL, COUNT, UNTIL, BYDAY and BYMONTHDAY.
+//
+// Occurrences keep the wall-clock time of the series start in its location,
+// so a series starting 08:00 Asia/Tokyo stays at 08:00 local across DST
+// changes.
+package rrule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Frequency string
+
+const (
+	Daily   Frequency = "DAILY"
+	Weekly  Frequency = "WEEKLY"
+	Monthly Frequency = "MONTHLY"
+)
+
+// WeekdayNum is a BYDAY entry. N selects the nth weekday of the month for
+// MONTHLY rules (1 = first, -1 = last); 0 means every such weekday.
+type WeekdayNum struct {
+	Day time.Weekday
+	N   int
+}
+
+// Rule is a parsed recurrence rule.
+type Rule struct {
+	Freq       Frequency
+	Interval   int
+	Count      int
+	Until      time.Time
+	ByDay      []WeekdayNum
+	ByMonthDay []int
+}
+
+// maxPeriods bounds expansion so a rule whose filters never match (BYMONTHDAY=31
+// every 12 months from February) cannot loop forever.
+const maxPeriods = 10000
+
+var weekdayCodes = map[string]time.Weekday{
+	"SU": time.Sunday, "MO": time.Monday, "TU": time.Tuesday, "WE": time.Wednesday,
+	"TH": time.Thursday, "FR": time.Friday, "SA": time.Saturday,
+}
+
+// Parse reads a rule such as "FREQ=WEEKLY;BYDAY=MO,WE,FR;UNTIL=20261231".
+// An optional "RRULE:" prefix is accepted. A floating or date-only UNTIL is
+// read in loc; a date-only UNTIL includes the whole day.
+func Parse(s string, loc *time.Location) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("rrule: empty rule")
+	}
+	r := &Rule{Interval: 1}
+	seen := map[string]bool{}
+	for _, part := range strings.Split(s, ";") {
+		key, val, ok := strings.Cut(part, "=")
+		if !ok || val == "" {
+			return nil, fmt.Errorf("rrule: malformed part %q", part)
+		}
+		key = strings.ToUpper(key)
+		if seen[key] {
+			return nil, fmt.Errorf("rrule: %s given twice", key)
+		}
+		seen[key] = true
+
+		var err error
+		switch key {
+		case "FREQ":
+			r.Freq = Frequency(strings.ToUpper(val))
+			switch r.Freq {
+			case Daily, Weekly, Monthly:
+			default:
+				err = fmt.Errorf("unsupported FREQ %q", val)
+			}
+		case "INTERVAL":
+			r.Interval, err = strconv.Atoi(val)
+			if err == nil && r.Interval < 1 {
+				err = fmt.Errorf("INTERVAL must be positive")
+			}
+		case "COUNT":
+			r.Count, err = strconv.Atoi(val)
+			if err == nil && r.Count < 1 {
+				err = fmt.Errorf("COUNT must be positive")
+			}
+		case "UNTIL":
+			r.Until, err = parseUntil(val, loc)
+		case "BYDAY":
+			r.ByDay, err = parseByDay(val)
+		case "BYMONTHDAY":
+			r.ByMonthDay, err = parseByMonthDay(val)
+		case "WKST":
+			if val != "MO" {
+				err = fmt.Errorf("only WKST=MO is supported")
+			}
+		default:
+			err = fmt.Errorf("unsupported part %s", key)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("rrule: %w", err)
+		}
+	}
+
+	if r.Freq == "" {
+		return nil, fmt.Errorf("rrule: FREQ is required")
+	}
+	if r.Count > 0 && !r.Until.IsZero() {
+		return nil, fmt.Errorf("rrule: COUNT and UNTIL are mutually exclusive")
+	}
+	if r.Freq != Monthly {
+		for _, d := range r.ByDay {
+			if d.N != 0 {
+				return nil, fmt.Errorf("rrule: numbered BYDAY is only valid with FREQ=MONTHLY")
+			}
+		}
+		if len(r.ByMonthDay) > 0 {
+			return nil, fmt.Errorf("rrule: BYMONTHDAY is only valid with FREQ=MONTHLY")
+		}
+	}
+	return r, nil
+}
+
+func parseUntil(val string, loc *time.Location) (time.Time, error) {
+	if t, err := time.Parse("20060102T150405Z", val); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102T150405", val, loc); err == nil {
+		return t, nil
+	}
+	if t, err := time.ParseInLocation("20060102", val, loc); err == nil {
+		return t.AddDate(0, 0, 1).Add(-time.Second), nil
+	}
+	return time.Time{}, fmt.Errorf("invalid UNTIL %q", val)
+}
+
+func parseByDay(val string) ([]WeekdayNum, error) {
+	var days []WeekdayNum
+	for _, item := range strings.Split(strings.ToUpper(val), ",") {
+		if len(item) < 2 {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		code := item[len(item)-2:]
+		day, ok := weekdayCodes[code]
+		if !ok {
+			return nil, fmt.Errorf("invalid BYDAY %q", item)
+		}
+		n := 0
+		if prefix := item[:len(item)-2]; prefix != "" {
+			var err error
+			n, err = strconv.Atoi(prefix)
+			if err != nil || n == 0 || n < -5 || n > 5 {
+				return nil, fmt.Errorf("invalid BYDAY %q", item)
+			}
+		}
+		days = append(days, WeekdayNum{Day: day, N: n})
+	}
+	return days, nil
+}
+
+func parseByMonthDay(val string) ([]int, error) {
+	var days []int
+	for _, item := range strings.Split(val, ",") {
+		d, err := strconv.Atoi(item)
+		if err != nil || d == 0 || d < -31 || d > 31 {
+			return nil, fmt.Errorf("invalid BYMONTHDAY %q", item)
+		}
+		days = append(days, d)
+	}
+	return days, nil
+}
+
+// String renders the rule in canonical RRULE form, without the prefix.
+func (r *Rule) String() string {
+	parts := []string{"FREQ=" + string(r.Freq)}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if r.Count > 0 {
+		parts = append(parts, "COUNT="+strconv.Itoa(r.Count))
+	}
+	if !r.Until.IsZero() {
+		parts = append(parts, "UNTIL="+r.Until.UTC().Format("20060102T150405Z"))
+	}
+	if len(r.ByDay) > 0 {
+		codes := make([]string, len(r.ByDay))
+		for i, d := range r.ByDay {
+			code := strings.ToUpper(d.Day.String()[:2])
+			if d.N != 0 {
+				code = strconv.Itoa(d.N) + code
+			}
+			codes[i] = code
+		}
+		parts = append(parts, "BYDAY="+strings.Join(codes, ","))
+	}
+	if len(r.ByMonthDay) > 0 {
+		days := make([]string, len(r.ByMonthDay))
+		for i, d := range r.ByMonthDay {
+			days[i] = strconv.Itoa(d)
+		}
+		parts = append(parts, "BYMONTHDAY="+strings.Join(days, ","))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Between returns the occurrence start times in [from, to) of the series
+// beginning at dtstart. Candidates before dtstart are skipped, so a dtstart
+// that does not match the rule is not itself an occurrence. COUNT is counted
+// from dtstart whatever the window.
+func (r *Rule) Between(dtstart, from, to time.Time) []time.Time {
+	var out []time.Time
+	n := 0
+	for period := 0; period < maxPeriods; period++ {
+		for _, t := range r.period(dtstart, period) {
+			if t.Before(dtstart) {
+				continue
+			}
+			if !r.Until.IsZero() && t.After(r.Until) {
+				return out
+			}
+			if !t.Before(to) {
+				return out
+			}
+			n++
+			if !t.Before(from) {
+				out = append(out, t)
+			}
+			if r.Count > 0 && n >= r.Count {
+				return out
+			}
+		}
+	}
+	return out
+}
+
+// Finite reports whether the series ends on its own.
+func (r *Rule) Finite() bool {
+	return r.Count > 0 || !r.Until.IsZero()
+}
+
+// period returns the sorted candidates of the nth period after dtstart.
+func (r *Rule) period(dtstart time.Time, n int) []time.Time {
+	step := n * r.Interval
+	at := func(y int, m time.Month, d int) time.Time {
+		return time.Date(y, m, d, dtstart.Hour(), dtstart.Minute(), dtstart.Second(), 0, dtstart.Location())
+	}
+
+	var out []time.Time
+	switch r.Freq {
+	case Daily:
+		t := at(dtstart.Year(), dtstart.Month(), dtstart.Day()+step)
+		if len(r.ByDay) == 0 || r.hasWeekday(t.Weekday()) {
+			out = append(out, t)
+		}
+
+	case Weekly:
+		// Weeks start on Monday.
+		offset := (int(dtstart.Weekday()) + 6) % 7
+		monday := at(dtstart.Year(), dtstart.Month(), dtstart.Day()-offset+7*step)
+		if len(r.ByDay) == 0 {
+			out = append(out, at(monday.Year(), monday.Month(), monday.Day()+offset))
+			break
+		}
+		for _, d := range r.ByDay {
+			out = append(out, at(monday.Year(), monday.Month(), monday.Day()+(int(d.Day)+6)%7))
+		}
+
+	case Monthly:
+		first := time.Date(dtstart.Year(), dtstart.Month()+time.Month(step), 1, 0, 0, 0, 0, dtstart.Location())
+		y, m := first.Year(), first.Month()
+		last := daysIn(y, m)
+		switch {
+		case len(r.ByMonthDay) > 0:
+			for _, d := range r.ByMonthDay {
+				if d < 0 {
+					d = last + 1 + d
+				}
+				if d >= 1 && d <= last {
+					out = append(out, at(y, m, d))
+				}
+			}
+		case len(r.ByDay) > 0:
+			for _, wd := range r.ByDay {
+				days := weekdaysIn(y, m, wd.Day)
+				if wd.N != 0 {
+					days = nth(days, wd.N)
+				}
+				for _, d := range days {
+					out = append(out, at(y, m, d))
+				}
+			}
+		default:
+			// RFC 5545 skips months without the start's day (no Feb 30th).
+			if dtstart.Day() <= last {
+				out = append(out, at(y, m, dtstart.Day()))
+			}
+		}
+	}
+
+	sort.Slice(out, func(i, j int) bool { return out[i].Before(out[j]) })
+	return dedupe(out)
+}
+
+func (r *Rule) hasWeekday(d time.Weekday) bool {
+	for _, wd := range r.ByDay {
+		if wd.Day == d {
+			return true
+		}
+	}
+	return false
+}
+
+func daysIn(y int, m time.Month) int {
+	return time.Date(y, m+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+// weekdaysIn lists the days of the month falling on wd.
+func weekdaysIn(y int, m time.Month, wd time.Weekday) []int {
+	first := time.Date(y, m, 1, 0, 0, 0, 0, time.UTC).Weekday()
+	var days []int
+	for d := 1 + (int(wd)-int(first)+7)%7; d <= daysIn(y, m); d += 7 {
+		days = append(days, d)
+	}
+	return days
+}
+
+// nth picks the nth of days, counting from the end when n is negative.
+func nth(days []int, n int) []int {
+	i := n - 1
+	if n < 0 {
+		i = len(days) + n
+	}
+	if i < 0 || i >= len(days) {
+		return nil
+	}
+	return days[i : i+1]
+}
+
+func dedupe(ts []time.Time) []time.Time {
+	out := ts[:0]
+	for i, t := range ts {
+		if i == 0 || !t.Equal(ts[i-1]) {
+			out = append(out, t)
+		}
+	}
+	return out
+}
@@ -0,0 +1,132 @@
+package rrule
+
+import (
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, s string, loc *time.Location) *Rule {
+	t.Helper()
+	r, err := Parse(s, loc)
+	if err != nil {
+		t.Fatalf("Parse(%q): %v", s, err)
+	}
+	return r
+}
+
+func formatAll(ts []time.Time) []string {
+	out := make([]string, len(ts))
+	for i, t := range ts {
+		out[i] = t.Format("2006-01-02 15:04 Mon")
+	}
+	return out
+}
+
+func assertTimes(t *testing.T, got []time.Time, want ...string) {
+	t.Helper()
+	g := formatAll(got)
+	if len(g) != len(want) {
+		t.Fatalf("got %v, want %v", g, want)
+	}
+	for i := range want {
+		if g[i] != want[i] {
+			t.Fatalf("got %v, want %v", g, want)
+		}
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"INTERVAL=2",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;INTERVAL=0",
+		"FREQ=DAILY;COUNT=3;UNTIL=20261231",
+		"FREQ=WEEKLY;BYDAY=1MO",
+		"FREQ=DAILY;BYMONTHDAY=1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=WEEKLY;BYDAY=XX",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=DAILY;BYHOUR=8",
+	} {
+		if _, err := Parse(s, time.UTC); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", s)
+		}
+	}
+}
+
+func TestParse_RoundTrip(t *testing.T) {
+	r := mustParse(t, "RRULE:freq=monthly;interval=2;byday=1MO,-1FR;until=20261231T000000Z", time.UTC)
+	if got, want := r.String(), "FREQ=MONTHLY;INTERVAL=2;UNTIL=20261231T000000Z;BYDAY=1MO,-1FR"; got != want {
+		t.Errorf("String() = %q, want %q", got, want)
+	}
+}
+
+func TestBetween_Weekdays(t *testing.T) {
+	manila := time.FixedZone("PHT", 8*3600)
+	r := mustParse(t, "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", manila)
+	start := time.Date(2026, 3, 4, 8, 0, 0, 0, manila) // Wednesday
+
+	got := r.Between(start, start, start.AddDate(0, 0, 7))
+	assertTimes(t, got,
+		"2026-03-04 08:00 Wed", "2026-03-05 08:00 Thu", "2026-03-06 08:00 Fri",
+		"2026-03-09 08:00 Mon", "2026-03-10 08:00 Tue")
+}
+
+func TestBetween_CountCountsFromStart(t *testing.T) {
+	r := mustParse(t, "FREQ=DAILY;COUNT=5", time.UTC)
+	start := time.Date(2026, 1, 1, 9, 0, 0, 0, time.UTC)
+
+	// A window starting after three occurrences only sees the last two.
+	got := r.Between(start, start.AddDate(0, 0, 3), start.AddDate(1, 0, 0))
+	assertTimes(t, got, "2026-01-04 09:00 Sun", "2026-01-05 09:00 Mon")
+}
+
+func TestBetween_DateOnlyUntilIncludesDay(t *testing.T) {
+	r := mustParse(t, "FREQ=DAILY;INTERVAL=2;UNTIL=20260105", time.UTC)
+	start := time.Date(2026, 1, 1, 18, 0, 0, 0, time.UTC)
+
+	got := r.Between(start, start, start.AddDate(1, 0, 0))
+	assertTimes(t, got, "2026-01-01 18:00 Thu", "2026-01-03 18:00 Sat", "2026-01-05 18:00 Mon")
+}
+
+func TestBetween_MonthlySkipsShortMonths(t *testing.T) {
+	r := mustParse(t, "FREQ=MONTHLY;COUNT=3", time.UTC)
+	start := time.Date(2026, 1, 31, 8, 0, 0, 0, time.UTC)
+
+	got := r.Between(start, start, start.AddDate(1, 0, 0))
+	assertTimes(t, got, "2026-01-31 08:00 Sat", "2026-03-31 08:00 Tue", "2026-05-31 08:00 Sun")
+}
+
+func TestBetween_MonthlyNthWeekday(t *testing.T) {
+	r := mustParse(t, "FREQ=MONTHLY;BYDAY=1MO,-1FR", time.UTC)
+	start := time.Date(2026, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	got := r.Between(start, start, time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC))
+	assertTimes(t, got,
+		"2026-01-05 10:00 Mon", "2026-01-30 10:00 Fri",
+		"2026-02-02 10:00 Mon", "2026-02-27 10:00 Fri")
+}
+
+func TestBetween_LastDayOfMonth(t *testing.T) {
+	r := mustParse(t, "FREQ=MONTHLY;BYMONTHDAY=-1;COUNT=2", time.UTC)
+	start := time.Date(2026, 1, 1, 17, 0, 0, 0, time.UTC)
+
+	got := r.Between(start, start, start.AddDate(1, 0, 0))
+	assertTimes(t, got, "2026-01-31 17:00 Sat", "2026-02-28 17:00 Sat")
+}
+
+func TestBetween_KeepsWallClockAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skip("tzdata not available")
+	}
+	r := mustParse(t, "FREQ=WEEKLY;COUNT=2", ny)
+	start := time.Date(2026, 3, 2, 8, 0, 0, 0, ny) // DST starts 2026-03-08
+
+	got := r.Between(start, start, start.AddDate(0, 1, 0))
+	assertTimes(t, got, "2026-03-02 08:00 Mon", "2026-03-09 08:00 Mon")
+	if got[1].Sub(got[0]) != 7*24*time.Hour-time.Hour {
+		t.Errorf("gap = %v, want 167h", got[1].Sub(got[0]))
+	}
+}
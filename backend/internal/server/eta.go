@@ -0,0 +1,39 @@
+package server
+
+import (
+	"log"
+
+	"github.com/kento/driver/backend/internal/config"
+	"github.com/kento/driver/backend/internal/eta"
+	"github.com/kento/driver/backend/internal/maps"
+)
+
+// buildETAProvider chains the configured routing backends in order, skipping
+// any that are not set up, and always ends with the straight-line fallback at
+// the configured speed profile.
+func buildETAProvider(cfg *config.Config, mapsClient *maps.Client, learned *eta.Learned, profile eta.SpeedProfile) eta.Provider {
+	var providers []eta.Provider
+	var names []string
+	for _, name := range cfg.ETAProviders {
+		switch name {
+		case "google":
+			if cfg.GoogleMapsAPIKey == "" {
+				continue
+			}
+			providers = append(providers, eta.NewGoogle(mapsClient))
+		case "osrm":
+			if cfg.OSRMURL == "" {
+				continue
+			}
+			providers = append(providers, eta.NewOSRM(cfg.OSRMURL))
+		case "learned":
+			providers = append(providers, learned)
+		}
+		names = append(names, name)
+	}
+	providers = append(providers, eta.NewHaversine(profile))
+	names = append(names, "haversine")
+
+	log.Printf("[eta] providers: %v (timeout %s)", names, cfg.ETAProviderTimeout)
+	return eta.NewChain(cfg.ETAProviderTimeout, providers...)
+}
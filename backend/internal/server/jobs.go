@@ -0,0 +1,94 @@
+package server
+
+import (
+	"context"
+	"time"
+
+	"github.com/kento/driver/backend/internal/config"
+	"github.com/kento/driver/backend/internal/jobs"
+	"github.com/kento/driver/backend/internal/service"
+)
+
+// registerJobs wires the periodic maintenance routines into the scheduler.
+func registerJobs(
+	sched *jobs.Scheduler,
+	cfg *config.Config,
+	reservationSvc *service.ReservationService,
+	dispatchSvc *service.DispatchService,
+	seriesSvc *service.ReservationSeriesService,
+	waitlistSvc *service.WaitlistService,
+	reminderSvc *service.ReminderService,
+	bookingSvc *service.BookingService,
+	tokenSvc *service.TokenService,
+	locationSvc *service.LocationService,
+	travelModelSvc *service.TravelModelService,
+	maintenanceSvc *service.MaintenanceService,
+	documentSvc *service.DocumentService,
+) {
+	sched.Register("reservation.auto_complete", time.Minute, func(ctx context.Context) (int, error) {
+		n, err := reservationSvc.AutoCompleteExpired(ctx)
+		return int(n), err
+	})
+
+	// Occurrences are keyed by series and slot, so an overlapping run cannot
+	// create one twice.
+	sched.Register("reservation.series_materialize", time.Hour, func(ctx context.Context) (int, error) {
+		return seriesSvc.MaterializeDue(ctx)
+	})
+
+	// A reservation gets at most one trip, so overlapping runs are harmless.
+	sched.Register("reservation.create_trips", time.Minute, func(ctx context.Context) (int, error) {
+		return dispatchSvc.CreateReservationTrips(ctx, cfg.ReservationTripLead)
+	})
+
+	sched.Register("reservation.waitlist_expire", time.Minute, func(ctx context.Context) (int, error) {
+		return waitlistSvc.ExpireStarted(ctx)
+	})
+
+	// Reminders are claimed in the database before sending, so running every
+	// minute (or late) never sends one twice.
+	sched.Register("reservation.reminders", time.Minute, func(ctx context.Context) (int, error) {
+		return reminderSvc.SendDue(ctx)
+	})
+
+	// A schedule has at most one open window, so overlapping runs plan it
+	// once.
+	sched.Register("maintenance.plan_due", time.Hour, func(ctx context.Context) (int, error) {
+		return maintenanceSvc.PlanDue(ctx, cfg.MaintenancePlanLead)
+	})
+
+	// Overrunning work is flagged a trip lead ahead, so a reservation it
+	// holds up never gets a trip; flagging is idempotent.
+	sched.Register("maintenance.sync_overrun", time.Minute, func(ctx context.Context) (int, error) {
+		return maintenanceSvc.SyncOverrunning(ctx)
+	})
+
+	// Warnings are claimed in the database before sending, so each goes out
+	// once however often this runs.
+	sched.Register("document.expiry_warnings", time.Hour, func(ctx context.Context) (int, error) {
+		return documentSvc.SendExpiryWarnings(ctx)
+	})
+
+	sched.Register("dispatch.offer_timeout", 15*time.Second, func(ctx context.Context) (int, error) {
+		return bookingSvc.ExpireDispatchOffers(ctx)
+	})
+
+	sched.Register("token.clean_expired", time.Hour, func(ctx context.Context) (int, error) {
+		return 0, tokenSvc.CleanExpired(ctx)
+	})
+
+	// Partitions are created a week ahead so a missed run or a leader
+	// handover never leaves a day without one.
+	sched.Register("location.partitions", time.Hour, func(ctx context.Context) (int, error) {
+		return locationSvc.EnsurePartitions(ctx, 7)
+	})
+
+	sched.Register("location.retention", 6*time.Hour, func(ctx context.Context) (int, error) {
+		return locationSvc.ApplyRetention(ctx, cfg.LocationLogRetentionDays, cfg.LocationHistoryMaxDays)
+	})
+
+	// Followers pick up the stored table on their next reload.
+	sched.Register("eta.learn_speeds", 6*time.Hour, func(ctx context.Context) (int, error) {
+		return travelModelSvc.Retrain(ctx)
+	})
+}
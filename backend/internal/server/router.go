@@ -26,18 +26,29 @@ func noDirectoryListing(next http.Handler) http.Handler {
 func buildRouter(
 	cfg *config.Config,
 	tokenBlacklist middleware.TokenBlacklist,
+	depotLoader middleware.DepotLoader,
 	authH *handler.AuthHandler,
 	vehicleH *handler.VehicleHandler,
 	dispatchH *handler.DispatchHandler,
 	reservationH *handler.ReservationHandler,
+	seriesH *handler.ReservationSeriesHandler,
+	waitlistH *handler.WaitlistHandler,
+	calendarH *handler.CalendarHandler,
 	conflictH *handler.ConflictHandler,
+	maintenanceH *handler.MaintenanceHandler,
 	attendanceH *handler.AttendanceHandler,
+	mileageH *handler.MileageHandler,
+	documentH *handler.DocumentHandler,
+	depotH *handler.DepotHandler,
 	locationH *handler.LocationHandler,
 	adminH *handler.AdminHandler,
 	notifH *handler.NotificationHandler,
 	routeH *handler.RouteHandler,
 	bookingH *handler.BookingHandler,
 	passengerH *handler.PassengerHandler,
+	streamH *handler.StreamHandler,
+	jobH *handler.JobHandler,
+	etaH *handler.ETAHandler,
 ) chi.Router {
 	r := chi.NewRouter()
 
@@ -72,9 +83,33 @@ func buildRouter(
 		r.Post("/auth/passenger/register", passengerH.Register)
 		r.Post("/auth/passenger/login", passengerH.Login)
 
+		// Calendar feeds (calendar clients cannot log in; the token in the
+		// URL is the credential)
+		r.Get("/calendar/{token}.ics", calendarH.Feed)
+
+		// Event streams (EventSource cannot send headers, so the token may
+		// also arrive as ?access_token=)
+		r.Group(func(r chi.Router) {
+			r.Use(middleware.TokenFromQuery("access_token"))
+			r.Use(middleware.JWTAuth(cfg.JWTSecret, tokenBlacklist))
+			r.Use(middleware.DepotScope(depotLoader))
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireRole("admin", "dispatcher", "viewer"))
+				r.Get("/stream/fleet", streamH.Fleet)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireRole("passenger"))
+				r.Get("/passenger/rides/{id}/stream", streamH.Ride)
+			})
+		})
+
 		// Authenticated routes
 		r.Group(func(r chi.Router) {
 			r.Use(middleware.JWTAuth(cfg.JWTSecret, tokenBlacklist))
+			// Dispatchers only see the depots they work
+			r.Use(middleware.DepotScope(depotLoader))
 
 			// Auth
 			r.Get("/auth/me", authH.Me)
@@ -83,6 +118,12 @@ func buildRouter(
 			// Notifications
 			r.Put("/notifications/fcm-token", notifH.UpdateFCMToken)
 
+			// Calendar feed URLs (what each caller may subscribe to is
+			// decided per subject)
+			r.Get("/calendar-feeds", calendarH.ListFeeds)
+			r.Post("/calendar-feeds", calendarH.CreateFeed)
+			r.Delete("/calendar-feeds/{id}", calendarH.RevokeFeed)
+
 			// Vehicles (P1 - all authenticated)
 			r.Get("/vehicles", vehicleH.List)
 			r.Get("/vehicles/{id}", vehicleH.Get)
@@ -90,18 +131,37 @@ func buildRouter(
 			r.Get("/vehicles/{id}/timeline", bookingH.GetVehicleTimeline)
 			r.Get("/vehicles/available", vehicleH.ListAvailable)
 
+			// Fleet-wide free/busy grid (staff)
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireRole("admin", "dispatcher", "viewer"))
+				r.Get("/fleet/availability", vehicleH.Availability)
+			})
+
+			// Depots
+			r.Get("/depots", depotH.List)
+			r.Get("/depots/{id}", depotH.Get)
+
 			// Routes (Google Routes API proxy)
 			r.Post("/routes/compute", routeH.ComputeRoute)
 
 			// Dispatches (read: all; write: dispatcher+)
 			r.Get("/dispatches", dispatchH.List)
+			r.Get("/dispatches/state-machine", dispatchH.StateMachine)
 			r.Get("/dispatches/{id}", dispatchH.Get)
-			r.Get("/dispatches/{id}/eta", dispatchH.GetETASnapshots)
+
+			// ETA decision records are for staff only
+			r.Group(func(r chi.Router) {
+				r.Use(middleware.RequireRole("admin", "dispatcher", "viewer"))
+				r.Get("/dispatches/{id}/eta", dispatchH.GetETASnapshots)
+				r.Get("/dispatches/{id}/eta/review", dispatchH.ReviewETAs)
+			})
 
 			// Reservations (read: all)
 			r.Get("/reservations", reservationH.List)
 			r.Get("/reservations/{id}", reservationH.Get)
 			r.Get("/reservations/availability", reservationH.CheckAvailability)
+			r.Get("/reservation-series", seriesH.List)
+			r.Get("/reservation-series/{id}", seriesH.Get)
 
 			// Attendance (read: all)
 			r.Get("/attendance/history", attendanceH.GetHistory)
@@ -122,19 +182,40 @@ func buildRouter(
 				r.Post("/reservations", reservationH.Create)
 				r.Put("/reservations/{id}", reservationH.Update)
 				r.Post("/reservations/{id}/cancel", reservationH.Cancel)
+				r.Post("/reservation-series", seriesH.Create)
+				r.Put("/reservation-series/{id}", seriesH.Update)
+				r.Post("/reservation-series/{id}/cancel", seriesH.Cancel)
 
 				// Unified booking
 				r.Post("/bookings", bookingH.CreateBooking)
+				r.Get("/waitlist", waitlistH.List)
+				r.Get("/waitlist/{id}", waitlistH.Get)
+				r.Post("/waitlist/{id}/cancel", waitlistH.Cancel)
 
 				// Conflict management (P7)
 				r.Get("/conflicts", conflictH.ListPending)
 				r.Get("/conflicts/{id}", conflictH.Get)
+				r.Get("/conflicts/{id}/suggestions", conflictH.Suggestions)
 				r.Post("/conflicts/{id}/reassign", conflictH.Reassign)
 				r.Post("/conflicts/{id}/change-time", conflictH.ChangeTime)
 				r.Post("/conflicts/{id}/cancel", conflictH.Cancel)
 
-				// Vehicle maintenance toggle (P10)
-				r.Patch("/vehicles/{id}/maintenance", vehicleH.ToggleMaintenance)
+				// Maintenance windows and schedules
+				r.Get("/maintenance", maintenanceH.List)
+				r.Get("/maintenance/{id}", maintenanceH.Get)
+				r.Patch("/maintenance/{id}", maintenanceH.Update)
+				r.Post("/maintenance/{id}/start", maintenanceH.Start)
+				r.Post("/maintenance/{id}/complete", maintenanceH.Complete)
+				r.Post("/maintenance/{id}/cancel", maintenanceH.Cancel)
+				r.Post("/vehicles/{id}/maintenance-windows", maintenanceH.Create)
+				r.Get("/vehicles/{id}/fuel-logs", mileageH.ListFuelLogs)
+				r.Get("/vehicles/{id}/mileage", mileageH.Mileage)
+				r.Get("/vehicles/{id}/assignments", attendanceH.ListAssignments)
+				r.Get("/vehicles/{id}/maintenance-schedules", maintenanceH.ListSchedules)
+				r.Post("/vehicles/{id}/maintenance-schedules", maintenanceH.CreateSchedule)
+				r.Delete("/maintenance-schedules/{id}", maintenanceH.DeleteSchedule)
+
+				r.Patch("/vehicles/{id}/turnaround", vehicleH.SetTurnaround)
 			})
 
 			// Admin only routes
@@ -146,12 +227,32 @@ func buildRouter(
 				r.Put("/admin/users/{id}/priority", adminH.UpdatePriority)
 				r.Get("/admin/audit-logs", adminH.ListAuditLogs)
 				r.Get("/admin/audit-logs/{id}", adminH.GetAuditLog)
+				r.Get("/admin/jobs", jobH.List)
+				r.Get("/admin/eta/accuracy", etaH.Accuracy)
 
 				// Vehicle CRUD (admin only)
 				r.Post("/vehicles", vehicleH.Create)
 				r.Put("/vehicles/{id}", vehicleH.Update)
 				r.Delete("/vehicles/{id}", vehicleH.Delete)
 				r.Post("/vehicles/{id}/photo", vehicleH.UploadPhoto)
+				r.Post("/vehicles/{id}/transfer", vehicleH.Transfer)
+
+				// Depots and depot membership (admin only)
+				r.Post("/depots", depotH.Create)
+				r.Put("/depots/{id}", depotH.Update)
+				r.Delete("/depots/{id}", depotH.Delete)
+				r.Get("/admin/users/{id}/depots", depotH.GetUserDepots)
+				r.Put("/admin/users/{id}/depots", depotH.SetUserDepots)
+
+				// Vehicle and driver documents (admin only)
+				r.Get("/documents", documentH.List)
+				r.Get("/documents/{id}", documentH.Get)
+				r.Patch("/documents/{id}", documentH.Update)
+				r.Delete("/documents/{id}", documentH.Delete)
+				r.Post("/documents/{id}/scan", documentH.UploadScan)
+				r.Get("/documents/{id}/scan", documentH.DownloadScan)
+				r.Post("/vehicles/{id}/documents", documentH.CreateForVehicle)
+				r.Post("/admin/users/{id}/documents", documentH.CreateForUser)
 
 				// Force assign (P8 - admin only)
 				r.Post("/conflicts/{id}/force-assign", conflictH.ForceAssign)
@@ -179,8 +280,11 @@ func buildRouter(
 				r.Post("/attendance/clock-out", attendanceH.ClockOut)
 				r.Get("/attendance/status", attendanceH.GetStatus)
 				r.Put("/driver/status", attendanceH.UpdateDriverStatus)
+				r.Post("/driver/fuel-logs", mileageH.CreateFuelLog)
+				r.Post("/driver/fuel-logs/{id}/receipt", mileageH.UploadReceipt)
 				r.Get("/driver/trips/current", dispatchH.CurrentTrip)
 				r.Post("/driver/trips/{id}/accept", dispatchH.AcceptTrip)
+				r.Post("/driver/trips/{id}/decline", bookingH.DeclineTrip)
 				r.Post("/driver/trips/{id}/en-route", dispatchH.EnRouteTrip)
 				r.Post("/driver/trips/{id}/arrived", dispatchH.ArriveTrip)
 				r.Post("/driver/board", dispatchH.DriverBoard)
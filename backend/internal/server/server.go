@@ -1,6 +1,7 @@
 package server
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"log"
@@ -11,15 +12,35 @@ import (
 
 	"github.com/kento/driver/backend/internal/config"
 	"github.com/kento/driver/backend/internal/db"
+	"github.com/kento/driver/backend/internal/eta"
 	"github.com/kento/driver/backend/internal/handler"
+	"github.com/kento/driver/backend/internal/jobs"
 	"github.com/kento/driver/backend/internal/maps"
 	"github.com/kento/driver/backend/internal/middleware"
 	"github.com/kento/driver/backend/internal/notify"
+	"github.com/kento/driver/backend/internal/realtime"
 	"github.com/kento/driver/backend/internal/repository"
 	"github.com/kento/driver/backend/internal/service"
 )
 
-func New(cfg *config.Config) (*http.Server, error) {
+// Server is the HTTP server plus the background work that must stop with it.
+type Server struct {
+	*http.Server
+	scheduler *jobs.Scheduler
+	// stop is closed on shutdown to end the per-replica background loops
+	stop chan struct{}
+}
+
+// Shutdown stops accepting requests, waits for in-flight ones, then stops
+// background jobs and loops and releases job leadership.
+func (s *Server) Shutdown(ctx context.Context) error {
+	err := s.Server.Shutdown(ctx)
+	close(s.stop)
+	s.scheduler.Stop()
+	return err
+}
+
+func New(cfg *config.Config) (*Server, error) {
 	// Database
 	database, err := db.Connect(cfg.DatabaseURL)
 	if err != nil {
@@ -48,10 +69,18 @@ func New(cfg *config.Config) (*http.Server, error) {
 	dispatchRepo := repository.NewDispatchRepo(database)
 	reservationRepo := repository.NewReservationRepo(database)
 	conflictRepo := repository.NewConflictRepo(database)
+	maintenanceRepo := repository.NewMaintenanceRepo(database)
+	seriesRepo := repository.NewReservationSeriesRepo(database)
+	waitlistRepo := repository.NewWaitlistRepo(database)
+	calendarRepo := repository.NewCalendarRepo(database)
 	attendanceRepo := repository.NewAttendanceRepo(database)
+	mileageRepo := repository.NewMileageRepo(database)
+	documentRepo := repository.NewDocumentRepo(database)
+	depotRepo := repository.NewDepotRepo(database)
 	locationRepo := repository.NewLocationRepo(database)
 	auditRepo := repository.NewAuditRepo(database)
 	tokenRepo := repository.NewTokenRepo(database)
+	travelRepo := repository.NewTravelRepo(database)
 
 	// Notification service
 	fcmSvc, err := notify.NewFCMService(cfg.FirebaseCredentialsPath, userRepo)
@@ -60,17 +89,58 @@ func New(cfg *config.Config) (*http.Server, error) {
 		fcmSvc, _ = notify.NewFCMService("", userRepo)
 	}
 
+	// Real-time event hub (fleet stream)
+	hub := realtime.NewHub()
+
+	// Maps client and ETA routing
+	mapsClient := maps.NewClient(cfg.GoogleMapsAPIKey)
+	speedProfile, err := eta.ParseSpeedProfile(cfg.ETASpeedProfile)
+	if err != nil {
+		return nil, fmt.Errorf("ETA_SPEED_PROFILE: %w", err)
+	}
+	learnedETA := eta.NewLearned()
+	etaProvider := buildETAProvider(cfg, mapsClient, learnedETA, speedProfile)
+
 	// Services
 	auditSvc := service.NewAuditService(auditRepo)
 	tokenSvc := service.NewTokenService(tokenRepo)
 	authSvc := service.NewAuthService(userRepo, cfg.JWTSecret, cfg.JWTAccessExpiry, cfg.JWTRefreshExpiry)
-	vehicleSvc := service.NewVehicleService(vehicleRepo, cfg.LocationStaleThreshold, auditSvc)
-	attendanceSvc := service.NewAttendanceService(attendanceRepo, auditSvc)
-	locationSvc := service.NewLocationService(locationRepo)
-	dispatchSvc := service.NewDispatchService(dispatchRepo, vehicleRepo, auditSvc, cfg.LocationStaleThreshold, fcmSvc)
-	reservationSvc := service.NewReservationService(reservationRepo, conflictRepo, auditSvc)
-	conflictSvc := service.NewConflictService(conflictRepo, reservationRepo, auditSvc)
-	bookingSvc := service.NewBookingService(dispatchSvc, reservationSvc, vehicleRepo, reservationRepo, auditSvc, fcmSvc)
+	vehicleSvc := service.NewVehicleService(vehicleRepo, depotRepo, cfg.LocationStaleThreshold, auditSvc, hub)
+	attendanceSvc := service.NewAttendanceService(attendanceRepo, vehicleRepo, dispatchRepo, auditSvc, hub)
+	locationSvc := service.NewLocationService(locationRepo, hub)
+	authz := service.NewAuthorizer(vehicleRepo, auditSvc)
+	waitlistSvc := service.NewWaitlistService(waitlistRepo, reservationRepo, authz, auditSvc, fcmSvc)
+	// Travel-time conflicts cost a Routes API call per neighbouring booking
+	var travelTimer service.TravelTimer
+	if cfg.ReservationTravelCheck {
+		travelTimer = mapsClient
+	}
+	reservationSvc := service.NewReservationService(reservationRepo, conflictRepo, vehicleRepo, maintenanceRepo, dispatchRepo, waitlistSvc, auditSvc, hub, travelTimer, cfg.ETAProviderTimeout)
+	dispatchSvc := service.NewDispatchService(dispatchRepo, vehicleRepo, auditSvc, cfg.LocationStaleThreshold, fcmSvc, hub, etaProvider, authz, reservationSvc)
+	seriesSvc := service.NewReservationSeriesService(seriesRepo, reservationSvc, auditSvc, cfg.SeriesHorizonDays)
+	conflictSvc := service.NewConflictService(conflictRepo, reservationRepo, vehicleRepo, maintenanceRepo, reservationSvc, auditSvc)
+	maintenanceSvc := service.NewMaintenanceService(maintenanceRepo, vehicleRepo, reservationSvc, auditSvc, hub, cfg.ReservationTripLead)
+	mileageSvc := service.NewMileageService(mileageRepo, vehicleRepo, auditSvc, cfg.MileageAnomalyPct, cfg.MileageAnomalyMinKm)
+	depotSvc := service.NewDepotService(depotRepo, userRepo, auditSvc)
+	documentSvc := service.NewDocumentService(documentRepo, vehicleRepo, userRepo, auditSvc, fcmSvc, cfg.DocumentExpiryWarnDays)
+	calendarSvc := service.NewCalendarService(calendarRepo, reservationRepo, vehicleRepo, userRepo, authz, auditSvc)
+	reminderSvc := service.NewReminderService(reservationRepo, fcmSvc, cfg.ReservationReminderMin)
+	autoDispatchSvc := service.NewAutoDispatchService(dispatchSvc, dispatchRepo, vehicleRepo, auditSvc,
+		service.AutoDispatchMode(cfg.AutoDispatchMode), service.AutoDispatchWeights{
+			ETA:      cfg.AutoDispatchWeightETA,
+			Fairness: cfg.AutoDispatchWeightFair,
+			Idle:     cfg.AutoDispatchWeightIdle,
+		})
+	travelModelSvc := service.NewTravelModelService(travelRepo, learnedETA, speedProfile, cfg.ETASpeedProfile, cfg.ETALearnWindowDays)
+	bookingSvc := service.NewBookingService(dispatchSvc, autoDispatchSvc, reservationSvc, waitlistSvc, vehicleRepo, reservationRepo, auditSvc, fcmSvc, cfg.DispatchAcceptTimeout)
+
+	// Computed vehicle status changes are derived from the events above
+	statusTracker := service.NewFleetStatusTracker(vehicleRepo, hub, cfg.LocationStaleThreshold)
+	go statusTracker.Run(15 * time.Second)
+
+	// Background jobs (only the advisory-lock leader runs them)
+	scheduler := jobs.NewScheduler(jobs.NewPGLeader(database, jobs.AdvisoryLockKey))
+	registerJobs(scheduler, cfg, reservationSvc, dispatchSvc, seriesSvc, waitlistSvc, reminderSvc, bookingSvc, tokenSvc, locationSvc, travelModelSvc, maintenanceSvc, documentSvc)
 
 	// Upload directory
 	uploadDir := filepath.Join(".", "uploads")
@@ -78,8 +148,11 @@ func New(cfg *config.Config) (*http.Server, error) {
 		return nil, fmt.Errorf("create upload dir: %w", err)
 	}
 
-	// Maps client
-	mapsClient := maps.NewClient(cfg.GoogleMapsAPIKey)
+	// Document scans are private, so they are kept outside the uploads
+	scanDir := filepath.Join(".", "documents")
+	if err := os.MkdirAll(scanDir, 0700); err != nil {
+		return nil, fmt.Errorf("create document dir: %w", err)
+	}
 
 	// Login rate limiter (5 failed attempts, 15 minute lockout)
 	loginLimiter := middleware.NewLoginLimiter(5, 15*time.Minute)
@@ -88,22 +161,32 @@ func New(cfg *config.Config) (*http.Server, error) {
 	authH := handler.NewAuthHandler(authSvc, tokenSvc, loginLimiter)
 	vehicleH := handler.NewVehicleHandler(vehicleSvc, locationSvc, uploadDir)
 	dispatchH := handler.NewDispatchHandler(dispatchSvc, vehicleSvc)
-	reservationH := handler.NewReservationHandler(reservationSvc, authSvc)
+	reservationH := handler.NewReservationHandler(reservationSvc, authSvc, vehicleSvc)
+	seriesH := handler.NewReservationSeriesHandler(seriesSvc, authSvc, vehicleSvc)
+	waitlistH := handler.NewWaitlistHandler(waitlistSvc)
+	calendarH := handler.NewCalendarHandler(calendarSvc)
 	conflictH := handler.NewConflictHandler(conflictSvc, reservationSvc)
+	maintenanceH := handler.NewMaintenanceHandler(maintenanceSvc)
 	attendanceH := handler.NewAttendanceHandler(attendanceSvc)
+	mileageH := handler.NewMileageHandler(mileageSvc, uploadDir)
+	documentH := handler.NewDocumentHandler(documentSvc, scanDir)
+	depotH := handler.NewDepotHandler(depotSvc)
 	locationH := handler.NewLocationHandler(locationSvc, vehicleSvc)
 	adminH := handler.NewAdminHandler(userRepo, auditSvc)
 	notifH := handler.NewNotificationHandler(userRepo)
 	routeH := handler.NewRouteHandler(mapsClient)
 	bookingH := handler.NewBookingHandler(bookingSvc, authSvc)
 	passengerH := handler.NewPassengerHandler(authSvc, dispatchSvc, locationSvc, bookingSvc, loginLimiter)
+	streamH := handler.NewStreamHandler(hub, vehicleSvc, dispatchSvc, locationSvc)
+	jobH := handler.NewJobHandler(scheduler)
+	etaH := handler.NewETAHandler(travelModelSvc)
 
 	// Router
 	router := buildRouter(
-		cfg, tokenSvc,
-		authH, vehicleH, dispatchH, reservationH, conflictH,
-		attendanceH, locationH, adminH, notifH, routeH,
-		bookingH, passengerH,
+		cfg, tokenSvc, depotSvc,
+		authH, vehicleH, dispatchH, reservationH, seriesH, waitlistH, calendarH, conflictH, maintenanceH,
+		attendanceH, mileageH, documentH, depotH, locationH, adminH, notifH, routeH,
+		bookingH, passengerH, streamH, jobH, etaH,
 	)
 
 	srv := &http.Server{
@@ -115,6 +198,9 @@ func New(cfg *config.Config) (*http.Server, error) {
 		IdleTimeout:       60 * time.Second,
 	}
 
+	// Disconnect stream clients on shutdown; this also stops the status tracker
+	srv.RegisterOnShutdown(hub.Close)
+
 	// TLS support: use ListenAndServeTLS when certs are provided
 	if cfg.TLSCert != "" && cfg.TLSKey != "" {
 		srv.TLSConfig = &tls.Config{
@@ -122,5 +208,11 @@ func New(cfg *config.Config) (*http.Server, error) {
 		}
 	}
 
-	return srv, nil
+	scheduler.Start()
+
+	// Learned ETA speeds are retrained by the leader; every replica reloads them
+	stop := make(chan struct{})
+	go travelModelSvc.Run(10*time.Minute, stop)
+
+	return &Server{Server: srv, scheduler: scheduler, stop: stop}, nil
 }
@@ -2,22 +2,43 @@ package service
 
 import (
 	"context"
+	"errors"
+	"log"
 
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/realtime"
 	"github.com/kento/driver/backend/internal/repository"
 	"github.com/kento/driver/backend/pkg/apperror"
 )
 
+// AttendanceService runs driver shifts. A shift is also the driver's
+// assignment to a vehicle: it is checked out at clock-in and returned at
+// clock-out, and whoever holds it gets its trips and notifications.
 type AttendanceService struct {
-	repo     *repository.AttendanceRepo
-	auditSvc *AuditService
+	repo         *repository.AttendanceRepo
+	vehicleRepo  *repository.VehicleRepo
+	dispatchRepo *repository.DispatchRepo
+	auditSvc     *AuditService
+	hub          *realtime.Hub
 }
 
-func NewAttendanceService(repo *repository.AttendanceRepo, auditSvc *AuditService) *AttendanceService {
-	return &AttendanceService{repo: repo, auditSvc: auditSvc}
+func NewAttendanceService(repo *repository.AttendanceRepo, vehicleRepo *repository.VehicleRepo, dispatchRepo *repository.DispatchRepo, auditSvc *AuditService, hub *realtime.Hub) *AttendanceService {
+	return &AttendanceService{repo: repo, vehicleRepo: vehicleRepo, dispatchRepo: dispatchRepo, auditSvc: auditSvc, hub: hub}
 }
 
-func (s *AttendanceService) ClockIn(ctx context.Context, driverID string) (*model.DriverAttendance, error) {
+var (
+	errInvalidOdometer = apperror.New(400, "INVALID_ODOMETER", "odometer_km must not be negative")
+	errVehicleTaken    = apperror.New(409, "VEHICLE_TAKEN", "vehicle is checked out by another driver")
+)
+
+// ClockIn starts a shift and checks out vehicleID for it. Without one the
+// driver takes the vehicle of their last shift if nobody holds it, and
+// otherwise works without a vehicle. The odometer reading, if given, is the
+// start of the shift's distance.
+func (s *AttendanceService) ClockIn(ctx context.Context, driverID string, vehicleID *string, odometerKm *int) (*model.DriverAttendance, error) {
+	if odometerKm != nil && *odometerKm < 0 {
+		return nil, errInvalidOdometer
+	}
 	existing, err := s.repo.GetActiveByDriverID(ctx, driverID)
 	if err != nil {
 		return nil, err
@@ -26,15 +47,62 @@ func (s *AttendanceService) ClockIn(ctx context.Context, driverID string) (*mode
 		return nil, apperror.New(400, "ALREADY_CLOCKED_IN", "driver is already clocked in")
 	}
 
-	a, err := s.repo.ClockIn(ctx, driverID)
+	vehicleID, err = s.pickVehicle(ctx, driverID, vehicleID)
 	if err != nil {
 		return nil, err
 	}
+	if vehicleID == nil && odometerKm != nil {
+		return nil, apperror.New(400, "NO_VEHICLE", "driver has no vehicle to read the odometer of")
+	}
+
+	a, err := s.repo.ClockIn(ctx, driverID, vehicleID, odometerKm)
+	if errors.Is(err, repository.ErrVehicleHeld) {
+		return nil, errVehicleTaken
+	}
+	if err != nil {
+		return nil, err
+	}
+	s.recordOdometer(ctx, vehicleID, odometerKm)
 	s.auditSvc.Log(ctx, driverID, "attendance.clock_in", "attendance", a.ID, nil, a, "")
+	s.hub.Publish(realtime.Event{Type: realtime.EventDriverAttendance, Data: a})
 	return a, nil
 }
 
-func (s *AttendanceService) ClockOut(ctx context.Context, driverID string) error {
+// pickVehicle returns the vehicle to check out at clock-in: the requested
+// one, which must be free, or else the free vehicle of the driver's last
+// shift. Nil means none.
+func (s *AttendanceService) pickVehicle(ctx context.Context, driverID string, requested *string) (*string, error) {
+	if requested != nil && *requested != "" {
+		v, err := s.vehicleRepo.GetByID(ctx, *requested)
+		if err != nil {
+			return nil, err
+		}
+		if v == nil {
+			return nil, apperror.New(404, "NOT_FOUND", "vehicle not found")
+		}
+		if v.DriverID != nil {
+			return nil, errVehicleTaken
+		}
+		return &v.ID, nil
+	}
+
+	v, err := s.vehicleRepo.GetLastByDriverID(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil || v.DriverID != nil {
+		return nil, nil
+	}
+	return &v.ID, nil
+}
+
+// ClockOut ends the driver's shift and returns its vehicle. A driver cannot
+// leave with a trip offered or under way, and an odometer reading below the
+// one taken at clock-in is refused.
+func (s *AttendanceService) ClockOut(ctx context.Context, driverID string, odometerKm *int) error {
+	if odometerKm != nil && *odometerKm < 0 {
+		return errInvalidOdometer
+	}
 	existing, err := s.repo.GetActiveByDriverID(ctx, driverID)
 	if err != nil {
 		return err
@@ -42,15 +110,46 @@ func (s *AttendanceService) ClockOut(ctx context.Context, driverID string) error
 	if existing == nil {
 		return apperror.New(400, "NOT_CLOCKED_IN", "driver is not clocked in")
 	}
+	if odometerKm != nil {
+		if existing.VehicleID == nil {
+			return apperror.New(400, "NO_VEHICLE", "shift has no vehicle to read the odometer of")
+		}
+		if existing.ClockInOdometerKm != nil && *odometerKm < *existing.ClockInOdometerKm {
+			return apperror.New(400, "INVALID_ODOMETER", "odometer_km is below the reading at clock-in")
+		}
+	}
+	if existing.VehicleID != nil {
+		d, err := s.dispatchRepo.GetActiveByVehicleID(ctx, *existing.VehicleID)
+		if err != nil {
+			return err
+		}
+		if d != nil {
+			return apperror.New(409, "TRIP_IN_PROGRESS", "answer the trip offer or finish the trip before clocking out")
+		}
+	}
 
-	err = s.repo.ClockOut(ctx, existing.ID)
+	err = s.repo.ClockOut(ctx, existing.ID, odometerKm)
 	if err != nil {
 		return err
 	}
+	s.recordOdometer(ctx, existing.VehicleID, odometerKm)
 	s.auditSvc.Log(ctx, driverID, "attendance.clock_out", "attendance", existing.ID, existing, nil, "")
+	s.hub.Publish(realtime.Event{Type: realtime.EventDriverAttendance, Data: existing})
 	return nil
 }
 
+// recordOdometer keeps the vehicle's last known reading current for
+// distance-based maintenance. The shift is already saved, so failures are
+// logged.
+func (s *AttendanceService) recordOdometer(ctx context.Context, vehicleID *string, odometerKm *int) {
+	if vehicleID == nil || odometerKm == nil {
+		return
+	}
+	if err := s.vehicleRepo.RecordOdometer(ctx, *vehicleID, *odometerKm); err != nil {
+		log.Printf("[attendance] record odometer of %s: %v", *vehicleID, err)
+	}
+}
+
 func (s *AttendanceService) UpdateDriverStatus(ctx context.Context, driverID string, status model.DriverStatus) (*model.DriverAttendance, error) {
 	existing, err := s.repo.GetActiveByDriverID(ctx, driverID)
 	if err != nil {
@@ -69,6 +168,7 @@ func (s *AttendanceService) UpdateDriverStatus(ctx context.Context, driverID str
 	s.auditSvc.Log(ctx, driverID, "attendance.update_status", "attendance", existing.ID,
 		map[string]interface{}{"driver_status": oldStatus},
 		map[string]interface{}{"driver_status": status}, "")
+	s.hub.Publish(realtime.Event{Type: realtime.EventDriverAttendance, Data: existing})
 	return existing, nil
 }
 
@@ -76,6 +176,11 @@ func (s *AttendanceService) GetStatus(ctx context.Context, driverID string) (*mo
 	return s.repo.GetActiveByDriverID(ctx, driverID)
 }
 
+// ListAssignments returns who had the vehicle checked out, latest first.
+func (s *AttendanceService) ListAssignments(ctx context.Context, vehicleID string, limit int) ([]model.VehicleAssignment, error) {
+	return s.repo.ListByVehicleID(ctx, vehicleID, limit)
+}
+
 func (s *AttendanceService) GetHistory(ctx context.Context, driverID string, limit int) ([]model.DriverAttendance, error) {
 	return s.repo.ListByDriverID(ctx, driverID, limit)
 }
@@ -0,0 +1,249 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/repository"
+)
+
+// AutoDispatchMode controls what the engine does with its ranking.
+type AutoDispatchMode string
+
+const (
+	AutoDispatchOff     AutoDispatchMode = "off"     // leave "any vehicle" dispatches pending
+	AutoDispatchSuggest AutoDispatchMode = "suggest" // rank and record, a dispatcher assigns
+	AutoDispatchAuto    AutoDispatchMode = "auto"    // assign the best vehicle immediately
+)
+
+// AutoDispatchWeights scales each ranking signal. Every signal is normalised
+// to [0,1] across the candidates, so weights are relative to each other.
+type AutoDispatchWeights struct {
+	ETA      float64 // shorter time to pickup
+	Fairness float64 // fewer trips this shift
+	Idle     float64 // longer since the last trip ended
+}
+
+// AutoDispatchService picks a vehicle for immediate bookings that did not
+// ask for a specific one.
+type AutoDispatchService struct {
+	dispatchSvc *DispatchService
+	repo        *repository.DispatchRepo
+	vehicleRepo *repository.VehicleRepo
+	auditSvc    *AuditService
+	mode        AutoDispatchMode
+	weights     AutoDispatchWeights
+}
+
+func NewAutoDispatchService(dispatchSvc *DispatchService, repo *repository.DispatchRepo, vehicleRepo *repository.VehicleRepo, auditSvc *AuditService, mode AutoDispatchMode, weights AutoDispatchWeights) *AutoDispatchService {
+	return &AutoDispatchService{dispatchSvc: dispatchSvc, repo: repo, vehicleRepo: vehicleRepo, auditSvc: auditSvc, mode: mode, weights: weights}
+}
+
+// Rank scores every vehicle that could take the dispatch right now, best
+// first. Only available vehicles (clocked in, not stale, not busy) with a
+// known position and no expired documents are candidates, since assignment
+// refuses the others; vehicles in exclude are skipped. Returns
+// nil when the dispatch has no pickup coordinates. Only vehicles within the
+// depot scope depotIDs, nil for all, are considered. The ETAs of every
+// located vehicle among them are returned too, as the snapshot of the
+// decision.
+func (s *AutoDispatchService) Rank(ctx context.Context, d *model.Dispatch, exclude, depotIDs []string) ([]dto.AutoDispatchCandidate, []dto.VehicleETA, error) {
+	if d.PickupLat == nil || d.PickupLng == nil {
+		return nil, nil, nil
+	}
+
+	etas, err := s.dispatchSvc.CalculateETAs(ctx, *d.PickupLat, *d.PickupLng, depotIDs)
+	if err != nil {
+		return nil, nil, err
+	}
+	workloads, err := s.repo.ListWorkloads(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+	byVehicle := make(map[string]model.VehicleWorkload, len(workloads))
+	for _, w := range workloads {
+		byVehicle[w.VehicleID] = w
+	}
+	skip := make(map[string]bool, len(exclude))
+	for _, id := range exclude {
+		skip[id] = true
+	}
+
+	var ids []string
+	for _, e := range etas {
+		if e.IsAvailable && !skip[e.VehicleID] {
+			ids = append(ids, e.VehicleID)
+		}
+	}
+	if len(ids) > 0 {
+		expired, err := s.vehicleRepo.ExpiredDocumentVehicleIDs(ctx, ids)
+		if err != nil {
+			return nil, nil, err
+		}
+		for _, id := range expired {
+			skip[id] = true
+		}
+	}
+
+	now := time.Now()
+	var cands []dto.AutoDispatchCandidate
+	for _, e := range etas {
+		if !e.IsAvailable || skip[e.VehicleID] {
+			continue
+		}
+		c := dto.AutoDispatchCandidate{
+			VehicleID:   e.VehicleID,
+			VehicleName: e.VehicleName,
+			DurationSec: e.DurationSec,
+			DistanceM:   e.DistanceM,
+		}
+		if w, ok := byVehicle[e.VehicleID]; ok {
+			c.ShiftTrips = w.ShiftTrips
+			if w.IdleSince != nil {
+				c.IdleSec = int(now.Sub(*w.IdleSince).Seconds())
+			}
+		}
+		cands = append(cands, c)
+	}
+
+	scoreCandidates(cands, s.weights)
+	return cands, etas, nil
+}
+
+// scoreCandidates fills in the component scores and sorts best first.
+func scoreCandidates(cands []dto.AutoDispatchCandidate, w AutoDispatchWeights) {
+	if len(cands) == 0 {
+		return
+	}
+	minDur, maxTrips, maxIdle := cands[0].DurationSec, 0, 0
+	for _, c := range cands {
+		if c.DurationSec < minDur {
+			minDur = c.DurationSec
+		}
+		if c.ShiftTrips > maxTrips {
+			maxTrips = c.ShiftTrips
+		}
+		if c.IdleSec > maxIdle {
+			maxIdle = c.IdleSec
+		}
+	}
+
+	for i := range cands {
+		c := &cands[i]
+		c.ETAScore = 1
+		if c.DurationSec > 0 {
+			c.ETAScore = float64(minDur) / float64(c.DurationSec)
+		}
+		c.FairnessScore = 1
+		if maxTrips > 0 {
+			c.FairnessScore = 1 - float64(c.ShiftTrips)/float64(maxTrips)
+		}
+		if maxIdle > 0 {
+			c.IdleScore = float64(c.IdleSec) / float64(maxIdle)
+		}
+		c.Score = w.ETA*c.ETAScore + w.Fairness*c.FairnessScore + w.Idle*c.IdleScore
+	}
+
+	sort.SliceStable(cands, func(i, j int) bool {
+		if cands[i].Score != cands[j].Score {
+			return cands[i].Score > cands[j].Score
+		}
+		return cands[i].DurationSec < cands[j].DurationSec
+	})
+}
+
+// Dispatch runs the engine for a newly created pending dispatch. The ranked
+// ETAs are stored as the dispatch's ETA snapshots and the decision is written
+// to the audit log. In auto mode the winner is assigned; in suggest mode it
+// is only reported. Vehicles are picked from within the booker's depot
+// scope depotIDs, nil for all. Returns nil when the engine is off.
+func (s *AutoDispatchService) Dispatch(ctx context.Context, d *model.Dispatch, actorID string, depotIDs []string) (*dto.AutoDispatchResult, error) {
+	return s.run(ctx, d, actorID, actorID, false, depotIDs)
+}
+
+// Redispatch offers a dispatch that came back to pending to the next-best
+// vehicle, skipping every vehicle that already declined it. The new offer is
+// made on behalf of whoever assigned the dispatch originally, and stays
+// within the depot of the vehicle that last declined it.
+func (s *AutoDispatchService) Redispatch(ctx context.Context, d *model.Dispatch, actorID string) (*dto.AutoDispatchResult, error) {
+	assignerID := d.RequesterID
+	if d.DispatcherID != nil {
+		assignerID = *d.DispatcherID
+	}
+	var depotIDs []string
+	if n := len(d.DeclinedVehicleIDs); n > 0 {
+		v, err := s.vehicleRepo.GetByID(ctx, d.DeclinedVehicleIDs[n-1])
+		if err != nil {
+			return nil, err
+		}
+		if v != nil && v.DepotID != nil {
+			depotIDs = []string{*v.DepotID}
+		}
+	}
+	return s.run(ctx, d, actorID, assignerID, true, depotIDs)
+}
+
+func (s *AutoDispatchService) run(ctx context.Context, d *model.Dispatch, actorID, assignerID string, reoffer bool, depotIDs []string) (*dto.AutoDispatchResult, error) {
+	if s.mode == AutoDispatchOff {
+		return nil, nil
+	}
+
+	var exclude []string
+	if reoffer {
+		exclude = d.DeclinedVehicleIDs
+	}
+	cands, etas, err := s.Rank(ctx, d, exclude, depotIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	result := &dto.AutoDispatchResult{Mode: string(s.mode), Candidates: cands}
+	if len(cands) == 0 {
+		result.Reason = "no available vehicle with a known location and valid documents"
+		if d.PickupLat == nil || d.PickupLng == nil {
+			result.Reason = "dispatch has no pickup coordinates"
+		}
+	} else {
+		winner := cands[0]
+		result.VehicleID = &winner.VehicleID
+		result.Reason = explainChoice(winner, len(cands))
+	}
+
+	action := "dispatch.auto_suggest"
+	if s.mode == AutoDispatchAuto && result.VehicleID != nil {
+		// The assignment records etas as its snapshot
+		if err := s.dispatchSvc.AssignRanked(ctx, d.ID, *result.VehicleID, assignerID, etas); err != nil {
+			return result, err
+		}
+		result.Assigned = true
+		action = "dispatch.auto_assign"
+		if reoffer {
+			action = "dispatch.auto_reassign"
+		}
+	} else {
+		var suggested string
+		if result.VehicleID != nil {
+			suggested = *result.VehicleID
+		}
+		s.dispatchSvc.RecordETAs(ctx, d.ID, etas, model.ETATriggerAutoSuggest, actorID, suggested)
+	}
+
+	s.auditSvc.Log(ctx, actorID, action, "dispatch", d.ID, nil, result, result.Reason)
+	log.Printf("[autodispatch] dispatch=%s mode=%s candidates=%d excluded=%d assigned=%v reason=%q",
+		d.ID, s.mode, len(cands), len(exclude), result.Assigned, result.Reason)
+	return result, nil
+}
+
+func explainChoice(c dto.AutoDispatchCandidate, total int) string {
+	return fmt.Sprintf("%s ranked first of %d: ETA %ds (%.2f), %d trips this shift (%.2f), idle %s (%.2f), score %.2f",
+		c.VehicleName, total,
+		c.DurationSec, c.ETAScore,
+		c.ShiftTrips, c.FairnessScore,
+		(time.Duration(c.IdleSec) * time.Second).String(), c.IdleScore,
+		c.Score)
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"context"
+	"math"
+	"testing"
+	"time"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/eta"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/internal/testdb"
+)
+
+func TestScoreCandidates(t *testing.T) {
+	type scores struct{ eta, fairness, idle float64 }
+	tests := []struct {
+		name    string
+		weights AutoDispatchWeights
+		cands   []dto.AutoDispatchCandidate
+		order   []string
+		scores  map[string]scores
+	}{
+		{
+			name:    "eta weight picks the nearest",
+			weights: AutoDispatchWeights{ETA: 1},
+			cands: []dto.AutoDispatchCandidate{
+				{VehicleID: "far", DurationSec: 600},
+				{VehicleID: "near", DurationSec: 300},
+			},
+			order:  []string{"near", "far"},
+			scores: map[string]scores{"near": {1, 1, 0}, "far": {0.5, 1, 0}},
+		},
+		{
+			name:    "fairness weight picks the fewest trips",
+			weights: AutoDispatchWeights{Fairness: 1},
+			cands: []dto.AutoDispatchCandidate{
+				{VehicleID: "busy", DurationSec: 300, ShiftTrips: 4},
+				{VehicleID: "fresh", DurationSec: 600, ShiftTrips: 1},
+			},
+			order:  []string{"fresh", "busy"},
+			scores: map[string]scores{"busy": {1, 0, 0}, "fresh": {0.5, 0.75, 0}},
+		},
+		{
+			name:    "idle weight picks the longest idle",
+			weights: AutoDispatchWeights{Idle: 1},
+			cands: []dto.AutoDispatchCandidate{
+				{VehicleID: "recent", DurationSec: 300, IdleSec: 300},
+				{VehicleID: "waiting", DurationSec: 600, IdleSec: 1200},
+			},
+			order:  []string{"waiting", "recent"},
+			scores: map[string]scores{"recent": {1, 1, 0.25}, "waiting": {0.5, 1, 1}},
+		},
+		{
+			name:    "weights combine",
+			weights: AutoDispatchWeights{ETA: 1, Fairness: 1},
+			cands: []dto.AutoDispatchCandidate{
+				{VehicleID: "near-busy", DurationSec: 300, ShiftTrips: 4},
+				{VehicleID: "far-fresh", DurationSec: 600},
+			},
+			order: []string{"far-fresh", "near-busy"},
+		},
+		{
+			name:    "ties go to the shorter trip",
+			weights: AutoDispatchWeights{Fairness: 1},
+			cands: []dto.AutoDispatchCandidate{
+				{VehicleID: "a", DurationSec: 900, ShiftTrips: 2},
+				{VehicleID: "b", DurationSec: 300, ShiftTrips: 2},
+				{VehicleID: "c", DurationSec: 600, ShiftTrips: 2},
+			},
+			order: []string{"b", "c", "a"},
+		},
+		{
+			name:    "missing eta, idle and trip data",
+			weights: AutoDispatchWeights{ETA: 1, Fairness: 1, Idle: 1},
+			cands: []dto.AutoDispatchCandidate{
+				{VehicleID: "unknown"},
+			},
+			order:  []string{"unknown"},
+			scores: map[string]scores{"unknown": {1, 1, 0}},
+		},
+	}
+
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			scoreCandidates(tc.cands, tc.weights)
+
+			for i, id := range tc.order {
+				if tc.cands[i].VehicleID != id {
+					t.Fatalf("position %d = %s, want %s (order %v)", i, tc.cands[i].VehicleID, id, tc.order)
+				}
+			}
+			for _, c := range tc.cands {
+				want, ok := tc.scores[c.VehicleID]
+				if !ok {
+					continue
+				}
+				got := scores{c.ETAScore, c.FairnessScore, c.IdleScore}
+				if !approxEqual(got.eta, want.eta) || !approxEqual(got.fairness, want.fairness) || !approxEqual(got.idle, want.idle) {
+					t.Errorf("%s scores = %+v, want %+v", c.VehicleID, got, want)
+				}
+			}
+		})
+	}
+}
+
+func approxEqual(a, b float64) bool {
+	return math.Abs(a-b) < 1e-9
+}
+
+// TestRank_ExpiredDocuments puts a vehicle with a lapsed registration right
+// at the pickup and another a few kilometres away. Assignment would refuse
+// the nearer one, so it must not be ranked at all. Needs a migrated Postgres
+// in TEST_DATABASE_URL.
+func TestRank_ExpiredDocuments(t *testing.T) {
+	conn := testdb.Open(t)
+	ctx := context.Background()
+	near := testdb.NewFixture(t, conn, "Rank Near", model.RoleDriver)
+	far := testdb.NewFixture(t, conn, "Rank Far", model.RoleDriver)
+	t.Cleanup(func() {
+		for _, f := range []testdb.Fixture{near, far} {
+			conn.Exec(`DELETE FROM vehicle_location_current WHERE vehicle_id = $1`, f.VehicleID)
+			conn.Exec(`DELETE FROM driver_attendance WHERE vehicle_id = $1`, f.VehicleID)
+			conn.Exec(`DELETE FROM documents WHERE vehicle_id = $1`, f.VehicleID)
+		}
+	})
+
+	lat, lng := 35.6895, 139.6917
+	attendance := repository.NewAttendanceRepo(conn)
+	for _, v := range []struct {
+		f   testdb.Fixture
+		lat float64
+	}{{near, lat}, {far, lat + 0.03}} {
+		if _, err := attendance.ClockIn(ctx, v.f.UserID, &v.f.VehicleID, nil); err != nil {
+			t.Fatalf("clock in: %v", err)
+		}
+		if _, err := conn.ExecContext(ctx, `
+			INSERT INTO vehicle_location_current (vehicle_id, location, recorded_at)
+			VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326)::geography, NOW())`, v.f.VehicleID, lng, v.lat); err != nil {
+			t.Fatalf("insert location: %v", err)
+		}
+	}
+	if _, err := conn.ExecContext(ctx, `
+		INSERT INTO documents (vehicle_id, type, expires_on, created_by)
+		VALUES ($1, 'registration', CURRENT_DATE - 1, $2)`, near.VehicleID, near.UserID); err != nil {
+		t.Fatalf("insert document: %v", err)
+	}
+
+	vehicleRepo := repository.NewVehicleRepo(conn)
+	dispatchRepo := repository.NewDispatchRepo(conn)
+	dispatchSvc := NewDispatchService(dispatchRepo, vehicleRepo, nil, 5*time.Minute, nil, nil,
+		eta.NewHaversine(eta.SpeedProfile{DefaultKmh: 18}), nil, nil)
+	autoSvc := NewAutoDispatchService(dispatchSvc, dispatchRepo, vehicleRepo, nil, AutoDispatchAuto, AutoDispatchWeights{ETA: 1})
+
+	cands, _, err := autoSvc.Rank(ctx, &model.Dispatch{PickupLat: &lat, PickupLng: &lng}, nil, nil)
+	if err != nil {
+		t.Fatalf("Rank: %v", err)
+	}
+	ranked := false
+	for _, c := range cands {
+		switch c.VehicleID {
+		case near.VehicleID:
+			t.Errorf("vehicle with an expired registration was ranked")
+		case far.VehicleID:
+			ranked = true
+		}
+	}
+	if !ranked {
+		t.Errorf("the farther vehicle was not ranked")
+	}
+}
@@ -2,6 +2,9 @@ package service
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"log"
 	"time"
 
 	"github.com/kento/driver/backend/internal/dto"
@@ -13,39 +16,60 @@ import (
 
 type BookingService struct {
 	dispatchSvc     *DispatchService
+	autoDispatchSvc *AutoDispatchService
 	reservationSvc  *ReservationService
+	waitlistSvc     *WaitlistService
 	vehicleRepo     *repository.VehicleRepo
 	reservationRepo *repository.ReservationRepo
 	auditSvc        *AuditService
 	fcmSvc          *notify.FCMService
+	acceptTimeout   time.Duration
 }
 
 func NewBookingService(
 	dispatchSvc *DispatchService,
+	autoDispatchSvc *AutoDispatchService,
 	reservationSvc *ReservationService,
+	waitlistSvc *WaitlistService,
 	vehicleRepo *repository.VehicleRepo,
 	reservationRepo *repository.ReservationRepo,
 	auditSvc *AuditService,
 	fcmSvc *notify.FCMService,
+	acceptTimeout time.Duration,
 ) *BookingService {
 	return &BookingService{
 		dispatchSvc:     dispatchSvc,
+		autoDispatchSvc: autoDispatchSvc,
 		reservationSvc:  reservationSvc,
+		waitlistSvc:     waitlistSvc,
 		vehicleRepo:     vehicleRepo,
 		reservationRepo: reservationRepo,
 		auditSvc:        auditSvc,
 		fcmSvc:          fcmSvc,
+		acceptTimeout:   acceptTimeout,
 	}
 }
 
-func (s *BookingService) CreateBooking(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, priorityLevel int) (*dto.UnifiedBookingResponse, error) {
+// CreateBooking books a trip now or a reservation for later. A specific
+// vehicle must lie within the depot scope depotIDs, nil for all, and any
+// vehicle is picked from within it.
+func (s *BookingService) CreateBooking(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, priorityLevel int, depotIDs []string) (*dto.UnifiedBookingResponse, error) {
+	if req.Mode == "specific" && req.VehicleID != nil && depotIDs != nil {
+		v, err := s.vehicleRepo.GetByID(ctx, *req.VehicleID)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil && !model.InDepots(depotIDs, v.DepotID) {
+			return nil, errOutOfDepot
+		}
+	}
 	if req.IsNow {
-		return s.createNowBooking(ctx, req, requesterID)
+		return s.createNowBooking(ctx, req, requesterID, depotIDs)
 	}
-	return s.createFutureBooking(ctx, req, requesterID, priorityLevel)
+	return s.createFutureBooking(ctx, req, requesterID, priorityLevel, depotIDs)
 }
 
-func (s *BookingService) createNowBooking(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string) (*dto.UnifiedBookingResponse, error) {
+func (s *BookingService) createNowBooking(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, depotIDs []string) (*dto.UnifiedBookingResponse, error) {
 	// For immediate dispatch, use the first destination as the dropoff address
 	var dropoff *string
 	if len(req.Destinations) > 0 {
@@ -69,20 +93,34 @@ func (s *BookingService) createNowBooking(ctx context.Context, req dto.UnifiedBo
 
 	// If specific vehicle requested, assign immediately
 	if req.Mode == "specific" && req.VehicleID != nil {
-		if err := s.dispatchSvc.Assign(ctx, dispatch.ID, *req.VehicleID, requesterID); err != nil {
+		if err := s.dispatchSvc.Assign(ctx, dispatch.ID, *req.VehicleID, model.DispatchActorSystem, requesterID, depotIDs); err != nil {
 			return nil, err
 		}
 		// Re-fetch to get updated state
 		dispatch, _ = s.dispatchSvc.GetByID(ctx, dispatch.ID)
 	}
 
+	// Any vehicle: let the auto-dispatch engine rank (and maybe assign).
+	// The booking stands either way; a dispatcher can still assign by hand.
+	var auto *dto.AutoDispatchResult
+	if req.Mode == "any" {
+		auto, err = s.autoDispatchSvc.Dispatch(ctx, dispatch, requesterID, depotIDs)
+		if err != nil {
+			log.Printf("[autodispatch] dispatch %s: %v", dispatch.ID, err)
+		}
+		if auto != nil && auto.Assigned {
+			dispatch, _ = s.dispatchSvc.GetByID(ctx, dispatch.ID)
+		}
+	}
+
 	return &dto.UnifiedBookingResponse{
-		Type:     "dispatch",
-		Dispatch: dispatch,
+		Type:         "dispatch",
+		Dispatch:     dispatch,
+		AutoDispatch: auto,
 	}, nil
 }
 
-func (s *BookingService) createFutureBooking(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, priorityLevel int) (*dto.UnifiedBookingResponse, error) {
+func (s *BookingService) createFutureBooking(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, priorityLevel int, depotIDs []string) (*dto.UnifiedBookingResponse, error) {
 	if req.StartTime == nil || req.EndTime == nil {
 		return nil, apperror.New(400, "MISSING_TIME", "start_time and end_time are required for future bookings")
 	}
@@ -90,23 +128,7 @@ func (s *BookingService) createFutureBooking(ctx context.Context, req dto.Unifie
 		return nil, apperror.New(400, "MISSING_VEHICLE", "vehicle_id is required when mode is specific")
 	}
 
-	var vehicleID string
-	if req.Mode == "specific" {
-		vehicleID = *req.VehicleID
-	} else {
-		// Find an available vehicle for the time slot
-		vehicleIDs, err := s.reservationRepo.FindAvailableVehicleForSlot(ctx, *req.StartTime, *req.EndTime, nil)
-		if err != nil {
-			return nil, err
-		}
-		if len(vehicleIDs) == 0 {
-			return nil, apperror.New(404, "NO_VEHICLE_AVAILABLE", "no vehicles available for this time slot")
-		}
-		vehicleID = vehicleIDs[0]
-	}
-
 	reservation := &model.Reservation{
-		VehicleID:     vehicleID,
 		RequesterID:   requesterID,
 		StartTime:     *req.StartTime,
 		EndTime:       *req.EndTime,
@@ -121,16 +143,29 @@ func (s *BookingService) createFutureBooking(ctx context.Context, req dto.Unifie
 		Status:        model.ReservationStatusPendingDriver,
 	}
 
-	if err := s.reservationRepo.Create(ctx, reservation); err != nil {
+	if req.Mode == "specific" {
+		// A requested vehicle may already be taken; the conflict workflow
+		// decides who keeps it.
+		reservation.VehicleID = *req.VehicleID
+		if err := s.reservationSvc.Place(ctx, reservation); err != nil {
+			return nil, err
+		}
+	} else if err := placeOnFreeVehicle(ctx, s.reservationRepo, reservation, depotIDs); err != nil {
+		if err == errNoVehicle && req.Waitlist {
+			return s.joinWaitlist(ctx, req, requesterID, priorityLevel, depotIDs)
+		}
 		return nil, err
 	}
 
 	s.auditSvc.Log(ctx, requesterID, "reservation.create", "reservation", reservation.ID, nil, reservation, "")
 
-	// Notify the driver of the assigned vehicle
-	go s.fcmSvc.NotifyVehicleDriver(ctx, vehicleID, "Reservation Pending", reservation.Purpose, map[string]string{
-		"type": "reservation_pending", "reservation_id": reservation.ID,
-	})
+	// Notify the driver of the assigned vehicle; a booking queued behind a
+	// conflict waits for the resolution instead. The push outlives the request.
+	if reservation.Status == model.ReservationStatusPendingDriver {
+		go s.fcmSvc.NotifyVehicleDriver(context.WithoutCancel(ctx), reservation.VehicleID, "Reservation Pending", reservation.Purpose, map[string]string{
+			"type": "reservation_pending", "reservation_id": reservation.ID,
+		})
+	}
 
 	return &dto.UnifiedBookingResponse{
 		Type:        "reservation",
@@ -138,6 +173,55 @@ func (s *BookingService) createFutureBooking(ctx context.Context, req dto.Unifie
 	}, nil
 }
 
+// joinWaitlist queues a future booking no vehicle was free for. It is booked
+// automatically if a vehicle within the depot scope depotIDs, nil for all,
+// frees up before the slot starts.
+func (s *BookingService) joinWaitlist(ctx context.Context, req dto.UnifiedBookingRequest, requesterID string, priorityLevel int, depotIDs []string) (*dto.UnifiedBookingResponse, error) {
+	entry := &model.WaitlistEntry{
+		RequesterID:   requesterID,
+		StartTime:     *req.StartTime,
+		EndTime:       *req.EndTime,
+		Purpose:       req.Purpose,
+		Destinations:  req.Destinations,
+		Notes:         req.Notes,
+		PassengerName: req.PassengerName,
+		PickupAddress: &req.PickupAddress,
+		PickupLat:     req.PickupLat,
+		PickupLng:     req.PickupLng,
+		PriorityLevel: priorityLevel,
+		DepotIDs:      depotIDs,
+	}
+	if err := s.waitlistSvc.Join(ctx, entry); err != nil {
+		return nil, err
+	}
+	return &dto.UnifiedBookingResponse{
+		Type:     "waitlist",
+		Waitlist: entry,
+	}, nil
+}
+
+var errNoVehicle = apperror.New(404, "NO_VEHICLE_AVAILABLE", "no vehicles available for this time slot")
+
+// placeOnFreeVehicle stores res on the first vehicle within the depot scope
+// depotIDs, nil for all, free for its slot and the turnarounds around it. A
+// vehicle taken by a concurrent booking between the search and the insert is
+// skipped.
+func placeOnFreeVehicle(ctx context.Context, repo *repository.ReservationRepo, res *model.Reservation, depotIDs []string) error {
+	vehicleIDs, err := repo.FindAvailableVehicleForSlot(ctx, res.StartTime, res.EndTime, nil, depotIDs)
+	if err != nil {
+		return err
+	}
+	for _, id := range vehicleIDs {
+		res.VehicleID = id
+		err := repo.Create(ctx, res)
+		if errors.Is(err, repository.ErrReservationOverlap) {
+			continue
+		}
+		return err
+	}
+	return errNoVehicle
+}
+
 func (s *BookingService) DriverAcceptReservation(ctx context.Context, reservationID, driverID string) error {
 	res, err := s.reservationRepo.GetByID(ctx, reservationID)
 	if err != nil {
@@ -165,8 +249,9 @@ func (s *BookingService) DriverAcceptReservation(ctx context.Context, reservatio
 
 	s.auditSvc.Log(ctx, driverID, "reservation.driver_accept", "reservation", reservationID, res, nil, "")
 
-	// Notify the requester that their reservation was accepted
-	go s.fcmSvc.NotifyUser(ctx, res.RequesterID, "Reservation Confirmed", res.Purpose, map[string]string{
+	// Notify the requester that their reservation was accepted; the push
+	// outlives the request
+	go s.fcmSvc.NotifyUser(context.WithoutCancel(ctx), res.RequesterID, "Reservation Confirmed", res.Purpose, map[string]string{
 		"type": "reservation_confirmed", "reservation_id": reservationID,
 	})
 
@@ -204,7 +289,13 @@ func (s *BookingService) DriverDeclineReservation(ctx context.Context, reservati
 	s.auditSvc.Log(ctx, driverID, "reservation.driver_decline", "reservation", reservationID, res, nil, reason)
 
 	// Auto-reassign to next available vehicle
-	return s.autoReassign(ctx, reservationID, res)
+	if err := s.autoReassign(ctx, reservationID, res); err != nil {
+		return err
+	}
+
+	// Either way the declining vehicle is now free for the slot
+	s.waitlistSvc.SlotReleased(ctx, res.StartTime, res.EndTime)
+	return nil
 }
 
 func (s *BookingService) autoReassign(ctx context.Context, reservationID string, res *model.Reservation) error {
@@ -214,25 +305,163 @@ func (s *BookingService) autoReassign(ctx context.Context, reservationID string,
 		excludeIDs = append(excludeIDs, id)
 	}
 
-	vehicleIDs, err := s.reservationRepo.FindAvailableVehicleForSlot(ctx, res.StartTime, res.EndTime, excludeIDs)
+	// The reservation stays with the declining vehicle's depot
+	var depotIDs []string
+	v, err := s.vehicleRepo.GetByID(ctx, res.VehicleID)
 	if err != nil {
 		return err
 	}
+	if v != nil && v.DepotID != nil {
+		depotIDs = []string{*v.DepotID}
+	}
 
-	if len(vehicleIDs) == 0 {
-		// No vehicles available - mark as driver_declined (terminal)
-		return s.reservationRepo.UpdateStatus(ctx, reservationID, model.ReservationStatusDriverDeclined)
+	vehicleIDs, err := s.reservationRepo.FindAvailableVehicleForSlot(ctx, res.StartTime, res.EndTime, excludeIDs, depotIDs)
+	if err != nil {
+		return err
+	}
+
+	// Reassign to the first vehicle still free; one taken since the search
+	// is skipped
+	for _, id := range vehicleIDs {
+		err := s.reservationRepo.UpdateVehicle(ctx, reservationID, id)
+		if errors.Is(err, repository.ErrReservationOverlap) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+
+		// Notify the new driver; the push outlives the request
+		go s.fcmSvc.NotifyVehicleDriver(context.WithoutCancel(ctx), id, "Reservation Pending", res.Purpose, map[string]string{
+			"type": "reservation_pending", "reservation_id": reservationID,
+		})
+		return nil
 	}
 
-	// Reassign to the first available vehicle
-	if err := s.reservationRepo.UpdateVehicle(ctx, reservationID, vehicleIDs[0]); err != nil {
+	// No vehicles available - mark as driver_declined (terminal)
+	return s.reservationRepo.UpdateStatus(ctx, reservationID, model.ReservationStatusDriverDeclined)
+}
+
+// DriverDeclineDispatch lets a driver turn down a trip offered to their
+// vehicle. The trip moves on to the next-best vehicle, or to the dispatchers
+// when it was made from a reservation.
+func (s *BookingService) DriverDeclineDispatch(ctx context.Context, dispatchID, driverID, reason string) error {
+	d, err := s.dispatchSvc.GetByID(ctx, dispatchID)
+	if err != nil {
 		return err
 	}
+	if d == nil {
+		return apperror.ErrNotFound
+	}
+	if d.Status != model.DispatchStatusAssigned {
+		return apperror.New(400, "INVALID_STATUS", "trip is not awaiting driver acceptance")
+	}
 
-	// Notify the new driver
-	go s.fcmSvc.NotifyVehicleDriver(ctx, vehicleIDs[0], "Reservation Pending", res.Purpose, map[string]string{
-		"type": "reservation_pending", "reservation_id": reservationID,
-	})
+	// Verify the vehicle belongs to this driver
+	vehicle, err := s.vehicleRepo.GetByDriverID(ctx, driverID)
+	if err != nil {
+		return err
+	}
+	if vehicle == nil || d.VehicleID == nil || vehicle.ID != *d.VehicleID {
+		return apperror.New(403, "NOT_YOUR_VEHICLE", "this trip is not assigned to your vehicle")
+	}
+
+	if err := s.dispatchSvc.ReleaseOffer(ctx, dispatchID, vehicle.ID, model.DispatchActorDriver, driverID, "dispatch.driver_decline", reason); err != nil {
+		return err
+	}
+	return s.autoReassignDispatch(ctx, dispatchID, driverID)
+}
+
+// ExpireDispatchOffers treats trips a driver has not accepted within the
+// acceptance timeout as declined and moves them on. Returns how many offers
+// expired.
+func (s *BookingService) ExpireDispatchOffers(ctx context.Context) (int, error) {
+	if s.acceptTimeout <= 0 {
+		return 0, nil
+	}
+	offers, err := s.dispatchSvc.ListExpiredOffers(ctx, time.Now().Add(-s.acceptTimeout))
+	if err != nil {
+		return 0, err
+	}
+
+	reason := fmt.Sprintf("not accepted within %s", s.acceptTimeout)
+	expired := 0
+	for _, d := range offers {
+		vehicle, err := s.vehicleRepo.GetByID(ctx, *d.VehicleID)
+		if err != nil || vehicle == nil {
+			log.Printf("[dispatch] expire offer %s: vehicle lookup: %v", d.ID, err)
+			continue
+		}
+
+		// The silent driver is recorded as the one who let the offer go. Drivers
+		// cannot clock out on an offer, so someone normally holds the vehicle;
+		// otherwise the system let it go.
+		actorID := ""
+		if vehicle.DriverID != nil {
+			actorID = *vehicle.DriverID
+		}
+		err = s.dispatchSvc.ReleaseOffer(ctx, d.ID, vehicle.ID, model.DispatchActorSystem, actorID, "dispatch.offer_timeout", reason)
+		var appErr *apperror.AppError
+		if errors.As(err, &appErr) && appErr.Code == "OFFER_CLOSED" {
+			continue // accepted at the last moment
+		}
+		if err != nil {
+			return expired, err
+		}
+		expired++
+
+		if vehicle.DriverID != nil {
+			// The job's context ends when it returns, before the push is sent
+			go s.fcmSvc.NotifyUser(context.WithoutCancel(ctx), *vehicle.DriverID, "Trip Offer Expired", d.Purpose, map[string]string{
+				"type": "dispatch_offer_expired", "dispatch_id": d.ID,
+			})
+		}
+
+		if err := s.autoReassignDispatch(ctx, d.ID, actorID); err != nil {
+			log.Printf("[dispatch] reassign %s after timeout: %v", d.ID, err)
+		}
+	}
+	return expired, nil
+}
+
+// autoReassignDispatch offers a released dispatch to the next-best vehicle.
+// When the engine finds nobody (or only suggests), dispatchers are alerted
+// to assign it by hand. A trip made from a reservation always goes to them:
+// the reservation still holds the declining vehicle, and moving the trip
+// alone would leave the two apart.
+func (s *BookingService) autoReassignDispatch(ctx context.Context, dispatchID, actorID string) error {
+	d, err := s.dispatchSvc.GetByID(ctx, dispatchID)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return apperror.ErrNotFound
+	}
+
+	var result *dto.AutoDispatchResult
+	var reason string
+	if d.ReservationID != nil {
+		reason = fmt.Sprintf("declined by %d vehicle(s), reservation %s still holds the vehicle", len(d.DeclinedVehicleIDs), *d.ReservationID)
+	} else {
+		result, err = s.autoDispatchSvc.Redispatch(ctx, d, actorID)
+		if err != nil {
+			return err
+		}
+		if result != nil && result.Assigned {
+			return nil
+		}
+
+		reason = fmt.Sprintf("declined by %d vehicle(s), no vehicle left to offer", len(d.DeclinedVehicleIDs))
+		if result != nil && result.VehicleID != nil {
+			reason = fmt.Sprintf("declined by %d vehicle(s), suggested next: %s", len(d.DeclinedVehicleIDs), result.Candidates[0].VehicleName)
+		}
+	}
+	s.auditSvc.Log(ctx, actorID, "dispatch.escalate", "dispatch", dispatchID, nil, result, reason)
+
+	// Outlives the request or offer_timeout run that escalated
+	go s.fcmSvc.NotifyRole(context.WithoutCancel(ctx), "Dispatch Needs a Vehicle", reason, map[string]string{
+		"type": "dispatch_escalated", "dispatch_id": dispatchID,
+	}, model.RoleAdmin, model.RoleDispatcher)
 
 	return nil
 }
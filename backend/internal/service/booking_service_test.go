@@ -0,0 +1,146 @@
+package service
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/kento/driver/backend/internal/db"
+	"github.com/kento/driver/backend/internal/eta"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/notify"
+	"github.com/kento/driver/backend/internal/realtime"
+	"github.com/kento/driver/backend/internal/repository"
+)
+
+// TestDriverDeclineDispatch_ReservationTrip declines the trip made from a
+// reservation while another vehicle stands free right at the pickup. The
+// trip must go back to the dispatchers rather than to that vehicle, since
+// the reservation still holds the declining one. Needs a migrated Postgres
+// in TEST_DATABASE_URL.
+func TestDriverDeclineDispatch_ReservationTrip(t *testing.T) {
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	conn, err := db.Connect(url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	defer conn.Close()
+	if err := db.RunMigrations(conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+	var requesterID, declinerID, otherID, declinedVehicle, freeVehicle string
+	for _, u := range []struct {
+		id   *string
+		role model.Role
+	}{{&requesterID, model.RoleDispatcher}, {&declinerID, model.RoleDriver}, {&otherID, model.RoleDriver}} {
+		if err := conn.GetContext(ctx, u.id, `
+			INSERT INTO users (employee_id, password_hash, name, role)
+			VALUES ($1, 'x', 'Decline Test', $2) RETURNING id`, "decline-"+uuid.NewString()[:8], u.role); err != nil {
+			t.Fatalf("insert user: %v", err)
+		}
+	}
+	for plate, id := range map[string]*string{"DCL-" + suffix: &declinedVehicle, "FRE-" + suffix: &freeVehicle} {
+		if err := conn.GetContext(ctx, id, `
+			INSERT INTO vehicles (name, license_plate)
+			VALUES ('Decline Test', $1) RETURNING id`, plate); err != nil {
+			t.Fatalf("insert vehicle: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		vehicles := []interface{}{declinedVehicle, freeVehicle}
+		users := []interface{}{requesterID, declinerID, otherID}
+		conn.Exec(`DELETE FROM audit_logs WHERE actor_id IN ($1, $2, $3)`, users...)
+		conn.Exec(`DELETE FROM dispatches WHERE requester_id = $1`, requesterID)
+		conn.Exec(`DELETE FROM reservations WHERE requester_id = $1`, requesterID)
+		conn.Exec(`DELETE FROM vehicle_location_current WHERE vehicle_id IN ($1, $2)`, vehicles...)
+		conn.Exec(`DELETE FROM driver_attendance WHERE vehicle_id IN ($1, $2)`, vehicles...)
+		conn.Exec(`DELETE FROM vehicles WHERE id IN ($1, $2)`, vehicles...)
+		conn.Exec(`DELETE FROM users WHERE id IN ($1, $2, $3)`, users...)
+	})
+
+	attendance := repository.NewAttendanceRepo(conn)
+	if _, err := attendance.ClockIn(ctx, declinerID, &declinedVehicle, nil); err != nil {
+		t.Fatalf("clock in: %v", err)
+	}
+	if _, err := attendance.ClockIn(ctx, otherID, &freeVehicle, nil); err != nil {
+		t.Fatalf("clock in: %v", err)
+	}
+	lat, lng := 35.6895, 139.6917
+	if _, err := conn.ExecContext(ctx, `
+		INSERT INTO vehicle_location_current (vehicle_id, location, recorded_at)
+		VALUES ($1, ST_SetSRID(ST_MakePoint($2, $3), 4326)::geography, NOW())`, freeVehicle, lng, lat); err != nil {
+		t.Fatalf("insert location: %v", err)
+	}
+
+	userRepo := repository.NewUserRepo(conn)
+	vehicleRepo := repository.NewVehicleRepo(conn)
+	dispatchRepo := repository.NewDispatchRepo(conn)
+	reservationRepo := repository.NewReservationRepo(conn)
+	auditSvc := NewAuditService(repository.NewAuditRepo(conn))
+	fcmSvc, _ := notify.NewFCMService("", userRepo)
+	hub := realtime.NewHub()
+	defer hub.Close()
+	waitlistSvc := NewWaitlistService(repository.NewWaitlistRepo(conn), reservationRepo, NewAuthorizer(repository.NewVehicleRepo(conn), auditSvc), auditSvc, fcmSvc)
+	reservationSvc := NewReservationService(reservationRepo, repository.NewConflictRepo(conn), vehicleRepo,
+		repository.NewMaintenanceRepo(conn), dispatchRepo, waitlistSvc, auditSvc, hub, nil, time.Second)
+	dispatchSvc := NewDispatchService(dispatchRepo, vehicleRepo, auditSvc, 5*time.Minute, fcmSvc, hub,
+		eta.NewHaversine(eta.SpeedProfile{DefaultKmh: 18}), NewAuthorizer(vehicleRepo, auditSvc), reservationSvc)
+	autoSvc := NewAutoDispatchService(dispatchSvc, dispatchRepo, vehicleRepo, auditSvc, AutoDispatchAuto, AutoDispatchWeights{ETA: 1})
+	bookingSvc := NewBookingService(dispatchSvc, autoSvc, reservationSvc, waitlistSvc, vehicleRepo, reservationRepo, auditSvc, fcmSvc, time.Minute)
+
+	start := time.Now().Add(10 * time.Minute).Truncate(time.Minute)
+	res := &model.Reservation{
+		VehicleID:   declinedVehicle,
+		RequesterID: requesterID,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Purpose:     "decline test",
+		PickupLat:   &lat,
+		PickupLng:   &lng,
+		Status:      model.ReservationStatusConfirmed,
+	}
+	if err := reservationRepo.Create(ctx, res); err != nil {
+		t.Fatalf("create reservation: %v", err)
+	}
+	trip := &model.Dispatch{
+		RequesterID:    requesterID,
+		Purpose:        res.Purpose,
+		PassengerCount: 1,
+		PickupAddress:  "(reservation)",
+		PickupLat:      &lat,
+		PickupLng:      &lng,
+		VehicleID:      &declinedVehicle,
+		ReservationID:  &res.ID,
+	}
+	if created, err := dispatchRepo.CreateForReservation(ctx, trip); err != nil || !created {
+		t.Fatalf("create trip: created=%v err=%v", created, err)
+	}
+
+	if err := bookingSvc.DriverDeclineDispatch(ctx, trip.ID, declinerID, "flat tyre"); err != nil {
+		t.Fatalf("DriverDeclineDispatch: %v", err)
+	}
+
+	d, err := dispatchRepo.GetByID(ctx, trip.ID)
+	if err != nil {
+		t.Fatalf("get trip: %v", err)
+	}
+	if d.Status != model.DispatchStatusPending || d.VehicleID != nil {
+		t.Errorf("trip is %s on %v; want pending without a vehicle", d.Status, d.VehicleID)
+	}
+	after, err := reservationRepo.GetByID(ctx, res.ID)
+	if err != nil {
+		t.Fatalf("get reservation: %v", err)
+	}
+	if after.VehicleID != declinedVehicle || after.Status != model.ReservationStatusConfirmed {
+		t.Errorf("reservation is %s on %s; want it confirmed on the declining vehicle", after.Status, after.VehicleID)
+	}
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/kento/driver/backend/internal/ical"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/policy"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+// Feeds cover a month back, so recent trips stay visible, and half a year
+// ahead. Clients are asked to poll every 15 minutes.
+const (
+	feedPast    = 30 * 24 * time.Hour
+	feedAhead   = 180 * 24 * time.Hour
+	feedRefresh = 15 * time.Minute
+)
+
+// CalendarService issues private iCalendar feed URLs and renders them.
+type CalendarService struct {
+	repo            *repository.CalendarRepo
+	reservationRepo *repository.ReservationRepo
+	vehicleRepo     *repository.VehicleRepo
+	userRepo        *repository.UserRepo
+	authz           *Authorizer
+	auditSvc        *AuditService
+}
+
+func NewCalendarService(repo *repository.CalendarRepo, reservationRepo *repository.ReservationRepo, vehicleRepo *repository.VehicleRepo, userRepo *repository.UserRepo, authz *Authorizer, auditSvc *AuditService) *CalendarService {
+	return &CalendarService{
+		repo:            repo,
+		reservationRepo: reservationRepo,
+		vehicleRepo:     vehicleRepo,
+		userRepo:        userRepo,
+		authz:           authz,
+		auditSvc:        auditSvc,
+	}
+}
+
+// CreateFeed issues a new feed of feedType for subjectID. An empty subjectID
+// means the caller's own schedule: their vehicle, or themselves.
+func (s *CalendarService) CreateFeed(ctx context.Context, sub policy.Subject, feedType model.CalendarFeedType, subjectID string) (*model.CalendarFeed, error) {
+	if !feedType.IsValid() {
+		return nil, apperror.New(400, "INVALID_FEED_TYPE", "type must be vehicle, driver or requester")
+	}
+
+	if subjectID == "" {
+		subjectID = sub.UserID
+		if feedType == model.CalendarFeedVehicle {
+			v, err := s.vehicleRepo.GetByDriverID(ctx, sub.UserID)
+			if err != nil {
+				return nil, err
+			}
+			if v == nil {
+				return nil, apperror.New(400, "MISSING_SUBJECT", "subject_id is required for vehicle feeds")
+			}
+			subjectID = v.ID
+		}
+	}
+
+	ownerID, _, err := s.resolve(ctx, feedType, subjectID)
+	if err != nil {
+		return nil, err
+	}
+	f := policy.CalendarFeed{Type: feedType, OwnerID: ownerID}
+	if err := s.authz.CalendarFeed(ctx, sub, policy.CalendarSubscribe, f, string(feedType), subjectID); err != nil {
+		return nil, err
+	}
+
+	token, err := newFeedToken()
+	if err != nil {
+		return nil, err
+	}
+	feed := &model.CalendarFeed{
+		Token:       token,
+		SubjectType: feedType,
+		SubjectID:   subjectID,
+		CreatedBy:   sub.UserID,
+	}
+	if err := s.repo.Create(ctx, feed); err != nil {
+		return nil, err
+	}
+
+	// The token is a credential; keep it out of the audit log
+	s.auditSvc.Log(ctx, sub.UserID, "calendar_feed.create", "calendar_feed", feed.ID, nil,
+		map[string]string{"subject_type": string(feedType), "subject_id": subjectID}, "")
+	return feed, nil
+}
+
+func (s *CalendarService) ListFeeds(ctx context.Context, userID string) ([]model.CalendarFeed, error) {
+	return s.repo.ListByCreator(ctx, userID)
+}
+
+func (s *CalendarService) RevokeFeed(ctx context.Context, sub policy.Subject, id string) error {
+	feed, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if feed == nil || feed.RevokedAt != nil {
+		return apperror.ErrNotFound
+	}
+	f := policy.CalendarFeed{Type: feed.SubjectType, CreatedBy: feed.CreatedBy}
+	if err := s.authz.CalendarFeed(ctx, sub, policy.CalendarRevoke, f, "calendar_feed", id); err != nil {
+		return err
+	}
+	if err := s.repo.Revoke(ctx, id); err != nil {
+		return err
+	}
+	s.auditSvc.Log(ctx, sub.UserID, "calendar_feed.revoke", "calendar_feed", id,
+		map[string]string{"subject_type": string(feed.SubjectType), "subject_id": feed.SubjectID}, nil, "")
+	return nil
+}
+
+// Feed renders the calendar behind token, or returns nil when there is no
+// such active feed.
+func (s *CalendarService) Feed(ctx context.Context, token string) (*ical.Calendar, error) {
+	feed, err := s.repo.GetActiveByToken(ctx, token)
+	if err != nil || feed == nil {
+		return nil, err
+	}
+	_, name, err := s.resolve(ctx, feed.SubjectType, feed.SubjectID)
+	if err != nil {
+		return nil, err
+	}
+
+	var vehicleID, requesterID, driverID string
+	switch feed.SubjectType {
+	case model.CalendarFeedVehicle:
+		vehicleID = feed.SubjectID
+	case model.CalendarFeedRequester:
+		requesterID = feed.SubjectID
+	case model.CalendarFeedDriver:
+		// The trips handed to the driver on their own shifts, not whatever
+		// the vehicle they hold right now is booked for
+		driverID = feed.SubjectID
+	}
+
+	now := time.Now()
+	reservations, err := s.reservationRepo.ListFeed(ctx, vehicleID, requesterID, driverID, now.Add(-feedPast), now.Add(feedAhead))
+	if err != nil {
+		return nil, err
+	}
+
+	cal := &ical.Calendar{ProdID: feedProdID, Name: name, Refresh: feedRefresh}
+	for i := range reservations {
+		cal.Events = append(cal.Events, reservationEvent(&reservations[i], feed.SubjectType))
+	}
+	return cal, nil
+}
+
+const feedProdID = "-//fleettrack//Reservations//EN"
+
+// resolve returns whose schedule a feed subject is and the feed's name. A
+// missing subject is a 404.
+func (s *CalendarService) resolve(ctx context.Context, feedType model.CalendarFeedType, subjectID string) (ownerID, name string, err error) {
+	if feedType == model.CalendarFeedVehicle {
+		v, err := s.vehicleRepo.GetByID(ctx, subjectID)
+		if err != nil {
+			return "", "", err
+		}
+		if v == nil {
+			return "", "", apperror.New(404, "NOT_FOUND", "vehicle not found")
+		}
+		var ownerID string
+		if v.DriverID != nil {
+			ownerID = *v.DriverID
+		}
+		return ownerID, v.Name + " reservations", nil
+	}
+
+	u, err := s.userRepo.GetByID(ctx, subjectID)
+	if err != nil {
+		return "", "", err
+	}
+	if u == nil {
+		return "", "", apperror.New(404, "NOT_FOUND", "user not found")
+	}
+	if feedType == model.CalendarFeedDriver {
+		if u.Role != model.RoleDriver {
+			return "", "", apperror.New(400, "NOT_A_DRIVER", "user is not a driver")
+		}
+		return u.ID, u.Name + " trips", nil
+	}
+	return u.ID, u.Name + " bookings", nil
+}
+
+func reservationEvent(r *model.ReservationWithDetails, feedType model.CalendarFeedType) ical.Event {
+	// Vehicle and driver feeds say who booked; a requester's says which car
+	summary := r.Purpose + " - " + r.RequesterName
+	if feedType == model.CalendarFeedRequester {
+		summary = r.Purpose + " - " + r.VehicleName
+	}
+
+	lines := []string{
+		"Status: " + string(r.Status),
+		"Vehicle: " + r.VehicleName,
+		"Requested by: " + r.RequesterName,
+	}
+	if r.PassengerName != nil && *r.PassengerName != "" {
+		lines = append(lines, "Passenger: "+*r.PassengerName)
+	}
+	if r.PickupAddress != nil && *r.PickupAddress != "" {
+		lines = append(lines, "Pickup: "+*r.PickupAddress)
+	}
+	for i, d := range r.Destinations {
+		if len(r.Destinations) == 1 {
+			lines = append(lines, "Destination: "+d)
+			break
+		}
+		lines = append(lines, "Destination "+strconv.Itoa(i+1)+": "+d)
+	}
+	if r.Notes != nil && *r.Notes != "" {
+		lines = append(lines, "Notes: "+*r.Notes)
+	}
+	if r.CancelReason != nil && *r.CancelReason != "" {
+		lines = append(lines, "Cancelled: "+*r.CancelReason)
+	}
+
+	var location string
+	if r.PickupAddress != nil {
+		location = *r.PickupAddress
+	}
+
+	return ical.Event{
+		UID: r.ID + "@fleettrack",
+		// Grows with every update, as clients need to replace their copy
+		Sequence:    int(r.UpdatedAt.Sub(r.CreatedAt) / time.Second),
+		Stamp:       r.UpdatedAt,
+		Start:       r.StartTime,
+		End:         r.EndTime,
+		Summary:     summary,
+		Location:    location,
+		Description: strings.Join(lines, "\n"),
+		Status:      eventStatus(r.Status),
+	}
+}
+
+func eventStatus(s model.ReservationStatus) ical.Status {
+	switch s {
+	case model.ReservationStatusPendingDriver, model.ReservationStatusPendingConflict:
+		return ical.StatusTentative
+	case model.ReservationStatusCancelled, model.ReservationStatusDriverDeclined:
+		return ical.StatusCancelled
+	}
+	return ical.StatusConfirmed
+}
+
+// newFeedToken returns 256 random bits, URL-safe.
+func newFeedToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -2,123 +2,201 @@ package service
 
 import (
 	"context"
+	"sort"
+	"time"
 
+	"github.com/kento/driver/backend/internal/dto"
 	"github.com/kento/driver/backend/internal/model"
 	"github.com/kento/driver/backend/internal/repository"
 	"github.com/kento/driver/backend/pkg/apperror"
 )
 
+// Suggestions look a day either side of the conflict for free slots and
+// return the few best of each kind.
+const (
+	suggestionWindow   = 24 * time.Hour
+	suggestionVehicles = 5
+	suggestionSlots    = 4
+)
+
+// ConflictService settles conflicts. Every change it makes to a reservation
+// goes through ReservationService, so a resolution that lands in another
+// clash files a new conflict instead of double-booking. A conflict is only
+// resolved once its change is saved.
 type ConflictService struct {
 	conflictRepo    *repository.ConflictRepo
 	reservationRepo *repository.ReservationRepo
+	vehicleRepo     *repository.VehicleRepo
+	maintenanceRepo *repository.MaintenanceRepo
+	reservationSvc  *ReservationService
 	auditSvc        *AuditService
 }
 
-func NewConflictService(conflictRepo *repository.ConflictRepo, reservationRepo *repository.ReservationRepo, auditSvc *AuditService) *ConflictService {
+func NewConflictService(conflictRepo *repository.ConflictRepo, reservationRepo *repository.ReservationRepo, vehicleRepo *repository.VehicleRepo, maintenanceRepo *repository.MaintenanceRepo, reservationSvc *ReservationService, auditSvc *AuditService) *ConflictService {
 	return &ConflictService{
 		conflictRepo:    conflictRepo,
 		reservationRepo: reservationRepo,
+		vehicleRepo:     vehicleRepo,
+		maintenanceRepo: maintenanceRepo,
+		reservationSvc:  reservationSvc,
 		auditSvc:        auditSvc,
 	}
 }
 
-func (s *ConflictService) ListPending(ctx context.Context) ([]model.ReservationConflict, error) {
-	return s.conflictRepo.ListPending(ctx)
+// ListPending returns the conflict queue within the depot scope depotIDs,
+// nil for all.
+func (s *ConflictService) ListPending(ctx context.Context, depotIDs []string) ([]model.ReservationConflict, error) {
+	return s.conflictRepo.ListPending(ctx, depotIDs)
 }
 
-func (s *ConflictService) GetByID(ctx context.Context, id string) (*model.ReservationConflict, error) {
-	return s.conflictRepo.GetByID(ctx, id)
+// GetByID returns the conflict, or nil when it does not exist or its losing
+// reservation lies outside the depot scope depotIDs, nil for all.
+func (s *ConflictService) GetByID(ctx context.Context, id string, depotIDs []string) (*model.ReservationConflict, error) {
+	conflict, err := s.conflictRepo.GetByID(ctx, id)
+	if err != nil || conflict == nil {
+		return nil, err
+	}
+	if err := s.checkScope(ctx, conflict, depotIDs); err == errOutOfDepot {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return conflict, nil
 }
 
-func (s *ConflictService) ResolveReassign(ctx context.Context, conflictID, newVehicleID, resolvedBy, reason string) error {
-	conflict, err := s.conflictRepo.GetByID(ctx, conflictID)
+// pending returns the conflict if it is still open and lies within the
+// depot scope depotIDs.
+func (s *ConflictService) pending(ctx context.Context, id string, depotIDs []string) (*model.ReservationConflict, error) {
+	conflict, err := s.conflictRepo.GetByID(ctx, id)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	if conflict == nil {
-		return apperror.ErrNotFound
+		return nil, apperror.ErrNotFound
+	}
+	if err := s.checkScope(ctx, conflict, depotIDs); err != nil {
+		return nil, err
 	}
 	if conflict.Status != model.ConflictStatusPending {
-		return apperror.New(400, "ALREADY_RESOLVED", "conflict is already resolved")
+		return nil, apperror.New(400, "ALREADY_RESOLVED", "conflict is already resolved")
 	}
+	return conflict, nil
+}
 
-	losingRes, err := s.reservationRepo.GetByID(ctx, conflict.LosingReservationID)
-	if err != nil {
-		return err
+// checkScope refuses a conflict whose losing reservation's vehicle belongs
+// to a depot outside depotIDs, the same way the conflict queue is filtered.
+func (s *ConflictService) checkScope(ctx context.Context, conflict *model.ReservationConflict, depotIDs []string) error {
+	if depotIDs == nil {
+		return nil
 	}
-
-	// Update the losing reservation's vehicle
-	losingRes.VehicleID = newVehicleID
-	if err := s.reservationRepo.Update(ctx, losingRes); err != nil {
+	res, err := s.reservationRepo.GetByID(ctx, conflict.LosingReservationID)
+	if err != nil || res == nil {
 		return err
 	}
+	return s.checkVehicleScope(ctx, res.VehicleID, depotIDs)
+}
 
-	// Mark losing reservation as confirmed
-	if err := s.reservationRepo.UpdateStatus(ctx, conflict.LosingReservationID, model.ReservationStatusConfirmed); err != nil {
-		return err
+// checkVehicleScope refuses a vehicle of a depot outside depotIDs. Unknown
+// vehicles are left to the caller.
+func (s *ConflictService) checkVehicleScope(ctx context.Context, vehicleID string, depotIDs []string) error {
+	if depotIDs == nil {
+		return nil
 	}
-
-	// Mark winning reservation as confirmed (if it was pending_conflict)
-	_ = s.reservationRepo.UpdateStatus(ctx, conflict.WinningReservationID, model.ReservationStatusConfirmed)
-
-	// Resolve the conflict
-	if err := s.conflictRepo.Resolve(ctx, conflictID, resolvedBy, reason, model.ConflictStatusResolvedReassign); err != nil {
+	v, err := s.vehicleRepo.GetByID(ctx, vehicleID)
+	if err != nil {
 		return err
 	}
-
-	s.auditSvc.Log(ctx, resolvedBy, "conflict.resolve_reassign", "conflict", conflictID, conflict, nil, reason)
+	if v != nil && !model.InDepots(depotIDs, v.DepotID) {
+		return errOutOfDepot
+	}
 	return nil
 }
 
-func (s *ConflictService) ResolveChangeTime(ctx context.Context, conflictID, resolvedBy, reason string, losingRes *model.Reservation) error {
-	conflict, err := s.conflictRepo.GetByID(ctx, conflictID)
+// ResolveReassign moves the losing reservation to newVehicleID. Both the
+// conflict and the new vehicle must lie within the depot scope depotIDs.
+func (s *ConflictService) ResolveReassign(ctx context.Context, conflictID, newVehicleID, resolvedBy, reason string, depotIDs []string) error {
+	conflict, err := s.pending(ctx, conflictID, depotIDs)
 	if err != nil {
 		return err
 	}
-	if conflict == nil {
+
+	losingRes, err := s.reservationRepo.GetByID(ctx, conflict.LosingReservationID)
+	if err != nil {
+		return err
+	}
+	if losingRes == nil {
 		return apperror.ErrNotFound
 	}
-	if conflict.Status != model.ConflictStatusPending {
-		return apperror.New(400, "ALREADY_RESOLVED", "conflict is already resolved")
+	v, err := s.vehicleRepo.GetByID(ctx, newVehicleID)
+	if err != nil {
+		return err
 	}
-
-	if err := s.reservationRepo.Update(ctx, losingRes); err != nil {
+	if v == nil {
+		return apperror.New(400, "INVALID_VEHICLE", "vehicle not found")
+	}
+	if !model.InDepots(depotIDs, v.DepotID) {
+		return errOutOfDepot
+	}
+	// The vehicle must stay roadworthy until the reservation ends, as
+	// FindAvailableVehicleForSlot and ListFreeVehicles require
+	expired, err := s.vehicleRepo.ExpiredDocumentsBy(ctx, newVehicleID, losingRes.EndTime)
+	if err != nil {
 		return err
 	}
-
-	if err := s.reservationRepo.UpdateStatus(ctx, conflict.LosingReservationID, model.ReservationStatusConfirmed); err != nil {
+	if err := documentsExpired(expired); err != nil {
 		return err
 	}
 
-	_ = s.reservationRepo.UpdateStatus(ctx, conflict.WinningReservationID, model.ReservationStatusConfirmed)
+	losingRes.VehicleID = newVehicleID
+	if err := s.reservationSvc.checkMaintenance(ctx, losingRes); err != nil {
+		return err
+	}
 
-	if err := s.conflictRepo.Resolve(ctx, conflictID, resolvedBy, reason, model.ConflictStatusResolvedChanged); err != nil {
+	st := &settlement{conflictID: conflictID, status: model.ConflictStatusResolvedReassign, reason: reason}
+	if err := s.reservationSvc.reschedule(ctx, losingRes, resolvedBy, st); err != nil {
+		return err
+	}
+	if err := s.recheck(ctx, conflict, resolvedBy); err != nil {
 		return err
 	}
 
-	s.auditSvc.Log(ctx, resolvedBy, "conflict.resolve_change_time", "conflict", conflictID, conflict, losingRes, reason)
+	s.auditSvc.Log(ctx, resolvedBy, "conflict.resolve_reassign", "conflict", conflictID, conflict, losingRes, reason)
 	return nil
 }
 
-func (s *ConflictService) ResolveCancel(ctx context.Context, conflictID, resolvedBy, reason string) error {
-	conflict, err := s.conflictRepo.GetByID(ctx, conflictID)
+func (s *ConflictService) ResolveChangeTime(ctx context.Context, conflictID, resolvedBy, reason string, losingRes *model.Reservation, depotIDs []string) error {
+	if !losingRes.EndTime.After(losingRes.StartTime) {
+		return apperror.New(400, "INVALID_TIME_RANGE", "end_time must be after start_time")
+	}
+	conflict, err := s.pending(ctx, conflictID, depotIDs)
 	if err != nil {
 		return err
 	}
-	if conflict == nil {
-		return apperror.ErrNotFound
-	}
-	if conflict.Status != model.ConflictStatusPending {
-		return apperror.New(400, "ALREADY_RESOLVED", "conflict is already resolved")
+	// Checked even when the slot does not move, which saves it unchecked
+	if err := s.reservationSvc.checkMaintenance(ctx, losingRes); err != nil {
+		return err
 	}
 
-	if err := s.reservationRepo.Cancel(ctx, conflict.LosingReservationID, resolvedBy, reason); err != nil {
+	st := &settlement{conflictID: conflictID, status: model.ConflictStatusResolvedChanged, reason: reason}
+	if err := s.reservationSvc.reschedule(ctx, losingRes, resolvedBy, st); err != nil {
+		return err
+	}
+	if err := s.recheck(ctx, conflict, resolvedBy); err != nil {
 		return err
 	}
 
-	_ = s.reservationRepo.UpdateStatus(ctx, conflict.WinningReservationID, model.ReservationStatusConfirmed)
+	s.auditSvc.Log(ctx, resolvedBy, "conflict.resolve_change_time", "conflict", conflictID, conflict, losingRes, reason)
+	return nil
+}
+
+func (s *ConflictService) ResolveCancel(ctx context.Context, conflictID, resolvedBy, reason string, depotIDs []string) error {
+	conflict, err := s.pending(ctx, conflictID, depotIDs)
+	if err != nil {
+		return err
+	}
 
-	if err := s.conflictRepo.Resolve(ctx, conflictID, resolvedBy, reason, model.ConflictStatusResolvedCancelled); err != nil {
+	st := &settlement{conflictID: conflictID, status: model.ConflictStatusResolvedCancelled, reason: reason}
+	if err := s.reservationSvc.cancelInFavourOf(ctx, conflict.LosingReservationID, conflict.Other(conflict.LosingReservationID), resolvedBy, reason, st); err != nil {
 		return err
 	}
 
@@ -126,34 +204,148 @@ func (s *ConflictService) ResolveCancel(ctx context.Context, conflictID, resolve
 	return nil
 }
 
-func (s *ConflictService) ForceAssign(ctx context.Context, conflictID, resolvedBy, reason string) error {
+func (s *ConflictService) ForceAssign(ctx context.Context, conflictID, resolvedBy, reason string, depotIDs []string) error {
 	if reason == "" {
 		return apperror.New(400, "REASON_REQUIRED", "reason is required for force assign")
 	}
 
-	conflict, err := s.conflictRepo.GetByID(ctx, conflictID)
+	conflict, err := s.pending(ctx, conflictID, depotIDs)
 	if err != nil {
 		return err
 	}
-	if conflict == nil {
-		return apperror.ErrNotFound
-	}
-	if conflict.Status != model.ConflictStatusPending {
-		return apperror.New(400, "ALREADY_RESOLVED", "conflict is already resolved")
+	if conflict.WinningReservationID == nil {
+		return apperror.New(400, "MAINTENANCE_CONFLICT", "a reservation cannot be forced into maintenance; move or cancel the maintenance window instead")
 	}
 
 	// Force: keep losing reservation's original slot, cancel winning
-	if err := s.reservationRepo.UpdateStatus(ctx, conflict.LosingReservationID, model.ReservationStatusConfirmed); err != nil {
+	st := &settlement{conflictID: conflictID, status: model.ConflictStatusForceAssigned, reason: reason}
+	if err := s.reservationSvc.cancelInFavourOf(ctx, *conflict.WinningReservationID, conflict.LosingReservationID, resolvedBy, "force assigned: "+reason, st); err != nil {
 		return err
 	}
-	if err := s.reservationRepo.Cancel(ctx, conflict.WinningReservationID, resolvedBy, "force assigned: "+reason); err != nil {
+
+	s.auditSvc.Log(ctx, resolvedBy, "conflict.force_assign", "conflict", conflictID, conflict, nil, reason)
+	return nil
+}
+
+// recheck confirms both sides of a resolved conflict that are left without
+// pending conflicts. The loser only needs it when it kept its slot.
+func (s *ConflictService) recheck(ctx context.Context, conflict *model.ReservationConflict, actorID string) error {
+	if err := s.reservationSvc.Recheck(ctx, conflict.LosingReservationID, actorID); err != nil {
 		return err
 	}
+	if conflict.WinningReservationID == nil {
+		return nil
+	}
+	return s.reservationSvc.Recheck(ctx, *conflict.WinningReservationID, actorID)
+}
 
-	if err := s.conflictRepo.Resolve(ctx, conflictID, resolvedBy, reason, model.ConflictStatusForceAssigned); err != nil {
-		return err
+// Suggestions ranks ways to move the losing reservation of a pending
+// conflict out of the way: vehicles free for its slot, most spare time
+// around it first, and the nearest free slots of the same length on its own
+// vehicle. Only turnarounds are checked, so a suggestion can still end in a
+// travel-time conflict. Vehicles are only offered from within the depot
+// scope depotIDs, nil for all.
+func (s *ConflictService) Suggestions(ctx context.Context, conflictID string, depotIDs []string) (*dto.ConflictSuggestions, error) {
+	conflict, err := s.pending(ctx, conflictID, depotIDs)
+	if err != nil {
+		return nil, err
+	}
+	res, err := s.reservationRepo.GetByID(ctx, conflict.LosingReservationID)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil {
+		return nil, apperror.ErrNotFound
 	}
 
-	s.auditSvc.Log(ctx, resolvedBy, "conflict.force_assign", "conflict", conflictID, conflict, nil, reason)
-	return nil
+	out := &dto.ConflictSuggestions{
+		ConflictID:    conflict.ID,
+		ReservationID: res.ID,
+		Vehicles:      []dto.VehicleSuggestion{},
+		Slots:         []dto.SlotSuggestion{},
+	}
+
+	candidates, err := s.reservationRepo.ListFreeVehicles(ctx, res.StartTime, res.EndTime, suggestionWindow, res.VehicleID, depotIDs)
+	if err != nil {
+		return nil, err
+	}
+	for _, c := range candidates {
+		slack, ok := slotSlack(c, res.StartTime, res.EndTime)
+		if !ok {
+			continue
+		}
+		out.Vehicles = append(out.Vehicles, dto.VehicleSuggestion{
+			VehicleID:    c.VehicleID,
+			VehicleName:  c.VehicleName,
+			LicensePlate: c.LicensePlate,
+			SlackSec:     int(slack / time.Second),
+		})
+	}
+	// Candidates come ordered by name, which breaks ties
+	sort.SliceStable(out.Vehicles, func(i, j int) bool {
+		return out.Vehicles[i].SlackSec > out.Vehicles[j].SlackSec
+	})
+	if len(out.Vehicles) > suggestionVehicles {
+		out.Vehicles = out.Vehicles[:suggestionVehicles]
+	}
+
+	v, err := s.vehicleRepo.GetByID(ctx, res.VehicleID)
+	if err != nil {
+		return nil, err
+	}
+	var turnaround time.Duration
+	if v != nil {
+		turnaround = time.Duration(v.TurnaroundMin) * time.Minute
+	}
+	length := res.EndTime.Sub(res.StartTime)
+	margin := suggestionWindow + length + turnaround
+	taken, err := s.reservationRepo.FindOverlapping(ctx, res.VehicleID, res.StartTime.Add(-margin), res.EndTime.Add(margin), res.ID)
+	if err != nil {
+		return nil, err
+	}
+	windows, err := s.maintenanceRepo.FindBlocking(ctx, res.VehicleID, res.StartTime.Add(-margin), res.EndTime.Add(margin))
+	if err != nil {
+		return nil, err
+	}
+	busy := make([]model.Busy, 0, len(taken)+len(windows))
+	for _, t := range taken {
+		busy = append(busy, model.Busy{Start: t.StartTime, End: t.EndTime})
+	}
+	for _, w := range windows {
+		end := w.EndTime
+		if w.Status == model.MaintenanceStatusInProgress && end.Before(time.Now()) {
+			end = time.Now()
+		}
+		busy = append(busy, model.Busy{Start: w.StartTime, End: end})
+	}
+	for _, start := range model.FreeSlots(busy, res.StartTime, length, turnaround, suggestionWindow, time.Now(), suggestionSlots) {
+		out.Slots = append(out.Slots, dto.SlotSuggestion{
+			StartTime: start,
+			EndTime:   start.Add(length),
+			ShiftSec:  int(start.Sub(res.StartTime) / time.Second),
+		})
+	}
+	return out, nil
+}
+
+// slotSlack is the spare time a candidate vehicle has around [start, end)
+// once its turnaround is allowed for on both sides, capped at the
+// suggestion window. It is false when the turnaround does not fit.
+func slotSlack(c model.SlotCandidate, start, end time.Time) (time.Duration, bool) {
+	turnaround := time.Duration(c.TurnaroundMin) * time.Minute
+	slack := suggestionWindow
+	if c.PrevEnd != nil {
+		slack = minDuration(slack, start.Sub(*c.PrevEnd)-turnaround)
+	}
+	if c.NextStart != nil {
+		slack = minDuration(slack, c.NextStart.Sub(end)-turnaround)
+	}
+	return slack, slack >= 0
+}
+
+func minDuration(a, b time.Duration) time.Duration {
+	if a < b {
+		return a
+	}
+	return b
 }
@@ -0,0 +1,174 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+var (
+	errOutOfDepot     = apperror.New(403, "OUT_OF_DEPOT", "the vehicle belongs to a depot you do not dispatch for")
+	errDepotNameTaken = apperror.New(409, "DEPOT_NAME_TAKEN", "another depot already has that name")
+)
+
+// DepotService keeps the depots vehicles and users belong to, and which
+// depots each dispatcher works.
+type DepotService struct {
+	repo     *repository.DepotRepo
+	userRepo *repository.UserRepo
+	auditSvc *AuditService
+}
+
+func NewDepotService(repo *repository.DepotRepo, userRepo *repository.UserRepo, auditSvc *AuditService) *DepotService {
+	return &DepotService{repo: repo, userRepo: userRepo, auditSvc: auditSvc}
+}
+
+// List returns the depots within the depot scope depotIDs, nil for all.
+func (s *DepotService) List(ctx context.Context, depotIDs []string) ([]model.Depot, error) {
+	return s.repo.List(ctx, depotIDs)
+}
+
+func (s *DepotService) GetByID(ctx context.Context, id string) (*model.Depot, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *DepotService) get(ctx context.Context, id string) (*model.Depot, error) {
+	d, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, apperror.ErrNotFound
+	}
+	return d, nil
+}
+
+func validateDepot(d *model.Depot) error {
+	d.Name = strings.TrimSpace(d.Name)
+	if d.Name == "" || utf8.RuneCountInString(d.Name) > 100 {
+		return apperror.New(400, "VALIDATION_ERROR", "name is required and must be at most 100 characters")
+	}
+	if (d.Latitude == nil) != (d.Longitude == nil) {
+		return apperror.New(400, "VALIDATION_ERROR", "latitude and longitude must be given together")
+	}
+	if d.Latitude != nil && (*d.Latitude < -90 || *d.Latitude > 90 || *d.Longitude < -180 || *d.Longitude > 180) {
+		return apperror.New(400, "VALIDATION_ERROR", "latitude and longitude must be valid GPS coordinates")
+	}
+	return nil
+}
+
+func depotErr(err error) error {
+	if errors.Is(err, repository.ErrDepotNameTaken) {
+		return errDepotNameTaken
+	}
+	return err
+}
+
+func (s *DepotService) Create(ctx context.Context, actorID string, req dto.CreateDepotRequest) (*model.Depot, error) {
+	d := &model.Depot{Name: req.Name, Address: req.Address, Latitude: req.Latitude, Longitude: req.Longitude}
+	if err := validateDepot(d); err != nil {
+		return nil, err
+	}
+	if err := s.repo.Create(ctx, d); err != nil {
+		return nil, depotErr(err)
+	}
+	s.auditSvc.Log(ctx, actorID, "depot.create", "depot", d.ID, nil, d, "")
+	return d, nil
+}
+
+func (s *DepotService) Update(ctx context.Context, actorID, id string, req dto.UpdateDepotRequest) (*model.Depot, error) {
+	before, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	d := *before
+	if req.Name != nil {
+		d.Name = *req.Name
+	}
+	if req.Address != nil {
+		d.Address = req.Address
+	}
+	if req.Latitude != nil || req.Longitude != nil {
+		d.Latitude, d.Longitude = req.Latitude, req.Longitude
+	}
+	if err := validateDepot(&d); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, &d); err != nil {
+		return nil, depotErr(err)
+	}
+	after, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.auditSvc.Log(ctx, actorID, "depot.update", "depot", id, before, after, "")
+	return after, nil
+}
+
+// Delete removes a depot. Its vehicles become shared between all depots.
+func (s *DepotService) Delete(ctx context.Context, actorID, id string) error {
+	before, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.auditSvc.Log(ctx, actorID, "depot.delete", "depot", id, before, nil, "")
+	return nil
+}
+
+// DepotIDsOf returns the depots the user is a member of, empty but not nil
+// when none.
+func (s *DepotService) DepotIDsOf(ctx context.Context, userID string) ([]string, error) {
+	ids, err := s.repo.ListUserDepotIDs(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if ids == nil {
+		ids = []string{}
+	}
+	return ids, nil
+}
+
+// SetUserDepots replaces the depots a user is a member of.
+func (s *DepotService) SetUserDepots(ctx context.Context, actorID, userID string, depotIDs []string) ([]string, error) {
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, apperror.ErrNotFound
+	}
+	for _, id := range depotIDs {
+		d, err := s.repo.GetByID(ctx, id)
+		if err != nil {
+			return nil, err
+		}
+		if d == nil {
+			return nil, apperror.New(400, "INVALID_DEPOT", "depot "+id+" not found")
+		}
+	}
+
+	before, err := s.DepotIDsOf(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetUserDepots(ctx, userID, depotIDs); err != nil {
+		return nil, err
+	}
+	after, err := s.DepotIDsOf(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	s.auditSvc.Log(ctx, actorID, "user.depots_set", "user", userID,
+		map[string]interface{}{"depot_ids": before}, map[string]interface{}{"depot_ids": after}, "")
+	return after, nil
+}
@@ -2,12 +2,17 @@ package service
 
 import (
 	"context"
-	"math"
+	"log"
+	"strings"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/eta"
 	"github.com/kento/driver/backend/internal/model"
 	"github.com/kento/driver/backend/internal/notify"
+	"github.com/kento/driver/backend/internal/policy"
+	"github.com/kento/driver/backend/internal/realtime"
 	"github.com/kento/driver/backend/internal/repository"
 	"github.com/kento/driver/backend/pkg/apperror"
 )
@@ -18,10 +23,31 @@ type DispatchService struct {
 	auditSvc    *AuditService
 	staleThr    time.Duration
 	fcmSvc      *notify.FCMService
+	hub         *realtime.Hub
+	eta         eta.Provider
+	authz       *Authorizer
+	// reservationSvc keeps a reservation in step with the trip made from it
+	reservationSvc *ReservationService
 }
 
-func NewDispatchService(repo *repository.DispatchRepo, vehicleRepo *repository.VehicleRepo, auditSvc *AuditService, staleThr time.Duration, fcmSvc *notify.FCMService) *DispatchService {
-	return &DispatchService{repo: repo, vehicleRepo: vehicleRepo, auditSvc: auditSvc, staleThr: staleThr, fcmSvc: fcmSvc}
+func NewDispatchService(repo *repository.DispatchRepo, vehicleRepo *repository.VehicleRepo, auditSvc *AuditService, staleThr time.Duration, fcmSvc *notify.FCMService, hub *realtime.Hub, etaProvider eta.Provider, authz *Authorizer, reservationSvc *ReservationService) *DispatchService {
+	return &DispatchService{repo: repo, vehicleRepo: vehicleRepo, auditSvc: auditSvc, staleThr: staleThr, fcmSvc: fcmSvc, hub: hub, eta: etaProvider, authz: authz, reservationSvc: reservationSvc}
+}
+
+// publish pushes the current state of a dispatch to stream subscribers.
+func (s *DispatchService) publish(d *model.Dispatch) {
+	publishDispatch(s.hub, d)
+}
+
+func publishDispatch(hub *realtime.Hub, d *model.Dispatch) {
+	if d == nil {
+		return
+	}
+	e := realtime.Event{Type: realtime.EventDispatchUpdated, DispatchID: d.ID, Data: d}
+	if d.VehicleID != nil {
+		e.VehicleID = *d.VehicleID
+	}
+	hub.Publish(e)
 }
 
 func (s *DispatchService) Create(ctx context.Context, req dto.CreateDispatchRequest, requesterID string) (*model.Dispatch, error) {
@@ -44,6 +70,7 @@ func (s *DispatchService) Create(ctx context.Context, req dto.CreateDispatchRequ
 	}
 
 	s.auditSvc.Log(ctx, requesterID, "dispatch.create", "dispatch", d.ID, nil, d, "")
+	s.publish(d)
 
 	// Notify dispatchers of new dispatch
 	go s.fcmSvc.NotifyRole(ctx, "New Dispatch", d.Purpose, map[string]string{
@@ -55,7 +82,7 @@ func (s *DispatchService) Create(ctx context.Context, req dto.CreateDispatchRequ
 
 func (s *DispatchService) QuickBoard(ctx context.Context, req dto.QuickBoardRequest, dispatcherID string) (*model.Dispatch, error) {
 	// Verify vehicle is not already on a trip
-	vehicles, err := s.vehicleRepo.ListWithStatus(ctx, s.staleThr)
+	vehicles, err := s.vehicleRepo.ListWithStatus(ctx, s.staleThr, nil)
 	if err != nil {
 		return nil, err
 	}
@@ -65,6 +92,9 @@ func (s *DispatchService) QuickBoard(ctx context.Context, req dto.QuickBoardRequ
 			if v.Status == model.VehicleStatusInTrip {
 				return nil, apperror.New(400, "VEHICLE_BUSY", "vehicle already has an active trip")
 			}
+			if v.Status == model.VehicleStatusMaintenance {
+				return nil, errVehicleInMaintenance
+			}
 			found = true
 			break
 		}
@@ -72,6 +102,9 @@ func (s *DispatchService) QuickBoard(ctx context.Context, req dto.QuickBoardRequ
 	if !found {
 		return nil, apperror.ErrNotFound
 	}
+	if err := s.checkDocuments(ctx, req.VehicleID); err != nil {
+		return nil, err
+	}
 
 	purpose := req.Purpose
 	if purpose == "" {
@@ -100,18 +133,17 @@ func (s *DispatchService) QuickBoard(ctx context.Context, req dto.QuickBoardRequ
 	}
 
 	// Assign + advance to en_route immediately
-	if err := s.repo.Assign(ctx, d.ID, req.VehicleID, dispatcherID); err != nil {
-		return nil, err
-	}
-	if err := s.repo.UpdateStatus(ctx, d.ID, model.DispatchStatusAccepted); err != nil {
+	boarded, err := s.repo.Board(ctx, d.ID, d.Version, req.VehicleID, dispatcherID)
+	if err != nil {
 		return nil, err
 	}
-	if err := s.repo.UpdateStatus(ctx, d.ID, model.DispatchStatusEnRoute); err != nil {
-		return nil, err
+	if !boarded {
+		return nil, errStaleDispatch
 	}
 
 	result, _ := s.repo.GetByID(ctx, d.ID)
 	s.auditSvc.Log(ctx, dispatcherID, "dispatch.quick_board", "dispatch", d.ID, nil, result, "")
+	s.publish(result)
 	return result, nil
 }
 
@@ -119,11 +151,34 @@ func (s *DispatchService) GetByID(ctx context.Context, id string) (*model.Dispat
 	return s.repo.GetByID(ctx, id)
 }
 
-func (s *DispatchService) List(ctx context.Context, status string, limit, offset int) ([]model.Dispatch, error) {
+// GetFor returns a dispatch if sub may perform action on it.
+func (s *DispatchService) GetFor(ctx context.Context, sub policy.Subject, action policy.Action, id string) (*model.Dispatch, error) {
+	d, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, apperror.ErrNotFound
+	}
+	if err := s.authz.Dispatch(ctx, sub, action, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+// List returns the dispatches within the depot scope depotIDs, nil for all.
+func (s *DispatchService) List(ctx context.Context, sub policy.Subject, status string, depotIDs []string, limit, offset int) ([]model.Dispatch, error) {
+	if err := s.authz.ListDispatches(ctx, sub); err != nil {
+		return nil, err
+	}
 	if limit <= 0 {
 		limit = 50
 	}
-	return s.repo.List(ctx, status, limit, offset)
+	return s.repo.List(ctx, status, depotIDs, limit, offset)
+}
+
+func (s *DispatchService) ListActive(ctx context.Context) ([]model.Dispatch, error) {
+	return s.repo.ListActive(ctx)
 }
 
 func (s *DispatchService) ListByRequester(ctx context.Context, requesterID, status string, limit, offset int) ([]model.Dispatch, error) {
@@ -133,7 +188,49 @@ func (s *DispatchService) ListByRequester(ctx context.Context, requesterID, stat
 	return s.repo.ListByRequester(ctx, requesterID, status, limit, offset)
 }
 
-func (s *DispatchService) Assign(ctx context.Context, dispatchID, vehicleID, dispatcherID string) error {
+// Assign offers a dispatch to a vehicle. The candidate ETAs recorded with it
+// are those of the vehicles within the depot scope depotIDs, nil for all.
+func (s *DispatchService) Assign(ctx context.Context, dispatchID, vehicleID string, actor model.DispatchActor, dispatcherID string, depotIDs []string) error {
+	return s.assign(ctx, dispatchID, vehicleID, actor, dispatcherID, model.ETATriggerAssign, nil, depotIDs)
+}
+
+var errVehicleInMaintenance = apperror.New(409, "VEHICLE_IN_MAINTENANCE", "the vehicle is in maintenance")
+
+var errReservationVehicle = apperror.New(409, "RESERVATION_VEHICLE", "the trip's reservation holds another vehicle; move the reservation instead")
+
+// checkDocuments refuses a vehicle whose registration, insurance or
+// emission test has expired.
+func (s *DispatchService) checkDocuments(ctx context.Context, vehicleID string) error {
+	expired, err := s.vehicleRepo.ExpiredDocuments(ctx, vehicleID)
+	if err != nil {
+		return err
+	}
+	return documentsExpired(expired)
+}
+
+// documentsExpired is the DOCUMENTS_EXPIRED error naming the expired types,
+// or nil when there are none.
+func documentsExpired(expired []model.DocumentType) error {
+	if len(expired) == 0 {
+		return nil
+	}
+	names := make([]string, len(expired))
+	for i, t := range expired {
+		names[i] = string(t)
+	}
+	return apperror.New(409, "DOCUMENTS_EXPIRED", "the vehicle's documents have expired: "+strings.Join(names, ", "))
+}
+
+// AssignRanked assigns a vehicle picked automatically from etas, recording
+// those estimates as the decision instead of recalculating them.
+func (s *DispatchService) AssignRanked(ctx context.Context, dispatchID, vehicleID, dispatcherID string, etas []dto.VehicleETA) error {
+	return s.assign(ctx, dispatchID, vehicleID, model.DispatchActorSystem, dispatcherID, model.ETATriggerAutoAssign, etas, nil)
+}
+
+// assign records the candidate list the choice was made from alongside the
+// assignment. With nil etas the candidates are calculated afresh from the
+// vehicles within depotIDs.
+func (s *DispatchService) assign(ctx context.Context, dispatchID, vehicleID string, actor model.DispatchActor, dispatcherID, trigger string, etas []dto.VehicleETA, depotIDs []string) error {
 	before, err := s.repo.GetByID(ctx, dispatchID)
 	if err != nil {
 		return err
@@ -141,26 +238,106 @@ func (s *DispatchService) Assign(ctx context.Context, dispatchID, vehicleID, dis
 	if before == nil {
 		return apperror.ErrNotFound
 	}
-	if before.Status != model.DispatchStatusPending {
-		return apperror.New(400, "INVALID_STATUS", "dispatch is not in pending status")
+	if err := checkTransition(before, model.DispatchStatusAssigned, actor); err != nil {
+		return err
+	}
+	if err := s.authz.Dispatch(ctx, policy.ForActor(actor, dispatcherID), policy.DispatchTransition, before); err != nil {
+		return err
+	}
+	// A reservation trip runs on the reservation's vehicle. Moving the
+	// reservation cancels the trip and a new one follows on the new vehicle.
+	if before.ReservationID != nil {
+		res, err := s.reservationSvc.GetByID(ctx, *before.ReservationID)
+		if err != nil {
+			return err
+		}
+		if res != nil && res.VehicleID != vehicleID {
+			return errReservationVehicle
+		}
+	}
+	inMaintenance, err := s.vehicleRepo.InMaintenance(ctx, vehicleID)
+	if err != nil {
+		return err
+	}
+	if inMaintenance {
+		return errVehicleInMaintenance
+	}
+	if err := s.checkDocuments(ctx, vehicleID); err != nil {
+		return err
 	}
 
-	if err := s.repo.Assign(ctx, dispatchID, vehicleID, dispatcherID); err != nil {
+	assigned, err := s.repo.Assign(ctx, dispatchID, before.Version, vehicleID, dispatcherID)
+	if err != nil {
 		return err
 	}
+	if !assigned {
+		return errStaleDispatch
+	}
 
 	after, _ := s.repo.GetByID(ctx, dispatchID)
 	s.auditSvc.Log(ctx, dispatcherID, "dispatch.assign", "dispatch", dispatchID, before, after, "")
+	s.publish(after)
+
+	if etas == nil && before.PickupLat != nil && before.PickupLng != nil {
+		etas, err = s.CalculateETAs(ctx, *before.PickupLat, *before.PickupLng, depotIDs)
+		if err != nil {
+			log.Printf("[dispatch] ETA candidates for assignment of %s: %v", dispatchID, err)
+		}
+	}
+	s.RecordETAs(ctx, dispatchID, etas, trigger, dispatcherID, vehicleID)
 
 	// Notify the driver of the assigned vehicle
-	go s.fcmSvc.NotifyVehicleDriver(ctx, vehicleID, "Trip Assigned", "You have been assigned a new trip", map[string]string{
+	go s.fcmSvc.NotifyVehicleDriver(context.WithoutCancel(ctx), vehicleID, "Trip Assigned", "You have been assigned a new trip", map[string]string{
 		"type": "dispatch_assigned", "dispatch_id": dispatchID,
 	})
 
 	return nil
 }
 
-func (s *DispatchService) UpdateStatus(ctx context.Context, dispatchID string, status model.DispatchStatus, actorID string) error {
+var errStaleDispatch = apperror.New(409, "STALE_DISPATCH", "dispatch was changed by someone else; reload and try again")
+
+// checkTransition validates a status change against the state machine. A
+// change with no edge from the current status conflicts with the dispatch's
+// state; an edge the actor may not take is forbidden.
+func checkTransition(d *model.Dispatch, to model.DispatchStatus, actor model.DispatchActor) error {
+	t, ok := model.FindDispatchTransition(d.Status, to)
+	if ok && t.AllowedBy(actor) {
+		return nil
+	}
+	if ok {
+		return transitionError(403, string(actor)+" may not move a dispatch from "+string(d.Status)+" to "+string(to), d, to, actor)
+	}
+	return transitionError(409, "dispatch cannot move from "+string(d.Status)+" to "+string(to), d, to, actor)
+}
+
+func transitionError(status int, msg string, d *model.Dispatch, to model.DispatchStatus, actor model.DispatchActor) error {
+	return apperror.New(status, "INVALID_TRANSITION", msg).WithDetails(dto.InvalidTransitionDetails{
+		From:    d.Status,
+		To:      to,
+		Actor:   actor,
+		Allowed: model.NextDispatchStatuses(d.Status, actor),
+	})
+}
+
+// StateMachine returns the dispatch lifecycle definition.
+func (s *DispatchService) StateMachine() dto.DispatchStateMachine {
+	var terminal []model.DispatchStatus
+	for _, st := range model.DispatchStatuses {
+		if st.IsTerminal() {
+			terminal = append(terminal, st)
+		}
+	}
+	return dto.DispatchStateMachine{
+		Statuses:    model.DispatchStatuses,
+		Terminal:    terminal,
+		Actors:      model.DispatchActors,
+		Transitions: model.DispatchTransitions,
+	}
+}
+
+// UpdateStatus advances a trip through its lifecycle. Assignment, release
+// and cancellation carry more than a status and have their own methods.
+func (s *DispatchService) UpdateStatus(ctx context.Context, dispatchID string, status model.DispatchStatus, actor model.DispatchActor, actorID string) error {
 	before, err := s.repo.GetByID(ctx, dispatchID)
 	if err != nil {
 		return err
@@ -169,16 +346,40 @@ func (s *DispatchService) UpdateStatus(ctx context.Context, dispatchID string, s
 		return apperror.ErrNotFound
 	}
 
-	if err := s.repo.UpdateStatus(ctx, dispatchID, status); err != nil {
+	switch {
+	case status == model.DispatchStatusAssigned, status == model.DispatchStatusPending, status == model.DispatchStatusCancelled:
+		return apperror.New(400, "BAD_REQUEST", "use the assign, decline or cancel action for this status")
+	case before.Status == model.DispatchStatusPending:
+		// Quick boarding creates its own dispatch; a pending one has no
+		// vehicle to be en route in.
+		return transitionError(409, "dispatch has no vehicle yet", before, status, actor)
+	}
+	if err := checkTransition(before, status, actor); err != nil {
+		return err
+	}
+	if err := s.authz.Dispatch(ctx, policy.ForActor(actor, actorID), policy.DispatchTransition, before); err != nil {
+		return err
+	}
+
+	moved, err := s.repo.Transition(ctx, dispatchID, before.Status, before.Version, status)
+	if err != nil {
 		return err
 	}
+	if !moved {
+		return errStaleDispatch
+	}
 
 	after, _ := s.repo.GetByID(ctx, dispatchID)
 	s.auditSvc.Log(ctx, actorID, "dispatch.status_change", "dispatch", dispatchID, before, after, "")
+	s.publish(after)
+
+	if status == model.DispatchStatusCompleted && before.ReservationID != nil {
+		s.reservationSvc.CompleteTrip(ctx, *before.ReservationID, actorID)
+	}
 	return nil
 }
 
-func (s *DispatchService) Cancel(ctx context.Context, dispatchID, reason, actorID string) error {
+func (s *DispatchService) Cancel(ctx context.Context, dispatchID, reason string, actor model.DispatchActor, actorID string) error {
 	before, err := s.repo.GetByID(ctx, dispatchID)
 	if err != nil {
 		return err
@@ -186,16 +387,151 @@ func (s *DispatchService) Cancel(ctx context.Context, dispatchID, reason, actorI
 	if before == nil {
 		return apperror.ErrNotFound
 	}
+	if err := checkTransition(before, model.DispatchStatusCancelled, actor); err != nil {
+		return err
+	}
+	if err := s.authz.Dispatch(ctx, policy.ForActor(actor, actorID), policy.DispatchCancel, before); err != nil {
+		return err
+	}
 
-	if err := s.repo.Cancel(ctx, dispatchID, reason); err != nil {
+	cancelled, err := s.repo.Cancel(ctx, dispatchID, before.Status, before.Version, reason)
+	if err != nil {
 		return err
 	}
+	if !cancelled {
+		return errStaleDispatch
+	}
 
 	after, _ := s.repo.GetByID(ctx, dispatchID)
 	s.auditSvc.Log(ctx, actorID, "dispatch.cancel", "dispatch", dispatchID, before, after, reason)
+	s.publish(after)
+
+	// A cancelled trip takes its reservation with it
+	if before.ReservationID != nil {
+		if err := s.reservationSvc.Cancel(ctx, *before.ReservationID, actorID, reason); err != nil {
+			log.Printf("[dispatch] cancel reservation %s of %s: %v", *before.ReservationID, dispatchID, err)
+		}
+	}
+	return nil
+}
+
+// CreateReservationTrips turns confirmed reservations starting within lead
+// into trips offered to their vehicle, so drivers run them through the usual
+// accept, en route, arrived and complete steps. Returns how many were made.
+func (s *DispatchService) CreateReservationTrips(ctx context.Context, lead time.Duration) (int, error) {
+	due, err := s.reservationSvc.ListDueForTrip(ctx, lead)
+	if err != nil {
+		return 0, err
+	}
+	created := 0
+	for i := range due {
+		ok, err := s.createReservationTrip(ctx, &due[i])
+		if err != nil {
+			log.Printf("[dispatch] trip for reservation %s: %v", due[i].ID, err)
+			continue
+		}
+		if ok {
+			created++
+		}
+	}
+	return created, nil
+}
+
+// createReservationTrip makes the trip for res. A dispatch has one drop-off,
+// so it gets the last destination and any earlier ones go in the notes.
+func (s *DispatchService) createReservationTrip(ctx context.Context, res *model.Reservation) (bool, error) {
+	pickup := "(reservation)"
+	if res.PickupAddress != nil && *res.PickupAddress != "" {
+		pickup = *res.PickupAddress
+	}
+	var dropoff *string
+	notes := res.Notes
+	if n := len(res.Destinations); n > 0 {
+		dropoff = &res.Destinations[n-1]
+		if n > 1 {
+			via := "Via: " + strings.Join(res.Destinations[:n-1], "; ")
+			if notes != nil && *notes != "" {
+				via += "\n" + *notes
+			}
+			notes = &via
+		}
+	}
+	end := res.EndTime
+
+	d := &model.Dispatch{
+		RequesterID:    res.RequesterID,
+		Purpose:        res.Purpose,
+		PassengerName:  res.PassengerName,
+		PassengerCount: 1,
+		Notes:          notes,
+		PickupAddress:  pickup,
+		PickupLat:      res.PickupLat,
+		PickupLng:      res.PickupLng,
+		DropoffAddress: dropoff,
+		EstimatedEndAt: &end,
+		VehicleID:      &res.VehicleID,
+		ReservationID:  &res.ID,
+	}
+	created, err := s.repo.CreateForReservation(ctx, d)
+	if err != nil || !created {
+		return false, err
+	}
+
+	// The create_trips job makes the trip, not the requester; the booking
+	// itself is on record under their name
+	s.auditSvc.Log(ctx, "", "dispatch.create_from_reservation", "dispatch", d.ID, nil, d, "")
+	s.publish(d)
+
+	// The create_trips job's context ends when it returns
+	go s.fcmSvc.NotifyVehicleDriver(context.WithoutCancel(ctx), res.VehicleID, "Trip Assigned", res.Purpose, map[string]string{
+		"type": "dispatch_assigned", "dispatch_id": d.ID, "reservation_id": res.ID,
+	})
+	return true, nil
+}
+
+// ReleaseOffer takes a dispatch back from a vehicle whose driver declined it
+// (or let the offer expire) and returns it to pending. The vehicle is
+// remembered so it is not offered the same trip again.
+func (s *DispatchService) ReleaseOffer(ctx context.Context, dispatchID, vehicleID string, actor model.DispatchActor, actorID, action, reason string) error {
+	before, err := s.repo.GetByID(ctx, dispatchID)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return apperror.ErrNotFound
+	}
+	if before.Status != model.DispatchStatusAssigned || before.VehicleID == nil || *before.VehicleID != vehicleID {
+		return errOfferClosed
+	}
+	if err := checkTransition(before, model.DispatchStatusPending, actor); err != nil {
+		return err
+	}
+	if err := s.authz.Dispatch(ctx, policy.ForActor(actor, actorID), policy.DispatchTransition, before); err != nil {
+		return err
+	}
+
+	released, err := s.repo.ReleaseOffer(ctx, dispatchID, before.Version, vehicleID)
+	if err != nil {
+		return err
+	}
+	if !released {
+		return errOfferClosed
+	}
+
+	after, _ := s.repo.GetByID(ctx, dispatchID)
+	s.auditSvc.Log(ctx, actorID, action, "dispatch", dispatchID, before, after, reason)
+	s.publish(after)
 	return nil
 }
 
+var errOfferClosed = apperror.New(409, "OFFER_CLOSED", "trip was already accepted or reassigned")
+
+// ListExpiredOffers returns dispatches whose driver has not accepted an offer
+// made before the cutoff.
+func (s *DispatchService) ListExpiredOffers(ctx context.Context, before time.Time) ([]model.Dispatch, error) {
+	return s.repo.ListOffersAssignedBefore(ctx, before)
+}
+
 func (s *DispatchService) GetCurrentTripByDriverID(ctx context.Context, driverID string) (*model.Dispatch, error) {
 	return s.repo.GetActiveByDriverID(ctx, driverID)
 }
@@ -208,64 +544,240 @@ func (s *DispatchService) GetETASnapshots(ctx context.Context, dispatchID string
 	return s.repo.GetETASnapshots(ctx, dispatchID)
 }
 
-// CalculateETAs returns ETA estimates for all available vehicles to a given pickup point.
-// Uses haversine distance with simulated Manila traffic speed (~18 km/h avg).
-func (s *DispatchService) CalculateETAs(ctx context.Context, pickupLat, pickupLng float64) ([]dto.VehicleETA, error) {
-	vehicles, err := s.vehicleRepo.ListWithStatus(ctx, s.staleThr)
+// RecordETAs stores the candidate list a dispatch decision was made from as
+// one snapshot batch. chosenVehicleID marks the vehicle that was picked, if
+// any. Failures are logged: losing the record must not fail the decision.
+func (s *DispatchService) RecordETAs(ctx context.Context, dispatchID string, etas []dto.VehicleETA, trigger, actorID, chosenVehicleID string) {
+	if len(etas) == 0 {
+		return
+	}
+	batchID := uuid.NewString()
+	now := time.Now()
+	snaps := make([]model.DispatchETASnapshot, 0, len(etas))
+	for _, e := range etas {
+		snap := model.DispatchETASnapshot{
+			BatchID:       batchID,
+			DispatchID:    dispatchID,
+			VehicleID:     e.VehicleID,
+			VehicleStatus: e.Status,
+			OriginLat:     e.Latitude,
+			OriginLng:     e.Longitude,
+			DurationSec:   e.DurationSec,
+			DistanceM:     e.DistanceM,
+			Provider:      e.Provider,
+			Trigger:       trigger,
+			Chosen:        e.VehicleID == chosenVehicleID,
+			CalculatedAt:  now,
+		}
+		if actorID != "" {
+			snap.ActorID = &actorID
+		}
+		snaps = append(snaps, snap)
+	}
+	if err := s.repo.SaveETASnapshots(ctx, snaps); err != nil {
+		log.Printf("[dispatch] save ETA snapshots for %s (%s): %v", dispatchID, trigger, err)
+	}
+}
+
+// CalculateDispatchETAs calculates ETAs to a pickup on behalf of a specific
+// dispatch and records the candidate list the dispatcher was shown, the
+// vehicles within the depot scope depotIDs. A dispatch outside the scope is
+// not found.
+func (s *DispatchService) CalculateDispatchETAs(ctx context.Context, dispatchID string, pickupLat, pickupLng float64, actorID string, depotIDs []string) ([]dto.VehicleETA, error) {
+	d, err := s.repo.GetByID(ctx, dispatchID)
 	if err != nil {
 		return nil, err
 	}
+	if d == nil {
+		return nil, apperror.ErrNotFound
+	}
+	if d.VehicleID != nil && depotIDs != nil {
+		v, err := s.vehicleRepo.GetByID(ctx, *d.VehicleID)
+		if err != nil {
+			return nil, err
+		}
+		if v != nil && !model.InDepots(depotIDs, v.DepotID) {
+			return nil, apperror.ErrNotFound
+		}
+	}
 
-	// Manila default center for vehicles without GPS
-	defaultLat, defaultLng := 14.5547, 121.0244
-	offsets := [][2]float64{{0.005, 0.003}, {-0.003, 0.008}, {0.008, -0.004}, {-0.006, -0.006}, {0.002, 0.012}}
+	etas, err := s.CalculateETAs(ctx, pickupLat, pickupLng, depotIDs)
+	if err != nil {
+		return nil, err
+	}
+	s.RecordETAs(ctx, dispatchID, etas, model.ETATriggerCalculate, actorID, "")
+	return etas, nil
+}
 
-	var results []dto.VehicleETA
-	// Fixed average speed for Manila traffic: ~18 km/h
-	const avgSpeedKmh = 18.0
-
-	for i, v := range vehicles {
-		vLat := v.Latitude
-		vLng := v.Longitude
-		if vLat == nil {
-			lat := defaultLat + offsets[i%len(offsets)][0]
-			vLat = &lat
+// ReviewETAs groups the recorded snapshots of a dispatch into decisions,
+// newest first, and compares the ETA given at the latest assignment with the
+// actual pickup.
+func (s *DispatchService) ReviewETAs(ctx context.Context, dispatchID string) (*dto.DispatchETAReview, error) {
+	d, err := s.repo.GetByID(ctx, dispatchID)
+	if err != nil {
+		return nil, err
+	}
+	if d == nil {
+		return nil, apperror.ErrNotFound
+	}
+	snaps, err := s.repo.GetETASnapshots(ctx, dispatchID)
+	if err != nil {
+		return nil, err
+	}
+
+	review := &dto.DispatchETAReview{
+		DispatchID: d.ID,
+		Status:     string(d.Status),
+		VehicleID:  d.VehicleID,
+		AssignedAt: d.AssignedAt,
+		EnRouteAt:  d.EnRouteAt,
+		ArrivedAt:  d.ArrivedAt,
+		Decisions:  []dto.ETADecision{},
+	}
+
+	// Snapshots arrive grouped by batch, fastest first within each.
+	for _, snap := range snaps {
+		n := len(review.Decisions)
+		if n == 0 || review.Decisions[n-1].BatchID != snap.BatchID {
+			review.Decisions = append(review.Decisions, dto.ETADecision{
+				BatchID:          snap.BatchID,
+				Trigger:          snap.Trigger,
+				ActorID:          snap.ActorID,
+				ActorName:        snap.ActorName,
+				CalculatedAt:     snap.CalculatedAt,
+				FastestVehicleID: snap.VehicleID,
+			})
+			n++
+		}
+		dec := &review.Decisions[n-1]
+		dec.Candidates = append(dec.Candidates, snap)
+		if snap.Chosen {
+			vid := snap.VehicleID
+			dec.ChosenVehicleID = &vid
+			dec.ChosenRank = len(dec.Candidates)
+		}
+	}
+
+	review.Pickup = comparePickup(d, review.Decisions)
+	return review, nil
+}
+
+// comparePickup finds the assignment of the dispatch's current vehicle
+// (decisions are newest first) and measures its ETA against the arrival.
+func comparePickup(d *model.Dispatch, decisions []dto.ETADecision) *dto.PickupComparison {
+	if d.VehicleID == nil {
+		return nil
+	}
+	for _, dec := range decisions {
+		if dec.Trigger != model.ETATriggerAssign && dec.Trigger != model.ETATriggerAutoAssign {
+			continue
+		}
+		if dec.ChosenVehicleID == nil || *dec.ChosenVehicleID != *d.VehicleID {
+			continue
+		}
+		snap := dec.Candidates[dec.ChosenRank-1]
+		c := &dto.PickupComparison{
+			BatchID:              dec.BatchID,
+			VehicleID:            snap.VehicleID,
+			Provider:             snap.Provider,
+			CalculatedAt:         snap.CalculatedAt,
+			PredictedDurationSec: snap.DurationSec,
+			PredictedPickupAt:    snap.CalculatedAt.Add(time.Duration(snap.DurationSec) * time.Second),
 		}
-		if vLng == nil {
-			lng := defaultLng + offsets[i%len(offsets)][1]
-			vLng = &lng
+		if d.ArrivedAt != nil {
+			c.ActualPickupAt = d.ArrivedAt
+			errSec := int(d.ArrivedAt.Sub(c.PredictedPickupAt).Seconds())
+			c.ErrorSec = &errSec
+			if d.EnRouteAt != nil {
+				drive := int(d.ArrivedAt.Sub(*d.EnRouteAt).Seconds())
+				driveErr := drive - snap.DurationSec
+				c.ActualDriveSec = &drive
+				c.DriveErrorSec = &driveErr
+			}
 		}
+		return c
+	}
+	return nil
+}
+
+// CalculateETAs returns ETA estimates from every vehicle with a known GPS
+// position within the depot scope depotIDs, nil for all, to a given pickup
+// point. Vehicles that have never reported a location are left out rather
+// than guessed at.
+func (s *DispatchService) CalculateETAs(ctx context.Context, pickupLat, pickupLng float64, depotIDs []string) ([]dto.VehicleETA, error) {
+	vehicles, err := s.vehicleRepo.ListWithStatus(ctx, s.staleThr, depotIDs)
+	if err != nil {
+		return nil, err
+	}
 
-		distM := haversineDistance(*vLat, *vLng, pickupLat, pickupLng)
-		durationSec := int(math.Round(distM / (avgSpeedKmh * 1000 / 3600)))
-		if durationSec < 60 {
-			durationSec = 60 // minimum 1 min
+	var located []model.VehicleWithStatus
+	var origins []eta.Point
+	for _, v := range vehicles {
+		if v.Latitude == nil || v.Longitude == nil {
+			continue
 		}
+		located = append(located, v)
+		origins = append(origins, eta.Point{Lat: *v.Latitude, Lng: *v.Longitude})
+	}
+	if len(origins) == 0 {
+		return nil, nil
+	}
+
+	estimates, err := s.eta.Estimate(ctx, origins, eta.Point{Lat: pickupLat, Lng: pickupLng})
+	if err != nil && estimates == nil {
+		return nil, err
+	}
 
+	var results []dto.VehicleETA
+	for i, v := range located {
+		e := estimates[i]
+		if !e.OK() {
+			continue
+		}
 		results = append(results, dto.VehicleETA{
 			VehicleID:   v.ID,
 			VehicleName: v.Name,
 			DriverName:  v.DriverName,
 			Plate:       v.LicensePlate,
 			Status:      string(v.Status),
-			Latitude:    *vLat,
-			Longitude:   *vLng,
-			DistanceM:   int(math.Round(distM)),
-			DurationSec: durationSec,
+			Latitude:    *v.Latitude,
+			Longitude:   *v.Longitude,
+			DistanceM:   e.DistanceM,
+			DurationSec: e.DurationSec,
 			IsAvailable: string(v.Status) == "available",
+			Provider:    e.Provider,
 		})
 	}
 
 	return results, nil
 }
 
-func haversineDistance(lat1, lng1, lat2, lng2 float64) float64 {
-	const R = 6371000 // Earth radius in meters
-	dLat := (lat2 - lat1) * math.Pi / 180
-	dLng := (lng2 - lng1) * math.Pi / 180
-	a := math.Sin(dLat/2)*math.Sin(dLat/2) +
-		math.Cos(lat1*math.Pi/180)*math.Cos(lat2*math.Pi/180)*
-			math.Sin(dLng/2)*math.Sin(dLng/2)
-	c := 2 * math.Atan2(math.Sqrt(a), math.Sqrt(1-a))
-	return R * c
+// EstimateRideETA estimates how long the vehicle at (lat, lng) needs to reach
+// the next stop of a ride: the pickup until the driver has arrived there, the
+// dropoff afterwards. Returns nil when the ride is over, the stop has no
+// coordinates, or no provider could route it.
+func (s *DispatchService) EstimateRideETA(ctx context.Context, d *model.Dispatch, lat, lng float64) *dto.RideETA {
+	var target string
+	var toLat, toLng *float64
+	switch d.Status {
+	case model.DispatchStatusAssigned, model.DispatchStatusAccepted, model.DispatchStatusEnRoute:
+		target, toLat, toLng = "pickup", d.PickupLat, d.PickupLng
+	case model.DispatchStatusArrived:
+		target, toLat, toLng = "dropoff", d.DropoffLat, d.DropoffLng
+	default:
+		return nil
+	}
+	if toLat == nil || toLng == nil {
+		return nil
+	}
+
+	estimates, _ := s.eta.Estimate(ctx, []eta.Point{{Lat: lat, Lng: lng}}, eta.Point{Lat: *toLat, Lng: *toLng})
+	if len(estimates) == 0 || !estimates[0].OK() {
+		return nil
+	}
+	return &dto.RideETA{
+		Target:      target,
+		DistanceM:   estimates[0].DistanceM,
+		DurationSec: estimates[0].DurationSec,
+	}
 }
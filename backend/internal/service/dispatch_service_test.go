@@ -0,0 +1,57 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/kento/driver/backend/internal/eta"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/internal/testdb"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+// TestCalculateDispatchETAs_OutOfDepot asks for candidate ETAs on a trip
+// whose vehicle belongs to another depot. The dispatcher must not see it, so
+// the dispatch is not found and nothing is recorded against it. Needs a
+// migrated Postgres in TEST_DATABASE_URL.
+func TestCalculateDispatchETAs_OutOfDepot(t *testing.T) {
+	conn := testdb.Open(t)
+	ctx := context.Background()
+	f := testdb.NewFixture(t, conn, "Depot Scope Test", model.RoleDispatcher)
+
+	var north, south string
+	for name, id := range map[string]*string{"North " + f.VehicleID: &north, "South " + f.VehicleID: &south} {
+		if err := conn.GetContext(ctx, id, `INSERT INTO depots (name) VALUES ($1) RETURNING id`, name); err != nil {
+			t.Fatalf("insert depot: %v", err)
+		}
+	}
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM depots WHERE id IN ($1, $2)`, north, south)
+	})
+
+	vehicleRepo := repository.NewVehicleRepo(conn)
+	dispatchRepo := repository.NewDispatchRepo(conn)
+	if err := vehicleRepo.SetDepot(ctx, f.VehicleID, &north); err != nil {
+		t.Fatalf("set depot: %v", err)
+	}
+	d := &model.Dispatch{RequesterID: f.UserID, Purpose: "depot scope test", PassengerCount: 1, PickupAddress: "(test)"}
+	if err := dispatchRepo.Create(ctx, d); err != nil {
+		t.Fatalf("create dispatch: %v", err)
+	}
+	if ok, err := dispatchRepo.Assign(ctx, d.ID, d.Version, f.VehicleID, f.UserID); err != nil || !ok {
+		t.Fatalf("assign: ok=%v err=%v", ok, err)
+	}
+
+	svc := NewDispatchService(dispatchRepo, vehicleRepo, nil, 5*time.Minute, nil, nil,
+		eta.NewHaversine(eta.SpeedProfile{DefaultKmh: 18}), nil, nil)
+	_, err := svc.CalculateDispatchETAs(ctx, d.ID, 35.6895, 139.6917, f.UserID, []string{south})
+	if !errors.Is(err, apperror.ErrNotFound) {
+		t.Fatalf("CalculateDispatchETAs from another depot: err = %v, want not found", err)
+	}
+	if _, err := svc.CalculateDispatchETAs(ctx, d.ID, 35.6895, 139.6917, f.UserID, []string{north}); err != nil {
+		t.Errorf("CalculateDispatchETAs from the vehicle's depot: %v", err)
+	}
+}
@@ -0,0 +1,234 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/notify"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+// DocumentService keeps the registration, insurance, emission test and
+// licence papers of vehicles and drivers, and warns admins before and when
+// they expire. Dispatch refuses vehicles whose compliance documents expired.
+type DocumentService struct {
+	repo        *repository.DocumentRepo
+	vehicleRepo *repository.VehicleRepo
+	userRepo    *repository.UserRepo
+	auditSvc    *AuditService
+	fcmSvc      *notify.FCMService
+	warnDays    int
+}
+
+func NewDocumentService(repo *repository.DocumentRepo, vehicleRepo *repository.VehicleRepo, userRepo *repository.UserRepo, auditSvc *AuditService, fcmSvc *notify.FCMService, warnDays int) *DocumentService {
+	return &DocumentService{repo: repo, vehicleRepo: vehicleRepo, userRepo: userRepo, auditSvc: auditSvc, fcmSvc: fcmSvc, warnDays: warnDays}
+}
+
+func (s *DocumentService) List(ctx context.Context, vehicleID, userID, docType string, expiringWithin *int, limit, offset int) ([]model.Document, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	if expiringWithin != nil && *expiringWithin < 0 {
+		return nil, apperror.New(400, "VALIDATION_ERROR", "expiring_within_days must not be negative")
+	}
+	return s.repo.List(ctx, vehicleID, userID, docType, expiringWithin, limit, offset)
+}
+
+func (s *DocumentService) GetByID(ctx context.Context, id string) (*model.Document, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *DocumentService) get(ctx context.Context, id string) (*model.Document, error) {
+	doc, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if doc == nil {
+		return nil, apperror.ErrNotFound
+	}
+	return doc, nil
+}
+
+// parseDay reads a YYYY-MM-DD calendar day. Empty means none.
+func parseDay(field string, s *string) (*time.Time, error) {
+	if s == nil || *s == "" {
+		return nil, nil
+	}
+	t, err := time.Parse("2006-01-02", *s)
+	if err != nil {
+		return nil, apperror.New(400, "VALIDATION_ERROR", field+" must be a date (YYYY-MM-DD)")
+	}
+	return &t, nil
+}
+
+func validateDocument(doc *model.Document) error {
+	if doc.Number != nil && utf8.RuneCountInString(*doc.Number) > 100 {
+		return apperror.New(400, "VALIDATION_ERROR", "number must be at most 100 characters")
+	}
+	if doc.IssuedOn != nil && doc.ExpiresOn != nil && doc.ExpiresOn.Before(*doc.IssuedOn) {
+		return apperror.New(400, "INVALID_TIME_RANGE", "expires_on must not be before issued_on")
+	}
+	return nil
+}
+
+// CreateForVehicle adds a document of a vehicle.
+func (s *DocumentService) CreateForVehicle(ctx context.Context, actorID, vehicleID string, req dto.CreateDocumentRequest) (*model.Document, error) {
+	v, err := s.vehicleRepo.GetByID(ctx, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, apperror.ErrNotFound
+	}
+	return s.create(ctx, actorID, &model.Document{VehicleID: &vehicleID}, req)
+}
+
+// CreateForUser adds a document of a user, typically a driver's licence.
+func (s *DocumentService) CreateForUser(ctx context.Context, actorID, userID string, req dto.CreateDocumentRequest) (*model.Document, error) {
+	u, err := s.userRepo.GetByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if u == nil {
+		return nil, apperror.ErrNotFound
+	}
+	return s.create(ctx, actorID, &model.Document{UserID: &userID}, req)
+}
+
+func (s *DocumentService) create(ctx context.Context, actorID string, doc *model.Document, req dto.CreateDocumentRequest) (*model.Document, error) {
+	if !req.Type.IsValid() {
+		return nil, apperror.New(400, "INVALID_TYPE", "unknown document type")
+	}
+	if !req.Type.AppliesTo(doc.VehicleID != nil) {
+		return nil, apperror.New(400, "INVALID_TYPE", "a "+string(req.Type)+" document cannot belong to this subject")
+	}
+	var err error
+	if doc.IssuedOn, err = parseDay("issued_on", req.IssuedOn); err != nil {
+		return nil, err
+	}
+	if doc.ExpiresOn, err = parseDay("expires_on", req.ExpiresOn); err != nil {
+		return nil, err
+	}
+	doc.Type = req.Type
+	doc.Number = req.Number
+	doc.Notes = req.Notes
+	doc.CreatedBy = actorID
+	if err := validateDocument(doc); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Create(ctx, doc); err != nil {
+		return nil, err
+	}
+	s.auditSvc.Log(ctx, actorID, "document.create", "document", doc.ID, nil, doc, "")
+	return doc, nil
+}
+
+// Update corrects a document's details. A renewal is a new document, so the
+// expiry warnings of the old one stay as they were.
+func (s *DocumentService) Update(ctx context.Context, actorID, id string, req dto.UpdateDocumentRequest) (*model.Document, error) {
+	before, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	doc := *before
+	if req.Number != nil {
+		doc.Number = req.Number
+	}
+	if req.IssuedOn != nil {
+		if doc.IssuedOn, err = parseDay("issued_on", req.IssuedOn); err != nil {
+			return nil, err
+		}
+	}
+	if req.ExpiresOn != nil {
+		if doc.ExpiresOn, err = parseDay("expires_on", req.ExpiresOn); err != nil {
+			return nil, err
+		}
+	}
+	if req.Notes != nil {
+		doc.Notes = req.Notes
+	}
+	if err := validateDocument(&doc); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.Update(ctx, &doc); err != nil {
+		return nil, err
+	}
+	after, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.auditSvc.Log(ctx, actorID, "document.update", "document", id, before, after, "")
+	return after, nil
+}
+
+// SetScan records the stored scan of a document, nil to drop it.
+func (s *DocumentService) SetScan(ctx context.Context, actorID, id string, scanFile *string) error {
+	if err := s.repo.SetScan(ctx, id, scanFile); err != nil {
+		return err
+	}
+	s.auditSvc.Log(ctx, actorID, "document.scan", "document", id, nil, map[string]interface{}{"scan_file": scanFile}, "")
+	return nil
+}
+
+func (s *DocumentService) Delete(ctx context.Context, actorID, id string) error {
+	before, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+	s.auditSvc.Log(ctx, actorID, "document.delete", "document", id, before, nil, "")
+	return nil
+}
+
+// SendExpiryWarnings pushes to admins a warning for each current document
+// entering its last warnDays, and another once it has expired. Each warning
+// is claimed in the database before sending, so overlapping runs send it
+// once. Returns the number sent.
+func (s *DocumentService) SendExpiryWarnings(ctx context.Context) (int, error) {
+	due, err := s.repo.ListExpiring(ctx, s.warnDays)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	sent := 0
+	for _, doc := range due {
+		stage, ok := model.ExpiryStage(*doc.ExpiresOn, now, s.warnDays)
+		if !ok {
+			continue
+		}
+		claimed, err := s.repo.ClaimExpiryAlert(ctx, doc.ID, stage, *doc.ExpiresOn)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		title, body := expiryMessage(doc, stage)
+		s.fcmSvc.NotifyRole(ctx, title, body, map[string]string{
+			"type": "document_expiry", "document_id": doc.ID, "stage": stage,
+		}, model.RoleAdmin)
+		sent++
+	}
+	return sent, nil
+}
+
+func expiryMessage(doc model.DueDocument, stage string) (string, string) {
+	what := strings.ReplaceAll(string(doc.Type), "_", " ")
+	day := doc.ExpiresOn.Format("2006-01-02")
+	if stage == model.DocumentStageExpired {
+		return "Document Expired", fmt.Sprintf("%s: %s expired on %s", doc.SubjectName, what, day)
+	}
+	return "Document Expiring", fmt.Sprintf("%s: %s expires on %s", doc.SubjectName, what, day)
+}
@@ -0,0 +1,133 @@
+package service
+
+import (
+	"context"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/realtime"
+	"github.com/kento/driver/backend/internal/repository"
+)
+
+// FleetStatusTracker publishes vehicle.status events when a vehicle's computed
+// status changes. Services publish their own mutations on the hub; the tracker
+// reacts by re-running ListWithStatus at most once per debounce window and
+// diffing against the last statuses it saw. A periodic refresh catches the
+// purely time-driven transitions (GPS going stale, a reservation window opening).
+type FleetStatusTracker struct {
+	vehicleRepo *repository.VehicleRepo
+	hub         *realtime.Hub
+	staleThr    time.Duration
+	debounce    time.Duration
+
+	mu   sync.Mutex
+	last map[string]model.VehicleStatus
+	kick chan struct{}
+}
+
+func NewFleetStatusTracker(vehicleRepo *repository.VehicleRepo, hub *realtime.Hub, staleThr time.Duration) *FleetStatusTracker {
+	return &FleetStatusTracker{
+		vehicleRepo: vehicleRepo,
+		hub:         hub,
+		staleThr:    staleThr,
+		debounce:    time.Second,
+		kick:        make(chan struct{}, 1),
+	}
+}
+
+// Run consumes hub events and refreshes statuses every interval until the hub
+// is closed. It blocks, so callers start it in its own goroutine.
+func (t *FleetStatusTracker) Run(interval time.Duration) {
+	stop := make(chan struct{})
+	defer close(stop)
+	go t.refreshLoop(interval, stop)
+
+	for !t.hub.Closed() {
+		sub := t.hub.Subscribe(256, isStatusTrigger)
+		for e := range sub.Events() {
+			if e.Type == realtime.EventVehicleLocation && !t.mayLeaveStale(e.VehicleID) {
+				continue
+			}
+			select {
+			case t.kick <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
+
+func (t *FleetStatusTracker) refreshLoop(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		case <-t.kick:
+			// Coalesce bursts (e.g. assign + accept + en_route) into one query.
+			select {
+			case <-stop:
+				return
+			case <-time.After(t.debounce):
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		if err := t.Refresh(ctx); err != nil {
+			log.Printf("[realtime] status refresh failed: %v", err)
+		}
+		cancel()
+	}
+}
+
+// Refresh recomputes fleet statuses and publishes an event for each vehicle
+// whose status differs from the previous refresh. The first refresh only
+// records a baseline; connecting clients get the full picture from the snapshot.
+func (t *FleetStatusTracker) Refresh(ctx context.Context) error {
+	vehicles, err := t.vehicleRepo.ListWithStatus(ctx, t.staleThr, nil)
+	if err != nil {
+		return err
+	}
+
+	t.mu.Lock()
+	seeded := t.last != nil
+	next := make(map[string]model.VehicleStatus, len(vehicles))
+	var changes []dto.VehicleStatusChange
+	for _, v := range vehicles {
+		next[v.ID] = v.Status
+		if prev, ok := t.last[v.ID]; seeded && (!ok || prev != v.Status) {
+			changes = append(changes, dto.VehicleStatusChange{PreviousStatus: prev, Vehicle: v})
+		}
+	}
+	t.last = next
+	t.mu.Unlock()
+
+	for _, c := range changes {
+		t.hub.Publish(realtime.Event{Type: realtime.EventVehicleStatus, VehicleID: c.Vehicle.ID, Data: c})
+	}
+	return nil
+}
+
+// mayLeaveStale reports whether a location report could change the vehicle's
+// status. Only vehicles currently marked stale (or not yet seen) can flip on
+// a fresh fix, so ordinary position updates do not trigger a refresh.
+func (t *FleetStatusTracker) mayLeaveStale(vehicleID string) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	status, ok := t.last[vehicleID]
+	return !ok || status == model.VehicleStatusStale
+}
+
+func isStatusTrigger(e realtime.Event) bool {
+	switch e.Type {
+	case realtime.EventDispatchUpdated, realtime.EventVehicleUpdated, realtime.EventVehicleDeleted,
+		realtime.EventDriverAttendance, realtime.EventVehicleLocation:
+		return true
+	}
+	return false
+}
@@ -5,22 +5,36 @@ import (
 	"time"
 
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/realtime"
 	"github.com/kento/driver/backend/internal/repository"
 )
 
 type LocationService struct {
 	repo *repository.LocationRepo
+	hub  *realtime.Hub
 }
 
-func NewLocationService(repo *repository.LocationRepo) *LocationService {
-	return &LocationService{repo: repo}
+func NewLocationService(repo *repository.LocationRepo, hub *realtime.Hub) *LocationService {
+	return &LocationService{repo: repo, hub: hub}
 }
 
 func (s *LocationService) ReportLocations(ctx context.Context, vehicleID string, points []model.LocationPoint) error {
 	if len(points) == 0 {
 		return nil
 	}
-	return s.repo.BatchInsert(ctx, vehicleID, points)
+	if err := s.repo.BatchInsert(ctx, vehicleID, points); err != nil {
+		return err
+	}
+
+	// Only the newest point matters to live clients; history is queryable.
+	latest := points[0]
+	for _, p := range points[1:] {
+		if p.RecordedAt.After(latest.RecordedAt) {
+			latest = p
+		}
+	}
+	s.hub.Publish(realtime.Event{Type: realtime.EventVehicleLocation, VehicleID: vehicleID, Data: latest})
+	return nil
 }
 
 func (s *LocationService) GetHistory(ctx context.Context, vehicleID string, from, to time.Time) ([]model.VehicleLocation, error) {
@@ -30,3 +44,27 @@ func (s *LocationService) GetHistory(ctx context.Context, vehicleID string, from
 func (s *LocationService) GetCurrent(ctx context.Context, vehicleID string) (*model.VehicleLocationCurrent, error) {
 	return s.repo.GetCurrent(ctx, vehicleID)
 }
+
+// EnsurePartitions creates the raw history partitions for today and the next
+// daysAhead days so incoming points never fall into the default partition.
+func (s *LocationService) EnsurePartitions(ctx context.Context, daysAhead int) (int, error) {
+	now := time.Now()
+	return s.repo.EnsurePartitions(ctx, now, now.AddDate(0, 0, daysAhead))
+}
+
+// ApplyRetention ages out location history: raw points older than rawDays
+// are downsampled to one point per minute, and downsampled points older than
+// maxDays are deleted. Returns the number of partitions dropped.
+func (s *LocationService) ApplyRetention(ctx context.Context, rawDays, maxDays int) (int, error) {
+	if maxDays < rawDays {
+		maxDays = rawDays
+	}
+	now := time.Now()
+
+	raw, err := s.repo.DownsampleBefore(ctx, now.AddDate(0, 0, -rawDays))
+	if err != nil {
+		return raw, err
+	}
+	downsampled, err := s.repo.DeleteDownsampledBefore(ctx, now.AddDate(0, 0, -maxDays))
+	return raw + downsampled, err
+}
@@ -0,0 +1,385 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/realtime"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+// MaintenanceService plans and tracks the windows that take vehicles out of
+// service. A window blocks new reservations and dispatches; reservations
+// already holding its time are flagged as maintenance conflicts, and are
+// released again when the window moves or closes.
+type MaintenanceService struct {
+	repo           *repository.MaintenanceRepo
+	vehicleRepo    *repository.VehicleRepo
+	reservationSvc *ReservationService
+	auditSvc       *AuditService
+	hub            *realtime.Hub
+	// overrunLead is how far ahead work running past its end flags the
+	// reservations it holds up.
+	overrunLead time.Duration
+}
+
+func NewMaintenanceService(repo *repository.MaintenanceRepo, vehicleRepo *repository.VehicleRepo, reservationSvc *ReservationService, auditSvc *AuditService, hub *realtime.Hub, overrunLead time.Duration) *MaintenanceService {
+	return &MaintenanceService{repo: repo, vehicleRepo: vehicleRepo, reservationSvc: reservationSvc, auditSvc: auditSvc, hub: hub, overrunLead: overrunLead}
+}
+
+var errMaintenanceClosed = apperror.New(400, "INVALID_STATUS", "maintenance window is already done or cancelled")
+
+func (s *MaintenanceService) List(ctx context.Context, vehicleID, status string, from, to time.Time, limit, offset int) ([]model.MaintenanceWindow, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return s.repo.List(ctx, vehicleID, status, from, to, limit, offset)
+}
+
+func (s *MaintenanceService) GetByID(ctx context.Context, id string) (*model.MaintenanceWindow, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+func (s *MaintenanceService) get(ctx context.Context, id string) (*model.MaintenanceWindow, error) {
+	w, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if w == nil {
+		return nil, apperror.ErrNotFound
+	}
+	return w, nil
+}
+
+func validateWindow(w *model.MaintenanceWindow) error {
+	if !w.Type.IsValid() {
+		return apperror.New(400, "INVALID_TYPE", "unknown maintenance type")
+	}
+	if !w.EndTime.After(w.StartTime) {
+		return apperror.New(400, "INVALID_TIME_RANGE", "end_time must be after start_time")
+	}
+	return nil
+}
+
+// CreateWindow schedules maintenance on a vehicle.
+func (s *MaintenanceService) CreateWindow(ctx context.Context, actorID, vehicleID string, req dto.CreateMaintenanceWindowRequest) (*model.MaintenanceWindow, error) {
+	w := &model.MaintenanceWindow{
+		VehicleID: vehicleID,
+		Type:      req.Type,
+		StartTime: req.StartTime,
+		EndTime:   req.EndTime,
+		Vendor:    req.Vendor,
+		Notes:     req.Notes,
+		CreatedBy: actorID,
+	}
+	if err := validateWindow(w); err != nil {
+		return nil, err
+	}
+	if !w.EndTime.After(time.Now()) {
+		return nil, apperror.New(400, "PAST_TIME", "cannot schedule maintenance that has already ended")
+	}
+	v, err := s.vehicleRepo.GetByID(ctx, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, apperror.ErrNotFound
+	}
+
+	if err := s.repo.Create(ctx, w); err != nil {
+		return nil, err
+	}
+	s.auditSvc.Log(ctx, actorID, "maintenance.create", "maintenance", w.ID, nil, w, "")
+	s.changed(ctx, w, actorID)
+	return w, nil
+}
+
+// UpdateWindow edits an open window. Moving it flags the reservations it now
+// covers and releases the ones it no longer does.
+func (s *MaintenanceService) UpdateWindow(ctx context.Context, actorID, id string, req dto.UpdateMaintenanceWindowRequest) (*model.MaintenanceWindow, error) {
+	before, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	w := *before
+	if req.Type != nil {
+		w.Type = *req.Type
+	}
+	if req.StartTime != nil {
+		w.StartTime = *req.StartTime
+	}
+	if req.EndTime != nil {
+		w.EndTime = *req.EndTime
+	}
+	if req.Vendor != nil {
+		w.Vendor = req.Vendor
+	}
+	if req.Notes != nil {
+		w.Notes = req.Notes
+	}
+	if err := validateWindow(&w); err != nil {
+		return nil, err
+	}
+
+	updated, err := s.repo.Update(ctx, &w)
+	if err != nil {
+		return nil, err
+	}
+	if !updated {
+		return nil, errMaintenanceClosed
+	}
+	after, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.auditSvc.Log(ctx, actorID, "maintenance.update", "maintenance", id, before, after, "")
+	s.changed(ctx, after, actorID)
+	return after, nil
+}
+
+// StartWindow marks planned work as under way. From then on the vehicle is
+// blocked until the work is completed, however long it overruns.
+func (s *MaintenanceService) StartWindow(ctx context.Context, actorID, id string) (*model.MaintenanceWindow, error) {
+	before, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	started, err := s.repo.Start(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !started {
+		return nil, apperror.New(400, "INVALID_STATUS", "only planned maintenance can be started")
+	}
+	after, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	s.auditSvc.Log(ctx, actorID, "maintenance.start", "maintenance", id, before, after, "")
+	s.changed(ctx, after, actorID)
+	return after, nil
+}
+
+// CompleteWindow marks maintenance done, returning the vehicle to service.
+// The odometer reading, if given, updates the vehicle's, and a window planned
+// from a schedule restarts that schedule's intervals.
+func (s *MaintenanceService) CompleteWindow(ctx context.Context, actorID, id string, odometerKm *int) (*model.MaintenanceWindow, error) {
+	if odometerKm != nil && *odometerKm < 0 {
+		return nil, apperror.New(400, "INVALID_ODOMETER", "odometer_km must not be negative")
+	}
+	before, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	completed, err := s.repo.Complete(ctx, id, odometerKm)
+	if err != nil {
+		return nil, err
+	}
+	if !completed {
+		return nil, apperror.New(400, "INVALID_STATUS", "only maintenance that has begun can be completed")
+	}
+	after, err := s.get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	if odometerKm != nil {
+		if err := s.vehicleRepo.RecordOdometer(ctx, after.VehicleID, *odometerKm); err != nil {
+			log.Printf("[maintenance] record odometer of %s: %v", after.VehicleID, err)
+		}
+	}
+	if after.ScheduleID != nil && after.CompletedAt != nil {
+		if err := s.repo.MarkScheduleDone(ctx, *after.ScheduleID, *after.CompletedAt, odometerKm); err != nil {
+			log.Printf("[maintenance] mark schedule %s done: %v", *after.ScheduleID, err)
+		}
+	}
+
+	s.auditSvc.Log(ctx, actorID, "maintenance.complete", "maintenance", id, before, after, "")
+	s.changed(ctx, after, actorID)
+	return after, nil
+}
+
+// CancelWindow drops maintenance whose work has not started.
+func (s *MaintenanceService) CancelWindow(ctx context.Context, actorID, id, reason string) error {
+	before, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	cancelled, err := s.repo.Cancel(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return apperror.New(400, "INVALID_STATUS", "only planned maintenance can be cancelled")
+	}
+	after, err := s.get(ctx, id)
+	if err != nil {
+		return err
+	}
+	s.auditSvc.Log(ctx, actorID, "maintenance.cancel", "maintenance", id, before, after, reason)
+	s.changed(ctx, after, actorID)
+	return nil
+}
+
+// changed brings the reservations around w in line after it was saved and
+// tells stream subscribers the vehicle's status may have changed. The window
+// is already saved, so failures are logged.
+func (s *MaintenanceService) changed(ctx context.Context, w *model.MaintenanceWindow, actorID string) {
+	if err := s.reservationSvc.SyncMaintenance(ctx, w, actorID, s.overrunLead); err != nil {
+		log.Printf("[maintenance] conflicts of %s: %v", w.ID, err)
+	}
+	v, err := s.vehicleRepo.GetByID(ctx, w.VehicleID)
+	if err != nil || v == nil {
+		return
+	}
+	s.hub.Publish(realtime.Event{Type: realtime.EventVehicleUpdated, VehicleID: v.ID, Data: v})
+}
+
+// CreateSchedule sets up recurring maintenance on a vehicle.
+func (s *MaintenanceService) CreateSchedule(ctx context.Context, actorID, vehicleID string, req dto.CreateMaintenanceScheduleRequest) (*model.MaintenanceSchedule, error) {
+	if !req.Type.IsValid() {
+		return nil, apperror.New(400, "INVALID_TYPE", "unknown maintenance type")
+	}
+	if req.DurationMin <= 0 {
+		return nil, apperror.New(400, "VALIDATION_ERROR", "duration_min must be positive")
+	}
+	if req.IntervalDays == nil && req.IntervalKm == nil {
+		return nil, apperror.New(400, "VALIDATION_ERROR", "interval_days or interval_km is required")
+	}
+	if (req.IntervalDays != nil && *req.IntervalDays <= 0) || (req.IntervalKm != nil && *req.IntervalKm <= 0) {
+		return nil, apperror.New(400, "VALIDATION_ERROR", "intervals must be positive")
+	}
+	if req.LastDoneKm != nil && *req.LastDoneKm < 0 {
+		return nil, apperror.New(400, "INVALID_ODOMETER", "last_done_km must not be negative")
+	}
+	v, err := s.vehicleRepo.GetByID(ctx, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, apperror.ErrNotFound
+	}
+
+	sched := &model.MaintenanceSchedule{
+		VehicleID:    vehicleID,
+		Type:         req.Type,
+		Vendor:       req.Vendor,
+		Notes:        req.Notes,
+		DurationMin:  req.DurationMin,
+		IntervalDays: req.IntervalDays,
+		IntervalKm:   req.IntervalKm,
+		LastDoneAt:   time.Now(),
+		LastDoneKm:   req.LastDoneKm,
+		CreatedBy:    actorID,
+	}
+	if req.LastDoneAt != nil {
+		sched.LastDoneAt = *req.LastDoneAt
+	}
+	if sched.LastDoneKm == nil {
+		sched.LastDoneKm = v.OdometerKm
+	}
+
+	if err := s.repo.CreateSchedule(ctx, sched); err != nil {
+		return nil, err
+	}
+	s.auditSvc.Log(ctx, actorID, "maintenance_schedule.create", "maintenance_schedule", sched.ID, nil, sched, "")
+	return sched, nil
+}
+
+func (s *MaintenanceService) ListSchedules(ctx context.Context, vehicleID string) ([]model.MaintenanceSchedule, error) {
+	return s.repo.ListSchedules(ctx, vehicleID)
+}
+
+// DeleteSchedule stops a schedule. Windows already planned from it stay.
+func (s *MaintenanceService) DeleteSchedule(ctx context.Context, actorID, id string) error {
+	before, err := s.repo.GetSchedule(ctx, id)
+	if err != nil {
+		return err
+	}
+	if before == nil {
+		return apperror.ErrNotFound
+	}
+	if err := s.repo.DeleteSchedule(ctx, id); err != nil {
+		return err
+	}
+	s.auditSvc.Log(ctx, actorID, "maintenance_schedule.delete", "maintenance_schedule", id, before, nil, "")
+	return nil
+}
+
+// PlanDue plans a window for every schedule falling due within lead. The
+// window starts on the hour it falls due, or lead from now when that is
+// already past, so there is time to move the reservations it displaces.
+// Windows are attributed to whoever set up the schedule. Returns the number
+// planned.
+func (s *MaintenanceService) PlanDue(ctx context.Context, lead time.Duration) (int, error) {
+	schedules, err := s.repo.ListUnplannedSchedules(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	planned := 0
+	for _, sched := range schedules {
+		v, err := s.vehicleRepo.GetByID(ctx, sched.VehicleID)
+		if err != nil {
+			return planned, err
+		}
+		if v == nil {
+			continue
+		}
+		dueAt, ok := sched.DueAt(v.OdometerKm, now)
+		if !ok || dueAt.After(now.Add(lead)) {
+			continue
+		}
+
+		start := dueAt.Truncate(time.Hour)
+		if !start.After(now) {
+			start = now.Add(lead).Truncate(time.Hour)
+		}
+		schedID := sched.ID
+		w := &model.MaintenanceWindow{
+			VehicleID:  sched.VehicleID,
+			ScheduleID: &schedID,
+			Type:       sched.Type,
+			StartTime:  start,
+			EndTime:    start.Add(time.Duration(sched.DurationMin) * time.Minute),
+			Vendor:     sched.Vendor,
+			Notes:      sched.Notes,
+			CreatedBy:  sched.CreatedBy,
+		}
+		created, err := s.repo.CreateForSchedule(ctx, w)
+		if err != nil {
+			return planned, err
+		}
+		if !created {
+			continue
+		}
+		planned++
+		s.auditSvc.Log(ctx, sched.CreatedBy, "maintenance.plan", "maintenance", w.ID, nil, w, "maintenance schedule due")
+		s.changed(ctx, w, sched.CreatedBy)
+	}
+	return planned, nil
+}
+
+// SyncOverrunning flags the reservations held up by work still in progress
+// past its planned end. Nothing else touches such a window until it is
+// completed, so this is what catches the bookings the overrun now reaches.
+// Conflicts are attributed to whoever planned the window. Returns the number
+// of windows checked.
+func (s *MaintenanceService) SyncOverrunning(ctx context.Context) (int, error) {
+	windows, err := s.repo.ListOverrunning(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for i := range windows {
+		w := &windows[i]
+		if err := s.reservationSvc.SyncMaintenance(ctx, w, w.CreatedBy, s.overrunLead); err != nil {
+			return i, err
+		}
+	}
+	return len(windows), nil
+}
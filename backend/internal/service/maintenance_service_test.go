@@ -0,0 +1,81 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/notify"
+	"github.com/kento/driver/backend/internal/realtime"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/internal/testdb"
+)
+
+// TestSyncOverrunning leaves work in progress an hour past its end and books
+// the vehicle to start within the overrun lead. The job must flag that
+// booking as a maintenance conflict, and leave one starting later alone.
+// Needs a migrated Postgres in TEST_DATABASE_URL.
+func TestSyncOverrunning(t *testing.T) {
+	conn := testdb.Open(t)
+	ctx := context.Background()
+	f := testdb.NewFixture(t, conn, "Overrun Test", model.RoleDispatcher)
+	userID, vehicleID := f.UserID, f.VehicleID
+
+	now := time.Now()
+	if _, err := conn.ExecContext(ctx, `
+		INSERT INTO maintenance_windows (vehicle_id, type, status, start_time, end_time, created_by)
+		VALUES ($1, 'repair', 'in_progress', $2, $3, $4)`,
+		vehicleID, now.Add(-3*time.Hour), now.Add(-time.Hour), userID); err != nil {
+		t.Fatalf("insert window: %v", err)
+	}
+
+	reservationRepo := repository.NewReservationRepo(conn)
+	soon := &model.Reservation{
+		VehicleID: vehicleID, RequesterID: userID, Purpose: "overrun test",
+		StartTime: now.Add(10 * time.Minute), EndTime: now.Add(40 * time.Minute),
+		Status: model.ReservationStatusConfirmed,
+	}
+	later := &model.Reservation{
+		VehicleID: vehicleID, RequesterID: userID, Purpose: "overrun test",
+		StartTime: now.Add(2 * time.Hour), EndTime: now.Add(3 * time.Hour),
+		Status: model.ReservationStatusConfirmed,
+	}
+	for _, res := range []*model.Reservation{soon, later} {
+		if err := reservationRepo.Create(ctx, res); err != nil {
+			t.Fatalf("create reservation: %v", err)
+		}
+	}
+
+	vehicleRepo := repository.NewVehicleRepo(conn)
+	maintenanceRepo := repository.NewMaintenanceRepo(conn)
+	auditSvc := NewAuditService(repository.NewAuditRepo(conn))
+	hub := realtime.NewHub()
+	defer hub.Close()
+	fcmSvc, _ := notify.NewFCMService("", repository.NewUserRepo(conn))
+	waitlistSvc := NewWaitlistService(repository.NewWaitlistRepo(conn), reservationRepo, NewAuthorizer(repository.NewVehicleRepo(conn), auditSvc), auditSvc, fcmSvc)
+	reservationSvc := NewReservationService(reservationRepo, repository.NewConflictRepo(conn), vehicleRepo,
+		maintenanceRepo, repository.NewDispatchRepo(conn), waitlistSvc, auditSvc, hub, nil, time.Second)
+	svc := NewMaintenanceService(maintenanceRepo, vehicleRepo, reservationSvc, auditSvc, hub, 30*time.Minute)
+
+	if _, err := svc.SyncOverrunning(ctx); err != nil {
+		t.Fatalf("SyncOverrunning: %v", err)
+	}
+
+	for _, tc := range []struct {
+		res  *model.Reservation
+		want model.ReservationStatus
+	}{
+		{soon, model.ReservationStatusPendingConflict},
+		{later, model.ReservationStatusConfirmed},
+	} {
+		got, err := reservationRepo.GetByID(ctx, tc.res.ID)
+		if err != nil {
+			t.Fatalf("get reservation: %v", err)
+		}
+		if got.Status != tc.want {
+			t.Errorf("reservation starting %s is %s, want %s",
+				tc.res.StartTime.Sub(now).Round(time.Minute), got.Status, tc.want)
+		}
+	}
+}
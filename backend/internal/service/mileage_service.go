@@ -0,0 +1,241 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sort"
+	"time"
+	"unicode/utf8"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+// Mileage reports cover whole UTC days, at most maxMileageDays of them, and
+// the last defaultMileageDays when no range is given.
+const (
+	maxMileageDays     = 92
+	defaultMileageDays = 30
+)
+
+// MileageService records refuelling and reports how far vehicles drove, by
+// the odometer readings drivers give at clock-in and clock-out and by their
+// GPS track, flagging days on which the two disagree.
+type MileageService struct {
+	repo         *repository.MileageRepo
+	vehicleRepo  *repository.VehicleRepo
+	auditSvc     *AuditService
+	anomalyPct   float64
+	anomalyMinKm float64
+}
+
+func NewMileageService(repo *repository.MileageRepo, vehicleRepo *repository.VehicleRepo, auditSvc *AuditService, anomalyPct, anomalyMinKm float64) *MileageService {
+	return &MileageService{repo: repo, vehicleRepo: vehicleRepo, auditSvc: auditSvc, anomalyPct: anomalyPct, anomalyMinKm: anomalyMinKm}
+}
+
+// CreateFuelLog records a refuelling of the driver's vehicle. FueledAt
+// defaults to now.
+func (s *MileageService) CreateFuelLog(ctx context.Context, driverID string, req dto.CreateFuelLogRequest) (*model.FuelLog, error) {
+	if req.Litres <= 0 {
+		return nil, apperror.New(400, "VALIDATION_ERROR", "litres must be positive")
+	}
+	if req.Cost < 0 {
+		return nil, apperror.New(400, "VALIDATION_ERROR", "cost must not be negative")
+	}
+	if req.Station != nil && utf8.RuneCountInString(*req.Station) > 200 {
+		return nil, apperror.New(400, "VALIDATION_ERROR", "station must be at most 200 characters")
+	}
+	if req.OdometerKm != nil && *req.OdometerKm < 0 {
+		return nil, errInvalidOdometer
+	}
+	fueledAt := time.Now()
+	if req.FueledAt != nil {
+		if req.FueledAt.After(fueledAt) {
+			return nil, apperror.New(400, "VALIDATION_ERROR", "fueled_at must not be in the future")
+		}
+		fueledAt = *req.FueledAt
+	}
+	v, err := s.vehicleRepo.GetByDriverID(ctx, driverID)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, apperror.New(400, "NO_VEHICLE", "driver has no vehicle checked out")
+	}
+
+	f := &model.FuelLog{
+		VehicleID:  v.ID,
+		DriverID:   driverID,
+		Litres:     req.Litres,
+		Cost:       req.Cost,
+		Station:    req.Station,
+		OdometerKm: req.OdometerKm,
+		FueledAt:   fueledAt,
+	}
+	if err := s.repo.CreateFuelLog(ctx, f); err != nil {
+		return nil, err
+	}
+	if f.OdometerKm != nil {
+		if err := s.vehicleRepo.RecordOdometer(ctx, v.ID, *f.OdometerKm); err != nil {
+			log.Printf("[mileage] record odometer of %s: %v", v.ID, err)
+		}
+	}
+	s.auditSvc.Log(ctx, driverID, "fuel_log.create", "fuel_log", f.ID, nil, f, "")
+	return f, nil
+}
+
+func (s *MileageService) GetFuelLog(ctx context.Context, id string) (*model.FuelLog, error) {
+	return s.repo.GetFuelLog(ctx, id)
+}
+
+func (s *MileageService) SetFuelReceipt(ctx context.Context, id string, receiptURL *string) error {
+	return s.repo.SetFuelReceipt(ctx, id, receiptURL)
+}
+
+func (s *MileageService) ListFuelLogs(ctx context.Context, vehicleID string, from, to time.Time) ([]model.FuelLog, error) {
+	from, to, err := mileageRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	return s.repo.ListFuelLogs(ctx, vehicleID, from, to)
+}
+
+// mileageRange widens [from, to) to whole UTC days. A zero to means today
+// and a zero from the default span before it.
+func mileageRange(from, to time.Time) (time.Time, time.Time, error) {
+	if to.IsZero() {
+		to = time.Now()
+	}
+	// The day to falls on is included
+	to = to.UTC().Truncate(24*time.Hour).AddDate(0, 0, 1)
+	if from.IsZero() {
+		from = to.AddDate(0, 0, -defaultMileageDays)
+	}
+	from = from.UTC().Truncate(24 * time.Hour)
+	if !to.After(from) {
+		return from, to, apperror.New(400, "INVALID_TIME_RANGE", "to must not be before from")
+	}
+	if to.Sub(from) > maxMileageDays*24*time.Hour {
+		return from, to, apperror.New(400, "WINDOW_TOO_LARGE", "mileage reports may span at most 92 days")
+	}
+	return from, to, nil
+}
+
+// Mileage reports a vehicle's distance, refuelling and fuel economy per day
+// over [from, to). Economy is by the odometer when there are readings, and
+// by GPS otherwise.
+func (s *MileageService) Mileage(ctx context.Context, vehicleID string, from, to time.Time) (*dto.VehicleMileage, error) {
+	from, to, err := mileageRange(from, to)
+	if err != nil {
+		return nil, err
+	}
+	v, err := s.vehicleRepo.GetByID(ctx, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if v == nil {
+		return nil, apperror.ErrNotFound
+	}
+
+	shifts, err := s.repo.ShiftDistances(ctx, vehicleID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	gps, err := s.repo.DailyGPSDistance(ctx, vehicleID, from, to)
+	if err != nil {
+		return nil, err
+	}
+	fuel, err := s.repo.DailyFuel(ctx, vehicleID, from, to)
+	if err != nil {
+		return nil, err
+	}
+
+	out := &dto.VehicleMileage{
+		VehicleID:  vehicleID,
+		From:       from,
+		To:         to,
+		OdometerKm: v.OdometerKm,
+		Days:       mergeMileageDays(shifts, gps, fuel, s.anomalyPct, s.anomalyMinKm),
+		Anomalies:  []dto.DailyMileage{},
+	}
+	for i := range out.Days {
+		d := &out.Days[i]
+		if d.OdometerKm != nil {
+			out.DistanceOdometerKm += *d.OdometerKm
+		}
+		out.DistanceGPSKm += d.GPSKm
+		out.FuelLitres += d.FuelLitres
+		out.FuelCost += d.FuelCost
+		if d.Anomaly {
+			out.Anomalies = append(out.Anomalies, *d)
+		}
+	}
+	out.DistanceGPSKm = roundTenth(out.DistanceGPSKm)
+	out.FuelLitres = roundHundredth(out.FuelLitres)
+	out.FuelCost = roundHundredth(out.FuelCost)
+
+	distance := out.DistanceOdometerKm
+	if distance == 0 {
+		distance = out.DistanceGPSKm
+	}
+	if distance > 0 && out.FuelLitres > 0 {
+		economy := roundHundredth(distance / out.FuelLitres)
+		out.KmPerLitre = &economy
+	}
+	return out, nil
+}
+
+// mergeMileageDays lines up the per-day figures, one entry per day with any
+// of them, in order. Odometer distance is summed over the shifts clocked in
+// that day, and a day is an anomaly when one of them disagrees with the GPS
+// track over that same shift, so a shift running past midnight is not held
+// against the day's GPS total.
+func mergeMileageDays(shifts []model.ShiftDistance, gps []model.DailyDistance, fuel []model.DailyFuel, anomalyPct, anomalyMinKm float64) []dto.DailyMileage {
+	byDay := map[string]*dto.DailyMileage{}
+	day := func(t time.Time) *dto.DailyMileage {
+		key := t.Format("2006-01-02")
+		d, ok := byDay[key]
+		if !ok {
+			d = &dto.DailyMileage{Date: key}
+			byDay[key] = d
+		}
+		return d
+	}
+	for _, sh := range shifts {
+		d := day(sh.Day)
+		km := sh.OdometerKm
+		if d.OdometerKm != nil {
+			km += *d.OdometerKm
+		}
+		d.OdometerKm = &km
+		if model.DistanceDiverges(sh.OdometerKm, sh.GPSKm, anomalyPct, anomalyMinKm) {
+			d.Anomaly = true
+		}
+	}
+	for _, g := range gps {
+		day(g.Day).GPSKm = roundTenth(g.Km)
+	}
+	for _, f := range fuel {
+		d := day(f.Day)
+		d.FuelLitres = roundHundredth(f.Litres)
+		d.FuelCost = roundHundredth(f.Cost)
+	}
+
+	days := make([]dto.DailyMileage, 0, len(byDay))
+	for _, d := range byDay {
+		days = append(days, *d)
+	}
+	sort.Slice(days, func(i, j int) bool { return days[i].Date < days[j].Date })
+	return days
+}
+
+func roundTenth(f float64) float64 {
+	return math.Round(f*10) / 10
+}
+
+func roundHundredth(f float64) float64 {
+	return math.Round(f*100) / 100
+}
@@ -0,0 +1,58 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kento/driver/backend/internal/model"
+)
+
+func TestMergeMileageDays_PerShiftAnomaly(t *testing.T) {
+	day1 := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	day2 := day1.AddDate(0, 0, 1)
+	day3 := day1.AddDate(0, 0, 2)
+
+	shifts := []model.ShiftDistance{
+		// A night shift: clocked in late on day 1, most of its driving on day 2
+		{Day: day1, OdometerKm: 100, GPSKm: 98},
+		// Two shifts on day 3, the second with an odometer far off its track
+		{Day: day3, OdometerKm: 50, GPSKm: 49},
+		{Day: day3, OdometerKm: 120, GPSKm: 40},
+	}
+	gps := []model.DailyDistance{
+		{Day: day1, Km: 30},
+		{Day: day2, Km: 68},
+		{Day: day3, Km: 89},
+	}
+
+	days := mergeMileageDays(shifts, gps, nil, 20, 5)
+
+	want := []struct {
+		date     string
+		odometer *float64
+		anomaly  bool
+	}{
+		{"2026-03-01", floatPtr(100), false},
+		{"2026-03-02", nil, false},
+		{"2026-03-03", floatPtr(170), true},
+	}
+	if len(days) != len(want) {
+		t.Fatalf("got %d days, want %d: %+v", len(days), len(want), days)
+	}
+	for i, w := range want {
+		d := days[i]
+		if d.Date != w.date || d.Anomaly != w.anomaly {
+			t.Errorf("day %d = %s anomaly=%v, want %s anomaly=%v", i, d.Date, d.Anomaly, w.date, w.anomaly)
+		}
+		switch {
+		case w.odometer == nil && d.OdometerKm != nil:
+			t.Errorf("%s odometer = %v, want none", d.Date, *d.OdometerKm)
+		case w.odometer != nil && (d.OdometerKm == nil || *d.OdometerKm != *w.odometer):
+			t.Errorf("%s odometer = %v, want %v", d.Date, d.OdometerKm, *w.odometer)
+		}
+	}
+}
+
+func floatPtr(f float64) *float64 {
+	return &f
+}
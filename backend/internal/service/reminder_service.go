@@ -0,0 +1,70 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"math"
+	"time"
+
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/notify"
+	"github.com/kento/driver/backend/internal/repository"
+)
+
+// ReminderService sends "your car leaves soon" pushes for confirmed
+// reservations. Each (reservation, recipient, start time) is claimed in the
+// database before sending, so a reminder goes out once even when job runs
+// overlap or several replicas run the job.
+type ReminderService struct {
+	repo        *repository.ReservationRepo
+	fcmSvc      *notify.FCMService
+	leadMinutes int
+}
+
+func NewReminderService(repo *repository.ReservationRepo, fcmSvc *notify.FCMService, leadMinutes int) *ReminderService {
+	return &ReminderService{repo: repo, fcmSvc: fcmSvc, leadMinutes: leadMinutes}
+}
+
+// SendDue delivers every reminder that is due and returns how many were sent.
+func (s *ReminderService) SendDue(ctx context.Context) (int, error) {
+	due, err := s.repo.ListDueReminders(ctx, s.leadMinutes)
+	if err != nil {
+		return 0, err
+	}
+
+	sent := 0
+	for _, rem := range due {
+		claimed, err := s.repo.ClaimReminder(ctx, rem.ReservationID, rem.RecipientID, rem.StartTime)
+		if err != nil {
+			return sent, err
+		}
+		if !claimed {
+			continue
+		}
+
+		title, body := reminderMessage(rem, time.Now())
+		if err := s.fcmSvc.SendToUser(ctx, rem.RecipientID, title, body, map[string]string{
+			"type": "reservation_reminder", "reservation_id": rem.ReservationID,
+		}); err != nil {
+			// Give the reminder back so the next run retries it.
+			if relErr := s.repo.ReleaseReminder(ctx, rem.ReservationID, rem.RecipientID, rem.StartTime); relErr != nil {
+				log.Printf("[reminders] release reservation=%s recipient=%s: %v", rem.ReservationID, rem.RecipientID, relErr)
+			}
+			continue
+		}
+		sent++
+	}
+	return sent, nil
+}
+
+func reminderMessage(rem model.DueReminder, now time.Time) (string, string) {
+	minutes := int(math.Ceil(rem.StartTime.Sub(now).Minutes()))
+	if minutes < 1 {
+		minutes = 1
+	}
+	if rem.RecipientRole == "driver" {
+		return "Upcoming Trip", fmt.Sprintf("Reservation starts in %d min: %s", minutes, rem.Purpose)
+	}
+	return "Reservation Reminder", fmt.Sprintf("Your car (%s) leaves in %d min", rem.VehicleName, minutes)
+}
@@ -0,0 +1,39 @@
+package service
+
+import (
+	"testing"
+	"time"
+
+	"github.com/kento/driver/backend/internal/model"
+)
+
+func TestReminderMessage(t *testing.T) {
+	now := time.Date(2026, 5, 4, 8, 0, 0, 0, time.UTC)
+	tests := []struct {
+		name      string
+		role      string
+		lead      time.Duration
+		wantTitle string
+		wantBody  string
+	}{
+		{"requester", "requester", 30 * time.Minute, "Reservation Reminder", "Your car (Van 2) leaves in 30 min"},
+		{"requester shortly before", "requester", 15 * time.Minute, "Reservation Reminder", "Your car (Van 2) leaves in 15 min"},
+		{"driver", "driver", 30 * time.Minute, "Upcoming Trip", "Reservation starts in 30 min: airport run"},
+		{"part minute rounds up", "driver", 29*time.Minute + 10*time.Second, "Upcoming Trip", "Reservation starts in 30 min: airport run"},
+		{"job ran late", "requester", -2 * time.Minute, "Reservation Reminder", "Your car (Van 2) leaves in 1 min"},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			rem := model.DueReminder{
+				RecipientRole: tc.role,
+				StartTime:     now.Add(tc.lead),
+				Purpose:       "airport run",
+				VehicleName:   "Van 2",
+			}
+			title, body := reminderMessage(rem, now)
+			if title != tc.wantTitle || body != tc.wantBody {
+				t.Errorf("reminderMessage() = %q, %q; want %q, %q", title, body, tc.wantTitle, tc.wantBody)
+			}
+		})
+	}
+}
@@ -0,0 +1,288 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/internal/rrule"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+// ReservationSeriesService manages recurring reservations. Occurrences are
+// materialized as ordinary reservations up to a rolling horizon, each placed
+// through ReservationService so the overlap and priority rules apply to it
+// exactly as to a one-off booking. Editing or cancelling a single occurrence
+// goes through the reservation endpoints; the occurrence then keeps its own
+// values when the series is edited.
+type ReservationSeriesService struct {
+	repo           *repository.ReservationSeriesRepo
+	reservationSvc *ReservationService
+	auditSvc       *AuditService
+	horizon        time.Duration
+}
+
+func NewReservationSeriesService(repo *repository.ReservationSeriesRepo, reservationSvc *ReservationService, auditSvc *AuditService, horizonDays int) *ReservationSeriesService {
+	return &ReservationSeriesService{
+		repo:           repo,
+		reservationSvc: reservationSvc,
+		auditSvc:       auditSvc,
+		horizon:        time.Duration(horizonDays) * 24 * time.Hour,
+	}
+}
+
+func (s *ReservationSeriesService) Create(ctx context.Context, req dto.CreateReservationSeriesRequest, requesterID string, priorityLevel int) (*dto.ReservationSeriesDetail, error) {
+	if !req.EndTime.After(req.StartTime) {
+		return nil, apperror.New(400, "INVALID_TIME_RANGE", "end_time must be after start_time")
+	}
+	if req.StartTime.Before(time.Now()) {
+		return nil, apperror.New(400, "PAST_TIME", "cannot create reservation in the past")
+	}
+	if req.Timezone == "" {
+		req.Timezone = "UTC"
+	}
+	loc, err := time.LoadLocation(req.Timezone)
+	if err != nil {
+		return nil, apperror.New(400, "INVALID_TIMEZONE", "unknown timezone "+req.Timezone)
+	}
+	rule, err := rrule.Parse(req.RRule, loc)
+	if err != nil {
+		return nil, apperror.New(400, "INVALID_RRULE", err.Error())
+	}
+
+	series := &model.ReservationSeries{
+		VehicleID:     req.VehicleID,
+		RequesterID:   requesterID,
+		RRule:         rule.String(),
+		DTStart:       req.StartTime,
+		DurationSec:   int(req.EndTime.Sub(req.StartTime) / time.Second),
+		Timezone:      req.Timezone,
+		Purpose:       req.Purpose,
+		Destinations:  req.Destinations,
+		Notes:         req.Notes,
+		PriorityLevel: priorityLevel,
+	}
+	if err := s.repo.Create(ctx, series); err != nil {
+		return nil, err
+	}
+	s.auditSvc.Log(ctx, requesterID, "reservation_series.create", "reservation_series", series.ID, nil, series, "")
+
+	if _, err := s.Materialize(ctx, series); err != nil {
+		return nil, err
+	}
+	return s.detail(ctx, series.ID)
+}
+
+func (s *ReservationSeriesService) GetByID(ctx context.Context, id string) (*dto.ReservationSeriesDetail, error) {
+	return s.detail(ctx, id)
+}
+
+// List returns series on vehicles within the depot scope depotIDs, nil for all.
+func (s *ReservationSeriesService) List(ctx context.Context, requesterID, status string, depotIDs []string, limit, offset int) ([]model.ReservationSeries, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return s.repo.List(ctx, requesterID, status, depotIDs, limit, offset)
+}
+
+// Update applies a series-wide edit to the template and to every upcoming
+// occurrence that was not edited on its own. An occurrence the new values
+// clash for is queued as a conflict like any move, so the edit is never
+// refused half way. One that cannot be saved at all is logged and keeps its
+// old values; the rest still follow the template.
+func (s *ReservationSeriesService) Update(ctx context.Context, id string, req dto.UpdateReservationSeriesRequest, actorID string) (*dto.ReservationSeriesDetail, error) {
+	series, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if series == nil {
+		return nil, apperror.ErrNotFound
+	}
+	if series.Status == model.SeriesStatusCancelled {
+		return nil, apperror.New(400, "SERIES_CANCELLED", "reservation series is cancelled")
+	}
+
+	before := *series
+	if req.VehicleID != nil {
+		series.VehicleID = *req.VehicleID
+	}
+	if req.Purpose != nil {
+		series.Purpose = *req.Purpose
+	}
+	if req.Destinations != nil {
+		series.Destinations = req.Destinations
+	}
+	if req.Notes != nil {
+		series.Notes = req.Notes
+	}
+	if err := s.repo.UpdateTemplate(ctx, series); err != nil {
+		return nil, err
+	}
+
+	upcoming, err := s.repo.UpcomingOccurrences(ctx, id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	for i := range upcoming {
+		occ := &upcoming[i]
+		if occ.IsException {
+			continue
+		}
+		occ.VehicleID = series.VehicleID
+		occ.Purpose = series.Purpose
+		occ.Destinations = series.Destinations
+		occ.Notes = series.Notes
+		if err := s.reservationSvc.Reschedule(ctx, occ, actorID); err != nil {
+			log.Printf("[series] update occurrence %s of %s: %v", occ.ID, id, err)
+		}
+	}
+
+	s.auditSvc.Log(ctx, actorID, "reservation_series.update", "reservation_series", id, before, series, "")
+	return s.detail(ctx, id)
+}
+
+// Cancel ends the series and cancels every upcoming occurrence, including
+// ones edited on their own.
+func (s *ReservationSeriesService) Cancel(ctx context.Context, id, cancelledBy, reason string) error {
+	series, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if series == nil {
+		return apperror.ErrNotFound
+	}
+	if series.Status == model.SeriesStatusCancelled {
+		return apperror.New(400, "SERIES_CANCELLED", "reservation series is already cancelled")
+	}
+
+	// Stop the materializer first so it cannot add occurrences behind us
+	if err := s.repo.Cancel(ctx, id, cancelledBy, reason); err != nil {
+		return err
+	}
+	upcoming, err := s.repo.UpcomingOccurrences(ctx, id, time.Now())
+	if err != nil {
+		return err
+	}
+	for _, occ := range upcoming {
+		if err := s.reservationSvc.Cancel(ctx, occ.ID, cancelledBy, reason); err != nil {
+			return err
+		}
+	}
+
+	s.auditSvc.Log(ctx, cancelledBy, "reservation_series.cancel", "reservation_series", id, series, nil, reason)
+	return nil
+}
+
+// Materialize creates the series' missing occurrences up to the horizon and
+// reports how many it created. Slots that already have a reservation, even a
+// cancelled one, are left alone. An occurrence the vehicle's maintenance
+// blocks is still created, as pending_conflict with a maintenance conflict,
+// so the lost day shows up in the conflict queue. The series ends once its
+// rule has no occurrences left.
+func (s *ReservationSeriesService) Materialize(ctx context.Context, series *model.ReservationSeries) (int, error) {
+	loc, err := time.LoadLocation(series.Timezone)
+	if err != nil {
+		return 0, err
+	}
+	rule, err := rrule.Parse(series.RRule, loc)
+	if err != nil {
+		return 0, err
+	}
+
+	now := time.Now()
+	from := series.DTStart
+	if series.MaterializedUntil != nil {
+		from = *series.MaterializedUntil
+	}
+	if from.Before(now) {
+		from = now
+	}
+	to := now.Add(s.horizon)
+	dtstart := series.DTStart.In(loc)
+
+	existing, err := s.repo.OccurrenceStarts(ctx, series.ID, from, to)
+	if err != nil {
+		return 0, err
+	}
+	seen := make(map[int64]bool, len(existing))
+	for _, t := range existing {
+		seen[t.Unix()] = true
+	}
+
+	created := 0
+	for _, start := range rule.Between(dtstart, from, to) {
+		if seen[start.Unix()] {
+			continue
+		}
+		occurrenceStart := start
+		res := &model.Reservation{
+			VehicleID:       series.VehicleID,
+			RequesterID:     series.RequesterID,
+			StartTime:       start,
+			EndTime:         start.Add(series.Duration()),
+			Purpose:         series.Purpose,
+			Destinations:    series.Destinations,
+			Notes:           series.Notes,
+			PriorityLevel:   series.PriorityLevel,
+			Status:          model.ReservationStatusConfirmed,
+			SeriesID:        &series.ID,
+			OccurrenceStart: &occurrenceStart,
+		}
+		err := s.reservationSvc.Place(ctx, res)
+		if errors.Is(err, repository.ErrOccurrenceExists) {
+			continue
+		}
+		if err != nil {
+			// materialized_until stays put so the next run retries the rest
+			return created, err
+		}
+		created++
+	}
+
+	if err := s.repo.SetMaterializedUntil(ctx, series.ID, to); err != nil {
+		return created, err
+	}
+	if rule.Finite() && len(rule.Between(dtstart, to, to.AddDate(100, 0, 0))) == 0 {
+		if err := s.repo.End(ctx, series.ID); err != nil {
+			return created, err
+		}
+	}
+	return created, nil
+}
+
+// MaterializeDue extends every active series to the horizon. A failing
+// series is logged and retried on the next run without holding up the rest.
+func (s *ReservationSeriesService) MaterializeDue(ctx context.Context) (int, error) {
+	due, err := s.repo.ListDue(ctx, time.Now().Add(s.horizon))
+	if err != nil {
+		return 0, err
+	}
+	total := 0
+	for i := range due {
+		n, err := s.Materialize(ctx, &due[i])
+		total += n
+		if err != nil {
+			log.Printf("[series] materialize %s: %v", due[i].ID, err)
+		}
+	}
+	return total, nil
+}
+
+func (s *ReservationSeriesService) detail(ctx context.Context, id string) (*dto.ReservationSeriesDetail, error) {
+	series, err := s.repo.GetByID(ctx, id)
+	if err != nil || series == nil {
+		return nil, err
+	}
+	occurrences, err := s.repo.UpcomingOccurrences(ctx, id, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	if occurrences == nil {
+		occurrences = []model.Reservation{}
+	}
+	return &dto.ReservationSeriesDetail{ReservationSeries: *series, Occurrences: occurrences}, nil
+}
@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/internal/testdb"
+)
+
+// newTestSeries creates a daily series of three one-hour occurrences on
+// vehicleID, starting tomorrow, and removes it with its occurrences and the
+// maintenance windows of vehicleIDs when the test ends.
+func newTestSeries(t *testing.T, conn *sqlx.DB, svc *ReservationSeriesService, userID, vehicleID string, vehicleIDs ...string) *dto.ReservationSeriesDetail {
+	t.Helper()
+	start := time.Now().UTC().Add(24 * time.Hour).Truncate(time.Hour)
+	series, err := svc.Create(context.Background(), dto.CreateReservationSeriesRequest{
+		VehicleID: vehicleID,
+		StartTime: start,
+		EndTime:   start.Add(time.Hour),
+		RRule:     "FREQ=DAILY;COUNT=3",
+		Purpose:   "series test",
+	}, userID, 1)
+	if err != nil {
+		t.Fatalf("create series: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM reservation_conflicts WHERE losing_reservation_id IN (SELECT id FROM reservations WHERE series_id = $1)`, series.ID)
+		conn.Exec(`DELETE FROM reservations WHERE series_id = $1`, series.ID)
+		conn.Exec(`DELETE FROM reservation_series WHERE id = $1`, series.ID)
+		for _, id := range append(vehicleIDs, vehicleID) {
+			conn.Exec(`DELETE FROM maintenance_windows WHERE vehicle_id = $1`, id)
+		}
+	})
+	return series
+}
+
+// insertWindow books vehicleID for a planned repair over [start, end).
+func insertWindow(t *testing.T, conn *sqlx.DB, vehicleID, userID string, start, end time.Time) string {
+	t.Helper()
+	var id string
+	if err := conn.GetContext(context.Background(), &id, `
+		INSERT INTO maintenance_windows (vehicle_id, type, status, start_time, end_time, created_by)
+		VALUES ($1, 'repair', 'planned', $2, $3, $4) RETURNING id`,
+		vehicleID, start, end, userID); err != nil {
+		t.Fatalf("insert window: %v", err)
+	}
+	return id
+}
+
+// TestSeriesUpdate_OccurrenceInMaintenance moves a series to a vehicle that
+// is in the workshop for one of its days. The edit must go through for
+// every occurrence, with the blocked one queued behind a maintenance
+// conflict. Needs a migrated Postgres in TEST_DATABASE_URL.
+func TestSeriesUpdate_OccurrenceInMaintenance(t *testing.T) {
+	conn := testdb.Open(t)
+	ctx := context.Background()
+	from := testdb.NewFixture(t, conn, "Series From", model.RoleDispatcher)
+	to := testdb.NewFixture(t, conn, "Series To", model.RoleDispatcher)
+
+	svc := NewReservationSeriesService(repository.NewReservationSeriesRepo(conn), newTestReservationService(t, conn),
+		NewAuditService(repository.NewAuditRepo(conn)), 7)
+	series := newTestSeries(t, conn, svc, from.UserID, from.VehicleID, to.VehicleID)
+	if len(series.Occurrences) != 3 {
+		t.Fatalf("materialized %d occurrences, want 3", len(series.Occurrences))
+	}
+	blocked := series.Occurrences[1]
+	windowID := insertWindow(t, conn, to.VehicleID, from.UserID, blocked.StartTime, blocked.EndTime)
+
+	updated, err := svc.Update(ctx, series.ID, dto.UpdateReservationSeriesRequest{VehicleID: &to.VehicleID}, from.UserID)
+	if err != nil {
+		t.Fatalf("Update: %v", err)
+	}
+
+	for _, occ := range updated.Occurrences {
+		if occ.VehicleID != to.VehicleID {
+			t.Errorf("occurrence at %s stayed on its old vehicle", occ.StartTime.Format(time.RFC3339))
+		}
+		want := model.ReservationStatusConfirmed
+		if occ.ID == blocked.ID {
+			want = model.ReservationStatusPendingConflict
+		}
+		if occ.Status != want {
+			t.Errorf("occurrence at %s is %s, want %s", occ.StartTime.Format(time.RFC3339), occ.Status, want)
+		}
+	}
+	conflicts, err := repository.NewConflictRepo(conn).ListPendingFor(ctx, blocked.ID)
+	if err != nil {
+		t.Fatalf("list conflicts: %v", err)
+	}
+	if len(conflicts) != 1 || conflicts[0].MaintenanceWindowID == nil || *conflicts[0].MaintenanceWindowID != windowID {
+		t.Errorf("conflicts of the blocked occurrence = %+v, want one against the window", conflicts)
+	}
+}
+
+// TestMaterialize_OccurrenceInMaintenance puts the vehicle in the workshop
+// on the second day of a new series. That occurrence must still be created,
+// queued behind a maintenance conflict, and the others confirmed. Needs a
+// migrated Postgres in TEST_DATABASE_URL.
+func TestMaterialize_OccurrenceInMaintenance(t *testing.T) {
+	conn := testdb.Open(t)
+	ctx := context.Background()
+	f := testdb.NewFixture(t, conn, "Materialize Test", model.RoleDispatcher)
+
+	day := time.Now().UTC().Add(48 * time.Hour).Truncate(time.Hour)
+	windowID := insertWindow(t, conn, f.VehicleID, f.UserID, day.Add(-time.Hour), day.Add(2*time.Hour))
+
+	svc := NewReservationSeriesService(repository.NewReservationSeriesRepo(conn), newTestReservationService(t, conn),
+		NewAuditService(repository.NewAuditRepo(conn)), 7)
+	series := newTestSeries(t, conn, svc, f.UserID, f.VehicleID)
+
+	if len(series.Occurrences) != 3 {
+		t.Fatalf("materialized %d occurrences, want 3", len(series.Occurrences))
+	}
+	conflictRepo := repository.NewConflictRepo(conn)
+	for _, occ := range series.Occurrences {
+		want := model.ReservationStatusConfirmed
+		if occ.StartTime.Equal(day) {
+			want = model.ReservationStatusPendingConflict
+			conflicts, err := conflictRepo.ListPendingFor(ctx, occ.ID)
+			if err != nil {
+				t.Fatalf("list conflicts: %v", err)
+			}
+			if len(conflicts) != 1 || conflicts[0].MaintenanceWindowID == nil || *conflicts[0].MaintenanceWindowID != windowID {
+				t.Errorf("conflicts of the blocked occurrence = %+v, want one against the window", conflicts)
+			}
+		}
+		if occ.Status != want {
+			t.Errorf("occurrence at %s is %s, want %s", occ.StartTime.Format(time.RFC3339), occ.Status, want)
+		}
+	}
+}
@@ -2,22 +2,51 @@ package service
 
 import (
 	"context"
+	"errors"
+	"log"
 	"time"
 
 	"github.com/kento/driver/backend/internal/dto"
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/realtime"
 	"github.com/kento/driver/backend/internal/repository"
 	"github.com/kento/driver/backend/pkg/apperror"
 )
 
 type ReservationService struct {
-	repo         *repository.ReservationRepo
-	conflictRepo *repository.ConflictRepo
-	auditSvc     *AuditService
+	repo          *repository.ReservationRepo
+	conflictRepo  *repository.ConflictRepo
+	vehicleRepo   *repository.VehicleRepo
+	maintenance   *repository.MaintenanceRepo
+	dispatchRepo  *repository.DispatchRepo
+	waitlistSvc   *WaitlistService
+	auditSvc      *AuditService
+	hub           *realtime.Hub
+	travel        TravelTimer
+	travelTimeout time.Duration
 }
 
-func NewReservationService(repo *repository.ReservationRepo, conflictRepo *repository.ConflictRepo, auditSvc *AuditService) *ReservationService {
-	return &ReservationService{repo: repo, conflictRepo: conflictRepo, auditSvc: auditSvc}
+// NewReservationService returns the service. travel may be nil, which turns
+// the travel-time check off; vehicle turnarounds apply either way.
+func NewReservationService(repo *repository.ReservationRepo, conflictRepo *repository.ConflictRepo, vehicleRepo *repository.VehicleRepo, maintenance *repository.MaintenanceRepo, dispatchRepo *repository.DispatchRepo, waitlistSvc *WaitlistService, auditSvc *AuditService, hub *realtime.Hub, travel TravelTimer, travelTimeout time.Duration) *ReservationService {
+	return &ReservationService{
+		repo:          repo,
+		conflictRepo:  conflictRepo,
+		vehicleRepo:   vehicleRepo,
+		maintenance:   maintenance,
+		dispatchRepo:  dispatchRepo,
+		waitlistSvc:   waitlistSvc,
+		auditSvc:      auditSvc,
+		hub:           hub,
+		travel:        travel,
+		travelTimeout: travelTimeout,
+	}
+}
+
+// holdsVehicle reports whether a reservation in status keeps its vehicle
+// from being booked by others. A pending_conflict one does not.
+func holdsVehicle(status model.ReservationStatus) bool {
+	return status == model.ReservationStatusConfirmed || status == model.ReservationStatusPendingDriver
 }
 
 func (s *ReservationService) Create(ctx context.Context, req dto.CreateReservationRequest, requesterID string, priorityLevel int) (*model.Reservation, error) {
@@ -28,12 +57,6 @@ func (s *ReservationService) Create(ctx context.Context, req dto.CreateReservati
 		return nil, apperror.New(400, "PAST_TIME", "cannot create reservation in the past")
 	}
 
-	// Check for overlapping reservations
-	overlaps, err := s.repo.FindOverlapping(ctx, req.VehicleID, req.StartTime, req.EndTime, "")
-	if err != nil {
-		return nil, err
-	}
-
 	reservation := &model.Reservation{
 		VehicleID:     req.VehicleID,
 		RequesterID:   requesterID,
@@ -45,54 +68,167 @@ func (s *ReservationService) Create(ctx context.Context, req dto.CreateReservati
 		PriorityLevel: priorityLevel,
 		Status:        model.ReservationStatusConfirmed,
 	}
+	if err := s.Place(ctx, reservation); err != nil {
+		return nil, err
+	}
 
-	// Apply priority rules for conflicts
-	for _, existing := range overlaps {
-		if priorityLevel > existing.PriorityLevel {
-			// New reservation has higher priority: both become pending_conflict
-			reservation.Status = model.ReservationStatusPendingConflict
-			// Mark existing as pending_conflict too
-			_ = s.repo.UpdateStatus(ctx, existing.ID, model.ReservationStatusPendingConflict)
-		} else if priorityLevel == existing.PriorityLevel {
-			// Same priority: first-come-first-served, new one loses
-			reservation.Status = model.ReservationStatusPendingConflict
-		} else {
-			// New reservation has lower priority: it loses
-			reservation.Status = model.ReservationStatusPendingConflict
-		}
+	s.auditSvc.Log(ctx, requesterID, "reservation.create", "reservation", reservation.ID, nil, reservation, "")
+	return reservation, nil
+}
+
+// placeAttempts bounds how often Place re-reads overlaps after losing a race
+// for the slot. Each retry sees the winner, so the second attempt normally
+// settles it.
+const placeAttempts = 3
+
+var errSlotTaken = apperror.New(409, "RESERVATION_OVERLAP", "the vehicle is already reserved for that time")
+
+var errInMaintenance = apperror.New(409, "VEHICLE_IN_MAINTENANCE", "the vehicle is booked for maintenance at that time")
+
+// checkMaintenance refuses a slot a maintenance window blocks. Such clashes
+// are only queued as conflicts for reservations that were there before the
+// window was scheduled.
+func (s *ReservationService) checkMaintenance(ctx context.Context, res *model.Reservation) error {
+	windows, err := s.maintenance.FindBlocking(ctx, res.VehicleID, res.StartTime, res.EndTime)
+	if err != nil {
+		return err
+	}
+	if len(windows) > 0 {
+		return errInMaintenance
 	}
+	return nil
+}
 
-	if err := s.repo.Create(ctx, reservation); err != nil {
-		return nil, err
+// maintenanceBlock returns the maintenance windows blocking res's slot. A
+// one-off booking, or an occurrence edited on its own, is refused with
+// errInMaintenance instead. An occurrence following its series is queued
+// behind the windows, so the clash shows up in the conflict queue rather
+// than the series edit or materialization failing part way.
+func (s *ReservationService) maintenanceBlock(ctx context.Context, res *model.Reservation) ([]model.MaintenanceWindow, error) {
+	if res.SeriesID == nil || res.IsException {
+		return nil, s.checkMaintenance(ctx, res)
+	}
+	return s.maintenance.FindBlocking(ctx, res.VehicleID, res.StartTime, res.EndTime)
+}
+
+// recordMaintenance files a maintenance conflict between res and each
+// window blocking it.
+func (s *ReservationService) recordMaintenance(ctx context.Context, res *model.Reservation, windows []model.MaintenanceWindow) {
+	for _, w := range windows {
+		_, _ = s.conflictRepo.CreateForMaintenance(ctx, w.ID, res.ID)
+	}
+}
+
+// Place stores a new reservation, applying the priority rules to whatever it
+// overlaps or sits too close to: with a free slot it keeps its status,
+// otherwise it goes to pending_conflict with a conflict record per clash, and
+// a lower-priority holder is demoted. The reservations_no_overlap constraint
+// makes the check-then-insert safe: when a concurrent booking takes the slot
+// first the insert fails and the overlaps are read again. A series
+// occurrence blocked by maintenance is queued behind the window too.
+func (s *ReservationService) Place(ctx context.Context, res *model.Reservation) error {
+	windows, err := s.maintenanceBlock(ctx, res)
+	if err != nil {
+		return err
+	}
+	holding := res.Status
+	spacing, err := s.spacingViolations(ctx, res)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; attempt < placeAttempts; attempt++ {
+		overlaps, err := s.repo.FindOverlapping(ctx, res.VehicleID, res.StartTime, res.EndTime, "")
+		if err != nil {
+			return err
+		}
+		res.Status = holding
+		if len(overlaps) > 0 || len(spacing) > 0 || len(windows) > 0 {
+			res.Status = model.ReservationStatusPendingConflict
+		}
+
+		err = s.repo.Create(ctx, res)
+		if errors.Is(err, repository.ErrReservationOverlap) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		s.recordConflicts(ctx, res, overlaps)
+		s.recordSpacing(ctx, res, spacing)
+		s.recordMaintenance(ctx, res, windows)
+		return nil
 	}
+	return errSlotTaken
+}
 
-	// Create conflict records
+// recordConflicts files a conflict between res and each reservation it
+// overlaps. Higher priority wins; on equal priority the earlier booking does.
+func (s *ReservationService) recordConflicts(ctx context.Context, res *model.Reservation, overlaps []model.Reservation) {
 	for _, existing := range overlaps {
-		if priorityLevel > existing.PriorityLevel {
-			// New wins
-			_, _ = s.conflictRepo.Create(ctx, reservation.ID, existing.ID)
-		} else {
-			// Existing wins
-			_, _ = s.conflictRepo.Create(ctx, existing.ID, reservation.ID)
+		winner, loser := existing.ID, res.ID
+		if res.PriorityLevel > existing.PriorityLevel {
+			// New wins: the existing holder gives up the slot until resolved
+			_ = s.bump(ctx, existing.ID, "", "lost its slot to a higher priority booking")
+			winner, loser = res.ID, existing.ID
 		}
+		_, _ = s.conflictRepo.CreateUnlessPending(ctx, winner, loser, model.ConflictKindOverlap, nil, nil)
 	}
+}
 
-	s.auditSvc.Log(ctx, requesterID, "reservation.create", "reservation", reservation.ID, nil, reservation, "")
-	return reservation, nil
+// bump steps a reservation that lost its slot down to pending_conflict and
+// cancels the trip already made from it, if any. The system does this on
+// whoever's behalf when actorID is empty.
+func (s *ReservationService) bump(ctx context.Context, id, actorID, reason string) error {
+	if err := s.repo.UpdateStatus(ctx, id, model.ReservationStatusPendingConflict); err != nil {
+		return err
+	}
+	s.cancelTrip(ctx, id, actorID, reason)
+	return nil
 }
 
 func (s *ReservationService) GetByID(ctx context.Context, id string) (*model.Reservation, error) {
 	return s.repo.GetByID(ctx, id)
 }
 
-func (s *ReservationService) List(ctx context.Context, vehicleID string, from, to time.Time, status string, limit, offset int) ([]model.ReservationWithDetails, error) {
+func (s *ReservationService) List(ctx context.Context, vehicleID string, from, to time.Time, status string, depotIDs []string, limit, offset int) ([]model.ReservationWithDetails, error) {
 	if limit <= 0 {
 		limit = 50
 	}
-	return s.repo.List(ctx, vehicleID, from, to, status, limit, offset)
+	return s.repo.List(ctx, vehicleID, from, to, status, depotIDs, limit, offset)
 }
 
 func (s *ReservationService) Cancel(ctx context.Context, id, cancelledBy, reason string) error {
+	return s.cancel(ctx, id, "", cancelledBy, reason, nil)
+}
+
+// settlement is a conflict a dispatcher settles by changing or cancelling a
+// reservation. It stays pending until the change is saved, so a change that
+// fails leaves it in the queue, and is resolved before clashes are detected
+// again, so one the change did not clear is filed anew.
+type settlement struct {
+	conflictID string
+	status     model.ConflictStatus
+	reason     string
+}
+
+// settle resolves the conflict a saved change settled, if any. The change
+// stands either way, so a failure is logged.
+func (s *ReservationService) settle(ctx context.Context, st *settlement, actorID string) {
+	if st == nil {
+		return
+	}
+	if err := s.conflictRepo.Resolve(ctx, st.conflictID, actorID, st.reason, st.status); err != nil {
+		log.Printf("[reservation] resolve settled conflict %s: %v", st.conflictID, err)
+	}
+}
+
+// cancelInFavourOf cancels id to settle a conflict in favour of favouredID,
+// which gets the slot back before the waitlist is offered what is left.
+func (s *ReservationService) cancelInFavourOf(ctx context.Context, id, favouredID, cancelledBy, reason string, st *settlement) error {
+	return s.cancel(ctx, id, favouredID, cancelledBy, reason, st)
+}
+
+func (s *ReservationService) cancel(ctx context.Context, id, favouredID, cancelledBy, reason string, st *settlement) error {
 	before, err := s.repo.GetByID(ctx, id)
 	if err != nil {
 		return err
@@ -104,11 +240,119 @@ func (s *ReservationService) Cancel(ctx context.Context, id, cancelledBy, reason
 	if err := s.repo.Cancel(ctx, id, cancelledBy, reason); err != nil {
 		return err
 	}
+	s.settle(ctx, st, cancelledBy)
 
 	s.auditSvc.Log(ctx, cancelledBy, "reservation.cancel", "reservation", id, before, nil, reason)
+	s.cancelTrip(ctx, id, cancelledBy, reason)
+	s.dropConflicts(ctx, id, cancelledBy)
+
+	if favouredID != "" {
+		if err := s.Recheck(ctx, favouredID, cancelledBy); err != nil {
+			return err
+		}
+	}
+	if holdsVehicle(before.Status) {
+		s.waitlistSvc.SlotReleased(ctx, before.StartTime, before.EndTime)
+	}
 	return nil
 }
 
+// dropConflicts closes the pending conflicts of a cancelled reservation. The
+// other side of each may now be free to take its slot.
+func (s *ReservationService) dropConflicts(ctx context.Context, id, actorID string) {
+	pending, err := s.conflictRepo.ListPendingFor(ctx, id)
+	if err != nil {
+		log.Printf("[reservation] conflicts of cancelled %s: %v", id, err)
+		return
+	}
+	for _, c := range pending {
+		s.closeConflict(ctx, &c, id, actorID, model.ConflictStatusResolvedCancelled, "reservation cancelled")
+	}
+}
+
+// closeConflict resolves c on the system's behalf after a change to
+// changedID, then rechecks the other reservation in it, if any.
+func (s *ReservationService) closeConflict(ctx context.Context, c *model.ReservationConflict, changedID, actorID string, status model.ConflictStatus, reason string) {
+	if err := s.conflictRepo.Resolve(ctx, c.ID, actorID, reason, status); err != nil {
+		log.Printf("[reservation] resolve conflict %s: %v", c.ID, err)
+		return
+	}
+	s.auditSvc.Log(ctx, actorID, "conflict.auto_resolve", "conflict", c.ID, c, nil, reason)
+
+	other := c.Other(changedID)
+	if other == "" {
+		return
+	}
+	if err := s.Recheck(ctx, other, actorID); err != nil {
+		log.Printf("[reservation] recheck %s: %v", other, err)
+	}
+}
+
+// Recheck runs a pending_conflict reservation through detection again once
+// none of its conflicts is pending. It is confirmed unless its slot was
+// taken in the meantime, which files new conflicts instead.
+func (s *ReservationService) Recheck(ctx context.Context, id, actorID string) error {
+	res, err := s.repo.GetByID(ctx, id)
+	if err != nil || res == nil || res.Status != model.ReservationStatusPendingConflict {
+		return err
+	}
+	pending, err := s.conflictRepo.ListPendingFor(ctx, id)
+	if err != nil || len(pending) > 0 {
+		return err
+	}
+	return s.commit(ctx, res, model.ReservationStatusConfirmed, actorID, nil)
+}
+
+// cancelTrip cancels the unfinished trip made from a reservation that was
+// just cancelled, moved or bumped from its slot, so no driver runs it with a
+// vehicle or time the reservation no longer has. A reservation confirmed
+// again gets a fresh trip when it is next due. The reservation change is
+// already saved, so failures are logged.
+func (s *ReservationService) cancelTrip(ctx context.Context, reservationID, actorID, reason string) {
+	d, err := s.dispatchRepo.GetActiveByReservationID(ctx, reservationID)
+	if err != nil {
+		log.Printf("[reservation] find trip of %s: %v", reservationID, err)
+		return
+	}
+	if d == nil {
+		return
+	}
+	cancelled, err := s.dispatchRepo.Cancel(ctx, d.ID, d.Status, d.Version, reason)
+	if err != nil || !cancelled {
+		log.Printf("[reservation] cancel trip %s of %s: cancelled=%v err=%v", d.ID, reservationID, cancelled, err)
+		return
+	}
+	after, _ := s.dispatchRepo.GetByID(ctx, d.ID)
+	s.auditSvc.Log(ctx, actorID, "dispatch.cancel", "dispatch", d.ID, d, after, reason)
+	publishDispatch(s.hub, after)
+}
+
+// CompleteTrip completes a reservation whose trip the driver finished. Time
+// left in its slot goes to the waitlist.
+func (s *ReservationService) CompleteTrip(ctx context.Context, id, actorID string) {
+	res, err := s.repo.GetByID(ctx, id)
+	if err != nil || res == nil {
+		log.Printf("[reservation] complete %s: %v", id, err)
+		return
+	}
+	completed, err := s.repo.Complete(ctx, id)
+	if err != nil || !completed {
+		log.Printf("[reservation] complete %s: completed=%v err=%v", id, completed, err)
+		return
+	}
+	s.auditSvc.Log(ctx, actorID, "reservation.complete", "reservation", id, res, nil, "trip completed")
+
+	if now := time.Now(); res.EndTime.After(now) {
+		s.waitlistSvc.SlotReleased(ctx, now, res.EndTime)
+	}
+}
+
+// ListDueForTrip returns confirmed reservations starting within lead that
+// still need a trip.
+func (s *ReservationService) ListDueForTrip(ctx context.Context, lead time.Duration) ([]model.Reservation, error) {
+	return s.repo.ListDueForTrip(ctx, lead)
+}
+
 func (s *ReservationService) Update(ctx context.Context, id string, req dto.UpdateReservationRequest, actorID string) (*model.Reservation, error) {
 	existing, err := s.repo.GetByID(ctx, id)
 	if err != nil {
@@ -139,7 +383,10 @@ func (s *ReservationService) Update(ctx context.Context, id string, req dto.Upda
 		existing.Notes = req.Notes
 	}
 
-	if err := s.repo.Update(ctx, existing); err != nil {
+	// An occurrence edited on its own leaves its series' template behind
+	existing.IsException = existing.SeriesID != nil
+
+	if err := s.Reschedule(ctx, existing, actorID); err != nil {
 		return nil, err
 	}
 
@@ -147,14 +394,197 @@ func (s *ReservationService) Update(ctx context.Context, id string, req dto.Upda
 	return existing, nil
 }
 
-func (s *ReservationService) CheckAvailability(ctx context.Context, vehicleID string, startTime, endTime time.Time) ([]model.Reservation, error) {
-	return s.repo.FindOverlapping(ctx, vehicleID, startTime, endTime, "")
+// Reschedule saves changes to res. A new vehicle or time goes through the
+// same detection as a new booking: clashes are queued as conflicts rather
+// than rejected, and conflicts the move settled are closed. Time the old
+// slot no longer covers goes to the waitlist.
+func (s *ReservationService) Reschedule(ctx context.Context, res *model.Reservation, actorID string) error {
+	return s.reschedule(ctx, res, actorID, nil)
 }
 
-func (s *ReservationService) GetUpcomingReminders(ctx context.Context, minutesBefore int) ([]model.ReservationWithDetails, error) {
-	return s.repo.GetUpcomingReminders(ctx, minutesBefore)
+// reschedule is Reschedule settling conflict st, if any, once the change is
+// saved.
+func (s *ReservationService) reschedule(ctx context.Context, res *model.Reservation, actorID string, st *settlement) error {
+	prev, err := s.repo.GetByID(ctx, res.ID)
+	if err != nil {
+		return err
+	}
+	if prev == nil {
+		return apperror.ErrNotFound
+	}
+
+	moved := prev.VehicleID != res.VehicleID || !res.StartTime.Equal(prev.StartTime) || !res.EndTime.Equal(prev.EndTime)
+	active := holdsVehicle(prev.Status) || prev.Status == model.ReservationStatusPendingConflict
+	if !moved || !active {
+		if err := s.repo.Update(ctx, res); err != nil {
+			return err
+		}
+		s.settle(ctx, st, actorID)
+		return nil
+	}
+
+	free := prev.Status
+	if !holdsVehicle(free) {
+		free = model.ReservationStatusConfirmed
+	}
+	if err := s.commit(ctx, res, free, actorID, st); err != nil {
+		return err
+	}
+	s.cancelTrip(ctx, res.ID, actorID, "reservation moved")
+
+	shrunk := prev.VehicleID != res.VehicleID || res.StartTime.After(prev.StartTime) || res.EndTime.Before(prev.EndTime)
+	if holdsVehicle(prev.Status) && (shrunk || !holdsVehicle(res.Status)) {
+		s.waitlistSvc.SlotReleased(ctx, prev.StartTime, prev.EndTime)
+	}
+	return nil
 }
 
+// commit saves an existing reservation's slot and applies the priority rules
+// as Place does. Without a clash it takes status free; otherwise it steps
+// down to pending_conflict, which the reservations_no_overlap constraint
+// lets overlap. Slot and status are written in one statement, so a failure
+// leaves both as they were. Conflict st, if any, is then resolved, and the
+// reservation's pending conflicts with reservations it no longer clashes
+// with are closed.
+func (s *ReservationService) commit(ctx context.Context, res *model.Reservation, free model.ReservationStatus, actorID string, st *settlement) error {
+	windows, err := s.maintenanceBlock(ctx, res)
+	if err != nil {
+		return err
+	}
+	spacing, err := s.spacingViolations(ctx, res)
+	if err != nil {
+		return err
+	}
+	for attempt := 0; attempt < placeAttempts; attempt++ {
+		overlaps, err := s.repo.FindOverlapping(ctx, res.VehicleID, res.StartTime, res.EndTime, res.ID)
+		if err != nil {
+			return err
+		}
+
+		status := free
+		if len(overlaps) > 0 || len(spacing) > 0 || len(windows) > 0 {
+			status = model.ReservationStatusPendingConflict
+		}
+		err = s.repo.UpdateWithStatus(ctx, res, status)
+		if errors.Is(err, repository.ErrReservationOverlap) {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		res.Status = status
+
+		s.settle(ctx, st, actorID)
+		s.closeStale(ctx, res, overlaps, spacing, windows, actorID)
+		s.recordConflicts(ctx, res, overlaps)
+		s.recordSpacing(ctx, res, spacing)
+		s.recordMaintenance(ctx, res, windows)
+		return nil
+	}
+	return errSlotTaken
+}
+
+// closeStale resolves res's pending conflicts with reservations it no longer
+// overlaps or sits too close to, and with maintenance windows no longer
+// blocking it.
+func (s *ReservationService) closeStale(ctx context.Context, res *model.Reservation, overlaps []model.Reservation, spacing []spacingViolation, windows []model.MaintenanceWindow, actorID string) {
+	pending, err := s.conflictRepo.ListPendingFor(ctx, res.ID)
+	if err != nil {
+		log.Printf("[reservation] conflicts of %s: %v", res.ID, err)
+		return
+	}
+	clashing := make(map[string]bool, len(overlaps)+len(spacing)+len(windows))
+	for _, o := range overlaps {
+		clashing[o.ID] = true
+	}
+	for _, v := range spacing {
+		clashing[v.other.ID] = true
+	}
+	for _, w := range windows {
+		clashing[w.ID] = true
+	}
+	for _, c := range pending {
+		if other := c.Other(res.ID); other != "" && clashing[other] {
+			continue
+		}
+		if c.MaintenanceWindowID != nil && clashing[*c.MaintenanceWindowID] {
+			continue
+		}
+		s.closeConflict(ctx, &c, res.ID, actorID, model.ConflictStatusResolvedChanged, "no longer conflicting")
+	}
+}
+
+// SyncMaintenance brings the conflicts of maintenance window w in line with
+// the reservations it now blocks. Each reservation newly caught in it steps
+// down to pending_conflict with a maintenance conflict; one the window has
+// moved off, or every one once it is closed, is released and rechecked. Work
+// in progress past its end is taken to run on for overrun from now, so the
+// reservations about to start are flagged before their trip goes out.
+func (s *ReservationService) SyncMaintenance(ctx context.Context, w *model.MaintenanceWindow, actorID string, overrun time.Duration) error {
+	var blocked []model.Reservation
+	if w.Status.IsOpen() {
+		end := w.EndTime
+		if now := time.Now(); w.Status == model.MaintenanceStatusInProgress && end.Before(now) {
+			end = now.Add(overrun)
+		}
+		var err error
+		blocked, err = s.repo.FindOverlapping(ctx, w.VehicleID, w.StartTime, end, "")
+		if err != nil {
+			return err
+		}
+	}
+	pending, err := s.conflictRepo.ListPendingForWindow(ctx, w.ID)
+	if err != nil {
+		return err
+	}
+
+	flagged := make(map[string]bool, len(pending))
+	for _, c := range pending {
+		flagged[c.LosingReservationID] = true
+	}
+	for _, res := range blocked {
+		if flagged[res.ID] {
+			delete(flagged, res.ID)
+			continue
+		}
+		if err := s.bump(ctx, res.ID, actorID, "vehicle blocked by maintenance"); err != nil {
+			return err
+		}
+		if _, err := s.conflictRepo.CreateForMaintenance(ctx, w.ID, res.ID); err != nil {
+			return err
+		}
+	}
+
+	// What is left in flagged is no longer blocked
+	for _, c := range pending {
+		if !flagged[c.LosingReservationID] {
+			continue
+		}
+		if err := s.conflictRepo.Resolve(ctx, c.ID, actorID, "maintenance window moved or closed", model.ConflictStatusResolvedChanged); err != nil {
+			return err
+		}
+		s.auditSvc.Log(ctx, actorID, "conflict.auto_resolve", "conflict", c.ID, c, nil, "maintenance window moved or closed")
+		if err := s.Recheck(ctx, c.LosingReservationID, actorID); err != nil {
+			log.Printf("[reservation] recheck %s: %v", c.LosingReservationID, err)
+		}
+	}
+	return nil
+}
+
+func (s *ReservationService) CheckAvailability(ctx context.Context, vehicleID string, startTime, endTime time.Time) ([]model.Reservation, error) {
+	return s.repo.FindOverlapping(ctx, vehicleID, startTime, endTime, "")
+}
+
+// AutoCompleteExpired completes confirmed reservations that have ended. A
+// trip made from one that nobody accepted in time will not run any more, so
+// it is cancelled first rather than holding the reservation open.
 func (s *ReservationService) AutoCompleteExpired(ctx context.Context) (int64, error) {
+	stale, err := s.dispatchRepo.ListUnstartedPastReservations(ctx)
+	if err != nil {
+		return 0, err
+	}
+	for _, d := range stale {
+		s.cancelTrip(ctx, *d.ReservationID, "", "reservation ended before the trip started")
+	}
 	return s.repo.AutoCompleteExpired(ctx)
 }
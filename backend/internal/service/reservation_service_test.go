@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/notify"
+	"github.com/kento/driver/backend/internal/realtime"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/internal/testdb"
+)
+
+// newTestReservationService wires a ReservationService to conn without a
+// travel-time check.
+func newTestReservationService(t *testing.T, conn *sqlx.DB) *ReservationService {
+	reservationRepo := repository.NewReservationRepo(conn)
+	auditSvc := NewAuditService(repository.NewAuditRepo(conn))
+	hub := realtime.NewHub()
+	t.Cleanup(hub.Close)
+	fcmSvc, _ := notify.NewFCMService("", repository.NewUserRepo(conn))
+	waitlistSvc := NewWaitlistService(repository.NewWaitlistRepo(conn), reservationRepo, NewAuthorizer(repository.NewVehicleRepo(conn), auditSvc), auditSvc, fcmSvc)
+	return NewReservationService(reservationRepo, repository.NewConflictRepo(conn), repository.NewVehicleRepo(conn),
+		repository.NewMaintenanceRepo(conn), repository.NewDispatchRepo(conn), waitlistSvc, auditSvc, hub, nil, time.Second)
+}
+
+// TestPlace_ConcurrentSameSlot books overlapping slots on one vehicle from
+// many goroutines through Place. Every booking must be stored: one holds
+// the slot, the others wait in the conflict queue with a conflict filed
+// against them. Needs a migrated Postgres in TEST_DATABASE_URL.
+func TestPlace_ConcurrentSameSlot(t *testing.T) {
+	conn := testdb.Open(t)
+	ctx := context.Background()
+	f := testdb.NewFixture(t, conn, "Place Test", model.RoleDispatcher)
+	userID, vehicleID := f.UserID, f.VehicleID
+
+	svc := newTestReservationService(t, conn)
+
+	start := time.Now().Add(24 * time.Hour).Truncate(time.Minute)
+	const workers = 20
+	var (
+		wg   sync.WaitGroup
+		mu   sync.Mutex
+		errs []error
+	)
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			// Staggered but overlapping windows, so the ranges differ too.
+			offset := time.Duration(i) * time.Minute
+			err := svc.Place(ctx, &model.Reservation{
+				VehicleID:   vehicleID,
+				RequesterID: userID,
+				StartTime:   start.Add(offset),
+				EndTime:     start.Add(offset + time.Hour),
+				Purpose:     fmt.Sprintf("worker %d", i),
+				Status:      model.ReservationStatusConfirmed,
+			})
+			if err != nil {
+				mu.Lock()
+				errs = append(errs, err)
+				mu.Unlock()
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(errs) > 0 {
+		t.Fatalf("Place errors: %v", errs)
+	}
+
+	var stored []struct {
+		ID        string                  `db:"id"`
+		Status    model.ReservationStatus `db:"status"`
+		Conflicts int                     `db:"conflicts"`
+	}
+	if err := conn.SelectContext(ctx, &stored, `
+		SELECT r.id, r.status,
+			(SELECT COUNT(*) FROM reservation_conflicts c
+			 WHERE c.losing_reservation_id = r.id AND c.status = 'pending') AS conflicts
+		FROM reservations r WHERE r.vehicle_id = $1`, vehicleID); err != nil {
+		t.Fatalf("list reservations: %v", err)
+	}
+	if len(stored) != workers {
+		t.Fatalf("stored %d reservations, want %d", len(stored), workers)
+	}
+	confirmed := 0
+	for _, r := range stored {
+		switch r.Status {
+		case model.ReservationStatusConfirmed:
+			confirmed++
+		case model.ReservationStatusPendingConflict:
+			if r.Conflicts == 0 {
+				t.Errorf("reservation %s is pending_conflict with no conflict filed", r.ID)
+			}
+		default:
+			t.Errorf("reservation %s is %s", r.ID, r.Status)
+		}
+	}
+	if confirmed != 1 {
+		t.Errorf("%d reservations confirmed, want 1", confirmed)
+	}
+}
+
+// TestReschedule_CancelsTrip moves a reservation whose trip has already gone
+// out to the driver. The trip must be cancelled rather than left on the old
+// time, and the reservation must be due for a fresh one. Needs a migrated
+// Postgres in TEST_DATABASE_URL.
+func TestReschedule_CancelsTrip(t *testing.T) {
+	conn := testdb.Open(t)
+	ctx := context.Background()
+	f := testdb.NewFixture(t, conn, "Reschedule Test", model.RoleDispatcher)
+	svc := newTestReservationService(t, conn)
+	reservationRepo := repository.NewReservationRepo(conn)
+	dispatchRepo := repository.NewDispatchRepo(conn)
+
+	start := time.Now().Add(10 * time.Minute).Truncate(time.Minute)
+	res := &model.Reservation{
+		VehicleID:   f.VehicleID,
+		RequesterID: f.UserID,
+		StartTime:   start,
+		EndTime:     start.Add(time.Hour),
+		Purpose:     "reschedule test",
+		Status:      model.ReservationStatusConfirmed,
+	}
+	if err := reservationRepo.Create(ctx, res); err != nil {
+		t.Fatalf("create reservation: %v", err)
+	}
+	trip := &model.Dispatch{
+		RequesterID:    f.UserID,
+		Purpose:        res.Purpose,
+		PassengerCount: 1,
+		PickupAddress:  "(reservation)",
+		VehicleID:      &f.VehicleID,
+		ReservationID:  &res.ID,
+	}
+	if created, err := dispatchRepo.CreateForReservation(ctx, trip); err != nil || !created {
+		t.Fatalf("create trip: created=%v err=%v", created, err)
+	}
+
+	res.StartTime = start.Add(15 * time.Minute)
+	res.EndTime = res.StartTime.Add(time.Hour)
+	if err := svc.Reschedule(ctx, res, f.UserID); err != nil {
+		t.Fatalf("Reschedule: %v", err)
+	}
+
+	d, err := dispatchRepo.GetByID(ctx, trip.ID)
+	if err != nil {
+		t.Fatalf("get trip: %v", err)
+	}
+	if d.Status != model.DispatchStatusCancelled {
+		t.Errorf("trip is %s after the reservation moved, want cancelled", d.Status)
+	}
+	due, err := reservationRepo.ListDueForTrip(ctx, time.Hour)
+	if err != nil {
+		t.Fatalf("ListDueForTrip: %v", err)
+	}
+	found := false
+	for _, r := range due {
+		found = found || r.ID == res.ID
+	}
+	if !found {
+		t.Error("moved reservation is not due for a new trip")
+	}
+}
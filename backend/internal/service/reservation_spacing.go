@@ -0,0 +1,132 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kento/driver/backend/internal/maps"
+	"github.com/kento/driver/backend/internal/model"
+)
+
+// TravelTimer estimates the drive between two places. *maps.Client is one.
+type TravelTimer interface {
+	TravelTime(ctx context.Context, from, to maps.Place) (time.Duration, error)
+}
+
+// maxTravelGap bounds how far apart two reservations can be and still be
+// checked for travel time; no drive in the service area takes longer.
+const maxTravelGap = 3 * time.Hour
+
+// spacingViolation is a reservation holding the same vehicle that leaves too
+// little time before or after the one being placed.
+type spacingViolation struct {
+	other     model.Reservation
+	kind      model.ConflictKind
+	required  time.Duration
+	available time.Duration
+}
+
+// spacingViolations returns the reservations on res's vehicle that end too
+// shortly before it or start too shortly after it, given the vehicle's
+// turnaround and, when the travel check is on, the drive in between.
+func (s *ReservationService) spacingViolations(ctx context.Context, res *model.Reservation) ([]spacingViolation, error) {
+	v, err := s.vehicleRepo.GetByID(ctx, res.VehicleID)
+	if err != nil || v == nil {
+		return nil, err
+	}
+	turnaround := time.Duration(v.TurnaroundMin) * time.Minute
+	window := turnaround
+	if s.travel != nil {
+		window += maxTravelGap
+	}
+	if window == 0 {
+		return nil, nil
+	}
+
+	neighbours, err := s.repo.FindNeighbours(ctx, res.VehicleID, res.StartTime, res.EndTime, window, res.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []spacingViolation
+	for i := range neighbours {
+		earlier, later := &neighbours[i], res
+		if neighbours[i].StartTime.After(res.StartTime) {
+			earlier, later = res, &neighbours[i]
+		}
+		drive := s.drive(ctx, earlier, later)
+		kind, required, ok := model.CheckSpacing(earlier.EndTime, later.StartTime, turnaround, drive)
+		if ok {
+			continue
+		}
+		violations = append(violations, spacingViolation{
+			other:     neighbours[i],
+			kind:      kind,
+			required:  required,
+			available: later.StartTime.Sub(earlier.EndTime),
+		})
+	}
+	return violations, nil
+}
+
+// drive estimates the trip from where earlier ends to later's pickup. It is
+// zero when the travel check is off, either end is unknown or routing fails;
+// the turnaround still applies then.
+func (s *ReservationService) drive(ctx context.Context, earlier, later *model.Reservation) time.Duration {
+	if s.travel == nil {
+		return 0
+	}
+	from, ok := lastStop(earlier)
+	if !ok {
+		return 0
+	}
+	to, ok := pickupPlace(later)
+	if !ok {
+		return 0
+	}
+
+	tctx, cancel := context.WithTimeout(ctx, s.travelTimeout)
+	defer cancel()
+	d, err := s.travel.TravelTime(tctx, from, to)
+	if err != nil {
+		log.Printf("[reservation] travel time for vehicle %s at %s: %v", later.VehicleID, later.StartTime.Format(time.RFC3339), err)
+		return 0
+	}
+	return d
+}
+
+// lastStop is where a trip ends: its last destination, or its pickup when it
+// lists none.
+func lastStop(r *model.Reservation) (maps.Place, bool) {
+	for i := len(r.Destinations) - 1; i >= 0; i-- {
+		if r.Destinations[i] != "" {
+			return maps.Place{Address: r.Destinations[i]}, true
+		}
+	}
+	return pickupPlace(r)
+}
+
+func pickupPlace(r *model.Reservation) (maps.Place, bool) {
+	if r.PickupLat != nil && r.PickupLng != nil {
+		return maps.Place{LatLng: &maps.LatLng{Lat: *r.PickupLat, Lng: *r.PickupLng}}, true
+	}
+	if r.PickupAddress != nil && *r.PickupAddress != "" {
+		return maps.Place{Address: *r.PickupAddress}, true
+	}
+	return maps.Place{}, false
+}
+
+// recordSpacing files a turnaround or travel conflict per violation, with
+// the priority rules of recordConflicts.
+func (s *ReservationService) recordSpacing(ctx context.Context, res *model.Reservation, violations []spacingViolation) {
+	for _, v := range violations {
+		winner, loser := v.other.ID, res.ID
+		if res.PriorityLevel > v.other.PriorityLevel {
+			_ = s.bump(ctx, v.other.ID, "", "lost its slot to a higher priority booking")
+			winner, loser = res.ID, v.other.ID
+		}
+		required, available := int(v.required/time.Second), int(v.available/time.Second)
+		_, _ = s.conflictRepo.CreateUnlessPending(ctx, winner, loser, v.kind, &required, &available)
+	}
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"context"
+	"log"
+	"math"
+	"sync/atomic"
+	"time"
+
+	"github.com/kento/driver/backend/internal/dto"
+	"github.com/kento/driver/backend/internal/eta"
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+// TravelModelService learns average driving speeds from completed trips and
+// keeps the learned ETA provider up to date. The leader retrains and stores
+// the table; every replica reloads it periodically.
+type TravelModelService struct {
+	repo       *repository.TravelRepo
+	learned    *eta.Learned
+	flat       eta.SpeedProfile
+	flatSpec   string
+	windowDays int
+	trainedAt  atomic.Pointer[time.Time]
+}
+
+// NewTravelModelService builds the service. flat is the speed profile the
+// model is compared against (flatSpec is how it was configured) and
+// windowDays is how far back trips are learned from.
+func NewTravelModelService(repo *repository.TravelRepo, learned *eta.Learned, flat eta.SpeedProfile, flatSpec string, windowDays int) *TravelModelService {
+	return &TravelModelService{repo: repo, learned: learned, flat: flat, flatSpec: flatSpec, windowDays: windowDays}
+}
+
+// Retrain rebuilds the speed table from trips completed in the training
+// window, stores it and starts using it. Returns the number of cell/hour
+// aggregates learned.
+func (s *TravelModelService) Retrain(ctx context.Context) (int, error) {
+	legs, err := s.listLegs(ctx, time.Now().AddDate(0, 0, -s.windowDays))
+	if err != nil {
+		return 0, err
+	}
+	table := eta.BuildSpeedTable(legs)
+
+	now := time.Now()
+	stats := table.Stats()
+	speeds := make([]model.TravelSpeed, 0, len(stats))
+	for _, st := range stats {
+		speeds = append(speeds, model.TravelSpeed{
+			CellLat:     st.Cell.Lat,
+			CellLng:     st.Cell.Lng,
+			HourOfWeek:  st.HourOfWeek,
+			DistanceM:   st.DistanceM,
+			DurationSec: st.DurationSec,
+			Samples:     st.Samples,
+			TrainedAt:   now,
+		})
+	}
+	if err := s.repo.ReplaceSpeeds(ctx, speeds); err != nil {
+		return 0, err
+	}
+
+	s.learned.SetTable(table)
+	s.trainedAt.Store(&now)
+	log.Printf("[eta] learned %d cell/hour speeds from %d trip legs", table.Len(), len(legs))
+	return table.Len(), nil
+}
+
+// Load replaces the in-memory speeds with the stored table.
+func (s *TravelModelService) Load(ctx context.Context) error {
+	speeds, err := s.repo.ListSpeeds(ctx)
+	if err != nil {
+		return err
+	}
+	stats := make([]eta.SpeedStat, 0, len(speeds))
+	var trainedAt *time.Time
+	for _, sp := range speeds {
+		stats = append(stats, eta.SpeedStat{
+			Cell:        eta.Cell{Lat: sp.CellLat, Lng: sp.CellLng},
+			HourOfWeek:  sp.HourOfWeek,
+			DistanceM:   sp.DistanceM,
+			DurationSec: sp.DurationSec,
+			Samples:     sp.Samples,
+		})
+		if trainedAt == nil {
+			t := sp.TrainedAt
+			trainedAt = &t
+		}
+	}
+	s.learned.SetTable(eta.NewSpeedTable(stats))
+	s.trainedAt.Store(trainedAt)
+	return nil
+}
+
+// Run reloads the stored table every interval so replicas that are not the
+// job leader pick up retrained speeds, until stop is closed. It blocks, so
+// callers start it in its own goroutine.
+func (s *TravelModelService) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := s.Load(context.Background()); err != nil {
+			log.Printf("[eta] load learned speeds: %v", err)
+		}
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Accuracy scores the learned model against the flat speed profile on trips
+// completed in the last days days. The model under test is trained on the
+// preceding training window only, so the comparison is out of sample.
+func (s *TravelModelService) Accuracy(ctx context.Context, days int) (*dto.ETAAccuracyReport, error) {
+	if days < 1 || days > 90 {
+		return nil, apperror.New(400, "VALIDATION_ERROR", "days must be between 1 and 90")
+	}
+	to := time.Now()
+	from := to.AddDate(0, 0, -days)
+
+	legs, err := s.listLegs(ctx, from.AddDate(0, 0, -s.windowDays))
+	if err != nil {
+		return nil, err
+	}
+	var train, test []eta.Leg
+	for _, l := range legs {
+		if l.StartedAt.Before(from) {
+			train = append(train, l)
+		} else {
+			test = append(test, l)
+		}
+	}
+	table := eta.BuildSpeedTable(train)
+
+	flatPredict := func(l eta.Leg) (int, bool) {
+		return s.flat.Duration(eta.Distance(l.From, l.To), l.StartedAt), true
+	}
+	var covered int
+	learnedPredict := func(l eta.Leg) (int, bool) {
+		if e, ok := table.Predict(l.From, l.To, l.StartedAt); ok {
+			covered++
+			return e.DurationSec, true
+		}
+		return flatPredict(l)
+	}
+
+	report := &dto.ETAAccuracyReport{
+		From:          from,
+		To:            to,
+		TrainingLegs:  len(train),
+		Learned:       eta.Evaluate(test, learnedPredict),
+		Flat:          eta.Evaluate(test, flatPredict),
+		FlatProfile:   s.flatSpec,
+		LiveCells:     s.learned.Table().Len(),
+		LiveTrainedAt: s.trainedAt.Load(),
+	}
+	if report.Learned.Samples > 0 {
+		report.Coverage = math.Round(float64(covered)/float64(report.Learned.Samples)*1000) / 1000
+	}
+	if report.Flat.MAESec > 0 {
+		report.Improvement = math.Round((1-report.Learned.MAESec/report.Flat.MAESec)*1000) / 1000
+	}
+	return report, nil
+}
+
+// listLegs loads trip legs in server local time, which hour-of-week buckets
+// are defined in.
+func (s *TravelModelService) listLegs(ctx context.Context, since time.Time) ([]eta.Leg, error) {
+	rows, err := s.repo.ListTripLegs(ctx, since)
+	if err != nil {
+		return nil, err
+	}
+	legs := make([]eta.Leg, 0, len(rows))
+	for _, r := range rows {
+		legs = append(legs, eta.Leg{
+			From:        eta.Point{Lat: r.FromLat, Lng: r.FromLng},
+			To:          eta.Point{Lat: r.ToLat, Lng: r.ToLng},
+			StartedAt:   r.StartedAt.In(time.Local),
+			DurationSec: int(r.EndedAt.Sub(r.StartedAt).Seconds()),
+		})
+	}
+	return legs, nil
+}
@@ -4,26 +4,35 @@ import (
 	"context"
 	"time"
 
+	"github.com/kento/driver/backend/internal/dto"
 	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/realtime"
 	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/pkg/apperror"
 )
 
 type VehicleService struct {
 	repo           *repository.VehicleRepo
+	depotRepo      *repository.DepotRepo
 	staleThreshold time.Duration
 	auditSvc       *AuditService
+	hub            *realtime.Hub
 }
 
-func NewVehicleService(repo *repository.VehicleRepo, staleThreshold time.Duration, auditSvc *AuditService) *VehicleService {
+func NewVehicleService(repo *repository.VehicleRepo, depotRepo *repository.DepotRepo, staleThreshold time.Duration, auditSvc *AuditService, hub *realtime.Hub) *VehicleService {
 	return &VehicleService{
 		repo:           repo,
+		depotRepo:      depotRepo,
 		staleThreshold: staleThreshold,
 		auditSvc:       auditSvc,
+		hub:            hub,
 	}
 }
 
-func (s *VehicleService) ListWithStatus(ctx context.Context) ([]model.VehicleWithStatus, error) {
-	return s.repo.ListWithStatus(ctx, s.staleThreshold)
+// ListWithStatus returns the vehicles within the depot scope depotIDs, nil
+// for all.
+func (s *VehicleService) ListWithStatus(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error) {
+	return s.repo.ListWithStatus(ctx, s.staleThreshold, depotIDs)
 }
 
 func (s *VehicleService) GetByID(ctx context.Context, id string) (*model.Vehicle, error) {
@@ -34,27 +43,73 @@ func (s *VehicleService) GetByDriverID(ctx context.Context, driverID string) (*m
 	return s.repo.GetByDriverID(ctx, driverID)
 }
 
-func (s *VehicleService) ListAvailable(ctx context.Context) ([]model.VehicleWithStatus, error) {
-	return s.repo.ListAvailable(ctx, s.staleThreshold)
+func (s *VehicleService) ListAvailable(ctx context.Context, depotIDs []string) ([]model.VehicleWithStatus, error) {
+	return s.repo.ListAvailable(ctx, s.staleThreshold, depotIDs)
 }
 
-func (s *VehicleService) Create(ctx context.Context, actorID, name, licensePlate, driverID string) (*model.Vehicle, error) {
-	v, err := s.repo.Create(ctx, name, licensePlate, driverID)
+// Create adds a vehicle to the depot depotID, nil to share it between all.
+func (s *VehicleService) Create(ctx context.Context, actorID, name, licensePlate string, depotID *string) (*model.Vehicle, error) {
+	if err := s.checkDepot(ctx, depotID); err != nil {
+		return nil, err
+	}
+	v, err := s.repo.Create(ctx, name, licensePlate, depotID)
 	if err != nil {
 		return nil, err
 	}
 	s.auditSvc.Log(ctx, actorID, "vehicle.create", "vehicle", v.ID, nil, v, "")
+	s.hub.Publish(realtime.Event{Type: realtime.EventVehicleUpdated, VehicleID: v.ID, Data: v})
 	return v, nil
 }
 
-func (s *VehicleService) Update(ctx context.Context, actorID, vehicleID, name, licensePlate, driverID string) error {
+func (s *VehicleService) Update(ctx context.Context, actorID, vehicleID, name, licensePlate string) error {
 	before, _ := s.repo.GetByID(ctx, vehicleID)
-	err := s.repo.Update(ctx, vehicleID, name, licensePlate, driverID)
+	err := s.repo.Update(ctx, vehicleID, name, licensePlate)
 	if err != nil {
 		return err
 	}
 	after, _ := s.repo.GetByID(ctx, vehicleID)
 	s.auditSvc.Log(ctx, actorID, "vehicle.update", "vehicle", vehicleID, before, after, "")
+	s.hub.Publish(realtime.Event{Type: realtime.EventVehicleUpdated, VehicleID: vehicleID, Data: after})
+	return nil
+}
+
+// Transfer moves a vehicle to the depot depotID, nil to share it between
+// all. Its reservations and dispatches follow it.
+func (s *VehicleService) Transfer(ctx context.Context, actorID, vehicleID string, depotID *string) (*model.Vehicle, error) {
+	before, err := s.repo.GetByID(ctx, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	if before == nil {
+		return nil, apperror.ErrNotFound
+	}
+	if err := s.checkDepot(ctx, depotID); err != nil {
+		return nil, err
+	}
+	if err := s.repo.SetDepot(ctx, vehicleID, depotID); err != nil {
+		return nil, err
+	}
+	after, err := s.repo.GetByID(ctx, vehicleID)
+	if err != nil {
+		return nil, err
+	}
+	s.auditSvc.Log(ctx, actorID, "vehicle.transfer", "vehicle", vehicleID, before, after, "")
+	s.hub.Publish(realtime.Event{Type: realtime.EventVehicleUpdated, VehicleID: vehicleID, Data: after})
+	return after, nil
+}
+
+// checkDepot verifies a depot a vehicle is put into exists.
+func (s *VehicleService) checkDepot(ctx context.Context, depotID *string) error {
+	if depotID == nil {
+		return nil
+	}
+	d, err := s.depotRepo.GetByID(ctx, *depotID)
+	if err != nil {
+		return err
+	}
+	if d == nil {
+		return apperror.New(400, "INVALID_DEPOT", "depot not found")
+	}
 	return nil
 }
 
@@ -65,6 +120,7 @@ func (s *VehicleService) Delete(ctx context.Context, actorID, vehicleID string)
 		return err
 	}
 	s.auditSvc.Log(ctx, actorID, "vehicle.delete", "vehicle", vehicleID, before, nil, "")
+	s.hub.Publish(realtime.Event{Type: realtime.EventVehicleDeleted, VehicleID: vehicleID})
 	return nil
 }
 
@@ -72,13 +128,83 @@ func (s *VehicleService) UpdatePhotoURL(ctx context.Context, vehicleID string, p
 	return s.repo.UpdatePhotoURL(ctx, vehicleID, photoURL)
 }
 
-func (s *VehicleService) ToggleMaintenance(ctx context.Context, actorID, vehicleID string, maintenance bool) error {
-	before, _ := s.repo.GetByID(ctx, vehicleID)
-	err := s.repo.ToggleMaintenance(ctx, vehicleID, maintenance)
+// maxTurnaroundMin matches the vehicles.turnaround_min check constraint.
+const maxTurnaroundMin = 240
+
+// SetTurnaround sets how many minutes the vehicle needs between bookings.
+// It applies to reservations placed or moved from now on.
+func (s *VehicleService) SetTurnaround(ctx context.Context, actorID, vehicleID string, minutes int) error {
+	if minutes < 0 || minutes > maxTurnaroundMin {
+		return apperror.New(400, "INVALID_TURNAROUND", "turnaround_min must be between 0 and 240")
+	}
+	before, err := s.repo.GetByID(ctx, vehicleID)
 	if err != nil {
 		return err
 	}
+	if before == nil {
+		return apperror.ErrNotFound
+	}
+	if err := s.repo.SetTurnaround(ctx, vehicleID, minutes); err != nil {
+		return err
+	}
 	after, _ := s.repo.GetByID(ctx, vehicleID)
-	s.auditSvc.Log(ctx, actorID, "vehicle.maintenance_toggle", "vehicle", vehicleID, before, after, "")
+	s.auditSvc.Log(ctx, actorID, "vehicle.turnaround_set", "vehicle", vehicleID, before, after, "")
+	s.hub.Publish(realtime.Event{Type: realtime.EventVehicleUpdated, VehicleID: vehicleID, Data: after})
 	return nil
 }
+
+// Limits of the fleet availability grid, so one request cannot ask for a
+// year in one-minute cells.
+const (
+	maxAvailabilityWindow = 31 * 24 * time.Hour
+	minGranularity        = time.Minute
+	maxGranularity        = 24 * time.Hour
+)
+
+// Availability returns the free/busy grid over [from, to) of every vehicle
+// within the depot scope depotIDs, nil for all.
+func (s *VehicleService) Availability(ctx context.Context, from, to time.Time, granularity time.Duration, depotIDs []string) (*dto.FleetAvailability, error) {
+	if !to.After(from) {
+		return nil, apperror.New(400, "INVALID_TIME_RANGE", "to must be after from")
+	}
+	if to.Sub(from) > maxAvailabilityWindow {
+		return nil, apperror.New(400, "WINDOW_TOO_LARGE", "availability window may span at most 31 days")
+	}
+	if granularity < minGranularity || granularity > maxGranularity {
+		return nil, apperror.New(400, "INVALID_GRANULARITY", "granularity must be between 1m and 24h")
+	}
+
+	rows, err := s.repo.Availability(ctx, from, to, granularity, depotIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	// Rows arrive ordered by vehicle, then start
+	vehicles := []dto.VehicleAvailability{}
+	for _, row := range rows {
+		if n := len(vehicles); n == 0 || vehicles[n-1].VehicleID != row.VehicleID {
+			vehicles = append(vehicles, dto.VehicleAvailability{
+				VehicleID:    row.VehicleID,
+				VehicleName:  row.VehicleName,
+				LicensePlate: row.LicensePlate,
+				DriverName:   row.DriverName,
+				Busy:         []dto.BusyInterval{},
+				Free:         []dto.TimeInterval{},
+			})
+		}
+		v := &vehicles[len(vehicles)-1]
+		interval := dto.TimeInterval{Start: row.Start, End: row.End}
+		if row.Kind == model.AvailabilityFree {
+			v.Free = append(v.Free, interval)
+			continue
+		}
+		v.Busy = append(v.Busy, dto.BusyInterval{TimeInterval: interval, Kind: row.Kind, RefID: row.RefID, Status: row.Status})
+	}
+
+	return &dto.FleetAvailability{
+		From:        from,
+		To:          to,
+		Granularity: granularity.String(),
+		Vehicles:    vehicles,
+	}, nil
+}
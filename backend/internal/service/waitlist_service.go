@@ -0,0 +1,178 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/kento/driver/backend/internal/model"
+	"github.com/kento/driver/backend/internal/notify"
+	"github.com/kento/driver/backend/internal/policy"
+	"github.com/kento/driver/backend/internal/repository"
+	"github.com/kento/driver/backend/pkg/apperror"
+)
+
+// WaitlistService keeps future bookings no vehicle was free for. Whenever a
+// reservation gives up its vehicle, the waiting entries overlapping the freed
+// time are retried, higher priority first and then first come, and booked
+// like an "any vehicle" request.
+type WaitlistService struct {
+	repo            *repository.WaitlistRepo
+	reservationRepo *repository.ReservationRepo
+	authz           *Authorizer
+	auditSvc        *AuditService
+	fcmSvc          *notify.FCMService
+}
+
+func NewWaitlistService(repo *repository.WaitlistRepo, reservationRepo *repository.ReservationRepo, authz *Authorizer, auditSvc *AuditService, fcmSvc *notify.FCMService) *WaitlistService {
+	return &WaitlistService{repo: repo, reservationRepo: reservationRepo, authz: authz, auditSvc: auditSvc, fcmSvc: fcmSvc}
+}
+
+// Join adds entry to the waitlist.
+func (s *WaitlistService) Join(ctx context.Context, entry *model.WaitlistEntry) error {
+	if !entry.EndTime.After(entry.StartTime) {
+		return apperror.New(400, "INVALID_TIME_RANGE", "end_time must be after start_time")
+	}
+	if !entry.StartTime.After(time.Now()) {
+		return apperror.New(400, "PAST_TIME", "cannot wait for a slot that has already started")
+	}
+	if err := s.repo.Create(ctx, entry); err != nil {
+		return err
+	}
+	s.auditSvc.Log(ctx, entry.RequesterID, "waitlist.join", "waitlist", entry.ID, nil, entry, "")
+	return nil
+}
+
+func (s *WaitlistService) GetByID(ctx context.Context, id string) (*model.WaitlistEntry, error) {
+	return s.repo.GetByID(ctx, id)
+}
+
+// GetFor returns an entry if sub may read it.
+func (s *WaitlistService) GetFor(ctx context.Context, sub policy.Subject, id string) (*model.WaitlistEntry, error) {
+	entry, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if entry == nil {
+		return nil, apperror.ErrNotFound
+	}
+	if err := s.authz.Waitlist(ctx, sub, policy.WaitlistRead, entry); err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// List returns entries whose depot scope meets depotIDs, nil for all.
+func (s *WaitlistService) List(ctx context.Context, requesterID, status string, depotIDs []string, limit, offset int) ([]model.WaitlistEntry, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	return s.repo.List(ctx, requesterID, status, depotIDs, limit, offset)
+}
+
+// Cancel withdraws a waiting entry if sub may: only its requester and staff
+// may.
+func (s *WaitlistService) Cancel(ctx context.Context, sub policy.Subject, id string) error {
+	entry, err := s.repo.GetByID(ctx, id)
+	if err != nil {
+		return err
+	}
+	if entry == nil {
+		return apperror.ErrNotFound
+	}
+	if err := s.authz.Waitlist(ctx, sub, policy.WaitlistCancel, entry); err != nil {
+		return err
+	}
+	cancelled, err := s.repo.Cancel(ctx, id)
+	if err != nil {
+		return err
+	}
+	if !cancelled {
+		return apperror.New(400, "INVALID_STATUS", "waitlist entry is no longer waiting")
+	}
+	s.auditSvc.Log(ctx, sub.UserID, "waitlist.cancel", "waitlist", id, entry, nil, "")
+	return nil
+}
+
+// SlotReleased retries the entries waiting for time overlapping [startTime,
+// endTime) after a reservation gave it up. The release itself already
+// happened, so failures are logged rather than returned.
+func (s *WaitlistService) SlotReleased(ctx context.Context, startTime, endTime time.Time) {
+	entries, err := s.repo.FindWaiting(ctx, startTime, endTime)
+	if err != nil {
+		log.Printf("[waitlist] find waiting for %s-%s: %v", startTime.Format(time.RFC3339), endTime.Format(time.RFC3339), err)
+		return
+	}
+	for i := range entries {
+		if err := s.book(ctx, &entries[i]); err != nil {
+			log.Printf("[waitlist] book %s: %v", entries[i].ID, err)
+		}
+	}
+}
+
+// book tries to place entry on a free vehicle within the depot scope it was
+// queued with. The entry is claimed first so
+// two releases racing for it cannot book it twice; it goes back on the list
+// when no vehicle is free for its whole slot.
+func (s *WaitlistService) book(ctx context.Context, entry *model.WaitlistEntry) error {
+	claimed, err := s.repo.Claim(ctx, entry.ID)
+	if err != nil || !claimed {
+		return err
+	}
+
+	res := &model.Reservation{
+		RequesterID:   entry.RequesterID,
+		StartTime:     entry.StartTime,
+		EndTime:       entry.EndTime,
+		Purpose:       entry.Purpose,
+		Destinations:  entry.Destinations,
+		Notes:         entry.Notes,
+		PassengerName: entry.PassengerName,
+		PickupAddress: entry.PickupAddress,
+		PickupLat:     entry.PickupLat,
+		PickupLng:     entry.PickupLng,
+		PriorityLevel: entry.PriorityLevel,
+		Status:        model.ReservationStatusPendingDriver,
+	}
+	if err := placeOnFreeVehicle(ctx, s.reservationRepo, res, entry.DepotIDs); err != nil {
+		if relErr := s.repo.Release(ctx, entry.ID); relErr != nil {
+			log.Printf("[waitlist] release %s: %v", entry.ID, relErr)
+		}
+		if err == errNoVehicle {
+			return nil
+		}
+		return err
+	}
+	if err := s.repo.SetReservation(ctx, entry.ID, res.ID); err != nil {
+		return err
+	}
+
+	s.auditSvc.Log(ctx, entry.RequesterID, "waitlist.book", "waitlist", entry.ID, entry, res, "")
+
+	// Pushes outlive the request or job that freed the slot
+	notifyCtx := context.WithoutCancel(ctx)
+	go s.fcmSvc.NotifyUser(notifyCtx, entry.RequesterID, "Waitlist Booked", entry.Purpose, map[string]string{
+		"type": "waitlist_booked", "waitlist_id": entry.ID, "reservation_id": res.ID,
+	})
+	go s.fcmSvc.NotifyVehicleDriver(notifyCtx, res.VehicleID, "Reservation Pending", res.Purpose, map[string]string{
+		"type": "reservation_pending", "reservation_id": res.ID,
+	})
+	return nil
+}
+
+// ExpireStarted expires entries still waiting when their slot starts and
+// tells the requesters. Returns how many expired.
+func (s *WaitlistService) ExpireStarted(ctx context.Context) (int, error) {
+	expired, err := s.repo.ExpireStarted(ctx)
+	if err != nil {
+		return 0, err
+	}
+	// The job's context ends when it returns, before the pushes are sent
+	notifyCtx := context.WithoutCancel(ctx)
+	for _, e := range expired {
+		go s.fcmSvc.NotifyUser(notifyCtx, e.RequesterID, "Waitlist Expired", e.Purpose, map[string]string{
+			"type": "waitlist_expired", "waitlist_id": e.ID,
+		})
+	}
+	return len(expired), nil
+}
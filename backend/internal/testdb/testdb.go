@@ -0,0 +1,70 @@
+// Package testdb sets up the Postgres that repository and service tests run
+// against. Tests using it skip unless TEST_DATABASE_URL is set.
+package testdb
+
+import (
+	"context"
+	"os"
+	"testing"
+
+	"github.com/google/uuid"
+	"github.com/jmoiron/sqlx"
+
+	"github.com/kento/driver/backend/internal/db"
+	"github.com/kento/driver/backend/internal/model"
+)
+
+// Open connects to TEST_DATABASE_URL and migrates it, skipping t when the
+// variable is not set. The connection is closed when the test ends.
+func Open(t testing.TB) *sqlx.DB {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL not set")
+	}
+	conn, err := db.Connect(url)
+	if err != nil {
+		t.Fatalf("connect: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	if err := db.RunMigrations(conn); err != nil {
+		t.Fatalf("migrate: %v", err)
+	}
+	return conn
+}
+
+// Fixture is a user and a vehicle to book against.
+type Fixture struct {
+	UserID    string
+	VehicleID string
+}
+
+// NewFixture inserts a user with role and a vehicle, both called name. When
+// the test ends they are deleted along with the trips, reservations and
+// conflicts on the vehicle and what the user did in the audit log.
+func NewFixture(t testing.TB, conn *sqlx.DB, name string, role model.Role) Fixture {
+	t.Helper()
+	ctx := context.Background()
+	suffix := uuid.NewString()[:8]
+	var f Fixture
+	if err := conn.GetContext(ctx, &f.UserID, `
+		INSERT INTO users (employee_id, password_hash, name, role)
+		VALUES ($1, 'x', $2, $3) RETURNING id`, "test-"+suffix, name, role); err != nil {
+		t.Fatalf("insert user: %v", err)
+	}
+	if err := conn.GetContext(ctx, &f.VehicleID, `
+		INSERT INTO vehicles (name, license_plate)
+		VALUES ($1, $2) RETURNING id`, name, "TST-"+suffix); err != nil {
+		t.Fatalf("insert vehicle: %v", err)
+	}
+	t.Cleanup(func() {
+		conn.Exec(`DELETE FROM dispatches WHERE vehicle_id = $1 OR requester_id = $2`, f.VehicleID, f.UserID)
+		conn.Exec(`DELETE FROM reservation_conflicts WHERE losing_reservation_id IN (SELECT id FROM reservations WHERE vehicle_id = $1)
+			OR winning_reservation_id IN (SELECT id FROM reservations WHERE vehicle_id = $1)`, f.VehicleID)
+		conn.Exec(`DELETE FROM reservations WHERE vehicle_id = $1`, f.VehicleID)
+		conn.Exec(`DELETE FROM vehicles WHERE id = $1`, f.VehicleID)
+		conn.Exec(`DELETE FROM audit_logs WHERE actor_id = $1`, f.UserID)
+		conn.Exec(`DELETE FROM users WHERE id = $1`, f.UserID)
+	})
+	return f
+}
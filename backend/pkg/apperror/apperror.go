@@ -6,9 +6,10 @@ import (
 )
 
 type AppError struct {
-	Code    string `json:"code"`
-	Message string `json:"message"`
-	Status  int    `json:"-"`
+	Code    string      `json:"code"`
+	Message string      `json:"message"`
+	Details interface{} `json:"details,omitempty"`
+	Status  int         `json:"-"`
 }
 
 func (e *AppError) Error() string {
@@ -19,6 +20,14 @@ func New(status int, code, message string) *AppError {
 	return &AppError{Code: code, Message: message, Status: status}
 }
 
+// WithDetails returns a copy of the error carrying machine-readable details
+// for the client.
+func (e *AppError) WithDetails(details interface{}) *AppError {
+	c := *e
+	c.Details = details
+	return &c
+}
+
 var (
 	ErrUnauthorized    = New(http.StatusUnauthorized, "UNAUTHORIZED", "authentication required")
 	ErrForbidden       = New(http.StatusForbidden, "FORBIDDEN", "insufficient permissions")
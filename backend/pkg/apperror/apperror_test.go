@@ -59,6 +59,28 @@ func TestWriteError(t *testing.T) {
 	}
 }
 
+func TestWriteError_Details(t *testing.T) {
+	rec := httptest.NewRecorder()
+	err := ErrConflict.WithDetails(map[string]string{"from": "completed"})
+	WriteError(rec, err)
+
+	if ErrConflict.Details != nil {
+		t.Error("WithDetails modified the shared error")
+	}
+	var resp struct {
+		Error struct {
+			Code    string            `json:"code"`
+			Details map[string]string `json:"details"`
+		} `json:"error"`
+	}
+	if err := json.NewDecoder(rec.Body).Decode(&resp); err != nil {
+		t.Fatalf("failed to decode: %v", err)
+	}
+	if resp.Error.Code != "CONFLICT" || resp.Error.Details["from"] != "completed" {
+		t.Errorf("error = %+v, want CONFLICT with details", resp.Error)
+	}
+}
+
 func TestWriteSuccess(t *testing.T) {
 	rec := httptest.NewRecorder()
 	data := map[string]string{"message": "ok"}
@@ -47,6 +47,7 @@ export interface Dispatch {
   status: DispatchStatus;
   estimated_duration_sec?: number;
   estimated_distance_m?: number;
+  reservation_id?: string;
   created_at: string;
 }
 
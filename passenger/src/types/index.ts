@@ -82,4 +82,5 @@ export interface VehicleETA {
   distance_m: number;
   duration_sec: number;
   is_available: boolean;
+  provider: 'google' | 'osrm' | 'learned' | 'haversine';
 }
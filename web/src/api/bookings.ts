@@ -1,5 +1,5 @@
 import client from './client';
-import type { UnifiedBookingRequest, UnifiedBookingResponse, Reservation } from '../types/api';
+import type { UnifiedBookingRequest, UnifiedBookingResponse, Reservation, WaitlistEntry, WaitlistStatus } from '../types/api';
 
 export async function createBooking(req: UnifiedBookingRequest): Promise<UnifiedBookingResponse> {
   const { data } = await client.post<UnifiedBookingResponse>('/bookings', req);
@@ -25,3 +25,17 @@ export async function driverAcceptReservation(id: string): Promise<void> {
 export async function driverDeclineReservation(id: string, reason: string): Promise<void> {
   await client.post(`/driver/reservations/${id}/decline`, { reason });
 }
+
+export async function listWaitlist(params?: {
+  requester_id?: string;
+  status?: WaitlistStatus;
+  limit?: number;
+  offset?: number;
+}): Promise<WaitlistEntry[]> {
+  const { data } = await client.get<WaitlistEntry[]>('/waitlist', { params });
+  return data;
+}
+
+export async function cancelWaitlistEntry(id: string): Promise<void> {
+  await client.post(`/waitlist/${id}/cancel`);
+}
@@ -0,0 +1,16 @@
+import client from './client';
+import type { CalendarFeed, CalendarFeedType } from '../types/api';
+
+export async function listCalendarFeeds(): Promise<CalendarFeed[]> {
+  const { data } = await client.get<CalendarFeed[]>('/calendar-feeds');
+  return data;
+}
+
+export async function createCalendarFeed(type: CalendarFeedType, subjectId?: string): Promise<CalendarFeed> {
+  const { data } = await client.post<CalendarFeed>('/calendar-feeds', { type, subject_id: subjectId });
+  return data;
+}
+
+export async function revokeCalendarFeed(id: string): Promise<void> {
+  await client.delete(`/calendar-feeds/${id}`);
+}
@@ -0,0 +1,45 @@
+import client from './client';
+import type { Depot, Vehicle } from '../types/api';
+
+export interface DepotFields {
+  name?: string;
+  address?: string;
+  latitude?: number;
+  longitude?: number;
+}
+
+// Dispatchers only get the depots they work.
+export async function listDepots(): Promise<Depot[]> {
+  const { data } = await client.get<Depot[]>('/depots');
+  return data;
+}
+
+export async function createDepot(req: DepotFields & { name: string }): Promise<Depot> {
+  const { data } = await client.post<Depot>('/depots', req);
+  return data;
+}
+
+export async function updateDepot(id: string, req: DepotFields): Promise<Depot> {
+  const { data } = await client.put<Depot>(`/depots/${id}`, req);
+  return data;
+}
+
+export async function deleteDepot(id: string): Promise<void> {
+  await client.delete(`/depots/${id}`);
+}
+
+export async function getUserDepots(userId: string): Promise<string[]> {
+  const { data } = await client.get<{ depot_ids: string[] }>(`/admin/users/${userId}/depots`);
+  return data.depot_ids;
+}
+
+export async function setUserDepots(userId: string, depotIds: string[]): Promise<string[]> {
+  const { data } = await client.put<{ depot_ids: string[] }>(`/admin/users/${userId}/depots`, { depot_ids: depotIds });
+  return data.depot_ids;
+}
+
+// A null depot shares the vehicle between all depots.
+export async function transferVehicle(vehicleId: string, depotId: string | null): Promise<Vehicle> {
+  const { data } = await client.post<Vehicle>(`/vehicles/${vehicleId}/transfer`, { depot_id: depotId });
+  return data;
+}
@@ -1,5 +1,5 @@
 import client from './client';
-import type { Dispatch, ETASnapshot, VehicleETA } from '../types/api';
+import type { Dispatch, DispatchStateMachine, ETASnapshot, VehicleETA } from '../types/api';
 
 export async function listDispatches(status?: string): Promise<Dispatch[]> {
   const params = status ? { status } : {};
@@ -12,6 +12,11 @@ export async function getDispatch(id: string): Promise<Dispatch> {
   return data;
 }
 
+export async function getDispatchStateMachine(): Promise<DispatchStateMachine> {
+  const { data } = await client.get<DispatchStateMachine>('/dispatches/state-machine');
+  return data;
+}
+
 export async function createDispatch(req: {
   purpose: string;
   pickup_address: string;
@@ -75,10 +80,12 @@ export async function driverCurrentTrip(): Promise<Dispatch | null> {
   return data;
 }
 
-export async function calculateETAs(pickupLat: number, pickupLng: number): Promise<VehicleETA[]> {
+// Pass dispatchId to record the candidate list against an existing dispatch.
+export async function calculateETAs(pickupLat: number, pickupLng: number, dispatchId?: string): Promise<VehicleETA[]> {
   const { data } = await client.post<VehicleETA[]>('/dispatches/calculate-eta', {
     pickup_lat: pickupLat,
     pickup_lng: pickupLng,
+    dispatch_id: dispatchId,
   });
   return data;
 }
@@ -0,0 +1,53 @@
+import client from './client';
+import type { DocumentType, VehicleDocument } from '../types/api';
+
+// Dates are calendar days, YYYY-MM-DD.
+export interface DocumentFields {
+  number?: string;
+  issued_on?: string;
+  expires_on?: string;
+  notes?: string;
+}
+
+export async function listDocuments(params?: {
+  vehicle_id?: string;
+  user_id?: string;
+  type?: DocumentType;
+  expiring_within_days?: number;
+}): Promise<VehicleDocument[]> {
+  const { data } = await client.get<VehicleDocument[]>('/documents', { params });
+  return data;
+}
+
+export async function createVehicleDocument(vehicleId: string, req: DocumentFields & { type: DocumentType }): Promise<VehicleDocument> {
+  const { data } = await client.post<VehicleDocument>(`/vehicles/${vehicleId}/documents`, req);
+  return data;
+}
+
+export async function createUserDocument(userId: string, req: DocumentFields & { type: DocumentType }): Promise<VehicleDocument> {
+  const { data } = await client.post<VehicleDocument>(`/admin/users/${userId}/documents`, req);
+  return data;
+}
+
+export async function updateDocument(id: string, req: DocumentFields): Promise<VehicleDocument> {
+  const { data } = await client.patch<VehicleDocument>(`/documents/${id}`, req);
+  return data;
+}
+
+export async function deleteDocument(id: string): Promise<void> {
+  await client.delete(`/documents/${id}`);
+}
+
+export async function uploadDocumentScan(id: string, file: File): Promise<{ scan_file: string }> {
+  const formData = new FormData();
+  formData.append('scan', file);
+  const { data } = await client.post(`/documents/${id}/scan`, formData, {
+    headers: { 'Content-Type': 'multipart/form-data' },
+  });
+  return data;
+}
+
+export async function downloadDocumentScan(id: string): Promise<Blob> {
+  const { data } = await client.get<Blob>(`/documents/${id}/scan`, { responseType: 'blob' });
+  return data;
+}
@@ -0,0 +1,60 @@
+import client from './client';
+import type { MaintenanceSchedule, MaintenanceStatus, MaintenanceType, MaintenanceWindow } from '../types/api';
+
+export async function listMaintenance(params?: { vehicle_id?: string; status?: MaintenanceStatus; from?: string; to?: string }): Promise<MaintenanceWindow[]> {
+  const { data } = await client.get<MaintenanceWindow[]>('/maintenance', { params });
+  return data;
+}
+
+export async function createMaintenanceWindow(vehicleId: string, req: {
+  type: MaintenanceType;
+  start_time: string;
+  end_time: string;
+  vendor?: string;
+  notes?: string;
+}): Promise<MaintenanceWindow> {
+  const { data } = await client.post<MaintenanceWindow>(`/vehicles/${vehicleId}/maintenance-windows`, req);
+  return data;
+}
+
+export async function updateMaintenanceWindow(id: string, req: Partial<Pick<MaintenanceWindow, 'type' | 'start_time' | 'end_time' | 'vendor' | 'notes'>>): Promise<MaintenanceWindow> {
+  const { data } = await client.patch<MaintenanceWindow>(`/maintenance/${id}`, req);
+  return data;
+}
+
+export async function startMaintenance(id: string): Promise<MaintenanceWindow> {
+  const { data } = await client.post<MaintenanceWindow>(`/maintenance/${id}/start`);
+  return data;
+}
+
+export async function completeMaintenance(id: string, odometerKm?: number): Promise<MaintenanceWindow> {
+  const { data } = await client.post<MaintenanceWindow>(`/maintenance/${id}/complete`, { odometer_km: odometerKm });
+  return data;
+}
+
+export async function cancelMaintenance(id: string, reason?: string): Promise<void> {
+  await client.post(`/maintenance/${id}/cancel`, { reason });
+}
+
+export async function listMaintenanceSchedules(vehicleId: string): Promise<MaintenanceSchedule[]> {
+  const { data } = await client.get<MaintenanceSchedule[]>(`/vehicles/${vehicleId}/maintenance-schedules`);
+  return data;
+}
+
+export async function createMaintenanceSchedule(vehicleId: string, req: {
+  type: MaintenanceType;
+  duration_min: number;
+  interval_days?: number;
+  interval_km?: number;
+  last_done_at?: string;
+  last_done_km?: number;
+  vendor?: string;
+  notes?: string;
+}): Promise<MaintenanceSchedule> {
+  const { data } = await client.post<MaintenanceSchedule>(`/vehicles/${vehicleId}/maintenance-schedules`, req);
+  return data;
+}
+
+export async function deleteMaintenanceSchedule(id: string): Promise<void> {
+  await client.delete(`/maintenance-schedules/${id}`);
+}
@@ -0,0 +1,12 @@
+import client from './client';
+import type { FuelLog, VehicleMileage } from '../types/api';
+
+export async function getVehicleMileage(vehicleId: string, params?: { from?: string; to?: string }): Promise<VehicleMileage> {
+  const { data } = await client.get<VehicleMileage>(`/vehicles/${vehicleId}/mileage`, { params });
+  return data;
+}
+
+export async function listFuelLogs(vehicleId: string, params?: { from?: string; to?: string }): Promise<FuelLog[]> {
+  const { data } = await client.get<FuelLog[]>(`/vehicles/${vehicleId}/fuel-logs`, { params });
+  return data;
+}
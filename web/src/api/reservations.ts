@@ -1,5 +1,5 @@
 import client from './client';
-import type { Reservation, ReservationConflict, ConflictDetail } from '../types/api';
+import type { Reservation, ReservationConflict, ConflictDetail, ConflictSuggestions, ReservationSeries, ReservationSeriesDetail } from '../types/api';
 
 export async function listReservations(params?: {
   vehicle_id?: string;
@@ -44,6 +44,49 @@ export async function cancelReservation(id: string, reason: string) {
   await client.post(`/reservations/${id}/cancel`, { reason });
 }
 
+// Recurring series. Single occurrences are edited and cancelled with the
+// reservation functions above.
+export async function listReservationSeries(params?: {
+  requester_id?: string;
+  status?: string;
+}): Promise<ReservationSeries[]> {
+  const { data } = await client.get<ReservationSeries[]>('/reservation-series', { params });
+  return data;
+}
+
+export async function getReservationSeries(id: string): Promise<ReservationSeriesDetail> {
+  const { data } = await client.get<ReservationSeriesDetail>(`/reservation-series/${id}`);
+  return data;
+}
+
+export async function createReservationSeries(req: {
+  vehicle_id: string;
+  start_time: string;
+  end_time: string;
+  rrule: string;
+  timezone?: string;
+  purpose: string;
+  destinations?: string[];
+  notes?: string;
+}): Promise<ReservationSeriesDetail> {
+  const { data } = await client.post<ReservationSeriesDetail>('/reservation-series', req);
+  return data;
+}
+
+export async function updateReservationSeries(id: string, req: Partial<{
+  vehicle_id: string;
+  purpose: string;
+  destinations: string[];
+  notes: string;
+}>): Promise<ReservationSeriesDetail> {
+  const { data } = await client.put<ReservationSeriesDetail>(`/reservation-series/${id}`, req);
+  return data;
+}
+
+export async function cancelReservationSeries(id: string, reason: string) {
+  await client.post(`/reservation-series/${id}/cancel`, { reason });
+}
+
 export async function listConflicts(): Promise<ReservationConflict[]> {
   const { data } = await client.get<ReservationConflict[]>('/conflicts');
   return data;
@@ -54,6 +97,11 @@ export async function getConflict(id: string): Promise<ConflictDetail> {
   return data;
 }
 
+export async function getConflictSuggestions(id: string): Promise<ConflictSuggestions> {
+  const { data } = await client.get<ConflictSuggestions>(`/conflicts/${id}/suggestions`);
+  return data;
+}
+
 export async function resolveConflictReassign(id: string, newVehicleId: string, reason: string) {
   await client.post(`/conflicts/${id}/reassign`, { new_vehicle_id: newVehicleId, reason });
 }
@@ -1,5 +1,5 @@
 import client from './client';
-import type { Vehicle } from '../types/api';
+import type { FleetAvailability, Vehicle, VehicleAssignment } from '../types/api';
 
 export async function listVehicles(): Promise<Vehicle[]> {
   const { data } = await client.get<Vehicle[]>('/vehicles');
@@ -11,15 +11,25 @@ export async function listAvailableVehicles(): Promise<Vehicle[]> {
   return data;
 }
 
-export async function createVehicle(req: { name: string; license_plate: string; driver_id: string }) {
+export async function getFleetAvailability(params?: { from?: string; to?: string; granularity?: string }): Promise<FleetAvailability> {
+  const { data } = await client.get<FleetAvailability>('/fleet/availability', { params });
+  return data;
+}
+
+export async function createVehicle(req: { name: string; license_plate: string }) {
   const { data } = await client.post('/vehicles', req);
   return data;
 }
 
-export async function updateVehicle(id: string, req: { name: string; license_plate: string; driver_id: string }) {
+export async function updateVehicle(id: string, req: { name: string; license_plate: string }) {
   await client.put(`/vehicles/${id}`, req);
 }
 
+export async function listVehicleAssignments(id: string, limit?: number): Promise<VehicleAssignment[]> {
+  const { data } = await client.get<VehicleAssignment[]>(`/vehicles/${id}/assignments`, { params: { limit } });
+  return data;
+}
+
 export async function deleteVehicle(id: string) {
   await client.delete(`/vehicles/${id}`);
 }
@@ -33,6 +43,6 @@ export async function uploadVehiclePhoto(id: string, file: File): Promise<{ phot
   return data;
 }
 
-export async function toggleMaintenance(id: string, isMaintenance: boolean) {
-  await client.patch(`/vehicles/${id}/maintenance`, { is_maintenance: isMaintenance });
+export async function setTurnaround(id: string, turnaroundMin: number) {
+  await client.patch(`/vehicles/${id}/turnaround`, { turnaround_min: turnaroundMin });
 }
@@ -244,6 +244,17 @@ const en = {
     allClear: 'All Clear',
     noConflicts: 'No unresolved conflicts at this time',
     conflictId: 'Conflict #{id}',
+    kind: {
+      overlap: 'Overlap',
+      turnaround: 'Turnaround',
+      travel: 'Travel time',
+      maintenance: 'Maintenance',
+    },
+    gap: 'Needs {required} min between trips, has {available} min',
+    suggestions: 'Suggested moves',
+    moveToVehicle: 'Move to {name} ({plate})',
+    moveToSlot: 'Move to {time}',
+    suggestionReason: 'Applied suggested move',
     detailTitle: 'Conflict Details',
     detailSubtitle: 'Review and resolve the overlapping reservations below',
     winner: 'WINNER',
@@ -267,15 +278,13 @@ const en = {
     vehicleNamePlaceholder: 'e.g. Van A',
     licensePlateLabel: 'License Plate',
     licensePlatePlaceholder: 'e.g. NCR-1006',
-    assignedDriverLabel: 'Assigned Driver',
-    selectDriver: 'Select driver...',
-    driverAssigned: '- assigned',
     saving: 'Saving...',
     adding: 'Adding...',
     saveChanges: 'Save Changes',
     uploadPhoto: 'Click to upload photo',
     changePhoto: 'Change photo',
     driverLabel: 'Driver:',
+    noDriver: 'nobody on shift',
     confirmDeleteVehicle: 'Are you sure you want to delete this vehicle?',
     noVehicles: 'No vehicles yet. Click "Add Vehicle" to get started.',
     userManagement: 'User Management',
@@ -246,6 +246,17 @@ const ja: Translations = {
     allClear: '問題なし',
     noConflicts: '現在未解決の競合はありません',
     conflictId: '競合 #{id}',
+    kind: {
+      overlap: '時間重複',
+      turnaround: '準備時間不足',
+      travel: '移動時間不足',
+      maintenance: '整備',
+    },
+    gap: '予約間に{required}分必要ですが、{available}分しかありません',
+    suggestions: '移動先の候補',
+    moveToVehicle: '{name}（{plate}）に変更',
+    moveToSlot: '{time}に変更',
+    suggestionReason: '候補から移動',
     detailTitle: '競合の詳細',
     detailSubtitle: '以下の重複する予約を確認して解決してください',
     winner: '優先',
@@ -269,15 +280,13 @@ const ja: Translations = {
     vehicleNamePlaceholder: '例: バンA',
     licensePlateLabel: 'ナンバープレート',
     licensePlatePlaceholder: '例: 品川 300 あ 1234',
-    assignedDriverLabel: '担当ドライバー',
-    selectDriver: 'ドライバーを選択...',
-    driverAssigned: '- 割当済み',
     saving: '保存中...',
     adding: '追加中...',
     saveChanges: '変更を保存',
     uploadPhoto: 'クリックして写真をアップロード',
     changePhoto: '写真を変更',
     driverLabel: 'ドライバー:',
+    noDriver: 'シフト中のドライバーなし',
     confirmDeleteVehicle: 'この車両を削除してもよろしいですか？',
     noVehicles: '車両がまだありません。「車両を追加」をクリックして開始してください。',
     userManagement: 'ユーザー管理',
@@ -246,6 +246,17 @@ const ko: Translations = {
     allClear: '문제 없음',
     noConflicts: '현재 미해결 충돌이 없습니다',
     conflictId: '충돌 #{id}',
+    kind: {
+      overlap: '시간 중복',
+      turnaround: '준비 시간 부족',
+      travel: '이동 시간 부족',
+      maintenance: '정비',
+    },
+    gap: '운행 사이에 {required}분이 필요하지만 {available}분뿐입니다',
+    suggestions: '추천 변경안',
+    moveToVehicle: '{name}({plate})(으)로 변경',
+    moveToSlot: '{time}(으)로 변경',
+    suggestionReason: '추천 변경안 적용',
     detailTitle: '충돌 상세',
     detailSubtitle: '아래의 중복 예약을 검토하고 해결하세요',
     winner: '우선',
@@ -269,15 +280,13 @@ const ko: Translations = {
     vehicleNamePlaceholder: '예: 밴 A',
     licensePlateLabel: '번호판',
     licensePlatePlaceholder: '예: 12가 3456',
-    assignedDriverLabel: '담당 기사',
-    selectDriver: '기사를 선택하세요...',
-    driverAssigned: '- 배정됨',
     saving: '저장 중...',
     adding: '추가 중...',
     saveChanges: '변경 사항 저장',
     uploadPhoto: '사진을 업로드하려면 클릭하세요',
     changePhoto: '사진 변경',
     driverLabel: '기사:',
+    noDriver: '근무 중인 기사 없음',
     confirmDeleteVehicle: '이 차량을 삭제하시겠습니까?',
     noVehicles: '차량이 없습니다. "차량 추가"를 클릭하여 시작하세요.',
     userManagement: '사용자 관리',
@@ -246,6 +246,17 @@ const zh: Translations = {
     allClear: '无冲突',
     noConflicts: '目前没有未解决的冲突',
     conflictId: '冲突 #{id}',
+    kind: {
+      overlap: '时间重叠',
+      turnaround: '准备时间不足',
+      travel: '路程时间不足',
+      maintenance: '维修保养',
+    },
+    gap: '行程之间需要{required}分钟，实际只有{available}分钟',
+    suggestions: '建议的调整',
+    moveToVehicle: '改为{name}（{plate}）',
+    moveToSlot: '改到{time}',
+    suggestionReason: '采用建议的调整',
     detailTitle: '冲突详情',
     detailSubtitle: '请查看并解决以下重叠的预约',
     winner: '优先',
@@ -269,15 +280,13 @@ const zh: Translations = {
     vehicleNamePlaceholder: '例如 面包车A',
     licensePlateLabel: '车牌号',
     licensePlatePlaceholder: '例如 京A12345',
-    assignedDriverLabel: '指定驾驶员',
-    selectDriver: '选择驾驶员...',
-    driverAssigned: '- 已分配',
     saving: '保存中...',
     adding: '添加中...',
     saveChanges: '保存更改',
     uploadPhoto: '点击上传照片',
     changePhoto: '更换照片',
     driverLabel: '驾驶员:',
+    noDriver: '无当班驾驶员',
     confirmDeleteVehicle: '确定要删除此车辆吗？',
     noVehicles: '暂无车辆。点击"添加车辆"开始。',
     userManagement: '用户管理',
@@ -1,6 +1,6 @@
 import { useState, useEffect } from 'react';
-import { listConflicts, getConflict, resolveConflictCancel, forceAssign } from '../api/reservations';
-import type { ReservationConflict, ConflictDetail } from '../types/api';
+import { listConflicts, getConflict, getConflictSuggestions, resolveConflictCancel, resolveConflictReassign, resolveConflictChangeTime, forceAssign } from '../api/reservations';
+import type { ReservationConflict, ConflictDetail, ConflictSuggestions } from '../types/api';
 import { formatDateTime } from '../utils/formatters';
 import { usePermission } from '../hooks/usePermission';
 import { useI18nStore } from '../stores/i18nStore';
@@ -11,6 +11,7 @@ export function ConflictPage() {
   const isMobile = useIsMobile();
   const [conflicts, setConflicts] = useState<ReservationConflict[]>([]);
   const [detail, setDetail] = useState<ConflictDetail | null>(null);
+  const [suggestions, setSuggestions] = useState<ConflictSuggestions | null>(null);
   const [loadingDetail, setLoadingDetail] = useState(false);
   const isAdmin = usePermission('admin');
 
@@ -24,9 +25,11 @@ export function ConflictPage() {
   const handleViewDetail = async (id: string) => {
     setLoadingDetail(true);
     setDetail(null);
+    setSuggestions(null);
     try {
-      const data = await getConflict(id);
+      const [data, options] = await Promise.all([getConflict(id), getConflictSuggestions(id)]);
       setDetail(data);
+      setSuggestions(options);
     } finally {
       setLoadingDetail(false);
     }
@@ -41,6 +44,18 @@ export function ConflictPage() {
     }
   };
 
+  const handleMoveToVehicle = async (conflictId: string, vehicleId: string) => {
+    await resolveConflictReassign(conflictId, vehicleId, t('conflict.suggestionReason'));
+    setDetail(null);
+    fetchConflicts();
+  };
+
+  const handleMoveToSlot = async (conflictId: string, start: string, end: string) => {
+    await resolveConflictChangeTime(conflictId, start, end, t('conflict.suggestionReason'));
+    setDetail(null);
+    fetchConflicts();
+  };
+
   const handleForceAssign = async (conflictId: string) => {
     const reason = prompt(t('conflict.forceAssignReason'));
     if (reason) {
@@ -99,6 +114,9 @@ export function ConflictPage() {
                     background: '#f59e0b', animation: 'pulse 2s infinite',
                   }} />
                   <span style={{ fontWeight: 600, color: '#0f172a' }}>{t('conflict.conflictId', { id: c.id.slice(0, 8) })}</span>
+                  <span style={{ fontSize: '0.75rem', color: '#b45309', background: '#fef3c7', borderRadius: 6, padding: '2px 8px' }}>
+                    {t(`conflict.kind.${c.kind}` as Parameters<typeof t>[0])}
+                  </span>
                 </div>
                 <span style={{ fontSize: '0.8rem', color: '#94a3b8' }}>{formatDateTime(c.created_at, locale)}</span>
               </div>
@@ -142,6 +160,14 @@ export function ConflictPage() {
             <p style={{ margin: '0 0 24px', fontSize: '0.82rem', color: '#64748b' }}>
               {t('conflict.detailSubtitle')}
             </p>
+            {detail.conflict.required_gap_sec != null && detail.conflict.available_gap_sec != null && (
+              <p style={{ margin: '-12px 0 24px', fontSize: '0.82rem', color: '#b45309' }}>
+                {t(`conflict.kind.${detail.conflict.kind}` as Parameters<typeof t>[0])}: {t('conflict.gap', {
+                  required: Math.ceil(detail.conflict.required_gap_sec / 60),
+                  available: Math.floor(detail.conflict.available_gap_sec / 60),
+                })}
+              </p>
+            )}
 
             <div style={{ display: 'grid', gridTemplateColumns: isMobile ? '1fr' : '1fr 1fr', gap: 16, marginBottom: isMobile ? 20 : 28 }}>
               {/* Winner */}
@@ -156,15 +182,21 @@ export function ConflictPage() {
                     fontSize: '0.7rem', fontWeight: 700,
                   }}>{t('conflict.winner')}</span>
                 </div>
-                <div style={{ fontSize: '0.9rem', fontWeight: 600, marginBottom: 4 }}>{detail.winning_reservation?.purpose}</div>
-                <div style={{ fontSize: '0.82rem', color: '#475569', marginBottom: 2 }}>
-                  {t('conflict.priority')} <strong>{detail.winning_reservation?.priority_level}</strong>
-                </div>
-                <div style={{ fontSize: '0.78rem', color: '#64748b' }}>
-                  {detail.winning_reservation?.start_time && formatDateTime(detail.winning_reservation.start_time, locale)}
-                  {' - '}
-                  {detail.winning_reservation?.end_time && formatDateTime(detail.winning_reservation.end_time, locale)}
-                </div>
+                {detail.winning_reservation ? (
+                  <>
+                    <div style={{ fontSize: '0.9rem', fontWeight: 600, marginBottom: 4 }}>{detail.winning_reservation.purpose}</div>
+                    <div style={{ fontSize: '0.82rem', color: '#475569', marginBottom: 2 }}>
+                      {t('conflict.priority')} <strong>{detail.winning_reservation.priority_level}</strong>
+                    </div>
+                    <div style={{ fontSize: '0.78rem', color: '#64748b' }}>
+                      {formatDateTime(detail.winning_reservation.start_time, locale)}
+                      {' - '}
+                      {formatDateTime(detail.winning_reservation.end_time, locale)}
+                    </div>
+                  </>
+                ) : (
+                  <div style={{ fontSize: '0.9rem', fontWeight: 600 }}>{t('conflict.kind.maintenance')}</div>
+                )}
               </div>
 
               {/* Loser */}
@@ -191,13 +223,37 @@ export function ConflictPage() {
               </div>
             </div>
 
+            {suggestions && (suggestions.vehicles.length > 0 || suggestions.slots.length > 0) && (
+              <div style={{ marginBottom: isMobile ? 20 : 28 }}>
+                <div style={{ fontSize: '0.85rem', fontWeight: 600, color: '#0f172a', marginBottom: 8 }}>
+                  {t('conflict.suggestions')}
+                </div>
+                <div style={{ display: 'flex', gap: 8, flexWrap: 'wrap' }}>
+                  {suggestions.vehicles.map((v) => (
+                    <button key={v.vehicle_id} onClick={() => handleMoveToVehicle(detail.conflict.id, v.vehicle_id)} style={{
+                  padding: '6px 12px', background: '#eff6ff', color: '#1d4ed8',
+                  border: '1px solid #bfdbfe', borderRadius: 8, cursor: 'pointer',
+                  fontWeight: 500, fontSize: '0.8rem', fontFamily: 'inherit',
+                }}>{t('conflict.moveToVehicle', { name: v.vehicle_name, plate: v.license_plate })}</button>
+                  ))}
+                  {suggestions.slots.map((slot) => (
+                    <button key={slot.start_time} onClick={() => handleMoveToSlot(detail.conflict.id, slot.start_time, slot.end_time)} style={{
+                  padding: '6px 12px', background: '#eff6ff', color: '#1d4ed8',
+                  border: '1px solid #bfdbfe', borderRadius: 8, cursor: 'pointer',
+                  fontWeight: 500, fontSize: '0.8rem', fontFamily: 'inherit',
+                }}>{t('conflict.moveToSlot', { time: formatDateTime(slot.start_time, locale) })}</button>
+                  ))}
+                </div>
+              </div>
+            )}
+
             <div style={{ display: 'flex', gap: 8, flexWrap: 'wrap' }}>
               <button onClick={() => handleCancelLosing(detail.conflict.id)} style={{
                 padding: '9px 20px', background: '#dc2626', color: '#fff',
                 border: 'none', borderRadius: 8, cursor: 'pointer',
                 fontWeight: 600, fontSize: '0.85rem', fontFamily: 'inherit',
               }}>{t('conflict.cancelConflicting')}</button>
-              {isAdmin && (
+              {isAdmin && detail.conflict.kind !== 'maintenance' && (
                 <button onClick={() => handleForceAssign(detail.conflict.id)} style={{
                   padding: '9px 20px', background: '#7c3aed', color: '#fff',
                   border: 'none', borderRadius: 8, cursor: 'pointer',
@@ -58,34 +58,29 @@ function VehicleManagement() {
   const { t } = useI18nStore();
   const isMobile = useIsMobile();
   const [vehicles, setVehicles] = useState<Vehicle[]>([]);
-  const [users, setUsers] = useState<User[]>([]);
   const [showForm, setShowForm] = useState(false);
   const [editId, setEditId] = useState<string | null>(null);
-  const [form, setForm] = useState({ name: '', license_plate: '', driver_id: '' });
+  const [form, setForm] = useState({ name: '', license_plate: '' });
   const fileRef = useRef<HTMLInputElement>(null);
   const [uploadingId, setUploadingId] = useState<string | null>(null);
   const [submitting, setSubmitting] = useState(false);
 
   const fetchData = async () => {
-    const [v, u] = await Promise.all([listVehicles(), listUsers()]);
+    const v = await listVehicles();
     setVehicles(v || []);
-    setUsers(u || []);
   };
 
-  useEffect(() => { void (async () => { const [v, u] = await Promise.all([listVehicles(), listUsers()]); setVehicles(v || []); setUsers(u || []); })(); }, []);
-
-  const drivers = users.filter(u => u.role === 'driver');
-  const assignedDriverIds = new Set(vehicles.map(v => v.driver_id));
+  useEffect(() => { void (async () => { const v = await listVehicles(); setVehicles(v || []); })(); }, []);
 
   const openCreate = () => {
     setEditId(null);
-    setForm({ name: '', license_plate: '', driver_id: '' });
+    setForm({ name: '', license_plate: '' });
     setShowForm(true);
   };
 
   const openEdit = (v: Vehicle) => {
     setEditId(v.id);
-    setForm({ name: v.name, license_plate: v.license_plate, driver_id: v.driver_id });
+    setForm({ name: v.name, license_plate: v.license_plate });
     setShowForm(true);
   };
 
@@ -153,17 +148,6 @@ function VehicleManagement() {
                 <input required value={form.license_plate} onChange={e => setForm(f => ({ ...f, license_plate: e.target.value }))}
                   placeholder={t('settings.licensePlatePlaceholder')} />
               </div>
-              <div>
-                <label style={labelStyle}>{t('settings.assignedDriverLabel')} *</label>
-                <select required value={form.driver_id} onChange={e => setForm(f => ({ ...f, driver_id: e.target.value }))}>
-                  <option value="">{t('settings.selectDriver')}</option>
-                  {drivers.map(d => (
-                    <option key={d.id} value={d.id} disabled={assignedDriverIds.has(d.id) && d.id !== form.driver_id}>
-                      {d.name} ({d.employee_id}){assignedDriverIds.has(d.id) && d.id !== form.driver_id ? ` ${t('settings.driverAssigned')}` : ''}
-                    </option>
-                  ))}
-                </select>
-              </div>
             </div>
             <div style={{ display: 'flex', gap: 10, marginTop: 24, justifyContent: 'flex-end' }}>
               <button type="button" onClick={() => setShowForm(false)} style={{
@@ -254,7 +238,7 @@ function VehicleManagement() {
                   <StatusDot status={v.status} />
                 </div>
                 <div style={{ fontSize: '0.82rem', color: '#475569', marginTop: 10 }}>
-                  {t('settings.driverLabel')} <strong>{v.driver_name}</strong>
+                  {t('settings.driverLabel')} <strong>{v.driver_name || t('settings.noDriver')}</strong>
                 </div>
                 <div style={{ display: 'flex', gap: 8, marginTop: 14 }}>
                   <button onClick={() => openEdit(v)} style={{
@@ -26,6 +26,8 @@ export type ReservationStatus =
   | 'cancelled'
   | 'completed';
 
+export type ConflictKind = 'overlap' | 'turnaround' | 'travel' | 'maintenance';
+
 export type ConflictStatus =
   | 'pending'
   | 'resolved_reassign'
@@ -52,9 +54,14 @@ export interface Vehicle {
   id: string;
   name: string;
   license_plate: string;
-  driver_id: string;
+  /** Depot owning the vehicle; absent while it is shared by all depots. */
+  depot_id?: string;
+  /** Driver holding the vehicle on their shift; driver_name is empty without one. */
+  driver_id?: string;
   driver_name: string;
   is_maintenance: boolean;
+  turnaround_min: number;
+  odometer_km?: number;
   is_clocked_in: boolean;
   photo_url?: string;
   status: VehicleStatus;
@@ -65,6 +72,49 @@ export interface Vehicle {
   location_at?: string;
 }
 
+/** A shift on which the driver had the vehicle checked out. */
+export interface VehicleAssignment {
+  id: string;
+  driver_id: string;
+  driver_name: string;
+  vehicle_id?: string;
+  driver_status: 'active' | 'waiting';
+  clock_in_at: string;
+  clock_out_at?: string;
+  clock_in_odometer_km?: number;
+  clock_out_odometer_km?: number;
+  created_at: string;
+}
+
+export type AvailabilityKind = 'reservation' | 'dispatch' | 'maintenance' | 'driver_absent';
+
+export interface TimeInterval {
+  start: string;
+  end: string;
+}
+
+export interface BusyInterval extends TimeInterval {
+  kind: AvailabilityKind;
+  ref_id?: string;
+  status?: string;
+}
+
+export interface VehicleAvailability {
+  vehicle_id: string;
+  vehicle_name: string;
+  license_plate: string;
+  driver_name: string;
+  busy: BusyInterval[];
+  free: TimeInterval[];
+}
+
+export interface FleetAvailability {
+  from: string;
+  to: string;
+  granularity: string;
+  vehicles: VehicleAvailability[];
+}
+
 export interface Dispatch {
   id: string;
   vehicle_id?: string;
@@ -91,10 +141,28 @@ export interface Dispatch {
   completed_at?: string;
   cancelled_at?: string;
   cancel_reason?: string;
+  reservation_id?: string;
+  version: number;
   created_at: string;
   updated_at: string;
 }
 
+export type DispatchActor = 'driver' | 'dispatcher' | 'requester' | 'system';
+
+export interface DispatchTransition {
+  from: DispatchStatus;
+  to: DispatchStatus;
+  action: string;
+  actors: DispatchActor[];
+}
+
+export interface DispatchStateMachine {
+  statuses: DispatchStatus[];
+  terminal: DispatchStatus[];
+  actors: DispatchActor[];
+  transitions: DispatchTransition[];
+}
+
 export interface ETASnapshot {
   id: string;
   dispatch_id: string;
@@ -123,12 +191,41 @@ export interface Reservation {
   status: ReservationStatus;
   cancel_reason?: string;
   cancelled_by?: string;
+  series_id?: string;
+  occurrence_start?: string;
+  is_exception?: boolean;
   vehicle_name?: string;
   requester_name?: string;
   created_at: string;
   updated_at: string;
 }
 
+export type ReservationSeriesStatus = 'active' | 'ended' | 'cancelled';
+
+export interface ReservationSeries {
+  id: string;
+  vehicle_id: string;
+  requester_id: string;
+  rrule: string;
+  dtstart: string;
+  duration_sec: number;
+  timezone: string;
+  purpose: string;
+  destinations?: string[];
+  notes?: string;
+  priority_level: number;
+  status: ReservationSeriesStatus;
+  materialized_until?: string;
+  cancel_reason?: string;
+  cancelled_by?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface ReservationSeriesDetail extends ReservationSeries {
+  occurrences: Reservation[];
+}
+
 export type BookingMode = 'specific' | 'any';
 
 export interface UnifiedBookingRequest {
@@ -144,18 +241,45 @@ export interface UnifiedBookingRequest {
   destinations?: string[];
   passenger_name?: string;
   notes?: string;
+  waitlist?: boolean;
 }
 
 export interface UnifiedBookingResponse {
-  type: 'dispatch' | 'reservation';
+  type: 'dispatch' | 'reservation' | 'waitlist';
   dispatch?: Dispatch;
   reservation?: Reservation;
+  waitlist?: WaitlistEntry;
+}
+
+export type WaitlistStatus = 'waiting' | 'booked' | 'expired' | 'cancelled';
+
+export interface WaitlistEntry {
+  id: string;
+  requester_id: string;
+  start_time: string;
+  end_time: string;
+  purpose: string;
+  destinations: string[];
+  notes?: string;
+  passenger_name?: string;
+  pickup_address?: string;
+  pickup_lat?: number;
+  pickup_lng?: number;
+  priority_level: number;
+  status: WaitlistStatus;
+  reservation_id?: string;
+  created_at: string;
+  updated_at: string;
 }
 
 export interface ReservationConflict {
   id: string;
-  winning_reservation_id: string;
+  winning_reservation_id?: string;
+  maintenance_window_id?: string;
   losing_reservation_id: string;
+  kind: ConflictKind;
+  required_gap_sec?: number;
+  available_gap_sec?: number;
   status: ConflictStatus;
   resolved_by?: string;
   resolution_reason?: string;
@@ -165,10 +289,30 @@ export interface ReservationConflict {
 
 export interface ConflictDetail {
   conflict: ReservationConflict;
-  winning_reservation: Reservation;
+  winning_reservation?: Reservation;
   losing_reservation: Reservation;
 }
 
+export interface VehicleSuggestion {
+  vehicle_id: string;
+  vehicle_name: string;
+  license_plate: string;
+  slack_sec: number;
+}
+
+export interface SlotSuggestion {
+  start_time: string;
+  end_time: string;
+  shift_sec: number;
+}
+
+export interface ConflictSuggestions {
+  conflict_id: string;
+  reservation_id: string;
+  vehicles: VehicleSuggestion[];
+  slots: SlotSuggestion[];
+}
+
 export interface AuditLog {
   id: string;
   actor_id: string;
@@ -193,6 +337,7 @@ export interface VehicleETA {
   distance_m: number;
   duration_sec: number;
   is_available: boolean;
+  provider: 'google' | 'osrm' | 'learned' | 'haversine';
 }
 
 export interface ApiError {
@@ -201,3 +346,115 @@ export interface ApiError {
     message: string;
   };
 }
+
+export type CalendarFeedType = 'vehicle' | 'driver' | 'requester';
+
+export interface CalendarFeed {
+  id: string;
+  // Only returned when the feed is created; save the URL then.
+  token?: string;
+  subject_type: CalendarFeedType;
+  subject_id: string;
+  created_by: string;
+  created_at: string;
+  url?: string;
+}
+
+export type MaintenanceType = 'inspection' | 'oil_change' | 'tyres' | 'brakes' | 'repair' | 'cleaning' | 'other';
+export type MaintenanceStatus = 'planned' | 'in_progress' | 'done' | 'cancelled';
+
+export interface MaintenanceWindow {
+  id: string;
+  vehicle_id: string;
+  schedule_id?: string;
+  type: MaintenanceType;
+  status: MaintenanceStatus;
+  start_time: string;
+  end_time: string;
+  vendor?: string;
+  notes?: string;
+  odometer_km?: number;
+  created_by: string;
+  completed_at?: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface MaintenanceSchedule {
+  id: string;
+  vehicle_id: string;
+  type: MaintenanceType;
+  vendor?: string;
+  notes?: string;
+  duration_min: number;
+  interval_days?: number;
+  interval_km?: number;
+  last_done_at: string;
+  last_done_km?: number;
+  created_by: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface FuelLog {
+  id: string;
+  vehicle_id: string;
+  driver_id: string;
+  litres: number;
+  cost: number;
+  station?: string;
+  odometer_km?: number;
+  receipt_url?: string;
+  fueled_at: string;
+  created_at: string;
+}
+
+export interface DailyMileage {
+  date: string;
+  odometer_km: number | null;
+  gps_km: number;
+  fuel_litres: number;
+  fuel_cost: number;
+  anomaly: boolean;
+}
+
+export interface VehicleMileage {
+  vehicle_id: string;
+  from: string;
+  to: string;
+  odometer_km?: number;
+  distance_odometer_km: number;
+  distance_gps_km: number;
+  fuel_litres: number;
+  fuel_cost: number;
+  km_per_litre: number | null;
+  days: DailyMileage[];
+  anomalies: DailyMileage[];
+}
+
+export type DocumentType = 'registration' | 'insurance' | 'emission_test' | 'driver_licence' | 'other';
+
+export interface VehicleDocument {
+  id: string;
+  vehicle_id?: string;
+  user_id?: string;
+  type: DocumentType;
+  number?: string;
+  issued_on?: string;
+  expires_on?: string;
+  scan_file?: string;
+  notes?: string;
+  created_by: string;
+  created_at: string;
+  updated_at: string;
+}
+
+export interface Depot {
+  id: string;
+  name: string;
+  address?: string;
+  latitude?: number;
+  longitude?: number;
+  created_at: string;
+  updated_at: string;
+}